@@ -10,6 +10,7 @@ import (
 	"bufio"
 	"context"
 	"embed"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/fs"
@@ -18,12 +19,16 @@ import (
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"runtime/debug"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/chzyer/readline"
 	"github.com/sipeed/picoclaw/pkg/agent"
 	"github.com/sipeed/picoclaw/pkg/auth"
+	"github.com/sipeed/picoclaw/pkg/backup"
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/channels"
 	"github.com/sipeed/picoclaw/pkg/config"
@@ -40,6 +45,7 @@ import (
 	"github.com/sipeed/picoclaw/pkg/voice"
 	"github.com/sipeed/picoclaw/pkg/debugui"
 	"github.com/sipeed/picoclaw/pkg/secops"
+	"github.com/sipeed/picoclaw/pkg/secops/schema"
 )
 
 //go:generate cp -r ../../workspace .
@@ -55,6 +61,30 @@ var (
 
 const logo = "🦞"
 
+// init fills in version details from the Go build info when the binary was
+// built without the Makefile ldflags, e.g. via `go build` or `go install`.
+func init() {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	if version == "dev" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		version = info.Main.Version
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if gitCommit == "" && len(setting.Value) >= 8 {
+				gitCommit = setting.Value[:8]
+			}
+		case "vcs.time":
+			if buildTime == "" {
+				buildTime = setting.Value
+			}
+		}
+	}
+}
+
 // formatVersion returns the version string with optional git commit
 func formatVersion() string {
 	v := version
@@ -140,6 +170,12 @@ func main() {
 		statusCmd()
 	case "migrate":
 		migrateCmd()
+	case "secops":
+		secopsCmd()
+	case "backup":
+		backupCmd()
+	case "restore":
+		restoreCmd()
 	case "auth":
 		authCmd()
 	case "cron":
@@ -213,7 +249,10 @@ func printHelp() {
 	fmt.Println("  gateway     Start picoclaw gateway")
 	fmt.Println("  status      Show picoclaw status")
 	fmt.Println("  cron        Manage scheduled tasks")
-	fmt.Println("  migrate     Migrate from OpenClaw to PicoClaw")
+	fmt.Println("  migrate     Migrate from OpenClaw, or upgrade data schema (migrate schema)")
+	fmt.Println("  secops      SecOps maintenance (import proposals, export training data)")
+	fmt.Println("  backup      Back up proposals, schedules, sessions and config")
+	fmt.Println("  restore     Restore state from a backup archive")
 	fmt.Println("  skills      Manage skills (install, list, remove)")
 	fmt.Println("  version     Show version information")
 }
@@ -307,6 +346,10 @@ func migrateCmd() {
 		migrateHelp()
 		return
 	}
+	if len(os.Args) > 2 && os.Args[2] == "schema" {
+		migrateSchemaCmd(os.Args[3:])
+		return
+	}
 
 	opts := migrate.Options{}
 
@@ -370,6 +413,739 @@ func migrateHelp() {
 	fmt.Println("  picoclaw migrate --dry-run    Show what would be migrated")
 	fmt.Println("  picoclaw migrate --refresh    Re-sync workspace files")
 	fmt.Println("  picoclaw migrate --force      Migrate without confirmation")
+	fmt.Println()
+	fmt.Println("Upgrade persisted SecOps data and config between schema versions:")
+	fmt.Println("  picoclaw migrate schema [--dry-run] [--no-backup] [--workspace <dir>] [--config <file>]")
+}
+
+func migrateSchemaCmd(args []string) {
+	opts := schema.Options{ConfigPath: getConfigPath()}
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--dry-run":
+			opts.DryRun = true
+		case "--no-backup":
+			opts.NoBackup = true
+		case "--workspace":
+			if i+1 < len(args) {
+				opts.Workspace = args[i+1]
+				i++
+			}
+		case "--config":
+			if i+1 < len(args) {
+				opts.ConfigPath = args[i+1]
+				i++
+			}
+		default:
+			fmt.Printf("Unknown flag: %s\n", args[i])
+			migrateHelp()
+			os.Exit(1)
+		}
+	}
+
+	if opts.Workspace == "" {
+		cfg, err := config.LoadConfig(opts.ConfigPath)
+		if err != nil {
+			fmt.Printf("Error loading config: %v\n", err)
+			os.Exit(1)
+		}
+		opts.Workspace = cfg.WorkspacePath()
+	}
+
+	result, err := schema.Run(opts)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if result.From == result.To {
+		fmt.Printf("Workspace %s is already at schema version %d, nothing to do\n", opts.Workspace, result.To)
+		return
+	}
+
+	if result.DryRun {
+		fmt.Println("Dry run - no files were changed")
+	}
+	fmt.Printf("Schema version %d -> %d\n", result.From, result.To)
+	for _, step := range result.Applied {
+		fmt.Printf("  %s\n", step)
+	}
+	fmt.Println("Files:")
+	for _, c := range result.Changes {
+		switch {
+		case c.Created:
+			fmt.Printf("  + %s (%d bytes)\n", c.Path, c.After)
+		case c.Removed:
+			fmt.Printf("  - %s\n", c.Path)
+		default:
+			fmt.Printf("  ~ %s (%d -> %d bytes)\n", c.Path, c.Before, c.After)
+		}
+	}
+	if result.BackupDir != "" {
+		fmt.Printf("Backup: %s\n", result.BackupDir)
+	}
+	if !result.DryRun {
+		fmt.Println("Restart the gateway to load the migrated data.")
+	}
+}
+
+func secopsCmd() {
+	if len(os.Args) < 3 {
+		secopsHelp()
+		return
+	}
+
+	switch os.Args[2] {
+	case "import":
+		secopsImportCmd(os.Args[3:])
+	case "export-dataset":
+		secopsExportDatasetCmd(os.Args[3:])
+	case "eval":
+		secopsEvalCmd(os.Args[3:])
+	case "playbook":
+		secopsPlaybookCmd(os.Args[3:])
+	default:
+		fmt.Printf("Unknown secops command: %s\n", os.Args[2])
+		secopsHelp()
+	}
+}
+
+func secopsHelp() {
+	fmt.Println("\nSecOps commands:")
+	fmt.Println("  import <file>       Import historical proposals from CSV/JSON")
+	fmt.Println("  export-dataset      Export decided proposals as labeled JSONL training data")
+	fmt.Println("  eval                Replay a labeled dataset through the current prompt (no tools) and report accuracy")
+	fmt.Println("  playbook            Install, sync and sign playbook bundles")
+	fmt.Println()
+	fmt.Println("Import options:")
+	fmt.Println("  --format csv|json   Input format (default: from file extension)")
+	fmt.Println("  --map f=col,...     Map proposal fields to columns")
+	fmt.Println("                      fields: id, type, title, summary, status, severity, recommendation, reason, created_at")
+	fmt.Println("  --dry-run           Validate and report without importing")
+	fmt.Println()
+	fmt.Println("Unmapped columns are kept as proposal details. Stop the gateway before importing.")
+	fmt.Println()
+	fmt.Println("Export options:")
+	fmt.Println("  -o, --output <file> Output file (default: stdout)")
+	fmt.Println("  --since YYYY-MM-DD  Only proposals decided on or after this date")
+	fmt.Println("  --type t1,t2        Only these proposal types")
+	fmt.Println("  --no-redact         Keep IPs, emails, phone numbers and credentials as-is")
+	fmt.Println()
+	fmt.Println("Eval options:")
+	fmt.Println("  --dataset <file>    Labeled JSONL dataset (from export-dataset)")
+	fmt.Println("  --activity <name>   Activity to evaluate, e.g. risk_analysis")
+	fmt.Println("  --limit <n>         Evaluate at most n cases")
+	fmt.Println("  --price-in <x>      Price per 1M prompt tokens, for the cost estimate")
+	fmt.Println("  --price-out <x>     Price per 1M completion tokens")
+	fmt.Println("  --min-accuracy <x>  Exit with status 1 if accuracy is below x (0-1)")
+	fmt.Println("  --json              Print the full report as JSON")
+	fmt.Println()
+	fmt.Println("Playbook commands:")
+	fmt.Println("  playbook install <source>  Install a bundle from an https:// URL or oci://registry/repo:tag")
+	fmt.Println("  playbook sync              Reinstall all bundles at the digests pinned in playbooks/lock.json")
+	fmt.Println("  playbook list              List installed bundles")
+	fmt.Println("  playbook keygen            Generate an ed25519 signing key pair")
+	fmt.Println("  playbook sign <file>       Sign a bundle, writing <file>.sig")
+	fmt.Println()
+	fmt.Println("Playbook options:")
+	fmt.Println("  --name <name>       Install directory under playbooks/ (default: from the source)")
+	fmt.Println("  --sha256 <digest>   Refuse the bundle unless its SHA-256 matches")
+	fmt.Println("  --signature <src>   Signature file or https URL (default: <url>.sig or the OCI annotation)")
+	fmt.Println("  --update            Replace an installed bundle whose digest has changed")
+	fmt.Println("  --key <file>        Private key file for sign")
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  picoclaw secops import history.csv --map title=事件,type=类型,status=结论 --dry-run")
+	fmt.Println("  picoclaw secops export-dataset --since 2026-01-01 --type risk -o risk.jsonl")
+	fmt.Println("  picoclaw secops eval --dataset risk.jsonl --activity risk_analysis --min-accuracy 0.85")
+	fmt.Println("  picoclaw secops playbook install https://example.com/triage.tar.gz --sha256 3f2a...")
+	fmt.Println("  picoclaw secops playbook install oci://ghcr.io/acme/playbooks/triage:1.2.0")
+}
+
+func secopsImportCmd(args []string) {
+	opts := secops.ImportOptions{Mapping: make(map[string]string)}
+	var file string
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--format":
+			if i+1 < len(args) {
+				opts.Format = args[i+1]
+				i++
+			}
+		case "--map":
+			if i+1 < len(args) {
+				for _, pair := range strings.Split(args[i+1], ",") {
+					field, col, ok := strings.Cut(pair, "=")
+					if !ok {
+						fmt.Printf("Invalid mapping: %s\n", pair)
+						os.Exit(1)
+					}
+					opts.Mapping[strings.TrimSpace(field)] = strings.TrimSpace(col)
+				}
+				i++
+			}
+		case "--dry-run":
+			opts.DryRun = true
+		default:
+			if strings.HasPrefix(args[i], "-") || file != "" {
+				fmt.Printf("Unknown argument: %s\n", args[i])
+				secopsHelp()
+				os.Exit(1)
+			}
+			file = args[i]
+		}
+	}
+	if file == "" {
+		secopsHelp()
+		os.Exit(1)
+	}
+	opts.Source = filepath.Base(file)
+	if opts.Format == "" {
+		opts.Format = strings.TrimPrefix(strings.ToLower(filepath.Ext(file)), ".")
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	proposalService := secops.NewProposalService()
+	if err := proposalService.EnablePersistence(filepath.Join(cfg.WorkspacePath(), "secops", "proposals.json")); err != nil {
+		fmt.Printf("Error loading proposals: %v\n", err)
+		os.Exit(1)
+	}
+	if err := proposalService.EnableAudit(filepath.Join(cfg.WorkspacePath(), "secops", "proposal_audit.jsonl")); err != nil {
+		fmt.Printf("Error loading proposal audit: %v\n", err)
+		os.Exit(1)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	report, err := secops.ImportProposals(proposalService, f, opts)
+	if ferr := proposalService.Flush(); ferr != nil {
+		fmt.Printf("Error saving proposals: %v\n", ferr)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if report.DryRun {
+		fmt.Println("Dry run - nothing was imported")
+	}
+	fmt.Printf("  Rows:       %d\n", report.Total)
+	fmt.Printf("  Imported:   %d\n", report.Imported)
+	fmt.Printf("  Duplicates: %d\n", report.Duplicates)
+	fmt.Printf("  Invalid:    %d\n", report.Invalid)
+	for i, e := range report.Errors {
+		if i == 20 {
+			fmt.Printf("  ... and %d more\n", len(report.Errors)-20)
+			break
+		}
+		fmt.Printf("  row %d: %s\n", e.Row, e.Message)
+	}
+}
+
+func secopsExportDatasetCmd(args []string) {
+	opts := secops.DatasetOptions{Redact: true}
+	output := ""
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-o", "--output":
+			if i+1 < len(args) {
+				output = args[i+1]
+				i++
+			}
+		case "--since":
+			if i+1 < len(args) {
+				since, err := time.ParseInLocation("2006-01-02", args[i+1], time.Local)
+				if err != nil {
+					fmt.Printf("Invalid --since date: %s\n", args[i+1])
+					os.Exit(1)
+				}
+				opts.Since = since
+				i++
+			}
+		case "--type":
+			if i+1 < len(args) {
+				for _, t := range strings.Split(args[i+1], ",") {
+					if t = strings.TrimSpace(t); t != "" {
+						opts.Types = append(opts.Types, t)
+					}
+				}
+				i++
+			}
+		case "--no-redact":
+			opts.Redact = false
+		default:
+			fmt.Printf("Unknown argument: %s\n", args[i])
+			secopsHelp()
+			os.Exit(1)
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	proposalService := secops.NewProposalService()
+	if err := proposalService.EnablePersistence(filepath.Join(cfg.WorkspacePath(), "secops", "proposals.json")); err != nil {
+		fmt.Printf("Error loading proposals: %v\n", err)
+		os.Exit(1)
+	}
+
+	w := os.Stdout
+	if output != "" {
+		f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			fmt.Printf("Error creating output file: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	count, err := secops.ExportDataset(w, proposalService.GetAll(), opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "✓ Exported %d records\n", count)
+}
+
+func secopsEvalCmd(args []string) {
+	var dataset string
+	var minAccuracy float64
+	var asJSON bool
+	opts := secops.EvalOptions{}
+
+	parseFloat := func(name, value string) float64 {
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil || v < 0 {
+			fmt.Printf("Invalid %s: %s\n", name, value)
+			os.Exit(1)
+		}
+		return v
+	}
+
+	for i := 0; i < len(args); i++ {
+		if i+1 < len(args) {
+			switch args[i] {
+			case "--dataset":
+				dataset = args[i+1]
+				i++
+				continue
+			case "--activity":
+				opts.Activity = args[i+1]
+				i++
+				continue
+			case "--limit":
+				limit, err := strconv.Atoi(args[i+1])
+				if err != nil || limit < 0 {
+					fmt.Printf("Invalid --limit: %s\n", args[i+1])
+					os.Exit(1)
+				}
+				opts.Limit = limit
+				i++
+				continue
+			case "--price-in":
+				opts.PriceIn = parseFloat("--price-in", args[i+1])
+				i++
+				continue
+			case "--price-out":
+				opts.PriceOut = parseFloat("--price-out", args[i+1])
+				i++
+				continue
+			case "--min-accuracy":
+				minAccuracy = parseFloat("--min-accuracy", args[i+1])
+				i++
+				continue
+			}
+		}
+		if args[i] == "--json" {
+			asJSON = true
+			continue
+		}
+		fmt.Printf("Unknown argument: %s\n", args[i])
+		secopsHelp()
+		os.Exit(1)
+	}
+	if dataset == "" || opts.Activity == "" {
+		secopsHelp()
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	provider, err := providers.CreateProvider(cfg)
+	if err != nil {
+		fmt.Printf("Error creating provider: %v\n", err)
+		os.Exit(1)
+	}
+	agentLoop := agent.NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+
+	f, err := os.Open(dataset)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	report, err := secops.Evaluate(context.Background(), f, agentLoop.CompleteWithSystemPrompt, opts)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	} else {
+		fmt.Printf("Activity:   %s\n", report.Activity)
+		fmt.Printf("Cases:      %d (evaluated %d, errors %d)\n", report.Total, report.Evaluated, report.Errors)
+		fmt.Printf("Accuracy:   %.1f%% (%d/%d)\n", report.Accuracy*100, report.Correct, report.Evaluated)
+		fmt.Printf("Tokens:     %d prompt, %d completion\n", report.PromptTokens, report.CompletionTokens)
+		if opts.PriceIn > 0 || opts.PriceOut > 0 {
+			fmt.Printf("Cost:       %.4f\n", report.Cost)
+		}
+		fmt.Printf("Duration:   %s\n", time.Duration(report.DurationMs)*time.Millisecond)
+		fmt.Println()
+		fmt.Println("Confusion (label → predicted):")
+		fmt.Printf("  %-8s %8s %8s\n", "", "accept", "ignore")
+		for _, label := range []string{secops.ActionAccept, secops.ActionIgnore} {
+			row := report.Confusion[label]
+			fmt.Printf("  %-8s %8d %8d\n", label, row[secops.ActionAccept], row[secops.ActionIgnore])
+		}
+		fmt.Println()
+		for _, class := range []string{secops.ActionAccept, secops.ActionIgnore} {
+			c := report.Classes[class]
+			fmt.Printf("  %-8s precision %.2f  recall %.2f  support %d\n", class, c.Precision, c.Recall, c.Support)
+		}
+		for i, fail := range report.Failures {
+			if i == 0 {
+				fmt.Println()
+				fmt.Println("Failures:")
+			}
+			if i == 20 {
+				fmt.Printf("  ... and %d more\n", len(report.Failures)-20)
+				break
+			}
+			if fail.Error != "" {
+				fmt.Printf("  %s: error: %s\n", fail.ID, fail.Error)
+			} else {
+				fmt.Printf("  %s: label %s, predicted %s: %s\n", fail.ID, fail.Label, fail.Predicted, fail.Reason)
+			}
+		}
+	}
+
+	if minAccuracy > 0 && report.Accuracy < minAccuracy {
+		fmt.Fprintf(os.Stderr, "Accuracy %.3f is below the required %.3f\n", report.Accuracy, minAccuracy)
+		os.Exit(1)
+	}
+}
+
+func secopsPlaybookCmd(args []string) {
+	if len(args) == 0 {
+		secopsHelp()
+		return
+	}
+
+	switch args[0] {
+	case "keygen":
+		pub, priv, err := secops.GeneratePlaybookKey()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Public key (add to secops.playbooks.trusted_keys): %s\n", pub)
+		fmt.Printf("Private key (keep secret):                         %s\n", priv)
+		return
+	case "sign":
+		secopsPlaybookSignCmd(args[1:])
+		return
+	case "install", "sync", "list":
+	default:
+		fmt.Printf("Unknown playbook command: %s\n", args[0])
+		secopsHelp()
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	workspace := cfg.WorkspacePath()
+	ctx := context.Background()
+
+	switch args[0] {
+	case "install":
+		var opts secops.PlaybookImportOptions
+		var source string
+		for i := 1; i < len(args); i++ {
+			switch args[i] {
+			case "--name":
+				if i+1 < len(args) {
+					opts.Name = args[i+1]
+					i++
+				}
+			case "--sha256":
+				if i+1 < len(args) {
+					opts.SHA256 = args[i+1]
+					i++
+				}
+			case "--signature":
+				if i+1 < len(args) {
+					opts.Signature = args[i+1]
+					i++
+				}
+			case "--update":
+				opts.Update = true
+			default:
+				if strings.HasPrefix(args[i], "-") || source != "" {
+					fmt.Printf("Unknown argument: %s\n", args[i])
+					secopsHelp()
+					os.Exit(1)
+				}
+				source = args[i]
+			}
+		}
+		if source == "" {
+			secopsHelp()
+			os.Exit(1)
+		}
+		bundle, err := secops.ImportPlaybookBundle(ctx, workspace, source, cfg.SecOps.Playbooks, opts)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Installed %s (%s)\n", bundle.Name, bundle.Digest)
+		if bundle.SignedBy != "" {
+			fmt.Printf("  Signed by key %s\n", bundle.SignedBy)
+		} else {
+			fmt.Println("  Not signed")
+		}
+		for _, file := range bundle.Files {
+			fmt.Printf("  playbooks/%s/%s\n", bundle.Name, file)
+		}
+	case "sync":
+		bundles, err := secops.SyncPlaybookBundles(ctx, workspace, cfg.SecOps.Playbooks, nil)
+		for _, b := range bundles {
+			fmt.Printf("✓ %s (%s)\n", b.Name, b.Digest)
+		}
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "list":
+		bundles, err := secops.InstalledPlaybookBundles(workspace)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(bundles) == 0 {
+			fmt.Println("No playbook bundles installed.")
+			return
+		}
+		for _, b := range bundles {
+			signed := "unsigned"
+			if b.SignedBy != "" {
+				signed = "signed by " + b.SignedBy
+			}
+			fmt.Printf("%-20s %s  %s\n", b.Name, b.Digest, signed)
+			fmt.Printf("  %s (installed %s)\n", b.Source, b.InstalledAt.Format("2006-01-02 15:04"))
+		}
+	}
+}
+
+func secopsPlaybookSignCmd(args []string) {
+	var file, keyFile string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--key":
+			if i+1 < len(args) {
+				keyFile = args[i+1]
+				i++
+			}
+		default:
+			if strings.HasPrefix(args[i], "-") || file != "" {
+				fmt.Printf("Unknown argument: %s\n", args[i])
+				secopsHelp()
+				os.Exit(1)
+			}
+			file = args[i]
+		}
+	}
+	if file == "" || keyFile == "" {
+		secopsHelp()
+		os.Exit(1)
+	}
+
+	key, err := os.ReadFile(keyFile)
+	if err != nil {
+		fmt.Printf("Error reading key: %v\n", err)
+		os.Exit(1)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	sig, err := secops.SignPlaybookBundle(data, string(key))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(file+".sig", []byte(sig+"\n"), 0644); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Wrote %s.sig\n", file)
+}
+
+func backupCmd() {
+	output := fmt.Sprintf("picoclaw-backup-%s.tar.gz", time.Now().Format("20060102-150405"))
+
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-o", "--output":
+			if i+1 < len(args) {
+				output = args[i+1]
+				i++
+			}
+		case "--help", "-h":
+			backupHelp()
+			return
+		default:
+			fmt.Printf("Unknown flag: %s\n", args[i])
+			backupHelp()
+			os.Exit(1)
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		fmt.Printf("Error creating backup file: %v\n", err)
+		os.Exit(1)
+	}
+
+	manifest, err := backup.Create(f, backup.Options{
+		Workspace:  cfg.WorkspacePath(),
+		ConfigPath: getConfigPath(),
+		AppVersion: version,
+	})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(output)
+		fmt.Printf("Error creating backup: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("✓ Backed up %d files to %s\n", len(manifest.Files), output)
+}
+
+func backupHelp() {
+	fmt.Println("\nBack up picoclaw state")
+	fmt.Println()
+	fmt.Println("Usage: picoclaw backup [options]")
+	fmt.Println()
+	fmt.Println("Includes the config file and the workspace directories:")
+	fmt.Printf("  %s\n", strings.Join(backup.StateDirs, ", "))
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  -o, --output <file>   Archive path (default: picoclaw-backup-<timestamp>.tar.gz)")
+}
+
+func restoreCmd() {
+	opts := backup.RestoreOptions{}
+	var archive string
+
+	args := os.Args[2:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--force":
+			opts.Force = true
+		case "--skip-config":
+			opts.SkipConfig = true
+		case "--dry-run":
+			opts.DryRun = true
+		case "--help", "-h":
+			restoreHelp()
+			return
+		default:
+			if strings.HasPrefix(args[i], "-") || archive != "" {
+				fmt.Printf("Unknown argument: %s\n", args[i])
+				restoreHelp()
+				os.Exit(1)
+			}
+			archive = args[i]
+		}
+	}
+	if archive == "" {
+		restoreHelp()
+		os.Exit(1)
+	}
+
+	opts.ConfigPath = getConfigPath()
+	opts.Workspace = config.DefaultConfig().WorkspacePath()
+	if cfg, err := loadConfig(); err == nil {
+		opts.Workspace = cfg.WorkspacePath()
+	}
+
+	manifest, files, err := backup.Restore(archive, opts)
+	if err != nil {
+		for _, f := range files {
+			fmt.Printf("  exists: %s\n", f)
+		}
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if opts.DryRun {
+		for _, f := range files {
+			fmt.Printf("  would restore: %s\n", f)
+		}
+		return
+	}
+	fmt.Printf("✓ Restored %d files from backup created %s\n", len(files), manifest.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+	fmt.Println("  Restart the gateway to load the restored state.")
+}
+
+func restoreHelp() {
+	fmt.Println("\nRestore picoclaw state from a backup archive")
+	fmt.Println()
+	fmt.Println("Usage: picoclaw restore <archive> [options]")
+	fmt.Println()
+	fmt.Println("Stop the gateway before restoring.")
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  --dry-run       List files that would be restored")
+	fmt.Println("  --skip-config   Keep the current config file")
+	fmt.Println("  --force         Overwrite existing files")
 }
 
 func agentCmd() {
@@ -655,7 +1431,7 @@ func gatewayCmd() {
 	fmt.Println("✓ Heartbeat service started")
 
 	// 启动 SecOps 安全运营服务
-	secopsService, secopsErr = secops.NewService(&cfg.SecOps, agentLoop, msgBus)
+	secopsService, secopsErr = secops.NewService(&cfg.SecOps, agentLoop, msgBus, cfg.WorkspacePath())
 	if secopsErr != nil {
 		fmt.Printf("Error creating secops service: %v\n", secopsErr)
 	} else if secopsService != nil {
@@ -677,18 +1453,29 @@ func gatewayCmd() {
 		}()
 
 		debugUIServer = debugui.NewServer(
-			fmt.Sprintf("%s:%d", cfg.SecOps.DebugUI.Host, cfg.SecOps.DebugUI.Port),
+			cfg.SecOps.DebugUI,
 			agentLoop,
 			proposalService,
 			secopsService,
 			cfg.WorkspacePath(),
 		)
+		debugUIServer.SetBuildInfo(debugui.BuildInfo{
+			Version:   version,
+			GitCommit: gitCommit,
+			BuildTime: buildTime,
+			GoVersion: goVersion,
+		})
+		debugUIServer.SetConfigLoader(loadSecOpsConfig)
 		go func() {
 			if err := debugUIServer.Start(); err != nil {
 				logger.ErrorCF("debugui", "Debug UI server error", map[string]interface{}{"error": err.Error()})
 			}
 		}()
-		fmt.Printf("✓ Debug UI available at http://localhost:%d\n", cfg.SecOps.DebugUI.Port)
+		scheme := "http"
+		if cfg.SecOps.DebugUI.TLS.CertFile != "" {
+			scheme = "https"
+		}
+		fmt.Printf("✓ Debug UI available at %s://localhost:%d%s/\n", scheme, cfg.SecOps.DebugUI.Port, debugUIServer.BasePath())
 	}
 
 	stateManager := state.NewManager(cfg.WorkspacePath())
@@ -717,6 +1504,23 @@ func gatewayCmd() {
 
 	go agentLoop.Run(ctx)
 
+	// SIGHUP 重新读取配置文件并热加载安全运营配置
+	if secopsService != nil {
+		hupChan := make(chan os.Signal, 1)
+		signal.Notify(hupChan, syscall.SIGHUP)
+		go func() {
+			for range hupChan {
+				secopsCfg, err := loadSecOpsConfig()
+				if err == nil {
+					_, err = secopsService.Reload(secopsCfg)
+				}
+				if err != nil {
+					logger.ErrorCF("secops", "Config reload failed", map[string]interface{}{"error": err.Error()})
+				}
+			}
+		}()
+	}
+
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt)
 	<-sigChan
@@ -826,6 +1630,10 @@ func authCmd() {
 		authLogoutCmd()
 	case "status":
 		authStatusCmd()
+	case "recovery-codes":
+		authRecoveryCodesCmd()
+	case "passkeys":
+		authPasskeysCmd()
 	default:
 		fmt.Printf("Unknown auth command: %s\n", os.Args[2])
 		authHelp()
@@ -837,6 +1645,8 @@ func authHelp() {
 	fmt.Println("  login       Login via OAuth or paste token")
 	fmt.Println("  logout      Remove stored credentials")
 	fmt.Println("  status      Show current auth status")
+	fmt.Println("  recovery-codes  Generate new Debug UI recovery codes (invalidates old ones)")
+	fmt.Println("  passkeys    List Debug UI passkeys (remove <id> to delete one)")
 	fmt.Println()
 	fmt.Println("Login options:")
 	fmt.Println("  --provider <name>    Provider to login with (openai, anthropic)")
@@ -848,6 +1658,8 @@ func authHelp() {
 	fmt.Println("  picoclaw auth login --provider anthropic")
 	fmt.Println("  picoclaw auth logout --provider openai")
 	fmt.Println("  picoclaw auth status")
+	fmt.Println("  picoclaw auth recovery-codes")
+	fmt.Println("  picoclaw auth passkeys remove <id>")
 }
 
 func authLoginCmd() {
@@ -1032,6 +1844,78 @@ func authStatusCmd() {
 	}
 }
 
+func passkeyStorePath() string {
+	if cfg, err := loadConfig(); err == nil && cfg.SecOps.DebugUI.Auth.StorePath != "" {
+		return cfg.SecOps.DebugUI.Auth.StorePath
+	}
+	return auth.PasskeyFilePath()
+}
+
+func authRecoveryCodesCmd() {
+	path := passkeyStorePath()
+	store, err := auth.LoadPasskeyStore(path)
+	if err != nil {
+		fmt.Printf("Error loading passkey store: %v\n", err)
+		os.Exit(1)
+	}
+	codes, err := store.GenerateRecoveryCodes()
+	if err != nil {
+		fmt.Printf("Error generating recovery codes: %v\n", err)
+		os.Exit(1)
+	}
+	if err := auth.SavePasskeyStore(path, store); err != nil {
+		fmt.Printf("Error saving passkey store: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("Debug UI recovery codes (each works once, previous codes are revoked):")
+	fmt.Println()
+	for _, code := range codes {
+		fmt.Printf("  %s\n", code)
+	}
+	fmt.Println()
+	fmt.Println("Store them somewhere safe. They will not be shown again.")
+}
+
+func authPasskeysCmd() {
+	path := passkeyStorePath()
+	store, err := auth.LoadPasskeyStore(path)
+	if err != nil {
+		fmt.Printf("Error loading passkey store: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(os.Args) >= 5 && os.Args[3] == "remove" {
+		id := os.Args[4]
+		for i, c := range store.Credentials {
+			if c.ID == id {
+				store.Credentials = append(store.Credentials[:i], store.Credentials[i+1:]...)
+				if err := auth.SavePasskeyStore(path, store); err != nil {
+					fmt.Printf("Error saving passkey store: %v\n", err)
+					os.Exit(1)
+				}
+				fmt.Printf("Removed passkey %s\n", c.Name)
+				return
+			}
+		}
+		fmt.Printf("Passkey %s not found\n", id)
+		os.Exit(1)
+	}
+
+	if len(store.Credentials) == 0 {
+		fmt.Println("No passkeys registered.")
+		fmt.Println("Run: picoclaw auth recovery-codes, then sign in to the Debug UI with a code to register one.")
+	}
+	for _, c := range store.Credentials {
+		lastUsed := "never"
+		if !c.LastUsedAt.IsZero() {
+			lastUsed = c.LastUsedAt.Format("2006-01-02 15:04")
+		}
+		fmt.Printf("  %s  %s (created %s, last used %s)\n", c.ID, c.Name, c.CreatedAt.Format("2006-01-02"), lastUsed)
+	}
+	fmt.Printf("Recovery codes remaining: %d\n", len(store.RecoveryCodes))
+}
+
 func getConfigPath() string {
 	home, _ := os.UserHomeDir()
 	return filepath.Join(home, ".picoclaw", "config.json")
@@ -1060,6 +1944,15 @@ func loadConfig() (*config.Config, error) {
 	return config.LoadConfig(getConfigPath())
 }
 
+// loadSecOpsConfig 重新读取配置文件中的安全运营配置, 用于热加载
+func loadSecOpsConfig() (*config.SecOpsConfig, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &cfg.SecOps, nil
+}
+
 func cronCmd() {
 	if len(os.Args) < 3 {
 		cronHelp()
@@ -118,6 +118,13 @@ func (cb *ContextBuilder) BuildSystemPrompt() string {
 		parts = append(parts, bootstrapContent)
 	}
 
+	// SecOps context - business context maintained by analysts, re-read on every
+	// request so edits take effect without a restart
+	secopsContext := cb.LoadSecOpsContext()
+	if secopsContext != "" {
+		parts = append(parts, "# SecOps Context\n\n"+secopsContext)
+	}
+
 	// Skills - show summary, AI can read full content with read_file tool
 	skillsSummary := cb.skillsLoader.BuildSkillsSummary()
 	if skillsSummary != "" {
@@ -157,6 +164,16 @@ func (cb *ContextBuilder) LoadBootstrapFiles() string {
 	return result
 }
 
+// LoadSecOpsContext reads workspace/secops/context.md (business context, naming
+// conventions, known internal scanners, VIP systems). Returns "" if missing.
+func (cb *ContextBuilder) LoadSecOpsContext() string {
+	data, err := os.ReadFile(filepath.Join(cb.workspace, "secops", "context.md"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
 func (cb *ContextBuilder) BuildMessages(history []providers.Message, summary string, currentMessage string, media []string, channel, chatID string) []providers.Message {
 	messages := []providers.Message{}
 
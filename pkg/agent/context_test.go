@@ -0,0 +1,36 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildSystemPrompt_SecOpsContextHotReload(t *testing.T) {
+	workspace := t.TempDir()
+	cb := NewContextBuilder(workspace)
+
+	if strings.Contains(cb.BuildSystemPrompt(), "# SecOps Context") {
+		t.Fatal("SecOps context section present without context.md")
+	}
+
+	path := filepath.Join(workspace, "secops", "context.md")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("10.0.8.0/24 is the internal scanner range\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if prompt := cb.BuildSystemPrompt(); !strings.Contains(prompt, "# SecOps Context\n\n10.0.8.0/24 is the internal scanner range") {
+		t.Errorf("context.md not injected:\n%s", prompt)
+	}
+
+	if err := os.WriteFile(path, []byte("pay.example.com is a VIP system"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	prompt := cb.BuildSystemPrompt()
+	if !strings.Contains(prompt, "pay.example.com is a VIP system") || strings.Contains(prompt, "scanner range") {
+		t.Errorf("edited context.md not picked up:\n%s", prompt)
+	}
+}
@@ -0,0 +1,46 @@
+package agent
+
+import "context"
+
+// EventType identifies a progress event emitted while a message is processed.
+type EventType string
+
+const (
+	// EventToken carries a fragment of assistant content as it is generated.
+	EventToken EventType = "token"
+	// EventToolCall is emitted before a tool is executed.
+	EventToolCall EventType = "tool_call"
+	// EventToolResult is emitted after a tool returns.
+	EventToolResult EventType = "tool_result"
+)
+
+// Event is a progress update for callers that display the agent's work live.
+type Event struct {
+	Type       EventType `json:"type"`
+	Iteration  int       `json:"iteration"`
+	Content    string    `json:"content,omitempty"`
+	Tool       string    `json:"tool,omitempty"`
+	Args       string    `json:"args,omitempty"`
+	Result     string    `json:"result,omitempty"`
+	IsError    bool      `json:"isError,omitempty"`
+	DurationMs int64     `json:"durationMs,omitempty"` // tool execution time, set on tool results
+}
+
+// EventHandler receives progress events. It is called synchronously from the
+// agent loop and must not block.
+type EventHandler func(Event)
+
+type eventHandlerKey struct{}
+
+// WithEventHandler returns a context under which the agent loop reports
+// token, tool call and tool result events to handler. Providers that
+// implement providers.StreamingProvider are streamed when a handler is set.
+func WithEventHandler(ctx context.Context, handler EventHandler) context.Context {
+	return context.WithValue(ctx, eventHandlerKey{}, handler)
+}
+
+// EventHandlerFromContext returns the handler set by WithEventHandler, or nil.
+func EventHandlerFromContext(ctx context.Context) EventHandler {
+	handler, _ := ctx.Value(eventHandlerKey{}).(EventHandler)
+	return handler
+}
@@ -44,8 +44,14 @@ type AgentLoop struct {
 	running        atomic.Bool
 	summarizing    sync.Map // Tracks which sessions are currently being summarized
 	channelManager *channels.Manager
+	commands       sync.Map // Slash commands registered by extensions, name -> CommandHandler
+	traces         *TraceStore
 }
 
+// CommandHandler handles a slash command registered with RegisterCommand.
+// args are the whitespace-separated arguments following the command name.
+type CommandHandler func(ctx context.Context, msg bus.InboundMessage, args []string) string
+
 // processOptions configures how a message is processed
 type processOptions struct {
 	SessionKey      string // Session identifier for history/context
@@ -56,6 +62,7 @@ type processOptions struct {
 	EnableSummary   bool   // Whether to trigger summarization
 	SendResponse    bool   // Whether to send response via bus
 	NoHistory       bool   // If true, don't load session history (for heartbeat)
+	trace           *Trace // Tool call trace of this request, set by runAgentLoop
 }
 
 // createToolRegistry creates a tool registry with common tools.
@@ -151,6 +158,7 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 		contextBuilder: contextBuilder,
 		tools:          toolsRegistry,
 		summarizing:    sync.Map{},
+		traces:         newTraceStore(),
 	}
 }
 
@@ -204,6 +212,18 @@ func (al *AgentLoop) RegisterTool(tool tools.Tool) {
 	al.tools.Register(tool)
 }
 
+// RegisterCommand registers a slash command such as "/timeline" that is answered
+// directly instead of being sent to the LLM. Built-in commands take precedence.
+func (al *AgentLoop) RegisterCommand(name string, handler CommandHandler) {
+	al.commands.Store(name, handler)
+}
+
+// WrapProvider replaces the LLM provider with wrap(provider), e.g. to inject
+// faults. It must be called before Run; subagents keep the original provider.
+func (al *AgentLoop) WrapProvider(wrap func(providers.LLMProvider) providers.LLMProvider) {
+	al.provider = wrap(al.provider)
+}
+
 func (al *AgentLoop) SetChannelManager(cm *channels.Manager) {
 	al.channelManager = cm
 }
@@ -251,6 +271,33 @@ func (al *AgentLoop) ProcessHeartbeat(ctx context.Context, content, channel, cha
 	})
 }
 
+// Complete sends a single stateless prompt to the LLM, without tools or
+// session history. Useful for auxiliary tasks such as translation.
+func (al *AgentLoop) Complete(ctx context.Context, prompt string) (string, error) {
+	response, err := al.provider.Chat(ctx, []providers.Message{{Role: "user", Content: prompt}}, nil, al.model, map[string]interface{}{
+		"max_tokens":  4096,
+		"temperature": 0.3,
+	})
+	if err != nil {
+		return "", err
+	}
+	return response.Content, nil
+}
+
+// CompleteWithSystemPrompt sends a single stateless request with the agent's
+// current system prompt (identity, bootstrap files, skills) but without tools,
+// returning the raw response including token usage. Used for offline evaluation.
+func (al *AgentLoop) CompleteWithSystemPrompt(ctx context.Context, prompt string) (*providers.LLMResponse, error) {
+	messages := []providers.Message{
+		{Role: "system", Content: al.contextBuilder.BuildSystemPrompt()},
+		{Role: "user", Content: prompt},
+	}
+	return al.provider.Chat(ctx, messages, nil, al.model, map[string]interface{}{
+		"max_tokens":  4096,
+		"temperature": 0.3,
+	})
+}
+
 func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage) (string, error) {
 	// Add message preview to log (show full content for error messages)
 	var logContent string
@@ -378,7 +425,9 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, opts processOptions) (str
 	al.sessions.AddMessage(opts.SessionKey, "user", opts.UserMessage)
 
 	// 4. Run LLM iteration loop
+	opts.trace = al.traces.start(ctx, opts)
 	finalContent, iteration, err := al.runLLMIteration(ctx, messages, opts)
+	al.traces.finish(opts.trace, err)
 	if err != nil {
 		return "", err
 	}
@@ -426,6 +475,7 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, opts processOptions) (str
 func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.Message, opts processOptions) (string, int, error) {
 	iteration := 0
 	var finalContent string
+	onEvent := EventHandlerFromContext(ctx)
 
 	for iteration < al.maxIterations {
 		iteration++
@@ -465,10 +515,7 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 		// Retry loop for context/token errors
 		maxRetries := 2
 		for retry := 0; retry <= maxRetries; retry++ {
-			response, err = al.provider.Chat(ctx, messages, providerToolDefs, al.model, map[string]interface{}{
-				"max_tokens":  8192,
-				"temperature": 0.7,
-			})
+			response, err = al.chat(ctx, messages, providerToolDefs, iteration, onEvent)
 
 			if err == nil {
 				break // Success
@@ -665,7 +712,13 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 				}
 			}
 
+			if onEvent != nil {
+				onEvent(Event{Type: EventToolCall, Iteration: iteration, Tool: tc.Name, Args: argsPreview})
+			}
+
+			started := time.Now()
 			toolResult := al.tools.ExecuteWithContext(ctx, tc.Name, tc.Arguments, opts.Channel, opts.ChatID, asyncCallback)
+			duration := time.Since(started)
 
 			// Send ForUser content to user immediately if not Silent
 			if !toolResult.Silent && toolResult.ForUser != "" && opts.SendResponse {
@@ -687,6 +740,29 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 				contentForLLM = toolResult.Err.Error()
 			}
 
+			if opts.trace != nil {
+				al.traces.addCall(opts.trace, ToolCallTrace{
+					Tool:       tc.Name,
+					Args:       string(argsJSON),
+					Result:     contentForLLM,
+					IsError:    toolResult.IsError,
+					Iteration:  iteration,
+					StartedAt:  started,
+					DurationMs: duration.Milliseconds(),
+				})
+			}
+
+			if onEvent != nil {
+				onEvent(Event{
+					Type:       EventToolResult,
+					Iteration:  iteration,
+					Tool:       tc.Name,
+					Result:     utils.Truncate(contentForLLM, 500),
+					IsError:    toolResult.IsError,
+					DurationMs: duration.Milliseconds(),
+				})
+			}
+
 			toolResultMsg := providers.Message{
 				Role:       "tool",
 				Content:    contentForLLM,
@@ -702,6 +778,31 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 	return finalContent, iteration, nil
 }
 
+// chat calls the provider, streaming content to onEvent when both the
+// provider and the caller support it.
+func (al *AgentLoop) chat(ctx context.Context, messages []providers.Message, toolDefs []providers.ToolDefinition, iteration int, onEvent EventHandler) (*providers.LLMResponse, error) {
+	options := map[string]interface{}{
+		"max_tokens":  8192,
+		"temperature": 0.7,
+	}
+	if onEvent == nil {
+		return al.provider.Chat(ctx, messages, toolDefs, al.model, options)
+	}
+
+	if sp, ok := al.provider.(providers.StreamingProvider); ok {
+		return sp.ChatStream(ctx, messages, toolDefs, al.model, options, func(delta string) {
+			onEvent(Event{Type: EventToken, Iteration: iteration, Content: delta})
+		})
+	}
+
+	// Non-streaming providers report the whole reply as a single token event.
+	response, err := al.provider.Chat(ctx, messages, toolDefs, al.model, options)
+	if err == nil && response.Content != "" {
+		onEvent(Event{Type: EventToken, Iteration: iteration, Content: response.Content})
+	}
+	return response, err
+}
+
 // updateToolContexts updates the context for tools that need channel/chatID info.
 func (al *AgentLoop) updateToolContexts(channel, chatID string) {
 	// Use ContextualTool interface instead of type assertions
@@ -806,6 +907,16 @@ func (al *AgentLoop) forceCompression(sessionKey string) {
 	})
 }
 
+// Traces returns the tool call traces of recent requests.
+func (al *AgentLoop) Traces() *TraceStore {
+	return al.traces
+}
+
+// Sessions returns the conversation history store shared by all channels.
+func (al *AgentLoop) Sessions() *session.SessionManager {
+	return al.sessions
+}
+
 // GetStartupInfo returns information about loaded tools and skills for logging.
 func (al *AgentLoop) GetStartupInfo() map[string]interface{} {
 	info := make(map[string]interface{})
@@ -1062,5 +1173,9 @@ func (al *AgentLoop) handleCommand(ctx context.Context, msg bus.InboundMessage)
 		}
 	}
 
+	if handler, ok := al.commands.Load(cmd); ok {
+		return handler.(CommandHandler)(ctx, msg, args), true
+	}
+
 	return "", false
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -357,6 +358,39 @@ func TestAgentLoop_Stop(t *testing.T) {
 	}
 }
 
+// TestAgentLoop_RegisterCommand verifies registered slash commands bypass the LLM
+func TestAgentLoop_RegisterCommand(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "agent-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         tmpDir,
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &mockProvider{})
+	al.RegisterCommand("/echo", func(ctx context.Context, msg bus.InboundMessage, args []string) string {
+		return fmt.Sprintf("%s: %v", msg.Channel, args)
+	})
+
+	response, handled := al.handleCommand(context.Background(), bus.InboundMessage{Channel: "telegram", Content: "/echo a  b"})
+	if !handled || response != "telegram: [a b]" {
+		t.Errorf("registered command: handled=%v response=%q", handled, response)
+	}
+	if _, handled := al.handleCommand(context.Background(), bus.InboundMessage{Content: "/unknown"}); handled {
+		t.Error("Expected unknown command to fall through to the LLM")
+	}
+}
+
 // Mock implementations for testing
 
 type simpleMockProvider struct {
@@ -624,3 +658,149 @@ func TestAgentLoop_ContextExhaustionRetry(t *testing.T) {
 		t.Errorf("Expected history to be compressed (len < 8), got %d", len(finalHistory))
 	}
 }
+
+// streamingMockProvider requests mock_custom once, then streams its answer
+type streamingMockProvider struct {
+	calls int
+}
+
+func (m *streamingMockProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	return nil, fmt.Errorf("Chat should not be used when streaming")
+}
+
+func (m *streamingMockProvider) ChatStream(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}, onDelta func(string)) (*providers.LLMResponse, error) {
+	m.calls++
+	if m.calls == 1 {
+		onDelta("Checking")
+		return &providers.LLMResponse{
+			Content:   "Checking",
+			ToolCalls: []providers.ToolCall{{ID: "call_1", Name: "mock_custom", Arguments: map[string]interface{}{"q": "x"}}},
+		}, nil
+	}
+	onDelta("Hello")
+	onDelta(" world")
+	return &providers.LLMResponse{Content: "Hello world"}, nil
+}
+
+func (m *streamingMockProvider) GetDefaultModel() string {
+	return "mock-stream-model"
+}
+
+// TestAgentLoop_StreamsEvents verifies tokens and tool progress reach the event handler
+func TestAgentLoop_StreamsEvents(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &streamingMockProvider{})
+	al.RegisterTool(&mockCustomTool{})
+
+	var events []Event
+	ctx := WithEventHandler(context.Background(), func(e Event) {
+		events = append(events, e)
+	})
+	response, err := al.ProcessDirect(ctx, "hi", "stream-session")
+	if err != nil {
+		t.Fatalf("ProcessDirect: %v", err)
+	}
+	if response != "Hello world" {
+		t.Errorf("response = %q, want Hello world", response)
+	}
+
+	want := []EventType{EventToken, EventToolCall, EventToolResult, EventToken, EventToken}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events %+v, want %d", len(events), events, len(want))
+	}
+	for i, e := range events {
+		if e.Type != want[i] {
+			t.Errorf("event %d = %s, want %s", i, e.Type, want[i])
+		}
+	}
+	if events[1].Tool != "mock_custom" || events[1].Args != `{"q":"x"}` {
+		t.Errorf("tool_call = %+v", events[1])
+	}
+	if events[2].Result != "Custom tool executed" || events[2].IsError {
+		t.Errorf("tool_result = %+v", events[2])
+	}
+	if events[4].Iteration != 2 {
+		t.Errorf("final token iteration = %d, want 2", events[4].Iteration)
+	}
+}
+
+// toolCallMockProvider requests mock_custom once, then answers
+type toolCallMockProvider struct {
+	calls int
+}
+
+func (m *toolCallMockProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	m.calls++
+	if m.calls == 1 {
+		return &providers.LLMResponse{
+			ToolCalls: []providers.ToolCall{{ID: "call_1", Name: "mock_custom", Arguments: map[string]interface{}{"q": "x"}}},
+		}, nil
+	}
+	return &providers.LLMResponse{Content: "done"}, nil
+}
+
+func (m *toolCallMockProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+// TestAgentLoop_RecordsTrace verifies tool calls are recorded under the caller's trace ID
+func TestAgentLoop_RecordsTrace(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &toolCallMockProvider{})
+	al.RegisterTool(&mockCustomTool{})
+
+	if _, err := al.ProcessDirect(WithTraceID(context.Background(), "trace-1"), "hi", "trace-session"); err != nil {
+		t.Fatalf("ProcessDirect: %v", err)
+	}
+
+	trace, ok := al.Traces().Get("trace-1")
+	if !ok {
+		t.Fatal("trace not recorded")
+	}
+	if !trace.Done || trace.SessionKey != "trace-session" || trace.Message != "hi" || len(trace.Calls) != 1 {
+		t.Fatalf("trace = %+v", trace)
+	}
+	if c := trace.Calls[0]; c.Tool != "mock_custom" || c.Args != `{"q":"x"}` || c.Result != "Custom tool executed" || c.Iteration != 1 {
+		t.Errorf("call = %+v", c)
+	}
+	if list := al.Traces().List("other-session"); len(list) != 0 {
+		t.Errorf("List(other-session) = %+v, want none", list)
+	}
+}
+
+func TestTraceStore_Eviction(t *testing.T) {
+	ts := newTraceStore()
+	for i := 0; i < traceCapacity+5; i++ {
+		tr := ts.start(WithTraceID(context.Background(), fmt.Sprintf("t%d", i)), processOptions{SessionKey: "s"})
+		ts.addCall(tr, ToolCallTrace{Tool: "exec", Result: strings.Repeat("x", maxTraceField+100)})
+		ts.finish(tr, nil)
+	}
+	list := ts.List("")
+	if len(list) != traceCapacity || list[0].ID != fmt.Sprintf("t%d", traceCapacity+4) {
+		t.Fatalf("len = %d, newest = %s", len(list), list[0].ID)
+	}
+	if _, ok := ts.Get("t0"); ok {
+		t.Error("oldest trace should be evicted")
+	}
+	if got := list[0].Calls[0].Result; len(got) > maxTraceField+10 {
+		t.Errorf("result not truncated: %d bytes", len(got))
+	}
+}
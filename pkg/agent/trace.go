@@ -0,0 +1,157 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sipeed/picoclaw/pkg/utils"
+)
+
+const (
+	// traceCapacity is the number of request traces kept in memory.
+	traceCapacity = 200
+	// maxTraceField bounds the recorded tool arguments and results.
+	maxTraceField = 2000
+)
+
+// ToolCallTrace records a single tool invocation.
+type ToolCallTrace struct {
+	Tool       string    `json:"tool"`
+	Args       string    `json:"args"`
+	Result     string    `json:"result"`
+	IsError    bool      `json:"isError,omitempty"`
+	Iteration  int       `json:"iteration"`
+	StartedAt  time.Time `json:"startedAt"`
+	DurationMs int64     `json:"durationMs"`
+}
+
+// Trace records the tool calls made while answering one request.
+type Trace struct {
+	ID         string          `json:"id"`
+	SessionKey string          `json:"sessionKey"`
+	Channel    string          `json:"channel"`
+	ChatID     string          `json:"chatId"`
+	Message    string          `json:"message"` // truncated user message
+	StartedAt  time.Time       `json:"startedAt"`
+	DurationMs int64           `json:"durationMs"`
+	Done       bool            `json:"done"`
+	Error      string          `json:"error,omitempty"`
+	Calls      []ToolCallTrace `json:"calls"`
+}
+
+// TraceStore keeps the most recent request traces in memory.
+type TraceStore struct {
+	mu     sync.Mutex
+	traces []*Trace // oldest first
+	byID   map[string]*Trace
+}
+
+func newTraceStore() *TraceStore {
+	return &TraceStore{byID: make(map[string]*Trace)}
+}
+
+type traceIDKey struct{}
+
+// WithTraceID returns a context under which the agent loop records the
+// request's tool calls under id, so callers can look the trace up afterwards.
+// Without it a random id is used.
+func WithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, id)
+}
+
+// start begins a trace for a request, evicting the oldest one when full.
+func (ts *TraceStore) start(ctx context.Context, opts processOptions) *Trace {
+	id, _ := ctx.Value(traceIDKey{}).(string)
+	if id == "" {
+		id = uuid.NewString()
+	}
+	t := &Trace{
+		ID:         id,
+		SessionKey: opts.SessionKey,
+		Channel:    opts.Channel,
+		ChatID:     opts.ChatID,
+		Message:    utils.Truncate(opts.UserMessage, 200),
+		StartedAt:  time.Now(),
+		Calls:      []ToolCallTrace{},
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if old, ok := ts.byID[id]; ok {
+		ts.removeLocked(old)
+	}
+	if len(ts.traces) >= traceCapacity {
+		delete(ts.byID, ts.traces[0].ID)
+		ts.traces = ts.traces[1:]
+	}
+	ts.traces = append(ts.traces, t)
+	ts.byID[id] = t
+	return t
+}
+
+func (ts *TraceStore) removeLocked(t *Trace) {
+	delete(ts.byID, t.ID)
+	for i, cur := range ts.traces {
+		if cur == t {
+			ts.traces = append(ts.traces[:i], ts.traces[i+1:]...)
+			return
+		}
+	}
+}
+
+// addCall appends a finished tool call to the trace.
+func (ts *TraceStore) addCall(t *Trace, call ToolCallTrace) {
+	call.Args = utils.Truncate(call.Args, maxTraceField)
+	call.Result = utils.Truncate(call.Result, maxTraceField)
+	ts.mu.Lock()
+	t.Calls = append(t.Calls, call)
+	ts.mu.Unlock()
+}
+
+// finish marks the trace as complete.
+func (ts *TraceStore) finish(t *Trace, err error) {
+	ts.mu.Lock()
+	t.Done = true
+	t.DurationMs = time.Since(t.StartedAt).Milliseconds()
+	if err != nil {
+		t.Error = err.Error()
+	}
+	ts.mu.Unlock()
+}
+
+// Get returns a copy of the trace with the given id.
+func (ts *TraceStore) Get(id string) (Trace, bool) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	t, ok := ts.byID[id]
+	if !ok {
+		return Trace{}, false
+	}
+	return t.snapshot(), true
+}
+
+// List returns copies of the traces for sessionKey (all sessions when
+// empty), most recent first.
+func (ts *TraceStore) List(sessionKey string) []Trace {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	result := make([]Trace, 0, len(ts.traces))
+	for i := len(ts.traces) - 1; i >= 0; i-- {
+		if t := ts.traces[i]; sessionKey == "" || t.SessionKey == sessionKey {
+			result = append(result, t.snapshot())
+		}
+	}
+	return result
+}
+
+// snapshot copies the trace; the caller must hold the store lock.
+func (t *Trace) snapshot() Trace {
+	c := *t
+	c.Calls = append([]ToolCallTrace(nil), t.Calls...)
+	if !c.Done {
+		c.DurationMs = time.Since(c.StartedAt).Milliseconds()
+	}
+	return c
+}
@@ -0,0 +1,608 @@
+package auth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// COSE algorithm identifiers accepted for passkeys.
+const (
+	AlgES256 = -7
+	AlgEdDSA = -8
+	AlgRS256 = -257
+)
+
+const (
+	flagUserPresent  = 0x01
+	flagAttestedData = 0x40
+
+	challengeTTL      = 5 * time.Minute
+	recoveryCodeCount = 10
+)
+
+var (
+	ErrNoPasskeys          = errors.New("no passkeys registered")
+	ErrUnknownChallenge    = errors.New("unknown or expired challenge")
+	ErrUnknownCredential   = errors.New("unknown credential")
+	ErrInvalidRecoveryCode = errors.New("invalid recovery code")
+)
+
+// PasskeyCredential is a WebAuthn public key credential registered for the UI.
+type PasskeyCredential struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name,omitempty"`
+	PublicKey  []byte    `json:"public_key"`
+	Algorithm  int       `json:"algorithm"`
+	SignCount  uint32    `json:"sign_count"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at,omitempty"`
+}
+
+type recoveryCode struct {
+	Hash      string    `json:"hash"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PasskeyStore holds registered passkeys and hashed recovery codes.
+type PasskeyStore struct {
+	Credentials   []*PasskeyCredential `json:"credentials"`
+	RecoveryCodes []recoveryCode       `json:"recovery_codes"`
+}
+
+func PasskeyFilePath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".picoclaw", "passkeys.json")
+}
+
+func LoadPasskeyStore(path string) (*PasskeyStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &PasskeyStore{}, nil
+		}
+		return nil, err
+	}
+
+	var store PasskeyStore
+	if err := json.Unmarshal(data, &store); err != nil {
+		return nil, err
+	}
+	return &store, nil
+}
+
+func SavePasskeyStore(path string, store *PasskeyStore) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+func (s *PasskeyStore) credential(id string) *PasskeyCredential {
+	for _, c := range s.Credentials {
+		if c.ID == id {
+			return c
+		}
+	}
+	return nil
+}
+
+// GenerateRecoveryCodes replaces all recovery codes and returns the new
+// plaintext codes. Only their hashes are kept in the store.
+func (s *PasskeyStore) GenerateRecoveryCodes() ([]string, error) {
+	now := time.Now()
+	codes := make([]string, 0, recoveryCodeCount)
+	s.RecoveryCodes = make([]recoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 8)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		raw := hex.EncodeToString(buf)
+		codes = append(codes, raw[0:4]+"-"+raw[4:8]+"-"+raw[8:12]+"-"+raw[12:16])
+		s.RecoveryCodes = append(s.RecoveryCodes, recoveryCode{Hash: hashRecoveryCode(raw), CreatedAt: now})
+	}
+	return codes, nil
+}
+
+// UseRecoveryCode consumes a recovery code. Each code works exactly once.
+func (s *PasskeyStore) UseRecoveryCode(code string) error {
+	hash := hashRecoveryCode(code)
+	match := -1
+	for i, rc := range s.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(rc.Hash), []byte(hash)) == 1 {
+			match = i
+		}
+	}
+	if match < 0 {
+		return ErrInvalidRecoveryCode
+	}
+	s.RecoveryCodes = append(s.RecoveryCodes[:match], s.RecoveryCodes[match+1:]...)
+	return nil
+}
+
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(code)
+	normalized = strings.NewReplacer("-", "", " ", "").Replace(normalized)
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// RegistrationResponse is the browser's attestation result. Binary fields are
+// base64url encoded; PublicKey is the SPKI DER returned by
+// AuthenticatorAttestationResponse.getPublicKey().
+type RegistrationResponse struct {
+	ID                string `json:"id"`
+	Name              string `json:"name"`
+	ClientDataJSON    string `json:"clientDataJSON"`
+	AuthenticatorData string `json:"authenticatorData"`
+	PublicKey         string `json:"publicKey"`
+	PublicKeyAlg      int    `json:"publicKeyAlgorithm"`
+}
+
+// AssertionResponse is the browser's authentication result, base64url encoded.
+type AssertionResponse struct {
+	ID                string `json:"id"`
+	ClientDataJSON    string `json:"clientDataJSON"`
+	AuthenticatorData string `json:"authenticatorData"`
+	Signature         string `json:"signature"`
+}
+
+type credentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// PasskeyManager runs WebAuthn registration and login ceremonies against a
+// local PasskeyStore. Only "none" attestation is requested, so the
+// authenticator itself is trusted on first use.
+type PasskeyManager struct {
+	rpID   string
+	origin string
+	path   string
+
+	mu         sync.Mutex
+	challenges map[string]passkeyChallenge
+}
+
+type passkeyChallenge struct {
+	ceremony  string
+	expiresAt time.Time
+}
+
+func NewPasskeyManager(rpID, origin, path string) (*PasskeyManager, error) {
+	u, err := url.Parse(origin)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid origin %q", origin)
+	}
+	if rpID == "" {
+		rpID = u.Hostname()
+	}
+	host := u.Hostname()
+	if host != rpID && !strings.HasSuffix(host, "."+rpID) {
+		return nil, fmt.Errorf("rp_id %q does not match origin %q", rpID, origin)
+	}
+	if path == "" {
+		path = PasskeyFilePath()
+	}
+	return &PasskeyManager{
+		rpID:       rpID,
+		origin:     strings.TrimSuffix(origin, "/"),
+		path:       path,
+		challenges: make(map[string]passkeyChallenge),
+	}, nil
+}
+
+// Status reports how many passkeys and unused recovery codes exist.
+func (m *PasskeyManager) Status() (passkeys int, recoveryCodes int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	store, err := LoadPasskeyStore(m.path)
+	if err != nil {
+		return 0, 0, err
+	}
+	return len(store.Credentials), len(store.RecoveryCodes), nil
+}
+
+func (m *PasskeyManager) Credentials() ([]*PasskeyCredential, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	store, err := LoadPasskeyStore(m.path)
+	if err != nil {
+		return nil, err
+	}
+	return store.Credentials, nil
+}
+
+func (m *PasskeyManager) RemoveCredential(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	store, err := LoadPasskeyStore(m.path)
+	if err != nil {
+		return err
+	}
+	for i, c := range store.Credentials {
+		if c.ID == id {
+			store.Credentials = append(store.Credentials[:i], store.Credentials[i+1:]...)
+			return SavePasskeyStore(m.path, store)
+		}
+	}
+	return ErrUnknownCredential
+}
+
+// BeginRegistration returns PublicKeyCredentialCreationOptions for the browser.
+func (m *PasskeyManager) BeginRegistration() (map[string]interface{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	store, err := LoadPasskeyStore(m.path)
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := m.newChallengeLocked("webauthn.create")
+	if err != nil {
+		return nil, err
+	}
+
+	exclude := make([]credentialDescriptor, 0, len(store.Credentials))
+	for _, c := range store.Credentials {
+		exclude = append(exclude, credentialDescriptor{Type: "public-key", ID: c.ID})
+	}
+	return map[string]interface{}{
+		"challenge": challenge,
+		"rp":        map[string]string{"id": m.rpID, "name": "picoclaw"},
+		"user": map[string]string{
+			"id":          base64.RawURLEncoding.EncodeToString([]byte("picoclaw-admin")),
+			"name":        "admin",
+			"displayName": "admin",
+		},
+		"pubKeyCredParams": []map[string]interface{}{
+			{"type": "public-key", "alg": AlgES256},
+			{"type": "public-key", "alg": AlgEdDSA},
+			{"type": "public-key", "alg": AlgRS256},
+		},
+		"timeout":            challengeTTL.Milliseconds(),
+		"attestation":        "none",
+		"excludeCredentials": exclude,
+		"authenticatorSelection": map[string]string{
+			"residentKey":      "preferred",
+			"userVerification": "preferred",
+		},
+	}, nil
+}
+
+// FinishRegistration verifies the attestation response and stores the new passkey.
+func (m *PasskeyManager) FinishRegistration(resp RegistrationResponse) (*PasskeyCredential, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	clientData, err := decodeB64(resp.ClientDataJSON)
+	if err != nil {
+		return nil, fmt.Errorf("clientDataJSON: %w", err)
+	}
+	if err := m.verifyClientDataLocked(clientData, "webauthn.create"); err != nil {
+		return nil, err
+	}
+	authData, err := decodeB64(resp.AuthenticatorData)
+	if err != nil {
+		return nil, fmt.Errorf("authenticatorData: %w", err)
+	}
+	parsed, err := m.verifyAuthenticatorData(authData)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.flags&flagAttestedData == 0 {
+		return nil, errors.New("authenticator data has no attested credential")
+	}
+	credID := base64.RawURLEncoding.EncodeToString(parsed.credentialID)
+	if credID != strings.TrimRight(resp.ID, "=") {
+		return nil, errors.New("credential id mismatch")
+	}
+	publicKey, err := decodeB64(resp.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("publicKey: %w", err)
+	}
+	if _, err := parsePublicKey(resp.PublicKeyAlg, publicKey); err != nil {
+		return nil, err
+	}
+
+	store, err := LoadPasskeyStore(m.path)
+	if err != nil {
+		return nil, err
+	}
+	if store.credential(credID) != nil {
+		return nil, errors.New("credential already registered")
+	}
+	name := strings.TrimSpace(resp.Name)
+	if name == "" {
+		name = fmt.Sprintf("passkey-%d", len(store.Credentials)+1)
+	}
+	cred := &PasskeyCredential{
+		ID:        credID,
+		Name:      name,
+		PublicKey: publicKey,
+		Algorithm: resp.PublicKeyAlg,
+		SignCount: parsed.signCount,
+		CreatedAt: time.Now(),
+	}
+	store.Credentials = append(store.Credentials, cred)
+	if err := SavePasskeyStore(m.path, store); err != nil {
+		return nil, err
+	}
+	return cred, nil
+}
+
+// BeginLogin returns PublicKeyCredentialRequestOptions for the browser.
+func (m *PasskeyManager) BeginLogin() (map[string]interface{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	store, err := LoadPasskeyStore(m.path)
+	if err != nil {
+		return nil, err
+	}
+	if len(store.Credentials) == 0 {
+		return nil, ErrNoPasskeys
+	}
+	challenge, err := m.newChallengeLocked("webauthn.get")
+	if err != nil {
+		return nil, err
+	}
+
+	allow := make([]credentialDescriptor, 0, len(store.Credentials))
+	for _, c := range store.Credentials {
+		allow = append(allow, credentialDescriptor{Type: "public-key", ID: c.ID})
+	}
+	return map[string]interface{}{
+		"challenge":        challenge,
+		"rpId":             m.rpID,
+		"allowCredentials": allow,
+		"userVerification": "preferred",
+		"timeout":          challengeTTL.Milliseconds(),
+	}, nil
+}
+
+// FinishLogin verifies an assertion against the stored passkey.
+func (m *PasskeyManager) FinishLogin(resp AssertionResponse) (*PasskeyCredential, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	clientData, err := decodeB64(resp.ClientDataJSON)
+	if err != nil {
+		return nil, fmt.Errorf("clientDataJSON: %w", err)
+	}
+	if err := m.verifyClientDataLocked(clientData, "webauthn.get"); err != nil {
+		return nil, err
+	}
+	authData, err := decodeB64(resp.AuthenticatorData)
+	if err != nil {
+		return nil, fmt.Errorf("authenticatorData: %w", err)
+	}
+	parsed, err := m.verifyAuthenticatorData(authData)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := decodeB64(resp.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("signature: %w", err)
+	}
+
+	store, err := LoadPasskeyStore(m.path)
+	if err != nil {
+		return nil, err
+	}
+	cred := store.credential(strings.TrimRight(resp.ID, "="))
+	if cred == nil {
+		return nil, ErrUnknownCredential
+	}
+
+	clientHash := sha256.Sum256(clientData)
+	signed := append(append([]byte{}, authData...), clientHash[:]...)
+	if err := verifySignature(cred.Algorithm, cred.PublicKey, signed, signature); err != nil {
+		return nil, err
+	}
+	// A counter that does not move forward indicates a cloned authenticator.
+	// Authenticators that don't implement counters always report zero.
+	if (parsed.signCount != 0 || cred.SignCount != 0) && parsed.signCount <= cred.SignCount {
+		return nil, errors.New("signature counter did not increase")
+	}
+
+	cred.SignCount = parsed.signCount
+	cred.LastUsedAt = time.Now()
+	if err := SavePasskeyStore(m.path, store); err != nil {
+		return nil, err
+	}
+	return cred, nil
+}
+
+// UseRecoveryCode consumes a recovery code for a passkey-less login.
+func (m *PasskeyManager) UseRecoveryCode(code string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	store, err := LoadPasskeyStore(m.path)
+	if err != nil {
+		return err
+	}
+	if err := store.UseRecoveryCode(code); err != nil {
+		return err
+	}
+	return SavePasskeyStore(m.path, store)
+}
+
+// GenerateRecoveryCodes replaces the stored recovery codes.
+func (m *PasskeyManager) GenerateRecoveryCodes() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	store, err := LoadPasskeyStore(m.path)
+	if err != nil {
+		return nil, err
+	}
+	codes, err := store.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := SavePasskeyStore(m.path, store); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (m *PasskeyManager) newChallengeLocked(ceremony string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	now := time.Now()
+	for k, c := range m.challenges {
+		if now.After(c.expiresAt) {
+			delete(m.challenges, k)
+		}
+	}
+	challenge := base64.RawURLEncoding.EncodeToString(buf)
+	m.challenges[challenge] = passkeyChallenge{ceremony: ceremony, expiresAt: now.Add(challengeTTL)}
+	return challenge, nil
+}
+
+func (m *PasskeyManager) verifyClientDataLocked(raw []byte, ceremony string) error {
+	var cd struct {
+		Type      string `json:"type"`
+		Challenge string `json:"challenge"`
+		Origin    string `json:"origin"`
+	}
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return fmt.Errorf("clientDataJSON: %w", err)
+	}
+	if cd.Type != ceremony {
+		return fmt.Errorf("unexpected client data type %q", cd.Type)
+	}
+
+	challenge := strings.TrimRight(cd.Challenge, "=")
+	c, ok := m.challenges[challenge]
+	// Challenges are single use, even when verification fails later on.
+	delete(m.challenges, challenge)
+	if !ok || c.ceremony != ceremony || time.Now().After(c.expiresAt) {
+		return ErrUnknownChallenge
+	}
+	if cd.Origin != m.origin {
+		return fmt.Errorf("unexpected origin %q", cd.Origin)
+	}
+	return nil
+}
+
+type authenticatorData struct {
+	rpIDHash     []byte
+	flags        byte
+	signCount    uint32
+	credentialID []byte
+}
+
+func parseAuthenticatorData(b []byte) (*authenticatorData, error) {
+	if len(b) < 37 {
+		return nil, errors.New("authenticator data too short")
+	}
+	d := &authenticatorData{
+		rpIDHash:  b[:32],
+		flags:     b[32],
+		signCount: binary.BigEndian.Uint32(b[33:37]),
+	}
+	if d.flags&flagAttestedData != 0 {
+		// aaguid (16) + credentialIdLength (2) + credentialId
+		if len(b) < 55 {
+			return nil, errors.New("attested credential data too short")
+		}
+		n := int(binary.BigEndian.Uint16(b[53:55]))
+		if len(b) < 55+n {
+			return nil, errors.New("credential id truncated")
+		}
+		d.credentialID = b[55 : 55+n]
+	}
+	return d, nil
+}
+
+func (m *PasskeyManager) verifyAuthenticatorData(b []byte) (*authenticatorData, error) {
+	d, err := parseAuthenticatorData(b)
+	if err != nil {
+		return nil, err
+	}
+	rpHash := sha256.Sum256([]byte(m.rpID))
+	if !bytes.Equal(d.rpIDHash, rpHash[:]) {
+		return nil, errors.New("rp id hash mismatch")
+	}
+	if d.flags&flagUserPresent == 0 {
+		return nil, errors.New("user presence flag not set")
+	}
+	return d, nil
+}
+
+func parsePublicKey(alg int, spki []byte) (crypto.PublicKey, error) {
+	pub, err := x509.ParsePKIXPublicKey(spki)
+	if err != nil {
+		return nil, fmt.Errorf("parse public key: %w", err)
+	}
+	switch alg {
+	case AlgES256:
+		if k, ok := pub.(*ecdsa.PublicKey); ok && k.Curve == elliptic.P256() {
+			return k, nil
+		}
+	case AlgEdDSA:
+		if k, ok := pub.(ed25519.PublicKey); ok {
+			return k, nil
+		}
+	case AlgRS256:
+		if k, ok := pub.(*rsa.PublicKey); ok {
+			return k, nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %d", alg)
+	}
+	return nil, fmt.Errorf("public key does not match algorithm %d", alg)
+}
+
+func verifySignature(alg int, spki, data, sig []byte) error {
+	pub, err := parsePublicKey(alg, spki)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256(data)
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		if ecdsa.VerifyASN1(k, digest[:], sig) {
+			return nil
+		}
+	case ed25519.PublicKey:
+		if ed25519.Verify(k, data, sig) {
+			return nil
+		}
+	case *rsa.PublicKey:
+		if rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) == nil {
+			return nil
+		}
+	}
+	return errors.New("invalid signature")
+}
+
+func decodeB64(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
@@ -0,0 +1,239 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+const testOrigin = "https://soc.example.com"
+
+// fakeAuthenticator signs WebAuthn ceremonies the way a platform authenticator would.
+type fakeAuthenticator struct {
+	key       *ecdsa.PrivateKey
+	credID    []byte
+	rpID      string
+	signCount uint32
+}
+
+func newFakeAuthenticator(t *testing.T, rpID string) *fakeAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	return &fakeAuthenticator{key: key, credID: []byte("credential-1"), rpID: rpID}
+}
+
+func (a *fakeAuthenticator) authData(attested bool) []byte {
+	rpHash := sha256.Sum256([]byte(a.rpID))
+	b := append([]byte{}, rpHash[:]...)
+	flags := byte(flagUserPresent)
+	if attested {
+		flags |= flagAttestedData
+	}
+	b = append(b, flags)
+	b = binary.BigEndian.AppendUint32(b, a.signCount)
+	if attested {
+		b = append(b, make([]byte, 16)...)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(a.credID)))
+		b = append(b, a.credID...)
+	}
+	return b
+}
+
+func clientData(t *testing.T, typ, challenge, origin string) []byte {
+	t.Helper()
+	b, err := json.Marshal(map[string]string{"type": typ, "challenge": challenge, "origin": origin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func (a *fakeAuthenticator) register(t *testing.T, challenge string) RegistrationResponse {
+	t.Helper()
+	spki, err := x509.MarshalPKIXPublicKey(&a.key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := base64.RawURLEncoding.EncodeToString
+	return RegistrationResponse{
+		ID:                enc(a.credID),
+		Name:              "laptop",
+		ClientDataJSON:    enc(clientData(t, "webauthn.create", challenge, testOrigin)),
+		AuthenticatorData: enc(a.authData(true)),
+		PublicKey:         enc(spki),
+		PublicKeyAlg:      AlgES256,
+	}
+}
+
+func (a *fakeAuthenticator) assert(t *testing.T, challenge, origin string) AssertionResponse {
+	t.Helper()
+	a.signCount++
+	cd := clientData(t, "webauthn.get", challenge, origin)
+	ad := a.authData(false)
+	cdHash := sha256.Sum256(cd)
+	digest := sha256.Sum256(append(append([]byte{}, ad...), cdHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := base64.RawURLEncoding.EncodeToString
+	return AssertionResponse{
+		ID:                enc(a.credID),
+		ClientDataJSON:    enc(cd),
+		AuthenticatorData: enc(ad),
+		Signature:         enc(sig),
+	}
+}
+
+func newTestManager(t *testing.T) *PasskeyManager {
+	t.Helper()
+	m, err := NewPasskeyManager("", testOrigin, filepath.Join(t.TempDir(), "passkeys.json"))
+	if err != nil {
+		t.Fatalf("NewPasskeyManager() error: %v", err)
+	}
+	return m
+}
+
+func registerFake(t *testing.T, m *PasskeyManager, a *fakeAuthenticator) {
+	t.Helper()
+	opts, err := m.BeginRegistration()
+	if err != nil {
+		t.Fatalf("BeginRegistration() error: %v", err)
+	}
+	if _, err := m.FinishRegistration(a.register(t, opts["challenge"].(string))); err != nil {
+		t.Fatalf("FinishRegistration() error: %v", err)
+	}
+}
+
+func TestPasskeyRegisterAndLogin(t *testing.T) {
+	m := newTestManager(t)
+	a := newFakeAuthenticator(t, "soc.example.com")
+
+	if _, err := m.BeginLogin(); !errors.Is(err, ErrNoPasskeys) {
+		t.Fatalf("BeginLogin() without passkeys error = %v, want ErrNoPasskeys", err)
+	}
+
+	registerFake(t, m, a)
+	if n, _, _ := m.Status(); n != 1 {
+		t.Fatalf("Status() passkeys = %d, want 1", n)
+	}
+
+	opts, err := m.BeginLogin()
+	if err != nil {
+		t.Fatalf("BeginLogin() error: %v", err)
+	}
+	cred, err := m.FinishLogin(a.assert(t, opts["challenge"].(string), testOrigin))
+	if err != nil {
+		t.Fatalf("FinishLogin() error: %v", err)
+	}
+	if cred.Name != "laptop" || cred.SignCount != 1 || cred.LastUsedAt.IsZero() {
+		t.Errorf("FinishLogin() credential = %+v", cred)
+	}
+}
+
+func TestPasskeyLoginRejections(t *testing.T) {
+	m := newTestManager(t)
+	a := newFakeAuthenticator(t, "soc.example.com")
+	registerFake(t, m, a)
+
+	// 签名来自钓鱼站点
+	opts, _ := m.BeginLogin()
+	if _, err := m.FinishLogin(a.assert(t, opts["challenge"].(string), "https://soc.example.com.evil.io")); err == nil {
+		t.Error("FinishLogin() accepted foreign origin")
+	}
+
+	// 挑战只能使用一次
+	opts, _ = m.BeginLogin()
+	resp := a.assert(t, opts["challenge"].(string), testOrigin)
+	if _, err := m.FinishLogin(resp); err != nil {
+		t.Fatalf("FinishLogin() error: %v", err)
+	}
+	if _, err := m.FinishLogin(resp); !errors.Is(err, ErrUnknownChallenge) {
+		t.Errorf("replayed FinishLogin() error = %v, want ErrUnknownChallenge", err)
+	}
+
+	// 未知挑战
+	if _, err := m.FinishLogin(a.assert(t, "bm90LWlzc3VlZA", testOrigin)); !errors.Is(err, ErrUnknownChallenge) {
+		t.Errorf("FinishLogin() with unissued challenge error = %v", err)
+	}
+
+	// 其他密钥的签名
+	opts, _ = m.BeginLogin()
+	other := newFakeAuthenticator(t, "soc.example.com")
+	other.signCount = 10
+	if _, err := m.FinishLogin(other.assert(t, opts["challenge"].(string), testOrigin)); err == nil {
+		t.Error("FinishLogin() accepted signature from a different key")
+	}
+
+	// 计数器回退视为克隆
+	opts, _ = m.BeginLogin()
+	a.signCount = 0
+	if _, err := m.FinishLogin(a.assert(t, opts["challenge"].(string), testOrigin)); err == nil {
+		t.Error("FinishLogin() accepted non-increasing sign count")
+	}
+}
+
+func TestPasskeyRegistrationRejectsWrongRPID(t *testing.T) {
+	m := newTestManager(t)
+	a := newFakeAuthenticator(t, "evil.io")
+	opts, _ := m.BeginRegistration()
+	if _, err := m.FinishRegistration(a.register(t, opts["challenge"].(string))); err == nil {
+		t.Error("FinishRegistration() accepted authenticator data for another rp id")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	m := newTestManager(t)
+	codes, err := m.GenerateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes() error: %v", err)
+	}
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("got %d codes, want %d", len(codes), recoveryCodeCount)
+	}
+
+	if err := m.UseRecoveryCode("0000-0000-0000-0000"); !errors.Is(err, ErrInvalidRecoveryCode) {
+		t.Errorf("UseRecoveryCode(bogus) error = %v", err)
+	}
+	// 大小写和空格不影响
+	if err := m.UseRecoveryCode(" " + codes[0] + " "); err != nil {
+		t.Fatalf("UseRecoveryCode() error: %v", err)
+	}
+	if err := m.UseRecoveryCode(codes[0]); !errors.Is(err, ErrInvalidRecoveryCode) {
+		t.Errorf("reused recovery code error = %v, want ErrInvalidRecoveryCode", err)
+	}
+	if _, left, _ := m.Status(); left != recoveryCodeCount-1 {
+		t.Errorf("recovery codes left = %d, want %d", left, recoveryCodeCount-1)
+	}
+
+	// 重新生成后旧码失效
+	if _, err := m.GenerateRecoveryCodes(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.UseRecoveryCode(codes[1]); !errors.Is(err, ErrInvalidRecoveryCode) {
+		t.Errorf("old recovery code still valid after regeneration")
+	}
+}
+
+func TestNewPasskeyManagerValidation(t *testing.T) {
+	if _, err := NewPasskeyManager("", "soc.example.com", ""); err == nil {
+		t.Error("expected error for origin without scheme")
+	}
+	if _, err := NewPasskeyManager("other.com", testOrigin, ""); err == nil {
+		t.Error("expected error for rp id not matching origin")
+	}
+	if _, err := NewPasskeyManager("example.com", testOrigin, ""); err != nil {
+		t.Errorf("registrable parent domain rejected: %v", err)
+	}
+}
@@ -0,0 +1,316 @@
+// Package backup creates and restores versioned archives of picoclaw state
+// (proposals, schedules, sessions, workspace state and a config snapshot),
+// for host migration and disaster recovery.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FormatVersion is the archive layout version written into the manifest.
+// Restore refuses archives with a newer version.
+const FormatVersion = 1
+
+const (
+	manifestName    = "manifest.json"
+	configEntry     = "config/config.json"
+	workspacePrefix = "workspace/"
+)
+
+// StateDirs are the workspace directories included in a backup.
+var StateDirs = []string{"secops", "state", "cron", "sessions"}
+
+// Manifest describes the contents of a backup archive.
+type Manifest struct {
+	FormatVersion int       `json:"format_version"`
+	CreatedAt     time.Time `json:"created_at"`
+	AppVersion    string    `json:"app_version,omitempty"`
+	Files         []string  `json:"files"`
+}
+
+// Options configures Create.
+type Options struct {
+	Workspace  string
+	ConfigPath string // optional; skipped when empty or missing
+	AppVersion string
+}
+
+// Create writes a gzipped tar archive of the state to w.
+func Create(w io.Writer, opts Options) (*Manifest, error) {
+	type entry struct{ name, src string }
+	var entries []entry
+
+	if opts.ConfigPath != "" {
+		if _, err := os.Stat(opts.ConfigPath); err == nil {
+			entries = append(entries, entry{configEntry, opts.ConfigPath})
+		}
+	}
+
+	for _, dir := range StateDirs {
+		root := filepath.Join(opts.Workspace, dir)
+		err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if !info.Mode().IsRegular() || strings.HasSuffix(p, ".tmp") {
+				return nil
+			}
+			rel, err := filepath.Rel(opts.Workspace, p)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, entry{workspacePrefix + filepath.ToSlash(rel), p})
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("scan %s: %w", root, err)
+		}
+	}
+
+	manifest := &Manifest{
+		FormatVersion: FormatVersion,
+		CreatedAt:     time.Now().UTC(),
+		AppVersion:    opts.AppVersion,
+		Files:         make([]string, len(entries)),
+	}
+	for i, e := range entries {
+		manifest.Files[i] = e.name
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeEntry(tw, manifestName, data, manifest.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		if err := addFile(tw, e.name, e.src); err != nil {
+			return nil, fmt.Errorf("add %s: %w", e.src, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func writeEntry(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	hdr := &tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), ModTime: modTime}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+func addFile(tw *tar.Writer, name, src string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr := &tar.Header{Name: name, Mode: 0600, Size: info.Size(), ModTime: info.ModTime()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.CopyN(tw, f, info.Size())
+	return err
+}
+
+// RestoreOptions configures Restore.
+type RestoreOptions struct {
+	Workspace  string
+	ConfigPath string
+	SkipConfig bool // do not restore the config snapshot
+	Force      bool // overwrite existing files
+	DryRun     bool // only validate and report what would be written
+}
+
+// Restore extracts the archive at archivePath and returns the files written.
+// Without Force it fails before writing anything if any target file already
+// exists, returning the conflicting files instead.
+func Restore(archivePath string, opts RestoreOptions) (*Manifest, []string, error) {
+	manifest, targets, err := scan(archivePath, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !opts.Force {
+		var conflicts []string
+		for _, target := range targets {
+			if _, err := os.Stat(target); err == nil {
+				conflicts = append(conflicts, target)
+			}
+		}
+		if len(conflicts) > 0 {
+			return manifest, conflicts, fmt.Errorf("%d file(s) already exist, use --force to overwrite", len(conflicts))
+		}
+	}
+
+	if opts.DryRun {
+		return manifest, targets, nil
+	}
+
+	err = walk(archivePath, func(hdr *tar.Header, r io.Reader) error {
+		if hdr.Name == manifestName {
+			return nil
+		}
+		target, ok, err := targetPath(hdr.Name, opts)
+		if err != nil || !ok {
+			return err
+		}
+		return writeFile(target, r)
+	})
+	if err != nil {
+		return manifest, nil, err
+	}
+	return manifest, targets, nil
+}
+
+// scan validates the manifest and every entry path, returning the target files.
+func scan(archivePath string, opts RestoreOptions) (*Manifest, []string, error) {
+	var manifest *Manifest
+	var targets []string
+
+	err := walk(archivePath, func(hdr *tar.Header, r io.Reader) error {
+		if manifest == nil {
+			if hdr.Name != manifestName {
+				return fmt.Errorf("not a picoclaw backup: missing %s", manifestName)
+			}
+			manifest = &Manifest{}
+			if err := json.NewDecoder(r).Decode(manifest); err != nil {
+				return fmt.Errorf("invalid manifest: %w", err)
+			}
+			if manifest.FormatVersion < 1 || manifest.FormatVersion > FormatVersion {
+				return fmt.Errorf("unsupported backup format version %d (supported: %d)", manifest.FormatVersion, FormatVersion)
+			}
+			return nil
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			return fmt.Errorf("unexpected entry type for %s", hdr.Name)
+		}
+		target, ok, err := targetPath(hdr.Name, opts)
+		if err != nil {
+			return err
+		}
+		if ok {
+			targets = append(targets, target)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if manifest == nil {
+		return nil, nil, fmt.Errorf("not a picoclaw backup: empty archive")
+	}
+	return manifest, targets, nil
+}
+
+// targetPath maps an archive entry to its destination, rejecting path traversal.
+func targetPath(name string, opts RestoreOptions) (string, bool, error) {
+	if name == configEntry {
+		if opts.SkipConfig || opts.ConfigPath == "" {
+			return "", false, nil
+		}
+		return opts.ConfigPath, true, nil
+	}
+
+	rel, ok := strings.CutPrefix(name, workspacePrefix)
+	if !ok || rel == "" {
+		return "", false, fmt.Errorf("unexpected entry %s", name)
+	}
+	clean := path.Clean(rel)
+	if clean != rel || strings.HasPrefix(clean, "../") || clean == ".." || path.IsAbs(clean) {
+		return "", false, fmt.Errorf("unsafe entry path %s", name)
+	}
+
+	top := strings.SplitN(clean, "/", 2)[0]
+	allowed := false
+	for _, dir := range StateDirs {
+		if top == dir {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return "", false, fmt.Errorf("unexpected entry %s", name)
+	}
+	return filepath.Join(opts.Workspace, filepath.FromSlash(clean)), true, nil
+}
+
+func walk(archivePath string, fn func(hdr *tar.Header, r io.Reader) error) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("not a picoclaw backup: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(hdr, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func writeFile(target string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		return err
+	}
+	tmp := target + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, target)
+}
@@ -0,0 +1,115 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFileT(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func createBackup(t *testing.T, workspace, configPath string) string {
+	t.Helper()
+	archive := filepath.Join(t.TempDir(), "backup.tar.gz")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := Create(f, Options{Workspace: workspace, ConfigPath: configPath, AppVersion: "test"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	return archive
+}
+
+func TestBackupRestoreRoundTrip(t *testing.T) {
+	src := t.TempDir()
+	configPath := filepath.Join(src, "config.json")
+	writeFileT(t, configPath, `{"secops":{}}`)
+	writeFileT(t, filepath.Join(src, "workspace", "secops", "proposals.json"), `[]`)
+	writeFileT(t, filepath.Join(src, "workspace", "cron", "jobs.json"), `{"jobs":[]}`)
+	writeFileT(t, filepath.Join(src, "workspace", "memory", "MEMORY.md"), "not backed up")
+
+	archive := createBackup(t, filepath.Join(src, "workspace"), configPath)
+
+	dst := t.TempDir()
+	opts := RestoreOptions{
+		Workspace:  filepath.Join(dst, "workspace"),
+		ConfigPath: filepath.Join(dst, "config.json"),
+	}
+	manifest, written, err := Restore(archive, opts)
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if manifest.FormatVersion != FormatVersion || manifest.AppVersion != "test" {
+		t.Errorf("unexpected manifest: %+v", manifest)
+	}
+	if len(written) != 3 {
+		t.Errorf("expected 3 files restored, got %v", written)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dst, "workspace", "cron", "jobs.json"))
+	if err != nil || string(data) != `{"jobs":[]}` {
+		t.Errorf("cron store not restored: %q %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "workspace", "memory")); !os.IsNotExist(err) {
+		t.Error("memory directory must not be part of the backup")
+	}
+
+	// 目标已存在时默认拒绝覆盖
+	if _, conflicts, err := Restore(archive, opts); err == nil || len(conflicts) != 3 {
+		t.Errorf("expected conflicts without --force, got %v, %v", conflicts, err)
+	}
+	opts.Force = true
+	if _, _, err := Restore(archive, opts); err != nil {
+		t.Errorf("forced restore failed: %v", err)
+	}
+}
+
+func TestRestoreRejectsUnsafePaths(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "evil.tar.gz")
+	f, _ := os.Create(archive)
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	manifest := []byte(`{"format_version":1,"files":[]}`)
+	tw.WriteHeader(&tar.Header{Name: manifestName, Mode: 0600, Size: int64(len(manifest))})
+	tw.Write(manifest)
+	payload := []byte("x")
+	tw.WriteHeader(&tar.Header{Name: "workspace/secops/../../etc/passwd", Mode: 0600, Size: 1})
+	tw.Write(payload)
+	tw.Close()
+	gz.Close()
+	f.Close()
+
+	dst := t.TempDir()
+	if _, _, err := Restore(archive, RestoreOptions{Workspace: dst}); err == nil {
+		t.Fatal("expected unsafe path to be rejected")
+	}
+}
+
+func TestRestoreRejectsNewerFormat(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "future.tar.gz")
+	f, _ := os.Create(archive)
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	manifest := []byte(`{"format_version":99,"files":[]}`)
+	tw.WriteHeader(&tar.Header{Name: manifestName, Mode: 0600, Size: int64(len(manifest))})
+	tw.Write(manifest)
+	tw.Close()
+	gz.Close()
+	f.Close()
+
+	if _, _, err := Restore(archive, RestoreOptions{Workspace: t.TempDir()}); err == nil {
+		t.Fatal("expected newer format version to be rejected")
+	}
+}
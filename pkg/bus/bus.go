@@ -2,25 +2,105 @@ package bus
 
 import (
 	"context"
+	"strings"
 	"sync"
 )
 
+// eventBuffer is the number of events queued per subscriber before new
+// events are dropped for that subscriber.
+const eventBuffer = 100
+
 type MessageBus struct {
 	inbound  chan InboundMessage
 	outbound chan OutboundMessage
 	handlers map[string]MessageHandler
+	subs     map[int]*subscription
+	nextSub  int
 	closed   bool
 	mu       sync.RWMutex
 }
 
+type subscription struct {
+	pattern string
+	events  chan Event
+}
+
 func NewMessageBus() *MessageBus {
 	return &MessageBus{
 		inbound:  make(chan InboundMessage, 100),
 		outbound: make(chan OutboundMessage, 100),
 		handlers: make(map[string]MessageHandler),
+		subs:     make(map[int]*subscription),
+	}
+}
+
+// Subscribe calls handler for every event whose topic matches pattern, in
+// publish order, on a goroutine owned by the subscription. A pattern is an
+// exact topic, a prefix ending in ".*" (e.g. "secops.proposal.*"), or "*".
+// The returned function cancels the subscription.
+func (mb *MessageBus) Subscribe(pattern string, handler EventHandler) (unsubscribe func()) {
+	sub := &subscription{pattern: pattern, events: make(chan Event, eventBuffer)}
+
+	mb.mu.Lock()
+	if mb.closed {
+		mb.mu.Unlock()
+		return func() {}
+	}
+	id := mb.nextSub
+	mb.nextSub++
+	mb.subs[id] = sub
+	mb.mu.Unlock()
+
+	go func() {
+		for evt := range sub.events {
+			handler(evt)
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			mb.mu.Lock()
+			defer mb.mu.Unlock()
+			if _, ok := mb.subs[id]; ok {
+				delete(mb.subs, id)
+				close(sub.events)
+			}
+		})
 	}
 }
 
+// Publish delivers evt to all matching subscribers without blocking. It
+// returns the number of subscribers that received the event; subscribers
+// whose queue is full miss it.
+func (mb *MessageBus) Publish(evt Event) int {
+	mb.mu.RLock()
+	defer mb.mu.RUnlock()
+	if mb.closed {
+		return 0
+	}
+	delivered := 0
+	for _, sub := range mb.subs {
+		if !topicMatches(sub.pattern, evt.Topic) {
+			continue
+		}
+		select {
+		case sub.events <- evt:
+			delivered++
+		default:
+		}
+	}
+	return delivered
+}
+
+func topicMatches(pattern, topic string) bool {
+	if pattern == "*" || pattern == topic {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(pattern, "*")
+	return ok && strings.HasSuffix(prefix, ".") && strings.HasPrefix(topic, prefix)
+}
+
 func (mb *MessageBus) PublishInbound(msg InboundMessage) {
 	mb.mu.RLock()
 	defer mb.mu.RUnlock()
@@ -79,4 +159,8 @@ func (mb *MessageBus) Close() {
 	mb.closed = true
 	close(mb.inbound)
 	close(mb.outbound)
+	for id, sub := range mb.subs {
+		delete(mb.subs, id)
+		close(sub.events)
+	}
 }
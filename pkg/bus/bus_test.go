@@ -0,0 +1,58 @@
+package bus
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTopicMatches(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, topic string
+		want           bool
+	}{
+		{"secops.proposal.created", "secops.proposal.created", true},
+		{"secops.proposal.*", "secops.proposal.accepted", true},
+		{"secops.*", "secops.proposal.accepted", true},
+		{"*", "anything", true},
+		{"secops.proposal.*", "secops.proposals", false},
+		{"secops.proposal*", "secops.proposals", false},
+		{"secops.proposal.created", "secops.proposal.ignored", false},
+	} {
+		if got := topicMatches(tc.pattern, tc.topic); got != tc.want {
+			t.Errorf("topicMatches(%q, %q) = %v, want %v", tc.pattern, tc.topic, got, tc.want)
+		}
+	}
+}
+
+func TestSubscribePublish(t *testing.T) {
+	mb := NewMessageBus()
+	got := make(chan Event, 10)
+	unsubscribe := mb.Subscribe("secops.proposal.*", func(evt Event) { got <- evt })
+
+	if n := mb.Publish(Event{Topic: "secops.proposal.created", Payload: "p1"}); n != 1 {
+		t.Fatalf("delivered = %d, want 1", n)
+	}
+	if n := mb.Publish(Event{Topic: "cron.fired"}); n != 0 {
+		t.Errorf("unmatched topic delivered to %d subscribers", n)
+	}
+	select {
+	case evt := <-got:
+		if evt.Payload != "p1" {
+			t.Errorf("payload = %v", evt.Payload)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("event not delivered")
+	}
+
+	unsubscribe()
+	unsubscribe()
+	if n := mb.Publish(Event{Topic: "secops.proposal.created"}); n != 0 {
+		t.Errorf("delivered after unsubscribe = %d", n)
+	}
+
+	mb.Subscribe("*", func(Event) {})
+	mb.Close()
+	if n := mb.Publish(Event{Topic: "secops.proposal.created"}); n != 0 {
+		t.Errorf("delivered after close = %d", n)
+	}
+}
@@ -17,3 +17,12 @@ type OutboundMessage struct {
 }
 
 type MessageHandler func(InboundMessage) error
+
+// Event is a structured notification published under a dotted topic,
+// e.g. "secops.proposal.created".
+type Event struct {
+	Topic   string      `json:"topic"`
+	Payload interface{} `json:"payload"`
+}
+
+type EventHandler func(Event)
@@ -0,0 +1,41 @@
+package debugui
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+// requestActor 记录在提案审计日志中的操作者
+//
+// 登录会话视为 Debug UI 操作, 按登录方式记录用户名、通行密钥名称或 "token";
+// 请求头认证视为 API 调用; 未开启认证时只能以客户端地址标识操作者。
+func (s *Server) requestActor(r *http.Request) secops.Actor {
+	if s.auth != nil {
+		if sess, ok := s.auth.session(r); ok {
+			name := sess.Method
+			if sess.Credential != "" {
+				name += ":" + sess.Credential
+			}
+			return secops.Actor{Name: name, Via: secops.ViaDebugUI}
+		}
+		if _, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			return secops.Actor{Name: "token", Via: secops.ViaAPI}
+		}
+		if username, _, ok := r.BasicAuth(); ok {
+			return secops.Actor{Name: "password:" + username, Via: secops.ViaAPI}
+		}
+	}
+
+	// 浏览器发起的 fetch 带有 Sec-Fetch-Mode, 脚本调用通常没有
+	via := secops.ViaAPI
+	if r.Header.Get("Sec-Fetch-Mode") != "" {
+		via = secops.ViaDebugUI
+	}
+	// 双向 TLS 下以客户端证书标识操作者
+	if name := clientCertName(r); name != "" {
+		return secops.Actor{Name: "cert:" + name, Via: via}
+	}
+	return secops.Actor{Name: clientIP(r, s.config.TrustProxy), Via: via}
+}
@@ -0,0 +1,128 @@
+package debugui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// adminReadPaths 只读但属于管理操作的接口 (会话、凭据、训练数据导出); 导出接口另按 isExportPath 匹配
+var adminReadPaths = map[string]bool{
+	"/api/auth/sessions": true,
+	"/api/auth/passkeys": true,
+	"/api/dataset":       true,
+}
+
+// exportPrefix 报告、案件和提案的导出接口
+const exportPrefix = "/api/export/"
+
+// isExportPath 导出接口: /api/export/ 下的路由以及以 /export 结尾的路由, 新增的导出接口默认受白名单限制
+func isExportPath(path string) bool {
+	return strings.HasPrefix(path, exportPrefix) || strings.HasSuffix(path, "/export")
+}
+
+// loginPaths 登录/注销本身不受白名单限制, 否则白名单外无法查看只读页面
+var loginPaths = map[string]bool{
+	"/api/auth/login/begin":    true,
+	"/api/auth/login/finish":   true,
+	"/api/auth/login/token":    true,
+	"/api/auth/login/password": true,
+	"/api/auth/recovery":       true,
+	"/api/auth/logout":         true,
+
+	// 飞书服务器的地址不在白名单中, 回调自行校验 Verification Token
+	feishuCallbackPath: true,
+}
+
+// parseAllowlist 解析 CIDR 列表, 单个 IP 视为 /32 或 /128
+func parseAllowlist(entries []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		if strings.Contains(e, "/") {
+			p, err := netip.ParsePrefix(e)
+			if err != nil {
+				return nil, fmt.Errorf("debugui.admin_allowlist: invalid CIDR %q", e)
+			}
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(e)
+		if err != nil {
+			return nil, fmt.Errorf("debugui.admin_allowlist: invalid IP %q", e)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// isAdminRoute 变更类请求 (非 GET/HEAD/OPTIONS)、管理类只读接口和诊断接口
+func isAdminRoute(r *http.Request) bool {
+	if !strings.HasPrefix(r.URL.Path, "/api/") || loginPaths[r.URL.Path] {
+		return false
+	}
+	if strings.HasPrefix(r.URL.Path, diagnosticsPrefix) {
+		return true
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return adminReadPaths[r.URL.Path] || isExportPath(r.URL.Path)
+	}
+	return true
+}
+
+// ipAllowed 客户端地址是否命中白名单
+func ipAllowed(ip string, allowlist []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range allowlist {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// withAdminAllowlist 变更与管理接口仅允许白名单网段访问, 与登录状态无关
+func (s *Server) withAdminAllowlist(next http.Handler) http.Handler {
+	if len(s.adminAllowlist) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isAdminRoute(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ip := clientIP(r, s.config.TrustProxy)
+		if ipAllowed(ip, s.adminAllowlist) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		logger.WarnCF("debugui", "Admin route blocked by allowlist",
+			map[string]interface{}{
+				"method":    r.Method,
+				"path":      r.URL.Path,
+				"client_ip": ip,
+			})
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error":    "forbidden",
+			"reason":   "client address is not in debugui.admin_allowlist",
+			"clientIp": ip,
+			"method":   r.Method,
+			"path":     r.URL.Path,
+		})
+	})
+}
@@ -0,0 +1,80 @@
+package debugui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestParseAllowlist(t *testing.T) {
+	prefixes, err := parseAllowlist([]string{"10.10.0.0/24", " 192.168.1.5 ", "", "fd00::/8", "10.20.3.4/16"})
+	if err != nil {
+		t.Fatalf("parseAllowlist: %v", err)
+	}
+	if len(prefixes) != 4 {
+		t.Fatalf("got %d prefixes, want 4", len(prefixes))
+	}
+	if prefixes[3].String() != "10.20.0.0/16" {
+		t.Errorf("prefix not masked: %s", prefixes[3])
+	}
+
+	for _, bad := range []string{"10.0.0.0/33", "not-an-ip"} {
+		if _, err := parseAllowlist([]string{bad}); err == nil {
+			t.Errorf("parseAllowlist(%q) expected error", bad)
+		}
+	}
+}
+
+func TestWithAdminAllowlist(t *testing.T) {
+	s := NewServer(config.DebugUIConfig{}, nil, nil, nil, t.TempDir())
+	var err error
+	s.adminAllowlist, err = parseAllowlist([]string{"10.10.0.0/24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := s.withAdminAllowlist(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		method, path, remote string
+		want                 int
+	}{
+		{http.MethodGet, "/api/proposals", "203.0.113.9:1234", http.StatusNoContent},
+		{http.MethodPost, "/api/proposal/p1/accept", "203.0.113.9:1234", http.StatusForbidden},
+		{http.MethodPost, "/api/proposal/p1/accept", "10.10.0.7:1234", http.StatusNoContent},
+		{http.MethodDelete, "/api/silence/s1", "[::ffff:10.10.0.8]:1234", http.StatusNoContent},
+		{http.MethodGet, "/api/dataset", "203.0.113.9:1234", http.StatusForbidden},
+		{http.MethodGet, "/api/proposals/export", "203.0.113.9:1234", http.StatusForbidden},
+		{http.MethodGet, "/api/export/report/daily", "203.0.113.9:1234", http.StatusForbidden},
+		{http.MethodGet, "/api/export/proposal/p1", "10.10.0.7:1234", http.StatusNoContent},
+		{http.MethodPost, "/api/auth/login/begin", "203.0.113.9:1234", http.StatusNoContent},
+		{http.MethodGet, "/", "203.0.113.9:1234", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, nil)
+		r.RemoteAddr = tt.remote
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		if rec.Code != tt.want {
+			t.Errorf("%s %s from %s: status = %d, want %d", tt.method, tt.path, tt.remote, rec.Code, tt.want)
+		}
+		if rec.Code == http.StatusForbidden && !strings.Contains(rec.Body.String(), "admin_allowlist") {
+			t.Errorf("403 body lacks diagnostics: %s", rec.Body.String())
+		}
+	}
+
+	// 经反向代理时只认代理追加的最后一个地址, 客户端伪造的前置地址不能绕过白名单
+	s.config.TrustProxy = true
+	r := httptest.NewRequest(http.MethodPost, "/api/proposal/p1/accept", nil)
+	r.RemoteAddr = "127.0.0.1:1234"
+	r.Header.Set("X-Forwarded-For", "10.10.0.5, 203.0.113.9")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("spoofed X-Forwarded-For: status = %d, want 403", rec.Code)
+	}
+}
@@ -0,0 +1,162 @@
+package debugui
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// approvalTimeout 等待分析师确认的最长时间, 超时视为拒绝
+const approvalTimeout = 5 * time.Minute
+
+// toolApproval 等待分析师确认的工具调用
+type toolApproval struct {
+	ID        string                 `json:"id"`
+	Session   string                 `json:"session"`
+	Tool      string                 `json:"tool"`
+	Args      map[string]interface{} `json:"args"`
+	CreatedAt time.Time              `json:"createdAt"`
+
+	decision chan error // nil 表示批准
+}
+
+// approvalQueue 对话中待确认的工具调用
+type approvalQueue struct {
+	mu      sync.Mutex
+	pending map[string]*toolApproval
+}
+
+func newApprovalQueue() *approvalQueue {
+	return &approvalQueue{pending: make(map[string]*toolApproval)}
+}
+
+// approver 返回指定会话的工具调用确认函数: 登记待确认项并阻塞直到分析师处理、超时或请求结束
+func (q *approvalQueue) approver(session string, done <-chan struct{}) func(ctx context.Context, tool string, args map[string]interface{}) error {
+	return func(ctx context.Context, tool string, args map[string]interface{}) error {
+		a := &toolApproval{
+			ID:        uuid.New().String(),
+			Session:   session,
+			Tool:      tool,
+			Args:      args,
+			CreatedAt: time.Now(),
+			decision:  make(chan error, 1),
+		}
+
+		q.mu.Lock()
+		q.pending[a.ID] = a
+		q.mu.Unlock()
+		defer func() {
+			q.mu.Lock()
+			delete(q.pending, a.ID)
+			q.mu.Unlock()
+		}()
+
+		timer := time.NewTimer(approvalTimeout)
+		defer timer.Stop()
+
+		select {
+		case err := <-a.decision:
+			return err
+		case <-timer.C:
+			return errors.New("approval timed out")
+		case <-done:
+			return errors.New("chat request closed before approval")
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// list 指定会话待确认的工具调用, 按创建时间排序
+func (q *approvalQueue) list(session string) []*toolApproval {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	result := make([]*toolApproval, 0)
+	for _, a := range q.pending {
+		if session == "" || a.Session == session {
+			result = append(result, a)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result
+}
+
+// resolve 处理待确认项, approve 为 false 时以 reason 拒绝
+func (q *approvalQueue) resolve(id string, approve bool, reason string) error {
+	q.mu.Lock()
+	a, ok := q.pending[id]
+	if ok {
+		delete(q.pending, id)
+	}
+	q.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("approval not found: %s", id)
+	}
+
+	if approve {
+		a.decision <- nil
+		return nil
+	}
+	if reason == "" {
+		reason = "no reason given"
+	}
+	a.decision <- fmt.Errorf("rejected by analyst: %s", reason)
+	return nil
+}
+
+// handleApprovals 获取对话中待确认的工具调用, 支持 ?session= 过滤
+func (s *Server) handleApprovals(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	session := r.URL.Query().Get("session")
+	if session != "" {
+		session = "debugui:" + session
+	}
+	approvals := s.approvals.list(session)
+	s.writeList(w, approvals, len(approvals), "")
+}
+
+// handleApproval 确认或拒绝工具调用, 请求体 {"approve": true, "reason": "..."}
+func (s *Server) handleApproval(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Approve bool   `json:"approve"`
+		Reason  string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	id := r.PathValue("id")
+	if err := s.approvals.resolve(id, req.Approve, req.Reason); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	status := "rejected"
+	if req.Approve {
+		status = "approved"
+	}
+	json.NewEncoder(w).Encode(map[string]string{
+		"status": status,
+		"id":     id,
+	})
+}
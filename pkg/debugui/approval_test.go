@@ -0,0 +1,72 @@
+package debugui
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApprovalQueue(t *testing.T) {
+	q := newApprovalQueue()
+	approve := q.approver("debugui:a", nil)
+
+	results := make(chan error, 2)
+	go func() {
+		results <- approve(context.Background(), "sheikah_api", map[string]interface{}{"api": "ignore_risk"})
+	}()
+	go func() {
+		results <- approve(context.Background(), "sheikah_api", map[string]interface{}{"api": "confirm_risk"})
+	}()
+
+	var pending []*toolApproval
+	for i := 0; i < 100 && len(pending) < 2; i++ {
+		time.Sleep(5 * time.Millisecond)
+		pending = q.list("debugui:a")
+	}
+	if len(pending) != 2 {
+		t.Fatalf("pending = %d, want 2", len(pending))
+	}
+	if got := q.list("debugui:other"); len(got) != 0 {
+		t.Errorf("other session sees %d approvals", len(got))
+	}
+
+	if err := q.resolve(pending[0].ID, true, ""); err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if err := q.resolve(pending[1].ID, false, "wrong host"); err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if err := q.resolve(pending[1].ID, true, ""); err == nil {
+		t.Error("expected error resolving twice")
+	}
+
+	var approved, rejected int
+	for i := 0; i < 2; i++ {
+		err := <-results
+		switch {
+		case err == nil:
+			approved++
+		case strings.Contains(err.Error(), "wrong host"):
+			rejected++
+		default:
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if approved != 1 || rejected != 1 {
+		t.Errorf("approved = %d, rejected = %d", approved, rejected)
+	}
+}
+
+func TestApprovalQueue_RequestClosed(t *testing.T) {
+	q := newApprovalQueue()
+	done := make(chan struct{})
+	close(done)
+
+	if err := q.approver("debugui:a", done)(context.Background(), "sheikah_api", nil); err == nil {
+		t.Error("expected error when the chat request is closed")
+	}
+	if len(q.list("")) != 0 {
+		t.Error("approval left pending after request closed")
+	}
+}
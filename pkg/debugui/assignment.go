@@ -0,0 +1,88 @@
+package debugui
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// handleAssign POST /api/proposal/{id}/assign 指派负责人
+//
+// 请求体: {"assignee": "alice"}, assignee 为空表示取消指派
+func (s *Server) handleAssign(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.proposalService == nil {
+		http.Error(w, "proposal service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req struct {
+		Assignee string `json:"assignee"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id := r.PathValue("id")
+	proposal, err := s.proposalService.Assign(id, req.Assignee, s.requestActor(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":       id,
+		"assignee": proposal.Assignee,
+	})
+}
+
+// handleComments GET /api/proposal/{id}/comments 获取评论, POST 追加评论
+//
+// POST 请求体: {"text": "..."}, 作者为当前登录的分析师
+func (s *Server) handleComments(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if s.proposalService == nil {
+		http.Error(w, "proposal service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	id := r.PathValue("id")
+	switch r.Method {
+	case http.MethodGet:
+		comments, ok := s.proposalService.Comments(id)
+		if !ok {
+			http.Error(w, "proposal not found: "+id, http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":       id,
+			"comments": comments,
+		})
+
+	case http.MethodPost:
+		var req struct {
+			Text string `json:"text"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		comment, err := s.proposalService.AddComment(id, req.Text, s.requestActor(r))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(comment)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,58 @@
+package debugui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+func TestAssignmentAndCommentRoutes(t *testing.T) {
+	ps := secops.NewProposalService()
+	id := ps.Create(secops.NewProposal("risk", "撞库攻击", "", nil))
+	s := NewServer(config.DebugUIConfig{}, nil, ps, nil, "")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/proposals", s.handleProposals)
+	mux.HandleFunc("/api/proposal/{id}/assign", s.handleAssign)
+	mux.HandleFunc("/api/proposal/{id}/comments", s.handleComments)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := do("POST", "/api/proposal/"+id+"/assign", `{"assignee":"alice"}`); rec.Code != http.StatusOK {
+		t.Fatalf("assign: code=%d %s", rec.Code, rec.Body)
+	}
+	if rec := do("POST", "/api/proposal/"+id+"/comments", `{"text":""}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("empty comment: code=%d", rec.Code)
+	}
+	if rec := do("POST", "/api/proposal/"+id+"/comments", `{"text":"已封禁来源 IP"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("comment: code=%d %s", rec.Code, rec.Body)
+	}
+
+	rec := do("GET", "/api/proposal/"+id+"/comments", "")
+	var resp struct {
+		Comments []secops.Comment `json:"comments"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || len(resp.Comments) != 1 || resp.Comments[0].Text != "已封禁来源 IP" {
+		t.Errorf("comments = %+v, %v", resp, err)
+	}
+	if rec := do("GET", "/api/proposal/missing/comments", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("missing proposal: code=%d", rec.Code)
+	}
+
+	rec = do("GET", "/api/proposals?assignee=alice", "")
+	var list struct {
+		Items []map[string]interface{} `json:"items"`
+	}
+	json.NewDecoder(rec.Body).Decode(&list)
+	if len(list.Items) != 1 || list.Items[0]["assignee"] != "alice" || list.Items[0]["commentCount"] != 1.0 {
+		t.Errorf("list = %+v", list.Items)
+	}
+}
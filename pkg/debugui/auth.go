@@ -0,0 +1,953 @@
+package debugui
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/auth"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const sessionCookie = "picoclaw_session"
+
+// publicPaths 未登录时允许访问的路径
+var publicPaths = map[string]bool{
+	"/login":                   true,
+	"/api/auth/status":         true,
+	"/api/auth/login/begin":    true,
+	"/api/auth/login/finish":   true,
+	"/api/auth/recovery":       true,
+	"/api/auth/login/token":    true,
+	"/api/auth/login/password": true,
+
+	// 飞书卡片回调由飞书服务器发起, 以 Verification Token 校验来源
+	feishuCallbackPath: true,
+}
+
+// authManager Debug UI 登录与会话管理
+type authManager struct {
+	passkeys   *auth.PasskeyManager // 未开启通行密钥登录时为 nil
+	token      string
+	username   string
+	password   string
+	sessions   *sessionStore
+	limiter    *loginLimiter
+	secure     bool
+	trustProxy bool
+}
+
+// authConfigured 是否配置了任一登录方式
+func authConfigured(cfg config.DebugUIAuthConfig) bool {
+	return cfg.Enabled || cfg.Token != "" || cfg.Username != "" || cfg.Password != ""
+}
+
+func newAuthManager(cfg config.DebugUIAuthConfig, trustProxy bool) (*authManager, error) {
+	if (cfg.Username == "") != (cfg.Password == "") {
+		return nil, errors.New("debugui.auth.username and debugui.auth.password must be set together")
+	}
+
+	var passkeys *auth.PasskeyManager
+	if cfg.Enabled {
+		if cfg.Origin == "" {
+			return nil, errors.New("debugui.auth.origin is required when passkey login is enabled")
+		}
+		pm, err := auth.NewPasskeyManager(cfg.RPID, cfg.Origin, cfg.StorePath)
+		if err != nil {
+			return nil, fmt.Errorf("debugui.auth: %w", err)
+		}
+		passkeys = pm
+	}
+
+	ttl, err := parseAuthDuration("session_ttl", cfg.SessionTTL, defaultSessionTTL)
+	if err != nil {
+		return nil, err
+	}
+	idle, err := parseAuthDuration("idle_timeout", cfg.IdleTimeout, defaultIdleTimeout)
+	if err != nil {
+		return nil, err
+	}
+	maxSessions := defaultMaxSessions
+	if cfg.MaxSessions != 0 {
+		maxSessions = cfg.MaxSessions
+	}
+	if maxSessions < 0 {
+		maxSessions = 0
+	}
+
+	return &authManager{
+		passkeys:   passkeys,
+		token:      cfg.Token,
+		username:   cfg.Username,
+		password:   cfg.Password,
+		sessions:   newSessionStore(idle, ttl, maxSessions),
+		limiter:    newLoginLimiter(),
+		secure:     strings.HasPrefix(cfg.Origin, "https://"),
+		trustProxy: trustProxy,
+	}, nil
+}
+
+// parseAuthDuration 解析时长配置, 空串使用默认值, "0" 表示不限制 (仅 idle_timeout)
+func parseAuthDuration(name, value string, def time.Duration) (time.Duration, error) {
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 || (d == 0 && name == "session_ttl") {
+		return 0, fmt.Errorf("debugui.auth.%s: invalid duration %q", name, value)
+	}
+	return d, nil
+}
+
+// startSession 创建会话并写入 Cookie
+func (a *authManager) startSession(w http.ResponseWriter, r *http.Request, method, credential string) error {
+	token, sess, err := a.sessions.create(authSession{
+		Method:     method,
+		Credential: credential,
+		ClientIP:   clientIP(r, a.trustProxy),
+		UserAgent:  r.UserAgent(),
+	})
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  sess.ExpiresAt,
+		HttpOnly: true,
+		Secure:   a.secure,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return nil
+}
+
+// session 返回请求携带的有效会话
+func (a *authManager) session(r *http.Request) (authSession, bool) {
+	c, err := r.Cookie(sessionCookie)
+	if err != nil || c.Value == "" {
+		return authSession{}, false
+	}
+	return a.sessions.lookup(c.Value)
+}
+
+// authenticated 请求是否携带有效会话, 或在请求头中携带访问令牌 / Basic 认证
+func (a *authManager) authenticated(r *http.Request) bool {
+	if _, ok := a.session(r); ok {
+		return true
+	}
+	return a.headerAuthenticated(r)
+}
+
+// headerAuthenticated 校验 "Authorization: Bearer <token>" 或 HTTP Basic 认证, 供脚本直接调用 API;
+// 与登录接口共用失败退避, 锁定期间一律视为未认证
+func (a *authManager) headerAuthenticated(r *http.Request) bool {
+	method, account, ok := a.headerCredentials(r)
+	if method == "" || a.limiter.locked(loginKeys(clientIP(r, a.trustProxy), account), time.Now()) > 0 {
+		return false
+	}
+	if !ok {
+		a.loginFailed(r, method, account)
+		return false
+	}
+	a.loginSucceeded(r, account)
+	return true
+}
+
+// headerCredentials 校验请求头携带的凭据; method 为空表示未携带
+func (a *authManager) headerCredentials(r *http.Request) (method, account string, ok bool) {
+	if token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found {
+		return "token", "token", a.checkToken(strings.TrimSpace(token))
+	}
+	if username, password, found := r.BasicAuth(); found {
+		return "password", username, a.checkPassword(username, password)
+	}
+	return "", "", false
+}
+
+// checkToken 校验访问令牌
+func (a *authManager) checkToken(token string) bool {
+	return a.token != "" && secretEqual(token, a.token)
+}
+
+// checkPassword 校验用户名密码; 两项都比较完再返回, 避免泄露用户名是否正确
+func (a *authManager) checkPassword(username, password string) bool {
+	if a.username == "" {
+		return false
+	}
+	userOK := secretEqual(username, a.username)
+	passOK := secretEqual(password, a.password)
+	return userOK && passOK
+}
+
+// methods 已开启的登录方式
+func (a *authManager) methods() map[string]bool {
+	return map[string]bool{
+		"passkey":  a.passkeys != nil,
+		"token":    a.token != "",
+		"password": a.username != "",
+	}
+}
+
+// secretEqual 常量时间比较, 先取哈希使耗时与长度无关
+func secretEqual(a, b string) bool {
+	ha := sha256.Sum256([]byte(a))
+	hb := sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(ha[:], hb[:]) == 1
+}
+
+// clearCookie 清除浏览器中的会话 Cookie
+func (a *authManager) clearCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   a.secure,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// endSession 注销当前会话
+func (a *authManager) endSession(w http.ResponseWriter, r *http.Request) {
+	if c, err := r.Cookie(sessionCookie); err == nil {
+		a.sessions.revokeToken(c.Value)
+	}
+	a.clearCookie(w)
+}
+
+// withAuth 配置了登录方式时, 未登录的 API 请求返回 401, 页面请求跳转到登录页
+func (s *Server) withAuth(next http.Handler) http.Handler {
+	if s.auth == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if publicPaths[r.URL.Path] || s.auth.authenticated(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			if method, account, _ := s.auth.headerCredentials(r); method != "" && s.auth.throttled(w, r, account) {
+				return
+			}
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		http.Redirect(w, r, s.externalBasePath(r)+"/login", http.StatusFound)
+	})
+}
+
+// handleLoginPage 登录与通行密钥管理页面
+func (s *Server) handleLoginPage(w http.ResponseWriter, r *http.Request) {
+	s.writePage(w, r, loginHTML)
+}
+
+// handleAuthStatus 返回登录状态; 未登录时不暴露登录方式和通行密钥数量以外的信息
+func (s *Server) handleAuthStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if s.auth == nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{"enabled": false, "authenticated": true})
+		return
+	}
+
+	authenticated := s.auth.authenticated(r)
+	resp := map[string]interface{}{
+		"enabled":       true,
+		"authenticated": authenticated,
+		"methods":       s.auth.methods(),
+	}
+	if s.auth.passkeys != nil {
+		passkeys, codes, err := s.auth.passkeys.Status()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp["passkeys"] = passkeys
+		if authenticated {
+			resp["recoveryCodes"] = codes
+		}
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleLoginBegin 发起通行密钥登录
+func (s *Server) handleLoginBegin(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePasskeyPost(w, r) {
+		return
+	}
+	opts, err := s.auth.passkeys.BeginLogin()
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, auth.ErrNoPasskeys) {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(opts)
+}
+
+// handleLoginFinish 校验通行密钥签名并建立会话
+func (s *Server) handleLoginFinish(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePasskeyPost(w, r) {
+		return
+	}
+	var resp auth.AssertionResponse
+	if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	cred, err := s.auth.passkeys.FinishLogin(resp)
+	if err != nil {
+		logger.WarnCF("debugui", "Passkey login failed",
+			map[string]interface{}{
+				"client_ip": clientIP(r, s.config.TrustProxy),
+				"error":     err.Error(),
+			})
+		http.Error(w, "passkey verification failed", http.StatusUnauthorized)
+		return
+	}
+	if err := s.auth.startSession(w, r, "passkey", cred.Name); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.InfoCF("debugui", "Passkey login",
+		map[string]interface{}{
+			"client_ip": clientIP(r, s.config.TrustProxy),
+			"passkey":   cred.Name,
+		})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// handleRecoveryLogin 使用一次性恢复码登录, 用于首次注册或丢失通行密钥
+func (s *Server) handleRecoveryLogin(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePasskeyPost(w, r) {
+		return
+	}
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if s.auth.throttled(w, r, "recovery") {
+		return
+	}
+
+	if err := s.auth.passkeys.UseRecoveryCode(req.Code); err != nil {
+		logger.WarnCF("debugui", "Recovery code login failed",
+			map[string]interface{}{
+				"client_ip": clientIP(r, s.config.TrustProxy),
+			})
+		s.auth.loginFailed(r, "recovery", "recovery")
+		http.Error(w, "invalid recovery code", http.StatusUnauthorized)
+		return
+	}
+	s.auth.loginSucceeded(r, "recovery")
+	if err := s.auth.startSession(w, r, "recovery", ""); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.InfoCF("debugui", "Recovery code login",
+		map[string]interface{}{
+			"client_ip": clientIP(r, s.config.TrustProxy),
+		})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// handleTokenLogin 使用配置的访问令牌登录
+func (s *Server) handleTokenLogin(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAuthPost(w, r) {
+		return
+	}
+	if s.auth.token == "" {
+		http.Error(w, "token login not enabled", http.StatusNotFound)
+		return
+	}
+	var req struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if s.auth.throttled(w, r, "token") {
+		return
+	}
+
+	if !s.auth.checkToken(strings.TrimSpace(req.Token)) {
+		logger.WarnCF("debugui", "Token login failed",
+			map[string]interface{}{
+				"client_ip": clientIP(r, s.config.TrustProxy),
+			})
+		s.auth.loginFailed(r, "token", "token")
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+	s.auth.loginSucceeded(r, "token")
+	if err := s.auth.startSession(w, r, "token", ""); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.InfoCF("debugui", "Token login",
+		map[string]interface{}{
+			"client_ip": clientIP(r, s.config.TrustProxy),
+		})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// handlePasswordLogin 使用配置的用户名密码登录
+func (s *Server) handlePasswordLogin(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAuthPost(w, r) {
+		return
+	}
+	if s.auth.username == "" {
+		http.Error(w, "password login not enabled", http.StatusNotFound)
+		return
+	}
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if s.auth.throttled(w, r, req.Username) {
+		return
+	}
+
+	if !s.auth.checkPassword(req.Username, req.Password) {
+		logger.WarnCF("debugui", "Password login failed",
+			map[string]interface{}{
+				"client_ip": clientIP(r, s.config.TrustProxy),
+				"username":  req.Username,
+			})
+		s.auth.loginFailed(r, "password", req.Username)
+		http.Error(w, "invalid username or password", http.StatusUnauthorized)
+		return
+	}
+	s.auth.loginSucceeded(r, req.Username)
+	if err := s.auth.startSession(w, r, "password", req.Username); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	logger.InfoCF("debugui", "Password login",
+		map[string]interface{}{
+			"client_ip": clientIP(r, s.config.TrustProxy),
+			"username":  req.Username,
+		})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// handleLogout 注销
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAuthPost(w, r) {
+		return
+	}
+	s.auth.endSession(w, r)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// handleRegisterBegin 发起通行密钥注册 (需已登录)
+func (s *Server) handleRegisterBegin(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePasskeyPost(w, r) {
+		return
+	}
+	opts, err := s.auth.passkeys.BeginRegistration()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(opts)
+}
+
+// handleRegisterFinish 校验注册结果并保存通行密钥
+func (s *Server) handleRegisterFinish(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePasskeyPost(w, r) {
+		return
+	}
+	var resp auth.RegistrationResponse
+	if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	cred, err := s.auth.passkeys.FinishRegistration(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.InfoCF("debugui", "Passkey registered",
+		map[string]interface{}{
+			"client_ip": clientIP(r, s.config.TrustProxy),
+			"passkey":   cred.Name,
+		})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(passkeyView(cred))
+}
+
+// handlePasskeys 列出已注册的通行密钥
+func (s *Server) handlePasskeys(w http.ResponseWriter, r *http.Request) {
+	if s.auth == nil || s.auth.passkeys == nil {
+		http.Error(w, "passkey login not enabled", http.StatusNotFound)
+		return
+	}
+	creds, err := s.auth.passkeys.Credentials()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	items := make([]map[string]interface{}, 0, len(creds))
+	for _, c := range creds {
+		items = append(items, passkeyView(c))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
+// handlePasskey 删除通行密钥
+func (s *Server) handlePasskey(w http.ResponseWriter, r *http.Request) {
+	if s.auth == nil || s.auth.passkeys == nil {
+		http.Error(w, "passkey login not enabled", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := s.auth.passkeys.RemoveCredential(r.PathValue("id")); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "removed"})
+}
+
+// handleRecoveryCodes 重新生成恢复码, 旧恢复码全部失效; 明文只返回这一次
+func (s *Server) handleRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	if !s.requirePasskeyPost(w, r) {
+		return
+	}
+	codes, err := s.auth.passkeys.GenerateRecoveryCodes()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"codes": codes})
+}
+
+// handleSessions 列出当前有效的登录会话
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	if s.auth == nil {
+		http.Error(w, "login not enabled", http.StatusNotFound)
+		return
+	}
+	current, _ := s.auth.session(r)
+	items := make([]map[string]interface{}, 0)
+	for _, sess := range s.auth.sessions.list() {
+		items = append(items, map[string]interface{}{
+			"id":         sess.ID,
+			"method":     sess.Method,
+			"credential": sess.Credential,
+			"clientIp":   sess.ClientIP,
+			"userAgent":  sess.UserAgent,
+			"createdAt":  sess.CreatedAt,
+			"lastSeenAt": sess.LastSeenAt,
+			"expiresAt":  sess.ExpiresAt,
+			"current":    sess.ID == current.ID,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
+// handleSession 注销指定会话
+func (s *Server) handleSession(w http.ResponseWriter, r *http.Request) {
+	if s.auth == nil {
+		http.Error(w, "login not enabled", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := r.PathValue("id")
+	current, _ := s.auth.session(r)
+	if !s.auth.sessions.revoke(id) {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	if id == current.ID {
+		s.auth.clearCookie(w)
+	}
+
+	logger.InfoCF("debugui", "Session revoked",
+		map[string]interface{}{
+			"client_ip": clientIP(r, s.config.TrustProxy),
+			"session":   id,
+		})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "revoked", "id": id})
+}
+
+// handleLogoutAll 所有设备退出登录, 包括当前会话
+func (s *Server) handleLogoutAll(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAuthPost(w, r) {
+		return
+	}
+	n := s.auth.sessions.revokeAll()
+	s.auth.clearCookie(w)
+
+	logger.InfoCF("debugui", "All sessions revoked",
+		map[string]interface{}{
+			"client_ip": clientIP(r, s.config.TrustProxy),
+			"count":     n,
+		})
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok", "revoked": n})
+}
+
+func (s *Server) requireAuthPost(w http.ResponseWriter, r *http.Request) bool {
+	if s.auth == nil {
+		http.Error(w, "login not enabled", http.StatusNotFound)
+		return false
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	return true
+}
+
+func (s *Server) requirePasskeyPost(w http.ResponseWriter, r *http.Request) bool {
+	if s.auth == nil || s.auth.passkeys == nil {
+		http.Error(w, "passkey login not enabled", http.StatusNotFound)
+		return false
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	return true
+}
+
+func passkeyView(c *auth.PasskeyCredential) map[string]interface{} {
+	return map[string]interface{}{
+		"id":         c.ID,
+		"name":       c.Name,
+		"createdAt":  c.CreatedAt,
+		"lastUsedAt": c.LastUsedAt,
+	}
+}
+
+// loginHTML 登录与通行密钥管理页面
+var loginHTML = []byte(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>登录 - 安全运营龙虾</title>
+    <script src="https://cdn.tailwindcss.com"></script>
+    <script defer src="https://cdn.jsdelivr.net/npm/alpinejs@3.x.x/dist/cdn.min.js"></script>
+    <style>[x-cloak] { display: none !important; }</style>
+</head>
+<body class="bg-gray-900 text-gray-100" x-data="login()">
+    <div class="min-h-screen flex items-center justify-center p-4">
+        <div class="w-full max-w-md bg-gray-800 border border-gray-700 rounded-lg p-6 space-y-5">
+            <div class="flex items-center space-x-3">
+                <span class="text-2xl">🦞</span>
+                <h1 class="text-xl font-bold">安全运营龙虾</h1>
+            </div>
+
+            <div x-show="status && !status.enabled" x-cloak class="text-sm text-gray-300">
+                未启用登录。 <a :href="apiURL('/')" class="text-blue-400 hover:underline">返回控制台</a>
+            </div>
+
+            <!-- 未登录 -->
+            <div x-show="status && status.enabled && !status.authenticated" x-cloak class="space-y-4">
+                <form x-show="method('password')" @submit.prevent="loginPassword()" class="space-y-2">
+                    <input x-model="username" autocomplete="username" placeholder="用户名"
+                           class="w-full bg-gray-900 border border-gray-600 rounded-lg px-3 py-2 text-sm">
+                    <input x-model="password" type="password" autocomplete="current-password" placeholder="密码"
+                           class="w-full bg-gray-900 border border-gray-600 rounded-lg px-3 py-2 text-sm">
+                    <button type="submit" :disabled="busy || !username || !password"
+                            class="w-full px-4 py-2 rounded-lg bg-blue-600 hover:bg-blue-700 disabled:opacity-50 font-medium">登录</button>
+                </form>
+                <form x-show="method('token')" @submit.prevent="loginToken()" class="space-y-2"
+                      :class="method('password') && 'border-t border-gray-700 pt-4'">
+                    <label class="text-sm text-gray-400">访问令牌</label>
+                    <div class="flex space-x-2">
+                        <input x-model="token" type="password" autocomplete="off"
+                               class="flex-1 bg-gray-900 border border-gray-600 rounded-lg px-3 py-2 text-sm font-mono">
+                        <button type="submit" :disabled="busy || !token.trim()"
+                                class="px-4 py-2 rounded-lg bg-gray-700 hover:bg-gray-600 disabled:opacity-50 text-sm">登录</button>
+                    </div>
+                </form>
+                <div x-show="method('passkey')" class="space-y-4"
+                     :class="(method('password') || method('token')) && 'border-t border-gray-700 pt-4'">
+                    <button @click="loginPasskey()" :disabled="busy || status.passkeys === 0"
+                            class="w-full px-4 py-2 rounded-lg bg-blue-600 hover:bg-blue-700 disabled:opacity-50 font-medium">
+                        🔑 使用通行密钥登录
+                    </button>
+                    <p x-show="status.passkeys === 0" class="text-xs text-gray-400">
+                        尚未注册通行密钥。请在服务器上执行 <code class="text-yellow-300">picoclaw auth recovery-codes</code> 生成恢复码, 用恢复码登录后注册通行密钥。
+                    </p>
+                    <div class="border-t border-gray-700 pt-4 space-y-2">
+                        <label class="text-sm text-gray-400">恢复码</label>
+                        <div class="flex space-x-2">
+                            <input x-model="code" @keydown.enter="loginRecovery()" placeholder="xxxx-xxxx-xxxx-xxxx"
+                                   class="flex-1 bg-gray-900 border border-gray-600 rounded-lg px-3 py-2 text-sm font-mono">
+                            <button @click="loginRecovery()" :disabled="busy || !code.trim()"
+                                    class="px-4 py-2 rounded-lg bg-gray-700 hover:bg-gray-600 disabled:opacity-50 text-sm">登录</button>
+                        </div>
+                        <p class="text-xs text-gray-500">每个恢复码只能使用一次。</p>
+                    </div>
+                </div>
+            </div>
+
+            <!-- 已登录: 管理通行密钥与恢复码 -->
+            <div x-show="status && status.enabled && status.authenticated" x-cloak class="space-y-4">
+                <p x-show="!method('passkey')" class="text-sm text-gray-300">已登录。</p>
+                <div x-show="method('passkey')">
+                    <h2 class="text-sm font-semibold text-gray-300 mb-2">通行密钥</h2>
+                    <p x-show="passkeys.length === 0" class="text-xs text-yellow-300">尚未注册通行密钥, 请立即注册。</p>
+                    <template x-for="pk in passkeys" :key="pk.id">
+                        <div class="flex items-center justify-between bg-gray-900 rounded px-3 py-2 mb-1 text-sm">
+                            <div>
+                                <div x-text="pk.name"></div>
+                                <div class="text-xs text-gray-500" x-text="'最近使用: ' + formatTime(pk.lastUsedAt)"></div>
+                            </div>
+                            <button @click="removePasskey(pk)" class="text-xs text-red-400 hover:text-red-300">删除</button>
+                        </div>
+                    </template>
+                    <div class="flex space-x-2 mt-2">
+                        <input x-model="name" placeholder="名称, 如 MacBook Touch ID"
+                               class="flex-1 bg-gray-900 border border-gray-600 rounded-lg px-3 py-2 text-sm">
+                        <button @click="registerPasskey()" :disabled="busy"
+                                class="px-4 py-2 rounded-lg bg-blue-600 hover:bg-blue-700 disabled:opacity-50 text-sm">注册</button>
+                    </div>
+                </div>
+
+                <div x-show="method('passkey')" class="border-t border-gray-700 pt-4">
+                    <h2 class="text-sm font-semibold text-gray-300 mb-2">恢复码</h2>
+                    <p class="text-xs text-gray-400 mb-2" x-text="'剩余可用: ' + (status.recoveryCodes || 0)"></p>
+                    <button @click="regenerateCodes()" :disabled="busy"
+                            class="px-4 py-2 rounded-lg bg-gray-700 hover:bg-gray-600 disabled:opacity-50 text-sm">重新生成</button>
+                    <div x-show="codes.length > 0" class="mt-3 bg-gray-900 rounded p-3">
+                        <p class="text-xs text-yellow-300 mb-2">请妥善保存, 离开本页后将无法再次查看:</p>
+                        <template x-for="c in codes" :key="c">
+                            <div class="font-mono text-sm" x-text="c"></div>
+                        </template>
+                    </div>
+                </div>
+
+                <div class="border-t border-gray-700 pt-4 flex justify-between text-sm">
+                    <a :href="apiURL('/')" class="text-blue-400 hover:underline">返回控制台</a>
+                    <button @click="logout()" class="text-gray-400 hover:text-gray-200">退出登录</button>
+                </div>
+            </div>
+
+            <p x-show="error" x-cloak x-text="error" class="text-sm text-red-400"></p>
+        </div>
+    </div>
+
+    <script>
+        const BASE_PATH = {{BASE_PATH_JSON}};
+
+        function apiURL(path) {
+            return BASE_PATH + path;
+        }
+
+        function b64ToBuf(s) {
+            s = s.replace(/-/g, '+').replace(/_/g, '/');
+            while (s.length % 4) s += '=';
+            return Uint8Array.from(atob(s), c => c.charCodeAt(0)).buffer;
+        }
+
+        function bufToB64(buf) {
+            let s = '';
+            new Uint8Array(buf).forEach(b => s += String.fromCharCode(b));
+            return btoa(s).replace(/\+/g, '-').replace(/\//g, '_').replace(/=+$/, '');
+        }
+
+        async function postJSON(path, body) {
+            const response = await fetch(apiURL(path), {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify(body || {})
+            });
+            if (!response.ok) {
+                throw new Error((await response.text()).trim());
+            }
+            return response.json();
+        }
+
+        function login() {
+            return {
+                status: null,
+                passkeys: [],
+                codes: [],
+                code: '',
+                name: '',
+                token: '',
+                username: '',
+                password: '',
+                busy: false,
+                error: '',
+
+                init() {
+                    this.refresh();
+                },
+
+                async refresh() {
+                    const response = await fetch(apiURL('/api/auth/status'));
+                    this.status = await response.json();
+                    if (this.status.enabled && this.status.authenticated && this.method('passkey')) {
+                        const list = await fetch(apiURL('/api/auth/passkeys'));
+                        this.passkeys = await list.json();
+                    }
+                },
+
+                method(name) {
+                    return !!(this.status && this.status.methods && this.status.methods[name]);
+                },
+
+                async run(fn) {
+                    this.busy = true;
+                    this.error = '';
+                    try {
+                        await fn();
+                    } catch (e) {
+                        this.error = e.message || String(e);
+                    } finally {
+                        this.busy = false;
+                    }
+                },
+
+                loginPasskey() {
+                    return this.run(async () => {
+                        const opts = await postJSON('/api/auth/login/begin');
+                        opts.challenge = b64ToBuf(opts.challenge);
+                        opts.allowCredentials = opts.allowCredentials.map(c => ({ ...c, id: b64ToBuf(c.id) }));
+                        const cred = await navigator.credentials.get({ publicKey: opts });
+                        await postJSON('/api/auth/login/finish', {
+                            id: cred.id,
+                            clientDataJSON: bufToB64(cred.response.clientDataJSON),
+                            authenticatorData: bufToB64(cred.response.authenticatorData),
+                            signature: bufToB64(cred.response.signature)
+                        });
+                        location.href = apiURL('/');
+                    });
+                },
+
+                loginPassword() {
+                    return this.run(async () => {
+                        await postJSON('/api/auth/login/password', { username: this.username, password: this.password });
+                        this.password = '';
+                        location.href = apiURL('/');
+                    });
+                },
+
+                loginToken() {
+                    return this.run(async () => {
+                        await postJSON('/api/auth/login/token', { token: this.token.trim() });
+                        this.token = '';
+                        location.href = apiURL('/');
+                    });
+                },
+
+                loginRecovery() {
+                    return this.run(async () => {
+                        await postJSON('/api/auth/recovery', { code: this.code.trim() });
+                        this.code = '';
+                        await this.refresh();
+                    });
+                },
+
+                registerPasskey() {
+                    return this.run(async () => {
+                        const opts = await postJSON('/api/auth/register/begin');
+                        opts.challenge = b64ToBuf(opts.challenge);
+                        opts.user.id = b64ToBuf(opts.user.id);
+                        opts.excludeCredentials = opts.excludeCredentials.map(c => ({ ...c, id: b64ToBuf(c.id) }));
+                        const cred = await navigator.credentials.create({ publicKey: opts });
+                        const publicKey = cred.response.getPublicKey && cred.response.getPublicKey();
+                        if (!publicKey) {
+                            throw new Error('当前浏览器不支持导出通行密钥公钥, 请升级浏览器后重试');
+                        }
+                        await postJSON('/api/auth/register/finish', {
+                            id: cred.id,
+                            name: this.name.trim(),
+                            clientDataJSON: bufToB64(cred.response.clientDataJSON),
+                            authenticatorData: bufToB64(cred.response.getAuthenticatorData()),
+                            publicKey: bufToB64(publicKey),
+                            publicKeyAlgorithm: cred.response.getPublicKeyAlgorithm()
+                        });
+                        this.name = '';
+                        await this.refresh();
+                    });
+                },
+
+                removePasskey(pk) {
+                    if (!confirm('删除通行密钥 "' + pk.name + '"?')) return;
+                    return this.run(async () => {
+                        const response = await fetch(apiURL('/api/auth/passkey/' + encodeURIComponent(pk.id)), { method: 'DELETE' });
+                        if (!response.ok) {
+                            throw new Error((await response.text()).trim());
+                        }
+                        await this.refresh();
+                    });
+                },
+
+                regenerateCodes() {
+                    if (!confirm('重新生成后旧恢复码全部失效, 继续?')) return;
+                    return this.run(async () => {
+                        const data = await postJSON('/api/auth/recovery-codes');
+                        this.codes = data.codes;
+                        await this.refresh();
+                    });
+                },
+
+                logout() {
+                    return this.run(async () => {
+                        await postJSON('/api/auth/logout');
+                        this.passkeys = [];
+                        this.codes = [];
+                        await this.refresh();
+                    });
+                },
+
+                formatTime(t) {
+                    if (!t || t.startsWith('0001')) return '从未';
+                    return new Date(t).toLocaleString();
+                }
+            };
+        }
+    </script>
+</body>
+</html>
+`)
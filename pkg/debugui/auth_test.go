@@ -0,0 +1,222 @@
+package debugui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func newAuthTestServer(t *testing.T) (*Server, http.Handler) {
+	t.Helper()
+	s := NewServer(config.DebugUIConfig{}, nil, nil, nil, t.TempDir())
+	am, err := newAuthManager(config.DebugUIAuthConfig{
+		Enabled:   true,
+		Origin:    "https://soc.example.com",
+		StorePath: filepath.Join(t.TempDir(), "passkeys.json"),
+	}, false)
+	if err != nil {
+		t.Fatalf("newAuthManager: %v", err)
+	}
+	s.auth = am
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/auth/recovery", s.handleRecoveryLogin)
+	mux.HandleFunc("/api/auth/status", s.handleAuthStatus)
+	mux.HandleFunc("/api/info", s.handleInfo)
+	mux.HandleFunc("/login", s.handleLoginPage)
+	mux.HandleFunc("/", s.handleIndex)
+	return s, s.withAuth(mux)
+}
+
+func TestWithAuth_RequiresSession(t *testing.T) {
+	_, h := newAuthTestServer(t)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/info", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("api without session: status = %d, want 401", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/login" {
+		t.Errorf("page without session: status = %d, location = %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/login", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("login page: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestRecoveryLoginStartsSession(t *testing.T) {
+	s, h := newAuthTestServer(t)
+	codes, err := s.auth.passkeys.GenerateRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/auth/recovery", strings.NewReader(`{"code":"bad"}`)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("bad code: status = %d, want 401", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/auth/recovery", strings.NewReader(`{"code":"`+codes[0]+`"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("recovery login: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != sessionCookie || !cookies[0].HttpOnly || !cookies[0].Secure {
+		t.Fatalf("session cookie = %+v", cookies)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/info", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("api with session: status = %d, want 200", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/auth/status", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if !strings.Contains(rec.Body.String(), `"authenticated":true`) || !strings.Contains(rec.Body.String(), `"recoveryCodes":9`) {
+		t.Errorf("status = %s", rec.Body.String())
+	}
+}
+
+func TestNewAuthManagerRequiresOrigin(t *testing.T) {
+	if _, err := newAuthManager(config.DebugUIAuthConfig{Enabled: true}, false); err == nil {
+		t.Error("expected error without origin")
+	}
+	if _, err := newAuthManager(config.DebugUIAuthConfig{Enabled: true, Origin: "https://a.example.com", SessionTTL: "soon"}, false); err == nil {
+		t.Error("expected error for invalid session_ttl")
+	}
+}
+
+func newStaticAuthTestServer(t *testing.T) (*Server, http.Handler) {
+	t.Helper()
+	s := NewServer(config.DebugUIConfig{}, nil, nil, nil, t.TempDir())
+	am, err := newAuthManager(config.DebugUIAuthConfig{
+		Token:    "s3cret-token",
+		Username: "admin",
+		Password: "hunter2",
+	}, false)
+	if err != nil {
+		t.Fatalf("newAuthManager: %v", err)
+	}
+	s.auth = am
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/auth/login/token", s.handleTokenLogin)
+	mux.HandleFunc("/api/auth/login/password", s.handlePasswordLogin)
+	mux.HandleFunc("/api/auth/login/begin", s.handleLoginBegin)
+	mux.HandleFunc("/api/auth/status", s.handleAuthStatus)
+	mux.HandleFunc("/api/info", s.handleInfo)
+	mux.HandleFunc("/", s.handleIndex)
+	return s, s.withAuth(mux)
+}
+
+func TestWithAuth_StaticCredentialHeaders(t *testing.T) {
+	_, h := newStaticAuthTestServer(t)
+
+	tests := []struct {
+		name string
+		set  func(r *http.Request)
+		want int
+	}{
+		{"none", func(r *http.Request) {}, http.StatusUnauthorized},
+		{"bearer", func(r *http.Request) { r.Header.Set("Authorization", "Bearer s3cret-token") }, http.StatusOK},
+		{"bad bearer", func(r *http.Request) { r.Header.Set("Authorization", "Bearer nope") }, http.StatusUnauthorized},
+		{"basic", func(r *http.Request) { r.SetBasicAuth("admin", "hunter2") }, http.StatusOK},
+		{"bad basic", func(r *http.Request) { r.SetBasicAuth("admin", "wrong") }, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/info", nil)
+		tt.set(req)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.SetBasicAuth("admin", "hunter2")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("index with basic auth: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestStaticLoginStartsSession(t *testing.T) {
+	_, h := newStaticAuthTestServer(t)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/auth/login/password", strings.NewReader(`{"username":"admin","password":"bad"}`)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("bad password: status = %d, want 401", rec.Code)
+	}
+
+	for _, tc := range []struct{ path, body string }{
+		{"/api/auth/login/password", `{"username":"admin","password":"hunter2"}`},
+		{"/api/auth/login/token", `{"token":"s3cret-token"}`},
+	} {
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, body = %s", tc.path, rec.Code, rec.Body.String())
+		}
+		cookies := rec.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != sessionCookie {
+			t.Fatalf("%s: session cookie = %+v", tc.path, cookies)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/api/info", nil)
+		req.AddCookie(cookies[0])
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: api with session: status = %d, want 200", tc.path, rec.Code)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/auth/status", nil))
+	body := rec.Body.String()
+	if !strings.Contains(body, `"password":true`) || !strings.Contains(body, `"passkey":false`) || strings.Contains(body, "passkeys") {
+		t.Errorf("status = %s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/auth/login/begin", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("passkey login without passkeys: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestNewAuthManagerStaticCredentials(t *testing.T) {
+	if _, err := newAuthManager(config.DebugUIAuthConfig{Username: "admin"}, false); err == nil {
+		t.Error("expected error for username without password")
+	}
+	am, err := newAuthManager(config.DebugUIAuthConfig{Token: "t"}, false)
+	if err != nil {
+		t.Fatalf("token only: %v", err)
+	}
+	if am.passkeys != nil {
+		t.Error("passkeys should be disabled without auth.enabled")
+	}
+	if authConfigured(config.DebugUIAuthConfig{}) {
+		t.Error("empty config should not require login")
+	}
+}
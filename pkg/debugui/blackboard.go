@@ -0,0 +1,63 @@
+package debugui
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+// handleBlackboard GET /api/blackboard 列出活动共享的黑板条目, 支持 ?namespace= 过滤和分页
+func (s *Server) handleBlackboard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if s.secopsService == nil {
+		s.writeList(w, []interface{}{}, 0, "")
+		return
+	}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	entries, err := s.secopsService.Findings(r.Context(), r.URL.Query().Get("namespace"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	total := len(entries)
+	entries, nextCursor := paginate(entries, page)
+	s.writeList(w, entries, total, nextCursor)
+}
+
+// handleBlackboardEntry DELETE /api/blackboard/{namespace}/{key...} 删除失效的黑板条目
+func (s *Server) handleBlackboardEntry(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if s.secopsService == nil {
+		http.Error(w, "secops service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	namespace, key := r.PathValue("namespace"), r.PathValue("key")
+	err := s.secopsService.RemoveFinding(r.Context(), namespace, key)
+	if errors.Is(err, secops.ErrBlackboardNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.InfoCF("debugui", "Blackboard finding removed",
+		map[string]interface{}{
+			"namespace": namespace,
+			"key":       key,
+			"by":        s.requestActor(r).Name,
+		})
+	json.NewEncoder(w).Encode(map[string]string{"status": "removed"})
+}
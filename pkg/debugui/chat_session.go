@@ -0,0 +1,117 @@
+package debugui
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// chatSessionPrefix Debug UI 对话在 Agent 会话存储中的键前缀, 会话 ID 为去掉前缀的部分
+const chatSessionPrefix = "debugui:"
+
+// chatSessionInfo 对话会话摘要
+type chatSessionInfo struct {
+	ID         string    `json:"id"`
+	Messages   int       `json:"messages"`
+	Summarized bool      `json:"summarized"` // 较早的消息已压缩为摘要
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// chatMessage 会话历史中的一条消息; 助手回复附带渲染后的 HTML
+type chatMessage struct {
+	Role       string               `json:"role"`
+	Content    string               `json:"content"`
+	HTML       string               `json:"html,omitempty"`
+	ToolCalls  []providers.ToolCall `json:"toolCalls,omitempty"`
+	ToolCallID string               `json:"toolCallId,omitempty"`
+}
+
+// handleChatSessions GET /api/sessions 列出 Debug UI 对话会话, 最近活动的在前
+func (s *Server) handleChatSessions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if s.agentLoop == nil {
+		http.Error(w, "agent not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	list := s.agentLoop.Sessions().List(chatSessionPrefix)
+	sessions := make([]chatSessionInfo, 0, len(list))
+	for _, sess := range list {
+		sessions = append(sessions, chatSessionInfo{
+			ID:         strings.TrimPrefix(sess.Key, chatSessionPrefix),
+			Messages:   sess.Messages,
+			Summarized: sess.Summary,
+			CreatedAt:  sess.Created,
+			UpdatedAt:  sess.Updated,
+		})
+	}
+	s.writeList(w, sessions, len(sessions), "")
+}
+
+// handleChatSessionMessages GET /api/sessions/{id}/messages 获取会话历史;
+// 历史超长被压缩过时, summary 为较早消息的摘要
+func (s *Server) handleChatSessionMessages(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if s.agentLoop == nil {
+		http.Error(w, "agent not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	key := chatSessionPrefix + r.PathValue("id")
+	store := s.agentLoop.Sessions()
+	history := store.GetHistory(key)
+	messages := make([]chatMessage, 0, len(history))
+	for _, m := range history {
+		msg := chatMessage{
+			Role:       m.Role,
+			Content:    m.Content,
+			ToolCalls:  m.ToolCalls,
+			ToolCallID: m.ToolCallID,
+		}
+		if m.Role == "assistant" && m.Content != "" {
+			msg.HTML = renderMarkdown(m.Content)
+		}
+		messages = append(messages, msg)
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":       r.PathValue("id"),
+		"summary":  store.GetSummary(key),
+		"messages": messages,
+	})
+}
+
+// handleChatSession DELETE /api/sessions/{id} 清空会话历史, 之后的对话从头开始
+func (s *Server) handleChatSession(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if s.agentLoop == nil {
+		http.Error(w, "agent not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	id := r.PathValue("id")
+	found, err := s.agentLoop.Sessions().Delete(chatSessionPrefix + id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+
+	logger.InfoCF("debugui", "Chat session cleared",
+		map[string]interface{}{
+			"session": id,
+			"by":      s.requestActor(r).Name,
+		})
+	json.NewEncoder(w).Encode(map[string]string{"status": "deleted", "id": id})
+}
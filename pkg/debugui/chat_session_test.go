@@ -0,0 +1,70 @@
+package debugui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/agent"
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestChatSessions(t *testing.T) {
+	workspace := t.TempDir()
+	al := agent.NewAgentLoop(&config.Config{
+		Agents: config.AgentsConfig{Defaults: config.AgentDefaults{Workspace: workspace, Model: "test-model"}},
+	}, bus.NewMessageBus(), nil)
+	store := al.Sessions()
+	store.AddMessage("debugui:triage", "user", "查一下 203.0.113.45")
+	store.AddMessage("debugui:triage", "assistant", "**高危**: SQL 注入")
+	store.Save("debugui:triage")
+	store.AddMessage("telegram:42", "user", "hi")
+
+	s := NewServer(config.DebugUIConfig{}, al, nil, nil, workspace)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/sessions", s.handleChatSessions)
+	mux.HandleFunc("GET /api/sessions/{id}/messages", s.handleChatSessionMessages)
+	mux.HandleFunc("DELETE /api/sessions/{id}", s.handleChatSession)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/sessions", nil))
+	var list struct {
+		Items []chatSessionInfo `json:"items"`
+	}
+	json.NewDecoder(rec.Body).Decode(&list)
+	if len(list.Items) != 1 || list.Items[0].ID != "triage" || list.Items[0].Messages != 2 {
+		t.Fatalf("sessions = %+v, want only the debugui session", list.Items)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/sessions/triage/messages", nil))
+	var history struct {
+		Messages []chatMessage `json:"messages"`
+	}
+	json.NewDecoder(rec.Body).Decode(&history)
+	if len(history.Messages) != 2 || history.Messages[0].Role != "user" || history.Messages[1].HTML == "" {
+		t.Fatalf("messages = %+v", history.Messages)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/sessions/triage", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("delete status = %d: %s", rec.Code, rec.Body)
+	}
+	if len(store.GetHistory("debugui:triage")) != 0 {
+		t.Error("history must be cleared")
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "sessions", "debugui_triage.json")); !os.IsNotExist(err) {
+		t.Errorf("session file must be removed, stat err = %v", err)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/sessions/triage", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("second delete status = %d, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,219 @@
+package debugui
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+const (
+	defaultChatMaxConcurrent = 4
+	defaultChatMaxQueue      = 16
+	defaultChatPerMinute     = 20
+
+	// chatRateWindow 单会话限速的滑动窗口
+	chatRateWindow = time.Minute
+	// chatQueueRetryAfter 排队已满时建议的重试间隔
+	chatQueueRetryAfter = 5 * time.Second
+	// chatQueuePoll 流式接口推送排队位置的间隔
+	chatQueuePoll = time.Second
+)
+
+// chatLimiter 对话接口的限速和并发控制: 每个调用方每分钟的请求数有上限,
+// 同时执行的 ProcessDirect 有上限, 超出的请求按到达顺序排队, 队列满时返回 429
+type chatLimiter struct {
+	maxConcurrent int // <= 0 不限制
+	maxQueue      int // < 0 不限制
+	perMinute     int // <= 0 不限制
+
+	mu        sync.Mutex
+	running   int
+	queue     []*chatTicket
+	recent    map[string][]time.Time // 调用方 -> 窗口内的请求时间
+	lastSweep time.Time
+	rejected  uint64
+}
+
+// chatTicket 一次对话请求的执行名额; ready 关闭表示轮到执行
+type chatTicket struct {
+	ready    chan struct{}
+	released bool
+}
+
+// chatRejection 被拒绝的对话请求, 以 429 返回
+type chatRejection struct {
+	Error      string `json:"error"`
+	Reason     string `json:"reason"` // rate_limited / queue_full
+	RetryAfter int    `json:"retryAfter"`
+	Running    int    `json:"running,omitempty"`
+	Queued     int    `json:"queued,omitempty"`
+}
+
+// newChatLimiter 按配置创建限流器; 0 取默认值, -1 不限制
+func newChatLimiter(cfg config.ChatLimitConfig) *chatLimiter {
+	l := &chatLimiter{
+		maxConcurrent: defaultChatMaxConcurrent,
+		maxQueue:      defaultChatMaxQueue,
+		perMinute:     defaultChatPerMinute,
+		recent:        make(map[string][]time.Time),
+	}
+	if cfg.MaxConcurrent != 0 {
+		l.maxConcurrent = cfg.MaxConcurrent
+	}
+	if cfg.MaxQueue != 0 {
+		l.maxQueue = cfg.MaxQueue
+	}
+	if cfg.PerMinute != 0 {
+		l.perMinute = cfg.PerMinute
+	}
+	return l
+}
+
+// enter 记录一次请求并申请执行名额; 返回的 ticket 必须以 leave 释放。
+// 名额已满时排队, position 为排队位置 (从 1 开始), 0 表示可以立即执行
+func (l *chatLimiter) enter(caller string, now time.Time) (ticket *chatTicket, position int, rejection *chatRejection) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.perMinute > 0 {
+		l.sweep(now)
+		times := l.recent[caller]
+		for len(times) > 0 && !times[0].After(now.Add(-chatRateWindow)) {
+			times = times[1:]
+		}
+		if len(times) >= l.perMinute {
+			l.rejected++
+			retry := times[0].Add(chatRateWindow).Sub(now)
+			return nil, 0, &chatRejection{
+				Error:      "too many chat requests, limit is " + strconv.Itoa(l.perMinute) + " per minute",
+				Reason:     "rate_limited",
+				RetryAfter: retrySeconds(retry),
+			}
+		}
+		l.recent[caller] = append(times, now)
+	}
+
+	ticket = &chatTicket{ready: make(chan struct{})}
+	if l.maxConcurrent <= 0 || (l.running < l.maxConcurrent && len(l.queue) == 0) {
+		l.running++
+		close(ticket.ready)
+		return ticket, 0, nil
+	}
+	if l.maxQueue >= 0 && len(l.queue) >= l.maxQueue {
+		l.rejected++
+		return nil, 0, &chatRejection{
+			Error:      "chat queue is full, try again later",
+			Reason:     "queue_full",
+			RetryAfter: retrySeconds(chatQueueRetryAfter),
+			Running:    l.running,
+			Queued:     len(l.queue),
+		}
+	}
+	l.queue = append(l.queue, ticket)
+	return ticket, len(l.queue), nil
+}
+
+// sweep 删除窗口内没有请求的调用方, 每个窗口最多执行一次; 调用方持有锁
+func (l *chatLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < chatRateWindow {
+		return
+	}
+	l.lastSweep = now
+	cutoff := now.Add(-chatRateWindow)
+	for caller, times := range l.recent {
+		if len(times) == 0 || !times[len(times)-1].After(cutoff) {
+			delete(l.recent, caller)
+		}
+	}
+}
+
+// position 排队位置, 从 1 开始; 0 表示已轮到执行或已离开队列
+func (l *chatLimiter) position(ticket *chatTicket) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, t := range l.queue {
+		if t == ticket {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// wait 等待轮到执行; 排队位置变化时调用 onPosition (可为 nil)。ctx 取消时离开队列
+func (l *chatLimiter) wait(ctx context.Context, ticket *chatTicket, onPosition func(int)) error {
+	last := 0
+	ticker := time.NewTicker(chatQueuePoll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticket.ready:
+			return nil
+		case <-ctx.Done():
+			l.leave(ticket)
+			return ctx.Err()
+		case <-ticker.C:
+			if pos := l.position(ticket); pos > 0 && pos != last && onPosition != nil {
+				last = pos
+				onPosition(pos)
+			}
+		}
+	}
+}
+
+// leave 释放执行名额或离开队列; 释放名额时由队首请求接替
+func (l *chatLimiter) leave(ticket *chatTicket) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if ticket.released {
+		return
+	}
+	ticket.released = true
+	for i, t := range l.queue {
+		if t == ticket {
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+			return
+		}
+	}
+	if len(l.queue) > 0 {
+		next := l.queue[0]
+		l.queue = l.queue[1:]
+		close(next.ready)
+		return
+	}
+	l.running--
+}
+
+// stats 正在执行、排队和累计拒绝的请求数
+func (l *chatLimiter) stats() chatStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return chatStats{Running: l.running, Queued: len(l.queue), Rejected: l.rejected}
+}
+
+func retrySeconds(d time.Duration) int {
+	return int(math.Max(1, math.Ceil(d.Seconds())))
+}
+
+// chatCaller 限速的调用方: 登录会话按会话区分, 其余按 requestActor 的标识 (令牌、用户名或客户端地址)
+func (s *Server) chatCaller(r *http.Request) string {
+	if s.auth != nil {
+		if sess, ok := s.auth.session(r); ok {
+			return "session:" + sess.ID
+		}
+	}
+	return s.requestActor(r).Name
+}
+
+// writeChatRejection 以 429 返回被拒绝的对话请求, 附带 Retry-After
+func writeChatRejection(w http.ResponseWriter, rej *chatRejection) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.Itoa(rej.RetryAfter))
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(rej)
+}
@@ -0,0 +1,94 @@
+package debugui
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestChatLimiterPerMinute(t *testing.T) {
+	l := newChatLimiter(config.ChatLimitConfig{PerMinute: 2, MaxConcurrent: -1})
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		ticket, _, rej := l.enter("session:a", now)
+		if rej != nil {
+			t.Fatalf("request %d rejected: %+v", i, rej)
+		}
+		l.leave(ticket)
+	}
+	_, _, rej := l.enter("session:a", now.Add(10*time.Second))
+	if rej == nil || rej.Reason != "rate_limited" || rej.RetryAfter != 50 {
+		t.Fatalf("third request = %+v, want rate_limited retry after 50s", rej)
+	}
+
+	// 其他调用方不受影响, 窗口过后恢复
+	if _, _, rej := l.enter("session:b", now); rej != nil {
+		t.Errorf("other caller rejected: %+v", rej)
+	}
+	if _, _, rej := l.enter("session:a", now.Add(chatRateWindow+time.Second)); rej != nil {
+		t.Errorf("request after window rejected: %+v", rej)
+	}
+	if st := l.stats(); st.Rejected != 1 {
+		t.Errorf("rejected = %d, want 1", st.Rejected)
+	}
+}
+
+func TestChatLimiterQueue(t *testing.T) {
+	l := newChatLimiter(config.ChatLimitConfig{MaxConcurrent: 1, MaxQueue: 2, PerMinute: -1})
+	now := time.Now()
+
+	first, pos, _ := l.enter("a", now)
+	if pos != 0 {
+		t.Fatalf("first position = %d, want 0", pos)
+	}
+	second, pos2, _ := l.enter("b", now)
+	third, pos3, _ := l.enter("c", now)
+	if pos2 != 1 || pos3 != 2 {
+		t.Fatalf("queue positions = %d, %d, want 1, 2", pos2, pos3)
+	}
+	if _, _, rej := l.enter("d", now); rej == nil || rej.Reason != "queue_full" || rej.Running != 1 || rej.Queued != 2 {
+		t.Fatalf("fourth request = %+v, want queue_full", rej)
+	}
+
+	// 排队中的请求取消后离开队列, 后面的请求前移
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.wait(ctx, second, nil); err == nil {
+		t.Fatal("wait with cancelled context succeeded")
+	}
+	if pos := l.position(third); pos != 1 {
+		t.Errorf("third position after cancel = %d, want 1", pos)
+	}
+
+	l.leave(first)
+	if err := l.wait(context.Background(), third, nil); err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	if st := l.stats(); st.Running != 1 || st.Queued != 0 {
+		t.Errorf("stats = %+v, want 1 running", st)
+	}
+	l.leave(third)
+	l.leave(third)
+	if st := l.stats(); st.Running != 0 {
+		t.Errorf("running after double leave = %d, want 0", st.Running)
+	}
+}
+
+func TestWriteChatRejection(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeChatRejection(rec, &chatRejection{Error: "chat queue is full", Reason: "queue_full", RetryAfter: 5, Running: 4, Queued: 16})
+
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "5" {
+		t.Fatalf("status = %d, Retry-After = %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	var body chatRejection
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Reason != "queue_full" || body.Queued != 16 {
+		t.Errorf("body = %+v, err %v", body, err)
+	}
+}
@@ -0,0 +1,181 @@
+package debugui
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	gzipPool = sync.Pool{
+		New: func() interface{} {
+			w, _ := gzip.NewWriterLevel(io.Discard, gzip.DefaultCompression)
+			return w
+		},
+	}
+	zstdPool = sync.Pool{
+		New: func() interface{} {
+			w, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedFastest), zstd.WithEncoderConcurrency(1))
+			return w
+		},
+	}
+)
+
+// compressibleTypes 需要压缩的响应类型; SSE 等流式响应不压缩
+var compressibleTypes = []string{
+	"application/json",
+	"text/html",
+	"text/plain",
+	"text/css",
+	"text/csv",
+	"application/javascript",
+}
+
+// negotiateEncoding 根据 Accept-Encoding 选择压缩算法, 优先 zstd, 其次 gzip
+func negotiateEncoding(acceptEncoding string) string {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		q := 1.0
+		for _, f := range fields[1:] {
+			f = strings.TrimSpace(f)
+			if strings.HasPrefix(f, "q=") {
+				if v, err := strconv.ParseFloat(f[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if name != "" && q > 0 {
+			accepted[name] = true
+		}
+	}
+
+	switch {
+	case accepted["zstd"]:
+		return "zstd"
+	case accepted["gzip"]:
+		return "gzip"
+	}
+	return ""
+}
+
+// compressWriter 在首次写入时根据响应类型决定是否压缩
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	encoder     io.WriteCloser
+	decided     bool
+	wroteHeader bool
+}
+
+func (cw *compressWriter) decide(status int) {
+	if cw.decided {
+		return
+	}
+	cw.decided = true
+
+	h := cw.Header()
+	if status < 200 || status == http.StatusNoContent || status == http.StatusNotModified ||
+		h.Get("Content-Encoding") != "" {
+		return
+	}
+
+	contentType := h.Get("Content-Type")
+	compressible := false
+	for _, t := range compressibleTypes {
+		if strings.HasPrefix(contentType, t) {
+			compressible = true
+			break
+		}
+	}
+	if !compressible {
+		return
+	}
+
+	switch cw.encoding {
+	case "zstd":
+		enc := zstdPool.Get().(*zstd.Encoder)
+		enc.Reset(cw.ResponseWriter)
+		cw.encoder = enc
+	case "gzip":
+		enc := gzipPool.Get().(*gzip.Writer)
+		enc.Reset(cw.ResponseWriter)
+		cw.encoder = enc
+	default:
+		return
+	}
+
+	h.Set("Content-Encoding", cw.encoding)
+	h.Del("Content-Length")
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.decide(status)
+	cw.wroteHeader = true
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		if cw.Header().Get("Content-Type") == "" {
+			cw.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.encoder != nil {
+		return cw.encoder.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// Flush 刷新压缩缓冲区和底层连接
+func (cw *compressWriter) Flush() {
+	switch enc := cw.encoder.(type) {
+	case *gzip.Writer:
+		enc.Flush()
+	case *zstd.Encoder:
+		enc.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// close 结束压缩流并归还编码器
+func (cw *compressWriter) close() {
+	switch enc := cw.encoder.(type) {
+	case *gzip.Writer:
+		enc.Close()
+		gzipPool.Put(enc)
+	case *zstd.Encoder:
+		enc.Close()
+		zstdPool.Put(enc)
+	}
+	cw.encoder = nil
+}
+
+// withCompression 按 Accept-Encoding 透明压缩 API 和页面响应
+func (s *Server) withCompression(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		defer cw.close()
+		next.ServeHTTP(cw, r)
+	})
+}
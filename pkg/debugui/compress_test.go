@@ -0,0 +1,51 @@
+package debugui
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := map[string]string{
+		"":                     "",
+		"gzip, deflate":        "gzip",
+		"gzip, zstd":           "zstd",
+		"zstd;q=0, gzip;q=0.5": "gzip",
+		"br":                   "",
+	}
+	for in, want := range tests {
+		if got := negotiateEncoding(in); got != want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestWithCompression_Gzip(t *testing.T) {
+	s := NewServer(config.DebugUIConfig{}, nil, nil, nil, "")
+	handler := s.withCompression(http.HandlerFunc(s.handleIndex))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", rec.Header().Get("Content-Encoding"))
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("read gzip body: %v", err)
+	}
+	if len(body) == 0 || body[0] != '<' {
+		t.Error("decompressed body should be the HTML page")
+	}
+}
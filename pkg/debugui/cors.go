@@ -0,0 +1,124 @@
+package debugui
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+const defaultCORSMaxAge = 10 * time.Minute
+
+var (
+	// corsDefaultHeaders 始终允许的请求头
+	corsDefaultHeaders = []string{"Content-Type", "Authorization", "If-None-Match"}
+	// corsExposedHeaders 跨域页面可读取的响应头
+	corsExposedHeaders = "ETag, Retry-After, Content-Disposition, X-TAXII-Date-Added-First, X-TAXII-Date-Added-Last"
+	corsMethods        = "GET, POST, PUT, PATCH, DELETE"
+)
+
+// corsPolicy 解析后的跨域配置
+type corsPolicy struct {
+	anyOrigin   bool
+	origins     map[string]bool // 规范化后的 scheme://host[:port]
+	headers     string
+	credentials bool
+	maxAge      string
+}
+
+// newCORSPolicy 校验并解析跨域配置; 未配置来源时返回 nil, 不输出任何 CORS 响应头
+func newCORSPolicy(cfg config.CORSConfig) (*corsPolicy, error) {
+	p := &corsPolicy{origins: make(map[string]bool), credentials: cfg.AllowCredentials}
+	for _, o := range cfg.AllowedOrigins {
+		o = strings.TrimSpace(o)
+		switch {
+		case o == "":
+			continue
+		case o == "*":
+			p.anyOrigin = true
+			continue
+		}
+		u, err := url.Parse(o)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			return nil, fmt.Errorf("debugui.cors.allowed_origins: invalid origin %q, expected scheme://host[:port]", o)
+		}
+		p.origins[strings.ToLower(u.Scheme+"://"+u.Host)] = true
+	}
+	if !p.anyOrigin && len(p.origins) == 0 {
+		return nil, nil
+	}
+	if p.anyOrigin && p.credentials {
+		return nil, fmt.Errorf("debugui.cors: allow_credentials cannot be used with allowed origin \"*\"")
+	}
+
+	headers := append([]string(nil), corsDefaultHeaders...)
+	for _, h := range cfg.AllowedHeaders {
+		if h = strings.TrimSpace(h); h != "" {
+			headers = append(headers, http.CanonicalHeaderKey(h))
+		}
+	}
+	p.headers = strings.Join(headers, ", ")
+
+	maxAge := defaultCORSMaxAge
+	if cfg.MaxAge != "" {
+		d, err := time.ParseDuration(cfg.MaxAge)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("debugui.cors.max_age: invalid duration %q", cfg.MaxAge)
+		}
+		maxAge = d
+	}
+	p.maxAge = strconv.Itoa(int(maxAge.Seconds()))
+	return p, nil
+}
+
+// allowed 请求来源是否在允许列表中
+func (p *corsPolicy) allowed(origin string) bool {
+	return p.anyOrigin || p.origins[strings.ToLower(origin)]
+}
+
+// withCORS 允许配置的来源跨域调用 /api 接口; 预检请求在认证之前直接响应,
+// 其余请求附加 CORS 响应头后照常经过白名单和认证
+func (s *Server) withCORS(next http.Handler) http.Handler {
+	if s.cors == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if !s.cors.allowed(origin) {
+			if preflight {
+				http.Error(w, "origin not allowed", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if s.cors.anyOrigin {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		if s.cors.credentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+		if preflight {
+			w.Header().Set("Access-Control-Allow-Methods", corsMethods)
+			w.Header().Set("Access-Control-Allow-Headers", s.cors.headers)
+			w.Header().Set("Access-Control-Max-Age", s.cors.maxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+		next.ServeHTTP(w, r)
+	})
+}
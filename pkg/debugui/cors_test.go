@@ -0,0 +1,84 @@
+package debugui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestNewCORSPolicy(t *testing.T) {
+	if p, err := newCORSPolicy(config.CORSConfig{}); p != nil || err != nil {
+		t.Errorf("empty config = %+v, %v, want nil", p, err)
+	}
+
+	p, err := newCORSPolicy(config.CORSConfig{
+		AllowedOrigins: []string{"https://Dash.example.com", " http://localhost:3000/ "},
+		AllowedHeaders: []string{"x-request-id"},
+		MaxAge:         "1h",
+	})
+	if err != nil {
+		t.Fatalf("newCORSPolicy: %v", err)
+	}
+	if !p.allowed("https://dash.example.com") || !p.allowed("http://localhost:3000") || p.allowed("https://evil.example.com") {
+		t.Errorf("origins = %v", p.origins)
+	}
+	if p.headers != "Content-Type, Authorization, If-None-Match, X-Request-Id" || p.maxAge != "3600" {
+		t.Errorf("headers = %q, maxAge = %q", p.headers, p.maxAge)
+	}
+
+	for _, bad := range []config.CORSConfig{
+		{AllowedOrigins: []string{"dash.example.com"}},
+		{AllowedOrigins: []string{"https://dash.example.com/app"}},
+		{AllowedOrigins: []string{"*"}, AllowCredentials: true},
+		{AllowedOrigins: []string{"*"}, MaxAge: "soon"},
+	} {
+		if _, err := newCORSPolicy(bad); err == nil {
+			t.Errorf("newCORSPolicy(%+v) expected error", bad)
+		}
+	}
+}
+
+func TestWithCORS(t *testing.T) {
+	s := NewServer(config.DebugUIConfig{}, nil, nil, nil, t.TempDir())
+	var err error
+	s.cors, err = newCORSPolicy(config.CORSConfig{AllowedOrigins: []string{"https://dash.example.com"}, AllowCredentials: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 认证在 CORS 之后, 预检请求不应到达
+	h := s.withCORS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+
+	tests := []struct {
+		method, path, origin string
+		want                 int
+		allowOrigin          string
+	}{
+		{http.MethodOptions, "/api/proposals", "https://dash.example.com", http.StatusNoContent, "https://dash.example.com"},
+		{http.MethodOptions, "/api/proposals", "https://evil.example.com", http.StatusForbidden, ""},
+		{http.MethodGet, "/api/proposals", "https://dash.example.com", http.StatusUnauthorized, "https://dash.example.com"},
+		{http.MethodGet, "/api/proposals", "https://evil.example.com", http.StatusUnauthorized, ""},
+		{http.MethodGet, "/api/proposals", "", http.StatusUnauthorized, ""},
+		{http.MethodGet, "/", "https://dash.example.com", http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		if tt.method == http.MethodOptions {
+			r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		if rec.Code != tt.want || rec.Header().Get("Access-Control-Allow-Origin") != tt.allowOrigin {
+			t.Errorf("%s %s from %q: status = %d, allow origin = %q", tt.method, tt.path, tt.origin, rec.Code, rec.Header().Get("Access-Control-Allow-Origin"))
+		}
+		if tt.allowOrigin != "" && rec.Header().Get("Access-Control-Allow-Credentials") != "true" {
+			t.Errorf("%s %s: missing Access-Control-Allow-Credentials", tt.method, tt.path)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package debugui
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// handleDailyReport POST /api/daily-report 由 Agent 生成运营日报并写入报告目录
+//
+// 请求体可选: {"until": "2026-03-02T08:00:00+08:00", "post": true};
+// 统计 until 之前 24 小时, until 为空时取当前时间, post 为 true 时同时发往 daily_report.targets 配置的通知目标
+func (s *Server) handleDailyReport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.secopsService == nil {
+		http.Error(w, "secops service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req struct {
+		Until string `json:"until"`
+		Post  bool   `json:"post"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	until, err := parseQueryTime(req.Until)
+	if err != nil {
+		http.Error(w, "invalid until: "+req.Until, http.StatusBadRequest)
+		return
+	}
+
+	report, err := s.secopsService.GenerateDailyReport(r.Context(), until, req.Post)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(report)
+}
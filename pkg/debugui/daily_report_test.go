@@ -0,0 +1,59 @@
+package debugui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/agent"
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+func TestHandleDailyReport(t *testing.T) {
+	workspace := t.TempDir()
+	msgBus := bus.NewMessageBus()
+	al := agent.NewAgentLoop(&config.Config{
+		Agents: config.AgentsConfig{Defaults: config.AgentDefaults{Workspace: workspace, Model: "test-model"}},
+	}, msgBus, nil)
+	svc, err := secops.NewService(&config.SecOpsConfig{Enabled: true}, al, msgBus, workspace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(config.DebugUIConfig{}, nil, svc.ProposalService(), svc, "")
+
+	for _, tc := range []struct {
+		method, body string
+		want         int
+	}{
+		{http.MethodGet, "", http.StatusMethodNotAllowed},
+		{http.MethodPost, `{"until": "yesterday"}`, http.StatusBadRequest},
+		{http.MethodPost, `{"until": "2999-01-01"}`, http.StatusBadRequest},
+		{http.MethodPost, `{"post": true}`, http.StatusBadRequest}, // 未配置 daily_report.targets
+	} {
+		rec := httptest.NewRecorder()
+		s.handleDailyReport(rec, httptest.NewRequest(tc.method, "/api/daily-report", strings.NewReader(tc.body)))
+		if rec.Code != tc.want {
+			t.Errorf("%s %s: status = %d, want %d (%s)", tc.method, tc.body, rec.Code, tc.want, rec.Body.String())
+		}
+	}
+
+	// Agent 没有可用的模型时按模板生成, 写入报告目录
+	rec := httptest.NewRecorder()
+	s.handleDailyReport(rec, httptest.NewRequest(http.MethodPost, "/api/daily-report", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d (%s)", rec.Code, rec.Body.String())
+	}
+	var report secops.DailyReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "secops", "reports", report.File)); report.File == "" || err != nil {
+		t.Errorf("report file %q: %v", report.File, err)
+	}
+}
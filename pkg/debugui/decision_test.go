@@ -0,0 +1,92 @@
+package debugui
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+func TestHandleAccept_EditedParams(t *testing.T) {
+	ps := secops.NewProposalService()
+	p := secops.NewProposal("risk", "撞库攻击", "", nil)
+	p.Parameters = map[string]secops.Param{"block_hours": {Key: "block_hours", Type: "number", Value: "24"}}
+	id := ps.Create(p)
+	s := NewServer(config.DebugUIConfig{}, nil, ps, nil, "")
+
+	accept := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.handleAccept(rec, httptest.NewRequest("POST", "/api/proposal/"+id+"/accept", strings.NewReader(body)))
+		return rec
+	}
+	if rec := accept(`{"params":{"block_hours":"forever"}}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("invalid param: code=%d", rec.Code)
+	}
+	if rec := accept(`{"params":{"block_hours":"72"}}`); rec.Code != http.StatusOK {
+		t.Fatalf("accept: code=%d %s", rec.Code, rec.Body)
+	}
+	got, _ := ps.Get(id)
+	if got.Decision.Params["block_hours"] != "72" || len(got.Decision.Overrides) != 1 || got.Decision.Overrides[0].From != "24" {
+		t.Errorf("decision = %+v", got.Decision)
+	}
+}
+
+func TestHandleBulkDecision(t *testing.T) {
+	ps := secops.NewProposalService()
+	ps.SetRequireOverrideReason(true)
+	ps.SetExecutor(func(ctx context.Context, api string, params map[string]string) (string, error) {
+		return `{"code": 0}`, nil
+	})
+	var ids []string
+	for _, title := range []string{"弱口令 a", "弱口令 b"} {
+		p := secops.NewProposal("weak", title, "", nil)
+		p.Recommendation = secops.ActionAccept
+		ids = append(ids, ps.Create(p))
+	}
+	s := NewServer(config.DebugUIConfig{}, nil, ps, nil, "")
+
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.handleBulkDecision(rec, httptest.NewRequest("POST", "/api/proposals/bulk", strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := post(`{"ids":["` + ids[0] + `","missing"],"action":"accept"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown id: code=%d", rec.Code)
+	}
+	if rec := post(`{"ids":["` + ids[0] + `","` + ids[1] + `"],"action":"ignore"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("override without reason: code=%d", rec.Code)
+	}
+	if p, _ := ps.Get(ids[0]); p.Status != secops.ProposalStatusPending {
+		t.Fatalf("failed batch decided %s", p.Status)
+	}
+
+	rec := post(`{"ids":["` + ids[0] + `","` + ids[1] + `"],"action":"ignore","reason":"测试账号"}`)
+	var resp struct {
+		Count   int      `json:"count"`
+		Queued  []string `json:"queued"`
+		Results []struct {
+			ID     string                `json:"id"`
+			Status secops.ProposalStatus `json:"status"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("code=%d: %v", rec.Code, err)
+	}
+	// 决策记录后立即返回, 绑定的 API 排队执行
+	if rec.Code != http.StatusAccepted || resp.Count != 2 || len(resp.Queued) != 2 ||
+		resp.Results[1].ID != ids[1] || resp.Results[1].Status != secops.ProposalStatusIgnored {
+		t.Errorf("code=%d response = %+v", rec.Code, resp)
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleBulkDecision(rec, httptest.NewRequest("GET", "/api/proposals/bulk", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("get: code=%d", rec.Code)
+	}
+}
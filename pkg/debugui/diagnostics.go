@@ -0,0 +1,166 @@
+package debugui
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	runtimepprof "runtime/pprof"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// diagnosticsPrefix pprof、expvar 和运行时快照接口的路径前缀, 均属于管理接口
+const diagnosticsPrefix = "/api/debug/"
+
+// snapshotProfiles 运行时快照包含的 profile 及 debug 级别; goroutine 使用 debug=2 输出完整调用栈
+var snapshotProfiles = []struct {
+	name  string
+	debug int
+	file  string
+}{
+	{"goroutine", 2, "goroutine.txt"},
+	{"heap", 0, "heap.pb.gz"},
+	{"allocs", 0, "allocs.pb.gz"},
+	{"block", 0, "block.pb.gz"},
+	{"mutex", 0, "mutex.pb.gz"},
+	{"threadcreate", 1, "threadcreate.txt"},
+}
+
+// checkDiagnostics 诊断接口会暴露调用栈和内存概况, 必须开启登录; IP 白名单只作为额外限制
+func (s *Server) checkDiagnostics() error {
+	if s.config.Diagnostics && s.auth == nil {
+		return errors.New("debugui.diagnostics requires debugui.auth")
+	}
+	return nil
+}
+
+// registerDiagnostics 注册 pprof、expvar 和快照下载接口; 命令行参数中常带有凭据, 不提供 pprof/cmdline
+func (s *Server) registerDiagnostics(mux *http.ServeMux) {
+	mux.HandleFunc("GET "+diagnosticsPrefix+"pprof/", s.handlePprof)
+	mux.HandleFunc("GET "+diagnosticsPrefix+"pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("POST "+diagnosticsPrefix+"pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("GET "+diagnosticsPrefix+"pprof/profile", s.handleCPUProfile)
+	mux.HandleFunc("GET "+diagnosticsPrefix+"pprof/trace", s.handleTrace)
+	mux.HandleFunc("GET "+diagnosticsPrefix+"vars", handleVars)
+	mux.HandleFunc("GET "+diagnosticsPrefix+"snapshot", s.handleSnapshot)
+}
+
+// handlePprof pprof 索引页和按名称的 profile; net/http/pprof 按 /debug/pprof/ 解析名称, 这里去掉 /api 前缀后转交
+func (s *Server) handlePprof(w http.ResponseWriter, r *http.Request) {
+	if strings.TrimPrefix(r.URL.Path, diagnosticsPrefix+"pprof/") == "cmdline" {
+		http.NotFound(w, r)
+		return
+	}
+	r2 := r.Clone(r.Context())
+	r2.URL.Path = strings.TrimPrefix(r.URL.Path, "/api")
+	pprof.Index(w, r2)
+}
+
+// handleVars 与 expvar.Handler 相同, 但不输出 cmdline
+func handleVars(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	fmt.Fprintf(w, "{\n")
+	first := true
+	expvar.Do(func(kv expvar.KeyValue) {
+		if kv.Key == "cmdline" {
+			return
+		}
+		if !first {
+			fmt.Fprintf(w, ",\n")
+		}
+		first = false
+		fmt.Fprintf(w, "%q: %s", kv.Key, kv.Value)
+	})
+	fmt.Fprintf(w, "\n}\n")
+}
+
+// handleCPUProfile CPU profile 和 trace 会持续采样数秒, 记录调用者便于追溯
+func (s *Server) handleCPUProfile(w http.ResponseWriter, r *http.Request) {
+	s.logDiagnostics(r, "cpu profile")
+	pprof.Profile(w, r)
+}
+
+func (s *Server) handleTrace(w http.ResponseWriter, r *http.Request) {
+	s.logDiagnostics(r, "trace")
+	pprof.Trace(w, r)
+}
+
+// handleSnapshot 下载 goroutine 调用栈、堆和阻塞 profile 以及内存用量的 tar.gz 快照
+func (s *Server) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	s.logDiagnostics(r, "snapshot")
+
+	now := time.Now().UTC()
+	var buf bytes.Buffer
+	if err := s.writeSnapshot(&buf, now); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="soclaw-diagnostics-%s.tar.gz"`, now.Format("20060102-150405")))
+	w.Write(buf.Bytes())
+}
+
+func (s *Server) writeSnapshot(out *bytes.Buffer, now time.Time) error {
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+	add := func(name string, data []byte) error {
+		hdr := &tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), ModTime: now}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	for _, p := range snapshotProfiles {
+		profile := runtimepprof.Lookup(p.name)
+		if profile == nil {
+			continue
+		}
+		var b bytes.Buffer
+		if p.name == "heap" {
+			runtime.GC() // 与 /debug/pprof/heap?gc=1 一致, 反映当前存活对象
+		}
+		if err := profile.WriteTo(&b, p.debug); err != nil {
+			return fmt.Errorf("%s profile: %w", p.name, err)
+		}
+		if err := add(p.file, b.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	info, err := json.MarshalIndent(map[string]interface{}{
+		"takenAt": now,
+		"build":   s.buildInfo,
+		"version": s.version(),
+		"stats":   s.stats(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := add("stats.json", info); err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func (s *Server) logDiagnostics(r *http.Request, kind string) {
+	logger.InfoCF("debugui", "Diagnostics requested",
+		map[string]interface{}{
+			"kind":      kind,
+			"client_ip": clientIP(r, s.config.TrustProxy),
+		})
+}
@@ -0,0 +1,104 @@
+package debugui
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestCheckDiagnostics(t *testing.T) {
+	s := NewServer(config.DebugUIConfig{Diagnostics: true}, nil, nil, nil, t.TempDir())
+	if err := s.checkDiagnostics(); err == nil {
+		t.Error("diagnostics without auth must be rejected")
+	}
+	// 只有 IP 白名单时同样拒绝
+	var err error
+	if s.adminAllowlist, err = parseAllowlist([]string{"127.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.checkDiagnostics(); err == nil {
+		t.Error("diagnostics with only admin_allowlist must be rejected")
+	}
+
+	auth, _ := newAuthTestServer(t)
+	s.auth = auth.auth
+	if err := s.checkDiagnostics(); err != nil {
+		t.Errorf("checkDiagnostics() with auth = %v", err)
+	}
+}
+
+func TestDiagnosticsRoutes(t *testing.T) {
+	s := NewServer(config.DebugUIConfig{Diagnostics: true}, nil, nil, nil, t.TempDir())
+	mux := http.NewServeMux()
+	s.registerDiagnostics(mux)
+
+	for _, path := range []string{"/api/debug/pprof/", "/api/debug/pprof/goroutine?debug=1", "/api/debug/vars"} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s = %d: %s", path, rec.Code, rec.Body.String())
+		}
+	}
+
+	// 命令行参数可能带有凭据, 不对外提供
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/debug/pprof/cmdline", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET /api/debug/pprof/cmdline = %d, want 404", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/debug/vars", nil))
+	if body := rec.Body.String(); strings.Contains(body, `"cmdline"`) || !strings.Contains(body, `"memstats"`) {
+		t.Errorf("GET /api/debug/vars = %s", body)
+	}
+
+	for _, path := range []string{"/api/debug/vars", "/api/debug/pprof/heap"} {
+		if !isAdminRoute(httptest.NewRequest(http.MethodGet, path, nil)) {
+			t.Errorf("GET %s must be an admin route", path)
+		}
+	}
+}
+
+func TestDiagnosticsSnapshot(t *testing.T) {
+	s := NewServer(config.DebugUIConfig{Diagnostics: true}, nil, nil, nil, t.TempDir())
+	var buf bytes.Buffer
+	if err := s.writeSnapshot(&buf, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(tr)
+		files[hdr.Name] = string(data)
+	}
+
+	if !strings.Contains(files["goroutine.txt"], "goroutine ") {
+		t.Errorf("goroutine.txt missing stacks: %q", files["goroutine.txt"])
+	}
+	if _, ok := files["heap.pb.gz"]; !ok {
+		t.Error("heap profile missing")
+	}
+	if !strings.Contains(files["stats.json"], `"heapAllocBytes"`) {
+		t.Errorf("stats.json = %s", files["stats.json"])
+	}
+}
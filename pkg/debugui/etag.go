@@ -0,0 +1,75 @@
+package debugui
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// etagMatches 判断 If-None-Match 是否命中给定 ETag (支持列表、弱校验和 "*")
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkETag 设置 ETag 并处理条件请求, 命中时已写入 304, 调用方应直接返回
+func checkETag(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// queryETag 以存储版本号和规范化后的查询参数生成 ETag: 同一版本下不同的筛选、分页、字段参数结果不同,
+// 不能共用 ETag; 参数按名称排序并忽略空值, 顺序不同的等价查询仍可命中 304
+func queryETag(r *http.Request, name string, version uint64, extra ...string) string {
+	q := r.URL.Query()
+	for k, vs := range q {
+		if strings.Join(vs, "") == "" {
+			delete(q, k)
+		}
+	}
+	sum := sha256.Sum256([]byte(q.Encode()))
+	etag := fmt.Sprintf("%s-%d-%s", name, version, hex.EncodeToString(sum[:6]))
+	for _, e := range extra {
+		etag += "-" + e
+	}
+	return `"` + etag + `"`
+}
+
+// writeJSONWithETag 编码 JSON, 以内容哈希作为 ETag 输出, 支持 304
+func writeJSONWithETag(w http.ResponseWriter, r *http.Request, v interface{}) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	if checkETag(w, r, etag) {
+		return
+	}
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,79 @@
+package debugui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+func TestHandleProposals_ConditionalGet(t *testing.T) {
+	ps := secops.NewProposalService()
+	ps.Create(secops.NewProposal("risk", "t", "s", nil))
+	s := NewServer(config.DebugUIConfig{}, nil, ps, nil, "")
+
+	rec := httptest.NewRecorder()
+	s.handleProposals(rec, httptest.NewRequest(http.MethodGet, "/api/proposals", nil))
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" {
+		t.Fatalf("first GET: code=%d etag=%q", rec.Code, etag)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/proposals", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	s.handleProposals(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("unchanged store: code=%d, want 304", rec.Code)
+	}
+
+	ps.Create(secops.NewProposal("weak", "t2", "s2", nil))
+	rec = httptest.NewRecorder()
+	s.handleProposals(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("changed store: code=%d, want 200", rec.Code)
+	}
+}
+
+func TestHandleProposals_ETagVariesByQuery(t *testing.T) {
+	ps := secops.NewProposalService()
+	ps.Create(secops.NewProposal("risk", "t", "s", nil))
+	ps.Create(secops.NewProposal("weak", "t2", "s2", nil))
+	s := NewServer(config.DebugUIConfig{}, nil, ps, nil, "")
+
+	get := func(target, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		s.handleProposals(rec, req)
+		return rec
+	}
+
+	etag := get("/api/proposals?type=risk&limit=1", "").Header().Get("ETag")
+	if rec := get("/api/proposals?type=weak&limit=1", etag); rec.Code != http.StatusOK {
+		t.Errorf("different filter: code=%d, want 200", rec.Code)
+	}
+	if rec := get("/api/proposals?type=risk&limit=1&fields=id", etag); rec.Code != http.StatusOK {
+		t.Errorf("different fields: code=%d, want 200", rec.Code)
+	}
+	// 参数顺序和空值不影响 ETag
+	if rec := get("/api/proposals?limit=1&status=&type=risk", etag); rec.Code != http.StatusNotModified {
+		t.Errorf("equivalent query: code=%d, want 304", rec.Code)
+	}
+}
+
+func TestEtagMatches(t *testing.T) {
+	if !etagMatches(`W/"abc", "def"`, `"abc"`) {
+		t.Error("weak etag in list should match")
+	}
+	if !etagMatches("*", `"x"`) {
+		t.Error("* should match")
+	}
+	if etagMatches(`"abc"`, `"abd"`) {
+		t.Error("different etags should not match")
+	}
+}
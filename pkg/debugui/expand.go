@@ -0,0 +1,92 @@
+package debugui
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+// maxRelatedProposals 展开相关提案时返回的最大条数
+const maxRelatedProposals = 20
+
+// expandable 提案详情支持展开的关联实体
+var expandable = map[string]bool{"run": true, "case": true, "related": true}
+
+// proposalRef 关联提案的摘要信息
+type proposalRef struct {
+	ID        string `json:"id"`
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    string `json:"status"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// caseJSON 展开的案件
+type caseJSON struct {
+	ID        string        `json:"id"`
+	Proposals []proposalRef `json:"proposals"`
+}
+
+// proposalExpansion 提案详情中展开的关联实体
+type proposalExpansion struct {
+	Run     *secops.Run   `json:"run,omitempty"`
+	Case    *caseJSON     `json:"case,omitempty"`
+	Related []proposalRef `json:"related,omitempty"`
+}
+
+// parseExpand 解析 ?expand=run,case,related
+func parseExpand(r *http.Request) (map[string]bool, error) {
+	result := make(map[string]bool)
+	raw := r.URL.Query().Get("expand")
+	if raw == "" {
+		return result, nil
+	}
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !expandable[name] {
+			return nil, fmt.Errorf("unknown expand: %s (supported: run, case, related)", name)
+		}
+		result[name] = true
+	}
+	return result, nil
+}
+
+// expandProposal 加载提案的关联实体
+func (s *Server) expandProposal(p *secops.Proposal, expand map[string]bool) proposalExpansion {
+	var e proposalExpansion
+
+	if expand["run"] && p.RunID != "" && s.secopsService != nil {
+		if run, ok := s.secopsService.Run(p.RunID); ok {
+			e.Run = run
+		}
+	}
+
+	if expand["case"] && p.CaseID != "" {
+		e.Case = &caseJSON{ID: p.CaseID, Proposals: toProposalRefs(s.proposalService.Case(p.CaseID))}
+	}
+
+	if expand["related"] {
+		e.Related = toProposalRefs(s.proposalService.Related(p.ID, maxRelatedProposals))
+	}
+
+	return e
+}
+
+func toProposalRefs(proposals []*secops.Proposal) []proposalRef {
+	refs := make([]proposalRef, len(proposals))
+	for i, p := range proposals {
+		refs[i] = proposalRef{
+			ID:        p.ID,
+			Type:      p.Type,
+			Title:     p.Title,
+			Status:    string(p.Status),
+			CreatedAt: p.CreatedAt.Format("2006-01-02 15:04:05"),
+		}
+	}
+	return refs
+}
@@ -0,0 +1,50 @@
+package debugui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+func TestHandleProposal_Expand(t *testing.T) {
+	ps := secops.NewProposalService()
+	a := secops.NewProposal("risk", "A", "", map[string]interface{}{"host": "shop.example.com"})
+	a.CaseID = "case-1"
+	b := secops.NewProposal("weak", "B", "", map[string]interface{}{"host": "shop.example.com"})
+	c := secops.NewProposal("risk", "C", "", map[string]interface{}{"host": "other"})
+	c.CaseID = "case-1"
+	ps.Create(a)
+	ps.Create(b)
+	ps.Create(c)
+
+	s := NewServer(config.DebugUIConfig{}, nil, ps, nil, "")
+	rec := httptest.NewRecorder()
+	s.handleProposal(rec, httptest.NewRequest(http.MethodGet, "/api/proposal/"+a.ID+"?expand=case,related", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var got struct {
+		Case    caseJSON      `json:"case"`
+		Related []proposalRef `json:"related"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Case.ID != "case-1" || len(got.Case.Proposals) != 2 {
+		t.Errorf("unexpected case: %+v", got.Case)
+	}
+	if len(got.Related) != 1 || got.Related[0].ID != b.ID {
+		t.Errorf("unexpected related: %+v", got.Related)
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleProposal(rec, httptest.NewRequest(http.MethodGet, "/api/proposal/"+a.ID+"?expand=owner", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown expand: status = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,404 @@
+package debugui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+// exportDocument 导出文档; 各部分为 renderMarkdown 的输出, 打印时每部分另起一页
+type exportDocument struct {
+	Title       string
+	Subtitle    string
+	GeneratedAt time.Time
+	Sections    []template.HTML
+	AutoPrint   bool
+}
+
+// reportFile 运营活动 report 钩子生成的报告文件
+type reportFile struct {
+	Name       string    `json:"name"`
+	Size       int64     `json:"size"`
+	ModifiedAt time.Time `json:"modifiedAt"`
+}
+
+// reportsDir report 钩子的输出目录
+func (s *Server) reportsDir() string {
+	return filepath.Join(s.workspace, "secops", "reports")
+}
+
+// handleExportProposal GET /api/export/proposal/{id} 导出单个提案
+func (s *Server) handleExportProposal(w http.ResponseWriter, r *http.Request) {
+	if s.proposalService == nil {
+		http.Error(w, "proposal service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	id := r.PathValue("id")
+	p, ok := s.proposalService.Get(id)
+	if !ok {
+		http.Error(w, "proposal not found", http.StatusNotFound)
+		return
+	}
+
+	s.writeExport(w, r, "proposal-"+id, exportDocument{
+		Title:    p.Title,
+		Subtitle: "安全运营提案 " + p.ID,
+		Sections: []template.HTML{template.HTML(renderMarkdown(proposalMarkdown(p)))},
+	})
+}
+
+// handleExportCase GET /api/export/case/{id} 导出案件: 概览与时间线, 之后每个提案一页
+func (s *Server) handleExportCase(w http.ResponseWriter, r *http.Request) {
+	if s.proposalService == nil {
+		http.Error(w, "proposal service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	caseID := r.PathValue("id")
+	proposals := s.proposalService.Case(caseID)
+	if len(proposals) == 0 {
+		http.Error(w, "case not found", http.StatusNotFound)
+		return
+	}
+
+	var overview strings.Builder
+	overview.WriteString("## 提案概览\n\n| # | 类型 | 级别 | 状态 | 标题 |\n|---|---|---|---|---|\n")
+	for i, p := range proposals {
+		fmt.Fprintf(&overview, "| P%d | %s | %s | %s | %s |\n", i+1, p.Type, p.Severity, p.Status, exportCell(p.Title))
+	}
+	if tl, err := s.proposalService.BuildTimeline(caseID, nil); err == nil {
+		overview.WriteString("\n" + demoteHeadings(tl.Markdown()))
+	}
+
+	sections := []template.HTML{template.HTML(renderMarkdown(overview.String()))}
+	for _, p := range proposals {
+		sections = append(sections, template.HTML(renderMarkdown(proposalMarkdown(p))))
+	}
+	s.writeExport(w, r, "case-"+caseID, exportDocument{
+		Title:    "案件 " + caseID,
+		Subtitle: fmt.Sprintf("共 %d 个提案", len(proposals)),
+		Sections: sections,
+	})
+}
+
+// handleReports GET /api/reports 列出已生成的报告, 最新的在前
+func (s *Server) handleReports(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	reports := []reportFile{}
+	if s.workspace != "" {
+		entries, err := os.ReadDir(s.reportsDir())
+		if err != nil && !os.IsNotExist(err) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, e := range entries {
+			info, err := e.Info()
+			if err != nil || !info.Mode().IsRegular() || strings.HasPrefix(e.Name(), ".") {
+				continue
+			}
+			reports = append(reports, reportFile{Name: e.Name(), Size: info.Size(), ModifiedAt: info.ModTime()})
+		}
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].ModifiedAt.After(reports[j].ModifiedAt)
+	})
+	s.writeList(w, reports, len(reports), "")
+}
+
+// handleExportReport GET /api/export/report/{name} 导出报告
+//
+// Markdown/文本报告按 Markdown 渲染; 其他格式 (如 HTML、JSON) 由模板拼接了未转义的数据, 作为代码块原样展示。
+func (s *Server) handleExportReport(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if s.workspace == "" || name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		http.Error(w, "report not found", http.StatusNotFound)
+		return
+	}
+	content, err := os.ReadFile(filepath.Join(s.reportsDir(), name))
+	if err != nil {
+		http.Error(w, "report not found", http.StatusNotFound)
+		return
+	}
+
+	src := string(content)
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".md", ".markdown", ".txt", "":
+	default:
+		src = "```\n" + strings.ReplaceAll(src, "```", "'''") + "\n```"
+	}
+	s.writeExport(w, r, strings.TrimSuffix(name, filepath.Ext(name)), exportDocument{
+		Title:    name,
+		Subtitle: "运营报告",
+		Sections: []template.HTML{template.HTML(renderMarkdown(src))},
+	})
+}
+
+// handleExportProposals GET /api/proposals/export 导出满足条件的全部提案 (含详情和决策), 用于合规周报
+//
+// format 为 csv (默认) 或 json; 支持 /api/proposals 的筛选和排序参数, from、to 等同于 since、until, 不分页。
+func (s *Server) handleExportProposals(w http.ResponseWriter, r *http.Request) {
+	if s.proposalService == nil {
+		http.Error(w, "proposal service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	q := r.URL.Query()
+	format := q.Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "json" {
+		http.Error(w, "format must be csv or json", http.StatusBadRequest)
+		return
+	}
+	filter, err := parseProposalFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if v := q.Get("from"); v != "" {
+		if filter.Since, err = parseQueryTime(v); err != nil {
+			http.Error(w, "invalid from: "+v, http.StatusBadRequest)
+			return
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		if filter.Until, err = parseQueryTime(v); err != nil {
+			http.Error(w, "invalid to: "+v, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="proposals-%s.%s"`, time.Now().Format("20060102"), format))
+	n, err := secops.ExportProposals(s.proposalService, w, filter, format)
+	if err != nil {
+		// 已开始写出, 只能记录日志
+		logger.WarnCF("debugui", "Proposal export failed",
+			map[string]interface{}{
+				"format":   format,
+				"exported": n,
+				"error":    err.Error(),
+			})
+	}
+}
+
+// writeExport 按 format 输出: pdf (默认, 需要 Chromium) 或 html (打印版页面, print=1 时自动弹出打印对话框)
+func (s *Server) writeExport(w http.ResponseWriter, r *http.Request, name string, doc exportDocument) {
+	q := r.URL.Query()
+	format := q.Get("format")
+	if format == "" {
+		format = "pdf"
+	}
+	if format != "pdf" && format != "html" {
+		http.Error(w, "format must be pdf or html", http.StatusBadRequest)
+		return
+	}
+
+	doc.GeneratedAt = time.Now()
+	doc.AutoPrint = format == "html" && q.Get("print") == "1"
+	var buf bytes.Buffer
+	if err := exportTemplate.Execute(&buf, doc); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if format == "html" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(buf.Bytes())
+		return
+	}
+
+	if s.pdf == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotImplemented)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": "pdf rendering requires Chromium (debugui.pdf.chrome_path); use format=html and print to PDF from the browser",
+		})
+		return
+	}
+	pdf, err := s.pdf.render(r.Context(), buf.Bytes())
+	if err != nil {
+		logger.WarnCF("debugui", "PDF export failed",
+			map[string]interface{}{
+				"name":  name,
+				"error": err.Error(),
+			})
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pdf"`, exportFileName(name)))
+	w.Write(pdf)
+}
+
+// proposalMarkdown 提案的完整内容, 供导出使用
+func proposalMarkdown(p *secops.Proposal) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "## %s\n\n", p.Title)
+
+	sb.WriteString("| 字段 | 值 |\n|---|---|\n")
+	row := func(k, v string) {
+		if v != "" {
+			fmt.Fprintf(&sb, "| %s | %s |\n", k, exportCell(v))
+		}
+	}
+	row("提案 ID", p.ID)
+	row("类型", p.Type)
+	row("严重级别", p.Severity)
+	row("状态", string(p.Status))
+	row("案件", p.CaseID)
+	row("Agent 建议", p.Recommendation)
+	row("ATT&CK", strings.Join(p.Techniques, ", "))
+	row("创建时间", p.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+	if p.CreatedBy != nil {
+		row("创建者", p.CreatedBy.Name+" ("+p.CreatedBy.Via+")")
+	}
+
+	if p.Summary != "" {
+		fmt.Fprintf(&sb, "\n### 摘要\n\n%s\n", p.Summary)
+	}
+
+	if len(p.Details) > 0 {
+		keys := make([]string, 0, len(p.Details))
+		for k := range p.Details {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		sb.WriteString("\n### 详细信息\n\n| 字段 | 值 |\n|---|---|\n")
+		for _, k := range keys {
+			v := p.Details[k]
+			if _, ok := v.(string); !ok {
+				b, _ := json.Marshal(v)
+				v = string(b)
+			}
+			fmt.Fprintf(&sb, "| %s | %s |\n", exportCell(k), exportCell(fmt.Sprint(v)))
+		}
+	}
+
+	if len(p.Evidence) > 0 {
+		sb.WriteString("\n### 证据\n")
+		for _, e := range p.Evidence {
+			fmt.Fprintf(&sb, "\n#### %s\n\n```%s\n%s\n```\n", e.Label, e.ContentType, strings.ReplaceAll(e.Content, "```", "'''"))
+		}
+	}
+
+	if rp := p.Reproduction; rp != nil {
+		sb.WriteString("\n### 复现命令\n\n凭据和个人信息已替换为占位符")
+		if len(rp.Placeholders) > 0 {
+			fmt.Fprintf(&sb, ", 验证前请填入: %s", strings.Join(rp.Placeholders, ", "))
+		}
+		fmt.Fprintf(&sb, "\n\n```bash\n%s\n```\n", strings.ReplaceAll(rp.Curl, "```", "'''"))
+	}
+
+	if d := p.Decision; d != nil {
+		sb.WriteString("\n### 决策\n\n")
+		fmt.Fprintf(&sb, "- 动作: %s\n", d.Action)
+		if d.By != nil && d.By.Name != "" {
+			fmt.Fprintf(&sb, "- 决策者: %s (%s)\n", d.By.Name, d.By.Via)
+		}
+		fmt.Fprintf(&sb, "- 时间: %s\n", d.DecidedAt.Local().Format("2006-01-02 15:04:05"))
+		if d.Reason != "" {
+			fmt.Fprintf(&sb, "- 理由: %s\n", d.Reason)
+		}
+		if d.Override {
+			sb.WriteString("- 与 Agent 建议相反\n")
+		}
+	}
+
+	if e := p.Execution; e != nil {
+		sb.WriteString("\n### 执行\n\n")
+		fmt.Fprintf(&sb, "- API: %s\n- 状态: %s\n- 次数: %d\n", e.API, e.Status, e.Attempts)
+		if e.Error != "" {
+			fmt.Fprintf(&sb, "- 错误: %s\n", e.Error)
+		}
+	}
+	return sb.String()
+}
+
+// demoteHeadings 标题降一级, 嵌入的文档 (如时间线) 不与导出文档的标题同级
+func demoteHeadings(md string) string {
+	lines := strings.Split(md, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "#") {
+			lines[i] = "#" + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// exportCell 表格单元格: 转义竖线, 换行合并为空格
+func exportCell(s string) string {
+	return strings.ReplaceAll(strings.Join(strings.Fields(s), " "), "|", `\|`)
+}
+
+// exportFileName 下载文件名, 去掉引号、路径分隔符和控制字符
+func exportFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '"' || r == '/' || r == '\\' || r < 0x20 {
+			return '-'
+		}
+		return r
+	}, name)
+}
+
+var exportTemplate = template.Must(template.New("export").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="UTF-8">
+<title>{{.Title}}</title>
+<style>
+    @page {
+        size: A4;
+        margin: 18mm 16mm 20mm;
+        @bottom-right { content: counter(page) " / " counter(pages); font-size: 9pt; color: #6b7280; }
+    }
+    body { font-family: "Noto Sans CJK SC", "Source Han Sans SC", "PingFang SC", "Microsoft YaHei", sans-serif;
+           font-size: 10.5pt; line-height: 1.6; color: #111827; margin: 0 auto; max-width: 180mm; }
+    header { border-bottom: 2px solid #1f2937; margin-bottom: 12pt; padding-bottom: 6pt; }
+    header h1 { font-size: 18pt; margin: 0; }
+    header p { margin: 2pt 0 0; color: #4b5563; font-size: 9.5pt; }
+    section + section { break-before: page; }
+    h1, h2, h3, h4 { break-after: avoid; line-height: 1.3; }
+    h2 { font-size: 14pt; margin: 14pt 0 6pt; }
+    h3 { font-size: 12pt; margin: 12pt 0 4pt; }
+    h4 { font-size: 10.5pt; margin: 8pt 0 4pt; color: #374151; }
+    table { width: 100%; border-collapse: collapse; margin: 6pt 0; font-size: 9.5pt; }
+    th, td { border: 1px solid #d1d5db; padding: 3pt 5pt; text-align: left; vertical-align: top; word-break: break-all; }
+    th { background: #f3f4f6; }
+    tr { break-inside: avoid; }
+    pre { background: #f9fafb; border: 1px solid #e5e7eb; padding: 6pt; font-size: 8.5pt;
+          white-space: pre-wrap; word-break: break-all; }
+    code { font-family: "JetBrains Mono", "DejaVu Sans Mono", monospace; }
+    blockquote { border-left: 3px solid #d1d5db; margin: 6pt 0; padding-left: 8pt; color: #4b5563; }
+    @media screen { body { padding: 16mm 0; } }
+</style>
+</head>
+<body>
+<header>
+    <h1>{{.Title}}</h1>
+    <p>{{.Subtitle}} · 生成于 {{.GeneratedAt.Format "2006-01-02 15:04:05 MST"}}</p>
+</header>
+{{range .Sections}}<section>
+{{.}}
+</section>
+{{end}}{{if .AutoPrint}}<script>window.addEventListener('load', () => window.print());</script>
+{{end}}</body>
+</html>
+`))
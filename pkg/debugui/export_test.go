@@ -0,0 +1,204 @@
+package debugui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+func newExportTestServer(t *testing.T) (*Server, *secops.ProposalService, http.Handler) {
+	t.Helper()
+	ps := secops.NewProposalService()
+	s := NewServer(config.DebugUIConfig{}, nil, ps, nil, t.TempDir())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/export/proposal/{id}", s.handleExportProposal)
+	mux.HandleFunc("GET /api/export/case/{id}", s.handleExportCase)
+	mux.HandleFunc("GET /api/export/report/{name}", s.handleExportReport)
+	mux.HandleFunc("GET /api/reports", s.handleReports)
+	mux.HandleFunc("GET /api/proposals/export", s.handleExportProposals)
+	return s, ps, mux
+}
+
+func TestExportProposalAndCase(t *testing.T) {
+	_, ps, h := newExportTestServer(t)
+	a := secops.NewProposal("risk", "SQL 注入 <script>", "攻击者利用 id 参数注入", map[string]interface{}{"ip": "203.0.113.45", "count": 12})
+	a.CaseID = "c1"
+	a.AddEvidence("访问日志", "2026-10-16T02:00:05Z GET /product?id=1' | 500")
+	aID := ps.Create(a)
+	b := secops.NewProposal("host", "WebShell 落地", "", nil)
+	b.CaseID = "c1"
+	ps.Create(b)
+	if err := ps.Ignore(aID, secops.DecisionRequest{Reason: "误报", By: secops.Actor{Name: "password:alice", Via: secops.ViaDebugUI}}); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/api/export/proposal/" + aID + "?format=html&print=1")
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("code=%d %s", rec.Code, body)
+	}
+	for _, want := range []string{
+		"<title>SQL 注入 &lt;script&gt;</title>",
+		"<td>ip</td><td>203.0.113.45</td>",
+		"GET /product?id=1&#39; | 500",
+		"<li>决策者: password:alice (debugui)</li>",
+		"window.print()",
+		"@page",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("proposal export missing %q", want)
+		}
+	}
+	if strings.Contains(body, "<script>\n") || strings.Contains(body, "注入 <script>") {
+		t.Error("proposal title must be escaped")
+	}
+
+	rec = get("/api/export/case/c1?format=html")
+	body = rec.Body.String()
+	if rec.Code != http.StatusOK || strings.Count(body, "<section>") != 3 ||
+		!strings.Contains(body, "<h2>事件时间线: c1</h2>") || !strings.Contains(body, "<td>P2</td><td>host</td>") {
+		t.Errorf("case export: code=%d\n%s", rec.Code, body)
+	}
+	if strings.Contains(body, "window.print()") {
+		t.Error("print script should only be added with print=1")
+	}
+
+	// 未找到 Chromium 时 PDF 不可用
+	if rec = get("/api/export/proposal/" + aID); rec.Code != http.StatusNotImplemented {
+		t.Errorf("pdf without chromium: code=%d", rec.Code)
+	}
+	if rec = get("/api/export/case/missing?format=html"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown case: code=%d", rec.Code)
+	}
+	if rec = get("/api/export/proposal/" + aID + "?format=docx"); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown format: code=%d", rec.Code)
+	}
+}
+
+func TestExportReports(t *testing.T) {
+	s, _, h := newExportTestServer(t)
+	if err := os.MkdirAll(s.reportsDir(), 0700); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(s.reportsDir(), "weekly-20261012-090000.md"), []byte("# 周报\n\n| 指标 | 数量 |\n|---|---|\n| 新提案 | 42 |\n"), 0600)
+	os.WriteFile(filepath.Join(s.reportsDir(), "weekly.html"), []byte("<b>raw</b>"), 0600)
+	os.WriteFile(filepath.Join(filepath.Dir(s.reportsDir()), "secret.md"), []byte("secret"), 0600)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/reports", nil))
+	var list struct {
+		Items []reportFile `json:"items"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil || len(list.Items) != 2 {
+		t.Fatalf("reports = %+v, %v", list, err)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+	if rec := get("/api/export/report/weekly-20261012-090000.md?format=html"); !strings.Contains(rec.Body.String(), "<td>新提案</td><td>42</td>") {
+		t.Errorf("markdown report: %s", rec.Body)
+	}
+	if rec := get("/api/export/report/weekly.html?format=html"); !strings.Contains(rec.Body.String(), "&lt;b&gt;raw&lt;/b&gt;") {
+		t.Errorf("html report must be shown as code: %s", rec.Body)
+	}
+	for _, path := range []string{"/api/export/report/..%2Fsecret.md?format=html", "/api/export/report/missing.md?format=html"} {
+		if rec := get(path); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: code=%d", path, rec.Code)
+		}
+	}
+}
+
+func TestExportPDF(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake chromium is a shell script")
+	}
+	// 模拟 Chromium: 将 --print-to-pdf 指定的文件写为固定内容
+	chrome := filepath.Join(t.TempDir(), "chromium")
+	script := "#!/bin/sh\nfor a in \"$@\"; do case \"$a\" in --print-to-pdf=*) printf '%%PDF-1.7 fake' > \"${a#--print-to-pdf=}\";; esac; done\n"
+	if err := os.WriteFile(chrome, []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	s, ps, h := newExportTestServer(t)
+	pr, err := newPDFRenderer(config.PDFExportConfig{ChromePath: chrome})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.pdf = pr
+	id := ps.Create(secops.NewProposal("risk", "撞库", "", nil))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/export/proposal/"+id, nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/pdf" ||
+		rec.Header().Get("Content-Disposition") != `attachment; filename="proposal-`+id+`.pdf"` || rec.Body.String() != "%PDF-1.7 fake" {
+		t.Errorf("pdf export: code=%d headers=%v body=%q", rec.Code, rec.Header(), rec.Body)
+	}
+
+	if _, err := newPDFRenderer(config.PDFExportConfig{ChromePath: filepath.Join(t.TempDir(), "missing")}); err == nil {
+		t.Error("expected error for missing chrome_path")
+	}
+	if _, err := newPDFRenderer(config.PDFExportConfig{Timeout: "soon"}); err == nil {
+		t.Error("expected error for invalid timeout")
+	}
+}
+
+func TestExportProposals(t *testing.T) {
+	_, ps, h := newExportTestServer(t)
+	ps.Create(secops.NewProposal("risk", "SQL 注入", "", map[string]interface{}{"ip": "203.0.113.45"}))
+
+	for _, c := range []struct {
+		query, contentType, want string
+		code                     int
+	}{
+		{"", "text/csv; charset=utf-8", "SQL 注入", http.StatusOK},
+		{"?format=json&status=pending&from=2000-01-01", "application/json", `"ip":"203.0.113.45"`, http.StatusOK},
+		{"?format=json&to=2000-01-01", "application/json", "[\n]\n", http.StatusOK},
+		{"?format=xlsx", "", "format must be csv or json", http.StatusBadRequest},
+		{"?from=yesterday", "", "invalid from", http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/proposals/export"+c.query, nil))
+		if rec.Code != c.code || !strings.Contains(rec.Body.String(), c.want) {
+			t.Errorf("%s: code=%d body=%q", c.query, rec.Code, rec.Body.String())
+			continue
+		}
+		if c.contentType != "" && rec.Header().Get("Content-Type") != c.contentType {
+			t.Errorf("%s: content type = %s", c.query, rec.Header().Get("Content-Type"))
+		}
+		if c.code == http.StatusOK && !strings.HasPrefix(rec.Header().Get("Content-Disposition"), `attachment; filename="proposals-`) {
+			t.Errorf("%s: content disposition = %s", c.query, rec.Header().Get("Content-Disposition"))
+		}
+	}
+}
+
+func TestProposalMarkdownReproduction(t *testing.T) {
+	p := secops.NewProposal("weak", "用户导出接口越权", "", nil)
+	p.Reproduction = &secops.Reproduction{
+		Curl:         "curl 'https://api.example.com/export' \\\n  -H 'Authorization: Bearer <AUTHORIZATION_1>'",
+		Placeholders: []string{"<AUTHORIZATION_1>"},
+		Source:       secops.ReproductionFromEvidence,
+	}
+	md := proposalMarkdown(p)
+	if !strings.Contains(md, "### 复现命令") || !strings.Contains(md, "验证前请填入: <AUTHORIZATION_1>") ||
+		!strings.Contains(md, "```bash\n"+p.Reproduction.Curl+"\n```") {
+		t.Errorf("markdown = %s", md)
+	}
+}
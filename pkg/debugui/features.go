@@ -0,0 +1,64 @@
+package debugui
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+// handleFeatures 列出功能开关的生效值、来源和运行时覆盖
+func (s *Server) handleFeatures(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"items": s.secopsService.FeatureFlags(),
+	})
+}
+
+// handleFeature 设置或清除功能开关的运行时覆盖; activity 为空时作用于整个实例, enabled 为 null 时清除覆盖
+func (s *Server) handleFeature(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPut {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.secopsService == nil {
+		http.Error(w, "secops service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req struct {
+		Activity string `json:"activity"`
+		Enabled  *bool  `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	name := r.PathValue("name")
+	if err := s.secopsService.SetFeatureOverride(name, req.Activity, req.Enabled, s.requestActor(r).Name); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	for _, st := range s.secopsService.FeatureFlags() {
+		if st.Name == name {
+			json.NewEncoder(w).Encode(st)
+			return
+		}
+	}
+}
+
+// streamingChatEnabled 是否开放流式对话; 关闭时 /api/chat/stream 返回 404, 前端回退到整体返回
+func (s *Server) streamingChatEnabled() bool {
+	return s.secopsService.FeatureEnabled(secops.FeatureStreamingChat, "")
+}
@@ -0,0 +1,52 @@
+package debugui
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+// feishuCallbackPath 飞书消息卡片回调地址, 在飞书开放平台应用的「消息卡片请求网址」中配置
+const feishuCallbackPath = "/api/notify/feishu/callback"
+
+// handleFeishuCallback POST 飞书卡片按钮回调, 映射为提案的确认/忽略
+//
+// 不要求登录, 由 secops 校验请求体中的 Verification Token; 响应体为飞书要求的格式 (challenge 或更新后的卡片)。
+func (s *Server) handleFeishuCallback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.secopsService == nil {
+		http.Error(w, "secops service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	resp, err := s.secopsService.FeishuCallback(body)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, secops.ErrFeishuToken) {
+			status = http.StatusUnauthorized
+			logger.WarnCF("debugui", "Feishu callback rejected",
+				map[string]interface{}{
+					"client_ip": clientIP(r, s.config.TrustProxy),
+				})
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
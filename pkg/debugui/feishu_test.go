@@ -0,0 +1,71 @@
+package debugui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/agent"
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+func TestHandleFeishuCallback(t *testing.T) {
+	workspace := t.TempDir()
+	msgBus := bus.NewMessageBus()
+	al := agent.NewAgentLoop(&config.Config{
+		Agents: config.AgentsConfig{Defaults: config.AgentDefaults{Workspace: workspace, Model: "test-model"}},
+	}, msgBus, nil)
+	svc, err := secops.NewService(&config.SecOpsConfig{
+		Enabled: true,
+		Notifications: config.NotificationConfig{
+			Targets: map[string]config.NotifyTargetConfig{
+				"lark": {Type: secops.NotifyTargetFeishu, URL: "https://open.feishu.cn/open-apis/bot/v2/hook/x", VerificationToken: "vt"},
+			},
+		},
+	}, al, msgBus, workspace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := svc.ProposalService().Create(secops.NewProposal("risk", "撞库", "", nil))
+
+	// 开启登录和管理白名单, 飞书回调仍可直接访问
+	s := NewServer(config.DebugUIConfig{}, nil, svc.ProposalService(), svc, "")
+	if s.auth, err = newAuthManager(config.DebugUIAuthConfig{Token: "s3cret-token"}, false); err != nil {
+		t.Fatal(err)
+	}
+	if s.adminAllowlist, err = parseAllowlist([]string{"10.10.0.0/24"}); err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(feishuCallbackPath, s.handleFeishuCallback)
+	h := s.withAdminAllowlist(s.withAuth(mux))
+
+	post := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, feishuCallbackPath, strings.NewReader(body))
+		r.RemoteAddr = "203.0.113.9:1234"
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+
+	if rec := post(`{"type":"url_verification","challenge":"c1","token":"vt"}`); rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"challenge":"c1"}` {
+		t.Errorf("url_verification: code=%d body=%s", rec.Code, rec.Body)
+	}
+	if rec := post(`{"type":"url_verification","challenge":"c1","token":"x"}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong token: code=%d", rec.Code)
+	}
+	if rec := post(`not json`); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid body: code=%d", rec.Code)
+	}
+
+	rec := post(`{"open_id":"ou_alice","token":"vt","action":{"value":{"proposal_id":"` + id + `","action":"accept"}}}`)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "已确认") {
+		t.Errorf("accept: code=%d body=%s", rec.Code, rec.Body)
+	}
+	if p, _ := svc.ProposalService().Get(id); p.Decision == nil || p.Decision.By == nil || p.Decision.By.Via != secops.ViaFeishu {
+		t.Errorf("decision = %+v", p.Decision)
+	}
+}
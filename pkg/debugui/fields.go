@@ -0,0 +1,61 @@
+package debugui
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// parseFields 解析 ?fields=id,title,status 字段选择参数, 未指定时返回 nil
+func parseFields(r *http.Request) []string {
+	raw := r.URL.Query().Get("fields")
+	if raw == "" {
+		return nil
+	}
+
+	var fields []string
+	for _, f := range strings.Split(raw, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// selectFields 仅保留对象中指定的顶层字段; 列表会逐项处理
+func selectFields(v interface{}, fields []string) (interface{}, error) {
+	if len(fields) == 0 {
+		return v, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+
+	return pickFields(generic, fields), nil
+}
+
+func pickFields(v interface{}, fields []string) interface{} {
+	switch val := v.(type) {
+	case []interface{}:
+		for i, item := range val {
+			val[i] = pickFields(item, fields)
+		}
+		return val
+	case map[string]interface{}:
+		picked := make(map[string]interface{}, len(fields))
+		for _, f := range fields {
+			if fv, ok := val[f]; ok {
+				picked[f] = fv
+			}
+		}
+		return picked
+	}
+	return v
+}
@@ -0,0 +1,31 @@
+package debugui
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+func TestSelectFields_Proposal(t *testing.T) {
+	p := secops.NewProposal("risk", "SQL 注入", "summary", map[string]interface{}{"evidence": "big blob"})
+	p.ID = "p1"
+
+	got, err := selectFields(p, []string{"id", "title", "status", "missing"})
+	if err != nil {
+		t.Fatalf("selectFields: %v", err)
+	}
+
+	want := map[string]interface{}{"id": "p1", "title": "SQL 注入", "status": "pending"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("selectFields = %v, want %v", got, want)
+	}
+}
+
+func TestSelectFields_NoFieldsReturnsInput(t *testing.T) {
+	in := []int{1, 2}
+	got, _ := selectFields(in, nil)
+	if !reflect.DeepEqual(got, in) {
+		t.Errorf("selectFields without fields should return input unchanged")
+	}
+}
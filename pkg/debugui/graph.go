@@ -0,0 +1,48 @@
+package debugui
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+// handleGraph GET /api/graph 查询实体关系图
+//
+// 参数: node (如 ip:203.0.113.45、proposal:<id>), depth (1-3, 默认 1), limit (节点数上限, 默认 50)
+func (s *Server) handleGraph(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if s.proposalService == nil {
+		http.Error(w, "proposal service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	q := r.URL.Query()
+	var depth, limit int
+	for name, dst := range map[string]*int{"depth": &depth, "limit": &limit} {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "invalid "+name, http.StatusBadRequest)
+			return
+		}
+		*dst = n
+	}
+
+	sub, err := s.proposalService.Graph(q.Get("node"), depth, limit)
+	if errors.Is(err, secops.ErrGraphNodeNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(sub)
+}
@@ -0,0 +1,46 @@
+package debugui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+func TestGraph(t *testing.T) {
+	ps := secops.NewProposalService()
+	ps.Create(&secops.Proposal{
+		ID: "p1", Title: "扫描 /admin", Status: secops.ProposalStatusPending,
+		Details: map[string]interface{}{"src_ip": "203.0.113.45", "host": "shop.example.com"},
+	})
+	s := NewServer(config.DebugUIConfig{}, nil, ps, nil, t.TempDir())
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/graph", s.handleGraph)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/graph?node=ip:203.0.113.45&depth=2", nil))
+	var sub secops.Subgraph
+	if err := json.NewDecoder(rec.Body).Decode(&sub); err != nil {
+		t.Fatal(err)
+	}
+	if sub.Root != "ip:203.0.113.45" || sub.Depth != 2 || len(sub.Nodes) != 3 || len(sub.Edges) != 3 {
+		t.Errorf("graph = %+v", sub)
+	}
+
+	for query, want := range map[string]int{
+		"node=ip:192.0.2.1":               http.StatusNotFound,
+		"node=alice":                      http.StatusBadRequest,
+		"node=ip:203.0.113.45&depth=x":    http.StatusBadRequest,
+		"node=ip:203.0.113.45&limit=0":    http.StatusBadRequest,
+		"node=proposal:p1&depth=1&limit=": http.StatusOK,
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/graph?"+query, nil))
+		if rec.Code != want {
+			t.Errorf("%s: status = %d, want %d", query, rec.Code, want)
+		}
+	}
+}
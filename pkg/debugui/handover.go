@@ -0,0 +1,50 @@
+package debugui
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+)
+
+// handleHandover POST /api/handover 由 Agent 生成交接班摘要
+//
+// 请求体可选: {"since": "2026-03-01T09:00:00+08:00" 或 "12h", "post": true};
+// since 为空时取当前班次的开始, post 为 true 时同时发往 handover.targets 配置的通知目标
+func (s *Server) handleHandover(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.secopsService == nil {
+		http.Error(w, "secops service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req struct {
+		Since string `json:"since"`
+		Post  bool   `json:"post"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var since time.Time
+	if d, err := time.ParseDuration(req.Since); err == nil && d > 0 {
+		since = time.Now().Add(-d)
+	} else if since, err = parseQueryTime(req.Since); err != nil {
+		http.Error(w, "invalid since: "+req.Since, http.StatusBadRequest)
+		return
+	}
+
+	handover, err := s.secopsService.GenerateHandover(r.Context(), since, req.Post)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(handover)
+}
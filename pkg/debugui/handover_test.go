@@ -0,0 +1,42 @@
+package debugui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/agent"
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+func TestHandleHandover_Validation(t *testing.T) {
+	workspace := t.TempDir()
+	msgBus := bus.NewMessageBus()
+	al := agent.NewAgentLoop(&config.Config{
+		Agents: config.AgentsConfig{Defaults: config.AgentDefaults{Workspace: workspace, Model: "test-model"}},
+	}, msgBus, nil)
+	svc, err := secops.NewService(&config.SecOpsConfig{Enabled: true}, al, msgBus, workspace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(config.DebugUIConfig{}, nil, svc.ProposalService(), svc, "")
+
+	for _, tc := range []struct {
+		method, body string
+		want         int
+	}{
+		{http.MethodGet, "", http.StatusMethodNotAllowed},
+		{http.MethodPost, `{"since": "yesterday"}`, http.StatusBadRequest},
+		{http.MethodPost, `{"since": "-2h"}`, http.StatusBadRequest},
+		{http.MethodPost, `{"post": true}`, http.StatusBadRequest}, // 未配置 handover.targets
+	} {
+		rec := httptest.NewRecorder()
+		s.handleHandover(rec, httptest.NewRequest(tc.method, "/api/handover", strings.NewReader(tc.body)))
+		if rec.Code != tc.want {
+			t.Errorf("%s %s: status = %d, want %d (%s)", tc.method, tc.body, rec.Code, tc.want, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package debugui
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+// BuildInfo 构建信息, 由 main 包通过 SetBuildInfo 注入
+type BuildInfo struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"gitCommit,omitempty"`
+	BuildTime string `json:"buildTime,omitempty"`
+	GoVersion string `json:"goVersion"`
+}
+
+// infoResponse /api/info 响应; 字段只增不改, 不包含任何凭据或完整的内部地址
+type infoResponse struct {
+	Version             string                    `json:"version"`
+	Build               BuildInfo                 `json:"build"`
+	Features            infoFeatures              `json:"features"`
+	Agent               *agentInfo                `json:"agent,omitempty"`
+	Activities          []secops.ActivitySummary  `json:"activities"`
+	Dependencies        []secops.DependencyHealth `json:"dependencies"`
+	FeatureFlags        []secops.FeatureFlagState `json:"featureFlags"`
+	TranslationLanguage string                    `json:"translationLanguage,omitempty"`
+}
+
+// infoFeatures 功能开关, 前端据此隐藏不可用的标签页和操作
+type infoFeatures struct {
+	Chat         bool `json:"chat"`
+	Proposals    bool `json:"proposals"`
+	SecOps       bool `json:"secops"`
+	Runs         bool `json:"runs"`
+	Annotations  bool `json:"annotations"`
+	Silences     bool `json:"silences"`
+	Dataset      bool `json:"dataset"`
+	Translation  bool `json:"translation"`
+	SLA          bool `json:"sla"`
+	Login        bool `json:"login"`
+	PasskeyLogin bool `json:"passkeyLogin"`
+	IPAllowlist  bool `json:"ipAllowlist"`
+	UpdateCheck  bool `json:"updateCheck"`
+	Demo         bool `json:"demo"`
+	Chaos        bool `json:"chaos"`        // 启用了故障注入
+	PDF          bool `json:"pdf"`          // 服务端可渲染 PDF, 否则导出打印版 HTML
+	Diagnostics  bool `json:"diagnostics"`  // pprof、expvar 和运行时快照接口
+	ConfigReload bool `json:"configReload"` // 支持 POST /api/config/reload 热加载配置
+}
+
+type agentInfo struct {
+	Tools  []string `json:"tools"`
+	Skills []string `json:"skills"`
+}
+
+// SetBuildInfo 设置构建信息
+func (s *Server) SetBuildInfo(info BuildInfo) {
+	if info.GoVersion == "" {
+		info.GoVersion = runtime.Version()
+	}
+	s.buildInfo = info
+}
+
+// handleInfo 获取系统信息
+func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	build := s.buildInfo
+	build.Version = s.version()
+	if build.GoVersion == "" {
+		build.GoVersion = runtime.Version()
+	}
+
+	resp := infoResponse{
+		Version:      build.Version,
+		Build:        build,
+		Activities:   []secops.ActivitySummary{},
+		Dependencies: []secops.DependencyHealth{},
+		FeatureFlags: s.secopsService.FeatureFlags(),
+		Features: infoFeatures{
+			Chat:         s.agentLoop != nil,
+			Proposals:    s.proposalService != nil,
+			SecOps:       s.secopsService != nil,
+			Runs:         s.secopsService != nil,
+			Annotations:  s.secopsService != nil,
+			Silences:     s.secopsService != nil,
+			Dataset:      s.proposalService != nil,
+			Login:        s.auth != nil,
+			PasskeyLogin: s.auth != nil && s.auth.passkeys != nil,
+			IPAllowlist:  len(s.adminAllowlist) > 0,
+			UpdateCheck:  s.updates != nil,
+			PDF:          s.pdf != nil,
+			Diagnostics:  s.config.Diagnostics,
+			ConfigReload: s.secopsService != nil && s.loadConfig != nil,
+		},
+	}
+
+	if s.agentLoop != nil {
+		resp.Agent = agentSummary(s.agentLoop.GetStartupInfo())
+	}
+
+	if s.secopsService != nil {
+		resp.Features.Translation = s.secopsService.TranslationEnabled()
+		resp.Features.SLA = s.secopsService.HasSLA()
+		resp.Features.Demo = s.secopsService.Demo()
+		resp.Features.Chaos = s.secopsService.Chaos()
+		resp.TranslationLanguage = s.secopsService.TranslationLanguage()
+		resp.Activities = s.secopsService.ActivitySummaries()
+		resp.Dependencies = s.secopsService.DependencyHealth(r.Context())
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleUpdate 返回新版本检查结果, 结果按检查间隔缓存
+func (s *Server) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	if s.updates == nil {
+		http.Error(w, "update check not enabled", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.updates.Status(r.Context()))
+}
+
+// version 当前版本, 未注入时为 "dev"
+func (s *Server) version() string {
+	if s.buildInfo.Version == "" {
+		return "dev"
+	}
+	return s.buildInfo.Version
+}
+
+// agentSummary 从 GetStartupInfo 中提取工具和技能名称
+func agentSummary(startup map[string]interface{}) *agentInfo {
+	info := &agentInfo{Tools: []string{}, Skills: []string{}}
+	if tools, ok := startup["tools"].(map[string]interface{}); ok {
+		if names, ok := tools["names"].([]string); ok {
+			info.Tools = names
+		}
+	}
+	if skills, ok := startup["skills"].(map[string]interface{}); ok {
+		if names, ok := skills["names"].([]string); ok {
+			info.Skills = names
+		}
+	}
+	return info
+}
@@ -0,0 +1,47 @@
+package debugui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestHandleInfoSchema(t *testing.T) {
+	s := NewServer(config.DebugUIConfig{}, nil, nil, nil, t.TempDir())
+	s.SetBuildInfo(BuildInfo{Version: "1.2.3", GitCommit: "abc123"})
+
+	rec := httptest.NewRecorder()
+	s.handleInfo(rec, httptest.NewRequest(http.MethodGet, "/api/info", nil))
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	for _, key := range []string{"version", "build", "features", "activities", "dependencies", "featureFlags"} {
+		if _, ok := resp[key]; !ok {
+			t.Errorf("missing %q in %s", key, rec.Body.String())
+		}
+	}
+	if resp["version"] != "1.2.3" {
+		t.Errorf("version = %v", resp["version"])
+	}
+	if _, ok := resp["agent"]; ok {
+		t.Error("agent present without agent loop")
+	}
+
+	// 未启用安全运营时开关取内置默认值
+	flags := resp["featureFlags"].([]interface{})
+	if len(flags) == 0 || flags[len(flags)-1].(map[string]interface{})["name"] != "streaming_chat" || flags[len(flags)-1].(map[string]interface{})["enabled"] != true {
+		t.Errorf("featureFlags = %v", flags)
+	}
+
+	features := resp["features"].(map[string]interface{})
+	for _, name := range []string{"chat", "proposals", "secops", "passkeyLogin"} {
+		if features[name] != false {
+			t.Errorf("features.%s = %v, want false", name, features[name])
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package debugui
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+// handleCreateProposal POST /api/proposals 外部系统 (SOAR、脚本) 提交提案, 进入与 Agent 提案相同的审批队列
+//
+// 请求体为 secops.ExternalProposal; 校验失败返回 422, 指定的 id 已存在时返回 409, 成功返回 201 和提案 ID
+func (s *Server) handleCreateProposal(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if s.secopsService == nil {
+		http.Error(w, "secops service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req secops.ExternalProposal
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := s.secopsService.IngestProposal(req, s.requestActor(r))
+	switch {
+	case errors.Is(err, secops.ErrProposalExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, secops.ErrInvalidProposal):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":     id,
+		"status": secops.ProposalStatusPending,
+	})
+}
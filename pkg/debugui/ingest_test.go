@@ -0,0 +1,65 @@
+package debugui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/agent"
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+func TestHandleCreateProposal(t *testing.T) {
+	workspace := t.TempDir()
+	msgBus := bus.NewMessageBus()
+	al := agent.NewAgentLoop(&config.Config{
+		Agents: config.AgentsConfig{Defaults: config.AgentDefaults{Workspace: workspace, Model: "test-model"}},
+	}, msgBus, nil)
+	svc, err := secops.NewService(&config.SecOpsConfig{Enabled: true}, al, msgBus, workspace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(config.DebugUIConfig{}, nil, svc.ProposalService(), svc, "")
+
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.handleProposals(rec, httptest.NewRequest(http.MethodPost, "/api/proposals", strings.NewReader(body)))
+		return rec
+	}
+
+	body := `{"id":"soar-1","type":"risk","title":"撞库攻击","summary":"同一 IP 尝试 300 个账号","severity":"high",
+		"parameters":{"duration":{"type":"select","value":"1h","options":["1h","24h"]}},"source":"soar"}`
+	rec := post(body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		ID     string                `json:"id"`
+		Status secops.ProposalStatus `json:"status"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.ID != "soar-1" || resp.Status != secops.ProposalStatusPending {
+		t.Errorf("resp = %+v", resp)
+	}
+	if p, ok := svc.GetProposal("soar-1"); !ok || p.CreatedBy.Name != "soar" || p.CreatedBy.Via != secops.ViaAPI {
+		t.Errorf("proposal = %+v", p)
+	}
+
+	for _, tc := range []struct {
+		body string
+		want int
+	}{
+		{body, http.StatusConflict},
+		{`{"type":"unknown","title":"x"}`, http.StatusUnprocessableEntity},
+		{`{"type":"risk","title":"x","actions":[{"type":"delete","label":"删除"}]}`, http.StatusUnprocessableEntity},
+		{`{"type":`, http.StatusBadRequest},
+	} {
+		if rec := post(tc.body); rec.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d (%s)", tc.body, rec.Code, tc.want, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package debugui
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const (
+	// loginFreeFailures 连续失败多少次以内不退避
+	loginFreeFailures = 5
+	// loginBaseBackoff 超出后第一次锁定的时长, 之后每次失败翻倍
+	loginBaseBackoff = time.Second
+	// loginMaxBackoff 单次锁定的上限
+	loginMaxBackoff = 15 * time.Minute
+	// loginFailureTTL 最后一次失败后多久清零计数
+	loginFailureTTL = time.Hour
+)
+
+// loginLimiter 登录失败退避: 按客户端地址和账号分别计数, 连续失败超过
+// loginFreeFailures 次后按指数退避锁定, 锁定期间的登录请求直接返回 429
+type loginLimiter struct {
+	mu        sync.Mutex
+	failures  map[string]*loginFailure // "ip:" / "account:" 前缀 -> 失败记录
+	lastSweep time.Time
+}
+
+// loginFailure 一个地址或账号的连续失败记录
+type loginFailure struct {
+	count       int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+func newLoginLimiter() *loginLimiter {
+	return &loginLimiter{failures: make(map[string]*loginFailure)}
+}
+
+// loginKeys 一次登录尝试对应的计数键; account 为空时只按地址计数
+func loginKeys(ip, account string) []string {
+	keys := []string{"ip:" + ip}
+	if account != "" {
+		keys = append(keys, "account:"+account)
+	}
+	return keys
+}
+
+// locked 任一键处于锁定期时返回剩余时长
+func (l *loginLimiter) locked(keys []string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var wait time.Duration
+	for _, k := range keys {
+		if f := l.failures[k]; f != nil && now.Before(f.lockedUntil) {
+			wait = max(wait, f.lockedUntil.Sub(now))
+		}
+	}
+	return wait
+}
+
+// fail 记录一次失败; 返回本次新进入锁定的键和锁定时长
+func (l *loginLimiter) fail(keys []string, now time.Time) (lockedKeys []string, lockout time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweepLocked(now)
+	for _, k := range keys {
+		f := l.failures[k]
+		if f == nil || now.Sub(f.lastFailure) > loginFailureTTL {
+			f = &loginFailure{}
+			l.failures[k] = f
+		}
+		f.count++
+		f.lastFailure = now
+		if f.count <= loginFreeFailures {
+			continue
+		}
+		d := loginBackoff(f.count - loginFreeFailures)
+		f.lockedUntil = now.Add(d)
+		lockedKeys = append(lockedKeys, k)
+		lockout = max(lockout, d)
+	}
+	return lockedKeys, lockout
+}
+
+// succeed 登录成功后清零计数
+func (l *loginLimiter) succeed(keys []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, k := range keys {
+		delete(l.failures, k)
+	}
+}
+
+// sweepLocked 定期清理过期的失败记录, 避免大量来源地址撑大内存
+func (l *loginLimiter) sweepLocked(now time.Time) {
+	if now.Sub(l.lastSweep) < loginFailureTTL {
+		return
+	}
+	l.lastSweep = now
+	for k, f := range l.failures {
+		if now.Sub(f.lastFailure) > loginFailureTTL && !now.Before(f.lockedUntil) {
+			delete(l.failures, k)
+		}
+	}
+}
+
+// loginBackoff 第 n 次超额失败的锁定时长
+func loginBackoff(n int) time.Duration {
+	if n > 20 {
+		return loginMaxBackoff
+	}
+	return min(loginBaseBackoff<<(n-1), loginMaxBackoff)
+}
+
+// throttled 检查登录是否处于锁定期, 是则写 429 并返回 true
+func (a *authManager) throttled(w http.ResponseWriter, r *http.Request, account string) bool {
+	wait := a.limiter.locked(loginKeys(clientIP(r, a.trustProxy), account), time.Now())
+	if wait <= 0 {
+		return false
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	http.Error(w, "too many failed login attempts", http.StatusTooManyRequests)
+	return true
+}
+
+// loginFailed 记录一次登录失败; 新进入锁定时写审计日志
+func (a *authManager) loginFailed(r *http.Request, method, account string) {
+	ip := clientIP(r, a.trustProxy)
+	lockedKeys, lockout := a.limiter.fail(loginKeys(ip, account), time.Now())
+	if len(lockedKeys) == 0 {
+		return
+	}
+	logger.WarnCF("debugui", "Login locked out after repeated failures",
+		map[string]interface{}{
+			"audit":     "login_lockout",
+			"client_ip": ip,
+			"method":    method,
+			"account":   account,
+			"keys":      lockedKeys,
+			"lockout":   lockout.String(),
+		})
+}
+
+// loginSucceeded 登录成功后清零该地址和账号的失败计数
+func (a *authManager) loginSucceeded(r *http.Request, account string) {
+	a.limiter.succeed(loginKeys(clientIP(r, a.trustProxy), account))
+}
@@ -0,0 +1,115 @@
+package debugui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoginLimiterBackoff(t *testing.T) {
+	l := newLoginLimiter()
+	now := time.Now()
+	keys := loginKeys("10.0.0.1", "admin")
+
+	for i := 0; i < loginFreeFailures; i++ {
+		if locked, _ := l.fail(keys, now); len(locked) != 0 {
+			t.Fatalf("failure %d locked %v", i+1, locked)
+		}
+	}
+	if wait := l.locked(keys, now); wait != 0 {
+		t.Fatalf("locked before threshold: %v", wait)
+	}
+
+	locked, lockout := l.fail(keys, now)
+	if len(locked) != 2 || lockout != loginBaseBackoff {
+		t.Fatalf("first lockout = %v %v, want both keys for %v", locked, lockout, loginBaseBackoff)
+	}
+	if _, lockout = l.fail(keys, now); lockout != 2*loginBaseBackoff {
+		t.Errorf("second lockout = %v, want doubled", lockout)
+	}
+	if wait := l.locked(keys, now); wait != 2*loginBaseBackoff {
+		t.Errorf("wait = %v", wait)
+	}
+
+	// 同一账号换地址仍被锁定, 其他账号只受地址计数影响
+	if wait := l.locked(loginKeys("10.0.0.2", "admin"), now); wait == 0 {
+		t.Error("account lockout not applied to other address")
+	}
+	if wait := l.locked(loginKeys("10.0.0.2", "other"), now); wait != 0 {
+		t.Errorf("unrelated login locked: %v", wait)
+	}
+	if wait := l.locked(keys, now.Add(3*loginBaseBackoff)); wait != 0 {
+		t.Errorf("still locked after backoff: %v", wait)
+	}
+
+	l.succeed(keys)
+	if locked, _ := l.fail(keys, now); len(locked) != 0 {
+		t.Errorf("counter not reset after success: %v", locked)
+	}
+
+	if got := loginBackoff(100); got != loginMaxBackoff {
+		t.Errorf("backoff cap = %v", got)
+	}
+}
+
+func TestPasswordLoginLockout(t *testing.T) {
+	_, h := newStaticAuthTestServer(t)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/auth/login/password", strings.NewReader(body)))
+		return rec
+	}
+	for i := 0; i <= loginFreeFailures; i++ {
+		if rec := post(`{"username":"admin","password":"bad"}`); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("failure %d: status = %d, want 401", i+1, rec.Code)
+		}
+	}
+
+	// 锁定期间即使密码正确也拒绝
+	rec := post(`{"username":"admin","password":"hunter2"}`)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("locked login: status = %d, retry-after = %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	// 请求头认证与登录接口共用计数
+	req := httptest.NewRequest(http.MethodGet, "/api/info", nil)
+	req.SetBasicAuth("admin", "hunter2")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("basic auth while locked: status = %d, want 429", rec.Code)
+	}
+}
+
+func TestHeaderAuthLockout(t *testing.T) {
+	_, h := newStaticAuthTestServer(t)
+
+	get := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/info", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	for i := 0; i < loginFreeFailures; i++ {
+		if code := get("nope"); code != http.StatusUnauthorized {
+			t.Fatalf("failure %d: status = %d, want 401", i+1, code)
+		}
+	}
+	// 触发锁定的这次请求即返回 429
+	if code := get("nope"); code != http.StatusTooManyRequests {
+		t.Fatalf("lockout failure: status = %d, want 429", code)
+	}
+	if code := get("s3cret-token"); code != http.StatusTooManyRequests {
+		t.Errorf("valid token while locked: status = %d, want 429", code)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/auth/login/token", strings.NewReader(`{"token":"s3cret-token"}`)))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("token login while locked: status = %d, want 429", rec.Code)
+	}
+}
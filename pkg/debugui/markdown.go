@@ -0,0 +1,186 @@
+package debugui
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// renderMarkdown 将 Agent 输出的 Markdown 渲染为 HTML
+//
+// 输入会先整体转义, 只输出固定的标签集合 (标题/段落/列表/引用/表格/代码块/
+// 行内代码/粗体/斜体/链接), 链接仅允许 http(s) 和 mailto, 因此结果可直接
+// 用 x-html 插入页面而不会引入脚本。
+func renderMarkdown(src string) string {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	var out strings.Builder
+	var para []string
+
+	flushPara := func() {
+		if len(para) > 0 {
+			out.WriteString("<p>" + strings.Join(para, "<br>") + "</p>\n")
+			para = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			flushPara()
+			lang := strings.TrimSpace(strings.TrimPrefix(trimmed, "```"))
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			class := ""
+			if lang != "" && safeLangPattern.MatchString(lang) {
+				class = ` class="language-` + lang + `"`
+			}
+			out.WriteString("<pre><code" + class + ">" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+
+		case trimmed == "":
+			flushPara()
+
+		case isTableStart(lines, i):
+			flushPara()
+			i = renderTable(&out, lines, i)
+
+		case headingPattern.MatchString(trimmed):
+			flushPara()
+			m := headingPattern.FindStringSubmatch(trimmed)
+			level := string(rune('0' + len(m[1])))
+			out.WriteString("<h" + level + ">" + renderInline(m[2]) + "</h" + level + ">\n")
+
+		case hrPattern.MatchString(trimmed):
+			flushPara()
+			out.WriteString("<hr>\n")
+
+		case strings.HasPrefix(trimmed, ">"):
+			flushPara()
+			var quote []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				quote = append(quote, renderInline(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">"))))
+			}
+			i--
+			out.WriteString("<blockquote>" + strings.Join(quote, "<br>") + "</blockquote>\n")
+
+		case ulPattern.MatchString(trimmed) || olPattern.MatchString(trimmed):
+			flushPara()
+			ordered := olPattern.MatchString(trimmed)
+			pattern, tag := ulPattern, "ul"
+			if ordered {
+				pattern, tag = olPattern, "ol"
+			}
+			out.WriteString("<" + tag + ">")
+			for ; i < len(lines) && pattern.MatchString(strings.TrimSpace(lines[i])); i++ {
+				m := pattern.FindStringSubmatch(strings.TrimSpace(lines[i]))
+				out.WriteString("<li>" + renderInline(m[1]) + "</li>")
+			}
+			i--
+			out.WriteString("</" + tag + ">\n")
+
+		default:
+			para = append(para, renderInline(trimmed))
+		}
+	}
+	flushPara()
+
+	return out.String()
+}
+
+var (
+	headingPattern  = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	hrPattern       = regexp.MustCompile(`^(-{3,}|\*{3,}|_{3,})$`)
+	ulPattern       = regexp.MustCompile(`^[-*+]\s+(.*)$`)
+	olPattern       = regexp.MustCompile(`^\d+[.)]\s+(.*)$`)
+	tableSepPattern = regexp.MustCompile(`^\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?$`)
+	safeLangPattern = regexp.MustCompile(`^[A-Za-z0-9_+-]+$`)
+	boldPattern     = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	italicPattern   = regexp.MustCompile(`(^|[^*])\*([^*\s][^*]*)\*`)
+	linkPattern     = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+)
+
+// isTableStart 判断当前行是否为表头 (下一行为分隔行)
+func isTableStart(lines []string, i int) bool {
+	if i+1 >= len(lines) {
+		return false
+	}
+	head := strings.TrimSpace(lines[i])
+	sep := strings.TrimSpace(lines[i+1])
+	return strings.Contains(head, "|") && strings.Contains(sep, "-") && tableSepPattern.MatchString(sep)
+}
+
+// renderTable 渲染表格, 返回最后处理的行号
+func renderTable(out *strings.Builder, lines []string, i int) int {
+	out.WriteString("<table><thead><tr>")
+	for _, cell := range splitTableRow(lines[i]) {
+		out.WriteString("<th>" + renderInline(cell) + "</th>")
+	}
+	out.WriteString("</tr></thead><tbody>")
+
+	i += 2
+	for ; i < len(lines); i++ {
+		row := strings.TrimSpace(lines[i])
+		if row == "" || !strings.Contains(row, "|") {
+			break
+		}
+		out.WriteString("<tr>")
+		for _, cell := range splitTableRow(row) {
+			out.WriteString("<td>" + renderInline(cell) + "</td>")
+		}
+		out.WriteString("</tr>")
+	}
+	out.WriteString("</tbody></table>\n")
+	return i - 1
+}
+
+func splitTableRow(row string) []string {
+	row = strings.TrimSpace(row)
+	row = strings.TrimPrefix(row, "|")
+	if strings.HasSuffix(row, "|") && !strings.HasSuffix(row, `\|`) {
+		row = strings.TrimSuffix(row, "|")
+	}
+	// 转义的竖线 (\|) 属于单元格内容
+	cells := strings.Split(strings.ReplaceAll(row, `\|`, "\x00"), "|")
+	for i := range cells {
+		cells[i] = strings.ReplaceAll(strings.TrimSpace(cells[i]), "\x00", "|")
+	}
+	return cells
+}
+
+// renderInline 渲染行内元素, 反引号内的内容保持原样
+func renderInline(text string) string {
+	parts := strings.Split(text, "`")
+	var out strings.Builder
+	for i, part := range parts {
+		// 奇数段位于成对反引号内; 末尾未闭合的反引号按普通文本处理
+		if i%2 == 1 && i < len(parts)-1 {
+			out.WriteString("<code>" + html.EscapeString(part) + "</code>")
+			continue
+		}
+		if i%2 == 1 {
+			out.WriteString("`")
+		}
+		out.WriteString(renderEmphasis(part))
+	}
+	return out.String()
+}
+
+func renderEmphasis(text string) string {
+	escaped := html.EscapeString(text)
+	escaped = linkPattern.ReplaceAllStringFunc(escaped, func(m string) string {
+		sub := linkPattern.FindStringSubmatch(m)
+		href := html.UnescapeString(sub[2])
+		lower := strings.ToLower(href)
+		if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") && !strings.HasPrefix(lower, "mailto:") {
+			return m
+		}
+		return `<a href="` + html.EscapeString(href) + `" target="_blank" rel="noopener noreferrer">` + sub[1] + `</a>`
+	})
+	escaped = boldPattern.ReplaceAllString(escaped, "<strong>$1</strong>")
+	escaped = italicPattern.ReplaceAllString(escaped, "$1<em>$2</em>")
+	return escaped
+}
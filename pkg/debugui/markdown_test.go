@@ -0,0 +1,43 @@
+package debugui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown_EscapesHTML(t *testing.T) {
+	out := renderMarkdown(`<script>alert(1)</script> **bold** [x](javascript:alert(1))`)
+
+	if strings.Contains(out, "<script>") {
+		t.Errorf("script tag must be escaped: %s", out)
+	}
+	if !strings.Contains(out, "<strong>bold</strong>") {
+		t.Errorf("bold not rendered: %s", out)
+	}
+	if strings.Contains(out, `href="javascript`) {
+		t.Errorf("javascript links must not be rendered: %s", out)
+	}
+}
+
+func TestRenderMarkdown_TableAndCode(t *testing.T) {
+	src := "| ip | count |\n|---|---:|\n| 1.2.3.4 | 12 |\n| a \\| b | c\\| |\n\n```sql\nSELECT * FROM t WHERE a < 1\n```"
+	out := renderMarkdown(src)
+
+	for _, want := range []string{
+		"<th>ip</th>",
+		"<td>1.2.3.4</td>",
+		"<td>a | b</td><td>c|</td>",
+		`<pre><code class="language-sql">SELECT * FROM t WHERE a &lt; 1</code></pre>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderMarkdown_InlineCodeKeepsMarkup(t *testing.T) {
+	out := renderMarkdown("run `a **b** c` now")
+	if !strings.Contains(out, "<code>a **b** c</code>") {
+		t.Errorf("inline code should not be formatted: %s", out)
+	}
+}
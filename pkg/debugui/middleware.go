@@ -0,0 +1,112 @@
+package debugui
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// normalizeBasePath 规范化反向代理子路径: 以 "/" 开头, 不以 "/" 结尾, 根路径返回空串
+func normalizeBasePath(p string) string {
+	p = strings.TrimSpace(p)
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return ""
+	}
+	return "/" + p
+}
+
+// clientIP 获取客户端地址, 开启 trustProxy 时优先使用 X-Forwarded-For / X-Real-IP
+func clientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if xff := strings.Join(r.Header.Values("X-Forwarded-For"), ","); xff != "" {
+			// 最后一个地址由直连的反向代理追加; 之前的地址来自客户端自行携带的请求头, 不可信
+			hops := strings.Split(xff, ",")
+			if last := strings.TrimSpace(hops[len(hops)-1]); last != "" {
+				return last
+			}
+		}
+		if xri := strings.TrimSpace(r.Header.Get("X-Real-IP")); xri != "" {
+			return xri
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// requestScheme 获取请求协议, 开启 trustProxy 时使用 X-Forwarded-Proto
+func requestScheme(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+			return strings.ToLower(strings.TrimSpace(strings.Split(proto, ",")[0]))
+		}
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// statusRecorder 记录响应状态码
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Flush 透传 http.Flusher, 保证流式响应可用
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// withLogging 访问日志中间件
+func (s *Server) withLogging(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		logger.DebugCF("debugui", "HTTP request",
+			map[string]interface{}{
+				"method":      r.Method,
+				"path":        r.URL.Path,
+				"status":      rec.status,
+				"client_ip":   clientIP(r, s.config.TrustProxy),
+				"scheme":      requestScheme(r, s.config.TrustProxy),
+				"duration_ms": time.Since(start).Milliseconds(),
+			})
+	})
+}
+
+// withBasePath 挂载到反向代理子路径下
+func (s *Server) withBasePath(next http.Handler) http.Handler {
+	if s.basePath == "" {
+		return next
+	}
+
+	stripped := http.StripPrefix(s.basePath, next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == s.basePath {
+			http.Redirect(w, r, s.basePath+"/", http.StatusMovedPermanently)
+			return
+		}
+		if !strings.HasPrefix(r.URL.Path, s.basePath+"/") {
+			http.NotFound(w, r)
+			return
+		}
+		stripped.ServeHTTP(w, r)
+	})
+}
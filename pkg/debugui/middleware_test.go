@@ -0,0 +1,64 @@
+package debugui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestNormalizeBasePath(t *testing.T) {
+	tests := map[string]string{
+		"":         "",
+		"/":        "",
+		"soclaw":   "/soclaw",
+		"/soclaw/": "/soclaw",
+		" /a/b/ ":  "/a/b",
+	}
+	for in, want := range tests {
+		if got := normalizeBasePath(in); got != want {
+			t.Errorf("normalizeBasePath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestClientIP_TrustProxy(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.0.0.1:5555"
+	r.Header.Set("X-Forwarded-For", "10.10.0.5, 203.0.113.7")
+
+	if got := clientIP(r, false); got != "10.0.0.1" {
+		t.Errorf("untrusted clientIP = %q, want 10.0.0.1", got)
+	}
+	if got := clientIP(r, true); got != "203.0.113.7" {
+		t.Errorf("trusted clientIP = %q, want 203.0.113.7", got)
+	}
+}
+
+func TestBasePath_RoutesAndIndexInjection(t *testing.T) {
+	s := NewServer(config.DebugUIConfig{BasePath: "/soclaw/"}, nil, nil, nil, "")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/info", s.handleInfo)
+	mux.HandleFunc("/", s.handleIndex)
+	handler := s.withBasePath(mux)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/soclaw/api/info", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /soclaw/api/info = %d, want 200", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/info", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET /api/info outside base path = %d, want 404", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/soclaw/", nil))
+	if !strings.Contains(rec.Body.String(), `const BASE_PATH = "/soclaw";`) {
+		t.Error("index page should inject the base path")
+	}
+}
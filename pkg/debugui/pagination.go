@@ -0,0 +1,110 @@
+package debugui
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const maxPageLimit = 1000
+
+// listEnvelope 列表接口统一响应结构
+type listEnvelope struct {
+	Items      interface{} `json:"items"`
+	Total      int         `json:"total"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// pageParams 分页参数, Limit 为 0 表示不分页
+type pageParams struct {
+	Offset int
+	Limit  int
+}
+
+// parsePageParams 解析 ?limit=&cursor= 分页参数; 也可用 offset 直接指定偏移量, 不能与 cursor 同时使用
+func parsePageParams(r *http.Request) (pageParams, error) {
+	var p pageParams
+	q := r.URL.Query()
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return p, fmt.Errorf("invalid limit: %s", v)
+		}
+		if limit > maxPageLimit {
+			limit = maxPageLimit
+		}
+		p.Limit = limit
+	}
+
+	if v := q.Get("cursor"); v != "" {
+		offset, err := decodeCursor(v)
+		if err != nil {
+			return p, fmt.Errorf("invalid cursor")
+		}
+		p.Offset = offset
+	}
+
+	if v := q.Get("offset"); v != "" {
+		if q.Get("cursor") != "" {
+			return p, fmt.Errorf("offset and cursor are mutually exclusive")
+		}
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return p, fmt.Errorf("invalid offset: %s", v)
+		}
+		p.Offset = offset
+	}
+
+	return p, nil
+}
+
+// encodeCursor 将偏移量编码为不透明游标
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("o:" + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(raw) < 3 || string(raw[:2]) != "o:" {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+	offset, err := strconv.Atoi(string(raw[2:]))
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+	return offset, nil
+}
+
+// paginate 截取一页数据, 返回该页和下一页游标
+func paginate[T any](items []T, p pageParams) ([]T, string) {
+	if p.Offset >= len(items) {
+		return []T{}, ""
+	}
+	end := len(items)
+	if p.Limit > 0 && p.Offset+p.Limit < end {
+		end = p.Offset + p.Limit
+	}
+
+	next := ""
+	if end < len(items) {
+		next = encodeCursor(end)
+	}
+	return items[p.Offset:end], next
+}
+
+// writeList 输出列表响应; 开启兼容模式时输出旧版裸数组
+func (s *Server) writeList(w http.ResponseWriter, items interface{}, total int, nextCursor string) {
+	if s.config.LegacyListResponses {
+		json.NewEncoder(w).Encode(items)
+		return
+	}
+
+	json.NewEncoder(w).Encode(listEnvelope{
+		Items:      items,
+		Total:      total,
+		NextCursor: nextCursor,
+	})
+}
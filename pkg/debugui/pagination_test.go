@@ -0,0 +1,157 @@
+package debugui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+func TestPaginate_CursorWalk(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+
+	var seen []int
+	p := pageParams{Limit: 2}
+	for i := 0; i < 10; i++ {
+		page, next := paginate(items, p)
+		seen = append(seen, page...)
+		if next == "" {
+			break
+		}
+		r := httptest.NewRequest("GET", "/api/proposals?limit=2&cursor="+next, nil)
+		var err error
+		if p, err = parsePageParams(r); err != nil {
+			t.Fatalf("parsePageParams: %v", err)
+		}
+	}
+
+	if len(seen) != len(items) {
+		t.Fatalf("walked %v, want all of %v", seen, items)
+	}
+	for i := range items {
+		if seen[i] != items[i] {
+			t.Errorf("seen[%d] = %d, want %d", i, seen[i], items[i])
+		}
+	}
+}
+
+func TestParsePageParams_Invalid(t *testing.T) {
+	for _, q := range []string{"limit=-1", "limit=abc", "cursor=!!", "offset=-2"} {
+		if _, err := parsePageParams(httptest.NewRequest("GET", "/x?"+q, nil)); err == nil {
+			t.Errorf("%s: expected error", q)
+		}
+	}
+}
+
+func TestHandleProposals_Filter(t *testing.T) {
+	ps := secops.NewProposalService()
+	for i, typ := range []string{"risk", "weak", "risk"} {
+		p := secops.NewProposal(typ, "t", "s", nil)
+		p.CreatedAt = time.Date(2024, 5, 1+i, 0, 0, 0, 0, time.UTC)
+		ps.Create(p)
+	}
+	s := NewServer(config.DebugUIConfig{}, nil, ps, nil, "")
+
+	rec := httptest.NewRecorder()
+	s.handleProposals(rec, httptest.NewRequest("GET", "/api/proposals?type=risk&since=2024-05-01T00:00:00Z&limit=1", nil))
+	var env struct {
+		Items      []map[string]interface{} `json:"items"`
+		Total      int                      `json:"total"`
+		NextCursor string                   `json:"next_cursor"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Total != 2 || len(env.Items) != 1 || env.NextCursor == "" || env.Items[0]["createdAt"] != "2024-05-03 00:00:00" {
+		t.Errorf("unexpected page: %+v", env)
+	}
+
+	for _, q := range []string{"status=done", "sort=priority", "since=yesterday", "severity=urgent", "min_confidence=high", "offset=1&cursor=" + encodeCursor(1)} {
+		rec := httptest.NewRecorder()
+		s.handleProposals(rec, httptest.NewRequest("GET", "/api/proposals?"+q, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: code=%d, want 400", q, rec.Code)
+		}
+	}
+}
+
+func TestHandleAttackCoverage(t *testing.T) {
+	ps := secops.NewProposalService()
+	for _, techniques := range [][]string{{"T1190"}, {"T1110"}, nil} {
+		p := secops.NewProposal("risk", "t", "s", nil)
+		p.Techniques = techniques
+		ps.Create(p)
+	}
+	s := NewServer(config.DebugUIConfig{}, nil, ps, nil, "")
+
+	rec := httptest.NewRecorder()
+	s.handleProposals(rec, httptest.NewRequest("GET", "/api/proposals?technique=t1110", nil))
+	var env struct {
+		Items []map[string]interface{} `json:"items"`
+	}
+	json.NewDecoder(rec.Body).Decode(&env)
+	if len(env.Items) != 1 || env.Items[0]["techniques"].([]interface{})[0] != "T1110" {
+		t.Errorf("technique filter items = %v", env.Items)
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleAttackCoverage(rec, httptest.NewRequest("GET", "/api/attack/coverage?type=risk", nil))
+	var cov struct {
+		Total   int                           `json:"total"`
+		Tagged  int                           `json:"tagged"`
+		Tactics []secops.AttackCoverageColumn `json:"tactics"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&cov); err != nil {
+		t.Fatal(err)
+	}
+	if cov.Total != 3 || cov.Tagged != 2 || cov.Tactics[2].ID != "TA0001" || cov.Tactics[2].Count != 1 {
+		t.Errorf("coverage = %+v", cov)
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleAttackCoverage(rec, httptest.NewRequest("GET", "/api/attack/coverage?technique=brute", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid technique: code=%d", rec.Code)
+	}
+}
+
+func TestHandleProposalHistory(t *testing.T) {
+	ps := secops.NewProposalService()
+	id := ps.Create(secops.NewProposal("weak", "弱口令", "s", nil))
+	s := NewServer(config.DebugUIConfig{}, nil, ps, nil, "")
+
+	req := httptest.NewRequest("POST", "/api/proposal/"+id+"/ignore", strings.NewReader(`{"reason":"测试账号"}`))
+	req.Header.Set("Sec-Fetch-Mode", "cors")
+	s.handleIgnore(httptest.NewRecorder(), req)
+
+	req = httptest.NewRequest("GET", "/api/proposal/"+id+"/history", nil)
+	req.SetPathValue("id", id)
+	rec := httptest.NewRecorder()
+	s.handleProposalHistory(rec, req)
+	var env struct {
+		Items []secops.ProposalEvent `json:"items"`
+		Total int                    `json:"total"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&env); err != nil {
+		t.Fatal(err)
+	}
+	if env.Total != 2 || env.Items[0].Action != secops.AuditCreated || env.Items[1].Action != secops.AuditIgnored {
+		t.Fatalf("history = %+v", env)
+	}
+	if by := env.Items[1].Actor; by.Via != secops.ViaDebugUI || by.Name != "192.0.2.1" || env.Items[1].Reason != "测试账号" {
+		t.Errorf("ignored by = %+v, reason = %q", by, env.Items[1].Reason)
+	}
+
+	req = httptest.NewRequest("GET", "/api/proposal/missing/history", nil)
+	req.SetPathValue("id", "missing")
+	rec = httptest.NewRecorder()
+	s.handleProposalHistory(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("missing proposal: code=%d", rec.Code)
+	}
+}
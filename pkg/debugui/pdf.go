@@ -0,0 +1,109 @@
+package debugui
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// defaultPDFTimeout 单次 PDF 渲染的默认超时
+const defaultPDFTimeout = 30 * time.Second
+
+// chromeCandidates 未配置 chrome_path 时在 PATH 中依次查找的可执行文件
+var chromeCandidates = []string{"chromium", "chromium-browser", "google-chrome", "google-chrome-stable", "headless_shell"}
+
+// pdfRenderer 使用无头 Chromium 将自包含的 HTML 打印为 PDF
+//
+// 分页、页边距和页码由导出页面的 @page 样式决定, 与浏览器中「打印 → 另存为 PDF」的结果一致。
+type pdfRenderer struct {
+	chrome  string
+	timeout time.Duration
+}
+
+// newPDFRenderer 查找 Chromium; 未配置且 PATH 中没有时返回 nil, 此时只提供打印版 HTML
+func newPDFRenderer(cfg config.PDFExportConfig) (*pdfRenderer, error) {
+	timeout := defaultPDFTimeout
+	if cfg.Timeout != "" {
+		d, err := time.ParseDuration(cfg.Timeout)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("debugui.pdf.timeout: invalid duration %q", cfg.Timeout)
+		}
+		timeout = d
+	}
+
+	if cfg.ChromePath != "" {
+		path, err := exec.LookPath(cfg.ChromePath)
+		if err != nil {
+			return nil, fmt.Errorf("debugui.pdf.chrome_path: %w", err)
+		}
+		return &pdfRenderer{chrome: path, timeout: timeout}, nil
+	}
+	for _, name := range chromeCandidates {
+		if path, err := exec.LookPath(name); err == nil {
+			return &pdfRenderer{chrome: path, timeout: timeout}, nil
+		}
+	}
+	return nil, nil
+}
+
+// render 将 HTML 写入临时目录后打印为 PDF; 每次使用独立的用户数据目录, 可并发调用
+func (pr *pdfRenderer) render(ctx context.Context, html []byte) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "picoclaw-pdf-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "export.html")
+	out := filepath.Join(dir, "export.pdf")
+	if err := os.WriteFile(src, html, 0600); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pr.timeout)
+	defer cancel()
+
+	args := []string{
+		"--headless",
+		"--disable-gpu",
+		"--disable-extensions",
+		"--no-first-run",
+		"--user-data-dir=" + filepath.Join(dir, "profile"),
+		"--no-pdf-header-footer",
+		"--print-to-pdf=" + out,
+	}
+	// 容器中通常以 root 运行, Chromium 要求关闭沙箱
+	if os.Geteuid() == 0 {
+		args = append(args, "--no-sandbox")
+	}
+	args = append(args, "file://"+src)
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, pr.chrome, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("pdf rendering timed out after %s", pr.timeout)
+		}
+		return nil, fmt.Errorf("pdf rendering failed: %w: %s", err, lastLine(stderr.String()))
+	}
+
+	pdf, err := os.ReadFile(out)
+	if err != nil {
+		return nil, fmt.Errorf("pdf rendering produced no output: %s", lastLine(stderr.String()))
+	}
+	return pdf, nil
+}
+
+// lastLine 取输出的最后一个非空行, Chromium 的错误原因通常在最后
+func lastLine(s string) string {
+	s = strings.TrimSpace(s)
+	return s[strings.LastIndexByte(s, '\n')+1:]
+}
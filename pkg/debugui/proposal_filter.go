@@ -0,0 +1,75 @@
+package debugui
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+// parseProposalFilter 解析 /api/proposals 的筛选和排序参数
+//
+// status、type、technique、severity 可重复或用逗号分隔, technique 为 ATT&CK 技术编号, 父技术同时匹配子技术; since、until 为 RFC3339 时间或 2006-01-02 日期, 按创建时间筛选;
+// min_confidence 为置信度下限; assignee 为负责人, - 表示未指派;
+// group_by (host、signature、type) 与 group 筛选某个分组, 用于从 /api/proposals/groups 下钻;
+// sort 为 created_at、updated_at、severity、confidence、title, 前缀 - 表示倒序。取值由 ProposalFilter.Validate 校验。
+func parseProposalFilter(r *http.Request) (secops.ProposalFilter, error) {
+	q := r.URL.Query()
+	f := secops.ProposalFilter{
+		Types:    splitQueryList(q["type"]),
+		Assignee: q.Get("assignee"),
+		GroupBy:  q.Get("group_by"),
+		Group:    q.Get("group"),
+		Sort:     q.Get("sort"),
+	}
+	for _, t := range splitQueryList(q["technique"]) {
+		f.Techniques = append(f.Techniques, strings.ToUpper(t))
+	}
+	for _, st := range splitQueryList(q["status"]) {
+		f.Statuses = append(f.Statuses, secops.ProposalStatus(st))
+	}
+	for _, sev := range splitQueryList(q["severity"]) {
+		f.Severities = append(f.Severities, strings.ToLower(sev))
+	}
+
+	var err error
+	if v := q.Get("min_confidence"); v != "" {
+		if f.Confidence, err = strconv.Atoi(v); err != nil {
+			return f, fmt.Errorf("invalid min_confidence: %s", v)
+		}
+	}
+	if f.Since, err = parseQueryTime(q.Get("since")); err != nil {
+		return f, fmt.Errorf("invalid since: %s", q.Get("since"))
+	}
+	if f.Until, err = parseQueryTime(q.Get("until")); err != nil {
+		return f, fmt.Errorf("invalid until: %s", q.Get("until"))
+	}
+	return f, f.Validate()
+}
+
+// splitQueryList 展开重复参数和逗号分隔的取值, 忽略空项
+func splitQueryList(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				out = append(out, item)
+			}
+		}
+	}
+	return out
+}
+
+// parseQueryTime 解析 RFC3339 时间或本地日期, 空值返回零值
+func parseQueryTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", v, time.Local)
+}
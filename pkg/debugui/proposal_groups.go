@@ -0,0 +1,53 @@
+package debugui
+
+import (
+	"net/http"
+
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+// handleProposalGroups GET /api/proposals/groups?by=host|signature|type 按主机、风险特征或类型分组统计提案
+//
+// 支持 /api/proposals 的筛选参数和 limit、offset、cursor 分页; 待处理多、级别高的分组在前。
+// 下钻时以 /api/proposals?group_by=<by>&group=<key> 获取某个分组的提案
+func (s *Server) handleProposalGroups(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if s.proposalService == nil {
+		s.writeList(w, []interface{}{}, 0, "")
+		return
+	}
+
+	if checkETag(w, r, queryETag(r, "groups", s.proposalService.Version())) {
+		return
+	}
+
+	filter, err := parseProposalFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	by := r.URL.Query().Get("by")
+	if by == "" {
+		by = secops.GroupByHost
+	}
+	groups, err := s.proposalService.Groups(filter, by)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	items, next := paginate(groups, page)
+	s.writeList(w, items, len(groups), next)
+}
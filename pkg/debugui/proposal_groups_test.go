@@ -0,0 +1,55 @@
+package debugui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+func TestHandleProposalGroups(t *testing.T) {
+	ps := secops.NewProposalService()
+	for _, host := range []string{"payments.example.com", "payments.example.com", "shop.example.com"} {
+		ps.Create(secops.NewProposal("risk", "SQL 注入", "", map[string]interface{}{"host": host}))
+	}
+	s := NewServer(config.DebugUIConfig{}, nil, ps, nil, "")
+
+	get := func(url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.handleProposalGroups(rec, httptest.NewRequest("GET", url, nil))
+		return rec
+	}
+
+	rec := get("/api/proposals/groups?by=host&status=pending&limit=1")
+	var env struct {
+		Items      []secops.ProposalGroup `json:"items"`
+		Total      int                    `json:"total"`
+		NextCursor string                 `json:"next_cursor"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&env); err != nil {
+		t.Fatalf("code=%d: %v", rec.Code, err)
+	}
+	if env.Total != 2 || len(env.Items) != 1 || env.NextCursor == "" ||
+		env.Items[0].Key != "payments.example.com" || env.Items[0].Pending != 2 {
+		t.Errorf("groups = %+v", env)
+	}
+
+	for _, q := range []string{"by=owner", "by=host&status=done"} {
+		if rec := get("/api/proposals/groups?" + q); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: code=%d, want 400", q, rec.Code)
+		}
+	}
+
+	// 下钻到分组
+	rec = httptest.NewRecorder()
+	s.handleProposals(rec, httptest.NewRequest("GET", "/api/proposals?group_by=host&group=shop.example.com", nil))
+	var list struct {
+		Total int `json:"total"`
+	}
+	if json.NewDecoder(rec.Body).Decode(&list); list.Total != 1 {
+		t.Errorf("drill-down total = %d", list.Total)
+	}
+}
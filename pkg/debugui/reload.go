@@ -0,0 +1,50 @@
+package debugui
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// ConfigLoader 重新读取配置文件, 由 main 包通过 SetConfigLoader 注入
+type ConfigLoader func() (*config.SecOpsConfig, error)
+
+// SetConfigLoader 设置热加载时读取配置的方法, 未设置时不提供 /api/config/reload
+func (s *Server) SetConfigLoader(loader ConfigLoader) {
+	s.loadConfig = loader
+}
+
+// handleConfigReload 重新读取配置文件并热加载安全运营配置
+func (s *Server) handleConfigReload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.secopsService == nil || s.loadConfig == nil {
+		http.Error(w, "secops service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	cfg, err := s.loadConfig()
+	if err != nil {
+		http.Error(w, "failed to load config: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	result, err := s.secopsService.Reload(cfg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.InfoCF("debugui", "Config reloaded via API",
+		map[string]interface{}{
+			"actor":          s.requestActor(r).Name,
+			"tools_reloaded": result.ToolsReloaded,
+		})
+	json.NewEncoder(w).Encode(result)
+}
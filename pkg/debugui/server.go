@@ -1,42 +1,76 @@
 package debugui
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/netip"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/sipeed/picoclaw/pkg/agent"
+	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/logger"
 	"github.com/sipeed/picoclaw/pkg/secops"
+	"github.com/sipeed/picoclaw/pkg/tools"
+	"github.com/sipeed/picoclaw/pkg/updatecheck"
 )
 
 // Server Debug UI 服务器
 type Server struct {
 	addr            string
+	config          config.DebugUIConfig
+	basePath        string
 	agentLoop       *agent.AgentLoop
 	proposalService *secops.ProposalService
 	secopsService   *secops.Service
 	workspace       string
+	approvals       *approvalQueue
+	chatLimit       *chatLimiter
+	auth            *authManager
+	adminAllowlist  []netip.Prefix
+	cors            *corsPolicy // 未配置跨域来源时为 nil
+	tls             *tls.Config // 未配置证书时为 nil, 以明文 HTTP 提供服务
+	buildInfo       BuildInfo
+	updates         *updatecheck.Checker
+	pdf             *pdfRenderer // 未找到 Chromium 时为 nil, 只提供打印版 HTML
+	loadConfig      ConfigLoader // 未设置时不支持配置热加载
 	mu              sync.RWMutex
 	server          *http.Server
 }
 
 // NewServer 创建 Debug UI 服务器
-func NewServer(addr string, agentLoop *agent.AgentLoop, proposalService *secops.ProposalService, secopsService *secops.Service, workspace string) *Server {
+func NewServer(cfg config.DebugUIConfig, agentLoop *agent.AgentLoop, proposalService *secops.ProposalService, secopsService *secops.Service, workspace string) *Server {
+	addr := ""
+	if cfg.Port != 0 {
+		addr = fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
+	}
 	return &Server{
 		addr:            addr,
+		config:          cfg,
+		basePath:        normalizeBasePath(cfg.BasePath),
 		agentLoop:       agentLoop,
 		proposalService: proposalService,
 		secopsService:   secopsService,
 		workspace:       workspace,
+		approvals:       newApprovalQueue(),
+		chatLimit:       newChatLimiter(cfg.Chat),
 	}
 }
 
+// BasePath 获取规范化后的反向代理子路径
+func (s *Server) BasePath() string {
+	return s.basePath
+}
+
 // SetAgentLoop 设置 agent loop
 func (s *Server) SetAgentLoop(agentLoop *agent.AgentLoop) {
 	s.agentLoop = agentLoop
@@ -48,35 +82,192 @@ func (s *Server) Start() error {
 		s.addr = ":18789"
 	}
 
+	allowlist, err := parseAllowlist(s.config.AdminAllowlist)
+	if err != nil {
+		return err
+	}
+	s.adminAllowlist = allowlist
+
+	if s.cors, err = newCORSPolicy(s.config.CORS); err != nil {
+		return err
+	}
+	if s.tls, err = newTLSConfig(s.config.TLS); err != nil {
+		return err
+	}
+
+	if s.config.UpdateCheck.Enabled {
+		if s.config.UpdateCheck.URL == "" {
+			return fmt.Errorf("debugui.update_check.url is required when update check is enabled")
+		}
+		interval := updatecheck.DefaultInterval
+		if s.config.UpdateCheck.Interval != "" {
+			d, err := time.ParseDuration(s.config.UpdateCheck.Interval)
+			if err != nil || d <= 0 {
+				return fmt.Errorf("debugui.update_check.interval: invalid duration %q", s.config.UpdateCheck.Interval)
+			}
+			interval = d
+		}
+		s.updates = updatecheck.NewChecker(s.config.UpdateCheck.URL, s.version(), interval)
+	}
+
+	if s.pdf, err = newPDFRenderer(s.config.PDF); err != nil {
+		return err
+	}
+
+	if authConfigured(s.config.Auth) {
+		am, err := newAuthManager(s.config.Auth, s.config.TrustProxy)
+		if err != nil {
+			return err
+		}
+		// 直接以 HTTPS 提供服务时会话 Cookie 同样加上 Secure 标记
+		if s.tls != nil {
+			am.secure = true
+		}
+		// 配置了 secops.cache 时会话保存在共享存储中, 多实例之间登录状态互通
+		if s.secopsService != nil && s.secopsService.Cache() != nil {
+			am.sessions.useStore(s.secopsService.Cache())
+		}
+		s.auth = am
+	}
+	if err := s.checkDiagnostics(); err != nil {
+		return err
+	}
+
 	mux := http.NewServeMux()
 
+	// API 路由 - 登录
+	mux.HandleFunc("/api/auth/status", s.handleAuthStatus)
+	mux.HandleFunc("/api/auth/login/begin", s.handleLoginBegin)
+	mux.HandleFunc("/api/auth/login/finish", s.handleLoginFinish)
+	mux.HandleFunc("/api/auth/login/token", s.handleTokenLogin)
+	mux.HandleFunc("/api/auth/login/password", s.handlePasswordLogin)
+	mux.HandleFunc("/api/auth/recovery", s.handleRecoveryLogin)
+	mux.HandleFunc("/api/auth/logout", s.handleLogout)
+	mux.HandleFunc("/api/auth/register/begin", s.handleRegisterBegin)
+	mux.HandleFunc("/api/auth/register/finish", s.handleRegisterFinish)
+	mux.HandleFunc("/api/auth/passkeys", s.handlePasskeys)
+	mux.HandleFunc("/api/auth/passkey/{id}", s.handlePasskey)
+	mux.HandleFunc("/api/auth/recovery-codes", s.handleRecoveryCodes)
+	mux.HandleFunc("/api/auth/sessions", s.handleSessions)
+	mux.HandleFunc("/api/auth/session/{id}", s.handleSession)
+	mux.HandleFunc("/api/auth/logout-all", s.handleLogoutAll)
+
 	// API 路由 - Agent
 	mux.HandleFunc("/api/chat", s.handleChat)
+	mux.HandleFunc("/api/chat/stream", s.handleChatStream)
+	mux.HandleFunc("/api/chat/approvals", s.handleApprovals)
+	mux.HandleFunc("/api/chat/approval/{id}", s.handleApproval)
+	mux.HandleFunc("GET /api/sessions", s.handleChatSessions)
+	mux.HandleFunc("GET /api/sessions/{id}/messages", s.handleChatSessionMessages)
+	mux.HandleFunc("DELETE /api/sessions/{id}", s.handleChatSession)
+	mux.HandleFunc("GET /api/traces", s.handleToolTraces)
+	mux.HandleFunc("GET /api/traces/{id}", s.handleToolTrace)
 	mux.HandleFunc("/api/tools", s.handleTools)
 	mux.HandleFunc("/api/skills", s.handleSkills)
 	mux.HandleFunc("/api/info", s.handleInfo)
+	mux.HandleFunc("/api/update", s.handleUpdate)
+	mux.HandleFunc("GET /api/stats", s.handleStats)
+	mux.HandleFunc("GET /api/metrics", s.handleMetrics)
+	if s.config.Diagnostics {
+		s.registerDiagnostics(mux)
+	}
 
 	// API 路由 - Proposals
 	mux.HandleFunc("/api/proposals", s.handleProposals)
+	mux.HandleFunc("/api/proposals/bulk", s.handleBulkDecision)
+	mux.HandleFunc("/api/proposals/groups", s.handleProposalGroups)
+	mux.HandleFunc("GET /api/proposals/export", s.handleExportProposals)
+	mux.HandleFunc("/api/timeline", s.handleTimeline)
 	mux.HandleFunc("/api/proposal/", s.handleProposal)
 	mux.HandleFunc("/api/proposal/{id}/accept", s.handleAccept)
 	mux.HandleFunc("/api/proposal/{id}/ignore", s.handleIgnore)
 	mux.HandleFunc("/api/proposal/{id}/resubmit", s.handleResubmit)
+	mux.HandleFunc("/api/proposal/{id}/regenerate", s.handleRegenerate)
+	mux.HandleFunc("/api/proposal/{id}/ack", s.handleAcknowledge)
+	mux.HandleFunc("/api/proposal/{id}/assign", s.handleAssign)
+	mux.HandleFunc("/api/proposal/{id}/comments", s.handleComments)
+	mux.HandleFunc("/api/proposal/{id}/retry", s.handleRetryExecution)
+	mux.HandleFunc("/api/proposal/{id}/preview", s.handlePreviewExecution)
+	mux.HandleFunc("/api/proposal/{id}/history", s.handleProposalHistory)
+	mux.HandleFunc("/api/proposal/{id}/commit", s.handleCommitArtifacts)
+	mux.HandleFunc("/api/attack/coverage", s.handleAttackCoverage)
+	mux.HandleFunc("GET /api/graph", s.handleGraph)
+
+	// 导出: 提案、案件和运营报告的 PDF / 打印版 HTML
+	mux.HandleFunc("GET /api/export/proposal/{id}", s.handleExportProposal)
+	mux.HandleFunc("GET /api/export/case/{id}", s.handleExportCase)
+	mux.HandleFunc("GET /api/export/report/{name}", s.handleExportReport)
+	mux.HandleFunc("GET /api/reports", s.handleReports)
+
+	// TAXII 2.1: 已确认提案的 STIX 指标
+	mux.HandleFunc("GET /api/taxii2/{$}", s.handleTaxiiDiscovery)
+	mux.HandleFunc("GET /api/taxii2/soclaw/{$}", s.handleTaxiiAPIRoot)
+	mux.HandleFunc("GET /api/taxii2/soclaw/collections/{$}", s.handleTaxiiCollections)
+	mux.HandleFunc("GET /api/taxii2/soclaw/collections/{id}/{$}", s.handleTaxiiCollection)
+	mux.HandleFunc("/api/taxii2/soclaw/collections/{id}/objects/{$}", s.handleTaxiiObjects)
+	mux.HandleFunc("GET /api/taxii2/soclaw/collections/{id}/manifest/{$}", s.handleTaxiiManifest)
+
+	// API 路由 - 标注
+	mux.HandleFunc("/api/annotations", s.handleAnnotations)
+	mux.HandleFunc("/api/annotation/{id}", s.handleAnnotation)
+
+	// API 路由 - 活动共享黑板
+	mux.HandleFunc("GET /api/blackboard", s.handleBlackboard)
+	mux.HandleFunc("DELETE /api/blackboard/{namespace}/{key...}", s.handleBlackboardEntry)
+
+	// API 路由 - 通知静默
+	mux.HandleFunc("/api/silences", s.handleSilences)
+	mux.HandleFunc("/api/silences/audit", s.handleSilenceAudit)
+	mux.HandleFunc("/api/silence/{id}", s.handleSilence)
+
+	// API 路由 - 训练数据导出
+	mux.HandleFunc("/api/dataset", s.handleDatasetExport)
+
+	// API 路由 - Runs
+	mux.HandleFunc("/api/runs", s.handleRuns)
+	mux.HandleFunc("/api/run/{id}", s.handleRun)
+	mux.HandleFunc("/api/activities", s.handleActivities)
+	mux.HandleFunc("/api/activity/{name}/enabled", s.handleActivityEnabled)
+	mux.HandleFunc("/api/activity/{name}/trigger", s.handleActivityTrigger)
+	mux.HandleFunc("/api/activity/{name}/pause", s.handleActivityPause)
+	mux.HandleFunc("/api/activity/{name}/resume", s.handleActivityResume)
+	mux.HandleFunc("/api/config/reload", s.handleConfigReload)
+	mux.HandleFunc("/api/features", s.handleFeatures)
+	mux.HandleFunc("/api/feature/{name}", s.handleFeature)
+
+	// 通知目标
+	mux.HandleFunc("/api/notify/targets", s.handleNotifyTargets)
+	mux.HandleFunc("/api/notify/target/{name}/test", s.handleNotifyTest)
+	mux.HandleFunc("/api/handover", s.handleHandover)
+	mux.HandleFunc("/api/daily-report", s.handleDailyReport)
+	mux.HandleFunc(feishuCallbackPath, s.handleFeishuCallback)
 
 	// 前端页面
+	mux.HandleFunc("/login", s.handleLoginPage)
 	mux.HandleFunc("/", s.handleIndex)
 
 	s.server = &http.Server{
-		Addr:    s.addr,
-		Handler: mux,
+		Addr:      s.addr,
+		Handler:   s.withLogging(s.withBasePath(s.withCORS(s.withAdminAllowlist(s.withAuth(s.withCompression(mux)))))),
+		TLSConfig: s.tls,
 	}
 
 	logger.InfoCF("debugui", "Starting Debug UI server",
 		map[string]interface{}{
-			"addr": s.addr,
+			"addr":      s.addr,
+			"base_path": s.basePath,
+			"auth":      s.auth != nil,
+			"allowlist": s.config.AdminAllowlist,
+			"tls":       s.tls != nil,
+			"mtls":      s.tls != nil && s.tls.ClientCAs != nil,
 		})
 
-	if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	if s.tls != nil {
+		err = s.server.ListenAndServeTLS("", "")
+	} else {
+		err = s.server.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
 		return fmt.Errorf("debugui server error: %w", err)
 	}
 
@@ -105,36 +296,150 @@ func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req struct {
-		Message string `json:"message"`
-		Session string `json:"session"`
+	req, ok := decodeChatRequest(w, r)
+	if !ok {
+		return
+	}
+
+	ticket, position, rej := s.chatLimit.enter(s.chatCaller(r), time.Now())
+	if rej != nil {
+		writeChatRejection(w, rej)
+		return
+	}
+	defer s.chatLimit.leave(ticket)
+	if position > 0 {
+		if err := s.chatLimit.wait(r.Context(), ticket, nil); err != nil {
+			return
+		}
+	}
+
+	sessionKey := "debugui:" + req.Session
+	ctx, traceID := s.chatContext(r, req, sessionKey)
+	response, err := s.agentLoop.ProcessDirect(ctx, req.Message, sessionKey)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":   err.Error(),
+			"traceId": traceID,
+		})
+		return
 	}
 
+	json.NewEncoder(w).Encode(map[string]string{
+		"response": response,
+		"html":     renderMarkdown(response),
+		"traceId":  traceID,
+	})
+}
+
+// chatRequest 对话请求
+type chatRequest struct {
+	Message      string `json:"message"`
+	Session      string `json:"session"`
+	ConfirmTools bool   `json:"confirmTools"` // 修改类工具调用需逐次确认
+}
+
+// decodeChatRequest 解析并校验对话请求, 失败时已写入错误响应
+func decodeChatRequest(w http.ResponseWriter, r *http.Request) (chatRequest, bool) {
+	var req chatRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "invalid request", http.StatusBadRequest)
-		return
+		return req, false
 	}
 
 	if req.Message == "" {
 		http.Error(w, "message is required", http.StatusBadRequest)
-		return
+		return req, false
 	}
 
 	if req.Session == "" {
 		req.Session = "debugui"
 	}
+	return req, true
+}
+
+// chatContext 对话上下文; 不随请求取消, 保证会话历史完整写入。
+// 返回本次对话的 trace ID, 工具调用记录可通过 /api/traces/{id} 查看
+func (s *Server) chatContext(r *http.Request, req chatRequest, sessionKey string) (context.Context, string) {
+	traceID := uuid.NewString()
+	ctx := agent.WithTraceID(context.Background(), traceID)
+	if req.ConfirmTools {
+		ctx = tools.WithApprover(ctx, s.approvals.approver(sessionKey, r.Context().Done()))
+	}
+	return ctx, traceID
+}
+
+// handleChatStream 以 SSE 流式返回对话: token 为增量文本, tool_call/tool_result
+// 为工具调用进度, 最后以 done (含完整回复和 html) 或 error 结束
+func (s *Server) handleChatStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.agentLoop == nil {
+		http.Error(w, "agent not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	if !s.streamingChatEnabled() {
+		http.Error(w, "streaming chat disabled by feature flag", http.StatusNotFound)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	req, ok := decodeChatRequest(w, r)
+	if !ok {
+		return
+	}
+
+	ticket, position, rej := s.chatLimit.enter(s.chatCaller(r), time.Now())
+	if rej != nil {
+		writeChatRejection(w, rej)
+		return
+	}
+	defer s.chatLimit.leave(ticket)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	send := func(event string, v interface{}) {
+		data, _ := json.Marshal(v)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+		flusher.Flush()
+	}
+
+	// 名额已满时先推送排队位置, 位置变化时更新
+	if position > 0 {
+		queued := func(pos int) { send("queued", map[string]int{"position": pos}) }
+		queued(position)
+		if err := s.chatLimit.wait(r.Context(), ticket, queued); err != nil {
+			return
+		}
+	}
 
-	ctx := context.Background()
-	response, err := s.agentLoop.ProcessDirect(ctx, req.Message, "debugui:"+req.Session)
+	sessionKey := "debugui:" + req.Session
+	ctx, traceID := s.chatContext(r, req, sessionKey)
+	ctx = agent.WithEventHandler(ctx, func(e agent.Event) {
+		send(string(e.Type), e)
+	})
+	response, err := s.agentLoop.ProcessDirect(ctx, req.Message, sessionKey)
 	if err != nil {
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"error": err.Error(),
-		})
+		send("error", map[string]string{"error": err.Error(), "traceId": traceID})
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]string{
+	send("done", map[string]string{
 		"response": response,
+		"html":     renderMarkdown(response),
+		"traceId":  traceID,
 	})
 }
 
@@ -152,7 +457,7 @@ func (s *Server) handleTools(w http.ResponseWriter, r *http.Request) {
 	startupInfo := s.agentLoop.GetStartupInfo()
 	toolsInfo := startupInfo["tools"].(map[string]interface{})
 
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	writeJSONWithETag(w, r, map[string]interface{}{
 		"tools": toolsInfo["names"],
 	})
 }
@@ -218,39 +523,57 @@ func (s *Server) handleSkills(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	writeJSONWithETag(w, r, map[string]interface{}{
 		"skills":  skills,
 		"total":   len(skills),
 		"count":   len(skills),
 	})
 }
 
-// handleInfo 获取系统信息
-func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
+// handleProposals GET 获取所有提案, POST 接入外部提案
+func (s *Server) handleProposals(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		s.handleCreateProposal(w, r)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 
-	info := map[string]interface{}{
-		"version": "dev",
+	page, err := parsePageParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	if s.agentLoop != nil {
-		startupInfo := s.agentLoop.GetStartupInfo()
-		info["agent"] = startupInfo
+	if s.proposalService == nil {
+		s.writeList(w, []interface{}{}, 0, "")
+		return
 	}
 
-	json.NewEncoder(w).Encode(info)
-}
-
-// handleProposals 获取所有提案
-func (s *Server) handleProposals(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
-	if s.proposalService == nil {
-		json.NewEncoder(w).Encode([]interface{}{})
+	// 以存储版本号和查询参数作为 ETag, 未变化时无需重新编码; 配置 SLA 时计时按分钟变化
+	now := time.Now()
+	var extra []string
+	if s.secopsService != nil && s.secopsService.HasSLA() {
+		extra = append(extra, fmt.Sprint(now.Unix()/60))
+	}
+	if checkETag(w, r, queryETag(r, "proposals", s.proposalService.Version(), extra...)) {
 		return
 	}
 
-	proposals := s.proposalService.GetAll()
+	filter, err := parseProposalFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.Offset, filter.Limit = page.Offset, page.Limit
+	proposals, total, err := s.proposalService.GetFiltered(filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	nextCursor := ""
+	if end := page.Offset + len(proposals); page.Limit > 0 && end < total {
+		nextCursor = encodeCursor(end)
+	}
 
 	type proposalJSON struct {
 		ID         string `json:"id"`
@@ -260,6 +583,17 @@ func (s *Server) handleProposals(w http.ResponseWriter, r *http.Request) {
 		Status     string `json:"status"`
 		CreatedAt  string `json:"createdAt"`
 		UpdatedAt  string `json:"updatedAt"`
+		Severity          string `json:"severity,omitempty"`
+		Confidence        int    `json:"confidence,omitempty"`
+		Assignee          string `json:"assignee,omitempty"`
+		CommentCount      int    `json:"commentCount,omitempty"`
+		AckUntil          string `json:"ackUntil,omitempty"`
+		Recommendation    string `json:"recommendation,omitempty"`
+		SLAElapsedMinutes *int  `json:"slaElapsedMinutes,omitempty"`
+		SLALimitMinutes   *int  `json:"slaLimitMinutes,omitempty"`
+		SLABreached       *bool `json:"slaBreached,omitempty"`
+		Tactics           []string `json:"tactics,omitempty"`
+		Techniques        []string `json:"techniques,omitempty"`
 	}
 
 	result := make([]proposalJSON, len(proposals))
@@ -272,10 +606,66 @@ func (s *Server) handleProposals(w http.ResponseWriter, r *http.Request) {
 			Status:    string(p.Status),
 			CreatedAt: p.CreatedAt.Format("2006-01-02 15:04:05"),
 			UpdatedAt: p.UpdatedAt.Format("2006-01-02 15:04:05"),
+			Severity:       p.Severity,
+			Confidence:     p.Confidence,
+			Assignee:       p.Assignee,
+			CommentCount:   len(p.Comments),
+			Recommendation: p.Recommendation,
+			Tactics:        p.Tactics,
+			Techniques:     p.Techniques,
+		}
+		if ack := p.Acknowledgement; ack != nil && now.Before(ack.Until) {
+			result[i].AckUntil = ack.Until.Format("2006-01-02 15:04:05")
+		}
+		if s.secopsService != nil {
+			if elapsed, limit, ok := s.secopsService.SLAStatus(p, now); ok {
+				elapsedMin, limitMin := int(elapsed.Minutes()), int(limit.Minutes())
+				breached := elapsed > limit
+				result[i].SLAElapsedMinutes = &elapsedMin
+				result[i].SLALimitMinutes = &limitMin
+				result[i].SLABreached = &breached
+			}
 		}
 	}
 
-	json.NewEncoder(w).Encode(result)
+	items, err := selectFields(result, parseFields(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.writeList(w, items, total, nextCursor)
+}
+
+// handleAttackCoverage 按 ATT&CK 战术和技术统计提案数, 用于热力图; 支持 /api/proposals 的筛选参数
+func (s *Server) handleAttackCoverage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	filter, err := parseProposalFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var proposals []*secops.Proposal
+	if s.proposalService != nil {
+		if proposals, _, err = s.proposalService.GetFiltered(filter); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	tagged := 0
+	for _, p := range proposals {
+		if len(p.Techniques) > 0 {
+			tagged++
+		}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"total":   len(proposals),
+		"tagged":  tagged,
+		"tactics": secops.AttackCoverage(proposals),
+	})
 }
 
 // handleProposal 获取单个提案详情
@@ -293,13 +683,55 @@ func (s *Server) handleProposal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	expand, err := parseExpand(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	proposal, ok := s.proposalService.Get(id)
 	if !ok {
 		http.Error(w, "proposal not found", http.StatusNotFound)
 		return
 	}
 
-	json.NewEncoder(w).Encode(proposal)
+	detail := struct {
+		*secops.Proposal
+		proposalExpansion
+		SummaryHTML     string                        `json:"summaryHtml"`
+		Templates       []config.ActionTemplateConfig `json:"templates"`
+		Translation     *secops.SummaryVersion        `json:"translation,omitempty"`
+		TranslationHTML string                        `json:"translationHtml,omitempty"`
+		TranslationErr  string                        `json:"translationError,omitempty"`
+		Annotations     []*secops.Annotation          `json:"annotations,omitempty"`
+	}{
+		Proposal:          proposal,
+		proposalExpansion: s.expandProposal(proposal, expand),
+		SummaryHTML:       renderMarkdown(proposal.Summary),
+		Templates:         s.proposalService.Templates(proposal.Type),
+	}
+
+	if s.secopsService != nil {
+		detail.Annotations = s.secopsService.ProposalAnnotations(proposal)
+	}
+
+	// ?lang= 请求译文, 首次翻译后按提案缓存
+	if lang := r.URL.Query().Get("lang"); lang != "" && s.secopsService != nil {
+		if t, err := s.secopsService.TranslateProposal(r.Context(), id, lang); err != nil {
+			detail.TranslationErr = err.Error()
+		} else {
+			detail.Translation = t
+			detail.TranslationHTML = renderMarkdown(t.Summary)
+		}
+	}
+
+	result, err := selectFields(detail, parseFields(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(result)
 }
 
 // handleAccept 接受提案
@@ -319,20 +751,23 @@ func (s *Server) handleAccept(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var params map[string]string
-	if r.Body != nil {
-		json.NewDecoder(r.Body).Decode(&params)
-	}
-
-	if err := s.proposalService.Accept(id, params); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if err := s.proposalService.Accept(id, s.decodeDecisionBody(r)); err != nil {
+		http.Error(w, err.Error(), decisionErrorStatus(err))
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]string{
+	// 绑定的 API 在后台排队执行, 返回 202 及排队中的 execution; 未绑定或未配置执行器时为空
+	resp := map[string]interface{}{
 		"status": "accepted",
 		"id":     id,
-	})
+	}
+	if p, ok := s.proposalService.Get(id); ok && p.Execution != nil {
+		resp["execution"] = p.Execution
+		if p.Execution.Status == secops.ExecutionQueued {
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}
+	json.NewEncoder(w).Encode(resp)
 }
 
 // handleIgnore 忽略提案
@@ -352,19 +787,109 @@ func (s *Server) handleIgnore(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var params map[string]string
+	if err := s.proposalService.Ignore(id, s.decodeDecisionBody(r)); err != nil {
+		http.Error(w, err.Error(), decisionErrorStatus(err))
+		return
+	}
+
+	// 绑定的 API 在后台排队执行, 返回 202 及排队中的 execution; 未绑定或未配置执行器时为空
+	resp := map[string]interface{}{
+		"status": "ignored",
+		"id":     id,
+	}
+	if p, ok := s.proposalService.Get(id); ok && p.Execution != nil {
+		resp["execution"] = p.Execution
+		if p.Execution.Status == secops.ExecutionQueued {
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// decodeDecisionBody 解析接受/忽略请求体: {"params": {...}, "reason": "...", "template": "..."}, 决策者取自请求的认证信息
+func (s *Server) decodeDecisionBody(r *http.Request) secops.DecisionRequest {
+	var body struct {
+		Params   map[string]string `json:"params"`
+		Reason   string            `json:"reason"`
+		Template string            `json:"template"`
+	}
 	if r.Body != nil {
-		json.NewDecoder(r.Body).Decode(&params)
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+	return secops.DecisionRequest{
+		Params:   body.Params,
+		Reason:   body.Reason,
+		Template: body.Template,
+		By:       s.requestActor(r),
 	}
+}
 
-	if err := s.proposalService.Ignore(id, params); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+// decisionErrorStatus 决策失败时的 HTTP 状态码
+func decisionErrorStatus(err error) int {
+	if errors.Is(err, secops.ErrOverrideReasonRequired) || errors.Is(err, secops.ErrInvalidParam) {
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusBadRequest
+}
+
+// handleBulkDecision 批量确认或忽略提案
+//
+// 请求体: {"ids": [...], "action": "accept|ignore", "params": {...}, "reason": "...", "template": "..."},
+// 参数和理由对所选提案统一生效; 任一提案校验失败时整批不生效。
+// 决策记录后返回 202, 绑定的 API 在后台排队执行, queued 列出排队的提案, 可轮询提案详情查看执行结果
+func (s *Server) handleBulkDecision(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]string{
-		"status": "ignored",
-		"id":     id,
+	if s.proposalService == nil {
+		http.Error(w, "proposal service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var body struct {
+		IDs      []string          `json:"ids"`
+		Action   string            `json:"action"`
+		Params   map[string]string `json:"params"`
+		Reason   string            `json:"reason"`
+		Template string            `json:"template"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	decided, err := s.proposalService.DecideBulk(body.IDs, body.Action, secops.DecisionRequest{
+		Params:   body.Params,
+		Reason:   body.Reason,
+		Template: body.Template,
+		By:       s.requestActor(r),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), decisionErrorStatus(err))
+		return
+	}
+
+	results := make([]map[string]interface{}, len(decided))
+	queued := make([]string, 0, len(decided))
+	for i, p := range decided {
+		results[i] = map[string]interface{}{
+			"id":     p.ID,
+			"status": p.Status,
+		}
+		if p.Execution != nil && p.Execution.Status == secops.ExecutionQueued {
+			queued = append(queued, p.ID)
+		}
+	}
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "ok",
+		"count":   len(decided),
+		"queued":  queued,
+		"results": results,
 	})
 }
 
@@ -390,7 +915,7 @@ func (s *Server) handleResubmit(w http.ResponseWriter, r *http.Request) {
 		json.NewDecoder(r.Body).Decode(&params)
 	}
 
-	proposal, err := s.proposalService.Resubmit(id, params)
+	proposal, err := s.proposalService.Resubmit(id, params, s.requestActor(r))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -403,70 +928,811 @@ func (s *Server) handleResubmit(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// handleIndex 处理前端页面
-func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "text/html")
-	w.Write(indexHTML)
-}
+// handleRuns 获取活动执行记录, 支持 ?activity= 过滤
+func (s *Server) handleRuns(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
 
-var indexHTML = []byte(`<!DOCTYPE html>
-<html lang="zh-CN">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>安全运营龙虾</title>
-    <script src="https://cdn.tailwindcss.com"></script>
-    <script defer src="https://cdn.jsdelivr.net/npm/alpinejs@3.x.x/dist/cdn.min.js"></script>
-    <style>
-        [x-cloak] { display: none !important; }
-        .scrollbar-thin::-webkit-scrollbar { width: 6px; height: 6px; }
-        .scrollbar-thin::-webkit-scrollbar-track { background: #1f2937; }
-        .scrollbar-thin::-webkit-scrollbar-thumb { background: #4b5563; border-radius: 3px; }
-        .scrollbar-thin::-webkit-scrollbar-thumb:hover { background: #6b7280; }
-    </style>
-</head>
-<body class="bg-gray-900 text-gray-100" x-data="app()">
-    <div class="h-screen flex flex-col">
-        <!-- Header -->
-        <header class="bg-gray-800 border-b border-gray-700 px-4 py-3 flex items-center justify-between">
-            <div class="flex items-center space-x-3">
-                <span class="text-2xl">🦞</span>
-                <h1 class="text-xl font-bold">安全运营龙虾</h1>
-            </div>
-            <div class="flex items-center space-x-2">
-                <template x-for="tab in tabs" :key="tab.id">
-                    <button @click="activeTab = tab.id"
-                            :class="activeTab === tab.id ? 'bg-blue-600 text-white' : 'bg-gray-700 text-gray-300 hover:bg-gray-600'"
-                            class="px-4 py-2 rounded-lg font-medium transition-colors text-sm flex items-center space-x-2">
-                        <span x-text="tab.icon"></span>
-                        <span x-text="tab.name"></span>
-                        <span x-show="tab.id === 'proposals' && pendingCount > 0"
-                              x-text="pendingCount"
-                              class="ml-1 bg-red-500 text-white text-xs px-2 py-0.5 rounded-full"></span>
-                    </button>
-                </template>
-            </div>
-        </header>
+	page, err := parsePageParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-        <!-- Main Content -->
-        <div class="flex-1 flex overflow-hidden">
-            <!-- 对话 -->
-            <div x-show="activeTab === 'chat'" x-cloak class="flex-1 flex flex-col">
+	if s.secopsService == nil {
+		s.writeList(w, []interface{}{}, 0, "")
+		return
+	}
+
+	runs := s.secopsService.Runs(r.URL.Query().Get("activity"))
+	total := len(runs)
+	runs, nextCursor := paginate(runs, page)
+
+	items, err := selectFields(runs, parseFields(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.writeList(w, items, total, nextCursor)
+}
+
+// handleActivityEnabled POST {"enabled": bool} 启用或停用活动, 立即生效并在重启后保持
+func (s *Server) handleActivityEnabled(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.secopsService == nil {
+		http.Error(w, "secops service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Enabled == nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	name := r.PathValue("name")
+	if err := s.secopsService.SetActivityEnabled(name, *req.Enabled); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"name":    name,
+		"enabled": *req.Enabled,
+	})
+}
+
+// handleActivities 获取已配置的活动: 调度、模式、启停状态、最近一次和下次执行时间
+func (s *Server) handleActivities(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.secopsService == nil {
+		s.writeList(w, []interface{}{}, 0, "")
+		return
+	}
+
+	activities := s.secopsService.ActivitySummaries()
+	s.writeList(w, activities, len(activities), "")
+}
+
+// handleActivityTrigger 立即在后台执行一次活动, 返回 202 和执行记录
+func (s *Server) handleActivityTrigger(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.secopsService == nil {
+		http.Error(w, "secops service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	name := r.PathValue("name")
+	if _, ok := s.activitySummary(name); !ok {
+		http.Error(w, "activity not found: "+name, http.StatusNotFound)
+		return
+	}
+
+	run, err := s.secopsService.TriggerActivity(name)
+	if errors.Is(err, secops.ErrActivityRunning) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(run)
+}
+
+// handleActivityPause 暂停活动调度, 等同于停用
+func (s *Server) handleActivityPause(w http.ResponseWriter, r *http.Request) {
+	s.setActivityEnabled(w, r, false)
+}
+
+// handleActivityResume 恢复活动调度, 等同于启用
+func (s *Server) handleActivityResume(w http.ResponseWriter, r *http.Request) {
+	s.setActivityEnabled(w, r, true)
+}
+
+// setActivityEnabled 切换活动启停并返回更新后的活动概况
+func (s *Server) setActivityEnabled(w http.ResponseWriter, r *http.Request, enabled bool) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.secopsService == nil {
+		http.Error(w, "secops service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	name := r.PathValue("name")
+	if err := s.secopsService.SetActivityEnabled(name, enabled); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	summary, _ := s.activitySummary(name)
+	json.NewEncoder(w).Encode(summary)
+}
+
+// activitySummary 查找单个活动的概况
+func (s *Server) activitySummary(name string) (secops.ActivitySummary, bool) {
+	for _, sum := range s.secopsService.ActivitySummaries() {
+		if sum.Name == name {
+			return sum, true
+		}
+	}
+	return secops.ActivitySummary{}, false
+}
+
+// handleNotifyTargets 获取通知目标及引用它们的路由
+func (s *Server) handleNotifyTargets(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if s.secopsService == nil {
+		s.writeList(w, []interface{}{}, 0, "")
+		return
+	}
+
+	targets := s.secopsService.NotifyTargets()
+	s.writeList(w, targets, len(targets), "")
+}
+
+// handleNotifyTest POST 向通知目标发送测试通知
+func (s *Server) handleNotifyTest(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.secopsService == nil {
+		http.Error(w, "secops service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	result, err := s.secopsService.TestNotification(r.Context(), r.PathValue("name"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// handleRun 获取单次执行记录
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if s.secopsService == nil {
+		http.Error(w, "secops service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	run, ok := s.secopsService.Run(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "run not found", http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(run)
+}
+
+// handleRegenerate 请 Agent 重新生成提案标题和摘要
+func (s *Server) handleRegenerate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "proposal id required", http.StatusBadRequest)
+		return
+	}
+
+	if s.secopsService == nil {
+		http.Error(w, "secops service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req struct {
+		Instruction string `json:"instruction"`
+	}
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&req)
+	}
+
+	proposal, err := s.secopsService.RegenerateSummary(r.Context(), id, req.Instruction, s.requestActor(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":      "regenerated",
+		"id":          id,
+		"proposal":    proposal,
+		"summaryHtml": renderMarkdown(proposal.Summary),
+	})
+}
+
+// handleAcknowledge 知悉提案, 请求体 {"hours": 4, "reason": "..."}
+func (s *Server) handleAcknowledge(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.secopsService == nil {
+		http.Error(w, "secops service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req struct {
+		Hours  float64 `json:"hours"`
+		Reason string  `json:"reason"`
+	}
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&req)
+	}
+
+	id := r.PathValue("id")
+	proposal, err := s.secopsService.Acknowledge(id, time.Duration(req.Hours*float64(time.Hour)), req.Reason, s.requestActor(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":          "acknowledged",
+		"id":              id,
+		"acknowledgement": proposal.Acknowledgement,
+	})
+}
+
+// handleRetryExecution POST 立即重新执行失败的决策
+func (s *Server) handleRetryExecution(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.proposalService == nil {
+		http.Error(w, "proposal service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	id := r.PathValue("id")
+	execution, err := s.proposalService.RetryExecution(id, s.requestActor(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	status := secops.ProposalStatusExecutionFailed
+	if p, ok := s.proposalService.Get(id); ok {
+		status = p.Status
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    status,
+		"id":        id,
+		"execution": execution,
+	})
+}
+
+// handleCommitArtifacts 重新提交已确认提案附带的规则和配置到 Git 仓库
+func (s *Server) handleCommitArtifacts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.secopsService == nil || !s.secopsService.GitEnabled() {
+		http.Error(w, "git integration not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	id := r.PathValue("id")
+	change, err := s.secopsService.CommitArtifacts(id, s.requestActor(r))
+	if change == nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	// 提交失败时结果中带有错误, 同样记录在提案上
+	status := http.StatusOK
+	if err != nil {
+		status = http.StatusBadGateway
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":     id,
+		"change": change,
+	})
+}
+
+// handlePreviewExecution 预览决策将发送的 Sheikah 请求, 不实际调用;
+// 请求体与接受/忽略相同, 动作由 ?action=accept|ignore 指定
+func (s *Server) handlePreviewExecution(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.proposalService == nil {
+		http.Error(w, "proposal service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	preview, err := s.proposalService.Preview(r.PathValue("id"), r.URL.Query().Get("action"), s.decodeDecisionBody(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(preview)
+}
+
+// handleProposalHistory 获取提案的生命周期审计记录, 按时间顺序; 提案已归档时仍可查询
+func (s *Server) handleProposalHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if s.proposalService == nil {
+		http.Error(w, "proposal service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	id := r.PathValue("id")
+	events := s.proposalService.History(id)
+	if _, ok := s.proposalService.Get(id); !ok && len(events) == 0 {
+		http.Error(w, "proposal not found", http.StatusNotFound)
+		return
+	}
+
+	total := len(events)
+	events, nextCursor := paginate(events, page)
+	s.writeList(w, events, total, nextCursor)
+}
+
+// handleDatasetExport 导出已决策提案的 JSONL 标注数据, 支持 ?since=YYYY-MM-DD&type=risk,weak; 始终脱敏
+func (s *Server) handleDatasetExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.proposalService == nil {
+		http.Error(w, "proposal service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	opts := secops.DatasetOptions{Redact: true}
+	q := r.URL.Query()
+	if v := q.Get("since"); v != "" {
+		since, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			http.Error(w, "invalid since: "+v, http.StatusBadRequest)
+			return
+		}
+		opts.Since = since
+	}
+	if v := q.Get("type"); v != "" {
+		opts.Types = strings.Split(v, ",")
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="secops-dataset-%s.jsonl"`, time.Now().Format("20060102")))
+	if _, err := secops.ExportDataset(w, s.proposalService.GetAll(), opts); err != nil {
+		logger.ErrorCF("debugui", "Dataset export failed",
+			map[string]interface{}{
+				"error": err.Error(),
+			})
+	}
+}
+
+// handleAnnotations GET 列出标注 (?kind= 过滤, ?value= 查找适用的标注), POST 新增标注 {"kind", "value", "note", "author"}
+func (s *Server) handleAnnotations(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if s.secopsService == nil {
+		if r.Method == http.MethodGet {
+			s.writeList(w, []interface{}{}, 0, "")
+			return
+		}
+		http.Error(w, "secops service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		page, err := parsePageParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		kind, value := r.URL.Query().Get("kind"), r.URL.Query().Get("value")
+		var annotations []*secops.Annotation
+		if value != "" {
+			if kind == "" {
+				http.Error(w, "kind is required with value", http.StatusBadRequest)
+				return
+			}
+			annotations = s.secopsService.LookupAnnotations(kind, value)
+		} else {
+			annotations = s.secopsService.Annotations(kind)
+		}
+		total := len(annotations)
+		annotations, nextCursor := paginate(annotations, page)
+		s.writeList(w, annotations, total, nextCursor)
+
+	case http.MethodPost:
+		var req struct {
+			Kind   string `json:"kind"`
+			Value  string `json:"value"`
+			Note   string `json:"note"`
+			Author string `json:"author"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		annotation, err := s.secopsService.AddAnnotation(req.Kind, req.Value, req.Note, req.Author)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(annotation)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAnnotation PUT 修改标注 {"note"}, DELETE 删除标注
+func (s *Server) handleAnnotation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if s.secopsService == nil {
+		http.Error(w, "secops service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	id := r.PathValue("id")
+	switch r.Method {
+	case http.MethodPut:
+		var req struct {
+			Note string `json:"note"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		annotation, err := s.secopsService.UpdateAnnotation(id, req.Note)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(annotation)
+
+	case http.MethodDelete:
+		if !s.secopsService.DeleteAnnotation(id) {
+			http.Error(w, "annotation not found", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"status": "deleted",
+			"id":     id,
+		})
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSilences GET 获取有效的静默规则, POST 新增静默 {"type", "host", "hours", "reason"}
+func (s *Server) handleSilences(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if s.secopsService == nil {
+		if r.Method == http.MethodGet {
+			s.writeList(w, []interface{}{}, 0, "")
+			return
+		}
+		http.Error(w, "secops service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		silences := s.secopsService.Silences()
+		s.writeList(w, silences, len(silences), "")
+
+	case http.MethodPost:
+		var req struct {
+			Type   string  `json:"type"`
+			Host   string  `json:"host"`
+			Hours  float64 `json:"hours"`
+			Reason string  `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		silence, err := s.secopsService.AddSilence(req.Type, req.Host, time.Duration(req.Hours*float64(time.Hour)), req.Reason)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(silence)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSilence DELETE 提前解除静默, 可选 ?reason=
+func (s *Server) handleSilence(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.secopsService == nil {
+		http.Error(w, "secops service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	id := r.PathValue("id")
+	if err := s.secopsService.RemoveSilence(id, r.URL.Query().Get("reason")); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"status": "removed",
+		"id":     id,
+	})
+}
+
+// handleSilenceAudit 获取静默审计记录
+func (s *Server) handleSilenceAudit(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if s.secopsService == nil {
+		s.writeList(w, []interface{}{}, 0, "")
+		return
+	}
+
+	events := s.secopsService.SilenceAudit()
+	total := len(events)
+	events, nextCursor := paginate(events, page)
+	s.writeList(w, events, total, nextCursor)
+}
+
+// handleIndex 处理前端页面
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	s.writePage(w, r, indexHTML)
+}
+
+// externalBasePath 浏览器看到的子路径, 开启 trustProxy 时使用 X-Forwarded-Prefix
+func (s *Server) externalBasePath(r *http.Request) string {
+	if s.config.TrustProxy {
+		if prefix := r.Header.Get("X-Forwarded-Prefix"); prefix != "" {
+			return normalizeBasePath(prefix)
+		}
+	}
+	return s.basePath
+}
+
+// writePage 输出页面并注入子路径
+func (s *Server) writePage(w http.ResponseWriter, r *http.Request, page []byte) {
+	basePathJSON, _ := json.Marshal(s.externalBasePath(r))
+	w.Header().Set("Content-Type", "text/html")
+	w.Write(bytes.Replace(page, []byte("{{BASE_PATH_JSON}}"), basePathJSON, 1))
+}
+
+var indexHTML = []byte(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>安全运营龙虾</title>
+    <script src="https://cdn.tailwindcss.com"></script>
+    <script defer src="https://cdn.jsdelivr.net/npm/alpinejs@3.x.x/dist/cdn.min.js"></script>
+    <link rel="stylesheet" href="https://cdn.jsdelivr.net/npm/@highlightjs/cdn-assets@11/styles/github-dark.min.css">
+    <script src="https://cdn.jsdelivr.net/npm/@highlightjs/cdn-assets@11/highlight.min.js"></script>
+    <style>
+        [x-cloak] { display: none !important; }
+        .scrollbar-thin::-webkit-scrollbar { width: 6px; height: 6px; }
+        .scrollbar-thin::-webkit-scrollbar-track { background: #1f2937; }
+        .scrollbar-thin::-webkit-scrollbar-thumb { background: #4b5563; border-radius: 3px; }
+        .scrollbar-thin::-webkit-scrollbar-thumb:hover { background: #6b7280; }
+        .markdown h1, .markdown h2, .markdown h3 { font-weight: 700; margin: 0.5em 0; }
+        .markdown p { margin: 0.4em 0; }
+        .markdown ul { list-style: disc; padding-left: 1.5em; }
+        .markdown ol { list-style: decimal; padding-left: 1.5em; }
+        .markdown blockquote { border-left: 3px solid #4b5563; padding-left: 0.75em; color: #9ca3af; }
+        .markdown code { background: #111827; padding: 0.1em 0.3em; border-radius: 3px; font-size: 0.9em; }
+        .markdown pre { background: #111827; padding: 0.75em; border-radius: 6px; overflow-x: auto; margin: 0.5em 0; }
+        .markdown pre code { padding: 0; }
+        .markdown table { border-collapse: collapse; margin: 0.5em 0; font-size: 0.9em; }
+        .markdown th, .markdown td { border: 1px solid #4b5563; padding: 0.25em 0.6em; }
+        .markdown th { background: #374151; }
+        .markdown a { color: #60a5fa; text-decoration: underline; }
+    </style>
+</head>
+<body class="bg-gray-900 text-gray-100" x-data="app()">
+    <div class="h-screen flex flex-col">
+        <!-- Header -->
+        <header class="bg-gray-800 border-b border-gray-700 px-4 py-3 flex items-center justify-between">
+            <div class="flex items-center space-x-3">
+                <span class="text-2xl">🦞</span>
+                <h1 class="text-xl font-bold">安全运营龙虾</h1>
+            </div>
+            <div class="flex items-center space-x-2">
+                <a x-show="feature('login', false)" x-cloak :href="apiURL('/login')" title="登录与通行密钥"
+                   class="px-3 py-2 rounded-lg bg-gray-700 text-gray-300 hover:bg-gray-600 text-sm">🔑</a>
+                <template x-for="tab in tabs.filter(t => !t.feature || feature(t.feature))" :key="tab.id">
+                    <button @click="activeTab = tab.id"
+                            :class="activeTab === tab.id ? 'bg-blue-600 text-white' : 'bg-gray-700 text-gray-300 hover:bg-gray-600'"
+                            class="px-4 py-2 rounded-lg font-medium transition-colors text-sm flex items-center space-x-2">
+                        <span x-text="tab.icon"></span>
+                        <span x-text="tab.name"></span>
+                        <span x-show="tab.id === 'proposals' && pendingCount > 0"
+                              x-text="pendingCount"
+                              class="ml-1 bg-red-500 text-white text-xs px-2 py-0.5 rounded-full"></span>
+                    </button>
+                </template>
+            </div>
+        </header>
+        <div x-show="feature('demo', false)" x-cloak
+             class="bg-yellow-900 border-b border-yellow-700 text-yellow-200 text-sm px-4 py-1 text-center">
+            演示模式 · 数据均为示例, 处置操作不会发送到 Sheikah。配置 ClickHouse 和 Sheikah 后将 secops.demo 设为 false 即可切换到真实数据
+        </div>
+        <div x-show="feature('chaos', false)" x-cloak
+             class="bg-red-900 border-b border-red-700 text-red-200 text-sm px-4 py-1 text-center">
+            故障注入已开启 · 查询、处置、LLM 和通知会按配置比例失败或延迟, 仅用于演练, 请勿在生产环境使用
+        </div>
+
+        <!-- Main Content -->
+        <div class="flex-1 flex overflow-hidden">
+            <!-- 对话 -->
+            <div x-show="activeTab === 'chat'" x-cloak class="flex-1 flex flex-col">
+                <!-- 会话切换 -->
+                <div class="px-4 py-2 border-b border-gray-700 flex items-center space-x-2 text-sm">
+                    <span class="text-gray-400">会话</span>
+                    <select x-model="chatSession" @change="loadChatSession(chatSession)" :disabled="isLoading"
+                            class="bg-gray-800 border border-gray-600 rounded px-2 py-1 text-white">
+                        <template x-for="sess in chatSessions" :key="sess.id">
+                            <option :value="sess.id" :selected="sess.id === chatSession"
+                                    x-text="sess.id + ' · ' + sess.messages + ' 条' + (sess.updatedAt ? ' · ' + new Date(sess.updatedAt).toLocaleString() : '')"></option>
+                        </template>
+                    </select>
+                    <button @click="newChatSession()" :disabled="isLoading"
+                            class="px-2 py-1 bg-gray-700 rounded hover:bg-gray-600 disabled:opacity-50">新会话</button>
+                    <button @click="clearChatSession()" :disabled="isLoading || messages.length === 0"
+                            class="px-2 py-1 text-red-400 hover:text-red-300 disabled:opacity-50">清空</button>
+                </div>
                 <!-- 消息列表 -->
                 <div class="flex-1 overflow-y-auto p-4 space-y-4 scrollbar-thin">
                     <template x-for="(msg, idx) in messages" :key="idx">
                         <div :class="msg.role === 'user' ? 'ml-auto bg-blue-600' : 'mr-auto bg-gray-700'"
                              class="max-w-3xl rounded-lg p-3 px-4">
                             <div class="text-xs text-gray-400 mb-1" x-text="msg.role === 'user' ? '你' : '龙虾'"></div>
-                            <div class="whitespace-pre-wrap" x-text="msg.content"></div>
+                            <template x-for="(step, sidx) in (msg.steps || [])" :key="sidx">
+                                <div class="text-xs text-gray-400 mb-1">
+                                    <div x-show="step.note" class="whitespace-pre-wrap text-gray-500 mb-1" x-text="step.note"></div>
+                                    <span x-text="step.status === 'running' ? '⏳' : (step.isError ? '❌' : '✅')"></span>
+                                    <span class="font-mono text-gray-300" x-text="step.tool"></span>
+                                    <span class="font-mono break-all" x-text="step.args"></span>
+                                    <span x-show="step.durationMs !== undefined" class="text-gray-500" x-text="step.durationMs + ' ms'"></span>
+                                    <details x-show="step.result" class="ml-5">
+                                        <summary class="cursor-pointer">结果</summary>
+                                        <pre class="whitespace-pre-wrap text-gray-400" x-text="step.result"></pre>
+                                    </details>
+                                </div>
+                            </template>
+                            <div x-show="msg.streaming && !msg.content" class="text-gray-400 text-sm" x-text="msg.queued ? '排队中, 当前第 ' + msg.queued + ' 位...' : '思考中...'"></div>
+                            <div x-show="!msg.html" class="whitespace-pre-wrap" x-text="msg.content"></div>
+                            <div x-show="msg.html" class="markdown" x-html="msg.html"></div>
+                            <details x-show="msg.traceId" class="mt-2 text-xs text-gray-400" @toggle="$event.target.open && loadTrace(msg)">
+                                <summary class="cursor-pointer">工具调用记录</summary>
+                                <div x-show="msg.trace === undefined">加载中...</div>
+                                <div x-show="msg.trace === null">记录已过期</div>
+                                <template x-if="msg.trace">
+                                    <div>
+                                        <template x-for="(call, cidx) in msg.trace.calls" :key="cidx">
+                                            <div class="mt-1">
+                                                <span x-text="call.isError ? '❌' : '✅'"></span>
+                                                <span class="font-mono text-gray-300" x-text="call.tool"></span>
+                                                <span class="text-gray-500" x-text="call.durationMs + ' ms'"></span>
+                                                <details class="ml-5">
+                                                    <summary class="cursor-pointer">参数与结果</summary>
+                                                    <pre class="whitespace-pre-wrap break-all" x-text="call.args"></pre>
+                                                    <pre class="whitespace-pre-wrap text-gray-500" x-text="call.result"></pre>
+                                                </details>
+                                            </div>
+                                        </template>
+                                        <div class="mt-1 text-gray-500"
+                                             x-text="msg.trace.calls.length ? '共 ' + msg.trace.calls.length + ' 次工具调用, 总耗时 ' + msg.trace.durationMs + ' ms' : '未调用工具'"></div>
+                                    </div>
+                                </template>
+                            </details>
                         </div>
                     </template>
                     <div x-show="messages.length === 0" class="text-center text-gray-500 py-8">
                         开始与安全运营龙虾对话吧
                     </div>
+                    <template x-for="a in approvals" :key="a.id">
+                        <div class="mr-auto max-w-3xl rounded-lg p-3 px-4 bg-gray-800 border border-yellow-600">
+                            <div class="text-xs text-yellow-400 mb-1">待确认的操作</div>
+                            <div class="font-mono text-sm mb-1" x-text="a.tool"></div>
+                            <pre class="text-xs text-gray-300 whitespace-pre-wrap mb-2" x-text="JSON.stringify(a.args, null, 2)"></pre>
+                            <div class="flex space-x-2">
+                                <button @click="resolveApproval(a.id, true)"
+                                        class="px-3 py-1 text-sm bg-green-600 text-white rounded hover:bg-green-500">执行</button>
+                                <button @click="resolveApproval(a.id, false)"
+                                        class="px-3 py-1 text-sm bg-gray-600 text-white rounded hover:bg-gray-500">拒绝</button>
+                            </div>
+                        </div>
+                    </template>
                 </div>
                 <!-- 输入框 -->
                 <div class="p-4 border-t border-gray-700">
+                    <label class="flex items-center space-x-2 text-xs text-gray-400 mb-2">
+                        <input type="checkbox" x-model="confirmTools" @change="localStorage.setItem('confirmTools', confirmTools)">
+                        <span>修改类工具调用 (如 sheikah_api 确认/忽略) 执行前逐次确认</span>
+                    </label>
                     <form @submit.prevent="sendMessage" class="flex space-x-2">
                         <input type="text" x-model="inputMessage"
                                placeholder="输入消息..."
@@ -530,19 +1796,154 @@ var indexHTML = []byte(`<!DOCTYPE html>
                     </button>
                 </div>
 
+                <!-- 通知静默 -->
+                <div x-show="silences.length > 0" class="mb-6">
+                    <h3 class="text-sm font-medium text-gray-400 mb-3">通知静默</h3>
+                    <div class="space-y-2">
+                        <template x-for="sl in silences" :key="sl.id">
+                            <div class="flex items-center justify-between bg-gray-800 rounded px-3 py-2 text-sm">
+                                <span>
+                                    <span class="text-gray-200" x-text="[sl.type, sl.host].filter(Boolean).join(' / ')"></span>
+                                    <span class="text-gray-500 ml-2" x-text="'至 ' + new Date(sl.expiresAt).toLocaleString()"></span>
+                                    <span class="text-gray-500 ml-2" x-show="sl.reason" x-text="sl.reason"></span>
+                                </span>
+                                <button @click="removeSilence(sl.id)" class="text-xs text-gray-400 hover:text-white">解除</button>
+                            </div>
+                        </template>
+                    </div>
+                </div>
+
+                <!-- ATT&CK 覆盖热力图 -->
+                <div x-show="coverage && coverage.tagged > 0" class="mb-6">
+                    <div class="flex items-center justify-between mb-3">
+                        <h3 class="text-sm font-medium text-gray-400">
+                            ATT&amp;CK 覆盖
+                            <span class="text-gray-500 ml-2" x-text="coverage ? coverage.tagged + ' / ' + coverage.total + ' 个提案已标注' : ''"></span>
+                        </h3>
+                        <button x-show="techniqueFilter" @click="filterTechnique(techniqueFilter)"
+                                class="text-xs text-gray-400 hover:text-white" x-text="'清除筛选 ' + techniqueFilter"></button>
+                    </div>
+                    <div class="flex space-x-2 overflow-x-auto scrollbar-thin pb-2">
+                        <template x-for="col in (coverage ? coverage.tactics : [])" :key="col.id || 'unmapped'">
+                            <div class="w-36 flex-shrink-0">
+                                <div class="text-xs font-semibold rounded px-2 py-1 mb-1"
+                                     :class="heatClass(col.count)" :title="col.id">
+                                    <span x-text="col.name"></span>
+                                    <span class="float-right" x-text="col.count || ''"></span>
+                                </div>
+                                <template x-for="cell in col.techniques" :key="cell.technique">
+                                    <button @click="filterTechnique(cell.technique)"
+                                            class="w-full text-left text-xs rounded px-2 py-1 mb-1 truncate"
+                                            :class="[heatClass(cell.count), techniqueFilter === cell.technique ? 'ring-2 ring-white' : '']"
+                                            :title="cell.technique + ' ' + (cell.name || '')">
+                                        <span x-text="cell.technique"></span>
+                                        <span class="float-right" x-text="cell.count"></span>
+                                    </button>
+                                </template>
+                            </div>
+                        </template>
+                    </div>
+                </div>
+
+                <!-- 提案分组 -->
+                <div class="mb-6">
+                    <div class="flex flex-wrap items-center gap-2 mb-3">
+                        <h3 class="text-sm font-medium text-gray-400 mr-2">分组</h3>
+                        <select x-model="groupBy" @change="groupKey = null; fetchProposals()"
+                                class="px-2 py-1 bg-gray-700 border border-gray-600 rounded text-xs">
+                            <option value="">不分组</option>
+                            <option value="host">按主机</option>
+                            <option value="signature">按风险特征</option>
+                            <option value="type">按类型</option>
+                        </select>
+                        <template x-if="groupKey !== null">
+                            <button @click="groupKey = null; fetchProposals()" class="text-xs text-gray-400 hover:text-white"
+                                    x-text="'当前分组: ' + groupLabel(groupKey) + ' ✕'"></button>
+                        </template>
+                    </div>
+                    <div x-show="groupBy && groupKey === null" class="grid gap-2 sm:grid-cols-2 lg:grid-cols-4">
+                        <template x-for="g in groups" :key="g.key">
+                            <button @click="groupKey = g.key; fetchProposals()"
+                                    class="text-left bg-gray-800 rounded-lg px-3 py-2 border hover:border-blue-500 transition-colors"
+                                    :class="g.pending > 0 ? 'border-yellow-700' : 'border-gray-700'">
+                                <div class="flex items-center justify-between">
+                                    <span class="font-mono text-sm truncate" x-text="groupLabel(g.key)"></span>
+                                    <span x-show="g.maxSeverity" class="px-1.5 py-0.5 text-xs rounded ml-2"
+                                          :class="severityClass(g.maxSeverity)" x-text="g.maxSeverity"></span>
+                                </div>
+                                <div class="text-xs mt-1">
+                                    <span :class="g.pending > 0 ? 'text-yellow-400' : 'text-gray-500'" x-text="g.pending + ' 待处理'"></span>
+                                    <span class="text-gray-500" x-text="' / 共 ' + g.count"></span>
+                                </div>
+                            </button>
+                        </template>
+                        <p x-show="groups.length === 0" class="text-xs text-gray-500">暂无提案</p>
+                    </div>
+                </div>
+
                 <!-- 待处理提案 -->
-                <div x-show="pendingProposals.length > 0" class="mb-6">
-                    <h3 class="text-sm font-medium text-gray-400 mb-3">待处理</h3>
+                <div x-show="pendingProposals.length > 0 || severityFilter || assigneeFilter" class="mb-6">
+                    <div class="flex flex-wrap items-center gap-2 mb-3">
+                        <h3 class="text-sm font-medium text-gray-400 mr-2">待处理</h3>
+                        <select x-model="severityFilter" @change="fetchProposals()"
+                                class="px-2 py-1 bg-gray-700 border border-gray-600 rounded text-xs">
+                            <option value="">全部级别</option>
+                            <option value="critical">critical</option>
+                            <option value="critical,high">high 及以上</option>
+                            <option value="critical,high,medium">medium 及以上</option>
+                        </select>
+                        <label class="flex items-center text-xs text-gray-400">
+                            <input type="checkbox" class="mr-1" x-model="sortBySeverity" @change="fetchProposals()">
+                            严重级别优先
+                        </label>
+                        <input type="text" x-model="assigneeFilter" @change="fetchProposals()" placeholder="负责人 (- 为未指派)"
+                               class="px-2 py-1 bg-gray-700 border border-gray-600 rounded text-xs w-36">
+                        <label class="flex items-center text-xs text-gray-400">
+                            <input type="checkbox" class="mr-1"
+                                   :checked="selectedIds.length > 0 && selectedIds.length === pendingProposals.length"
+                                   @change="toggleSelectAll($event.target.checked)">
+                            全选
+                        </label>
+                        <template x-if="selectedIds.length > 0">
+                            <div class="flex flex-wrap items-center gap-2">
+                                <span class="text-xs text-gray-400" x-text="'已选 ' + selectedIds.length + ' 项'"></span>
+                                <input type="text" x-model="bulkReason" placeholder="统一理由 (可选)"
+                                       class="px-2 py-1 bg-gray-700 border border-gray-600 rounded text-xs w-48">
+                                <button @click="bulkDecide('accept')"
+                                        class="px-3 py-1 bg-green-600 text-xs rounded hover:bg-green-700">批量确认</button>
+                                <button @click="bulkDecide('ignore')"
+                                        class="px-3 py-1 bg-gray-600 text-xs rounded hover:bg-gray-700">批量忽略</button>
+                            </div>
+                        </template>
+                    </div>
                     <div class="grid gap-4 lg:grid-cols-2">
                         <template x-for="p in pendingProposals" :key="p.id">
                             <div class="bg-gray-800 rounded-lg p-4 border border-yellow-600 hover:border-yellow-500 transition-colors">
                                 <div class="flex items-center justify-between mb-2">
-                                    <span class="px-2 py-1 text-xs font-semibold rounded"
-                                          :class="typeClass(p.type)" x-text="p.type"></span>
+                                    <span>
+                                        <input type="checkbox" class="mr-1" :value="p.id" x-model="selectedIds">
+                                        <span class="px-2 py-1 text-xs font-semibold rounded"
+                                              :class="typeClass(p.type)" x-text="p.type"></span>
+                                        <span x-show="p.severity" class="px-2 py-1 text-xs rounded"
+                                              :class="severityClass(p.severity)" x-text="p.severity"></span>
+                                        <span x-show="p.confidence" class="text-xs text-gray-400" title="Agent 置信度"
+                                              x-text="'置信度 ' + p.confidence + '%'"></span>
+                                        <span x-show="p.assignee" class="text-xs text-blue-300" x-text="'@' + p.assignee"></span>
+                                        <span x-show="p.commentCount" class="text-xs text-gray-400" x-text="'💬 ' + p.commentCount"></span>
+                                    </span>
                                     <span class="text-xs text-gray-500" x-text="p.createdAt"></span>
                                 </div>
                                 <h4 class="font-bold mb-1" x-text="p.title"></h4>
                                 <p class="text-sm text-gray-400 mb-3" x-text="p.summary"></p>
+                                <div x-show="p.techniques && p.techniques.length" class="mb-2">
+                                    <template x-for="t in (p.techniques || [])" :key="t">
+                                        <span class="px-1.5 py-0.5 mr-1 text-xs rounded bg-gray-700 text-gray-300" x-text="t"></span>
+                                    </template>
+                                </div>
+                                <div x-show="p.ackUntil" class="text-xs text-blue-400 mb-1" x-text="'已知悉, 提醒暂停至 ' + p.ackUntil"></div>
+                                <div x-show="p.slaLimitMinutes" class="text-xs mb-3"
+                                     :class="p.slaBreached ? 'text-red-400' : 'text-gray-500'"
+                                     x-text="'SLA: ' + p.slaElapsedMinutes + ' / ' + p.slaLimitMinutes + ' 分钟 (工作时间)'"></div>
                                 <div class="flex space-x-2">
                                     <button @click="acceptProposal(p.id)"
                                             class="px-3 py-1 bg-green-600 text-sm rounded hover:bg-green-700">确认</button>
@@ -605,9 +2006,158 @@ var indexHTML = []byte(`<!DOCTYPE html>
 
             <!-- 设置 -->
             <div x-show="activeTab === 'settings'" x-cloak class="flex-1 p-6 overflow-y-auto scrollbar-thin">
+                <div x-show="feature('login', false)" x-cloak class="mb-6">
+                    <div class="flex items-center justify-between mb-4">
+                        <h2 class="text-xl font-bold">登录会话</h2>
+                        <button @click="logoutAll()"
+                                class="px-3 py-1.5 rounded-lg bg-red-600 hover:bg-red-700 text-sm">所有设备退出登录</button>
+                    </div>
+                    <div class="bg-gray-800 rounded-lg border border-gray-700 divide-y divide-gray-700">
+                        <template x-for="sess in sessions" :key="sess.id">
+                            <div class="p-3 flex items-center justify-between text-sm">
+                                <div>
+                                    <div>
+                                        <span x-text="sess.clientIp"></span>
+                                        <span class="text-gray-400" x-text="sessionMethod(sess)"></span>
+                                        <span x-show="sess.current" class="ml-1 text-xs bg-green-700 px-2 py-0.5 rounded">当前</span>
+                                    </div>
+                                    <div class="text-xs text-gray-500 truncate max-w-xl" x-text="sess.userAgent"></div>
+                                    <div class="text-xs text-gray-500"
+                                         x-text="'登录 ' + new Date(sess.createdAt).toLocaleString() + ' · 最近活动 ' + new Date(sess.lastSeenAt).toLocaleString() + ' · 到期 ' + new Date(sess.expiresAt).toLocaleString()"></div>
+                                </div>
+                                <button @click="revokeSession(sess)" class="text-xs text-red-400 hover:text-red-300">注销</button>
+                            </div>
+                        </template>
+                    </div>
+                </div>
                 <h2 class="text-xl font-bold mb-4">系统信息</h2>
-                <div class="bg-gray-800 rounded-lg p-4 border border-gray-700">
-                    <pre class="text-sm text-gray-300 whitespace-pre-wrap" x-text="JSON.stringify(info, null, 2)"></pre>
+                <div x-show="update && update.updateAvailable" x-cloak
+                     class="bg-blue-900 border border-blue-700 rounded-lg p-3 mb-4 text-sm flex items-center justify-between">
+                    <span x-text="'发现新版本 ' + (update && update.latest) + ', 当前版本 ' + (update && update.current)"></span>
+                    <a x-show="update && update.releaseUrl" :href="update && update.releaseUrl" target="_blank" rel="noopener"
+                       class="text-blue-300 hover:underline">查看发布说明</a>
+                </div>
+                <div x-show="update && update.error" x-cloak class="text-xs text-gray-500 mb-2"
+                     x-text="'版本检查失败: ' + (update && update.error)"></div>
+                <div class="bg-gray-800 rounded-lg p-4 border border-gray-700 mb-6 text-sm space-y-1">
+                    <div><span class="text-gray-400">版本</span> <span x-text="info.version"></span>
+                        <span x-show="info.build && info.build.gitCommit" class="text-gray-500" x-text="'(git: ' + (info.build && info.build.gitCommit) + ')'"></span></div>
+                    <div x-show="info.build && info.build.buildTime"><span class="text-gray-400">构建时间</span> <span x-text="info.build && info.build.buildTime"></span></div>
+                    <div><span class="text-gray-400">Go</span> <span x-text="info.build && info.build.goVersion"></span></div>
+                    <div class="flex flex-wrap gap-1 pt-2">
+                        <template x-for="(on, name) in (info.features || {})" :key="name">
+                            <span class="px-2 py-0.5 rounded text-xs" :class="on ? 'bg-green-800 text-green-200' : 'bg-gray-700 text-gray-400'" x-text="name"></span>
+                        </template>
+                    </div>
+                </div>
+
+                <h2 x-show="(info.dependencies || []).length > 0" class="text-xl font-bold mb-4">依赖状态</h2>
+                <div x-show="(info.dependencies || []).length > 0" class="bg-gray-800 rounded-lg border border-gray-700 divide-y divide-gray-700 mb-6">
+                    <template x-for="dep in (info.dependencies || [])" :key="dep.name">
+                        <div class="p-3 flex items-center justify-between text-sm">
+                            <div>
+                                <span x-text="dep.name"></span>
+                                <span class="text-gray-500 ml-2" x-text="dep.target"></span>
+                                <span x-show="dep.error" class="text-red-400 ml-2" x-text="dep.error"></span>
+                                <span x-show="dep.circuitOpen" class="ml-2 text-xs px-1.5 py-0.5 rounded bg-red-900 text-red-200" title="连续查询失败已熔断, 调度的活动跳过执行, 探测恢复后自动继续">已熔断</span>
+                            </div>
+                            <span class="px-2 py-0.5 rounded text-xs"
+                                  :class="{ 'bg-green-800 text-green-200': dep.status === 'ok', 'bg-red-800 text-red-200': dep.status === 'down', 'bg-yellow-800 text-yellow-200': dep.status === 'demo', 'bg-gray-700 text-gray-300': !['ok', 'down', 'demo'].includes(dep.status) }"
+                                  x-text="dep.status + (dep.latencyMs ? ' · ' + dep.latencyMs + 'ms' : '')"></span>
+                        </div>
+                    </template>
+                </div>
+
+                <h2 x-show="stats.stores.length > 0" class="text-xl font-bold mb-4">内存用量</h2>
+                <div x-show="stats.stores.length > 0" class="bg-gray-800 rounded-lg border border-gray-700 divide-y divide-gray-700 mb-6">
+                    <template x-for="store in stats.stores" :key="store.name">
+                        <div class="p-3 flex items-center justify-between text-sm">
+                            <div>
+                                <span x-text="store.name"></span>
+                                <span class="text-gray-500 ml-2" x-text="store.items + (store.maxItems ? ' / ' + store.maxItems : '') + ' 条 · ' + formatBytes(store.bytes) + (store.maxBytes ? ' / ' + formatBytes(store.maxBytes) : '')"></span>
+                            </div>
+                            <span class="text-xs text-gray-400"
+                                  x-text="'已淘汰 ' + store.evicted + (store.offloaded ? ' · 磁盘 ' + store.offloaded : '')"></span>
+                        </div>
+                    </template>
+                </div>
+
+                <div x-show="feature('diagnostics', false)" x-cloak class="mb-6 text-sm">
+                    <a :href="apiURL('/api/debug/snapshot')" class="text-blue-400 hover:underline">下载诊断快照</a>
+                    <span class="text-gray-500 ml-2">goroutine 调用栈、堆 profile 和内存用量 (tar.gz)</span>
+                </div>
+
+                <h2 x-show="(info.activities || []).length > 0" class="text-xl font-bold mb-4">运营活动</h2>
+                <div x-show="(info.activities || []).length > 0" class="bg-gray-800 rounded-lg border border-gray-700 divide-y divide-gray-700">
+                    <template x-for="act in (info.activities || [])" :key="act.name">
+                        <div class="p-3 flex items-center justify-between text-sm">
+                            <div>
+                                <span x-text="act.name" :class="act.enabled ? '' : 'text-gray-500 line-through'"></span>
+                                <span class="text-gray-500 ml-2" x-text="act.mode + ' · ' + act.schedule"></span>
+                                <span x-show="act.window" class="ml-2 text-xs px-1.5 py-0.5 rounded bg-gray-700 text-gray-300" title="执行时段, 时段外的调度推迟到下一个时段开始" x-text="act.window"></span>
+                                <span x-show="act.dryRun" class="ml-2 text-xs px-1.5 py-0.5 rounded bg-yellow-900 text-yellow-200" title="修改类 Sheikah API 调用不发送, 返回模拟成功">试运行</span>
+                                <span x-show="act.playbook" class="ml-2 text-xs px-1.5 py-0.5 rounded bg-indigo-900 text-indigo-200" :title="act.playbook">剧本</span>
+                                <span x-show="act.lastError" class="text-red-400 ml-2" x-text="act.lastError"></span>
+                            </div>
+                            <div class="flex items-center space-x-3">
+                                <span class="text-xs text-gray-400"
+                                      x-text="act.running ? '执行中...' : act.queued ? '排队中...' : (act.lastRunAt ? act.lastStatus + ' · ' + new Date(act.lastRunAt).toLocaleString() : '未执行')"></span>
+                                <span x-show="act.skipped" class="text-xs text-yellow-400" title="上一次执行未结束、非工作日或执行时段外, 调度被跳过"
+                                      x-text="'跳过 ' + act.skipped + ' 次'"></span>
+                                <span x-show="act.nextRunAt" class="text-xs text-gray-500"
+                                      x-text="'下次 ' + new Date(act.nextRunAt).toLocaleString()"></span>
+                                <button @click="triggerActivity(act)" :disabled="act.running || act.queued"
+                                        class="text-xs px-2 py-1 rounded bg-gray-700 hover:bg-gray-600 disabled:opacity-50">立即执行</button>
+                                <span x-show="act.overridden" class="text-xs text-yellow-400" title="启停状态已在界面修改, 与配置文件不同">已覆盖</span>
+                                <button @click="toggleActivity(act)" role="switch" :aria-checked="act.enabled"
+                                        :title="act.enabled ? '停用' : '启用'"
+                                        class="relative inline-flex h-5 w-9 items-center rounded-full transition"
+                                        :class="act.enabled ? 'bg-green-600' : 'bg-gray-600'">
+                                    <span class="inline-block h-4 w-4 transform rounded-full bg-white transition"
+                                          :class="act.enabled ? 'translate-x-4' : 'translate-x-1'"></span>
+                                </button>
+                            </div>
+                        </div>
+                    </template>
+                </div>
+
+                <h2 x-show="notifyTargets.length > 0" class="text-xl font-bold mb-4 mt-6">通知目标</h2>
+                <div x-show="notifyTargets.length > 0" class="bg-gray-800 rounded-lg border border-gray-700 divide-y divide-gray-700 mb-6">
+                    <template x-for="target in notifyTargets" :key="target.name">
+                        <div class="p-3 flex items-center justify-between text-sm">
+                            <div>
+                                <span x-text="target.name"></span>
+                                <span class="text-gray-500 ml-2"
+                                      x-text="target.type === 'channel' ? target.channel + ' · ' + target.chatId : target.type + ' · ' + target.url"></span>
+                                <span x-show="target.signed" class="ml-1 text-xs bg-gray-700 px-2 py-0.5 rounded" title="请求附带 HMAC-SHA256 签名">已签名</span>
+                                <span class="text-gray-500 ml-2"
+                                      x-text="target.routes.length ? target.routes.length + ' 条路由' : '未被任何路由引用'"></span>
+                            </div>
+                            <div class="flex items-center space-x-3">
+                                <template x-if="notifyTests[target.name]">
+                                    <span class="text-xs"
+                                          :class="notifyTests[target.name].status === 'failed' ? 'text-red-400' : 'text-green-400'"
+                                          x-text="notifyTests[target.name].status === 'failed' ? '失败: ' + notifyTests[target.name].error : (notifyTests[target.name].status === 'queued' ? '已提交到消息通道' : '发送成功 · ' + notifyTests[target.name].latencyMs + 'ms')"></span>
+                                </template>
+                                <button @click="testNotify(target.name)"
+                                        class="px-2 py-1 text-xs bg-gray-700 hover:bg-gray-600 rounded">发送测试</button>
+                            </div>
+                        </div>
+                    </template>
+                </div>
+
+                <h2 x-show="reports.length > 0" class="text-xl font-bold mb-4 mt-6">运营报告</h2>
+                <div x-show="reports.length > 0" class="bg-gray-800 rounded-lg border border-gray-700 divide-y divide-gray-700 mb-6 max-h-96 overflow-y-auto scrollbar-thin">
+                    <template x-for="report in reports" :key="report.name">
+                        <div class="p-3 flex items-center justify-between text-sm">
+                            <div>
+                                <span x-text="report.name"></span>
+                                <span class="text-gray-500 ml-2" x-text="new Date(report.modifiedAt).toLocaleString()"></span>
+                            </div>
+                            <a :href="exportURL('/api/export/report/' + encodeURIComponent(report.name))" target="_blank"
+                               class="px-2 py-1 text-xs bg-gray-700 hover:bg-gray-600 rounded">导出 PDF</a>
+                        </div>
+                    </template>
                 </div>
             </div>
         </div>
@@ -638,12 +2188,79 @@ var indexHTML = []byte(`<!DOCTYPE html>
                         <div>
                             <div class="p-6">
                                 <div class="flex items-center justify-between mb-4">
-                                    <span class="px-3 py-1 text-sm font-semibold rounded"
-                                          :class="typeClass(currentProposal.type)" x-text="currentProposal.type"></span>
+                                    <span>
+                                        <span class="px-3 py-1 text-sm font-semibold rounded"
+                                              :class="typeClass(currentProposal.type)" x-text="currentProposal.type"></span>
+                                        <span x-show="currentProposal.severity" class="px-2 py-1 text-xs rounded"
+                                              :class="severityClass(currentProposal.severity)" x-text="currentProposal.severity"></span>
+                                        <span x-show="currentProposal.confidence" class="text-xs text-gray-400"
+                                              x-text="'置信度 ' + currentProposal.confidence + '%'"></span>
+                                    </span>
                                     <span class="text-sm text-gray-400" x-text="currentProposal.createdAt"></span>
                                 </div>
-                                <h3 class="text-xl font-bold mb-2" x-text="currentProposal.title"></h3>
-                                <p class="text-gray-400 mb-4" x-text="currentProposal.summary"></p>
+                                <template x-if="currentProposal.translation && !showOriginalText">
+                                    <div>
+                                        <h3 class="text-xl font-bold mb-2" x-text="currentProposal.translation.title"></h3>
+                                        <div class="markdown text-gray-400 mb-4" x-html="currentProposal.translationHtml"></div>
+                                    </div>
+                                </template>
+                                <template x-if="!currentProposal.translation || showOriginalText">
+                                    <div>
+                                        <h3 class="text-xl font-bold mb-2" x-text="currentProposal.title"></h3>
+                                        <div x-show="currentProposal.summaryHtml" class="markdown text-gray-400 mb-4" x-html="currentProposal.summaryHtml"></div>
+                                        <p x-show="!currentProposal.summaryHtml" class="text-gray-400 mb-4" x-text="currentProposal.summary"></p>
+                                    </div>
+                                </template>
+                                <div x-show="currentProposal.techniques && currentProposal.techniques.length" class="mb-3 text-xs">
+                                    <span class="text-gray-400 mr-2">ATT&amp;CK</span>
+                                    <template x-for="t in (currentProposal.techniques || [])" :key="t">
+                                        <a :href="'https://attack.mitre.org/techniques/' + t.replace('.', '/') + '/'" target="_blank" rel="noopener"
+                                           class="px-1.5 py-0.5 mr-1 rounded bg-gray-700 text-gray-300 hover:text-white" x-text="t"></a>
+                                    </template>
+                                </div>
+                                <div class="flex items-center space-x-3 mb-2 text-xs text-gray-400">
+                                    <span>翻译</span>
+                                    <select x-model="translateLang" @change="saveTranslateLang(); viewProposal(currentProposal.id)"
+                                            class="bg-gray-900 border border-gray-600 rounded px-2 py-0.5 text-white">
+                                        <option value="">原文</option>
+                                        <option value="zh-CN">中文</option>
+                                        <option value="en">English</option>
+                                        <option value="ja">日本語</option>
+                                    </select>
+                                    <button x-show="currentProposal.translation" @click="showOriginalText = !showOriginalText"
+                                            class="hover:text-white" x-text="showOriginalText ? '显示译文' : '显示原文'"></button>
+                                    <span x-show="currentProposal.translationError" class="text-red-400" x-text="currentProposal.translationError"></span>
+                                </div>
+                                <div class="flex items-center space-x-3 mb-4 text-xs">
+                                    <span x-show="currentProposal.summaryRegenerated" class="px-2 py-0.5 rounded bg-purple-900 text-purple-300">Agent 重新生成</span>
+                                    <button x-show="currentProposal.originalSummary" @click="showOriginalSummary = !showOriginalSummary"
+                                            class="text-gray-400 hover:text-white" x-text="showOriginalSummary ? '隐藏原始版本' : '查看原始版本'"></button>
+                                    <button @click="regenerateSummary()" :disabled="regenerating"
+                                            class="text-blue-400 hover:text-blue-300 disabled:opacity-50" x-text="regenerating ? '生成中...' : '重新生成摘要'"></button>
+                                </div>
+                                <div x-show="showOriginalSummary && currentProposal.originalSummary" class="mb-4 p-3 border border-gray-700 rounded text-sm">
+                                    <div class="text-gray-300 font-medium mb-1" x-text="currentProposal.originalSummary?.title"></div>
+                                    <p class="text-gray-500 whitespace-pre-wrap" x-text="currentProposal.originalSummary?.summary"></p>
+                                </div>
+                                <p x-show="currentProposal.acknowledgement" class="text-sm text-blue-400 mb-4"
+                                   x-text="currentProposal.acknowledgement ? '已知悉, 提醒暂停至 ' + new Date(currentProposal.acknowledgement.until).toLocaleString() : ''"></p>
+                                <div x-show="(currentProposal.annotations || []).length > 0 || currentProposal.details?.host" class="mb-4">
+                                    <div class="flex items-center justify-between">
+                                        <h4 class="text-sm font-medium text-gray-400">标注</h4>
+                                        <button x-show="currentProposal.details && currentProposal.details.host"
+                                                @click="annotateHost()" class="text-xs text-blue-400 hover:text-blue-300">标注主机</button>
+                                    </div>
+                                    <template x-for="a in (currentProposal.annotations || [])" :key="a.id">
+                                        <div class="text-sm bg-gray-900 rounded px-3 py-2 mt-2">
+                                            <span class="text-xs text-gray-500" x-text="a.kind + ': ' + a.value"></span>
+                                            <p class="text-gray-200" x-text="a.note"></p>
+                                        </div>
+                                    </template>
+                                </div>
+
+                                <p x-show="currentProposal.recommendation" class="text-sm text-gray-400 mb-4">
+                                    Agent 建议: <span class="text-gray-200" x-text="currentProposal.recommendation === 'accept' ? '接受' : '忽略'"></span>
+                                </p>
 
                                 <div class="bg-gray-900 rounded-lg p-4 mb-4">
                                     <h4 class="text-sm font-medium text-gray-400 mb-2">详细信息</h4>
@@ -657,14 +2274,338 @@ var indexHTML = []byte(`<!DOCTYPE html>
                                     </div>
                                 </div>
 
+                                <p x-show="currentProposal.run" class="text-xs text-gray-500 mb-4">
+                                    来自活动 <span class="text-gray-300" x-text="currentProposal.run?.activity"></span>
+                                    执行于 <span x-text="currentProposal.run ? new Date(currentProposal.run.startedAt).toLocaleString() : ''"></span>
+                                    (<span x-text="currentProposal.run?.status"></span>)
+                                    <template x-for="hook in (currentProposal.run?.hooks || [])">
+                                        <span class="ml-2" :class="hook.status === 'succeeded' ? 'text-green-500' : 'text-red-400'"
+                                              :title="hook.error || hook.output || ''"
+                                              x-text="hook.type + (hook.target ? ' → ' + hook.target : '')"></span>
+                                    </template>
+                                </p>
+
+                                <div x-show="(currentProposal.run?.steps || []).length > 0" class="bg-gray-900 rounded-lg p-4 mb-4">
+                                    <h4 class="text-sm font-medium text-gray-400 mb-2">剧本步骤</h4>
+                                    <div class="space-y-1 text-xs">
+                                        <template x-for="step in (currentProposal.run?.steps || [])">
+                                            <div class="flex items-center space-x-2" :title="step.error || step.output || ''">
+                                                <span :class="step.status === 'succeeded' ? 'text-green-500' : (step.status === 'skipped' || step.status === 'resumed' ? 'text-gray-500' : 'text-red-400')"
+                                                      x-text="step.status"></span>
+                                                <span class="text-gray-300" x-text="step.id"></span>
+                                                <span class="text-gray-500" x-text="step.type + ' · ' + step.durationMs + 'ms'"></span>
+                                            </div>
+                                        </template>
+                                    </div>
+                                </div>
+
+                                <template x-for="group in [
+                                    { title: '同案件提案', items: (currentProposal.case?.proposals || []).filter(x => x.id !== currentProposal.id) },
+                                    { title: '相关提案', items: currentProposal.related || [] }
+                                ]" :key="group.title">
+                                    <div x-show="group.items.length > 0" class="mb-4">
+                                        <h4 class="text-sm font-medium text-gray-400 mb-2" x-text="group.title"></h4>
+                                        <div class="space-y-1">
+                                            <template x-for="ref in group.items" :key="ref.id">
+                                                <button @click="viewProposal(ref.id)"
+                                                        class="w-full flex items-center justify-between text-left text-sm px-3 py-1.5 bg-gray-900 rounded hover:bg-gray-700">
+                                                    <span class="truncate" x-text="ref.title"></span>
+                                                    <span class="text-xs text-gray-500 ml-3 whitespace-nowrap" x-text="ref.status + ' · ' + ref.createdAt"></span>
+                                                </button>
+                                            </template>
+                                        </div>
+                                    </div>
+                                </template>
+
+                                <div class="mb-4">
+                                    <div class="flex items-center space-x-3 text-xs">
+                                        <button @click="graph ? (graph = null) : loadGraph('proposal:' + currentProposal.id, true)"
+                                                class="px-2 py-1 bg-gray-700 hover:bg-gray-600 rounded"
+                                                x-text="graph ? '收起关系图' : '实体关系图'"></button>
+                                        <template x-if="graph">
+                                            <span class="flex items-center space-x-3">
+                                                <span class="text-gray-500">中心 <span class="text-gray-300 font-mono" x-text="graph.root"></span></span>
+                                                <select x-model.number="graphDepth" @change="loadGraph(graph.root)" class="bg-gray-700 rounded px-1 py-0.5">
+                                                    <option value="1">深度 1</option>
+                                                    <option value="2">深度 2</option>
+                                                    <option value="3">深度 3</option>
+                                                </select>
+                                                <button x-show="graphTrail.length > 1" @click="graphBack()" class="text-blue-400 hover:text-blue-300">返回上一个</button>
+                                                <span x-show="graph.truncated" class="text-yellow-500">节点过多, 已截断</span>
+                                            </span>
+                                        </template>
+                                    </div>
+                                    <template x-if="graph">
+                                        <div class="mt-2 bg-gray-900 rounded p-2">
+                                            <svg viewBox="0 0 600 360" class="w-full h-80 cursor-pointer" x-html="graphSVG()" @click="pivotGraph($event)"></svg>
+                                            <p class="text-xs text-gray-500 mt-1">
+                                                点击实体以其为中心展开, 点击提案打开详情; 实线 targeted, 虚线 observed_with, 点线 decided
+                                            </p>
+                                        </div>
+                                    </template>
+                                </div>
+
+                                <div class="mb-4">
+                                    <div class="flex items-center space-x-3 text-xs">
+                                        <button @click="buildTimeline()" :disabled="timelineLoading"
+                                                class="px-2 py-1 bg-gray-700 hover:bg-gray-600 disabled:opacity-50 rounded"
+                                                x-text="timelineLoading ? '生成中...' : (currentProposal.caseId ? '生成案件时间线' : '生成时间线')"></button>
+                                        <label class="flex items-center text-gray-400">
+                                            <input type="checkbox" class="mr-1" x-model="timelineNarrate">
+                                            Agent 撰写事件经过
+                                        </label>
+                                        <span class="flex-1"></span>
+                                        <a :href="exportURL('/api/export/proposal/' + encodeURIComponent(currentProposal.id))" target="_blank"
+                                           class="text-blue-400 hover:text-blue-300">导出 PDF</a>
+                                        <a x-show="currentProposal.caseId" :href="exportURL('/api/export/case/' + encodeURIComponent(currentProposal.caseId || ''))" target="_blank"
+                                           class="text-blue-400 hover:text-blue-300">导出案件 PDF</a>
+                                    </div>
+                                    <template x-if="timeline">
+                                        <div class="mt-2 bg-gray-900 rounded p-3">
+                                            <div class="flex justify-end space-x-3 mb-1 text-xs">
+                                                <button @click="copyText(timeline.markdown)" class="text-blue-400 hover:text-blue-300">复制 Markdown</button>
+                                                <button @click="downloadTimeline()" class="text-blue-400 hover:text-blue-300">下载</button>
+                                            </div>
+                                            <div class="markdown text-sm text-gray-300 max-h-96 overflow-y-auto" x-html="timeline.html"></div>
+                                        </div>
+                                    </template>
+                                </div>
+
+                                <p x-show="currentProposal.evidenceArchive" class="text-xs text-gray-500 mb-4">
+                                    证据已归档: <span class="font-mono" x-text="currentProposal.evidenceArchive"></span>
+                                </p>
+                                <div x-show="(currentProposal.evidence || []).length > 0" class="mb-4">
+                                    <h4 class="text-sm font-medium text-gray-400 mb-2">证据</h4>
+                                    <template x-for="(ev, idx) in currentProposal.evidence" :key="idx">
+                                        <div class="mb-3">
+                                            <div class="flex items-center justify-between mb-1">
+                                                <span class="text-xs text-gray-400">
+                                                    <span x-text="ev.label"></span>
+                                                    <span class="ml-1 px-1 rounded bg-gray-700 text-gray-300" x-text="ev.contentType"></span>
+                                                </span>
+                                                <button @click="copyText(ev.content)" class="text-xs text-blue-400 hover:text-blue-300">复制</button>
+                                            </div>
+                                            <pre class="rounded-lg overflow-x-auto max-h-64 scrollbar-thin text-xs"><code :class="'language-' + highlightLang(ev.contentType)"
+                                                  x-text="ev.content"
+                                                  x-init="$nextTick(() => window.hljs && hljs.highlightElement($el))"></code></pre>
+                                        </div>
+                                    </template>
+                                </div>
+
+                                <div x-show="currentProposal.reproduction" class="mb-4">
+                                    <div class="flex items-center justify-between mb-1">
+                                        <h4 class="text-sm font-medium text-gray-400">复现命令</h4>
+                                        <button @click="copyText(currentProposal.reproduction?.curl)" class="text-xs text-blue-400 hover:text-blue-300">复制</button>
+                                    </div>
+                                    <p class="text-xs text-gray-500 mb-1">
+                                        凭据和个人信息已替换为占位符, 可直接附在工单中交给应用团队验证
+                                        <span x-show="(currentProposal.reproduction?.placeholders || []).length > 0"
+                                              x-text="'· 需填入 ' + (currentProposal.reproduction?.placeholders || []).join(', ')"></span>
+                                    </p>
+                                    <pre class="rounded-lg overflow-x-auto max-h-64 scrollbar-thin text-xs"><code class="language-bash"
+                                          x-text="currentProposal.reproduction?.curl"></code></pre>
+                                </div>
+
                                 <div x-show="Object.keys(currentProposal.parameters || {}).length > 0">
                                     <h4 class="text-sm font-medium text-gray-400 mb-2">可调整参数</h4>
                                     <div class="space-y-3 mb-4">
                                         <template x-for="(param, key) in currentProposal.parameters" :key="key">
                                             <div>
                                                 <label class="block text-sm font-medium text-gray-300 mb-1" x-text="param.label"></label>
-                                                <input type="text" x-model="param.value"
-                                                       class="w-full bg-gray-900 border border-gray-600 rounded px-3 py-2 text-white focus:outline-none focus:border-blue-500">
+                                                <template x-if="param.type === 'select' && (param.options || []).length > 0">
+                                                    <select x-model="param.value" :disabled="currentProposal.status !== 'pending'"
+                                                            class="w-full bg-gray-900 border border-gray-600 rounded px-3 py-2 text-white focus:outline-none focus:border-blue-500">
+                                                        <template x-for="opt in param.options" :key="opt">
+                                                            <option :value="opt" x-text="opt"></option>
+                                                        </template>
+                                                    </select>
+                                                </template>
+                                                <template x-if="!(param.type === 'select' && (param.options || []).length > 0)">
+                                                    <input :type="param.type === 'number' ? 'number' : 'text'" x-model="param.value"
+                                                           :readonly="currentProposal.status !== 'pending'"
+                                                           class="w-full bg-gray-900 border border-gray-600 rounded px-3 py-2 text-white focus:outline-none focus:border-blue-500">
+                                                </template>
+                                                <p x-show="currentProposal.status === 'pending' && String(param.value ?? '') !== (decision.suggested[key] ?? '')"
+                                                   class="text-xs text-yellow-400 mt-1" x-text="'Agent 建议: ' + (decision.suggested[key] || '(空)')"></p>
+                                            </div>
+                                        </template>
+                                    </div>
+                                    <p x-show="currentProposal.status === 'pending' && editedParams().length > 0" class="text-xs text-yellow-400 mb-4"
+                                       x-text="'已修改 ' + editedParams().length + ' 个参数, 确认时按修改后的值执行并记录到审计'"></p>
+                                    <div x-show="currentProposal.decision && (currentProposal.decision.overrides || []).length > 0" class="text-xs text-gray-400 mb-4">
+                                        <span>分析师修改: </span>
+                                        <template x-for="o in ((currentProposal.decision || {}).overrides || [])" :key="o.key">
+                                            <span class="mr-2 font-mono" x-text="o.key + ': ' + (o.from || '(空)') + ' → ' + (o.to || '(空)')"></span>
+                                        </template>
+                                    </div>
+                                </div>
+
+                                <div x-show="(currentProposal.items || []).length > 0 && !currentProposal.execution" class="mb-4">
+                                    <h4 class="text-sm font-medium text-gray-400 mb-2" x-text="'批量条目 (' + (currentProposal.items || []).length + ')'"></h4>
+                                    <div class="bg-gray-900 rounded p-3 text-xs font-mono space-y-1 max-h-48 overflow-y-auto">
+                                        <template x-for="(item, i) in (currentProposal.items || [])" :key="i">
+                                            <div class="text-gray-300 truncate" x-text="(i + 1) + '. ' + itemLabel(item)"></div>
+                                        </template>
+                                    </div>
+                                </div>
+
+                                <div x-show="currentProposal.execution" class="mb-4">
+                                    <template x-if="currentProposal.execution">
+                                        <div>
+                                            <h4 class="text-sm font-medium text-gray-400 mb-2">执行结果</h4>
+                                            <div class="bg-gray-900 rounded p-3 text-sm">
+                                                <div class="flex items-center justify-between mb-2">
+                                                    <span class="font-mono" x-text="currentProposal.execution.api"></span>
+                                                    <span class="text-xs px-2 py-0.5 rounded"
+                                                          :class="{ 'bg-green-800 text-green-200': currentProposal.execution.status === 'succeeded', 'bg-red-800 text-red-200': currentProposal.execution.status === 'failed', 'bg-gray-700 text-gray-300': !currentProposal.execution.status }"
+                                                          x-text="executionStatusText(currentProposal.execution)"></span>
+                                                </div>
+                                                <template x-if="(currentProposal.execution.items || []).length > 0">
+                                                    <div class="mb-2">
+                                                        <div class="flex items-center justify-between text-xs text-gray-400 mb-1">
+                                                            <span x-text="'条目进度 ' + currentProposal.execution.items.filter(i => i.status === 'succeeded').length + '/' + currentProposal.execution.items.length + ' 成功'"></span>
+                                                            <span x-show="currentProposal.execution.items.some(i => i.status === 'failed')" class="text-red-400"
+                                                                  x-text="currentProposal.execution.items.filter(i => i.status === 'failed').length + ' 条失败'"></span>
+                                                        </div>
+                                                        <div class="h-1.5 bg-gray-700 rounded mb-2 overflow-hidden">
+                                                            <div class="h-full bg-green-600"
+                                                                 :style="'width:' + (100 * currentProposal.execution.items.filter(i => i.status === 'succeeded').length / currentProposal.execution.items.length) + '%'"></div>
+                                                        </div>
+                                                        <div class="space-y-1 max-h-48 overflow-y-auto text-xs">
+                                                            <template x-for="item in currentProposal.execution.items" :key="item.index">
+                                                                <div class="flex items-center justify-between">
+                                                                    <span class="font-mono text-gray-300 truncate mr-2" x-text="(item.index + 1) + '. ' + itemLabel((currentProposal.items || [])[item.index] || item.params)"></span>
+                                                                    <span class="shrink-0"
+                                                                          :class="{ 'text-green-400': item.status === 'succeeded', 'text-red-400': item.status === 'failed', 'text-gray-500': !item.status }"
+                                                                          :title="item.error || ''"
+                                                                          x-text="item.status === 'succeeded' ? '成功' : (item.status === 'failed' ? '失败' : '等待')"></span>
+                                                                </div>
+                                                            </template>
+                                                        </div>
+                                                    </div>
+                                                </template>
+                                                <p x-show="currentProposal.execution.error" class="text-red-400 mb-2" x-text="currentProposal.execution.error"></p>
+                                                <div x-show="currentProposal.status === 'execution_failed'" class="flex items-center justify-between mb-2 text-xs text-gray-400">
+                                                    <span x-text="'已执行 ' + currentProposal.execution.attempts + ' 次 · ' + (currentProposal.execution.nextRetryAt ? '将于 ' + new Date(currentProposal.execution.nextRetryAt).toLocaleString() + ' 自动重试' : '不再自动重试')"></span>
+                                                    <button @click="retryExecution(currentProposal.id)" :disabled="retrying"
+                                                            class="px-2 py-1 bg-red-700 hover:bg-red-600 disabled:opacity-50 rounded text-white"
+                                                            x-text="retrying ? '执行中...' : ((currentProposal.execution.items || []).length ? '重试失败条目' : '立即重试')"></button>
+                                                </div>
+                                                <pre x-show="currentProposal.execution.response" class="text-xs text-gray-300 overflow-x-auto max-h-48"
+                                                     x-text="currentProposal.execution.response"></pre>
+                                            </div>
+                                        </div>
+                                    </template>
+                                </div>
+
+                                <div x-show="(currentProposal.artifacts || []).length > 0" class="mb-4">
+                                    <h4 class="text-sm font-medium text-gray-400 mb-2">规则与配置</h4>
+                                    <template x-for="(a, idx) in (currentProposal.artifacts || [])" :key="idx">
+                                        <div class="mb-3">
+                                            <div class="flex items-center justify-between mb-1">
+                                                <span class="text-xs text-gray-400">
+                                                    <span class="font-mono" x-text="a.name"></span>
+                                                    <span class="ml-1 px-1 rounded bg-gray-700 text-gray-300" x-text="a.kind"></span>
+                                                </span>
+                                                <button @click="copyText(a.content)" class="text-xs text-blue-400 hover:text-blue-300">复制</button>
+                                            </div>
+                                            <pre class="bg-gray-900 rounded p-2 overflow-x-auto max-h-48 text-xs text-gray-300" x-text="a.content"></pre>
+                                        </div>
+                                    </template>
+                                    <template x-if="currentProposal.change">
+                                        <div class="bg-gray-900 rounded p-3 text-xs">
+                                            <div class="flex items-center justify-between mb-1">
+                                                <span class="text-gray-400" x-text="'Git: ' + currentProposal.change.branch + (currentProposal.change.commit ? ' @ ' + currentProposal.change.commit.slice(0, 12) : '')"></span>
+                                                <span :class="currentProposal.change.error ? 'text-red-400' : 'text-green-400'"
+                                                      x-text="currentProposal.change.error ? '提交失败' : (currentProposal.change.commit ? '已提交' : '无变化')"></span>
+                                            </div>
+                                            <a x-show="currentProposal.change.pullRequest" :href="currentProposal.change.pullRequest" target="_blank"
+                                               class="text-blue-400 hover:text-blue-300 break-all" x-text="currentProposal.change.pullRequest"></a>
+                                            <p x-show="currentProposal.change.error" class="text-red-400 break-all" x-text="currentProposal.change.error"></p>
+                                        </div>
+                                    </template>
+                                    <button x-show="currentProposal.decision && currentProposal.decision.action === 'accept' && (!currentProposal.change || currentProposal.change.error)"
+                                            @click="commitArtifacts(currentProposal.id)" :disabled="committing"
+                                            class="mt-2 px-2 py-1 bg-blue-700 hover:bg-blue-600 disabled:opacity-50 rounded text-xs"
+                                            x-text="committing ? '提交中...' : '提交到 Git'"></button>
+                                </div>
+
+                                <div class="mb-4">
+                                    <div class="flex items-center space-x-2 mb-2">
+                                        <h4 class="text-sm font-medium text-gray-400">负责人</h4>
+                                        <input type="text" x-model="currentProposal.assignee" placeholder="未指派"
+                                               class="px-2 py-1 bg-gray-900 border border-gray-600 rounded text-xs w-40"
+                                               @keydown.enter="assignProposal()">
+                                        <button @click="assignProposal()" class="text-xs text-blue-400 hover:text-blue-300">指派</button>
+                                    </div>
+                                    <h4 class="text-sm font-medium text-gray-400 mb-2"
+                                        x-text="'调查记录 (' + (currentProposal.comments || []).length + ')'"></h4>
+                                    <div class="space-y-2 mb-2 max-h-48 overflow-y-auto">
+                                        <template x-for="c in (currentProposal.comments || [])" :key="c.id">
+                                            <div class="bg-gray-900 rounded p-2 text-xs">
+                                                <div class="text-gray-500 mb-1"
+                                                     x-text="(c.author.name || c.author.via) + ' · ' + new Date(c.at).toLocaleString()"></div>
+                                                <div class="text-gray-300 whitespace-pre-wrap break-words" x-text="c.text"></div>
+                                            </div>
+                                        </template>
+                                    </div>
+                                    <div class="flex space-x-2">
+                                        <textarea x-model="newComment" rows="2" placeholder="记录调查过程、结论或交接事项"
+                                                  class="flex-1 bg-gray-900 border border-gray-600 rounded px-2 py-1 text-xs text-white focus:outline-none focus:border-blue-500"></textarea>
+                                        <button @click="addComment()" :disabled="!newComment.trim()"
+                                                class="px-3 py-1 bg-blue-700 hover:bg-blue-600 disabled:opacity-50 rounded text-xs self-end">评论</button>
+                                    </div>
+                                </div>
+
+                                <div class="mb-4">
+                                    <button @click="toggleHistory()" class="text-sm font-medium text-gray-400 hover:text-white"
+                                            x-text="(history ? '▾' : '▸') + ' 历史'"></button>
+                                    <div x-show="history" class="mt-2 space-y-1 text-xs max-h-48 overflow-y-auto">
+                                        <template x-for="(ev, i) in (history || [])" :key="i">
+                                            <div class="flex items-start space-x-2">
+                                                <span class="text-gray-500 shrink-0" x-text="new Date(ev.at).toLocaleString()"></span>
+                                                <span class="text-gray-300 shrink-0" x-text="ev.action"></span>
+                                                <span class="text-gray-400 shrink-0" x-text="(ev.actor.name ? ev.actor.name + ' · ' : '') + ev.actor.via"></span>
+                                                <span class="text-gray-500 break-all"
+                                                      x-text="[ev.from && ev.to ? ev.from + ' → ' + ev.to : '', ev.reason, ev.detail, ev.params ? itemLabel(ev.params) : ''].filter(Boolean).join(' · ')"></span>
+                                            </div>
+                                        </template>
+                                        <p x-show="history && history.length === 0" class="text-gray-500">暂无记录</p>
+                                    </div>
+                                </div>
+
+                                <div x-show="currentProposal.status === 'pending'">
+                                    <h4 class="text-sm font-medium text-gray-400 mb-2">决策</h4>
+                                    <div class="space-y-3">
+                                        <select x-show="(currentProposal.templates || []).length > 0"
+                                                x-model="decision.template" @change="applyTemplate()"
+                                                class="w-full bg-gray-900 border border-gray-600 rounded px-3 py-2 text-white focus:outline-none focus:border-blue-500">
+                                            <option value="">不使用模板</option>
+                                            <template x-for="t in currentProposal.templates" :key="t.name">
+                                                <option :value="t.name" x-text="t.name + (t.action ? ' (' + (t.action === 'accept' ? '确认' : '忽略') + ')' : '')"></option>
+                                            </template>
+                                        </select>
+                                        <textarea x-model="decision.reason" rows="2" placeholder="决策备注 (可选)"
+                                                  class="w-full bg-gray-900 border border-gray-600 rounded px-3 py-2 text-white focus:outline-none focus:border-blue-500"></textarea>
+                                        <div class="flex items-center space-x-3 text-xs">
+                                            <span class="text-gray-400">预览将发送的请求:</span>
+                                            <button @click="previewExecution(currentProposal.id, 'accept')" class="text-blue-400 hover:text-blue-300">确认</button>
+                                            <button @click="previewExecution(currentProposal.id, 'ignore')" class="text-blue-400 hover:text-blue-300">忽略</button>
+                                        </div>
+                                        <template x-if="preview">
+                                            <div class="bg-gray-900 rounded p-3 text-xs">
+                                                <div class="text-gray-400 mb-2"
+                                                     x-text="(preview.action === 'accept' ? '确认' : '忽略') + (preview.api ? ' 将调用 ' + preview.api + ', 共 ' + preview.requests.length + ' 个请求' : ' 不调用 Sheikah API, 仅记录决策')"></div>
+                                                <div class="space-y-2 max-h-64 overflow-y-auto">
+                                                    <template x-for="req in preview.requests" :key="req.index">
+                                                        <div>
+                                                            <p x-show="req.error" class="text-red-400" x-text="(preview.requests.length > 1 ? (req.index + 1) + '. ' : '') + req.error"></p>
+                                                            <pre x-show="!req.error" class="font-mono text-gray-300 whitespace-pre-wrap break-all"
+                                                                 :class="req.skipped ? 'opacity-50' : ''"
+                                                                 x-text="renderPreviewRequest(req, preview.requests.length > 1)"></pre>
+                                                        </div>
+                                                    </template>
+                                                </div>
                                             </div>
                                         </template>
                                     </div>
@@ -675,10 +2616,15 @@ var indexHTML = []byte(`<!DOCTYPE html>
                                         class="px-4 py-2 bg-gray-700 text-white rounded-lg hover:bg-gray-600">关闭</button>
                                                 <template x-if="currentProposal.status === 'pending'">
                                                     <div class="flex space-x-2">
+                                                        <button @click="silenceProposal()"
+                                                                class="px-4 py-2 bg-gray-700 text-white rounded-lg hover:bg-gray-600">静默</button>
+                                                        <button @click="acknowledgeProposal(currentProposal.id)"
+                                                                class="px-4 py-2 bg-blue-700 text-white rounded-lg hover:bg-blue-600">知悉</button>
                                                         <button @click="ignoreProposal(currentProposal.id); showModal = false"
                                                                 class="px-4 py-2 bg-gray-600 text-white rounded-lg hover:bg-gray-500">忽略</button>
                                                         <button @click="acceptProposal(currentProposal.id); showModal = false"
-                                                                class="px-4 py-2 bg-green-600 text-white rounded-lg hover:bg-green-500">确认</button>
+                                                                class="px-4 py-2 bg-green-600 text-white rounded-lg hover:bg-green-500"
+                                                                x-text="editedParams().length > 0 ? '按修改确认' : '确认'"></button>
                                     </div>
                                 </template>
                             </div>
@@ -690,46 +2636,208 @@ var indexHTML = []byte(`<!DOCTYPE html>
     </div>
 
     <script>
+        const BASE_PATH = {{BASE_PATH_JSON}};
+
+        function apiURL(path) {
+            return BASE_PATH + path;
+        }
+
         function app() {
             return {
                 activeTab: 'chat',
                 tabs: [
-                    { id: 'chat', name: '对话', icon: '💬' },
-                    { id: 'tools', name: '工具', icon: '🔧' },
+                    { id: 'chat', name: '对话', icon: '💬', feature: 'chat' },
+                    { id: 'tools', name: '工具', icon: '🔧', feature: 'chat' },
                     { id: 'skills', name: '技能', icon: '✨' },
-                    { id: 'proposals', name: '提案', icon: '📋' },
+                    { id: 'proposals', name: '提案', icon: '📋', feature: 'proposals' },
                     { id: 'settings', name: '设置', icon: '⚙️' }
                 ],
                 messages: [],
+                chatSession: localStorage.getItem('chatSession') || 'debugui',
+                chatSessions: [],
                 inputMessage: '',
                 isLoading: false,
+                confirmTools: localStorage.getItem('confirmTools') === 'true',
+                approvals: [],
                 tools: [],
                 skills: [],
                 proposals: [],
+                selectedIds: [],
+                bulkReason: '',
+                coverage: null,
+                techniqueFilter: '',
+                severityFilter: '',
+                assigneeFilter: '',
+                newComment: '',
+                groupBy: '',
+                groupKey: null,
+                groups: [],
+                sortBySeverity: false,
+                silences: [],
+                sessions: [],
+                notifyTargets: [],
+                reports: [],
+                stats: { stores: [] },
+                notifyTests: {},
+                retrying: false,
+                committing: false,
+                update: null,
                 currentProposal: null,
                 showModal: false,
+                decision: { template: '', reason: '', suggested: {} },
+                preview: null,
+                history: null,
+                timeline: null,
+                graph: null,
+                graphDepth: 1,
+                graphTrail: [],
+                timelineNarrate: false,
+                timelineLoading: false,
+                showOriginalSummary: false,
+                showOriginalText: false,
+                translateLang: localStorage.getItem('translateLang'),
+                regenerating: false,
                 info: {},
 
-                init() {
-                    this.fetchInfo();
-                    this.fetchTools();
-                    this.fetchSkills();
-                    this.fetchProposals();
-                    setInterval(() => this.fetchProposals(), 5000);
+                init() {
+                    this.fetchInfo();
+                    this.fetchTools();
+                    this.loadChatSession(this.chatSession);
+                    this.fetchSkills();
+                    this.fetchProposals();
+                    this.fetchSilences();
+                    setInterval(() => this.fetchProposals(), 5000);
+                },
+
+                async fetchInfo() {
+                    try {
+                        const response = await fetch(apiURL('/api/info'));
+                        this.info = await response.json();
+                        if (this.translateLang === null) {
+                            this.translateLang = this.info.translationLanguage || '';
+                        }
+                        if (this.feature('login', false)) {
+                            this.fetchSessions();
+                        }
+                        if (this.feature('updateCheck', false)) {
+                            this.fetchUpdate();
+                        }
+                        if (this.feature('secops', false)) {
+                            this.fetchNotifyTargets();
+                            this.fetchReports();
+                            this.fetchStats();
+                        }
+                    } catch (e) {
+                        console.error('Failed to fetch info:', e);
+                    }
+                },
+
+                feature(name, fallback = true) {
+                    // 信息未加载时使用 fallback, 避免标签页闪烁
+                    if (!this.info.features) return fallback;
+                    return this.info.features[name] === true;
+                },
+
+                async fetchUpdate() {
+                    try {
+                        const response = await fetch(apiURL('/api/update'));
+                        if (response.ok) {
+                            this.update = await response.json();
+                        }
+                    } catch (e) {
+                        console.error('Failed to check for updates:', e);
+                    }
+                },
+
+                async fetchNotifyTargets() {
+                    try {
+                        const response = await fetch(apiURL('/api/notify/targets'));
+                        const data = await response.json();
+                        this.notifyTargets = Array.isArray(data) ? data : (data.items || []);
+                    } catch (e) {
+                        console.error('Failed to fetch notify targets:', e);
+                    }
+                },
+
+                async fetchStats() {
+                    try {
+                        const response = await fetch(apiURL('/api/stats'));
+                        this.stats = await response.json();
+                    } catch (e) {
+                        console.error('Failed to fetch stats:', e);
+                    }
+                },
+
+                formatBytes(n) {
+                    if (n >= 1 << 20) return (n / (1 << 20)).toFixed(1) + ' MB';
+                    if (n >= 1 << 10) return (n / (1 << 10)).toFixed(1) + ' KB';
+                    return n + ' B';
+                },
+
+                async fetchReports() {
+                    try {
+                        const response = await fetch(apiURL('/api/reports'));
+                        const data = await response.json();
+                        this.reports = Array.isArray(data) ? data : (data.items || []);
+                    } catch (e) {
+                        console.error('Failed to fetch reports:', e);
+                    }
+                },
+
+                // 服务端无法渲染 PDF 时打开打印版页面, 由浏览器另存为 PDF
+                exportURL(path) {
+                    return apiURL(path) + (this.feature('pdf', false) ? '' : '?format=html&print=1');
                 },
 
-                async fetchInfo() {
+                async testNotify(name) {
                     try {
-                        const response = await fetch('/api/info');
-                        this.info = await response.json();
+                        const res = await fetch(apiURL('/api/notify/target/' + encodeURIComponent(name) + '/test'), { method: 'POST' });
+                        if (!res.ok) {
+                            alert(await res.text());
+                            return;
+                        }
+                        this.notifyTests = { ...this.notifyTests, [name]: await res.json() };
                     } catch (e) {
-                        console.error('Failed to fetch info:', e);
+                        console.error('Failed to send test notification:', e);
+                    }
+                },
+
+                async fetchSessions() {
+                    try {
+                        const response = await fetch(apiURL('/api/auth/sessions'));
+                        this.sessions = await response.json();
+                    } catch (e) {
+                        console.error('Failed to fetch sessions:', e);
+                    }
+                },
+
+                sessionMethod(sess) {
+                    const labels = { passkey: '通行密钥', recovery: '恢复码', token: '访问令牌', password: '用户名密码' };
+                    return (labels[sess.method] || sess.method) + (sess.credential ? ' ' + sess.credential : '');
+                },
+
+                async revokeSession(sess) {
+                    if (!confirm('注销该会话?')) return;
+                    const response = await fetch(apiURL('/api/auth/session/' + encodeURIComponent(sess.id)), { method: 'DELETE' });
+                    if (sess.current) {
+                        location.href = apiURL('/login');
+                        return;
                     }
+                    if (!response.ok) {
+                        alert('注销失败: ' + (await response.text()));
+                    }
+                    this.fetchSessions();
+                },
+
+                async logoutAll() {
+                    if (!confirm('所有设备 (包括当前浏览器) 都将退出登录, 继续?')) return;
+                    await fetch(apiURL('/api/auth/logout-all'), { method: 'POST' });
+                    location.href = apiURL('/login');
                 },
 
                 async fetchTools() {
                     try {
-                        const response = await fetch('/api/tools');
+                        const response = await fetch(apiURL('/api/tools'));
                         const data = await response.json();
                         this.tools = data.tools || [];
                     } catch (e) {
@@ -739,7 +2847,7 @@ var indexHTML = []byte(`<!DOCTYPE html>
 
                 async fetchSkills() {
                     try {
-                        const response = await fetch('/api/skills');
+                        const response = await fetch(apiURL('/api/skills'));
                         const data = await response.json();
                         this.skills = data.skills || [];
                     } catch (e) {
@@ -749,11 +2857,77 @@ var indexHTML = []byte(`<!DOCTYPE html>
 
                 async fetchProposals() {
                     try {
-                        const response = await fetch('/api/proposals');
-                        this.proposals = await response.json();
+                        const query = new URLSearchParams();
+                        if (this.techniqueFilter) query.set('technique', this.techniqueFilter);
+                        if (this.severityFilter) query.set('severity', this.severityFilter);
+                        if (this.sortBySeverity) query.set('sort', '-severity');
+                        if (this.assigneeFilter.trim()) query.set('assignee', this.assigneeFilter.trim());
+                        if (this.groupBy) this.fetchGroups(new URLSearchParams(query));
+                        if (this.groupBy && this.groupKey !== null) {
+                            query.set('group_by', this.groupBy);
+                            query.set('group', this.groupKey);
+                        }
+                        const response = await fetch(apiURL('/api/proposals' + (query.toString() ? '?' + query : '')));
+                        const data = await response.json();
+                        this.proposals = Array.isArray(data) ? data : (data.items || []);
                     } catch (e) {
                         console.error('Failed to fetch proposals:', e);
                     }
+                    this.fetchCoverage();
+                },
+
+                // 分组统计使用与列表相同的筛选条件, 点击分组后列表只显示该组
+                async fetchGroups(query) {
+                    query.set('by', this.groupBy);
+                    query.set('limit', '200');
+                    try {
+                        const response = await fetch(apiURL('/api/proposals/groups?' + query));
+                        if (response.ok) {
+                            const data = await response.json();
+                            this.groups = Array.isArray(data) ? data : (data.items || []);
+                        }
+                    } catch (e) {
+                        console.error('Failed to fetch proposal groups:', e);
+                    }
+                },
+
+                groupLabel(key) {
+                    if (key) return key;
+                    return this.groupBy === 'host' ? '(无主机)' : '(空)';
+                },
+
+                async fetchCoverage() {
+                    try {
+                        const response = await fetch(apiURL('/api/attack/coverage'));
+                        if (response.ok) {
+                            this.coverage = await response.json();
+                        }
+                    } catch (e) {
+                        console.error('Failed to fetch ATT&CK coverage:', e);
+                    }
+                },
+
+                filterTechnique(technique) {
+                    this.techniqueFilter = this.techniqueFilter === technique ? '' : technique;
+                    this.fetchProposals();
+                },
+
+                heatClass(count) {
+                    if (!count) return 'bg-gray-800 text-gray-500';
+                    if (count >= 20) return 'bg-red-700 text-white';
+                    if (count >= 10) return 'bg-red-900 text-red-200';
+                    if (count >= 5) return 'bg-orange-900 text-orange-200';
+                    return 'bg-yellow-900 text-yellow-200';
+                },
+
+                async fetchSilences() {
+                    try {
+                        const response = await fetch(apiURL('/api/silences'));
+                        const data = await response.json();
+                        this.silences = Array.isArray(data) ? data : (data.items || []);
+                    } catch (e) {
+                        console.error('Failed to fetch silences:', e);
+                    }
                 },
 
                 get pendingProposals() {
@@ -773,54 +2947,743 @@ var indexHTML = []byte(`<!DOCTYPE html>
 
                     this.messages.push({ role: 'user', content: message });
 
+                    // 确认模式下轮询待确认的工具调用
+                    const poll = this.confirmTools ? setInterval(() => this.fetchApprovals(), 1000) : null;
+                    const body = JSON.stringify({ message: message, session: this.chatSession, confirmTools: this.confirmTools });
                     try {
-                        const response = await fetch('/api/chat', {
+                        const response = await fetch(apiURL('/api/chat/stream'), {
                             method: 'POST',
                             headers: { 'Content-Type': 'application/json' },
-                            body: JSON.stringify({ message: message })
+                            body: body
                         });
-                        const data = await response.json();
-                        this.messages.push({ role: 'assistant', content: data.response || data.error || '无响应' });
+                        if (response.status === 429) {
+                            // 超出限速或排队已满, 不回退到整体返回接口
+                            const data = await response.json().catch(() => ({}));
+                            const retry = response.headers.get('Retry-After');
+                            this.messages.push({ role: 'assistant', content: '请求过多: ' + (data.error || '请稍后重试') + (retry ? ' (' + retry + ' 秒后重试)' : '') });
+                        } else if (response.ok && response.body) {
+                            await this.readChatStream(response);
+                        } else {
+                            // 旧版本服务端没有流式接口时回退到整体返回
+                            const res = await fetch(apiURL('/api/chat'), {
+                                method: 'POST',
+                                headers: { 'Content-Type': 'application/json' },
+                                body: body
+                            });
+                            const data = await res.json();
+                            this.messages.push({ role: 'assistant', content: data.response || data.error || '无响应', html: data.html || '', traceId: data.traceId || '' });
+                        }
                     } catch (e) {
                         this.messages.push({ role: 'assistant', content: '错误: ' + e.message });
                     } finally {
+                        if (poll) clearInterval(poll);
+                        this.approvals = [];
                         this.isLoading = false;
+                        this.fetchChatSessions();
+                    }
+                },
+
+                async fetchChatSessions() {
+                    try {
+                        const response = await fetch(apiURL('/api/sessions'));
+                        if (!response.ok) return;
+                        const data = await response.json();
+                        const list = Array.isArray(data) ? data : (data.items || []);
+                        // 尚未发送消息的新会话不在服务端列表中
+                        if (!list.some(s => s.id === this.chatSession)) {
+                            list.unshift({ id: this.chatSession, messages: 0 });
+                        }
+                        this.chatSessions = list;
+                    } catch (e) {
+                        console.error('Failed to fetch chat sessions:', e);
+                    }
+                },
+
+                async loadChatSession(id) {
+                    this.chatSession = id;
+                    localStorage.setItem('chatSession', id);
+                    this.messages = [];
+                    try {
+                        const response = await fetch(apiURL('/api/sessions/' + encodeURIComponent(id) + '/messages'));
+                        if (response.ok) {
+                            const data = await response.json();
+                            const messages = [];
+                            if (data.summary) {
+                                messages.push({ role: 'assistant', content: '较早的对话摘要: ' + data.summary });
+                            }
+                            // 只显示用户消息和助手的文本回复, 工具调用过程不回放
+                            for (const m of data.messages || []) {
+                                if ((m.role === 'user' || m.role === 'assistant') && m.content) {
+                                    messages.push({ role: m.role, content: m.content, html: m.html || '' });
+                                }
+                            }
+                            if (this.chatSession === id) this.messages = messages;
+                        }
+                    } catch (e) {
+                        console.error('Failed to load chat session:', e);
+                    }
+                    this.fetchChatSessions();
+                },
+
+                newChatSession() {
+                    const id = 'chat-' + Date.now().toString(36);
+                    this.chatSession = id;
+                    localStorage.setItem('chatSession', id);
+                    this.messages = [];
+                    this.chatSessions = [{ id: id, messages: 0 }, ...this.chatSessions];
+                },
+
+                async clearChatSession() {
+                    if (!confirm('清空会话 ' + this.chatSession + ' 的全部历史?')) return;
+                    try {
+                        const response = await fetch(apiURL('/api/sessions/' + encodeURIComponent(this.chatSession)), { method: 'DELETE' });
+                        if (!response.ok && response.status !== 404) {
+                            alert(await response.text());
+                            return;
+                        }
+                        this.messages = [];
+                        this.fetchChatSessions();
+                    } catch (e) {
+                        console.error('Failed to clear chat session:', e);
+                    }
+                },
+
+                async readChatStream(response) {
+                    this.messages.push({ role: 'assistant', content: '', html: '', steps: [], streaming: true });
+                    const msg = this.messages[this.messages.length - 1];
+                    const reader = response.body.getReader();
+                    const decoder = new TextDecoder();
+                    let buffer = '';
+                    let finished = false;
+                    const handle = (event, data) => {
+                        if (event === 'queued') {
+                            msg.queued = data.position;
+                            return;
+                        }
+                        msg.queued = 0;
+                        if (event === 'token') {
+                            msg.content += data.content || '';
+                        } else if (event === 'tool_call') {
+                            // 工具调用前的文本作为该步骤的说明
+                            msg.steps.push({ tool: data.tool, args: data.args, note: msg.content.trim(), status: 'running' });
+                            msg.content = '';
+                        } else if (event === 'tool_result') {
+                            const step = [...msg.steps].reverse().find(s => s.tool === data.tool && s.status === 'running');
+                            if (step) Object.assign(step, { status: 'done', result: data.result, isError: !!data.isError, durationMs: data.durationMs || 0 });
+                        } else if (event === 'done') {
+                            msg.content = data.response || '无响应';
+                            msg.html = data.html || '';
+                            msg.traceId = data.traceId || '';
+                            finished = true;
+                        } else if (event === 'error') {
+                            msg.content = '错误: ' + data.error;
+                            msg.traceId = data.traceId || '';
+                            finished = true;
+                        }
+                    };
+                    while (true) {
+                        const { value, done } = await reader.read();
+                        if (done) break;
+                        buffer += decoder.decode(value, { stream: true });
+                        let sep;
+                        while ((sep = buffer.indexOf('\n\n')) >= 0) {
+                            const block = buffer.slice(0, sep);
+                            buffer = buffer.slice(sep + 2);
+                            let event = 'message', data = '';
+                            for (const line of block.split('\n')) {
+                                if (line.startsWith('event:')) event = line.slice(6).trim();
+                                else if (line.startsWith('data:')) data += line.slice(5).trim();
+                            }
+                            if (data) handle(event, JSON.parse(data));
+                        }
+                    }
+                    if (!finished && !msg.content) msg.content = '连接中断, 未收到完整回复';
+                    msg.streaming = false;
+                },
+
+                async loadTrace(msg) {
+                    if (msg.trace) return;
+                    try {
+                        const response = await fetch(apiURL('/api/traces/' + encodeURIComponent(msg.traceId)));
+                        msg.trace = response.ok ? await response.json() : null;
+                    } catch (e) {
+                        console.error('Failed to load trace:', e);
+                    }
+                },
+
+                async fetchApprovals() {
+                    try {
+                        const response = await fetch(apiURL('/api/chat/approvals?session=' + encodeURIComponent(this.chatSession)));
+                        const data = await response.json();
+                        this.approvals = Array.isArray(data) ? data : (data.items || []);
+                    } catch (e) {
+                        console.error('Failed to fetch approvals:', e);
+                    }
+                },
+
+                async resolveApproval(id, approve) {
+                    let reason = '';
+                    if (!approve) {
+                        reason = window.prompt('拒绝理由 (可选, 将反馈给 Agent):');
+                        if (reason === null) return;
+                    }
+                    try {
+                        const res = await fetch(apiURL('/api/chat/approval/' + id), {
+                            method: 'POST',
+                            headers: { 'Content-Type': 'application/json' },
+                            body: JSON.stringify({ approve: approve, reason: reason })
+                        });
+                        if (!res.ok) {
+                            alert(await res.text());
+                        }
+                        this.approvals = this.approvals.filter(a => a.id !== id);
+                    } catch (e) {
+                        console.error('Failed to resolve approval:', e);
                     }
                 },
 
                 async viewProposal(id) {
                     try {
-                        const response = await fetch('/api/proposal/' + id);
+                        const query = new URLSearchParams({ expand: 'run,case,related' });
+                        if (this.translateLang) query.set('lang', this.translateLang);
+                        const response = await fetch(apiURL('/api/proposal/' + id + '?' + query));
                         this.currentProposal = await response.json();
+                        this.decision = { template: '', reason: '', suggested: {} };
+                        this.newComment = '';
+                        for (const [key, param] of Object.entries(this.currentProposal.parameters || {})) {
+                            this.decision.suggested[key] = param.value || '';
+                        }
+                        this.preview = null;
+                        this.history = null;
+                        this.timeline = null;
+                        this.graph = null;
+                        this.graphTrail = [];
+                        this.showOriginalSummary = false;
+                        this.showOriginalText = false;
                         this.showModal = true;
                     } catch (e) {
                         console.error('Failed to fetch proposal:', e);
                     }
                 },
 
+                async toggleHistory() {
+                    if (this.history) {
+                        this.history = null;
+                        return;
+                    }
+                    try {
+                        const res = await fetch(apiURL('/api/proposal/' + this.currentProposal.id + '/history?limit=200'));
+                        this.history = (await res.json()).items || [];
+                    } catch (e) {
+                        console.error('Failed to fetch proposal history:', e);
+                    }
+                },
+
+                // 同案件的提案按案件生成, 否则只包含当前提案
+                timelineQuery() {
+                    const p = this.currentProposal;
+                    const query = new URLSearchParams(p.caseId ? { case: p.caseId } : { ids: p.id });
+                    if (this.timelineNarrate) query.set('narrate', '1');
+                    return query;
+                },
+
+                // 实体关系图: 以 node 为中心展开, fresh 时重新开始浏览路径
+                async loadGraph(node, fresh) {
+                    try {
+                        const query = new URLSearchParams({ node: node, depth: this.graphDepth });
+                        const res = await fetch(apiURL('/api/graph?' + query));
+                        if (!res.ok) {
+                            alert(await res.text());
+                            return;
+                        }
+                        this.graph = await res.json();
+                        if (fresh) this.graphTrail = [];
+                        if (this.graphTrail[this.graphTrail.length - 1] !== node) this.graphTrail.push(node);
+                    } catch (e) {
+                        console.error('Failed to fetch graph:', e);
+                    }
+                },
+
+                graphBack() {
+                    this.graphTrail.pop();
+                    this.loadGraph(this.graphTrail[this.graphTrail.length - 1]);
+                },
+
+                pivotGraph(event) {
+                    const el = event.target.closest('[data-node]');
+                    if (!el) return;
+                    const id = el.dataset.node;
+                    if (id.startsWith('proposal:') && id !== this.graph.root) {
+                        this.viewProposal(id.slice('proposal:'.length));
+                        return;
+                    }
+                    if (id !== this.graph.root) this.loadGraph(id);
+                },
+
+                // 按距离分环布局, 中心实体居中
+                graphSVG() {
+                    if (!this.graph) return '';
+                    const esc = v => String(v).replace(/[&<>"']/g, c => ({ '&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;', "'": '&#39;' })[c]);
+                    const colors = { ip: '#f87171', user: '#60a5fa', device: '#a78bfa', host: '#34d399', url: '#fbbf24', proposal: '#9ca3af' };
+                    const rings = {};
+                    this.graph.nodes.forEach(n => (rings[n.distance] = rings[n.distance] || []).push(n));
+                    const pos = {};
+                    Object.entries(rings).forEach(([d, nodes]) => {
+                        nodes.forEach((n, i) => {
+                            const angle = 2 * Math.PI * i / nodes.length + d * 0.4;
+                            pos[n.id] = { x: 300 + 90 * d * Math.cos(angle), y: 180 + 50 * d * Math.sin(angle) };
+                        });
+                    });
+                    const dash = { observed_with: '4,3', decided: '1,3' };
+                    let svg = '';
+                    this.graph.edges.forEach(e => {
+                        const a = pos[e.from], b = pos[e.to];
+                        svg += '<line x1="' + a.x + '" y1="' + a.y + '" x2="' + b.x + '" y2="' + b.y + '" stroke="#4b5563" stroke-width="' + Math.min(1 + e.count / 2, 4) + '"'
+                            + (dash[e.type] ? ' stroke-dasharray="' + dash[e.type] + '"' : '') + '><title>' + esc(e.from + ' ' + e.type + ' ' + e.to + ' ×' + e.count) + '</title></line>';
+                    });
+                    this.graph.nodes.forEach(n => {
+                        const p = pos[n.id];
+                        const label = n.type === 'proposal' ? (n.label || n.value) : n.value;
+                        const title = n.type === 'proposal' ? n.label + ' (' + n.status + ')' : n.id + ' · ' + n.proposals + ' 个提案';
+                        svg += '<g data-node="' + esc(n.id) + '"><circle cx="' + p.x + '" cy="' + p.y + '" r="' + (n.id === this.graph.root ? 9 : 6) + '" fill="' + (colors[n.type] || '#9ca3af') + '"></circle>'
+                            + '<text x="' + (p.x + 10) + '" y="' + (p.y + 3) + '" font-size="10" fill="#d1d5db">' + esc(label.length > 28 ? label.slice(0, 27) + '…' : label) + '</text>'
+                            + '<title>' + esc(title) + '</title></g>';
+                    });
+                    return svg;
+                },
+
+                async buildTimeline() {
+                    this.timelineLoading = true;
+                    try {
+                        const res = await fetch(apiURL('/api/timeline?' + this.timelineQuery()));
+                        if (!res.ok) {
+                            alert(await res.text());
+                            return;
+                        }
+                        this.timeline = await res.json();
+                        if (this.timeline.narrativeError) {
+                            alert('事件经过生成失败: ' + this.timeline.narrativeError);
+                        }
+                    } catch (e) {
+                        console.error('Failed to build timeline:', e);
+                    } finally {
+                        this.timelineLoading = false;
+                    }
+                },
+
+                downloadTimeline() {
+                    const blob = new Blob([this.timeline.markdown], { type: 'text/markdown' });
+                    const a = document.createElement('a');
+                    a.href = URL.createObjectURL(blob);
+                    a.download = 'timeline-' + (this.currentProposal.caseId || this.currentProposal.id) + '.md';
+                    a.click();
+                    URL.revokeObjectURL(a.href);
+                },
+
+                itemLabel(item) {
+                    if (!item) return '';
+                    return Object.entries(item).filter(([k]) => k !== 'note').map(([k, v]) => k + '=' + v).join(' ');
+                },
+
+                executionStatusText(execution) {
+                    if (execution.status === 'queued') return '排队中';
+                    if (!execution.finishedAt || execution.finishedAt.startsWith('0001-')) return '执行中';
+                    return execution.status + ' · ' + new Date(execution.finishedAt).toLocaleString();
+                },
+
+                async retryExecution(id) {
+                    this.retrying = true;
+                    try {
+                        const res = await fetch(apiURL('/api/proposal/' + id + '/retry'), { method: 'POST' });
+                        if (!res.ok) {
+                            alert(await res.text());
+                            return;
+                        }
+                        const data = await res.json();
+                        if (this.currentProposal && this.currentProposal.id === id) {
+                            this.currentProposal.status = data.status;
+                            this.currentProposal.execution = data.execution;
+                        }
+                        this.fetchProposals();
+                    } catch (e) {
+                        console.error('Failed to retry execution:', e);
+                    } finally {
+                        this.retrying = false;
+                    }
+                },
+
+                async commitArtifacts(id) {
+                    this.committing = true;
+                    try {
+                        const res = await fetch(apiURL('/api/proposal/' + id + '/commit'), { method: 'POST' });
+                        const text = await res.text();
+                        let data = null;
+                        try { data = JSON.parse(text); } catch (e) {}
+                        if (!data || !data.change) {
+                            alert(text);
+                            return;
+                        }
+                        if (this.currentProposal && this.currentProposal.id === id) {
+                            this.currentProposal.change = data.change;
+                        }
+                    } catch (e) {
+                        console.error('Failed to commit artifacts:', e);
+                    } finally {
+                        this.committing = false;
+                    }
+                },
+
                 async acceptProposal(id) {
+                    await this.decideProposal(id, 'accept');
+                },
+
+                async ignoreProposal(id) {
+                    await this.decideProposal(id, 'ignore');
+                },
+
+                saveTranslateLang() {
+                    localStorage.setItem('translateLang', this.translateLang);
+                },
+
+                async regenerateSummary() {
+                    const id = this.currentProposal.id;
+                    this.regenerating = true;
                     try {
-                        await fetch('/api/proposal/' + id + '/accept', {
+                        const res = await fetch(apiURL('/api/proposal/' + id + '/regenerate'), {
                             method: 'POST',
                             headers: { 'Content-Type': 'application/json' },
                             body: JSON.stringify({})
                         });
+                        if (!res.ok) {
+                            alert(await res.text());
+                            return;
+                        }
+                        const data = await res.json();
+                        if (this.currentProposal && this.currentProposal.id === id) {
+                            Object.assign(this.currentProposal, data.proposal, { summaryHtml: data.summaryHtml, translation: null, translationHtml: '' });
+                        }
                         this.fetchProposals();
                     } catch (e) {
-                        console.error('Failed to accept proposal:', e);
+                        console.error('Failed to regenerate summary:', e);
+                    } finally {
+                        this.regenerating = false;
                     }
                 },
 
-                async ignoreProposal(id) {
+                async annotateHost() {
+                    const host = this.currentProposal.details.host;
+                    const note = window.prompt('为主机 ' + host + ' 添加标注:');
+                    if (!note) return;
                     try {
-                        await fetch('/api/proposal/' + id + '/ignore', {
+                        const res = await fetch(apiURL('/api/annotations'), {
                             method: 'POST',
                             headers: { 'Content-Type': 'application/json' },
-                            body: JSON.stringify({})
+                            body: JSON.stringify({ kind: 'host', value: host, note: note })
+                        });
+                        if (!res.ok) {
+                            alert(await res.text());
+                            return;
+                        }
+                        const a = await res.json();
+                        this.currentProposal.annotations = [...(this.currentProposal.annotations || []), a];
+                    } catch (e) {
+                        console.error('Failed to add annotation:', e);
+                    }
+                },
+
+                async assignProposal() {
+                    const p = this.currentProposal;
+                    try {
+                        const res = await fetch(apiURL('/api/proposal/' + p.id + '/assign'), {
+                            method: 'POST',
+                            headers: { 'Content-Type': 'application/json' },
+                            body: JSON.stringify({ assignee: p.assignee || '' })
+                        });
+                        if (!res.ok) {
+                            alert(await res.text());
+                            return;
+                        }
+                        p.assignee = (await res.json()).assignee || '';
+                        this.history = null;
+                        this.fetchProposals();
+                    } catch (e) {
+                        console.error('Failed to assign proposal:', e);
+                    }
+                },
+
+                async addComment() {
+                    const p = this.currentProposal;
+                    try {
+                        const res = await fetch(apiURL('/api/proposal/' + p.id + '/comments'), {
+                            method: 'POST',
+                            headers: { 'Content-Type': 'application/json' },
+                            body: JSON.stringify({ text: this.newComment })
+                        });
+                        if (!res.ok) {
+                            alert(await res.text());
+                            return;
+                        }
+                        const c = await res.json();
+                        p.comments = [...(p.comments || []), c];
+                        this.newComment = '';
+                        this.history = null;
+                        this.fetchProposals();
+                    } catch (e) {
+                        console.error('Failed to add comment:', e);
+                    }
+                },
+
+                // 知悉提案: 暂不决策, 指定时间内不再提醒
+                async acknowledgeProposal(id) {
+                    const hours = window.prompt('暂停提醒多少小时?', '4');
+                    if (hours === null) return;
+                    try {
+                        const res = await fetch(apiURL('/api/proposal/' + id + '/ack'), {
+                            method: 'POST',
+                            headers: { 'Content-Type': 'application/json' },
+                            body: JSON.stringify({ hours: Number(hours), reason: this.decision.reason })
+                        });
+                        if (!res.ok) {
+                            alert(await res.text());
+                            return;
+                        }
+                        const data = await res.json();
+                        this.currentProposal.acknowledgement = data.acknowledgement;
+                        this.fetchProposals();
+                    } catch (e) {
+                        console.error('Failed to acknowledge proposal:', e);
+                    }
+                },
+
+                // 静默当前提案的主机 (无主机时静默整个类型)
+                async silenceProposal() {
+                    const p = this.currentProposal;
+                    const host = (p.details && typeof p.details.host === 'string') ? p.details.host : '';
+                    const scope = host ? '主机 ' + host : '类型 ' + p.type;
+                    const hours = window.prompt('静默' + scope + '的通知多少小时?', '24');
+                    if (hours === null) return;
+                    try {
+                        const res = await fetch(apiURL('/api/silences'), {
+                            method: 'POST',
+                            headers: { 'Content-Type': 'application/json' },
+                            body: JSON.stringify({ type: host ? '' : p.type, host: host, hours: Number(hours), reason: this.decision.reason })
+                        });
+                        if (!res.ok) {
+                            alert(await res.text());
+                            return;
+                        }
+                        this.fetchSilences();
+                    } catch (e) {
+                        console.error('Failed to create silence:', e);
+                    }
+                },
+
+                async toggleActivity(act) {
+                    try {
+                        const res = await fetch(apiURL('/api/activity/' + encodeURIComponent(act.name) + '/enabled'), {
+                            method: 'POST',
+                            headers: { 'Content-Type': 'application/json' },
+                            body: JSON.stringify({ enabled: !act.enabled })
+                        });
+                        if (!res.ok) {
+                            alert(await res.text());
+                        }
+                        this.fetchInfo();
+                    } catch (e) {
+                        console.error('Failed to toggle activity:', e);
+                    }
+                },
+
+                async triggerActivity(act) {
+                    try {
+                        const res = await fetch(apiURL('/api/activity/' + encodeURIComponent(act.name) + '/trigger'), { method: 'POST' });
+                        if (!res.ok) {
+                            alert(await res.text());
+                        }
+                        this.fetchInfo();
+                    } catch (e) {
+                        console.error('Failed to trigger activity:', e);
+                    }
+                },
+
+                async removeSilence(id) {
+                    try {
+                        const res = await fetch(apiURL('/api/silence/' + id), { method: 'DELETE' });
+                        if (!res.ok) {
+                            alert(await res.text());
+                        }
+                        this.fetchSilences();
+                    } catch (e) {
+                        console.error('Failed to remove silence:', e);
+                    }
+                },
+
+                // 选择模板时带出模板参数和备注
+                applyTemplate() {
+                    const t = (this.currentProposal.templates || []).find(x => x.name === this.decision.template);
+                    if (!t) return;
+                    for (const [key, value] of Object.entries(t.params || {})) {
+                        if (this.currentProposal.parameters && this.currentProposal.parameters[key]) {
+                            this.currentProposal.parameters[key].value = value;
+                        }
+                    }
+                    if (t.note) this.decision.reason = t.note;
+                },
+
+                // 与 Agent 建议相反的决策需填写理由
+                async decideProposal(id, action) {
+                    const inModal = this.currentProposal && this.currentProposal.id === id && this.showModal;
+                    const p = inModal ? this.currentProposal : this.proposals.find(x => x.id === id);
+                    const body = inModal ? this.decisionBody() : {};
+                    if (p && p.recommendation && p.recommendation !== action && !body.reason) {
+                        const reason = window.prompt('该决策与 Agent 建议 (' + p.recommendation + ') 相反, 请填写理由:');
+                        if (reason === null) return;
+                        body.reason = reason;
+                    }
+                    try {
+                        const res = await fetch(apiURL('/api/proposal/' + id + '/' + action), {
+                            method: 'POST',
+                            headers: { 'Content-Type': 'application/json' },
+                            body: JSON.stringify(body)
+                        });
+                        if (!res.ok) {
+                            alert(await res.text());
+                        } else {
+                            const data = await res.json();
+                            if (inModal) {
+                                this.currentProposal.status = data.status;
+                                this.currentProposal.execution = data.execution || null;
+                            }
+                            if (data.execution && data.execution.status === 'queued') {
+                                this.watchExecutions([id]);
+                            }
+                        }
+                        this.fetchProposals();
+                    } catch (e) {
+                        console.error('Failed to ' + action + ' proposal:', e);
+                    }
+                },
+
+                toggleSelectAll(checked) {
+                    this.selectedIds = checked ? this.pendingProposals.map(p => p.id) : [];
+                },
+
+                // 对所选提案统一确认或忽略, 任一提案校验失败时整批不生效
+                async bulkDecide(action) {
+                    const pending = new Set(this.pendingProposals.map(p => p.id));
+                    const ids = this.selectedIds.filter(id => pending.has(id));
+                    if (ids.length === 0) return;
+                    const label = action === 'accept' ? '确认' : '忽略';
+                    if (!confirm('确定' + label + '所选 ' + ids.length + ' 个提案?')) return;
+                    try {
+                        const res = await fetch(apiURL('/api/proposals/bulk'), {
+                            method: 'POST',
+                            headers: { 'Content-Type': 'application/json' },
+                            body: JSON.stringify({ ids, action, reason: this.bulkReason })
                         });
+                        if (!res.ok) {
+                            alert(await res.text());
+                            return;
+                        }
+                        const data = await res.json();
+                        this.selectedIds = [];
+                        this.bulkReason = '';
                         this.fetchProposals();
+                        this.watchExecutions(data.queued || []);
                     } catch (e) {
-                        console.error('Failed to ignore proposal:', e);
+                        console.error('Failed to bulk ' + action + ' proposals:', e);
+                    }
+                },
+
+                // 轮询后台排队执行的提案并同步到详情弹窗, 全部执行完成后提示失败的数量
+                async watchExecutions(ids) {
+                    let remaining = ids;
+                    let failed = 0;
+                    while (remaining.length > 0) {
+                        await new Promise(resolve => setTimeout(resolve, 2000));
+                        const next = [];
+                        for (const id of remaining) {
+                            try {
+                                const res = await fetch(apiURL('/api/proposal/' + id));
+                                if (!res.ok) continue;
+                                const p = await res.json();
+                                const execution = p.execution;
+                                if (this.currentProposal && this.currentProposal.id === id) {
+                                    this.currentProposal.status = p.status;
+                                    this.currentProposal.execution = execution || null;
+                                }
+                                if (execution && (execution.status === 'queued' || !execution.finishedAt || execution.finishedAt.startsWith('0001-'))) {
+                                    next.push(id);
+                                } else if (execution && execution.status === 'failed') {
+                                    failed++;
+                                }
+                            } catch (e) {
+                                next.push(id);
+                            }
+                        }
+                        remaining = next;
+                    }
+                    this.fetchProposals();
+                    if (failed > 0) {
+                        alert('决策已记录, 但 ' + failed + ' 个提案执行失败, 可在详情中重试');
+                    }
+                },
+
+                // 弹窗中与 Agent 建议值不同的参数
+                editedParams() {
+                    return Object.entries((this.currentProposal && this.currentProposal.parameters) || {})
+                        .filter(([key, param]) => String(param.value ?? '') !== (this.decision.suggested[key] ?? ''))
+                        .map(([key]) => key);
+                },
+
+                // 弹窗中填写的参数、模板和备注
+                decisionBody() {
+                    const body = { params: {}, template: this.decision.template, reason: this.decision.reason };
+                    for (const [key, param] of Object.entries(this.currentProposal.parameters || {})) {
+                        body.params[key] = String(param.value ?? '');
+                    }
+                    return body;
+                },
+
+                async previewExecution(id, action) {
+                    try {
+                        const res = await fetch(apiURL('/api/proposal/' + id + '/preview?action=' + action), {
+                            method: 'POST',
+                            headers: { 'Content-Type': 'application/json' },
+                            body: JSON.stringify(this.decisionBody())
+                        });
+                        if (!res.ok) {
+                            alert(await res.text());
+                            return;
+                        }
+                        this.preview = await res.json();
+                    } catch (e) {
+                        console.error('Failed to preview execution:', e);
+                    }
+                },
+
+                renderPreviewRequest(req, numbered) {
+                    let text = (numbered ? (req.index + 1) + '. ' : '') + req.method + ' ' + req.url;
+                    if (req.skipped) text += '    (已成功, 重试时跳过)';
+                    for (const [k, v] of Object.entries(req.headers || {})) text += '\n' + k + ': ' + v;
+                    if (req.body) {
+                        let body = req.body;
+                        try { body = JSON.stringify(JSON.parse(body), null, 2); } catch (e) {}
+                        text += '\n\n' + body;
+                    }
+                    return text;
+                },
+
+                highlightLang(contentType) {
+                    const langs = { 'sql': 'sql', 'json': 'json', 'http': 'http' };
+                    return langs[contentType] || 'plaintext';
+                },
+
+                async copyText(text) {
+                    try {
+                        await navigator.clipboard.writeText(text);
+                    } catch (e) {
+                        console.error('Failed to copy:', e);
                     }
                 },
 
@@ -829,17 +3692,32 @@ var indexHTML = []byte(`<!DOCTYPE html>
                         'risk': 'bg-red-900 text-red-300',
                         'weak': 'bg-yellow-900 text-yellow-300',
                         'api_biz': 'bg-blue-900 text-blue-300',
-                        'app': 'bg-purple-900 text-purple-300'
+                        'app': 'bg-purple-900 text-purple-300',
+                        'host': 'bg-green-900 text-green-300',
+                        'k8s': 'bg-cyan-900 text-cyan-300',
+                        'cloud': 'bg-indigo-900 text-indigo-300'
                     };
                     return classes[type] || 'bg-gray-700 text-gray-300';
                 },
 
+                severityClass(severity) {
+                    const classes = {
+                        'critical': 'bg-red-600 text-white',
+                        'high': 'bg-orange-800 text-orange-200',
+                        'medium': 'bg-yellow-800 text-yellow-200',
+                        'low': 'bg-gray-700 text-gray-300'
+                    };
+                    return classes[severity] || 'bg-gray-800 text-gray-400';
+                },
+
                 statusClass(status) {
                     const classes = {
                         'pending': 'bg-yellow-900 text-yellow-300',
                         'accepted': 'bg-green-900 text-green-300',
                         'ignored': 'bg-gray-700 text-gray-300',
-                        'modified': 'bg-blue-900 text-blue-300'
+                        'modified': 'bg-blue-900 text-blue-300',
+                        'execution_failed': 'bg-red-900 text-red-300',
+                        'expired': 'bg-gray-800 text-gray-500'
                     };
                     return classes[status] || 'bg-gray-700 text-gray-300';
                 },
@@ -849,7 +3727,9 @@ var indexHTML = []byte(`<!DOCTYPE html>
                         'pending': '待处理',
                         'accepted': '已确认',
                         'ignored': '已忽略',
-                        'modified': '已修改'
+                        'modified': '已修改',
+                        'execution_failed': '执行失败',
+                        'expired': '已过期'
                     };
                     return texts[status] || status;
                 }
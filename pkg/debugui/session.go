@@ -0,0 +1,268 @@
+package debugui
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/kvstore"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const (
+	defaultSessionTTL  = 12 * time.Hour
+	defaultIdleTimeout = 30 * time.Minute
+	defaultMaxSessions = 5
+)
+
+// authSession 登录会话; ID 为非机密标识, 仅用于列表展示和注销, Cookie 中保存的是 token
+type authSession struct {
+	ID         string    `json:"id"`
+	Method     string    `json:"method"` // passkey / recovery
+	Credential string    `json:"credential,omitempty"`
+	ClientIP   string    `json:"clientIp"`
+	UserAgent  string    `json:"userAgent"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+// sessionKeyPrefix 会话在共享存储中的键前缀, 键为 token 的 SHA-256, 存储中不保存 token 明文
+const sessionKeyPrefix = "debugui:session:"
+
+// sessionStore 会话表: 空闲超时、绝对超时, 超出并发上限时淘汰最久未活动的会话
+//
+// 会话保存在 kvstore 中, 配置 secops.cache 为 redis 时多个实例共享登录状态;
+// 存储出错时按会话无效处理, 不会放行请求。
+type sessionStore struct {
+	idle     time.Duration // 0 表示不限制空闲时间
+	absolute time.Duration
+	max      int // 0 表示不限制并发会话数
+
+	mu    sync.Mutex
+	store kvstore.Store
+	now   func() time.Time
+}
+
+func newSessionStore(idle, absolute time.Duration, max int) *sessionStore {
+	return &sessionStore{
+		idle:     idle,
+		absolute: absolute,
+		max:      max,
+		store:    kvstore.NewMemory(),
+		now:      time.Now,
+	}
+}
+
+// useStore 切换到共享存储, 需在对外服务前调用
+func (st *sessionStore) useStore(store kvstore.Store) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.store = store
+}
+
+func randomToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func sessionKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return sessionKeyPrefix + hex.EncodeToString(sum[:])
+}
+
+// storedSession 存储中的会话记录, 附带键以便按 ID 注销
+type storedSession struct {
+	authSession
+	Key string `json:"key"`
+}
+
+// save 写入会话, 存储侧的过期时间取绝对超时和空闲超时中较早者
+func (st *sessionStore) save(key string, sess authSession, now time.Time) error {
+	ttl := sess.ExpiresAt.Sub(now)
+	if st.idle > 0 && st.idle < ttl {
+		ttl = st.idle
+	}
+	if ttl <= 0 {
+		return st.store.Delete(context.Background(), key)
+	}
+	data, err := json.Marshal(storedSession{authSession: sess, Key: key})
+	if err != nil {
+		return err
+	}
+	return st.store.Set(context.Background(), key, data, ttl)
+}
+
+func (st *sessionStore) load(key string) (authSession, bool) {
+	data, err := st.store.Get(context.Background(), key)
+	if err != nil {
+		if !errors.Is(err, kvstore.ErrNotFound) {
+			logger.WarnCF("debugui", "Session store read failed",
+				map[string]interface{}{
+					"error": err.Error(),
+				})
+		}
+		return authSession{}, false
+	}
+	var rec storedSession
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return authSession{}, false
+	}
+	return rec.authSession, true
+}
+
+// all 返回存储中全部有效会话及其键, 顺带删除已过期的会话
+func (st *sessionStore) all(now time.Time) map[string]authSession {
+	keys, err := st.store.Keys(context.Background(), sessionKeyPrefix)
+	if err != nil {
+		logger.WarnCF("debugui", "Session store list failed",
+			map[string]interface{}{
+				"error": err.Error(),
+			})
+		return nil
+	}
+	items := make(map[string]authSession, len(keys))
+	for _, key := range keys {
+		sess, ok := st.load(key)
+		if !ok {
+			continue
+		}
+		if st.expired(&sess, now) {
+			st.store.Delete(context.Background(), key)
+			continue
+		}
+		items[key] = sess
+	}
+	return items
+}
+
+// create 创建会话, 返回 token 和会话快照
+func (st *sessionStore) create(info authSession) (string, authSession, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", authSession{}, err
+	}
+	id, err := randomToken(9)
+	if err != nil {
+		return "", authSession{}, err
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	now := st.now()
+
+	sess := info
+	sess.ID = id
+	sess.CreatedAt = now
+	sess.LastSeenAt = now
+	sess.ExpiresAt = now.Add(st.absolute)
+	key := sessionKey(token)
+	if err := st.save(key, sess, now); err != nil {
+		return "", authSession{}, err
+	}
+
+	if st.max > 0 {
+		others := st.all(now)
+		delete(others, key)
+		for len(others) >= st.max {
+			oldest := ""
+			for k, s := range others {
+				if oldest == "" || s.LastSeenAt.Before(others[oldest].LastSeenAt) {
+					oldest = k
+				}
+			}
+			st.store.Delete(context.Background(), oldest)
+			delete(others, oldest)
+		}
+	}
+	return token, sess, nil
+}
+
+// lookup 校验 token 并刷新最近活动时间
+func (st *sessionStore) lookup(token string) (authSession, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	key := sessionKey(token)
+	sess, ok := st.load(key)
+	if !ok {
+		return authSession{}, false
+	}
+	now := st.now()
+	if st.expired(&sess, now) {
+		st.store.Delete(context.Background(), key)
+		return authSession{}, false
+	}
+	sess.LastSeenAt = now
+	if err := st.save(key, sess, now); err != nil {
+		logger.WarnCF("debugui", "Session store write failed",
+			map[string]interface{}{
+				"error": err.Error(),
+			})
+	}
+	return sess, true
+}
+
+func (st *sessionStore) expired(sess *authSession, now time.Time) bool {
+	if now.After(sess.ExpiresAt) {
+		return true
+	}
+	return st.idle > 0 && now.Sub(sess.LastSeenAt) > st.idle
+}
+
+// list 返回有效会话, 最近活动的在前
+func (st *sessionStore) list() []authSession {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	all := st.all(st.now())
+	items := make([]authSession, 0, len(all))
+	for _, sess := range all {
+		items = append(items, sess)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].LastSeenAt.After(items[j].LastSeenAt)
+	})
+	return items
+}
+
+// revoke 按会话 ID 注销
+func (st *sessionStore) revoke(id string) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for key, sess := range st.all(st.now()) {
+		if sess.ID == id {
+			return st.store.Delete(context.Background(), key) == nil
+		}
+	}
+	return false
+}
+
+// revokeToken 按 token 注销
+func (st *sessionStore) revokeToken(token string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.store.Delete(context.Background(), sessionKey(token))
+}
+
+// revokeAll 注销全部会话, 返回注销数量
+func (st *sessionStore) revokeAll() int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	n := 0
+	for key := range st.all(st.now()) {
+		if st.store.Delete(context.Background(), key) == nil {
+			n++
+		}
+	}
+	return n
+}
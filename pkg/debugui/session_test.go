@@ -0,0 +1,116 @@
+package debugui
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/kvstore"
+)
+
+func TestSessionStoreTimeouts(t *testing.T) {
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	st := newSessionStore(30*time.Minute, 2*time.Hour, 0)
+	st.now = func() time.Time { return now }
+
+	token, _, err := st.create(authSession{Method: "passkey"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 持续活动可以续期空闲时间, 但不能超过绝对有效期
+	for i := 0; i < 5; i++ {
+		now = now.Add(20 * time.Minute)
+		if _, ok := st.lookup(token); !ok {
+			t.Fatalf("session expired after %d active periods", i+1)
+		}
+	}
+	now = now.Add(21 * time.Minute)
+	if _, ok := st.lookup(token); ok {
+		t.Error("session valid past absolute timeout")
+	}
+
+	token, _, _ = st.create(authSession{Method: "passkey"})
+	now = now.Add(31 * time.Minute)
+	if _, ok := st.lookup(token); ok {
+		t.Error("session valid past idle timeout")
+	}
+}
+
+func TestSessionStoreMaxSessionsEvictsLeastRecent(t *testing.T) {
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	st := newSessionStore(0, time.Hour, 2)
+	st.now = func() time.Time { return now }
+
+	first, _, _ := st.create(authSession{ClientIP: "10.0.0.1"})
+	now = now.Add(time.Minute)
+	second, _, _ := st.create(authSession{ClientIP: "10.0.0.2"})
+	now = now.Add(time.Minute)
+	st.lookup(first) // first 比 second 更近活动
+	now = now.Add(time.Minute)
+	third, _, _ := st.create(authSession{ClientIP: "10.0.0.3"})
+
+	if _, ok := st.lookup(second); ok {
+		t.Error("least recently used session was not evicted")
+	}
+	if _, ok := st.lookup(first); !ok {
+		t.Error("recently used session was evicted")
+	}
+	if _, ok := st.lookup(third); !ok {
+		t.Error("new session was evicted")
+	}
+}
+
+func TestSessionStoreRevoke(t *testing.T) {
+	st := newSessionStore(0, time.Hour, 0)
+	a, sessA, _ := st.create(authSession{})
+	b, _, _ := st.create(authSession{})
+
+	if st.revoke("unknown") {
+		t.Error("revoke(unknown) = true")
+	}
+	if !st.revoke(sessA.ID) {
+		t.Fatal("revoke() = false")
+	}
+	if _, ok := st.lookup(a); ok {
+		t.Error("revoked session still valid")
+	}
+	if len(st.list()) != 1 {
+		t.Errorf("list() = %d sessions, want 1", len(st.list()))
+	}
+
+	if n := st.revokeAll(); n != 1 {
+		t.Errorf("revokeAll() = %d, want 1", n)
+	}
+	if _, ok := st.lookup(b); ok {
+		t.Error("session valid after revokeAll")
+	}
+}
+
+func TestSessionStoreShared(t *testing.T) {
+	// 两个实例共享同一存储: 在一个实例登录, 另一个实例可见并可注销
+	shared := kvstore.NewMemory()
+	a := newSessionStore(time.Hour, 2*time.Hour, 0)
+	a.useStore(shared)
+	b := newSessionStore(time.Hour, 2*time.Hour, 0)
+	b.useStore(shared)
+
+	token, sess, err := a.create(authSession{Method: "password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := b.lookup(token); !ok || got.ID != sess.ID {
+		t.Fatalf("session not visible on second instance: %+v, %v", got, ok)
+	}
+	keys, _ := shared.Keys(context.Background(), "")
+	if len(keys) != 1 || strings.Contains(keys[0], token) {
+		t.Errorf("store keys = %v, token must not be stored in clear", keys)
+	}
+	if !b.revoke(sess.ID) {
+		t.Fatal("revoke() = false")
+	}
+	if _, ok := a.lookup(token); ok {
+		t.Error("session revoked on one instance is still valid on the other")
+	}
+}
@@ -0,0 +1,131 @@
+package debugui
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+// statsResponse /api/stats 响应
+type statsResponse struct {
+	Stores     []secops.StoreUsage `json:"stores"`
+	Runtime    runtimeStats        `json:"runtime"`
+	Chat       chatStats           `json:"chat"`
+	Activities activityStats       `json:"activities"`
+}
+
+// activityStats 活动执行的并发和跳过情况
+type activityStats struct {
+	secops.ActivityPoolStats
+	Skipped []secops.ActivitySkip `json:"skipped"`
+}
+
+// chatStats 对话接口的并发和限流情况
+type chatStats struct {
+	Running  int    `json:"running"`
+	Queued   int    `json:"queued"`
+	Rejected uint64 `json:"rejected"` // 因限速或队列已满返回 429 的请求数
+}
+
+// runtimeStats Go 运行时内存概况
+type runtimeStats struct {
+	HeapAllocBytes uint64 `json:"heapAllocBytes"`
+	HeapInuseBytes uint64 `json:"heapInuseBytes"`
+	SysBytes       uint64 `json:"sysBytes"`
+	Goroutines     int    `json:"goroutines"`
+	NumGC          uint32 `json:"numGC"`
+}
+
+func (s *Server) stats() statsResponse {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	resp := statsResponse{
+		Stores:     []secops.StoreUsage{},
+		Activities: activityStats{Skipped: []secops.ActivitySkip{}},
+		Runtime: runtimeStats{
+			HeapAllocBytes: ms.HeapAlloc,
+			HeapInuseBytes: ms.HeapInuse,
+			SysBytes:       ms.Sys,
+			Goroutines:     runtime.NumGoroutine(),
+			NumGC:          ms.NumGC,
+		},
+	}
+	if s.secopsService != nil {
+		resp.Stores = s.secopsService.MemoryUsage()
+		resp.Activities = activityStats{
+			ActivityPoolStats: s.secopsService.ActivityPoolStats(),
+			Skipped:           s.secopsService.ActivitySkips(),
+		}
+	}
+	if s.chatLimit != nil {
+		resp.Chat = s.chatLimit.stats()
+	}
+	return resp
+}
+
+// handleStats 各内存存储的用量和运行时内存
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.stats())
+}
+
+// handleMetrics 以 Prometheus 文本格式导出内存用量, 抓取时通过 Bearer 令牌认证
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeMetrics(w, s.stats())
+}
+
+func writeMetrics(w io.Writer, st statsResponse) {
+	type storeMetric struct {
+		name, help, kind string
+		value            func(secops.StoreUsage) (float64, bool)
+	}
+	metrics := []storeMetric{
+		{"soclaw_store_items", "Items held in memory by the store.", "gauge",
+			func(u secops.StoreUsage) (float64, bool) { return float64(u.Items), true }},
+		{"soclaw_store_bytes", "Estimated memory used by the store (serialized size).", "gauge",
+			func(u secops.StoreUsage) (float64, bool) { return float64(u.Bytes), true }},
+		{"soclaw_store_max_items", "Configured item limit of the store.", "gauge",
+			func(u secops.StoreUsage) (float64, bool) { return float64(u.MaxItems), u.MaxItems > 0 }},
+		{"soclaw_store_max_bytes", "Configured byte limit of the store.", "gauge",
+			func(u secops.StoreUsage) (float64, bool) { return float64(u.MaxBytes), u.MaxBytes > 0 }},
+		{"soclaw_store_evictions_total", "Items evicted to stay within the limits.", "counter",
+			func(u secops.StoreUsage) (float64, bool) { return float64(u.Evicted), true }},
+		{"soclaw_store_offloaded_items", "Items offloaded to disk and loaded on access.", "gauge",
+			func(u secops.StoreUsage) (float64, bool) { return float64(u.Offloaded), u.Name == "proposals" }},
+	}
+	for _, m := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		for _, u := range st.Stores {
+			if v, ok := m.value(u); ok {
+				fmt.Fprintf(w, "%s{store=%q} %g\n", m.name, u.Name, v)
+			}
+		}
+	}
+
+	runtimeMetrics := []struct {
+		name, help string
+		value      float64
+	}{
+		{"go_memstats_heap_alloc_bytes", "Bytes of allocated heap objects.", float64(st.Runtime.HeapAllocBytes)},
+		{"go_memstats_heap_inuse_bytes", "Bytes in in-use heap spans.", float64(st.Runtime.HeapInuseBytes)},
+		{"go_memstats_sys_bytes", "Bytes of memory obtained from the OS.", float64(st.Runtime.SysBytes)},
+		{"go_goroutines", "Number of goroutines that currently exist.", float64(st.Runtime.Goroutines)},
+		{"soclaw_chat_running", "Chat requests currently being processed.", float64(st.Chat.Running)},
+		{"soclaw_chat_queued", "Chat requests waiting for a free slot.", float64(st.Chat.Queued)},
+		{"soclaw_activity_running", "Activity runs currently holding a worker slot.", float64(st.Activities.Running)},
+		{"soclaw_activity_queued", "Activity runs waiting for a free worker slot.", float64(st.Activities.Queued)},
+	}
+	for _, m := range runtimeMetrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %g\n", m.name, m.help, m.name, m.name, m.value)
+	}
+	fmt.Fprintf(w, "# HELP soclaw_chat_rejected_total Chat requests rejected by rate limit or full queue.\n# TYPE soclaw_chat_rejected_total counter\nsoclaw_chat_rejected_total %d\n", st.Chat.Rejected)
+	fmt.Fprintf(w, "# HELP soclaw_activity_skipped_total Activity runs skipped by overlap protection or the business calendar.\n# TYPE soclaw_activity_skipped_total counter\n")
+	for _, sk := range st.Activities.Skipped {
+		fmt.Fprintf(w, "soclaw_activity_skipped_total{activity=%q,reason=%q} %d\n", sk.Activity, sk.Reason, sk.Count)
+	}
+}
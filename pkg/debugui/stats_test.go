@@ -0,0 +1,63 @@
+package debugui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+func TestStatsWithoutSecOps(t *testing.T) {
+	s := NewServer(config.DebugUIConfig{}, nil, nil, nil, t.TempDir())
+	rec := httptest.NewRecorder()
+	s.handleStats(rec, httptest.NewRequest(http.MethodGet, "/api/stats", nil))
+
+	var resp statsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Stores == nil || len(resp.Stores) != 0 || resp.Runtime.HeapAllocBytes == 0 || resp.Runtime.Goroutines == 0 {
+		t.Errorf("stats = %+v", resp)
+	}
+}
+
+func TestWriteMetrics(t *testing.T) {
+	var b strings.Builder
+	writeMetrics(&b, statsResponse{
+		Stores: []secops.StoreUsage{
+			{Name: "proposals", Items: 3, Bytes: 2048, MaxItems: 1000, Evicted: 7, Offloaded: 5},
+			{Name: "runs", Items: 10, Bytes: 4096, MaxItems: 500},
+		},
+		Runtime: runtimeStats{HeapAllocBytes: 1 << 20, Goroutines: 12},
+		Activities: activityStats{
+			ActivityPoolStats: secops.ActivityPoolStats{Limit: 4, Running: 4, Queued: 2},
+			Skipped:           []secops.ActivitySkip{{Activity: "risk_analysis", Reason: secops.SkipOverlap, Count: 3}},
+		},
+	})
+	out := b.String()
+	for _, want := range []string{
+		"# TYPE soclaw_store_items gauge\n",
+		`soclaw_store_items{store="proposals"} 3` + "\n",
+		`soclaw_store_bytes{store="runs"} 4096` + "\n",
+		`soclaw_store_max_items{store="runs"} 500` + "\n",
+		"# TYPE soclaw_store_evictions_total counter\n",
+		`soclaw_store_evictions_total{store="proposals"} 7` + "\n",
+		`soclaw_store_offloaded_items{store="proposals"} 5` + "\n",
+		"go_memstats_heap_alloc_bytes 1.048576e+06\n",
+		"go_goroutines 12\n",
+		"soclaw_activity_queued 2\n",
+		"# TYPE soclaw_activity_skipped_total counter\n",
+		`soclaw_activity_skipped_total{activity="risk_analysis",reason="overlap"} 3` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics missing %q\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "soclaw_store_max_bytes{") || strings.Contains(out, `soclaw_store_offloaded_items{store="runs"}`) {
+		t.Errorf("unset limits and offload counts must be omitted\n%s", out)
+	}
+}
@@ -0,0 +1,227 @@
+package debugui
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+const (
+	taxiiMediaType = "application/taxii+json;version=2.1"
+	stixMediaType  = "application/stix+json;version=2.1"
+
+	// taxiiCollectionID 已确认提案指标的集合 ID, 固定不变以便客户端长期订阅
+	taxiiCollectionID = "5f0c7b5e-3f4e-4e7c-8a52-0d1c7c9e6a31"
+
+	defaultTaxiiLimit = 100
+	maxTaxiiLimit     = 1000
+)
+
+// taxiiCollection TAXII 2.1 集合描述
+func taxiiCollection() map[string]interface{} {
+	return map[string]interface{}{
+		"id":          taxiiCollectionID,
+		"title":       "soclaw confirmed indicators",
+		"description": "IOCs extracted from proposals confirmed by analysts",
+		"can_read":    true,
+		"can_write":   false,
+		"media_types": []string{stixMediaType},
+	}
+}
+
+// writeTaxii 以 TAXII 媒体类型输出响应
+func writeTaxii(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", taxiiMediaType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// taxiiError TAXII 错误消息
+func taxiiError(w http.ResponseWriter, status int, title string) {
+	writeTaxii(w, status, map[string]interface{}{
+		"title":       title,
+		"http_status": strconv.Itoa(status),
+	})
+}
+
+// handleTaxiiDiscovery GET /api/taxii2/ 服务发现
+func (s *Server) handleTaxiiDiscovery(w http.ResponseWriter, r *http.Request) {
+	root := requestScheme(r, s.config.TrustProxy) + "://" + r.Host + s.externalBasePath(r) + "/api/taxii2/soclaw/"
+	writeTaxii(w, http.StatusOK, map[string]interface{}{
+		"title":       "soclaw TAXII server",
+		"description": "STIX 2.1 indicators from confirmed soclaw proposals",
+		"default":     root,
+		"api_roots":   []string{root},
+	})
+}
+
+// handleTaxiiAPIRoot GET /api/taxii2/soclaw/ API Root 信息
+func (s *Server) handleTaxiiAPIRoot(w http.ResponseWriter, r *http.Request) {
+	writeTaxii(w, http.StatusOK, map[string]interface{}{
+		"title":              "soclaw",
+		"versions":           []string{taxiiMediaType},
+		"max_content_length": 0,
+	})
+}
+
+// handleTaxiiCollections GET /api/taxii2/soclaw/collections/ 集合列表, 未开启 STIX 发布时为空
+func (s *Server) handleTaxiiCollections(w http.ResponseWriter, r *http.Request) {
+	if s.secopsService == nil || !s.secopsService.STIXEnabled() {
+		writeTaxii(w, http.StatusOK, map[string]interface{}{})
+		return
+	}
+	writeTaxii(w, http.StatusOK, map[string]interface{}{
+		"collections": []interface{}{taxiiCollection()},
+	})
+}
+
+// taxiiCollectionFound 校验集合 ID, 不存在时输出 404
+func (s *Server) taxiiCollectionFound(w http.ResponseWriter, r *http.Request) bool {
+	if s.secopsService == nil || !s.secopsService.STIXEnabled() || r.PathValue("id") != taxiiCollectionID {
+		taxiiError(w, http.StatusNotFound, "collection not found")
+		return false
+	}
+	return true
+}
+
+// handleTaxiiCollection GET /api/taxii2/soclaw/collections/{id}/ 集合信息
+func (s *Server) handleTaxiiCollection(w http.ResponseWriter, r *http.Request) {
+	if !s.taxiiCollectionFound(w, r) {
+		return
+	}
+	writeTaxii(w, http.StatusOK, taxiiCollection())
+}
+
+// taxiiPage 按 added_after、match[id]、match[type]、limit、next 筛选集合中的指标
+//
+// 返回当前页、是否还有更多及下一页游标
+func (s *Server) taxiiPage(w http.ResponseWriter, r *http.Request) ([]secops.IndicatorEntry, bool, string, bool) {
+	q := r.URL.Query()
+
+	var after time.Time
+	if v := q.Get("added_after"); v != "" {
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			taxiiError(w, http.StatusBadRequest, "invalid added_after: "+v)
+			return nil, false, "", false
+		}
+		after = t
+	}
+
+	limit := defaultTaxiiLimit
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			taxiiError(w, http.StatusBadRequest, "invalid limit: "+v)
+			return nil, false, "", false
+		}
+		limit = min(n, maxTaxiiLimit)
+	}
+
+	offset := 0
+	if v := q.Get("next"); v != "" {
+		n, err := decodeCursor(v)
+		if err != nil {
+			taxiiError(w, http.StatusBadRequest, "invalid next")
+			return nil, false, "", false
+		}
+		offset = n
+	}
+
+	var ids, types []string
+	if v := q.Get("match[id]"); v != "" {
+		ids = strings.Split(v, ",")
+	}
+	if v := q.Get("match[type]"); v != "" {
+		types = strings.Split(v, ",")
+	}
+
+	var entries []secops.IndicatorEntry
+	for _, e := range s.secopsService.Indicators(after) {
+		if len(ids) > 0 && !slices.Contains(ids, e.Indicator.ID) {
+			continue
+		}
+		if len(types) > 0 && !slices.Contains(types, e.Indicator.Type) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+
+	if offset > len(entries) {
+		offset = len(entries)
+	}
+	end := min(offset+limit, len(entries))
+	more := end < len(entries)
+	next := ""
+	if more {
+		next = encodeCursor(end)
+	}
+	return entries[offset:end], more, next, true
+}
+
+// setDateAddedHeaders 当前页首末对象的加入时间, 客户端据此作为下次轮询的 added_after
+func setDateAddedHeaders(w http.ResponseWriter, entries []secops.IndicatorEntry) {
+	if len(entries) == 0 {
+		return
+	}
+	w.Header().Set("X-TAXII-Date-Added-First", entries[0].DateAdded.UTC().Format(time.RFC3339Nano))
+	w.Header().Set("X-TAXII-Date-Added-Last", entries[len(entries)-1].DateAdded.UTC().Format(time.RFC3339Nano))
+}
+
+// handleTaxiiObjects GET /api/taxii2/soclaw/collections/{id}/objects/ 获取指标
+func (s *Server) handleTaxiiObjects(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		taxiiError(w, http.StatusMethodNotAllowed, "collection is read-only")
+		return
+	}
+	if !s.taxiiCollectionFound(w, r) {
+		return
+	}
+	entries, more, next, ok := s.taxiiPage(w, r)
+	if !ok {
+		return
+	}
+
+	objects := make([]interface{}, len(entries))
+	for i, e := range entries {
+		objects[i] = e.Indicator
+	}
+	envelope := map[string]interface{}{"more": more, "objects": objects}
+	if next != "" {
+		envelope["next"] = next
+	}
+	setDateAddedHeaders(w, entries)
+	writeTaxii(w, http.StatusOK, envelope)
+}
+
+// handleTaxiiManifest GET /api/taxii2/soclaw/collections/{id}/manifest/ 获取指标清单
+func (s *Server) handleTaxiiManifest(w http.ResponseWriter, r *http.Request) {
+	if !s.taxiiCollectionFound(w, r) {
+		return
+	}
+	entries, more, next, ok := s.taxiiPage(w, r)
+	if !ok {
+		return
+	}
+
+	records := make([]map[string]interface{}, len(entries))
+	for i, e := range entries {
+		records[i] = map[string]interface{}{
+			"id":         e.Indicator.ID,
+			"date_added": e.DateAdded,
+			"version":    e.Indicator.Modified,
+			"media_type": stixMediaType,
+		}
+	}
+	manifest := map[string]interface{}{"more": more, "objects": records}
+	if next != "" {
+		manifest["next"] = next
+	}
+	setDateAddedHeaders(w, entries)
+	writeTaxii(w, http.StatusOK, manifest)
+}
@@ -0,0 +1,125 @@
+package debugui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/agent"
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+func newTaxiiTestServer(t *testing.T) (*secops.Service, http.Handler) {
+	t.Helper()
+	workspace := t.TempDir()
+	msgBus := bus.NewMessageBus()
+	al := agent.NewAgentLoop(&config.Config{
+		Agents: config.AgentsConfig{Defaults: config.AgentDefaults{Workspace: workspace, Model: "test-model"}},
+	}, msgBus, nil)
+	svc, err := secops.NewService(&config.SecOpsConfig{
+		Enabled: true,
+		STIX:    config.STIXExportConfig{Enabled: true},
+	}, al, msgBus, workspace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 决策的执行和决策处理函数由服务的执行重试协程调用
+	if err := svc.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(svc.Stop)
+	s := NewServer(config.DebugUIConfig{}, nil, svc.ProposalService(), svc, "")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/taxii2/{$}", s.handleTaxiiDiscovery)
+	mux.HandleFunc("GET /api/taxii2/soclaw/collections/{$}", s.handleTaxiiCollections)
+	mux.HandleFunc("/api/taxii2/soclaw/collections/{id}/objects/{$}", s.handleTaxiiObjects)
+	mux.HandleFunc("GET /api/taxii2/soclaw/collections/{id}/manifest/{$}", s.handleTaxiiManifest)
+	return svc, mux
+}
+
+func TestTaxiiObjects(t *testing.T) {
+	svc, h := newTaxiiTestServer(t)
+	ps := svc.ProposalService()
+	for _, ip := range []string{"203.0.113.7", "198.51.100.9"} {
+		id := ps.Create(secops.NewProposal("risk", "SQL 注入", "", map[string]interface{}{"ip": ip}))
+		if err := ps.Accept(id, secops.DecisionRequest{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 指标在决策处理函数中异步发布
+	deadline := time.Now().Add(2 * time.Second)
+	for len(svc.Indicators(time.Time{})) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/api/taxii2/")
+	var discovery struct {
+		Default string `json:"default"`
+	}
+	json.NewDecoder(rec.Body).Decode(&discovery)
+	if rec.Header().Get("Content-Type") != taxiiMediaType || discovery.Default != "http://example.com/api/taxii2/soclaw/" {
+		t.Errorf("discovery = %q %+v", rec.Header().Get("Content-Type"), discovery)
+	}
+
+	rec = get("/api/taxii2/soclaw/collections/")
+	var collections struct {
+		Collections []map[string]interface{} `json:"collections"`
+	}
+	json.NewDecoder(rec.Body).Decode(&collections)
+	if len(collections.Collections) != 1 || collections.Collections[0]["id"] != taxiiCollectionID {
+		t.Fatalf("collections = %+v", collections)
+	}
+
+	objects := "/api/taxii2/soclaw/collections/" + taxiiCollectionID + "/objects/"
+	rec = get(objects + "?limit=1")
+	var env struct {
+		More    bool                     `json:"more"`
+		Next    string                   `json:"next"`
+		Objects []map[string]interface{} `json:"objects"`
+	}
+	json.NewDecoder(rec.Body).Decode(&env)
+	if !env.More || env.Next == "" || len(env.Objects) != 1 || env.Objects[0]["type"] != "indicator" ||
+		rec.Header().Get("X-TAXII-Date-Added-Last") == "" {
+		t.Fatalf("first page = %+v", env)
+	}
+	first := env.Objects[0]["id"]
+
+	rec = get(objects + "?limit=1&next=" + env.Next)
+	env.Next = ""
+	json.NewDecoder(rec.Body).Decode(&env)
+	if env.More || len(env.Objects) != 1 || env.Objects[0]["id"] == first {
+		t.Errorf("second page = %+v", env)
+	}
+
+	rec = get("/api/taxii2/soclaw/collections/" + taxiiCollectionID + "/manifest/?match[id]=" + first.(string))
+	var manifest struct {
+		Objects []map[string]interface{} `json:"objects"`
+	}
+	json.NewDecoder(rec.Body).Decode(&manifest)
+	if len(manifest.Objects) != 1 || manifest.Objects[0]["media_type"] != stixMediaType {
+		t.Errorf("manifest = %+v", manifest)
+	}
+
+	if rec = get(objects + "?added_after=yesterday"); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid added_after: code=%d", rec.Code)
+	}
+	if rec = get("/api/taxii2/soclaw/collections/other/objects/"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown collection: code=%d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, objects, nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("post objects: code=%d", rec.Code)
+	}
+}
@@ -0,0 +1,77 @@
+package debugui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// handleTimeline GET /api/timeline 生成事件时间线
+//
+// 参数: case (案件 ID) 和/或 ids (逗号分隔的提案 ID); narrate=1 时由 Agent 撰写事件经过;
+// format=markdown 时直接下载 Markdown, 否则返回 {"timeline", "markdown", "html"}
+func (s *Server) handleTimeline(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.proposalService == nil {
+		http.Error(w, "proposal service not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	q := r.URL.Query()
+	caseID := q.Get("case")
+	var ids []string
+	if v := q.Get("ids"); v != "" {
+		ids = strings.Split(v, ",")
+	}
+	if caseID == "" && len(ids) == 0 {
+		http.Error(w, "case or ids required", http.StatusBadRequest)
+		return
+	}
+
+	tl, err := s.proposalService.BuildTimeline(caseID, ids)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	// 撰写失败时仍返回时间线, 错误单独给出
+	narrativeError := ""
+	if q.Get("narrate") == "1" || q.Get("narrate") == "true" {
+		if s.secopsService == nil {
+			narrativeError = "secops service not available"
+		} else if err := s.secopsService.NarrateTimeline(r.Context(), tl); err != nil {
+			narrativeError = err.Error()
+			logger.WarnCF("debugui", "Timeline narrative failed", map[string]interface{}{"case": caseID, "error": err.Error()})
+		}
+	}
+
+	md := tl.Markdown()
+	if q.Get("format") == "markdown" {
+		name := "timeline"
+		if caseID != "" {
+			name += "-" + exportFileName(caseID)
+		}
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.md"`, name))
+		w.Write([]byte(md))
+		return
+	}
+
+	resp := map[string]interface{}{
+		"timeline": tl,
+		"markdown": md,
+		"html":     renderMarkdown(md),
+	}
+	if narrativeError != "" {
+		resp["narrativeError"] = narrativeError
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,55 @@
+package debugui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+func TestHandleTimeline(t *testing.T) {
+	ps := secops.NewProposalService()
+	p := secops.NewProposal("risk", "SQL 注入", "", nil)
+	p.CaseID = "case/1"
+	p.AddEvidence("访问日志", "2026-10-16T02:00:05Z GET /product?id=1' 500")
+	ps.Create(p)
+	s := NewServer(config.DebugUIConfig{}, nil, ps, nil, "")
+
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.handleTimeline(rec, httptest.NewRequest("GET", "/api/timeline?"+query, nil))
+		return rec
+	}
+
+	rec := get("case=case/1&narrate=1")
+	var resp struct {
+		Timeline       secops.Timeline `json:"timeline"`
+		Markdown       string          `json:"markdown"`
+		HTML           string          `json:"html"`
+		NarrativeError string          `json:"narrativeError"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("code=%d: %v", rec.Code, err)
+	}
+	if len(resp.Timeline.Entries) != 2 || !strings.Contains(resp.Markdown, "GET /product?id=1' 500") ||
+		!strings.Contains(resp.HTML, "<table>") || resp.NarrativeError == "" {
+		t.Errorf("response = %+v", resp)
+	}
+
+	rec = get("case=case/1&format=markdown")
+	if rec.Header().Get("Content-Disposition") != `attachment; filename="timeline-case-1.md"` ||
+		!strings.HasPrefix(rec.Body.String(), "# 事件时间线: case/1") {
+		t.Errorf("markdown download: %v\n%s", rec.Header(), rec.Body)
+	}
+
+	if rec = get(""); rec.Code != http.StatusBadRequest {
+		t.Errorf("no selection: code=%d", rec.Code)
+	}
+	if rec = get("ids=missing"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown proposal: code=%d", rec.Code)
+	}
+}
@@ -0,0 +1,138 @@
+package debugui
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// newTLSConfig 按配置创建 HTTPS 配置; 未配置证书时返回 nil, 以明文 HTTP 提供服务
+func newTLSConfig(cfg config.DebugUITLSConfig) (*tls.Config, error) {
+	if cfg.CertFile == "" && cfg.KeyFile == "" {
+		if cfg.ClientCAFile != "" {
+			return nil, fmt.Errorf("debugui.tls.client_ca_file requires cert_file and key_file")
+		}
+		return nil, nil
+	}
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, fmt.Errorf("debugui.tls: cert_file and key_file must be set together")
+	}
+
+	certs, err := newCertReloader(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	tc := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certs.getCertificate,
+	}
+	switch cfg.MinVersion {
+	case "", "1.2":
+	case "1.3":
+		tc.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("debugui.tls.min_version: unsupported version %q, expected \"1.2\" or \"1.3\"", cfg.MinVersion)
+	}
+
+	if cfg.ClientCAFile == "" {
+		if cfg.ClientAuth != "" {
+			return nil, fmt.Errorf("debugui.tls.client_auth requires client_ca_file")
+		}
+		return tc, nil
+	}
+	pem, err := os.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("debugui.tls.client_ca_file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("debugui.tls.client_ca_file: no PEM certificates in %s", cfg.ClientCAFile)
+	}
+	tc.ClientCAs = pool
+	switch strings.ToLower(cfg.ClientAuth) {
+	case "", "require":
+		tc.ClientAuth = tls.RequireAndVerifyClientCert
+	case "optional":
+		tc.ClientAuth = tls.VerifyClientCertIfGiven
+	default:
+		return nil, fmt.Errorf("debugui.tls.client_auth: unsupported mode %q, expected \"require\" or \"optional\"", cfg.ClientAuth)
+	}
+	return tc, nil
+}
+
+// certReloadInterval 检查证书文件是否更新的最小间隔
+const certReloadInterval = time.Minute
+
+// certReloader 服务端证书, 文件修改后在新的握手中重新加载, 证书轮换无需重启
+type certReloader struct {
+	certFile, keyFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) load() error {
+	info, err := os.Stat(r.certFile)
+	if err != nil {
+		return fmt.Errorf("debugui.tls.cert_file: %w", err)
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("debugui.tls: load key pair: %w", err)
+	}
+	r.cert = &cert
+	r.modTime = info.ModTime()
+	return nil
+}
+
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if now := time.Now(); now.Sub(r.checked) >= certReloadInterval {
+		r.checked = now
+		if info, err := os.Stat(r.certFile); err == nil && !info.ModTime().Equal(r.modTime) {
+			// 加载失败时继续使用旧证书, 避免证书和私钥未同时写完时中断服务
+			if err := r.load(); err != nil {
+				logger.WarnCF("debugui", "Failed to reload TLS certificate, keeping the previous one",
+					map[string]interface{}{"error": err.Error()})
+			} else {
+				logger.InfoCF("debugui", "TLS certificate reloaded",
+					map[string]interface{}{"cert_file": r.certFile})
+			}
+		}
+	}
+	return r.cert, nil
+}
+
+// clientCertName 双向 TLS 中已校验的客户端证书名称 (Common Name, 为空时取第一个 DNS SAN)
+func clientCertName(r *http.Request) string {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	leaf := r.TLS.VerifiedChains[0][0]
+	if leaf.Subject.CommonName != "" {
+		return leaf.Subject.CommonName
+	}
+	if len(leaf.DNSNames) > 0 {
+		return leaf.DNSNames[0]
+	}
+	return ""
+}
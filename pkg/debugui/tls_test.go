@@ -0,0 +1,185 @@
+package debugui
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// testCert 测试用证书; parent 为 nil 时自签名
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func newTestCert(t *testing.T, cn string, parent *testCert, isCA bool) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+// write 写入 PEM 证书和私钥, 返回文件路径
+func (c *testCert) write(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "soclaw test CA", nil, true)
+	caFile, _ := ca.write(t, dir, "ca")
+	certFile, keyFile := newTestCert(t, "127.0.0.1", ca, false).write(t, dir, "server")
+
+	if tc, err := newTLSConfig(config.DebugUITLSConfig{}); tc != nil || err != nil {
+		t.Errorf("empty config = %v, %v, want nil", tc, err)
+	}
+	tc, err := newTLSConfig(config.DebugUITLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile, ClientAuth: "optional", MinVersion: "1.3"})
+	if err != nil {
+		t.Fatalf("newTLSConfig: %v", err)
+	}
+	if tc.ClientAuth != tls.VerifyClientCertIfGiven || tc.MinVersion != tls.VersionTLS13 {
+		t.Errorf("client auth = %v, min version = %x", tc.ClientAuth, tc.MinVersion)
+	}
+
+	for _, bad := range []config.DebugUITLSConfig{
+		{CertFile: certFile},
+		{ClientCAFile: caFile},
+		{CertFile: certFile, KeyFile: caFile},
+		{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.1"},
+		{CertFile: certFile, KeyFile: keyFile, ClientAuth: "require"},
+		{CertFile: certFile, KeyFile: keyFile, ClientCAFile: keyFile},
+		{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile, ClientAuth: "always"},
+	} {
+		if _, err := newTLSConfig(bad); err == nil {
+			t.Errorf("newTLSConfig(%+v) expected error", bad)
+		}
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "soclaw test CA", nil, true)
+	caFile, _ := ca.write(t, dir, "ca")
+	certFile, keyFile := newTestCert(t, "127.0.0.1", ca, false).write(t, dir, "server")
+	tc, err := newTLSConfig(config.DebugUITLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// httptest.StartTLS 会填入自带的证书, 这里直接使用 http.Server 以验证 GetCertificate
+	s := NewServer(config.DebugUIConfig{}, nil, nil, nil, dir)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, s.requestActor(r).Name)
+		}),
+		TLSConfig: tc,
+		ErrorLog:  log.New(io.Discard, "", 0),
+	}
+	go srv.ServeTLS(ln, "", "")
+	defer srv.Close()
+	url := "https://" + ln.Addr().String()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	client := func(certs ...tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs}}}
+	}
+
+	if _, err := client().Get(url); err == nil {
+		t.Error("request without client certificate succeeded")
+	}
+	other := newTestCert(t, "intruder", nil, false)
+	if _, err := client(other.tlsCertificate()).Get(url); err == nil {
+		t.Error("request with untrusted client certificate succeeded")
+	}
+
+	resp, err := client(newTestCert(t, "analyst", ca, false).tlsCertificate()).Get(url)
+	if err != nil {
+		t.Fatalf("request with client certificate: %v", err)
+	}
+	defer resp.Body.Close()
+	if body, _ := io.ReadAll(resp.Body); string(body) != "cert:analyst" {
+		t.Errorf("actor = %q, want cert:analyst", body)
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := newTestCert(t, "old", nil, false).write(t, dir, "server")
+	r, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newTestCert(t, "new", nil, false).write(t, dir, "server")
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	r.checked = time.Time{}
+
+	cert, err := r.getCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leaf, _ := x509.ParseCertificate(cert.Certificate[0]); leaf.Subject.CommonName != "new" {
+		t.Errorf("certificate CN = %q after reload, want new", leaf.Subject.CommonName)
+	}
+}
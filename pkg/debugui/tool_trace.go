@@ -0,0 +1,90 @@
+package debugui
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/agent"
+)
+
+// traceSummary 工具调用记录列表项, 不含调用明细
+type traceSummary struct {
+	ID         string    `json:"id"`
+	SessionKey string    `json:"sessionKey"`
+	Channel    string    `json:"channel"`
+	Message    string    `json:"message"`
+	StartedAt  time.Time `json:"startedAt"`
+	DurationMs int64     `json:"durationMs"`
+	Done       bool      `json:"done"`
+	Error      string    `json:"error,omitempty"`
+	Calls      int       `json:"calls"`
+	Tools      []string  `json:"tools"` // 调用过的工具, 去重后按首次调用排序
+}
+
+// handleToolTraces GET /api/traces 列出最近请求的工具调用记录, 最新的在前;
+// ?session= 按会话键过滤 (如 debugui:debugui、heartbeat), 支持分页
+func (s *Server) handleToolTraces(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if s.agentLoop == nil {
+		s.writeList(w, []interface{}{}, 0, "")
+		return
+	}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	traces := s.agentLoop.Traces().List(r.URL.Query().Get("session"))
+	total := len(traces)
+	traces, nextCursor := paginate(traces, page)
+
+	items := make([]traceSummary, 0, len(traces))
+	for _, t := range traces {
+		items = append(items, summarizeTrace(t))
+	}
+	s.writeList(w, items, total, nextCursor)
+}
+
+// handleToolTrace GET /api/traces/{id} 单次请求的工具调用明细: 工具名、参数、截断后的结果和耗时。
+// 对话的 trace ID 随回复返回, 运营活动的 trace ID 为执行 ID
+func (s *Server) handleToolTrace(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if s.agentLoop == nil {
+		http.Error(w, "agent not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	trace, ok := s.agentLoop.Traces().Get(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "trace not found", http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(trace)
+}
+
+func summarizeTrace(t agent.Trace) traceSummary {
+	summary := traceSummary{
+		ID:         t.ID,
+		SessionKey: t.SessionKey,
+		Channel:    t.Channel,
+		Message:    t.Message,
+		StartedAt:  t.StartedAt,
+		DurationMs: t.DurationMs,
+		Done:       t.Done,
+		Error:      t.Error,
+		Calls:      len(t.Calls),
+		Tools:      []string{},
+	}
+	seen := make(map[string]bool)
+	for _, c := range t.Calls {
+		if !seen[c.Tool] {
+			seen[c.Tool] = true
+			summary.Tools = append(summary.Tools, c.Tool)
+		}
+	}
+	return summary
+}
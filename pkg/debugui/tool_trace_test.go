@@ -0,0 +1,71 @@
+package debugui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/agent"
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func TestToolTraces(t *testing.T) {
+	workspace := t.TempDir()
+	provider, err := providers.NewScriptedProvider(providers.ScriptedScript{
+		Rules: []providers.ScriptedRule{{
+			Match: "目录",
+			Steps: []providers.ScriptedStep{
+				{ToolCalls: []providers.ScriptedToolCall{{Name: "list_dir", Arguments: map[string]interface{}{"path": "."}}}},
+				{Content: "目录为空"},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	al := agent.NewAgentLoop(&config.Config{
+		Agents: config.AgentsConfig{Defaults: config.AgentDefaults{Workspace: workspace, Model: "test-model", MaxToolIterations: 5}},
+	}, bus.NewMessageBus(), provider)
+
+	s := NewServer(config.DebugUIConfig{}, al, nil, nil, workspace)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/chat", s.handleChat)
+	mux.HandleFunc("GET /api/traces", s.handleToolTraces)
+	mux.HandleFunc("GET /api/traces/{id}", s.handleToolTrace)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/chat", strings.NewReader(`{"message":"看看目录","session":"t"}`)))
+	var reply map[string]string
+	json.NewDecoder(rec.Body).Decode(&reply)
+	if reply["response"] != "目录为空" || reply["traceId"] == "" {
+		t.Fatalf("chat reply = %v", reply)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/traces?session=debugui:t", nil))
+	var list struct {
+		Items []traceSummary `json:"items"`
+	}
+	json.NewDecoder(rec.Body).Decode(&list)
+	if len(list.Items) != 1 || list.Items[0].ID != reply["traceId"] || list.Items[0].Calls != 1 || list.Items[0].Tools[0] != "list_dir" {
+		t.Fatalf("traces = %+v", list.Items)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/traces/"+reply["traceId"], nil))
+	var trace agent.Trace
+	json.NewDecoder(rec.Body).Decode(&trace)
+	if len(trace.Calls) != 1 || trace.Calls[0].Args != `{"path":"."}` || !trace.Done {
+		t.Errorf("trace = %+v", trace)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/traces/missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("missing trace status = %d, want 404", rec.Code)
+	}
+}
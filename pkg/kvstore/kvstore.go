@@ -0,0 +1,199 @@
+// Package kvstore provides a small key-value store for state that has to be
+// shared between instances in multi-instance deployments (login sessions,
+// counters, dedup markers). It is backed by Redis or by process memory.
+package kvstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// Backend names accepted in configuration.
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+// ErrNotFound is returned by Get when the key does not exist or has expired.
+var ErrNotFound = errors.New("key not found")
+
+// Store is a key-value store with per-key expiry. A ttl of 0 means the key
+// does not expire. Keys are relative to the store's configured prefix.
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// SetNX sets the key only if it does not exist and reports whether it was set.
+	SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	// Incr increments an integer counter; ttl is applied when the counter is created.
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	Delete(ctx context.Context, key string) error
+	// Keys lists the keys starting with prefix, in no particular order.
+	Keys(ctx context.Context, prefix string) ([]string, error)
+	// Backend reports the backend currently serving requests, e.g. "redis" or "memory".
+	Backend() string
+	Close() error
+}
+
+// New creates the store selected by configuration. A Redis store falls back
+// to process memory while Redis is unreachable, so an outage degrades
+// multi-instance consistency instead of failing requests. limit bounds the
+// in-process part of the store.
+func New(cfg config.CacheConfig, limit config.MemoryLimitConfig) (Store, error) {
+	mem := NewMemoryLimited(limit.MaxItems, int64(limit.MaxMB)<<20)
+	switch cfg.Backend {
+	case "", BackendMemory:
+		return mem, nil
+	case BackendRedis:
+		r, err := NewRedis(cfg.Redis)
+		if err != nil {
+			return nil, err
+		}
+		f := newFallback(r, mem)
+		ctx, cancel := context.WithTimeout(context.Background(), r.dialTimeout)
+		defer cancel()
+		if err := r.Ping(ctx); err != nil {
+			f.markDown(err)
+		}
+		return f, nil
+	default:
+		return nil, fmt.Errorf("cache.backend: unknown backend %q", cfg.Backend)
+	}
+}
+
+// fallbackRetry is how long the primary is bypassed after a connection error.
+const fallbackRetry = 10 * time.Second
+
+// fallback serves from the primary store and switches to the secondary while
+// the primary has connection problems. Server-side errors (e.g. wrong type)
+// are returned as is.
+type fallback struct {
+	primary   *Redis
+	secondary Store
+
+	mu        sync.Mutex
+	downUntil time.Time
+	now       func() time.Time
+}
+
+func newFallback(primary *Redis, secondary Store) *fallback {
+	return &fallback{primary: primary, secondary: secondary, now: time.Now}
+}
+
+// active returns the store to use for the next request.
+func (f *fallback) active() Store {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.now().Before(f.downUntil) {
+		return f.secondary
+	}
+	return f.primary
+}
+
+// markDown bypasses the primary for fallbackRetry; it logs only on transitions.
+func (f *fallback) markDown(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.downUntil.IsZero() {
+		logger.WarnCF("kvstore", "Redis unavailable, falling back to in-memory store",
+			map[string]interface{}{
+				"error": err.Error(),
+			})
+	}
+	f.downUntil = f.now().Add(fallbackRetry)
+}
+
+// markUp records a successful primary request.
+func (f *fallback) markUp() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.downUntil.IsZero() {
+		logger.InfoC("kvstore", "Redis connection restored")
+		f.downUntil = time.Time{}
+	}
+}
+
+// call runs op on the active store, retrying on the secondary if the primary
+// fails with a connection error.
+func (f *fallback) call(op func(Store) error) error {
+	s := f.active()
+	err := op(s)
+	if s != Store(f.primary) {
+		return err
+	}
+	if err != nil && isConnError(err) {
+		f.markDown(err)
+		return op(f.secondary)
+	}
+	f.markUp()
+	return err
+}
+
+func (f *fallback) Get(ctx context.Context, key string) (v []byte, err error) {
+	err = f.call(func(s Store) error { v, err = s.Get(ctx, key); return err })
+	return v, err
+}
+
+func (f *fallback) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return f.call(func(s Store) error { return s.Set(ctx, key, value, ttl) })
+}
+
+func (f *fallback) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (ok bool, err error) {
+	err = f.call(func(s Store) error { ok, err = s.SetNX(ctx, key, value, ttl); return err })
+	return ok, err
+}
+
+func (f *fallback) Incr(ctx context.Context, key string, ttl time.Duration) (n int64, err error) {
+	err = f.call(func(s Store) error { n, err = s.Incr(ctx, key, ttl); return err })
+	return n, err
+}
+
+func (f *fallback) Delete(ctx context.Context, key string) error {
+	return f.call(func(s Store) error { return s.Delete(ctx, key) })
+}
+
+func (f *fallback) Keys(ctx context.Context, prefix string) (keys []string, err error) {
+	err = f.call(func(s Store) error { keys, err = s.Keys(ctx, prefix); return err })
+	return keys, err
+}
+
+func (f *fallback) Backend() string {
+	return f.active().Backend()
+}
+
+func (f *fallback) Close() error {
+	return f.primary.Close()
+}
+
+// MemoryUsage reports the footprint of the in-process part of s: the store
+// itself for the memory backend, the fallback store for Redis.
+func MemoryUsage(s Store) (Usage, bool) {
+	if f, ok := s.(*fallback); ok {
+		s = f.secondary
+	}
+	m, ok := s.(*Memory)
+	if !ok {
+		return Usage{}, false
+	}
+	return m.Usage(), true
+}
+
+// Ping checks the connection of a Redis-backed store; for a memory store it
+// always succeeds. A successful ping switches a fallback store back to Redis.
+func Ping(ctx context.Context, s Store) error {
+	f, ok := s.(*fallback)
+	if !ok {
+		return nil
+	}
+	if err := f.primary.Ping(ctx); err != nil {
+		f.markDown(err)
+		return err
+	}
+	f.markUp()
+	return nil
+}
@@ -0,0 +1,201 @@
+package kvstore
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memory is an in-process store. Expired keys are removed lazily on access
+// and when listing keys. With limits set, the least recently used keys are
+// evicted once the store holds more than maxItems keys or maxBytes bytes.
+type Memory struct {
+	mu       sync.Mutex
+	items    map[string]*list.Element
+	order    *list.List // of *memoryItem, most recently used first
+	bytes    int64
+	maxItems int
+	maxBytes int64
+	evicted  uint64
+	now      func() time.Time
+}
+
+type memoryItem struct {
+	key     string
+	value   []byte
+	expires time.Time // zero means no expiry
+}
+
+func (it *memoryItem) size() int64 {
+	return int64(len(it.key) + len(it.value))
+}
+
+// Usage is the memory footprint of an in-process store. Bytes counts keys
+// and values only.
+type Usage struct {
+	Items    int
+	Bytes    int64
+	MaxItems int
+	MaxBytes int64
+	Evicted  uint64 // keys evicted to stay within the limits since start
+}
+
+// NewMemory creates an empty in-memory store without limits.
+func NewMemory() *Memory {
+	return NewMemoryLimited(0, 0)
+}
+
+// NewMemoryLimited creates an in-memory store holding at most maxItems keys
+// and maxBytes bytes; 0 means unlimited.
+func NewMemoryLimited(maxItems int, maxBytes int64) *Memory {
+	return &Memory{
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+		maxItems: maxItems,
+		maxBytes: maxBytes,
+		now:      time.Now,
+	}
+}
+
+// getLocked returns the live item for key and marks it as recently used,
+// deleting it if it has expired.
+func (m *Memory) getLocked(key string) (*memoryItem, bool) {
+	e, ok := m.items[key]
+	if !ok {
+		return nil, false
+	}
+	item := e.Value.(*memoryItem)
+	if !item.expires.IsZero() && !m.now().Before(item.expires) {
+		m.removeLocked(e)
+		return nil, false
+	}
+	m.order.MoveToFront(e)
+	return item, true
+}
+
+func (m *Memory) setLocked(key string, value []byte, ttl time.Duration) {
+	if e, ok := m.items[key]; ok {
+		m.removeLocked(e)
+	}
+	item := &memoryItem{key: key, value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		item.expires = m.now().Add(ttl)
+	}
+	m.items[key] = m.order.PushFront(item)
+	m.bytes += item.size()
+	m.evictLocked()
+}
+
+func (m *Memory) removeLocked(e *list.Element) {
+	item := m.order.Remove(e).(*memoryItem)
+	delete(m.items, item.key)
+	m.bytes -= item.size()
+}
+
+// evictLocked drops least recently used keys until the store is within its
+// limits. The most recently written key is always kept.
+func (m *Memory) evictLocked() {
+	for m.order.Len() > 1 &&
+		((m.maxItems > 0 && m.order.Len() > m.maxItems) || (m.maxBytes > 0 && m.bytes > m.maxBytes)) {
+		m.removeLocked(m.order.Back())
+		m.evicted++
+	}
+}
+
+func (m *Memory) Get(_ context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.getLocked(key)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), item.value...), nil
+}
+
+func (m *Memory) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.setLocked(key, value, ttl)
+	return nil
+}
+
+func (m *Memory) SetNX(_ context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.getLocked(key); ok {
+		return false, nil
+	}
+	m.setLocked(key, value, ttl)
+	return true, nil
+}
+
+func (m *Memory) Incr(_ context.Context, key string, ttl time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.getLocked(key)
+	if !ok {
+		m.setLocked(key, []byte("1"), ttl)
+		return 1, nil
+	}
+	n, err := strconv.ParseInt(string(item.value), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	n++
+	m.bytes -= item.size()
+	item.value = []byte(strconv.FormatInt(n, 10))
+	m.bytes += item.size()
+	m.evictLocked()
+	return n, nil
+}
+
+func (m *Memory) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.items[key]; ok {
+		m.removeLocked(e)
+	}
+	return nil
+}
+
+func (m *Memory) Keys(_ context.Context, prefix string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	keys := make([]string, 0)
+	for e := m.order.Front(); e != nil; {
+		next := e.Next()
+		item := e.Value.(*memoryItem)
+		if !item.expires.IsZero() && !now.Before(item.expires) {
+			m.removeLocked(e)
+		} else if strings.HasPrefix(item.key, prefix) {
+			keys = append(keys, item.key)
+		}
+		e = next
+	}
+	return keys, nil
+}
+
+// Usage reports the current footprint of the store.
+func (m *Memory) Usage() Usage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return Usage{
+		Items:    m.order.Len(),
+		Bytes:    m.bytes,
+		MaxItems: m.maxItems,
+		MaxBytes: m.maxBytes,
+		Evicted:  m.evicted,
+	}
+}
+
+func (m *Memory) Backend() string {
+	return BackendMemory
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
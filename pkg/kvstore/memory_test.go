@@ -0,0 +1,73 @@
+package kvstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryExpiry(t *testing.T) {
+	m := NewMemory()
+	now := time.Now()
+	m.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	m.Set(ctx, "a", []byte("1"), time.Minute)
+	m.Set(ctx, "b", []byte("2"), 0)
+	if n, _ := m.Incr(ctx, "c", time.Second); n != 1 {
+		t.Errorf("Incr = %d", n)
+	}
+	if keys, _ := m.Keys(ctx, ""); len(keys) != 3 {
+		t.Errorf("Keys = %v", keys)
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := m.Get(ctx, "a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expired key: %v", err)
+	}
+	if keys, _ := m.Keys(ctx, ""); len(keys) != 1 || keys[0] != "b" {
+		t.Errorf("Keys after expiry = %v", keys)
+	}
+	if ok, _ := m.SetNX(ctx, "a", []byte("3"), 0); !ok {
+		t.Error("SetNX should succeed on an expired key")
+	}
+	if ok, _ := m.SetNX(ctx, "a", []byte("4"), 0); ok {
+		t.Error("SetNX should fail on an existing key")
+	}
+	if n, _ := m.Incr(ctx, "c", time.Second); n != 1 {
+		t.Errorf("Incr after expiry = %d, want a fresh counter", n)
+	}
+}
+
+func TestMemoryLRUEviction(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryLimited(2, 0)
+	m.Set(ctx, "a", []byte("1"), 0)
+	m.Set(ctx, "b", []byte("2"), 0)
+	m.Get(ctx, "a") // a is now more recently used than b
+	m.Set(ctx, "c", []byte("3"), 0)
+
+	if _, err := m.Get(ctx, "b"); !errors.Is(err, ErrNotFound) {
+		t.Error("least recently used key was not evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, err := m.Get(ctx, key); err != nil {
+			t.Errorf("%s: %v", key, err)
+		}
+	}
+	if u := m.Usage(); u.Items != 2 || u.Bytes != 4 || u.Evicted != 1 {
+		t.Errorf("usage = %+v", u)
+	}
+
+	m = NewMemoryLimited(0, 10)
+	m.Set(ctx, "k1", []byte("12345"), 0)
+	m.Set(ctx, "k2", []byte("12345"), 0)
+	if u := m.Usage(); u.Items != 1 || u.Bytes != 7 {
+		t.Errorf("byte limit: usage = %+v", u)
+	}
+	m.Set(ctx, "big", []byte("0123456789abcdef"), 0)
+	if _, err := m.Get(ctx, "big"); err != nil {
+		t.Error("the latest key is kept even if it alone exceeds the limit")
+	}
+}
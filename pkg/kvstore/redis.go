@@ -0,0 +1,356 @@
+package kvstore
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+const (
+	defaultRedisPrefix      = "soclaw:"
+	defaultRedisDialTimeout = 3 * time.Second
+	defaultRedisPoolSize    = 8
+)
+
+// Redis is a minimal RESP2 client covering the commands the Store interface
+// needs. Connections are pooled; a connection that fails is discarded.
+type Redis struct {
+	addr        string
+	tlsConfig   *tls.Config
+	username    string
+	password    string
+	db          int
+	prefix      string
+	dialTimeout time.Duration
+	pool        chan *redisConn
+}
+
+// redisError is an error reply from the server (e.g. WRONGTYPE). The
+// connection is still usable after it.
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+// isConnError reports whether err is a transport problem rather than an
+// answer from the server.
+func isConnError(err error) bool {
+	var re redisError
+	return !errors.As(err, &re) && !errors.Is(err, ErrNotFound)
+}
+
+// NewRedis creates a Redis store from configuration. It does not connect.
+func NewRedis(cfg config.RedisConfig) (*Redis, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("cache.redis.url is required")
+	}
+	u, err := url.Parse(cfg.URL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid redis url: %q", cfg.URL)
+	}
+	r := &Redis{
+		prefix:      defaultRedisPrefix,
+		dialTimeout: defaultRedisDialTimeout,
+	}
+	switch u.Scheme {
+	case "redis":
+	case "rediss":
+		r.tlsConfig = &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}
+	default:
+		return nil, fmt.Errorf("redis url must be redis:// or rediss://: %q", cfg.URL)
+	}
+	r.addr = u.Host
+	if u.Port() == "" {
+		r.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		r.username = u.User.Username()
+		r.password, _ = u.User.Password()
+	}
+	if cfg.Password != "" {
+		r.password = cfg.Password
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if r.db, err = strconv.Atoi(db); err != nil || r.db < 0 {
+			return nil, fmt.Errorf("invalid redis database: %q", db)
+		}
+	}
+	if cfg.Prefix != "" {
+		r.prefix = cfg.Prefix
+	}
+	if cfg.DialTimeout != "" {
+		d, err := time.ParseDuration(cfg.DialTimeout)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("cache.redis.dial_timeout: invalid duration %q", cfg.DialTimeout)
+		}
+		r.dialTimeout = d
+	}
+	size := cfg.PoolSize
+	if size <= 0 {
+		size = defaultRedisPoolSize
+	}
+	r.pool = make(chan *redisConn, size)
+	return r, nil
+}
+
+type redisConn struct {
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// dial opens a connection and authenticates/selects the database.
+func (r *Redis) dial(ctx context.Context) (*redisConn, error) {
+	d := net.Dialer{Timeout: r.dialTimeout}
+	var conn net.Conn
+	var err error
+	if r.tlsConfig != nil {
+		conn, err = (&tls.Dialer{NetDialer: &d, Config: r.tlsConfig}).DialContext(ctx, "tcp", r.addr)
+	} else {
+		conn, err = d.DialContext(ctx, "tcp", r.addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &redisConn{conn: conn, rd: bufio.NewReader(conn)}
+
+	var setup [][]string
+	if r.password != "" {
+		if r.username != "" {
+			setup = append(setup, []string{"AUTH", r.username, r.password})
+		} else {
+			setup = append(setup, []string{"AUTH", r.password})
+		}
+	}
+	if r.db != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(r.db)})
+	}
+	for _, args := range setup {
+		if _, err := c.do(ctx, r.dialTimeout, args); err != nil {
+			conn.Close()
+			// Not wrapped: an auth failure makes the server unusable, so it
+			// counts as a connection error and triggers the fallback.
+			return nil, fmt.Errorf("redis %s: %s", strings.ToLower(args[0]), err.Error())
+		}
+	}
+	return c, nil
+}
+
+// do sends one command on the pooled connection and reads its reply.
+func (r *Redis) do(ctx context.Context, args ...string) (interface{}, error) {
+	var c *redisConn
+	select {
+	case c = <-r.pool:
+	default:
+		var err error
+		if c, err = r.dial(ctx); err != nil {
+			return nil, err
+		}
+	}
+	reply, err := c.do(ctx, r.dialTimeout, args)
+	if err != nil && isConnError(err) {
+		c.conn.Close()
+		return nil, err
+	}
+	select {
+	case r.pool <- c:
+	default:
+		c.conn.Close()
+	}
+	return reply, err
+}
+
+func (c *redisConn) do(ctx context.Context, timeout time.Duration, args []string) (interface{}, error) {
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	c.conn.SetDeadline(deadline)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return nil, err
+	}
+	return readReply(c.rd)
+}
+
+// readReply parses one RESP2 reply: simple strings are returned as string,
+// integers as int64, bulk strings as []byte (nil for null) and arrays as
+// []interface{}.
+func readReply(rd *bufio.Reader) (interface{}, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, redisError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed bulk length %q", body)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed array length %q", body)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = readReply(rd); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
+
+// Ping checks that the server is reachable.
+func (r *Redis) Ping(ctx context.Context) error {
+	_, err := r.do(ctx, "PING")
+	return err
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, error) {
+	reply, err := r.do(ctx, "GET", r.prefix+key)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := reply.([]byte)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return v, nil
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, err := r.do(ctx, r.setArgs(key, value, ttl)...)
+	return err
+}
+
+func (r *Redis) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	reply, err := r.do(ctx, append(r.setArgs(key, value, ttl), "NX")...)
+	if err != nil {
+		return false, err
+	}
+	return reply != nil, nil
+}
+
+func (r *Redis) setArgs(key string, value []byte, ttl time.Duration) []string {
+	args := []string{"SET", r.prefix + key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttlMillis(ttl), 10))
+	}
+	return args
+}
+
+func (r *Redis) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	reply, err := r.do(ctx, "INCR", r.prefix+key)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := reply.(int64)
+	if n == 1 && ttl > 0 {
+		if _, err := r.do(ctx, "PEXPIRE", r.prefix+key, strconv.FormatInt(ttlMillis(ttl), 10)); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+func (r *Redis) Delete(ctx context.Context, key string) error {
+	_, err := r.do(ctx, "DEL", r.prefix+key)
+	return err
+}
+
+func (r *Redis) Keys(ctx context.Context, prefix string) ([]string, error) {
+	pattern := globEscape(r.prefix+prefix) + "*"
+	keys := make([]string, 0)
+	cursor := "0"
+	for {
+		reply, err := r.do(ctx, "SCAN", cursor, "MATCH", pattern, "COUNT", "100")
+		if err != nil {
+			return nil, err
+		}
+		parts, ok := reply.([]interface{})
+		if !ok || len(parts) != 2 {
+			return nil, fmt.Errorf("redis: unexpected SCAN reply")
+		}
+		next, _ := parts[0].([]byte)
+		batch, _ := parts[1].([]interface{})
+		for _, k := range batch {
+			if b, ok := k.([]byte); ok {
+				keys = append(keys, strings.TrimPrefix(string(b), r.prefix))
+			}
+		}
+		if cursor = string(next); cursor == "0" || cursor == "" {
+			return keys, nil
+		}
+	}
+}
+
+func (r *Redis) Backend() string {
+	return BackendRedis
+}
+
+// Close closes the idle connections.
+func (r *Redis) Close() error {
+	for {
+		select {
+		case c := <-r.pool:
+			c.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+// ttlMillis rounds up so that sub-millisecond TTLs do not become "no expiry".
+func ttlMillis(ttl time.Duration) int64 {
+	return int64((ttl + time.Millisecond - 1) / time.Millisecond)
+}
+
+// globEscape escapes the glob metacharacters recognised by SCAN MATCH.
+func globEscape(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune(`*?[]\`, c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,321 @@
+package kvstore
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// fakeRedis is an in-process RESP server backed by a Memory store, enough
+// to exercise the client's commands.
+type fakeRedis struct {
+	ln       net.Listener
+	data     *Memory
+	password string
+
+	mu       sync.Mutex
+	commands []string
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRedis{ln: ln, data: NewMemory(), password: password}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return f
+}
+
+func (f *fakeRedis) url() string { return "redis://" + f.ln.Addr().String() + "/2" }
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		reply, err := readReply(rd)
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, a := range reply.([]interface{}) {
+			args = append(args, string(a.([]byte)))
+		}
+		f.mu.Lock()
+		f.commands = append(f.commands, strings.Join(args, " "))
+		f.mu.Unlock()
+		if !authed && args[0] != "AUTH" {
+			io.WriteString(conn, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+		io.WriteString(conn, f.exec(args, &authed))
+	}
+}
+
+func (f *fakeRedis) exec(args []string, authed *bool) string {
+	ctx := context.Background()
+	bulk := func(v []byte) string { return fmt.Sprintf("$%d\r\n%s\r\n", len(v), v) }
+	switch args[0] {
+	case "PING":
+		return "+PONG\r\n"
+	case "AUTH":
+		if args[len(args)-1] != f.password {
+			return "-WRONGPASS invalid username-password pair\r\n"
+		}
+		*authed = true
+		return "+OK\r\n"
+	case "SELECT":
+		return "+OK\r\n"
+	case "GET":
+		v, err := f.data.Get(ctx, args[1])
+		if err != nil {
+			return "$-1\r\n"
+		}
+		return bulk(v)
+	case "SET":
+		var ttl time.Duration
+		nx := false
+		for i := 3; i < len(args); i++ {
+			switch args[i] {
+			case "PX":
+				ms, _ := strconv.Atoi(args[i+1])
+				ttl = time.Duration(ms) * time.Millisecond
+				i++
+			case "NX":
+				nx = true
+			}
+		}
+		if nx {
+			if ok, _ := f.data.SetNX(ctx, args[1], []byte(args[2]), ttl); !ok {
+				return "$-1\r\n"
+			}
+			return "+OK\r\n"
+		}
+		f.data.Set(ctx, args[1], []byte(args[2]), ttl)
+		return "+OK\r\n"
+	case "INCR":
+		n, err := f.data.Incr(ctx, args[1], 0)
+		if err != nil {
+			return "-ERR value is not an integer or out of range\r\n"
+		}
+		return ":" + strconv.FormatInt(n, 10) + "\r\n"
+	case "PEXPIRE":
+		v, err := f.data.Get(ctx, args[1])
+		if err != nil {
+			return ":0\r\n"
+		}
+		ms, _ := strconv.Atoi(args[2])
+		f.data.Set(ctx, args[1], v, time.Duration(ms)*time.Millisecond)
+		return ":1\r\n"
+	case "DEL":
+		f.data.Delete(ctx, args[1])
+		return ":1\r\n"
+	case "SCAN":
+		keys, _ := f.data.Keys(ctx, "")
+		var b strings.Builder
+		n := 0
+		for _, k := range keys {
+			if ok, _ := path.Match(args[3], k); ok {
+				b.WriteString(bulk([]byte(k)))
+				n++
+			}
+		}
+		return fmt.Sprintf("*2\r\n$1\r\n0\r\n*%d\r\n%s", n, b.String())
+	}
+	return "-ERR unknown command '" + args[0] + "'\r\n"
+}
+
+func (f *fakeRedis) log() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.commands...)
+}
+
+func TestRedisStore(t *testing.T) {
+	srv := newFakeRedis(t, "s3cret")
+	r, err := NewRedis(config.RedisConfig{URL: srv.url(), Password: "s3cret", Prefix: "t:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	ctx := context.Background()
+
+	if err := r.Ping(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(missing) = %v", err)
+	}
+	if err := r.Set(ctx, "session:a", []byte("hello world"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := r.Get(ctx, "session:a"); err != nil || string(v) != "hello world" {
+		t.Errorf("Get = %q, %v", v, err)
+	}
+	if ok, err := r.SetNX(ctx, "session:a", []byte("x"), 0); err != nil || ok {
+		t.Errorf("SetNX(existing) = %v, %v", ok, err)
+	}
+	if ok, err := r.SetNX(ctx, "session:b", []byte("x"), 0); err != nil || !ok {
+		t.Errorf("SetNX(new) = %v, %v", ok, err)
+	}
+	r.Set(ctx, "other", []byte("1"), 0)
+	keys, err := r.Keys(ctx, "session:")
+	if err != nil || len(keys) != 2 {
+		t.Errorf("Keys = %v, %v", keys, err)
+	}
+	for _, k := range keys {
+		if !strings.HasPrefix(k, "session:") {
+			t.Errorf("key %q should be returned without the store prefix", k)
+		}
+	}
+
+	for want := int64(1); want <= 2; want++ {
+		if n, err := r.Incr(ctx, "hits", time.Second); err != nil || n != want {
+			t.Errorf("Incr = %d, %v; want %d", n, err, want)
+		}
+	}
+	if _, err := r.Incr(ctx, "session:a", 0); err == nil || isConnError(err) {
+		t.Errorf("Incr on non-integer should be a server error, got %v", err)
+	}
+	if err := r.Delete(ctx, "session:a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Get(ctx, "session:a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete = %v", err)
+	}
+
+	log := srv.log()
+	want := []string{"AUTH s3cret", "SELECT 2", "PING", "GET t:missing", "SET t:session:a hello world PX 60000"}
+	for i, w := range want {
+		if i >= len(log) || log[i] != w {
+			t.Fatalf("commands = %q, want prefix %q", log, want)
+		}
+	}
+	if !containsString(log, "PEXPIRE t:hits 1000") || containsString(log, "PEXPIRE t:hits 2000") {
+		t.Errorf("ttl should be applied once when the counter is created: %q", log)
+	}
+	if !containsString(log, "SCAN 0 MATCH t:session:* COUNT 100") {
+		t.Errorf("missing SCAN: %q", log)
+	}
+}
+
+func TestRedisAuthFailure(t *testing.T) {
+	srv := newFakeRedis(t, "s3cret")
+	r, err := NewRedis(config.RedisConfig{URL: srv.url(), Password: "wrong"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Ping(context.Background()); err == nil || !isConnError(err) {
+		t.Errorf("auth failure should be a connection error, got %v", err)
+	}
+}
+
+func TestNewRedisConfig(t *testing.T) {
+	r, err := NewRedis(config.RedisConfig{URL: "rediss://user:pw@cache.internal"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.addr != "cache.internal:6379" || r.tlsConfig == nil || r.username != "user" || r.password != "pw" ||
+		r.prefix != defaultRedisPrefix || r.dialTimeout != defaultRedisDialTimeout {
+		t.Errorf("unexpected client: %+v", r)
+	}
+	for _, cfg := range []config.RedisConfig{
+		{},
+		{URL: "http://cache:6379"},
+		{URL: "redis://cache:6379/x"},
+		{URL: "redis://cache:6379", DialTimeout: "soon"},
+	} {
+		if _, err := NewRedis(cfg); err == nil {
+			t.Errorf("NewRedis(%+v): expected error", cfg)
+		}
+	}
+	if _, err := New(config.CacheConfig{Backend: "memcached"}, config.MemoryLimitConfig{}); err == nil {
+		t.Error("expected error for unknown backend")
+	}
+}
+
+func TestFallback(t *testing.T) {
+	srv := newFakeRedis(t, "")
+	ctx := context.Background()
+	s, err := New(config.CacheConfig{Backend: BackendRedis, Redis: config.RedisConfig{URL: srv.url(), DialTimeout: "1s"}}, config.MemoryLimitConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := s.(*fallback)
+	now := time.Now()
+	f.now = func() time.Time { return now }
+	if s.Backend() != BackendRedis {
+		t.Fatalf("backend = %s", s.Backend())
+	}
+	s.Set(ctx, "k", []byte("redis"), 0)
+
+	// While Redis is down requests go to memory without returning errors.
+	srv.ln.Close()
+	f.primary.Close()
+	if err := s.Set(ctx, "k", []byte("memory"), 0); err != nil {
+		t.Fatalf("Set during outage: %v", err)
+	}
+	if s.Backend() != BackendMemory {
+		t.Errorf("backend during outage = %s", s.Backend())
+	}
+	if v, err := s.Get(ctx, "k"); err != nil || string(v) != "memory" {
+		t.Errorf("Get during outage = %q, %v", v, err)
+	}
+
+	// Redis is tried again after the retry interval.
+	now = now.Add(fallbackRetry)
+	if s.Backend() != BackendRedis {
+		t.Errorf("backend after retry interval = %s", s.Backend())
+	}
+}
+
+func TestFallbackUnreachableAtStartup(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	s, err := New(config.CacheConfig{Backend: BackendRedis, Redis: config.RedisConfig{URL: "redis://" + addr, DialTimeout: "200ms"}}, config.MemoryLimitConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Backend() != BackendMemory {
+		t.Errorf("backend = %s, want memory while redis is unreachable", s.Backend())
+	}
+	if n, err := s.Incr(context.Background(), "c", 0); err != nil || n != 1 {
+		t.Errorf("Incr = %d, %v", n, err)
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
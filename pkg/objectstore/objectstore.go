@@ -0,0 +1,22 @@
+// Package objectstore provides a minimal S3-compatible object storage client
+// (AWS S3, MinIO, Ceph RGW, ...) used for archives and large artifacts.
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when the requested object does not exist.
+var ErrNotFound = errors.New("object not found")
+
+// Store is an object storage backend. Keys are relative to the store's
+// configured prefix.
+type Store interface {
+	Put(ctx context.Context, key string, body io.ReadSeeker, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// Location returns a human readable reference to the object, e.g. s3://bucket/prefix/key.
+	Location(key string) string
+}
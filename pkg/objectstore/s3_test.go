@@ -0,0 +1,121 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// fakeS3 is an in-memory path-style S3 endpoint that records request headers.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	auth    []string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.auth = append(f.auth, r.Header.Get("Authorization"))
+
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.EscapedPath()] = body
+	case http.MethodGet:
+		body, ok := f.objects[r.URL.EscapedPath()]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Write(body)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.EscapedPath())
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func newTestStore(t *testing.T) (*S3Store, *fakeS3) {
+	t.Helper()
+	fake := &fakeS3{objects: make(map[string][]byte)}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	store, err := NewS3Store(config.ObjectStoreConfig{
+		Endpoint:  srv.URL,
+		Bucket:    "soc",
+		AccessKey: "AKIDEXAMPLE",
+		SecretKey: "secret",
+		Prefix:    "/prod/",
+		PathStyle: true,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Store failed: %v", err)
+	}
+	store.now = func() time.Time { return time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC) }
+	return store, fake
+}
+
+func TestS3Store_PutGetDelete(t *testing.T) {
+	store, fake := newTestStore(t)
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "archive/2026-10-16/risk report.tar.gz", bytes.NewReader([]byte("data")), "application/gzip"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if _, ok := fake.objects["/soc/prod/archive/2026-10-16/risk%20report.tar.gz"]; !ok {
+		t.Fatalf("object stored under unexpected key: %v", fake.objects)
+	}
+
+	rc, err := store.Get(ctx, "archive/2026-10-16/risk report.tar.gz")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	got, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(got) != "data" {
+		t.Errorf("Get returned %q", got)
+	}
+
+	if err := store.Delete(ctx, "archive/2026-10-16/risk report.tar.gz"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.Get(ctx, "archive/2026-10-16/risk report.tar.gz"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20261016/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature="
+	for _, auth := range fake.auth {
+		if !strings.HasPrefix(auth, want) {
+			t.Errorf("unexpected Authorization header: %s", auth)
+		}
+	}
+}
+
+func TestS3Store_Location(t *testing.T) {
+	store, _ := newTestStore(t)
+	if got := store.Location("archive/a.tar.gz"); got != "s3://soc/prod/archive/a.tar.gz" {
+		t.Errorf("Location = %s", got)
+	}
+}
+
+func TestNewS3Store_InvalidConfig(t *testing.T) {
+	for _, cfg := range []config.ObjectStoreConfig{
+		{Bucket: "soc"},
+		{Endpoint: "minio:9000", Bucket: "soc"},
+		{Endpoint: "ftp://minio", Bucket: "soc"},
+	} {
+		if _, err := NewS3Store(cfg); err == nil {
+			t.Errorf("expected error for %+v", cfg)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package providers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxStreamToolCalls bounds the tool call index accepted from a stream, so a
+// malformed chunk cannot make parseStream allocate an arbitrarily large slice.
+const maxStreamToolCalls = 128
+
+// streamChunk is one "data:" event of an OpenAI-compatible streaming response.
+type streamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content   string `json:"content"`
+			ToolCalls []struct {
+				Index    int    `json:"index"`
+				ID       string `json:"id"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *UsageInfo `json:"usage"`
+}
+
+// parseStream reads server-sent events until [DONE] or EOF, forwarding content
+// deltas to onDelta and reassembling tool calls from their fragments.
+func parseStream(r io.Reader, onDelta func(string)) (*LLMResponse, error) {
+	type partialCall struct {
+		id        string
+		name      string
+		arguments strings.Builder
+	}
+
+	var content strings.Builder
+	var calls []*partialCall
+	response := &LLMResponse{FinishReason: "stop"}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk streamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal stream chunk: %w", err)
+		}
+		if chunk.Usage != nil {
+			response.Usage = chunk.Usage
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+
+		choice := chunk.Choices[0]
+		if choice.FinishReason != "" {
+			response.FinishReason = choice.FinishReason
+		}
+		if delta := choice.Delta.Content; delta != "" {
+			content.WriteString(delta)
+			if onDelta != nil {
+				onDelta(delta)
+			}
+		}
+		for _, tc := range choice.Delta.ToolCalls {
+			if tc.Index < 0 || tc.Index >= maxStreamToolCalls {
+				return nil, fmt.Errorf("invalid tool call index %d in stream chunk", tc.Index)
+			}
+			for len(calls) <= tc.Index {
+				calls = append(calls, &partialCall{})
+			}
+			call := calls[tc.Index]
+			if tc.ID != "" {
+				call.id = tc.ID
+			}
+			if tc.Function.Name != "" {
+				call.name = tc.Function.Name
+			}
+			call.arguments.WriteString(tc.Function.Arguments)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	response.Content = content.String()
+	for _, call := range calls {
+		if call.name == "" {
+			continue
+		}
+		arguments := make(map[string]interface{})
+		if raw := call.arguments.String(); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &arguments); err != nil {
+				arguments["raw"] = raw
+			}
+		}
+		response.ToolCalls = append(response.ToolCalls, ToolCall{
+			ID:        call.id,
+			Name:      call.name,
+			Arguments: arguments,
+		})
+	}
+	return response, nil
+}
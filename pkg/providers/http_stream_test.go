@@ -0,0 +1,78 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPProviderChatStream(t *testing.T) {
+	chunks := []string{
+		`{"choices":[{"delta":{"content":"Hel"}}]}`,
+		`{"choices":[{"delta":{"content":"lo"}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"exec","arguments":"{\"cmd\":"}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"ls\"}"}}]}}]}`,
+		`{"choices":[{"delta":{},"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`,
+	}
+	var gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, c := range chunks {
+			fmt.Fprintf(w, "data: %s\n\n", c)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	p := NewHTTPProvider("key", srv.URL, "")
+	var deltas []string
+	resp, err := p.ChatStream(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, "m", nil, func(d string) {
+		deltas = append(deltas, d)
+	})
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+	if !strings.Contains(gotBody, `"stream":true`) {
+		t.Errorf("request body %s missing stream flag", gotBody)
+	}
+	if strings.Join(deltas, "|") != "Hel|lo" || resp.Content != "Hello" {
+		t.Errorf("deltas = %v, content = %q", deltas, resp.Content)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Name != "exec" || resp.ToolCalls[0].Arguments["cmd"] != "ls" {
+		t.Errorf("tool calls = %+v", resp.ToolCalls)
+	}
+	if resp.FinishReason != "tool_calls" || resp.Usage == nil || resp.Usage.TotalTokens != 5 {
+		t.Errorf("finish = %q, usage = %+v", resp.FinishReason, resp.Usage)
+	}
+}
+
+func TestHTTPProviderChatStreamPlainFallback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[{"message":{"content":"whole"},"finish_reason":"stop"}]}`)
+	}))
+	defer srv.Close()
+
+	var deltas []string
+	resp, err := NewHTTPProvider("", srv.URL, "").ChatStream(context.Background(), nil, nil, "m", nil, func(d string) {
+		deltas = append(deltas, d)
+	})
+	if err != nil || resp.Content != "whole" || len(deltas) != 1 {
+		t.Errorf("resp = %+v, deltas = %v, err = %v", resp, deltas, err)
+	}
+}
+
+func TestParseStreamRejectsToolCallIndex(t *testing.T) {
+	for _, index := range []int{-1, maxStreamToolCalls, 1 << 40} {
+		stream := fmt.Sprintf("data: {\"choices\":[{\"delta\":{\"tool_calls\":[{\"index\":%d,\"function\":{\"name\":\"x\"}}]}}]}\n\ndata: [DONE]\n", index)
+		if _, err := parseStream(strings.NewReader(stream), nil); err == nil || !strings.Contains(err.Error(), "tool call index") {
+			t.Errorf("index %d: err = %v", index, err)
+		}
+	}
+}
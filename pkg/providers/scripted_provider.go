@@ -0,0 +1,153 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// defaultScriptedReply is returned when no rule matches the prompt or the
+// matched rule has run out of steps.
+const defaultScriptedReply = "OK"
+
+// ScriptedScript describes the replies of a ScriptedProvider. Rules are tried
+// in order; the first whose Match pattern matches the latest user message
+// drives the conversation.
+type ScriptedScript struct {
+	Rules   []ScriptedRule `json:"rules"`
+	Default string         `json:"default,omitempty"` // reply when nothing matches, defaults to "OK"
+}
+
+// ScriptedRule replays Steps for prompts matching Match. The n-th LLM call
+// after the user message returns the n-th step, so a rule typically lists a
+// few tool calls followed by a final content-only reply.
+type ScriptedRule struct {
+	Name  string         `json:"name,omitempty"`
+	Match string         `json:"match"` // regular expression, matched against the latest user message
+	Steps []ScriptedStep `json:"steps"`
+}
+
+// ScriptedStep is one LLM turn: tool calls if any, otherwise the final reply.
+type ScriptedStep struct {
+	ToolCalls []ScriptedToolCall `json:"tool_calls,omitempty"`
+	Content   string             `json:"content,omitempty"`
+}
+
+// ScriptedToolCall is a tool invocation returned by a scripted step.
+type ScriptedToolCall struct {
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+}
+
+// ScriptedProvider implements LLMProvider without a model: replies are chosen
+// by pattern-matching the prompt against a script. It never touches the
+// network and keeps no state between calls (the turn is derived from the
+// message history), so runs are reproducible and safe to execute concurrently.
+type ScriptedProvider struct {
+	rules        []ScriptedRule
+	patterns     []*regexp.Regexp
+	defaultReply string
+}
+
+// NewScriptedProvider validates the script and compiles its patterns.
+func NewScriptedProvider(script ScriptedScript) (*ScriptedProvider, error) {
+	p := &ScriptedProvider{
+		rules:        script.Rules,
+		patterns:     make([]*regexp.Regexp, len(script.Rules)),
+		defaultReply: script.Default,
+	}
+	if p.defaultReply == "" {
+		p.defaultReply = defaultScriptedReply
+	}
+	for i, rule := range script.Rules {
+		re, err := regexp.Compile(rule.Match)
+		if err != nil {
+			return nil, fmt.Errorf("scripted rule %d (%s): invalid match pattern: %w", i, rule.Name, err)
+		}
+		if len(rule.Steps) == 0 {
+			return nil, fmt.Errorf("scripted rule %d (%s): no steps", i, rule.Name)
+		}
+		for j, step := range rule.Steps {
+			for _, tc := range step.ToolCalls {
+				if tc.Name == "" {
+					return nil, fmt.Errorf("scripted rule %d (%s) step %d: tool call without name", i, rule.Name, j)
+				}
+			}
+		}
+		p.patterns[i] = re
+	}
+	return p, nil
+}
+
+// LoadScriptedProvider reads a JSON script from path.
+func LoadScriptedProvider(path string) (*ScriptedProvider, error) {
+	if path == "" {
+		return nil, fmt.Errorf("scripted provider: providers.scripted.script is required")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("scripted provider: %w", err)
+	}
+	var script ScriptedScript
+	if err := json.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("scripted provider: parse %s: %w", path, err)
+	}
+	return NewScriptedProvider(script)
+}
+
+// Chat returns the next step of the first rule matching the latest user message.
+func (p *ScriptedProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	prompt, turn := "", 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			prompt = messages[i].Content
+			break
+		}
+		if messages[i].Role == "assistant" {
+			turn++
+		}
+	}
+
+	for i, re := range p.patterns {
+		if !re.MatchString(prompt) {
+			continue
+		}
+		steps := p.rules[i].Steps
+		if turn >= len(steps) {
+			break
+		}
+		return scriptedResponse(steps[turn], turn), nil
+	}
+	return &LLMResponse{Content: p.defaultReply, FinishReason: "stop"}, nil
+}
+
+func scriptedResponse(step ScriptedStep, turn int) *LLMResponse {
+	resp := &LLMResponse{Content: step.Content, FinishReason: "stop"}
+	for i, tc := range step.ToolCalls {
+		args := tc.Arguments
+		if args == nil {
+			args = map[string]interface{}{}
+		}
+		resp.ToolCalls = append(resp.ToolCalls, ToolCall{
+			ID:        fmt.Sprintf("scripted_%d_%d", turn, i),
+			Type:      "function",
+			Name:      tc.Name,
+			Arguments: args,
+		})
+	}
+	if len(resp.ToolCalls) > 0 {
+		resp.FinishReason = "tool_calls"
+	}
+	return resp
+}
+
+// GetDefaultModel returns the pseudo model name.
+func (p *ScriptedProvider) GetDefaultModel() string {
+	return "scripted"
+}
@@ -0,0 +1,99 @@
+package providers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+var _ LLMProvider = (*ScriptedProvider)(nil)
+
+func TestScriptedProviderTurns(t *testing.T) {
+	p, err := NewScriptedProvider(ScriptedScript{
+		Rules: []ScriptedRule{{
+			Match: "risk",
+			Steps: []ScriptedStep{
+				{ToolCalls: []ScriptedToolCall{{Name: "query_data", Arguments: map[string]interface{}{"sql_id": "pending_risk_events"}}}},
+				{Content: "done"},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("NewScriptedProvider: %v", err)
+	}
+	ctx := context.Background()
+
+	history := []Message{
+		{Role: "system", Content: "you are an analyst"},
+		{Role: "user", Content: "old risk question"},
+		{Role: "assistant", Content: "old answer"},
+		{Role: "user", Content: "run risk analysis"},
+	}
+	resp, err := p.Chat(ctx, history, nil, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Name != "query_data" || resp.FinishReason != "tool_calls" {
+		t.Fatalf("first turn = %+v", resp)
+	}
+
+	// The turn is derived from the history, not from the number of calls.
+	history = append(history,
+		Message{Role: "assistant", ToolCalls: resp.ToolCalls},
+		Message{Role: "tool", Content: "[]", ToolCallID: resp.ToolCalls[0].ID},
+	)
+	for i := 0; i < 2; i++ {
+		resp, err = p.Chat(ctx, history, nil, "", nil)
+		if err != nil || resp.Content != "done" || len(resp.ToolCalls) != 0 {
+			t.Fatalf("second turn = %+v, %v", resp, err)
+		}
+	}
+
+	// Exhausted rules and unmatched prompts fall back to the default reply.
+	history = append(history, Message{Role: "assistant", Content: "done"})
+	if resp, _ := p.Chat(ctx, history, nil, "", nil); resp.Content != defaultScriptedReply {
+		t.Errorf("exhausted rule = %q", resp.Content)
+	}
+	if resp, _ := p.Chat(ctx, []Message{{Role: "user", Content: "hello"}}, nil, "", nil); resp.Content != defaultScriptedReply {
+		t.Errorf("unmatched prompt = %q", resp.Content)
+	}
+}
+
+func TestNewScriptedProviderInvalid(t *testing.T) {
+	for _, script := range []ScriptedScript{
+		{Rules: []ScriptedRule{{Match: "(", Steps: []ScriptedStep{{Content: "x"}}}}},
+		{Rules: []ScriptedRule{{Match: "x"}}},
+		{Rules: []ScriptedRule{{Match: "x", Steps: []ScriptedStep{{ToolCalls: []ScriptedToolCall{{}}}}}}},
+	} {
+		if _, err := NewScriptedProvider(script); err == nil {
+			t.Errorf("NewScriptedProvider(%+v) expected error", script)
+		}
+	}
+}
+
+func TestCreateProviderScripted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.json")
+	if err := os.WriteFile(path, []byte(`{"rules":[{"match":"ping","steps":[{"content":"pong"}]}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Provider = "scripted"
+	cfg.Providers.Scripted.Script = path
+
+	provider, err := CreateProvider(cfg)
+	if err != nil {
+		t.Fatalf("CreateProvider: %v", err)
+	}
+	resp, err := provider.Chat(context.Background(), []Message{{Role: "user", Content: "ping"}}, nil, "", nil)
+	if err != nil || resp.Content != "pong" {
+		t.Errorf("reply = %+v, %v", resp, err)
+	}
+
+	cfg.Providers.Scripted.Script = ""
+	if _, err := CreateProvider(cfg); err == nil {
+		t.Error("expected error without script path")
+	}
+}
@@ -40,6 +40,13 @@ type LLMProvider interface {
 	GetDefaultModel() string
 }
 
+// StreamingProvider is implemented by providers that can stream the reply as
+// it is generated. onDelta receives each content fragment; the returned
+// response is the same as Chat would have produced.
+type StreamingProvider interface {
+	ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}, onDelta func(string)) (*LLMResponse, error)
+}
+
 type ToolDefinition struct {
 	Type     string                 `json:"type"`
 	Function ToolFunctionDefinition `json:"function"`
@@ -0,0 +1,221 @@
+package secops
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// defaultMaxConcurrentActivities 未配置时同时执行的活动数
+const defaultMaxConcurrentActivities = 4
+
+// 活动执行被跳过的原因
+const (
+	SkipOverlap        = "overlap"          // 上一次执行仍在排队或进行中
+	SkipNonBusinessDay = "non_business_day" // 工作日历中的非工作日
+	SkipClickHouseDown = "clickhouse_down"  // ClickHouse 熔断中
+	SkipOutsideWindow  = "outside_window"   // 执行时段外, 合并为下一个时段开始时的一次执行
+)
+
+// ActivityPoolStats 活动执行的并发情况
+type ActivityPoolStats struct {
+	Limit   int `json:"limit"`   // 同时执行的活动数上限, 0 表示不限制
+	Running int `json:"running"` // 正在执行的活动数
+	Queued  int `json:"queued"`  // 等待空闲名额的活动数
+}
+
+// ActivitySkip 活动某一原因的跳过次数
+type ActivitySkip struct {
+	Activity      string    `json:"activity"`
+	Reason        string    `json:"reason"`
+	Count         uint64    `json:"count"`
+	LastSkippedAt time.Time `json:"lastSkippedAt"`
+}
+
+// activityPool 活动执行的全局并发上限和单活动互斥: 同一活动同时只有一次执行在排队或进行,
+// 超出上限的执行按到达顺序等待名额; 零值不限制并发
+type activityPool struct {
+	mu       sync.Mutex
+	slots    chan struct{}            // 执行名额, 为空时不限制
+	inflight map[string]bool          // 排队或执行中的活动
+	queued   int                      // 等待名额的执行数
+	skips    map[string]*ActivitySkip // 活动/原因 -> 启动以来的跳过次数
+}
+
+// setLimit 设置同时执行的活动数; 0 取默认值, 负数不限制。只在启动前调用
+func (p *activityPool) setLimit(n int) {
+	if n == 0 {
+		n = defaultMaxConcurrentActivities
+	}
+	if n > 0 {
+		p.slots = make(chan struct{}, n)
+	}
+}
+
+// begin 登记活动的一次执行, 同一活动已在排队或执行时返回 false; 登记成功后必须调用 end
+func (p *activityPool) begin(activity string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.inflight[activity] {
+		return false
+	}
+	if p.inflight == nil {
+		p.inflight = make(map[string]bool)
+	}
+	p.inflight[activity] = true
+	return true
+}
+
+// end 结束活动的执行登记
+func (p *activityPool) end(activity string) {
+	p.mu.Lock()
+	delete(p.inflight, activity)
+	p.mu.Unlock()
+}
+
+// acquire 等待空闲的执行名额, ctx 取消时返回 false; 成功后必须调用 release
+func (p *activityPool) acquire(ctx context.Context) bool {
+	if p.slots == nil {
+		return ctx.Err() == nil
+	}
+	select {
+	case p.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	p.mu.Lock()
+	p.queued++
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.queued--
+		p.mu.Unlock()
+	}()
+
+	select {
+	case p.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// release 归还执行名额
+func (p *activityPool) release() {
+	if p.slots != nil {
+		<-p.slots
+	}
+}
+
+// skip 记录一次被跳过的执行
+func (p *activityPool) skip(activity, reason string, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.skips == nil {
+		p.skips = make(map[string]*ActivitySkip)
+	}
+	key := activity + "/" + reason
+	sk, ok := p.skips[key]
+	if !ok {
+		sk = &ActivitySkip{Activity: activity, Reason: reason}
+		p.skips[key] = sk
+	}
+	sk.Count++
+	sk.LastSkippedAt = now
+}
+
+// pending 活动是否有排队或执行中的执行
+func (p *activityPool) pending(activity string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.inflight[activity]
+}
+
+// skipCount 活动启动以来被跳过的执行次数
+func (p *activityPool) skipCount(activity string) uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var n uint64
+	for _, sk := range p.skips {
+		if sk.Activity == activity {
+			n += sk.Count
+		}
+	}
+	return n
+}
+
+// stats 当前的并发情况
+func (p *activityPool) stats() ActivityPoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	st := ActivityPoolStats{Queued: p.queued}
+	if p.slots != nil {
+		st.Limit = cap(p.slots)
+		st.Running = len(p.slots)
+	} else {
+		st.Running = len(p.inflight)
+	}
+	return st
+}
+
+// skipped 各活动的跳过次数, 按活动和原因排序
+func (p *activityPool) skipped() []ActivitySkip {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	result := make([]ActivitySkip, 0, len(p.skips))
+	for _, sk := range p.skips {
+		result = append(result, *sk)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Activity != result[j].Activity {
+			return result[i].Activity < result[j].Activity
+		}
+		return result[i].Reason < result[j].Reason
+	})
+	return result
+}
+
+// ActivityPoolStats 获取活动执行的并发情况
+func (s *Service) ActivityPoolStats() ActivityPoolStats {
+	return s.pool.stats()
+}
+
+// ActivitySkips 获取启动以来各活动被跳过的执行次数
+func (s *Service) ActivitySkips() []ActivitySkip {
+	return s.pool.skipped()
+}
+
+// beginActivity 登记活动的一次执行, 同一活动已在排队或执行时返回 false
+func (s *Service) beginActivity(name string) bool {
+	if s.runs.running(name) {
+		return false
+	}
+	return s.pool.begin(name)
+}
+
+// dispatch 在后台排队执行一次调度触发的活动; 上一次执行尚未结束时跳过并计数
+func (s *Service) dispatch(name string) {
+	if !s.beginActivity(name) {
+		s.pool.skip(name, SkipOverlap, time.Now())
+		logger.WarnCF("secops", "Activity run skipped: previous run still in progress",
+			map[string]interface{}{
+				"activity": name,
+			})
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.pool.end(name)
+		if !s.pool.acquire(s.ctx) {
+			return
+		}
+		defer s.pool.release()
+		s.execute(name, 0)
+	}()
+}
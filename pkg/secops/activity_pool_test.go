@@ -0,0 +1,112 @@
+package secops
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestActivityPool(t *testing.T) {
+	var p activityPool
+	p.setLimit(1)
+
+	if !p.begin("risk_analysis") || p.begin("risk_analysis") {
+		t.Fatal("second begin of the same activity should fail")
+	}
+	if !p.begin("weak_analysis") {
+		t.Fatal("other activities are not affected")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if !p.acquire(ctx) {
+		t.Fatal("first acquire should succeed")
+	}
+
+	// 名额已满, 第二个执行排队等待
+	acquired := make(chan bool)
+	go func() { acquired <- p.acquire(ctx) }()
+	deadline := time.Now().Add(time.Second)
+	for p.stats().Queued != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("stats = %+v, want 1 queued", p.stats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if st := p.stats(); st.Limit != 1 || st.Running != 1 {
+		t.Errorf("stats = %+v", st)
+	}
+	p.release()
+	if !<-acquired {
+		t.Fatal("queued acquire should succeed after release")
+	}
+	p.release()
+
+	// 取消后排队的执行放弃
+	p.acquire(ctx)
+	go func() { acquired <- p.acquire(ctx) }()
+	cancel()
+	if <-acquired {
+		t.Error("acquire should fail after cancel")
+	}
+	p.release()
+
+	p.end("risk_analysis")
+	if p.pending("risk_analysis") || !p.begin("risk_analysis") {
+		t.Error("activity should be free after end")
+	}
+
+	now := time.Now()
+	p.skip("risk_analysis", SkipOverlap, now)
+	p.skip("risk_analysis", SkipOverlap, now)
+	p.skip("risk_analysis", SkipNonBusinessDay, now)
+	skips := p.skipped()
+	if len(skips) != 2 || skips[0].Reason != SkipNonBusinessDay || skips[1].Count != 2 || !skips[1].LastSkippedAt.Equal(now) {
+		t.Errorf("skips = %+v", skips)
+	}
+	if n := p.skipCount("risk_analysis"); n != 3 {
+		t.Errorf("skip count = %d, want 3", n)
+	}
+
+	// 负数不限制并发
+	var unlimited activityPool
+	unlimited.setLimit(-1)
+	for i := 0; i < 10; i++ {
+		if !unlimited.acquire(context.Background()) {
+			t.Fatal("unlimited acquire failed")
+		}
+	}
+	if st := unlimited.stats(); st.Limit != 0 || st.Queued != 0 {
+		t.Errorf("unlimited stats = %+v", st)
+	}
+}
+
+func TestDispatchOverlap(t *testing.T) {
+	cfg := &config.SecOpsConfig{Activities: map[string]config.ActivityConfig{
+		"risk_analysis": {Enabled: true, Schedule: "30m"},
+	}}
+	svc := &Service{config: cfg, runs: newRunStore(), activityState: newActivityStateStore(), activities: make(map[string]*Activity), started: true}
+	svc.ctx, svc.cancel = context.WithCancel(context.Background())
+	defer svc.cancel()
+	if !svc.pool.begin("risk_analysis") {
+		t.Fatal("begin failed")
+	}
+
+	// 上一次执行未结束, 调度触发被跳过
+	svc.dispatch("risk_analysis")
+	svc.dispatch("risk_analysis")
+	if n := svc.pool.skipCount("risk_analysis"); n != 2 {
+		t.Errorf("skipped = %d, want 2", n)
+	}
+	if _, err := svc.TriggerActivity("risk_analysis"); !errors.Is(err, ErrActivityRunning) {
+		t.Errorf("trigger err = %v, want ErrActivityRunning", err)
+	}
+
+	sums := svc.ActivitySummaries()
+	if len(sums) != 1 || !sums[0].Queued || sums[0].Running || sums[0].Skipped != 2 {
+		t.Errorf("summaries = %+v", sums)
+	}
+}
@@ -0,0 +1,213 @@
+package secops
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// activityOverride 运行时对活动启停的覆盖, 优先于配置文件中的 enabled
+type activityOverride struct {
+	Enabled   bool      `json:"enabled"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// activityStateStore 活动启停覆盖, 持久化到 workspace/secops/activity_state.json,
+// 重启后仍然生效; 配置文件本身不被改写
+type activityStateStore struct {
+	overrides map[string]activityOverride
+	path      string
+	mu        sync.RWMutex
+}
+
+func newActivityStateStore() *activityStateStore {
+	return &activityStateStore{overrides: make(map[string]activityOverride)}
+}
+
+// load 加载已持久化的启停覆盖
+func (as *activityStateStore) load(path string) error {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	as.path = path
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &as.overrides); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if as.overrides == nil {
+		as.overrides = make(map[string]activityOverride)
+	}
+	return nil
+}
+
+// get 获取活动的启停覆盖
+func (as *activityStateStore) get(name string) (activityOverride, bool) {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	o, ok := as.overrides[name]
+	return o, ok
+}
+
+// set 记录活动的启停覆盖并持久化
+func (as *activityStateStore) set(name string, enabled bool) {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	as.overrides[name] = activityOverride{Enabled: enabled, UpdatedAt: time.Now()}
+	if as.path == "" {
+		return
+	}
+	if err := saveJSONAtomic(as.path, as.overrides); err != nil {
+		logger.ErrorCF("secops", "Failed to persist activity state",
+			map[string]interface{}{
+				"path":  as.path,
+				"error": err.Error(),
+			})
+	}
+}
+
+// activityEnabled 活动是否启用: 运行时覆盖优先, 否则取配置文件
+func (s *Service) activityEnabled(name string) bool {
+	if s.activityState != nil {
+		if o, ok := s.activityState.get(name); ok {
+			return o.Enabled
+		}
+	}
+	actCfg, _ := s.activityConfig(name)
+	return actCfg.Enabled
+}
+
+// SetActivityEnabled 启用或停用活动, 立即对调度生效并在重启后保持
+func (s *Service) SetActivityEnabled(name string, enabled bool) error {
+	actCfg, ok := s.activityConfig(name)
+	if !ok {
+		return fmt.Errorf("activity not found: %s", name)
+	}
+
+	s.activityState.set(name, enabled)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 服务未启动或已停止时只记录状态, 由 Start 决定是否调度
+	if !s.started || s.ctx.Err() != nil {
+		return nil
+	}
+
+	activity, running := s.activities[name]
+	switch {
+	case enabled && !running:
+		s.startActivityLocked(name, actCfg, true)
+	case !enabled && running:
+		close(activity.stopCh)
+		delete(s.activities, name)
+	}
+
+	logger.InfoCF("secops", "Activity toggled",
+		map[string]interface{}{
+			"activity": name,
+			"enabled":  enabled,
+		})
+	return nil
+}
+
+// startActivityLocked 启动活动调度, runNow 为 true 时立即执行一次; 调用方需持有 s.mu
+func (s *Service) startActivityLocked(name string, actCfg config.ActivityConfig, runNow bool) {
+	// 解析调度间隔
+	interval := s.parseSchedule(actCfg.Schedule)
+	if interval <= 0 {
+		interval = 30 * time.Minute // 默认30分钟
+	}
+	activity := &Activity{
+		Name:     name,
+		Config:   &actCfg,
+		stopCh:   make(chan struct{}),
+		interval: interval,
+		since:    time.Now(),
+		runNow:   runNow,
+	}
+	if actCfg.Window != "" {
+		// 配置加载时已校验
+		activity.window, _ = parseActivityWindow(actCfg.Window, s.config.Timezone)
+	}
+	s.activities[name] = activity
+
+	s.wg.Add(1)
+	go s.runActivity(activity)
+}
+
+// nextRun 按调度间隔推算的下次执行时间, 落在执行时段外时为下一个时段开始;
+// 配置了工作日历时非工作日的执行会被跳过
+func (a *Activity) nextRun(now time.Time) time.Time {
+	if a.interval <= 0 {
+		return time.Time{}
+	}
+	if deferred := a.deferredUntil.Load(); deferred != nil {
+		return *deferred
+	}
+	next := a.since
+	if elapsed := now.Sub(a.since); elapsed >= 0 {
+		next = a.since.Add((elapsed/a.interval + 1) * a.interval)
+	}
+	if a.window != nil {
+		return a.window.next(next)
+	}
+	return next
+}
+
+// ErrActivityRunning 活动正在执行, 不能重复触发
+var ErrActivityRunning = errors.New("activity is already running")
+
+// TriggerActivity 立即在后台执行一次活动, 不影响调度; 已停用的活动也可手动触发
+func (s *Service) TriggerActivity(name string) (*Run, error) {
+	if _, ok := s.activityConfig(name); !ok {
+		return nil, fmt.Errorf("activity not found: %s", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.started || s.ctx.Err() != nil {
+		return nil, fmt.Errorf("secops service is not running")
+	}
+
+	if !s.beginActivity(name) {
+		return nil, ErrActivityRunning
+	}
+	run := s.runs.start(name)
+	logger.InfoCF("secops", "Activity triggered manually",
+		map[string]interface{}{
+			"activity": name,
+			"run_id":   run.ID,
+		})
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.pool.end(name)
+		if !s.pool.acquire(s.ctx) {
+			s.runs.finish(run, "", s.ctx.Err())
+			return
+		}
+		defer s.pool.release()
+		s.executeRun(run, 0)
+	}()
+	cp := *run
+	return &cp, nil
+}
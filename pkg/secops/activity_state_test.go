@@ -0,0 +1,106 @@
+package secops
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestSetActivityEnabled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "activity_state.json")
+	cfg := &config.SecOpsConfig{Activities: map[string]config.ActivityConfig{
+		"risk_analysis": {Enabled: true, Schedule: "30m"},
+		"weak_analysis": {Enabled: false, Schedule: "1h"},
+	}}
+
+	svc := &Service{config: cfg, runs: newRunStore(), activityState: newActivityStateStore()}
+	if err := svc.activityState.load(path); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	if err := svc.SetActivityEnabled("unknown", true); err == nil {
+		t.Error("expected error for unknown activity")
+	}
+	if err := svc.SetActivityEnabled("risk_analysis", false); err != nil {
+		t.Fatalf("SetActivityEnabled: %v", err)
+	}
+	if err := svc.SetActivityEnabled("weak_analysis", true); err != nil {
+		t.Fatalf("SetActivityEnabled: %v", err)
+	}
+
+	// 重启后覆盖仍然生效
+	reloaded := &Service{config: cfg, runs: newRunStore(), activityState: newActivityStateStore()}
+	if err := reloaded.activityState.load(path); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	got := map[string]ActivitySummary{}
+	for _, sum := range reloaded.ActivitySummaries() {
+		got[sum.Name] = sum
+	}
+	if s := got["risk_analysis"]; s.Enabled || !s.Overridden {
+		t.Errorf("risk_analysis = %+v, want disabled and overridden", s)
+	}
+	if s := got["weak_analysis"]; !s.Enabled || !s.Overridden {
+		t.Errorf("weak_analysis = %+v, want enabled and overridden", s)
+	}
+
+	// 切回与配置一致时不再标记为覆盖
+	if err := reloaded.SetActivityEnabled("risk_analysis", true); err != nil {
+		t.Fatal(err)
+	}
+	for _, sum := range reloaded.ActivitySummaries() {
+		if sum.Name == "risk_analysis" && (!sum.Enabled || sum.Overridden) {
+			t.Errorf("risk_analysis = %+v, want enabled without override", sum)
+		}
+	}
+}
+
+func TestActivityNextRun(t *testing.T) {
+	since := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	a := &Activity{interval: 30 * time.Minute, since: since}
+	cases := map[time.Duration]time.Duration{
+		0:                30 * time.Minute,
+		10 * time.Minute: 30 * time.Minute,
+		30 * time.Minute: 60 * time.Minute,
+		95 * time.Minute: 120 * time.Minute,
+	}
+	for elapsed, want := range cases {
+		if got := a.nextRun(since.Add(elapsed)); !got.Equal(since.Add(want)) {
+			t.Errorf("nextRun(+%v) = %v, want +%v", elapsed, got, want)
+		}
+	}
+}
+
+func TestTriggerActivity(t *testing.T) {
+	cfg := &config.SecOpsConfig{Activities: map[string]config.ActivityConfig{
+		"risk_analysis": {Enabled: true, Schedule: "30m"},
+	}}
+	svc := &Service{config: cfg, runs: newRunStore(), activityState: newActivityStateStore(), activities: make(map[string]*Activity)}
+
+	if _, err := svc.TriggerActivity("unknown"); err == nil {
+		t.Error("expected error for unknown activity")
+	}
+	svc.ctx, svc.cancel = context.WithCancel(context.Background())
+	defer svc.cancel()
+	if _, err := svc.TriggerActivity("risk_analysis"); err == nil {
+		t.Error("expected error before the service is started")
+	}
+
+	// 正在执行时不能重复触发, 概况中标记为执行中
+	svc.started = true
+	run := svc.runs.start("risk_analysis")
+	if _, err := svc.TriggerActivity("risk_analysis"); !errors.Is(err, ErrActivityRunning) {
+		t.Errorf("err = %v, want ErrActivityRunning", err)
+	}
+	if sums := svc.ActivitySummaries(); !sums[0].Running {
+		t.Errorf("summary = %+v, want running", sums[0])
+	}
+	svc.runs.finish(run, "done", nil)
+	if sums := svc.ActivitySummaries(); sums[0].Running || sums[0].LastStatus != RunStatusSucceeded {
+		t.Errorf("summary = %+v, want finished", sums[0])
+	}
+}
@@ -0,0 +1,264 @@
+package secops
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// 标注对象类型
+const (
+	AnnotationHost = "host"
+	AnnotationURL  = "url"
+	AnnotationUser = "user"
+)
+
+// Annotation 分析师对主机、URL 或用户的标注, 如 "渗透测试机"、"历史遗留系统, 无鉴权属设计如此"
+type Annotation struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind"`  // host, url, user
+	Value     string    `json:"value"` // url 标注按前缀匹配, host 和 user 精确匹配 (不区分大小写)
+	Note      string    `json:"note"`
+	Author    string    `json:"author,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// matches 判断标注是否适用于给定对象
+func (a *Annotation) matches(kind, value string) bool {
+	if a.Kind != kind || value == "" {
+		return false
+	}
+	if kind == AnnotationURL {
+		return strings.HasPrefix(value, a.Value)
+	}
+	return strings.EqualFold(a.Value, value)
+}
+
+// annotationStore 标注存储
+type annotationStore struct {
+	annotations map[string]*Annotation
+	path        string
+	mu          sync.RWMutex
+}
+
+func newAnnotationStore() *annotationStore {
+	return &annotationStore{annotations: make(map[string]*Annotation)}
+}
+
+// load 加载已持久化的标注
+func (as *annotationStore) load(path string) error {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	as.path = path
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var annotations []*Annotation
+	if err := json.Unmarshal(data, &annotations); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for _, a := range annotations {
+		as.annotations[a.ID] = a
+	}
+	return nil
+}
+
+func validateAnnotation(kind, value, note string) error {
+	switch kind {
+	case AnnotationHost, AnnotationURL, AnnotationUser:
+	default:
+		return fmt.Errorf("invalid annotation kind: %q", kind)
+	}
+	if strings.TrimSpace(value) == "" {
+		return fmt.Errorf("annotation value is required")
+	}
+	if strings.TrimSpace(note) == "" {
+		return fmt.Errorf("annotation note is required")
+	}
+	return nil
+}
+
+// add 新增标注
+func (as *annotationStore) add(kind, value, note, author string) (*Annotation, error) {
+	if err := validateAnnotation(kind, value, note); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	a := &Annotation{
+		ID:        uuid.New().String(),
+		Kind:      kind,
+		Value:     strings.TrimSpace(value),
+		Note:      strings.TrimSpace(note),
+		Author:    author,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	as.annotations[a.ID] = a
+	as.saveLocked()
+	return a, nil
+}
+
+// update 修改标注内容
+func (as *annotationStore) update(id, note string) (*Annotation, error) {
+	if strings.TrimSpace(note) == "" {
+		return nil, fmt.Errorf("annotation note is required")
+	}
+
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	a, ok := as.annotations[id]
+	if !ok {
+		return nil, fmt.Errorf("annotation not found: %s", id)
+	}
+	a.Note = strings.TrimSpace(note)
+	a.UpdatedAt = time.Now()
+	as.saveLocked()
+	return a, nil
+}
+
+// remove 删除标注
+func (as *annotationStore) remove(id string) bool {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if _, ok := as.annotations[id]; !ok {
+		return false
+	}
+	delete(as.annotations, id)
+	as.saveLocked()
+	return true
+}
+
+// list 列出标注, kind 为空表示全部类型, 按对象排序
+func (as *annotationStore) list(kind string) []*Annotation {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	result := make([]*Annotation, 0, len(as.annotations))
+	for _, a := range as.annotations {
+		if kind == "" || a.Kind == kind {
+			result = append(result, a)
+		}
+	}
+	sortAnnotations(result)
+	return result
+}
+
+// lookup 查找适用于给定对象的标注
+func (as *annotationStore) lookup(kind, value string) []*Annotation {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	var result []*Annotation
+	for _, a := range as.annotations {
+		if a.matches(kind, value) {
+			result = append(result, a)
+		}
+	}
+	sortAnnotations(result)
+	return result
+}
+
+func sortAnnotations(list []*Annotation) {
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Kind != list[j].Kind {
+			return list[i].Kind < list[j].Kind
+		}
+		if list[i].Value != list[j].Value {
+			return list[i].Value < list[j].Value
+		}
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+}
+
+func (as *annotationStore) saveLocked() {
+	if as.path == "" {
+		return
+	}
+	annotations := make([]*Annotation, 0, len(as.annotations))
+	for _, a := range as.annotations {
+		annotations = append(annotations, a)
+	}
+	if err := saveJSONAtomic(as.path, annotations); err != nil {
+		logger.ErrorCF("secops", "Failed to persist annotations",
+			map[string]interface{}{
+				"path":  as.path,
+				"error": err.Error(),
+			})
+	}
+}
+
+// annotationDetailKeys 提案 details 中对应各类标注对象的字段
+var annotationDetailKeys = map[string][]string{
+	AnnotationHost: {"host", "domain"},
+	AnnotationURL:  {"url", "path"},
+	AnnotationUser: {"user", "user_id", "uid"},
+}
+
+// AddAnnotation 新增标注
+func (s *Service) AddAnnotation(kind, value, note, author string) (*Annotation, error) {
+	return s.annotations.add(kind, value, note, author)
+}
+
+// UpdateAnnotation 修改标注内容
+func (s *Service) UpdateAnnotation(id, note string) (*Annotation, error) {
+	return s.annotations.update(id, note)
+}
+
+// DeleteAnnotation 删除标注
+func (s *Service) DeleteAnnotation(id string) bool {
+	return s.annotations.remove(id)
+}
+
+// Annotations 列出标注
+func (s *Service) Annotations(kind string) []*Annotation {
+	return s.annotations.list(kind)
+}
+
+// LookupAnnotations 查找适用于给定对象的标注
+func (s *Service) LookupAnnotations(kind, value string) []*Annotation {
+	return s.annotations.lookup(kind, value)
+}
+
+// ProposalAnnotations 根据提案 details 中的 host/url/user 查找相关标注
+func (s *Service) ProposalAnnotations(p *Proposal) []*Annotation {
+	seen := make(map[string]bool)
+	var result []*Annotation
+	for _, kind := range []string{AnnotationHost, AnnotationURL, AnnotationUser} {
+		for _, key := range annotationDetailKeys[kind] {
+			value, _ := p.Details[key].(string)
+			for _, a := range s.annotations.lookup(kind, value) {
+				if !seen[a.ID] {
+					seen[a.ID] = true
+					result = append(result, a)
+				}
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,66 @@
+package secops
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAnnotationStore_LookupAndPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "annotations.json")
+	store := newAnnotationStore()
+	if err := store.load(path); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	if _, err := store.add("ip", "10.0.0.1", "note", ""); err == nil {
+		t.Error("expected error for invalid kind")
+	}
+	if _, err := store.add(AnnotationHost, "Pentest.Example.com", "渗透测试机", "alice"); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	legacy, err := store.add(AnnotationURL, "https://legacy.example.com/api/", "历史遗留系统, 无鉴权属设计如此", "")
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	if got := store.lookup(AnnotationHost, "pentest.example.com"); len(got) != 1 {
+		t.Errorf("host lookup = %d, want 1 (case-insensitive)", len(got))
+	}
+	if got := store.lookup(AnnotationURL, "https://legacy.example.com/api/export?id=1"); len(got) != 1 || got[0].ID != legacy.ID {
+		t.Errorf("url prefix lookup = %+v", got)
+	}
+	if got := store.lookup(AnnotationURL, "https://other.example.com/api/"); len(got) != 0 {
+		t.Errorf("unexpected url match: %+v", got)
+	}
+
+	reloaded := newAnnotationStore()
+	if err := reloaded.load(path); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if got := reloaded.list(""); len(got) != 2 {
+		t.Errorf("reloaded %d annotations, want 2", len(got))
+	}
+}
+
+func TestProposalAnnotationsAndTool(t *testing.T) {
+	svc := &Service{annotations: newAnnotationStore()}
+	svc.AddAnnotation(AnnotationHost, "10.0.8.15", "渗透测试机", "")
+	svc.AddAnnotation(AnnotationUser, "u-1001", "红队账号", "")
+
+	p := NewProposal("risk", "SQL 注入", "", map[string]interface{}{"host": "10.0.8.15", "uid": "u-1001", "url": 42})
+	if got := svc.ProposalAnnotations(p); len(got) != 2 {
+		t.Errorf("proposal annotations = %+v, want 2", got)
+	}
+
+	tool := NewAnnotationTool(svc)
+	result := tool.Execute(context.Background(), map[string]interface{}{"value": "10.0.8.15"})
+	if result.IsError || !strings.Contains(result.ForLLM, "渗透测试机") {
+		t.Errorf("tool result = %+v", result)
+	}
+	result = tool.Execute(context.Background(), map[string]interface{}{"kind": "user", "value": "10.0.8.15"})
+	if !strings.Contains(result.ForLLM, "no annotations") {
+		t.Errorf("tool result = %+v, want no annotations", result)
+	}
+}
@@ -0,0 +1,83 @@
+package secops
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// AnnotationTool 供 Agent 查询分析师对主机、URL、用户的标注
+type AnnotationTool struct {
+	service *Service
+}
+
+// NewAnnotationTool 创建标注查询工具
+func NewAnnotationTool(service *Service) *AnnotationTool {
+	return &AnnotationTool{service: service}
+}
+
+// Name 工具名称
+func (t *AnnotationTool) Name() string {
+	return "lookup_annotation"
+}
+
+// Description 工具描述
+func (t *AnnotationTool) Description() string {
+	return `查询分析师对主机、URL 或用户的标注 (如 "渗透测试机"、"历史遗留系统, 无鉴权属设计如此")。
+研判前应查询事件涉及的 host、url、user, 结论需考虑标注中的信息。
+- kind: host, url, user; 为空时按全部类型查询
+- value: 主机名/IP、完整 URL (按前缀匹配) 或用户 ID`
+}
+
+// Parameters 参数定义
+func (t *AnnotationTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"kind": map[string]interface{}{
+				"type": "string",
+				"enum": []string{AnnotationHost, AnnotationURL, AnnotationUser},
+			},
+			"value": map[string]interface{}{
+				"type": "string",
+			},
+		},
+		"required": []string{"value"},
+	}
+}
+
+// Execute 查询标注
+func (t *AnnotationTool) Execute(ctx context.Context, args map[string]interface{}) *tools.ToolResult {
+	kind, _ := args["kind"].(string)
+	value, _ := args["value"].(string)
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return tools.ErrorResult("value is required")
+	}
+
+	kinds := []string{AnnotationHost, AnnotationURL, AnnotationUser}
+	if kind != "" {
+		if _, ok := annotationDetailKeys[kind]; !ok {
+			return tools.ErrorResult(fmt.Sprintf("invalid kind: %q", kind))
+		}
+		kinds = []string{kind}
+	}
+
+	var sb strings.Builder
+	for _, k := range kinds {
+		for _, a := range t.service.LookupAnnotations(k, value) {
+			fmt.Fprintf(&sb, "- [%s] %s: %s", a.Kind, a.Value, a.Note)
+			if a.Author != "" {
+				fmt.Fprintf(&sb, " (%s)", a.Author)
+			}
+			sb.WriteString("\n")
+		}
+	}
+
+	if sb.Len() == 0 {
+		return tools.SilentResult(fmt.Sprintf("no annotations for %s", value))
+	}
+	return tools.SilentResult(sb.String())
+}
@@ -0,0 +1,584 @@
+package secops
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const (
+	defaultAnomalyInterval    = 5 * time.Minute
+	defaultAnomalySQLID       = "host_traffic_stats"
+	defaultAnomalyAlpha       = 0.3
+	defaultAnomalyThreshold   = 3.5
+	defaultAnomalyMinSamples  = 12
+	defaultAnomalyMinRequests = 50
+
+	// maxAnomalyResiduals 每个指标保留的历史残差数, 用于计算中位数和 MAD
+	maxAnomalyResiduals = 48
+	// anomalyBaselineTTL 超过该时长没有数据的主机清除基线
+	anomalyBaselineTTL = 7 * 24 * time.Hour
+	// preAlertRetention 已领取的预警保留时长
+	preAlertRetention = 7 * 24 * time.Hour
+	// maxPendingPreAlerts 待研判预警上限, 超出时丢弃最旧的
+	maxPendingPreAlerts = 1000
+)
+
+// 检测指标
+const (
+	MetricRequests  = "requests"   // 窗口内请求数
+	MetricErrorRate = "error_rate" // 窗口内错误请求占比
+)
+
+// 预警状态
+const (
+	PreAlertPending = "pending" // 等待 anomaly_analysis 活动领取
+	PreAlertClaimed = "claimed" // 已交给 Agent 研判
+)
+
+// PreAlert 统计检测产生的预警, 由 Agent 研判是否为真实攻击或故障
+type PreAlert struct {
+	ID          string    `json:"id"`
+	Host        string    `json:"host"`
+	Metric      string    `json:"metric"`    // 见 Metric* 常量
+	Direction   string    `json:"direction"` // spike 或 drop
+	Value       float64   `json:"value"`     // 本窗口的取值
+	Expected    float64   `json:"expected"`  // EWMA 基线
+	Score       float64   `json:"score"`     // 残差的稳健 z-score
+	Requests    int64     `json:"requests"`
+	Errors      int64     `json:"errors"`
+	Windows     int       `json:"windows"` // 领取前持续偏离的窗口数
+	WindowStart time.Time `json:"windowStart"`
+	WindowEnd   time.Time `json:"windowEnd"`
+	Status      string    `json:"status"`
+	DetectedAt  time.Time `json:"detectedAt"`
+	ClaimedAt   time.Time `json:"claimedAt,omitempty"`
+}
+
+// metricBaseline 单个指标的 EWMA 基线和近期残差
+type metricBaseline struct {
+	EWMA      float64   `json:"ewma"`
+	Samples   int       `json:"samples"`
+	Residuals []float64 `json:"residuals"`
+}
+
+// hostBaseline 主机的各项指标基线
+type hostBaseline struct {
+	Requests  metricBaseline `json:"requests"`
+	ErrorRate metricBaseline `json:"errorRate"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+}
+
+// anomalyStore 主机基线和预警, 持久化到 workspace/secops/anomaly.json
+type anomalyStore struct {
+	baselines map[string]*hostBaseline
+	alerts    map[string]*PreAlert
+	path      string
+	mu        sync.RWMutex
+}
+
+func newAnomalyStore() *anomalyStore {
+	return &anomalyStore{
+		baselines: make(map[string]*hostBaseline),
+		alerts:    make(map[string]*PreAlert),
+	}
+}
+
+// load 加载已持久化的基线和预警
+func (as *anomalyStore) load(path string) error {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	as.path = path
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var state struct {
+		Baselines map[string]*hostBaseline `json:"baselines"`
+		Alerts    []*PreAlert              `json:"alerts"`
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for host, b := range state.Baselines {
+		as.baselines[host] = b
+	}
+	for _, a := range state.Alerts {
+		as.alerts[a.ID] = a
+	}
+	return nil
+}
+
+// raiseLocked 加入预警; 同一主机同一指标已有待研判预警时更新其取值, 不重复入队。返回是否新增
+func (as *anomalyStore) raiseLocked(a *PreAlert) bool {
+	for _, cur := range as.alerts {
+		if cur.Status == PreAlertPending && cur.Host == a.Host && cur.Metric == a.Metric {
+			cur.Direction, cur.Value, cur.Expected, cur.Score = a.Direction, a.Value, a.Expected, a.Score
+			cur.Requests, cur.Errors = a.Requests, a.Errors
+			cur.WindowEnd, cur.DetectedAt = a.WindowEnd, a.DetectedAt
+			cur.Windows++
+			return false
+		}
+	}
+	a.ID = uuid.NewString()
+	a.Status = PreAlertPending
+	a.Windows = 1
+	as.alerts[a.ID] = a
+	return true
+}
+
+// pruneLocked 清理过期的已领取预警和长期无数据的主机基线, 并将待研判预警限制在上限以内
+func (as *anomalyStore) pruneLocked(now time.Time) {
+	for host, b := range as.baselines {
+		if now.Sub(b.UpdatedAt) > anomalyBaselineTTL {
+			delete(as.baselines, host)
+		}
+	}
+	var pending []*PreAlert
+	for id, a := range as.alerts {
+		switch {
+		case a.Status == PreAlertClaimed && now.Sub(a.ClaimedAt) > preAlertRetention:
+			delete(as.alerts, id)
+		case a.Status == PreAlertPending:
+			pending = append(pending, a)
+		}
+	}
+	if len(pending) <= maxPendingPreAlerts {
+		return
+	}
+	sortPreAlerts(pending, false)
+	for _, a := range pending[:len(pending)-maxPendingPreAlerts] {
+		delete(as.alerts, a.ID)
+	}
+	logger.WarnCF("secops", "Too many pending pre-alerts, oldest dropped",
+		map[string]interface{}{
+			"dropped": len(pending) - maxPendingPreAlerts,
+		})
+}
+
+// claim 按偏离程度从高到低领取最多 n 条待研判预警, 领取后不再返回
+func (as *anomalyStore) claim(n int, now time.Time) []*PreAlert {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	var pending []*PreAlert
+	for _, a := range as.alerts {
+		if a.Status == PreAlertPending {
+			pending = append(pending, a)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		if si, sj := math.Abs(pending[i].Score), math.Abs(pending[j].Score); si != sj {
+			return si > sj
+		}
+		return pending[i].DetectedAt.Before(pending[j].DetectedAt)
+	})
+	if len(pending) > n {
+		pending = pending[:n]
+	}
+	for _, a := range pending {
+		a.Status = PreAlertClaimed
+		a.ClaimedAt = now
+	}
+	if len(pending) > 0 {
+		as.saveLocked()
+	}
+	return pending
+}
+
+// byHost 主机最近的预警, 按检测时间倒序
+func (as *anomalyStore) byHost(host string, n int) []*PreAlert {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	var result []*PreAlert
+	for _, a := range as.alerts {
+		if a.Host == host {
+			result = append(result, a)
+		}
+	}
+	sortPreAlerts(result, true)
+	if len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
+
+// pendingCount 待研判预警数
+func (as *anomalyStore) pendingCount() int {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	n := 0
+	for _, a := range as.alerts {
+		if a.Status == PreAlertPending {
+			n++
+		}
+	}
+	return n
+}
+
+func (as *anomalyStore) saveLocked() {
+	if as.path == "" {
+		return
+	}
+	alerts := make([]*PreAlert, 0, len(as.alerts))
+	for _, a := range as.alerts {
+		alerts = append(alerts, a)
+	}
+	sortPreAlerts(alerts, false)
+	state := map[string]interface{}{
+		"baselines": as.baselines,
+		"alerts":    alerts,
+	}
+	if err := saveJSONAtomic(as.path, state); err != nil {
+		logger.ErrorCF("secops", "Failed to persist anomaly state",
+			map[string]interface{}{
+				"path":  as.path,
+				"error": err.Error(),
+			})
+	}
+}
+
+func sortPreAlerts(alerts []*PreAlert, desc bool) {
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].DetectedAt.Equal(alerts[j].DetectedAt) {
+			return alerts[i].ID < alerts[j].ID
+		}
+		return alerts[i].DetectedAt.After(alerts[j].DetectedAt) == desc
+	})
+}
+
+// queryFunc 按 sql_id 执行聚合查询, 与 SecOpsQueryDataTool.QueryTemplate 一致
+type queryFunc func(ctx context.Context, source, sqlID string, params map[string]string) ([]string, [][]interface{}, error)
+
+// anomalyDetector 定期聚合主机流量并检测偏离基线的指标, 不调用 LLM
+type anomalyDetector struct {
+	cfg      config.AnomalyConfig
+	interval time.Duration
+	store    *anomalyStore
+	now      func() time.Time
+}
+
+// newAnomalyDetector 校验配置并填充默认值
+func newAnomalyDetector(cfg config.AnomalyConfig, store *anomalyStore) (*anomalyDetector, error) {
+	interval := defaultAnomalyInterval
+	if cfg.Interval != "" {
+		d, err := time.ParseDuration(cfg.Interval)
+		if err != nil || d < time.Minute {
+			return nil, fmt.Errorf("invalid interval %q: must be at least 1m", cfg.Interval)
+		}
+		interval = d
+	}
+	if cfg.SQLID == "" {
+		cfg.SQLID = defaultAnomalySQLID
+	}
+	if cfg.Alpha == 0 {
+		cfg.Alpha = defaultAnomalyAlpha
+	}
+	if cfg.Alpha <= 0 || cfg.Alpha > 1 {
+		return nil, fmt.Errorf("invalid alpha %v: must be in (0, 1]", cfg.Alpha)
+	}
+	if cfg.Threshold == 0 {
+		cfg.Threshold = defaultAnomalyThreshold
+	}
+	if cfg.Threshold < 0 {
+		return nil, fmt.Errorf("invalid threshold %v", cfg.Threshold)
+	}
+	if cfg.MinSamples <= 0 {
+		cfg.MinSamples = defaultAnomalyMinSamples
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = defaultAnomalyMinRequests
+	}
+	return &anomalyDetector{cfg: cfg, interval: interval, store: store, now: time.Now}, nil
+}
+
+// runAnomalyDetection 按间隔检测, 直到服务停止
+func (s *Service) runAnomalyDetection() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.anomaly.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.ctx.Done():
+			return
+		}
+
+		queryTool, _ := s.tools()
+		raised, err := s.anomaly.detect(s.ctx, queryTool.QueryTemplate)
+		if err != nil {
+			logger.WarnCF("secops", "Anomaly detection failed",
+				map[string]interface{}{
+					"error": err.Error(),
+				})
+		} else if raised > 0 {
+			logger.InfoCF("secops", "Anomaly pre-alerts raised",
+				map[string]interface{}{
+					"raised":  raised,
+					"pending": s.anomaly.store.pendingCount(),
+				})
+		}
+	}
+}
+
+// trafficStat 主机在一个窗口内的流量
+type trafficStat struct {
+	requests, errors int64
+}
+
+// detect 执行一次聚合查询, 更新各主机的基线并生成预警; 返回新增的预警数
+func (d *anomalyDetector) detect(ctx context.Context, query queryFunc) (int, error) {
+	columns, rows, err := query(ctx, d.cfg.Source, d.cfg.SQLID, map[string]string{
+		"window_seconds": strconv.Itoa(int(d.interval.Seconds())),
+	})
+	if err != nil {
+		return 0, err
+	}
+	stats, err := parseTrafficStats(columns, rows)
+	if err != nil {
+		return 0, err
+	}
+	return d.observe(stats, d.now()), nil
+}
+
+// parseTrafficStats 按列名读取 host、requests、errors
+func parseTrafficStats(columns []string, rows [][]interface{}) (map[string]trafficStat, error) {
+	index := map[string]int{"host": -1, "requests": -1, "errors": -1}
+	for i, c := range columns {
+		if _, ok := index[c]; ok {
+			index[c] = i
+		}
+	}
+	for name, i := range index {
+		if i < 0 {
+			return nil, fmt.Errorf("aggregate query must return column %q", name)
+		}
+	}
+
+	stats := make(map[string]trafficStat, len(rows))
+	for _, row := range rows {
+		if len(row) < len(columns) {
+			continue
+		}
+		host := fmt.Sprint(row[index["host"]])
+		requests, err1 := statNumber(row[index["requests"]])
+		errors, err2 := statNumber(row[index["errors"]])
+		if host == "" || err1 != nil || err2 != nil {
+			continue
+		}
+		stats[host] = trafficStat{requests: requests, errors: errors}
+	}
+	return stats, nil
+}
+
+// statNumber ClickHouse 默认将 64 位整数输出为字符串
+func statNumber(v interface{}) (int64, error) {
+	switch n := v.(type) {
+	case float64:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case int:
+		return int64(n), nil
+	case string:
+		return strconv.ParseInt(n, 10, 64)
+	}
+	return 0, fmt.Errorf("not a number: %v", v)
+}
+
+// observe 用一个窗口的统计更新基线, 偏离超过阈值的指标生成预警
+//
+// 已有基线且平均请求数不低于 MinRequests 的主机在本窗口没有数据时按 0 请求处理, 以发现流量中断。
+func (d *anomalyDetector) observe(stats map[string]trafficStat, now time.Time) int {
+	d.store.mu.Lock()
+	defer d.store.mu.Unlock()
+
+	for host, b := range d.store.baselines {
+		if _, ok := stats[host]; !ok && b.Requests.EWMA >= float64(d.cfg.MinRequests) {
+			stats[host] = trafficStat{}
+		}
+	}
+
+	raised := 0
+	window := PreAlert{WindowStart: now.Add(-d.interval), WindowEnd: now, DetectedAt: now}
+	for host, st := range stats {
+		b, ok := d.store.baselines[host]
+		if !ok {
+			b = &hostBaseline{}
+			d.store.baselines[host] = b
+		}
+		b.UpdatedAt = now
+
+		requests := float64(st.requests)
+		expected := b.Requests.EWMA
+		score, ready := b.Requests.observe(requests, d.cfg.Alpha, d.cfg.MinSamples, math.Max(1, 0.05*expected))
+		if ready && math.Abs(score) >= d.cfg.Threshold {
+			a := window
+			a.Host, a.Metric, a.Value, a.Expected, a.Score = host, MetricRequests, requests, expected, score
+			a.Requests, a.Errors, a.Direction = st.requests, st.errors, "spike"
+			if score < 0 {
+				a.Direction = "drop"
+			}
+			if d.store.raiseLocked(&a) {
+				raised++
+			}
+		}
+
+		// 请求过少时错误率波动大, 不计入基线
+		if st.requests < int64(d.cfg.MinRequests) {
+			continue
+		}
+		rate := float64(st.errors) / float64(st.requests)
+		expected = b.ErrorRate.EWMA
+		score, ready = b.ErrorRate.observe(rate, d.cfg.Alpha, d.cfg.MinSamples, 0.005)
+		if ready && score >= d.cfg.Threshold {
+			a := window
+			a.Host, a.Metric, a.Value, a.Expected, a.Score = host, MetricErrorRate, rate, expected, score
+			a.Requests, a.Errors, a.Direction = st.requests, st.errors, "spike"
+			if d.store.raiseLocked(&a) {
+				raised++
+			}
+		}
+	}
+
+	d.store.pruneLocked(now)
+	d.store.saveLocked()
+	return raised
+}
+
+// observe 计算 x 相对 EWMA 的残差的稳健 z-score, 然后将 x 计入基线
+//
+// 样本数达到 minSamples 前只积累基线, ready 为 false; floor 为残差尺度的下限, 避免平稳序列的微小波动被放大。
+func (m *metricBaseline) observe(x, alpha float64, minSamples int, floor float64) (score float64, ready bool) {
+	if m.Samples == 0 {
+		m.EWMA = x
+		m.Samples = 1
+		return 0, false
+	}
+
+	r := x - m.EWMA
+	if m.Samples >= minSamples {
+		ready = true
+		score = robustZ(r, m.Residuals, floor)
+	}
+	m.Residuals = append(m.Residuals, r)
+	if len(m.Residuals) > maxAnomalyResiduals {
+		m.Residuals = m.Residuals[len(m.Residuals)-maxAnomalyResiduals:]
+	}
+	m.EWMA = alpha*x + (1-alpha)*m.EWMA
+	m.Samples++
+	return score, ready
+}
+
+// robustZ 以历史残差的中位数和 MAD 标准化 r
+func robustZ(r float64, history []float64, floor float64) float64 {
+	if len(history) == 0 {
+		return 0
+	}
+	med := median(history)
+	deviations := make([]float64, len(history))
+	for i, h := range history {
+		deviations[i] = math.Abs(h - med)
+	}
+	// 1.4826 * MAD 在正态分布下是标准差的一致估计
+	scale := math.Max(1.4826*median(deviations), floor)
+	return (r - med) / scale
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// preAlertQueries anomaly 数据源的内置查询, 模板内容为查询类型而非 SQL
+var preAlertQueries = map[string]string{
+	"pending_anomalies": "claim",
+	"anomalies_by_host": "by_host",
+}
+
+// preAlertSource 以 query_data 数据源的形式提供统计预警, 名称为 anomaly
+type preAlertSource struct {
+	store *anomalyStore
+}
+
+// Driver 数据源类型
+func (preAlertSource) Driver() string {
+	return "anomaly"
+}
+
+// Queries 可用的查询
+func (preAlertSource) Queries() map[string]string {
+	return preAlertQueries
+}
+
+// Query claim 按 batch_size 领取待研判预警; by_host 查询 host 主机最近 30 条预警
+func (src preAlertSource) Query(_ context.Context, query string, params map[string]string) ([]string, [][]interface{}, error) {
+	var alerts []*PreAlert
+	switch query {
+	case "claim":
+		n := 5
+		if v := params["batch_size"]; v != "" {
+			var err error
+			if n, err = strconv.Atoi(v); err != nil || n <= 0 {
+				return nil, nil, fmt.Errorf("invalid batch_size %q", v)
+			}
+		}
+		alerts = src.store.claim(n, time.Now())
+	case "by_host":
+		host := params["host"]
+		if host == "" {
+			return nil, nil, fmt.Errorf("missing parameter: host")
+		}
+		alerts = src.store.byHost(host, 30)
+	default:
+		return nil, nil, fmt.Errorf("anomaly source only supports sql_id: pending_anomalies, anomalies_by_host")
+	}
+
+	columns := []string{"id", "host", "metric", "direction", "value", "expected", "score", "requests", "errors", "windows", "window_start", "window_end"}
+	rows := make([][]interface{}, 0, len(alerts))
+	for _, a := range alerts {
+		rows = append(rows, []interface{}{
+			a.ID, a.Host, a.Metric, a.Direction, roundStat(a.Value), roundStat(a.Expected), roundStat(a.Score),
+			a.Requests, a.Errors, a.Windows, a.WindowStart.Format(time.RFC3339), a.WindowEnd.Format(time.RFC3339),
+		})
+	}
+	return columns, rows, nil
+}
+
+// roundStat 保留 4 位小数, 便于阅读
+func roundStat(v float64) float64 {
+	return math.Round(v*1e4) / 1e4
+}
+
+// Close 无需释放资源
+func (preAlertSource) Close() error {
+	return nil
+}
@@ -0,0 +1,120 @@
+package secops
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestAnomalyDetector(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "anomaly.json")
+	store := newAnomalyStore()
+	if err := store.load(path); err != nil {
+		t.Fatal(err)
+	}
+	d, err := newAnomalyDetector(config.AnomalyConfig{MinSamples: 6}, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 稳定的基线: shop 约 1000 请求、1% 错误, api 约 200 请求
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	noise := []int64{0, 12, -8, 5, -15, 9, -3, 7}
+	for i := 0; i < 10; i++ {
+		now = now.Add(d.interval)
+		n := noise[i%len(noise)]
+		if raised := d.observe(map[string]trafficStat{
+			"shop.example.com": {requests: 1000 + n, errors: 10},
+			"api.example.com":  {requests: 200 + n/2, errors: 2},
+		}, now); raised != 0 {
+			t.Fatalf("window %d raised %d pre-alerts on normal traffic", i, raised)
+		}
+	}
+
+	// shop 请求量和错误率同时突增, api 没有数据视为流量中断
+	now = now.Add(d.interval)
+	if raised := d.observe(map[string]trafficStat{"shop.example.com": {requests: 3000, errors: 900}}, now); raised != 3 {
+		t.Fatalf("raised = %d, want 3", raised)
+	}
+	alerts := store.byHost("shop.example.com", 10)
+	if len(alerts) != 2 {
+		t.Fatalf("shop alerts = %+v", alerts)
+	}
+	for _, a := range alerts {
+		if a.Direction != "spike" || a.Score < d.cfg.Threshold || a.Requests != 3000 || !a.WindowEnd.Equal(now) {
+			t.Errorf("alert = %+v", a)
+		}
+	}
+	if drop := store.byHost("api.example.com", 10); len(drop) != 1 || drop[0].Direction != "drop" || drop[0].Metric != MetricRequests || drop[0].Score >= 0 {
+		t.Errorf("drop alerts = %+v", drop)
+	}
+
+	// 持续偏离时更新已有预警, 不重复入队
+	now = now.Add(d.interval)
+	if raised := d.observe(map[string]trafficStat{"shop.example.com": {requests: 5000, errors: 2500}}, now); raised != 0 {
+		t.Errorf("repeated raised = %d, want 0", raised)
+	}
+	if store.pendingCount() != 3 {
+		t.Errorf("pending = %d, want 3", store.pendingCount())
+	}
+
+	// 通过 anomaly 数据源按偏离程度领取, 领取后不再返回
+	src := preAlertSource{store: store}
+	columns, rows, err := src.Query(context.Background(), "claim", map[string]string{"batch_size": "2"})
+	if err != nil || len(rows) != 2 || columns[1] != "host" || rows[0][1] != "shop.example.com" || rows[0][9] != 2 {
+		t.Fatalf("claim = %v %v, %v", columns, rows, err)
+	}
+	if _, rows, _ := src.Query(context.Background(), "claim", nil); len(rows) != 1 {
+		t.Errorf("second claim = %v", rows)
+	}
+	if _, _, err := src.Query(context.Background(), "by_host", nil); err == nil {
+		t.Error("by_host without host should fail")
+	}
+
+	// 基线和预警持久化
+	reloaded := newAnomalyStore()
+	if err := reloaded.load(path); err != nil {
+		t.Fatal(err)
+	}
+	if b := reloaded.baselines["shop.example.com"]; b == nil || b.Requests.Samples != 12 || len(reloaded.alerts) != 3 {
+		t.Errorf("reloaded baselines = %+v, alerts = %d", b, len(reloaded.alerts))
+	}
+}
+
+func TestAnomalyDetect(t *testing.T) {
+	d, err := newAnomalyDetector(config.AnomalyConfig{Interval: "10m"}, newAnomalyStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]string
+	query := func(_ context.Context, source, sqlID string, params map[string]string) ([]string, [][]interface{}, error) {
+		got = params
+		if sqlID != defaultAnomalySQLID {
+			t.Errorf("sql_id = %s", sqlID)
+		}
+		// ClickHouse JSONCompact 将 UInt64 输出为字符串
+		return []string{"host", "requests", "errors"}, [][]interface{}{{"shop.example.com", "1200", "30"}}, nil
+	}
+	if _, err := d.detect(context.Background(), query); err != nil {
+		t.Fatal(err)
+	}
+	if got["window_seconds"] != "600" || d.store.baselines["shop.example.com"].Requests.EWMA != 1200 {
+		t.Errorf("params = %v, baseline = %+v", got, d.store.baselines["shop.example.com"])
+	}
+
+	missing := func(context.Context, string, string, map[string]string) ([]string, [][]interface{}, error) {
+		return []string{"host", "count"}, nil, nil
+	}
+	if _, err := d.detect(context.Background(), missing); err == nil {
+		t.Error("expected error for missing columns")
+	}
+
+	for _, cfg := range []config.AnomalyConfig{{Interval: "30s"}, {Interval: "soon"}, {Alpha: 1.5}, {Threshold: -1}} {
+		if _, err := newAnomalyDetector(cfg, newAnomalyStore()); err == nil {
+			t.Errorf("%+v: expected error", cfg)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package secops
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// 负责人和评论的长度上限 (字符数)
+const (
+	maxAssigneeLength = 64
+	maxCommentLength  = 4000
+)
+
+// Comment 提案下的调查记录
+type Comment struct {
+	ID     string    `json:"id"`
+	Text   string    `json:"text"`
+	Author Actor     `json:"author"`
+	At     time.Time `json:"at"`
+}
+
+// Assign 指派提案负责人, assignee 为空表示取消指派; 已决策的提案也可以指派以便跟进
+func (s *ProposalService) Assign(id, assignee string, by Actor) (*Proposal, error) {
+	assignee = strings.TrimSpace(assignee)
+	if utf8.RuneCountInString(assignee) > maxAssigneeLength {
+		return nil, fmt.Errorf("assignee must not exceed %d characters", maxAssigneeLength)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.lookupLocked(id)
+	if !ok {
+		return nil, fmt.Errorf("proposal not found: %s", id)
+	}
+	if p.Assignee == assignee {
+		return p, nil
+	}
+
+	from := p.Assignee
+	now := time.Now()
+	p.Assignee = assignee
+	p.UpdatedAt = now
+	s.changed(p.ID)
+
+	logger.InfoCF("secops", "Proposal assigned",
+		map[string]interface{}{
+			"id":       p.ID,
+			"type":     p.Type,
+			"assignee": assignee,
+			"previous": from,
+			"by":       by.Name,
+		})
+
+	s.audit.record(ProposalEvent{
+		ProposalID: p.ID,
+		Action:     AuditAssigned,
+		Actor:      by,
+		Detail:     fmt.Sprintf("assignee: %q → %q", from, assignee),
+		At:         now,
+	})
+	return p, nil
+}
+
+// AddComment 在提案下追加评论, 记录决策之外的调查过程
+func (s *ProposalService) AddComment(id, text string, by Actor) (*Comment, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, fmt.Errorf("comment text is required")
+	}
+	if n := utf8.RuneCountInString(text); n > maxCommentLength {
+		return nil, fmt.Errorf("comment is %d characters, limit is %d", n, maxCommentLength)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.lookupLocked(id)
+	if !ok {
+		return nil, fmt.Errorf("proposal not found: %s", id)
+	}
+
+	now := time.Now()
+	c := Comment{ID: uuid.New().String(), Text: text, Author: by, At: now}
+	p.Comments = append(p.Comments, c)
+	p.UpdatedAt = now
+	s.changed(p.ID)
+
+	logger.InfoCF("secops", "Proposal commented",
+		map[string]interface{}{
+			"id":     p.ID,
+			"type":   p.Type,
+			"length": utf8.RuneCountInString(text),
+			"by":     by.Name,
+		})
+
+	s.audit.record(ProposalEvent{
+		ProposalID: p.ID,
+		Action:     AuditCommented,
+		Actor:      by,
+		Detail:     truncateText(text),
+		At:         now,
+	})
+	return &c, nil
+}
+
+// Comments 获取提案的评论, 按时间顺序
+func (s *ProposalService) Comments(id string) ([]Comment, bool) {
+	p, ok := s.Get(id)
+	if !ok {
+		return nil, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Comment(nil), p.Comments...), true
+}
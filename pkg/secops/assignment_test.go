@@ -0,0 +1,59 @@
+package secops
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProposalAssignmentAndComments(t *testing.T) {
+	s := NewProposalService()
+	a := s.Create(NewProposal("risk", "撞库攻击", "", nil))
+	b := s.Create(NewProposal("weak", "弱口令", "", nil))
+	alice := Actor{Name: "password:alice", Via: ViaDebugUI}
+
+	if _, err := s.Assign(a, " alice ", alice); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Assign("missing", "bob", alice); err == nil {
+		t.Error("expected error for unknown proposal")
+	}
+	if _, err := s.Assign(a, strings.Repeat("x", maxAssigneeLength+1), alice); err == nil {
+		t.Error("expected error for long assignee")
+	}
+
+	page, _, _ := s.GetFiltered(ProposalFilter{Assignee: "alice"})
+	if len(page) != 1 || page[0].ID != a {
+		t.Errorf("assignee filter = %v", page)
+	}
+	page, _, _ = s.GetFiltered(ProposalFilter{Assignee: UnassignedFilter})
+	if len(page) != 1 || page[0].ID != b {
+		t.Errorf("unassigned filter = %v", page)
+	}
+
+	if _, err := s.AddComment(a, "   ", alice); err == nil {
+		t.Error("expected error for empty comment")
+	}
+	if _, err := s.AddComment(a, strings.Repeat("长", maxCommentLength+1), alice); err == nil {
+		t.Error("expected error for long comment")
+	}
+	c, err := s.AddComment(a, "已联系业务方确认, 该 IP 为外部扫描器", alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	comments, ok := s.Comments(a)
+	if !ok || len(comments) != 1 || comments[0].ID != c.ID || comments[0].Author != alice {
+		t.Errorf("comments = %+v", comments)
+	}
+
+	// 取消指派, 审计记录包含指派和评论
+	if p, _ := s.Assign(a, "", alice); p.Assignee != "" {
+		t.Errorf("assignee after unassign = %q", p.Assignee)
+	}
+	var actions []string
+	for _, ev := range s.History(a) {
+		actions = append(actions, ev.Action)
+	}
+	if got := strings.Join(actions, ","); got != "created,assigned,commented,assigned" {
+		t.Errorf("audit actions = %s", got)
+	}
+}
@@ -0,0 +1,262 @@
+package secops
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// attackTechniqueID ATT&CK 技术编号, 如 T1190 或子技术 T1059.004
+var attackTechniqueID = regexp.MustCompile(`^T\d{4}(\.\d{3})?$`)
+
+// AttackTactic ATT&CK 企业矩阵的战术
+type AttackTactic struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// attackTactics 企业矩阵战术, 按攻击链顺序排列, 热力图按此顺序显示列
+var attackTactics = []AttackTactic{
+	{"TA0043", "Reconnaissance"},
+	{"TA0042", "Resource Development"},
+	{"TA0001", "Initial Access"},
+	{"TA0002", "Execution"},
+	{"TA0003", "Persistence"},
+	{"TA0004", "Privilege Escalation"},
+	{"TA0005", "Defense Evasion"},
+	{"TA0006", "Credential Access"},
+	{"TA0007", "Discovery"},
+	{"TA0008", "Lateral Movement"},
+	{"TA0009", "Collection"},
+	{"TA0011", "Command and Control"},
+	{"TA0010", "Exfiltration"},
+	{"TA0040", "Impact"},
+}
+
+// attackTechnique 内置的技术名称和所属战术
+type attackTechnique struct {
+	name    string
+	tactics []string
+}
+
+// attackTechniques 分类规则和常见研判结论涉及的技术; 不在表中的技术仍可标注, 但无法推导战术
+var attackTechniques = map[string]attackTechnique{
+	"T1595":     {"Active Scanning", []string{"TA0043"}},
+	"T1595.002": {"Vulnerability Scanning", []string{"TA0043"}},
+	"T1190":     {"Exploit Public-Facing Application", []string{"TA0001"}},
+	"T1133":     {"External Remote Services", []string{"TA0001", "TA0003"}},
+	"T1078":     {"Valid Accounts", []string{"TA0001", "TA0003", "TA0004", "TA0005"}},
+	"T1059":     {"Command and Scripting Interpreter", []string{"TA0002"}},
+	"T1059.004": {"Unix Shell", []string{"TA0002"}},
+	"T1609":     {"Container Administration Command", []string{"TA0002"}},
+	"T1610":     {"Deploy Container", []string{"TA0002", "TA0005"}},
+	"T1505.003": {"Web Shell", []string{"TA0003"}},
+	"T1136":     {"Create Account", []string{"TA0003"}},
+	"T1053.003": {"Cron", []string{"TA0002", "TA0003", "TA0004"}},
+	"T1098":     {"Account Manipulation", []string{"TA0003", "TA0004"}},
+	"T1068":     {"Exploitation for Privilege Escalation", []string{"TA0004"}},
+	"T1611":     {"Escape to Host", []string{"TA0004"}},
+	"T1070":     {"Indicator Removal", []string{"TA0005"}},
+	"T1562.001": {"Disable or Modify Tools", []string{"TA0005"}},
+	"T1110":     {"Brute Force", []string{"TA0006"}},
+	"T1552":     {"Unsecured Credentials", []string{"TA0006"}},
+	"T1552.007": {"Container API", []string{"TA0006"}},
+	"T1083":     {"File and Directory Discovery", []string{"TA0007"}},
+	"T1613":     {"Container and Resource Discovery", []string{"TA0007"}},
+	"T1021.004": {"SSH", []string{"TA0008"}},
+	"T1530":     {"Data from Cloud Storage", []string{"TA0009"}},
+	"T1005":     {"Data from Local System", []string{"TA0009"}},
+	"T1071.001": {"Web Protocols", []string{"TA0011"}},
+	"T1567":     {"Exfiltration Over Web Service", []string{"TA0010"}},
+	"T1496":     {"Resource Hijacking", []string{"TA0040"}},
+	"T1485":     {"Data Destruction", []string{"TA0040"}},
+	"T1486":     {"Data Encrypted for Impact", []string{"TA0040"}},
+	"T1498":     {"Network Denial of Service", []string{"TA0040"}},
+}
+
+// attackRule 关键词分类规则: 提案类型匹配 (为空时不限) 且标题、摘要或详情包含任一关键词时标注技术
+type attackRule struct {
+	types     []string
+	keywords  []string
+	technique string
+}
+
+// attackRules Agent 未标注技术时的后处理分类规则, 关键词按小写匹配
+var attackRules = []attackRule{
+	{nil, []string{"sql注入", "sql injection", "sqli", "命令注入", "command injection", "远程代码执行",
+		"反序列化", "deserialization", "ssrf", "文件包含", "路径穿越", "path traversal", "xxe", "log4j"}, "T1190"},
+	{nil, []string{"扫描器", "扫描", "scanner", "nmap", "sqlmap", "nuclei", "masscan", "目录爆破"}, "T1595.002"},
+	{nil, []string{"webshell", "web shell", "一句话木马", "冰蝎", "哥斯拉", "蚁剑"}, "T1505.003"},
+	{nil, []string{"暴力破解", "爆破", "brute force", "撞库", "credential stuffing", "password spray", "密码喷洒"}, "T1110"},
+	{nil, []string{"挖矿", "xmrig", "cryptomin", "miner"}, "T1496"},
+	{nil, []string{"勒索", "ransom"}, "T1486"},
+	{nil, []string{"反弹shell", "反弹 shell", "reverse shell", "bash -i", "/dev/tcp/"}, "T1059.004"},
+	{nil, []string{"crontab", "计划任务"}, "T1053.003"},
+	{nil, []string{"清除日志", "删除日志", "history -c", "log cleared"}, "T1070"},
+	{nil, []string{"提权", "privilege escalation", "sudo", "suid"}, "T1068"},
+	{nil, []string{"数据外传", "数据泄露", "exfiltration", "拖库"}, "T1567"},
+	{[]string{"host"}, []string{"sshd", "ssh 登录", "ssh login"}, "T1021.004"},
+	{[]string{"host"}, []string{"新增用户", "useradd", "user added"}, "T1136"},
+	{[]string{"host"}, []string{"完整性", "integrity checksum", "rootkit"}, "T1562.001"},
+	{[]string{"k8s"}, []string{"exec", "进入 pod", "进入pod"}, "T1609"},
+	{[]string{"k8s"}, []string{"rolebinding", "clusterrole", "rbac"}, "T1098"},
+	{[]string{"k8s"}, []string{"secret"}, "T1552.007"},
+	{[]string{"k8s"}, []string{"privileged", "特权容器", "hostpath", "逃逸"}, "T1611"},
+	{[]string{"cloud"}, []string{"s3", "bucket", "public read", "公开读", "存储桶"}, "T1530"},
+	{[]string{"cloud"}, []string{"access key", "iam user", "iam role", "iam policy", "mfa", "root account"}, "T1078"},
+	{[]string{"cloud"}, []string{"firewall", "security group", "安全组", "0.0.0.0/0"}, "T1133"},
+}
+
+// classifyAttack 按关键词规则推断提案涉及的 ATT&CK 技术
+func classifyAttack(p *Proposal) []string {
+	var sb strings.Builder
+	sb.WriteString(p.Title + "\n" + p.Summary + "\n")
+	for _, v := range p.Details {
+		if s, ok := v.(string); ok {
+			sb.WriteString(s + "\n")
+		}
+	}
+	text := strings.ToLower(sb.String())
+
+	var out []string
+	for _, rule := range attackRules {
+		if len(rule.types) > 0 && !slices.Contains(rule.types, p.Type) {
+			continue
+		}
+		for _, kw := range rule.keywords {
+			if strings.Contains(text, kw) {
+				out = append(out, rule.technique)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// normalizeTechniques 去除空白、转为大写并去重, 编号格式不合法时返回错误
+func normalizeTechniques(ids []string) ([]string, error) {
+	var out []string
+	for _, id := range ids {
+		id = strings.ToUpper(strings.TrimSpace(id))
+		if id == "" {
+			continue
+		}
+		if !attackTechniqueID.MatchString(id) {
+			return nil, fmt.Errorf("invalid ATT&CK technique: %q", id)
+		}
+		if !slices.Contains(out, id) {
+			out = append(out, id)
+		}
+	}
+	return out, nil
+}
+
+// techniqueTactics 技术所属的战术, 未知的子技术按父技术推导
+func techniqueTactics(technique string) []string {
+	if t, ok := attackTechniques[technique]; ok {
+		return t.tactics
+	}
+	parent, _, _ := strings.Cut(technique, ".")
+	return attackTechniques[parent].tactics
+}
+
+// tagAttack 补全提案的 ATT&CK 标注: 未标注技术时按规则分类, 再由技术推导战术
+func tagAttack(p *Proposal) {
+	if len(p.Techniques) == 0 {
+		p.Techniques = classifyAttack(p)
+	}
+	for _, technique := range p.Techniques {
+		for _, tactic := range techniqueTactics(technique) {
+			if !slices.Contains(p.Tactics, tactic) {
+				p.Tactics = append(p.Tactics, tactic)
+			}
+		}
+	}
+	sort.Slice(p.Tactics, func(i, j int) bool {
+		return tacticOrder(p.Tactics[i]) < tacticOrder(p.Tactics[j])
+	})
+}
+
+// tacticOrder 战术在攻击链中的位置, 未知战术排在最后
+func tacticOrder(id string) int {
+	for i, t := range attackTactics {
+		if t.ID == id {
+			return i
+		}
+	}
+	return len(attackTactics)
+}
+
+// matchTechnique 提案技术是否命中筛选的技术; 父技术同时命中其子技术
+func matchTechnique(techniques []string, want string) bool {
+	for _, t := range techniques {
+		if t == want || strings.HasPrefix(t, want+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// AttackCoverageCell 热力图中的一格: 某战术下某技术的提案数
+type AttackCoverageCell struct {
+	Technique string `json:"technique"`
+	Name      string `json:"name,omitempty"`
+	Count     int    `json:"count"`
+}
+
+// AttackCoverageColumn 热力图中的一列: 一个战术及其下已出现的技术
+type AttackCoverageColumn struct {
+	AttackTactic
+	Count      int                  `json:"count"`
+	Techniques []AttackCoverageCell `json:"techniques"`
+}
+
+// AttackCoverage 统计提案的 ATT&CK 覆盖, 按战术顺序返回全部战术列, 列内按提案数降序;
+// 无法推导战术的技术归入 ID 为空的 "Unmapped" 列
+func AttackCoverage(proposals []*Proposal) []AttackCoverageColumn {
+	counts := make(map[string]map[string]int)
+	add := func(tactic, technique string) {
+		if counts[tactic] == nil {
+			counts[tactic] = make(map[string]int)
+		}
+		counts[tactic][technique]++
+	}
+	for _, p := range proposals {
+		for _, technique := range p.Techniques {
+			tactics := techniqueTactics(technique)
+			if len(tactics) == 0 {
+				add("", technique)
+			}
+			for _, tactic := range tactics {
+				add(tactic, technique)
+			}
+		}
+	}
+
+	columns := make([]AttackCoverageColumn, 0, len(attackTactics)+1)
+	for _, tactic := range append(slices.Clone(attackTactics), AttackTactic{Name: "Unmapped"}) {
+		col := AttackCoverageColumn{AttackTactic: tactic, Techniques: []AttackCoverageCell{}}
+		for technique, n := range counts[tactic.ID] {
+			col.Techniques = append(col.Techniques, AttackCoverageCell{
+				Technique: technique,
+				Name:      attackTechniques[technique].name,
+				Count:     n,
+			})
+			col.Count += n
+		}
+		sort.Slice(col.Techniques, func(i, j int) bool {
+			a, b := col.Techniques[i], col.Techniques[j]
+			if a.Count != b.Count {
+				return a.Count > b.Count
+			}
+			return a.Technique < b.Technique
+		})
+		if tactic.ID == "" && col.Count == 0 {
+			continue
+		}
+		columns = append(columns, col)
+	}
+	return columns
+}
@@ -0,0 +1,96 @@
+package secops
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestTagAttack(t *testing.T) {
+	cases := []struct {
+		typ, title, summary string
+		techniques          []string
+		wantTechniques      []string
+		wantTactics         []string
+	}{
+		// 未标注时按关键词分类
+		{"risk", "SQL注入攻击成功", "sqlmap 对 /login 发起注入", nil,
+			[]string{"T1190", "T1595.002"}, []string{"TA0043", "TA0001"}},
+		{"k8s", "default 命名空间中 exec 进入 Pod", "", nil, []string{"T1609"}, []string{"TA0002"}},
+		// 仅对匹配的类型生效
+		{"risk", "exec 调用", "", nil, nil, nil},
+		// Agent 已标注时保留, 未知子技术按父技术推导战术
+		{"host", "可疑登录", "sshd brute force", []string{"T1059.006"}, []string{"T1059.006"}, []string{"TA0002"}},
+	}
+	for _, c := range cases {
+		p := NewProposal(c.typ, c.title, c.summary, nil)
+		p.Techniques = c.techniques
+		tagAttack(p)
+		if !slices.Equal(p.Techniques, c.wantTechniques) || !slices.Equal(p.Tactics, c.wantTactics) {
+			t.Errorf("%s: techniques=%v tactics=%v, want %v %v", c.title, p.Techniques, p.Tactics, c.wantTechniques, c.wantTactics)
+		}
+	}
+}
+
+func TestProposalToolTechniques(t *testing.T) {
+	svc := &Service{config: &config.SecOpsConfig{}, proposalService: NewProposalService(), runs: newRunStore()}
+	tool := NewProposalTool(svc)
+
+	res := tool.Execute(context.Background(), map[string]interface{}{
+		"type": "host", "title": "反弹 shell", "summary": "bash -i",
+		"techniques": []interface{}{" t1059.004", "T1059.004", "T1071.001"},
+	})
+	if res.IsError {
+		t.Fatal(res.ForLLM)
+	}
+	p := svc.proposalService.GetAll()[0]
+	if !slices.Equal(p.Techniques, []string{"T1059.004", "T1071.001"}) || !slices.Equal(p.Tactics, []string{"TA0002", "TA0011"}) {
+		t.Errorf("techniques=%v tactics=%v", p.Techniques, p.Tactics)
+	}
+
+	res = tool.Execute(context.Background(), map[string]interface{}{
+		"type": "host", "title": "x", "summary": "", "techniques": []interface{}{"TA0001"},
+	})
+	if !res.IsError {
+		t.Error("expected error for tactic id passed as technique")
+	}
+}
+
+func TestAttackFilterAndCoverage(t *testing.T) {
+	s := NewProposalService()
+	for i, techniques := range [][]string{{"T1190"}, {"T1059.004", "T1190"}, {"T1059"}, nil, {"T9999"}} {
+		p := NewProposal("risk", "p", "", nil)
+		p.ID = "id" + string(rune('0'+i))
+		p.Techniques = techniques
+		s.Create(p)
+	}
+
+	page, total, err := s.GetFiltered(ProposalFilter{Techniques: []string{"T1059"}, Sort: "title"})
+	if err != nil || total != 2 || page[0].ID != "id1" || page[1].ID != "id2" {
+		t.Errorf("parent technique filter: total=%d err=%v", total, err)
+	}
+	if _, total, _ := s.GetFiltered(ProposalFilter{Techniques: []string{"T1059.004", "T1190"}}); total != 2 {
+		t.Errorf("any-of filter total = %d", total)
+	}
+	if _, _, err := s.GetFiltered(ProposalFilter{Techniques: []string{"sqli"}}); err == nil {
+		t.Error("expected error for invalid technique")
+	}
+
+	columns := AttackCoverage(s.GetAll())
+	counts := map[string]map[string]int{}
+	for _, col := range columns {
+		counts[col.ID] = map[string]int{}
+		for _, cell := range col.Techniques {
+			counts[col.ID][cell.Technique] = cell.Count
+		}
+	}
+	if len(columns) != len(attackTactics)+1 || columns[2].ID != "TA0001" || columns[2].Count != 2 {
+		t.Fatalf("columns = %+v", columns)
+	}
+	if counts["TA0001"]["T1190"] != 2 || counts["TA0002"]["T1059"] != 1 || counts["TA0002"]["T1059.004"] != 1 ||
+		counts[""]["T9999"] != 1 || len(counts["TA0040"]) != 0 {
+		t.Errorf("counts = %v", counts)
+	}
+}
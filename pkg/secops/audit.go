@@ -0,0 +1,290 @@
+package secops
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// 操作来源
+const (
+	ViaActivity = "activity" // 运营活动中由 Agent 创建
+	ViaChat     = "chat"     // 对话中由 Agent 创建
+	ViaDebugUI  = "debugui"  // 分析师在 Debug UI 中操作
+	ViaAPI      = "api"      // 脚本直接调用 HTTP API
+	ViaFeishu   = "feishu"   // 分析师在飞书消息卡片中操作
+	ViaImport   = "import"   // 历史数据导入
+	ViaSystem   = "system"   // 后台任务: 执行、重试、过期、归档、云安全发现同步
+)
+
+// Actor 提案变更的操作者
+type Actor struct {
+	Name string `json:"name,omitempty"` // 用户名、活动名或后台任务名
+	Via  string `json:"via"`            // 操作来源, 见 Via* 常量
+}
+
+// systemActor 后台任务操作者
+func systemActor(name string) Actor {
+	return Actor{Name: name, Via: ViaSystem}
+}
+
+// 审计动作
+const (
+	AuditCreated         = "created"
+	AuditImported        = "imported"
+	AuditAccepted        = "accepted"
+	AuditIgnored         = "ignored"
+	AuditResubmitted     = "resubmitted"
+	AuditAcknowledged    = "acknowledged"
+	AuditAssigned        = "assigned"
+	AuditCommented       = "commented"
+	AuditRegenerated     = "regenerated"
+	AuditExecuted        = "executed"
+	AuditExecutionFailed = "execution_failed"
+	AuditRetried         = "retried"
+	AuditExpired         = "expired"
+	AuditArchived        = "archived"
+	AuditCommitted       = "committed"
+	AuditCommitFailed    = "commit_failed"
+)
+
+// ProposalEvent 提案生命周期审计记录
+type ProposalEvent struct {
+	ProposalID string            `json:"proposalId"`
+	Action     string            `json:"action"`
+	Actor      Actor             `json:"actor"`
+	From       ProposalStatus    `json:"from,omitempty"` // 变更前的状态, 状态未变化时为空
+	To         ProposalStatus    `json:"to,omitempty"`   // 变更后的状态
+	Params     map[string]string `json:"params,omitempty"`
+	Reason     string            `json:"reason,omitempty"`
+	Detail     string            `json:"detail,omitempty"` // 补充信息: 决策模板、调用的 API、错误、归档文件等
+	At         time.Time         `json:"at"`
+}
+
+// proposalAudit 只追加的提案审计日志, 每行一条 JSON 记录; 提案归档删除后记录仍然保留
+type proposalAudit struct {
+	events   map[string][]ProposalEvent // 按提案 ID 索引, 按时间顺序
+	file     *os.File
+	path     string
+	onRecord func(ProposalEvent) // 每条记录追加后调用, 用于发布到消息总线
+	mu       sync.RWMutex
+}
+
+func newProposalAudit() *proposalAudit {
+	return &proposalAudit{events: make(map[string][]ProposalEvent)}
+}
+
+// open 加载已有记录并以追加方式打开日志文件; 末尾不完整的行 (写入中断) 被忽略
+func (a *proposalAudit) open(path string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+		line := 0
+		for scanner.Scan() {
+			line++
+			var ev ProposalEvent
+			if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+				logger.WarnCF("secops", "Skipping malformed audit record",
+					map[string]interface{}{
+						"path": path,
+						"line": line,
+					})
+				continue
+			}
+			a.events[ev.ProposalID] = append(a.events[ev.ProposalID], ev)
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	a.file = f
+	a.path = path
+	return nil
+}
+
+// record 追加一条审计记录
+func (a *proposalAudit) record(ev ProposalEvent) {
+	if ev.At.IsZero() {
+		ev.At = time.Now()
+	}
+	if ev.Actor.Via == "" {
+		ev.Actor.Via = ViaSystem
+	}
+
+	a.mu.Lock()
+	a.events[ev.ProposalID] = append(a.events[ev.ProposalID], ev)
+	onRecord := a.onRecord
+	if a.file != nil {
+		a.write(ev)
+	}
+	a.mu.Unlock()
+
+	if onRecord != nil {
+		onRecord(ev)
+	}
+}
+
+// write 写入日志文件, 调用方持有 a.mu
+func (a *proposalAudit) write(ev ProposalEvent) {
+	data, err := json.Marshal(ev)
+	if err == nil {
+		_, err = a.file.Write(append(data, '\n'))
+	}
+	if err != nil {
+		logger.ErrorCF("secops", "Failed to write audit record",
+			map[string]interface{}{
+				"path":  a.path,
+				"id":    ev.ProposalID,
+				"error": err.Error(),
+			})
+	}
+}
+
+// history 某提案的审计记录, 按时间顺序
+func (a *proposalAudit) history(id string) []ProposalEvent {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	result := make([]ProposalEvent, len(a.events[id]))
+	copy(result, a.events[id])
+	return result
+}
+
+// retentionRecords 全部审计记录, ID 为 <提案 ID>-<序号>, 参与归档
+func (a *proposalAudit) retentionRecords() []retentionRecord {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	records := make([]retentionRecord, 0)
+	for id, events := range a.events {
+		for i, ev := range events {
+			data, err := json.Marshal(ev)
+			if err != nil {
+				continue
+			}
+			records = append(records, retentionRecord{ID: auditRecordID(id, i), Time: ev.At, Data: data})
+		}
+	}
+	return records
+}
+
+func auditRecordID(proposalID string, i int) string {
+	return fmt.Sprintf("%s-%04d", proposalID, i)
+}
+
+// prune 删除已归档的审计记录并重写日志文件
+func (a *proposalAudit) prune(ids []string, archive string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	archived := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		archived[id] = true
+	}
+	for id, events := range a.events {
+		kept := make([]ProposalEvent, 0, len(events))
+		for i, ev := range events {
+			if !archived[auditRecordID(id, i)] {
+				kept = append(kept, ev)
+			}
+		}
+		if len(kept) == 0 {
+			delete(a.events, id)
+		} else {
+			a.events[id] = kept
+		}
+	}
+
+	if a.file == nil {
+		return
+	}
+	if err := a.rewriteLocked(); err != nil {
+		logger.ErrorCF("secops", "Failed to rewrite audit log",
+			map[string]interface{}{
+				"path":  a.path,
+				"error": err.Error(),
+			})
+	}
+}
+
+// rewriteLocked 按时间顺序重写日志文件并重新以追加方式打开, 调用方持有 a.mu
+func (a *proposalAudit) rewriteLocked() error {
+	events := make([]ProposalEvent, 0)
+	for _, evs := range a.events {
+		events = append(events, evs...)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].At.Before(events[j].At)
+	})
+
+	var buf bytes.Buffer
+	for _, ev := range events {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			continue
+		}
+		buf.Write(append(data, '\n'))
+	}
+	tmp := a.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, a.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	a.file.Close()
+	f, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		a.file = nil
+		return err
+	}
+	a.file = f
+	return nil
+}
+
+func (a *proposalAudit) close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Close()
+	a.file = nil
+	return err
+}
+
+// EnableAudit 将审计记录追加写入 path, 并加载已有记录
+func (s *ProposalService) EnableAudit(path string) error {
+	return s.audit.open(path)
+}
+
+// History 提案的生命周期审计记录, 按时间顺序; 提案已归档时仍可查询
+func (s *ProposalService) History(id string) []ProposalEvent {
+	return s.audit.history(id)
+}
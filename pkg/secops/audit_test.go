@@ -0,0 +1,129 @@
+package secops
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestProposalAuditTrail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secops", "proposal_audit.jsonl")
+	s := NewProposalService()
+	if err := s.EnableAudit(path); err != nil {
+		t.Fatal(err)
+	}
+	fail := true
+	s.SetExecutor(func(ctx context.Context, api string, params map[string]string) (string, error) {
+		if fail {
+			return "", errors.New("sheikah unavailable")
+		}
+		return `{"code": 0}`, nil
+	})
+
+	p := NewProposal("risk", "SQL 注入", "", map[string]interface{}{"host": "shop.example.com", "risk": "SQL注入"})
+	p.Binding = defaultBinding(p)
+	p.CreatedBy = &Actor{Name: "daily_risk_review", Via: ViaActivity}
+	id := s.Create(p)
+
+	analyst := Actor{Name: "password:alice", Via: ViaDebugUI}
+	if _, err := s.Acknowledge(id, time.Hour, "排查中", analyst); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Accept(id, DecisionRequest{Reason: "确认注入", Params: map[string]string{"host": "edited.example.com"}, By: analyst}); err != nil {
+		t.Fatal(err)
+	}
+	s.runQueued(context.Background())
+	fail = false
+	if _, err := s.RetryExecution(id, Actor{Name: "token", Via: ViaAPI}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		action string
+		via    string
+		from   ProposalStatus
+		to     ProposalStatus
+	}{
+		{AuditCreated, ViaActivity, "", ProposalStatusPending},
+		{AuditAcknowledged, ViaDebugUI, "", ""},
+		{AuditAccepted, ViaDebugUI, ProposalStatusPending, ProposalStatusAccepted},
+		{AuditExecutionFailed, ViaSystem, ProposalStatusAccepted, ProposalStatusExecutionFailed},
+		{AuditRetried, ViaAPI, "", ""},
+		{AuditExecuted, ViaSystem, ProposalStatusExecutionFailed, ProposalStatusAccepted},
+	}
+	check := func(events []ProposalEvent) {
+		t.Helper()
+		if len(events) != len(want) {
+			t.Fatalf("history = %+v", events)
+		}
+		for i, w := range want {
+			ev := events[i]
+			if ev.ProposalID != id || ev.Action != w.action || ev.Actor.Via != w.via || ev.From != w.from || ev.To != w.to {
+				t.Errorf("event %d = %+v, want %+v", i, ev, w)
+			}
+		}
+		accepted := events[2]
+		if accepted.Actor.Name != "password:alice" || accepted.Reason != "确认注入" || accepted.Params["host"] != "edited.example.com" {
+			t.Errorf("accepted event = %+v", accepted)
+		}
+	}
+	check(s.History(id))
+
+	// 重新加载后记录仍在, 提案删除后仍可查询
+	if err := s.audit.close(); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := NewProposalService()
+	if err := reloaded.EnableAudit(path); err != nil {
+		t.Fatal(err)
+	}
+	check(reloaded.History(id))
+	if len(reloaded.History("missing")) != 0 {
+		t.Error("unexpected history for unknown proposal")
+	}
+
+	// 记录只追加, 写入中断的行被跳过
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"proposalId":"` + id + `","act`)
+	f.Close()
+	reloaded.audit.close()
+	again := NewProposalService()
+	if err := again.EnableAudit(path); err != nil {
+		t.Fatal(err)
+	}
+	check(again.History(id))
+	again.audit.close()
+}
+
+func TestProposalAuditBackgroundActors(t *testing.T) {
+	s := NewProposalService()
+	s.SetTTL(map[string]time.Duration{"*": time.Hour})
+	now := time.Now()
+	p := NewProposal("weak", "弱口令", "", nil)
+	p.CreatedAt = now.Add(-2 * time.Hour)
+	id := s.Create(p)
+	s.Expire(now)
+
+	imported := NewProposal("risk", "历史风险", "", nil)
+	imported.ID = "hist-1"
+	s.importProposals([]*Proposal{imported}, Actor{Name: "old.csv", Via: ViaImport})
+
+	events := s.History(id)
+	if len(events) != 2 || events[0].Actor.Via != ViaSystem || events[1].Action != AuditExpired ||
+		events[1].Actor.Name != "janitor" || events[1].To != ProposalStatusExpired {
+		t.Errorf("expire history = %+v", events)
+	}
+	events = s.History("hist-1")
+	if len(events) != 1 || events[0].Action != AuditImported || events[0].Actor.Name != "old.csv" {
+		t.Errorf("import history = %+v", events)
+	}
+	if got, _ := s.Get("hist-1"); got.CreatedBy == nil || got.CreatedBy.Via != ViaImport {
+		t.Errorf("createdBy = %+v", got.CreatedBy)
+	}
+}
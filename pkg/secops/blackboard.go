@@ -0,0 +1,171 @@
+package secops
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/kvstore"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const (
+	// blackboardKeyPrefix 黑板条目在共享缓存中的键前缀, 键为 <prefix><namespace>:<key>
+	blackboardKeyPrefix = "secops:blackboard:"
+	// defaultBlackboardTTL 未指定有效期时条目保留的时长
+	defaultBlackboardTTL = 24 * time.Hour
+	// maxBlackboardTTL 条目有效期上限, 过期的结论不应继续影响研判
+	maxBlackboardTTL = 7 * 24 * time.Hour
+	// maxBlackboardValue 条目内容的字符数上限
+	maxBlackboardValue = 2000
+	// maxBlackboardKey 条目键的字符数上限
+	maxBlackboardKey = 256
+)
+
+// blackboardNamespace 命名空间: 小写字母、数字、_ - 和 ., 不含作为分隔符的冒号
+var blackboardNamespace = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+
+// ErrBlackboardNotFound 黑板条目不存在或已过期
+var ErrBlackboardNotFound = errors.New("blackboard entry not found")
+
+// BlackboardEntry 活动之间共享的中间结论, 如 "203.0.113.45 自 10:00 起持续扫描"
+type BlackboardEntry struct {
+	Namespace   string    `json:"namespace"`
+	Key         string    `json:"key"`
+	Value       string    `json:"value"`
+	Publisher   string    `json:"publisher"` // 发布的活动名, 对话中发布时为 chat
+	PublishedAt time.Time `json:"publishedAt"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+// PublishFinding 发布或覆盖黑板条目; ttl 为 0 时取默认有效期, 超过上限时截断
+func (s *Service) PublishFinding(ctx context.Context, namespace, key, value string, ttl time.Duration) (*BlackboardEntry, error) {
+	key = strings.TrimSpace(key)
+	value = strings.TrimSpace(value)
+	if err := validateBlackboardKey(namespace, key); err != nil {
+		return nil, err
+	}
+	if value == "" {
+		return nil, fmt.Errorf("value is required")
+	}
+	if n := len([]rune(value)); n > maxBlackboardValue {
+		return nil, fmt.Errorf("value too long (%d chars, max %d)", n, maxBlackboardValue)
+	}
+	if ttl < 0 {
+		return nil, fmt.Errorf("ttl must be positive")
+	}
+	if ttl == 0 {
+		ttl = defaultBlackboardTTL
+	}
+	if ttl > maxBlackboardTTL {
+		ttl = maxBlackboardTTL
+	}
+
+	publisher := activityFromContext(ctx)
+	if publisher == "" {
+		publisher = "chat"
+	}
+	now := time.Now()
+	entry := &BlackboardEntry{
+		Namespace:   namespace,
+		Key:         key,
+		Value:       value,
+		Publisher:   publisher,
+		PublishedAt: now,
+		ExpiresAt:   now.Add(ttl),
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.cache.Set(ctx, blackboardKeyPrefix+namespace+":"+key, data, ttl); err != nil {
+		return nil, fmt.Errorf("failed to publish finding: %w", err)
+	}
+
+	logger.InfoCF("secops", "Blackboard finding published",
+		map[string]interface{}{
+			"namespace": namespace,
+			"key":       key,
+			"publisher": publisher,
+			"ttl":       ttl.String(),
+		})
+	return entry, nil
+}
+
+// Finding 读取单个黑板条目
+func (s *Service) Finding(ctx context.Context, namespace, key string) (*BlackboardEntry, error) {
+	if err := validateBlackboardKey(namespace, key); err != nil {
+		return nil, err
+	}
+	data, err := s.cache.Get(ctx, blackboardKeyPrefix+namespace+":"+key)
+	if errors.Is(err, kvstore.ErrNotFound) {
+		return nil, ErrBlackboardNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entry BlackboardEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("invalid blackboard entry: %w", err)
+	}
+	return &entry, nil
+}
+
+// Findings 列出命名空间下的全部条目, namespace 为空时列出全部; 按发布时间倒序
+func (s *Service) Findings(ctx context.Context, namespace string) ([]BlackboardEntry, error) {
+	prefix := blackboardKeyPrefix
+	if namespace != "" {
+		if !blackboardNamespace.MatchString(namespace) {
+			return nil, fmt.Errorf("invalid namespace %q", namespace)
+		}
+		prefix += namespace + ":"
+	}
+	keys, err := s.cache.Keys(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]BlackboardEntry, 0, len(keys))
+	for _, k := range keys {
+		data, err := s.cache.Get(ctx, k)
+		if err != nil {
+			// 列出后到读取前过期
+			continue
+		}
+		var entry BlackboardEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].PublishedAt.After(entries[j].PublishedAt)
+	})
+	return entries, nil
+}
+
+// RemoveFinding 删除黑板条目, 如结论已失效
+func (s *Service) RemoveFinding(ctx context.Context, namespace, key string) error {
+	if _, err := s.Finding(ctx, namespace, key); err != nil {
+		return err
+	}
+	return s.cache.Delete(ctx, blackboardKeyPrefix+namespace+":"+key)
+}
+
+func validateBlackboardKey(namespace, key string) error {
+	if !blackboardNamespace.MatchString(namespace) {
+		return fmt.Errorf("invalid namespace %q (lowercase letters, digits, _ - and .)", namespace)
+	}
+	if key == "" {
+		return fmt.Errorf("key is required")
+	}
+	if len(key) > maxBlackboardKey {
+		return fmt.Errorf("key too long (max %d bytes)", maxBlackboardKey)
+	}
+	return nil
+}
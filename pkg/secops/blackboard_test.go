@@ -0,0 +1,71 @@
+package secops
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/kvstore"
+)
+
+func TestBlackboardTool(t *testing.T) {
+	svc := &Service{cache: kvstore.NewMemory()}
+	tool := NewBlackboardTool(svc)
+
+	// risk_analysis 发布, host_analysis 读取
+	scanning := withActivity(t.Context(), "risk_analysis")
+	res := tool.Execute(scanning, map[string]interface{}{
+		"action": "publish", "namespace": "scan", "key": "203.0.113.45",
+		"value": "自 10:00 起持续扫描 /admin 路径", "ttl": "2h",
+	})
+	if res.IsError {
+		t.Fatalf("publish: %s", res.ForLLM)
+	}
+	tool.Execute(scanning, map[string]interface{}{
+		"action": "publish", "namespace": "change", "key": "shop.example.com", "value": "发布窗口 14:00-15:00",
+	})
+
+	res = tool.Execute(withActivity(t.Context(), "host_analysis"), map[string]interface{}{"action": "read", "namespace": "scan"})
+	if res.IsError || !strings.Contains(res.ForLLM, "203.0.113.45: 自 10:00 起持续扫描") || !strings.Contains(res.ForLLM, "risk_analysis") || strings.Contains(res.ForLLM, "shop.example.com") {
+		t.Errorf("read namespace = %q", res.ForLLM)
+	}
+
+	entry, err := svc.Finding(t.Context(), "scan", "203.0.113.45")
+	if err != nil || entry.Publisher != "risk_analysis" || entry.ExpiresAt.Sub(entry.PublishedAt) != 2*time.Hour {
+		t.Errorf("entry = %+v, %v", entry, err)
+	}
+	all, _ := svc.Findings(t.Context(), "")
+	if len(all) != 2 {
+		t.Errorf("findings = %+v, want 2", all)
+	}
+
+	// 对话中发布, 有效期截断到上限
+	long, err := svc.PublishFinding(t.Context(), "host", "web-01", "维护中", 30*24*time.Hour)
+	if err != nil || long.Publisher != "chat" || long.ExpiresAt.Sub(long.PublishedAt) != maxBlackboardTTL {
+		t.Errorf("capped entry = %+v, %v", long, err)
+	}
+
+	for _, args := range []map[string]interface{}{
+		{"action": "publish", "namespace": "Scan:x", "key": "k", "value": "v"},
+		{"action": "publish", "namespace": "scan", "key": "", "value": "v"},
+		{"action": "publish", "namespace": "scan", "key": "k", "value": ""},
+		{"action": "publish", "namespace": "scan", "key": "k", "value": "v", "ttl": "soon"},
+		{"action": "publish", "namespace": "scan", "key": "k", "value": strings.Repeat("x", maxBlackboardValue+1)},
+		{"action": "clear", "namespace": "scan"},
+	} {
+		if res := tool.Execute(t.Context(), args); !res.IsError {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+
+	if res := tool.Execute(t.Context(), map[string]interface{}{"action": "remove", "namespace": "scan", "key": "203.0.113.45"}); res.IsError {
+		t.Fatalf("remove: %s", res.ForLLM)
+	}
+	if _, err := svc.Finding(t.Context(), "scan", "203.0.113.45"); !errors.Is(err, ErrBlackboardNotFound) {
+		t.Errorf("after remove err = %v", err)
+	}
+	if res := tool.Execute(t.Context(), map[string]interface{}{"action": "read", "namespace": "scan", "key": "203.0.113.45"}); res.IsError || !strings.Contains(res.ForLLM, "no finding") {
+		t.Errorf("read removed = %q", res.ForLLM)
+	}
+}
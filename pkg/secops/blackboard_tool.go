@@ -0,0 +1,130 @@
+package secops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// BlackboardTool 供各活动发布和读取共享的中间结论
+type BlackboardTool struct {
+	service *Service
+}
+
+// NewBlackboardTool 创建黑板工具
+func NewBlackboardTool(service *Service) *BlackboardTool {
+	return &BlackboardTool{service: service}
+}
+
+// Name 工具名称
+func (t *BlackboardTool) Name() string {
+	return "blackboard"
+}
+
+// Description 工具描述
+func (t *BlackboardTool) Description() string {
+	return `活动之间共享中间结论的黑板 (如 "203.0.113.45 自 10:00 起持续扫描"、"shop.example.com 正在发布变更")。
+研判前可读取相关命名空间, 了解其他活动已发现的情况; 得出对其他活动有用的结论时发布, 结论失效时删除。
+- action: publish 发布或覆盖, read 读取 (key 为空时列出命名空间下全部条目), remove 删除
+- namespace: 命名空间, 如 scan (扫描源)、host (主机状态)、change (变更窗口)
+- key: 条目键, 通常为 IP、主机名或用户 ID
+- value: 结论内容 (publish 时必填), 写明依据和时间
+- ttl: 有效期, 如 2h、30m, 默认 24h, 最长 7 天`
+}
+
+// Parameters 参数定义
+func (t *BlackboardTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type": "string",
+				"enum": []string{"publish", "read", "remove"},
+			},
+			"namespace": map[string]interface{}{
+				"type": "string",
+			},
+			"key": map[string]interface{}{
+				"type": "string",
+			},
+			"value": map[string]interface{}{
+				"type": "string",
+			},
+			"ttl": map[string]interface{}{
+				"type": "string",
+			},
+		},
+		"required": []string{"action", "namespace"},
+	}
+}
+
+// Execute 发布、读取或删除黑板条目
+func (t *BlackboardTool) Execute(ctx context.Context, args map[string]interface{}) *tools.ToolResult {
+	action, _ := args["action"].(string)
+	namespace, _ := args["namespace"].(string)
+	key, _ := args["key"].(string)
+	namespace = strings.TrimSpace(namespace)
+	key = strings.TrimSpace(key)
+
+	switch action {
+	case "publish":
+		value, _ := args["value"].(string)
+		var ttl time.Duration
+		if raw, _ := args["ttl"].(string); raw != "" {
+			d, err := time.ParseDuration(raw)
+			if err != nil || d <= 0 {
+				return tools.ErrorResult(fmt.Sprintf("invalid ttl: %q", raw))
+			}
+			ttl = d
+		}
+		entry, err := t.service.PublishFinding(ctx, namespace, key, value, ttl)
+		if err != nil {
+			return tools.ErrorResult(err.Error())
+		}
+		return tools.SilentResult(fmt.Sprintf("published %s/%s, expires %s", entry.Namespace, entry.Key, entry.ExpiresAt.Format(time.RFC3339)))
+
+	case "read":
+		if key != "" {
+			entry, err := t.service.Finding(ctx, namespace, key)
+			if errors.Is(err, ErrBlackboardNotFound) {
+				return tools.SilentResult(fmt.Sprintf("no finding for %s/%s", namespace, key))
+			}
+			if err != nil {
+				return tools.ErrorResult(err.Error())
+			}
+			return tools.SilentResult(formatFinding(entry))
+		}
+		entries, err := t.service.Findings(ctx, namespace)
+		if err != nil {
+			return tools.ErrorResult(err.Error())
+		}
+		if len(entries) == 0 {
+			return tools.SilentResult(fmt.Sprintf("no findings in %s", namespace))
+		}
+		var sb strings.Builder
+		for i := range entries {
+			sb.WriteString(formatFinding(&entries[i]))
+		}
+		return tools.SilentResult(sb.String())
+
+	case "remove":
+		if err := t.service.RemoveFinding(ctx, namespace, key); err != nil {
+			return tools.ErrorResult(err.Error())
+		}
+		return tools.SilentResult(fmt.Sprintf("removed %s/%s", namespace, key))
+
+	default:
+		return tools.ErrorResult(fmt.Sprintf("invalid action: %q", action))
+	}
+}
+
+// formatFinding 条目的单行文本
+func formatFinding(e *BlackboardEntry) string {
+	return fmt.Sprintf("- [%s] %s: %s (%s, %s 发布, %s 过期)\n",
+		e.Namespace, e.Key, e.Value, e.Publisher,
+		e.PublishedAt.Format("01-02 15:04"), e.ExpiresAt.Format("01-02 15:04"))
+}
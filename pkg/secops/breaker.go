@@ -0,0 +1,135 @@
+package secops
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/tools/secops"
+)
+
+const (
+	defaultBreakerThreshold = 5                // 默认熔断前的连续失败次数
+	defaultProbeInterval    = 30 * time.Second // 默认熔断期间的探测间隔
+)
+
+// breakerSettings 解析熔断配置, 返回熔断阈值 (0 表示不熔断) 和探测间隔
+func breakerSettings(cfg config.ClickHouseBreakerConfig) (int, time.Duration, error) {
+	threshold := cfg.FailureThreshold
+	switch {
+	case threshold == 0:
+		threshold = defaultBreakerThreshold
+	case threshold < 0:
+		threshold = 0
+	}
+
+	interval := defaultProbeInterval
+	if cfg.ProbeInterval != "" {
+		d, err := time.ParseDuration(cfg.ProbeInterval)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid probe_interval %q: %w", cfg.ProbeInterval, err)
+		}
+		if d < time.Second {
+			return 0, 0, fmt.Errorf("probe_interval must be at least 1s")
+		}
+		interval = d
+	}
+	return threshold, interval, nil
+}
+
+// ClickHouseBreaker ClickHouse 熔断器的当前状态
+func (s *Service) ClickHouseBreaker() (secops.BreakerState, bool) {
+	if s.breaker == nil {
+		return secops.BreakerState{}, false
+	}
+	return s.breaker.State(), true
+}
+
+// clickHouseDown ClickHouse 是否处于熔断中
+func (s *Service) clickHouseDown() bool {
+	return s.breaker != nil && !s.breaker.Allow()
+}
+
+// onBreakerChange 熔断或恢复时记录日志, 按配置生成 system 提案
+func (s *Service) onBreakerChange(st secops.BreakerState) {
+	if !st.Open {
+		logger.InfoC("secops", "ClickHouse recovered, circuit breaker closed; scheduled activities resume")
+		return
+	}
+
+	logger.WarnCF("secops", "ClickHouse circuit breaker opened, scheduled activities will be skipped until it recovers",
+		map[string]interface{}{
+			"failures": st.Failures,
+			"error":    st.LastError,
+		})
+
+	s.configMu.RLock()
+	raise := s.config.ClickHouse.Breaker.RaiseProposal
+	s.configMu.RUnlock()
+	if raise && s.proposalService != nil {
+		s.CreateProposal(breakerProposal(st))
+	}
+}
+
+// breakerProposal 熔断时提醒运维的 system 提案, 每次熔断一条
+func breakerProposal(st secops.BreakerState) *Proposal {
+	summary := fmt.Sprintf("ClickHouse 连续 %d 次查询失败, 已熔断。熔断期间调度的活动跳过执行, "+
+		"后台探测恢复后自动继续。\n\n- **最近错误**: %s", st.Failures, st.LastError)
+	p := NewProposal("system", "ClickHouse 不可用, 活动已暂停", summary, map[string]interface{}{
+		"component": "clickhouse",
+		"failures":  st.Failures,
+		"error":     st.LastError,
+	})
+	p.ID = "system-clickhouse-" + st.OpenedAt.Format("20060102150405")
+	p.Severity = SeverityHigh
+	p.CreatedAt = st.OpenedAt
+	return p
+}
+
+// runClickHouseProbe 熔断期间或有地址不可用时定期探测 ClickHouse, 成功后恢复
+func (s *Service) runClickHouseProbe() {
+	defer s.wg.Done()
+
+	for {
+		s.configMu.RLock()
+		_, interval, err := breakerSettings(s.config.ClickHouse.Breaker)
+		s.configMu.RUnlock()
+		if err != nil {
+			interval = defaultProbeInterval
+		}
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+		s.probeClickHouse(s.ctx)
+	}
+}
+
+// probeClickHouse 熔断中或有地址被标记为不可用时探测一次 ClickHouse 的所有地址
+func (s *Service) probeClickHouse(ctx context.Context) {
+	queryTool, _ := s.tools()
+	if queryTool == nil {
+		return
+	}
+	down := s.clickHouseDown()
+	if !down && !queryTool.Degraded() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, healthTimeout)
+	defer cancel()
+	if err := queryTool.Ping(ctx); err != nil {
+		logger.WarnCF("secops", "ClickHouse still unavailable",
+			map[string]interface{}{
+				"error": err.Error(),
+			})
+		return
+	}
+	if down {
+		s.breaker.Success()
+	}
+}
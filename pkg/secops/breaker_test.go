@@ -0,0 +1,78 @@
+package secops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/tools/secops"
+)
+
+func TestBreakerSettings(t *testing.T) {
+	if threshold, interval, err := breakerSettings(config.ClickHouseBreakerConfig{}); err != nil || threshold != defaultBreakerThreshold || interval != defaultProbeInterval {
+		t.Errorf("defaults = %d %v %v", threshold, interval, err)
+	}
+	if threshold, interval, _ := breakerSettings(config.ClickHouseBreakerConfig{FailureThreshold: -1, ProbeInterval: "10s"}); threshold != 0 || interval != 10*time.Second {
+		t.Errorf("disabled = %d %v", threshold, interval)
+	}
+	for _, v := range []string{"soon", "500ms"} {
+		if _, _, err := breakerSettings(config.ClickHouseBreakerConfig{ProbeInterval: v}); err == nil {
+			t.Errorf("%s: expected error", v)
+		}
+	}
+}
+
+func TestClickHouseBreaker(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "Ok.\n")
+	}))
+	defer srv.Close()
+
+	cfg := &config.SecOpsConfig{
+		ClickHouse: config.ClickHouseConfig{Breaker: config.ClickHouseBreakerConfig{RaiseProposal: true}},
+		Activities: map[string]config.ActivityConfig{
+			"risk_analysis": {Enabled: true, Schedule: "30m"},
+			"host_analysis": {Enabled: true, Schedule: "30m", IgnoreBreaker: true},
+		},
+	}
+	svc := &Service{config: cfg, proposalService: NewProposalService(), runs: newRunStore(), breaker: secops.NewCircuitBreaker(1)}
+	svc.ctx, svc.cancel = context.WithCancel(context.Background())
+	defer svc.cancel()
+	svc.breaker.OnStateChange(svc.onBreakerChange)
+	svc.queryTool = secops.NewSecOpsQueryDataTool(nil, srv.URL, "", "")
+
+	// 熔断时生成 system 提案
+	svc.breaker.Failure(errors.New("connection refused"))
+	proposals := svc.proposalService.GetAll()
+	if len(proposals) != 1 || proposals[0].Type != "system" || proposals[0].Severity != SeverityHigh {
+		t.Fatalf("proposals = %+v", proposals)
+	}
+
+	// 熔断期间调度的活动跳过, 声明不依赖 ClickHouse 的活动照常排队
+	risk := &Activity{Name: "risk_analysis", Config: &config.ActivityConfig{}}
+	svc.runScheduled(risk)
+	if n := svc.pool.skipCount("risk_analysis"); n != 1 {
+		t.Errorf("risk skipped = %d, want 1", n)
+	}
+	svc.pool.begin("host_analysis")
+	svc.runScheduled(&Activity{Name: "host_analysis", Config: &config.ActivityConfig{IgnoreBreaker: true}})
+	if skips := svc.pool.skipped(); len(skips) != 2 || skips[0].Activity != "host_analysis" || skips[0].Reason != SkipOverlap {
+		t.Errorf("skips = %+v", skips)
+	}
+
+	// 探测成功后恢复
+	svc.probeClickHouse(context.Background())
+	if svc.clickHouseDown() {
+		t.Error("breaker should close after a successful probe")
+	}
+	svc.pool.begin("risk_analysis")
+	svc.runScheduled(risk)
+	if skips := svc.pool.skipped(); len(skips) != 3 || skips[1].Reason != SkipClickHouseDown || skips[2].Reason != SkipOverlap {
+		t.Errorf("skips after recovery = %+v", skips)
+	}
+}
@@ -0,0 +1,85 @@
+package secops
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaxBulkDecisions 单次批量决策的最大提案数
+const MaxBulkDecisions = 500
+
+// BulkDecisionError 批量决策中某个提案未通过校验, 整批均未生效
+type BulkDecisionError struct {
+	ID  string
+	Err error
+}
+
+func (e *BulkDecisionError) Error() string {
+	return fmt.Sprintf("proposal %s: %v", e.ID, e.Err)
+}
+
+func (e *BulkDecisionError) Unwrap() error {
+	return e.Err
+}
+
+// DecideBulk 以相同的动作、参数和理由批量确认或忽略提案
+//
+// 所有提案在同一把锁内先全部校验, 任一失败时整批不生效并返回 BulkDecisionError;
+// 全部通过后一次写入并持久化, 绑定的 API 排入执行队列后立即返回, 由执行重试协程逐个执行。
+// 执行失败记录在各自的 Execution 中, 不回滚决策。重复的 ID 只处理一次
+func (s *ProposalService) DecideBulk(ids []string, action string, req DecisionRequest) ([]*Proposal, error) {
+	if action != ActionAccept && action != ActionIgnore {
+		return nil, fmt.Errorf("invalid action: %s", action)
+	}
+
+	seen := make(map[string]bool, len(ids))
+	unique := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+	if len(unique) == 0 {
+		return nil, fmt.Errorf("no proposals selected")
+	}
+	if len(unique) > MaxBulkDecisions {
+		return nil, fmt.Errorf("too many proposals: %d (max %d)", len(unique), MaxBulkDecisions)
+	}
+
+	s.mu.Lock()
+	pending := make([]pendingDecision, 0, len(unique))
+	for _, id := range unique {
+		d, err := s.checkDecisionLocked(id, action, req)
+		if err != nil {
+			s.mu.Unlock()
+			return nil, &BulkDecisionError{ID: id, Err: err}
+		}
+		pending = append(pending, d)
+	}
+	status := decidedStatus(action)
+	now := time.Now()
+	var unbound []string
+	for _, d := range pending {
+		s.applyDecisionLocked(d, status, action, req, now)
+		if !s.queueExecutionLocked(d.p) {
+			unbound = append(unbound, d.p.ID)
+		}
+	}
+	s.changed(unique...)
+	s.mu.Unlock()
+	s.wakeExecutions()
+
+	// 无需执行的提案决策即完成, 其余在执行后由执行重试协程调用决策处理函数
+	for _, id := range unbound {
+		s.decided(id)
+	}
+	decided := make([]*Proposal, 0, len(unique))
+	for _, id := range unique {
+		if p, ok := s.Get(id); ok {
+			decided = append(decided, p)
+		}
+	}
+	return decided, nil
+}
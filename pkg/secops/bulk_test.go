@@ -0,0 +1,84 @@
+package secops
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDecideBulk(t *testing.T) {
+	s := NewProposalService()
+	s.SetRequireOverrideReason(true)
+	var calls []string
+	s.SetExecutor(func(ctx context.Context, api string, params map[string]string) (string, error) {
+		calls = append(calls, api+":"+params["note"])
+		return `{"code": 0}`, nil
+	})
+
+	var ids []string
+	for _, host := range []string{"a.example.com", "b.example.com"} {
+		p := NewProposal("weak", "弱口令", "", map[string]interface{}{"host": host})
+		p.Binding = defaultBinding(p)
+		p.Recommendation = ActionAccept
+		ids = append(ids, s.Create(p))
+	}
+	done := NewProposal("weak", "已处理", "", nil)
+	doneID := s.Create(done)
+	if err := s.Ignore(doneID, DecisionRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	s.runQueued(context.Background())
+	calls = nil
+
+	// 任一提案不可决策时整批不生效
+	_, err := s.DecideBulk(append([]string{ids[0]}, doneID, ids[1]), ActionAccept, DecisionRequest{})
+	var bulkErr *BulkDecisionError
+	if !errors.As(err, &bulkErr) || bulkErr.ID != doneID {
+		t.Fatalf("expected BulkDecisionError for %s, got %v", doneID, err)
+	}
+	_, err = s.DecideBulk(ids, ActionIgnore, DecisionRequest{})
+	if !errors.Is(err, ErrOverrideReasonRequired) {
+		t.Fatalf("expected ErrOverrideReasonRequired, got %v", err)
+	}
+	for _, id := range ids {
+		if got, _ := s.Get(id); got.Status != ProposalStatusPending {
+			t.Fatalf("proposal %s decided by failed batch: %s", id, got.Status)
+		}
+	}
+	if _, err := s.DecideBulk(ids, "delete", DecisionRequest{}); err == nil {
+		t.Error("expected error for invalid action")
+	}
+	if _, err := s.DecideBulk(nil, ActionAccept, DecisionRequest{}); err == nil {
+		t.Error("expected error for empty selection")
+	}
+
+	by := Actor{Name: "password:alice", Via: ViaDebugUI}
+	decided, err := s.DecideBulk(append(ids, ids[0]), ActionIgnore, DecisionRequest{Reason: "测试环境账号", By: by})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 决策立即生效, 绑定的 API 排队等待执行重试协程执行
+	if len(decided) != 2 || len(calls) != 0 {
+		t.Fatalf("decided=%d calls=%v", len(decided), calls)
+	}
+	for _, p := range decided {
+		if p.Status != ProposalStatusIgnored || p.Execution == nil || p.Execution.Status != ExecutionQueued {
+			t.Fatalf("proposal %s = %s %+v", p.ID, p.Status, p.Execution)
+		}
+	}
+	s.runQueued(context.Background())
+	if len(calls) != 2 || calls[0] != "ignore_weak:测试环境账号" {
+		t.Fatalf("calls=%v", calls)
+	}
+	for _, id := range ids {
+		p, _ := s.Get(id)
+		if p.Status != ProposalStatusIgnored || !p.Decision.Override || p.Execution == nil ||
+			p.Execution.Status != ExecutionSucceeded {
+			t.Errorf("proposal %s = %s %+v %+v", p.ID, p.Status, p.Decision, p.Execution)
+		}
+		events := s.History(p.ID)
+		if ev := events[len(events)-2]; ev.Action != AuditIgnored || ev.Actor != by {
+			t.Errorf("audit event = %+v", ev)
+		}
+	}
+}
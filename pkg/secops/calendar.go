@@ -0,0 +1,183 @@
+package secops
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+const dateLayout = "2006-01-02"
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Calendar 工作日历: 决定哪些日期/时段属于工作时间
+type Calendar struct {
+	Name          string
+	loc           *time.Location
+	workdays      map[time.Weekday]bool
+	holidays      map[string]bool
+	extraWorkdays map[string]bool
+	startMinute   int // 工作时段开始 (距零点分钟数)
+	endMinute     int // 工作时段结束, 等于 startMinute 时表示全天
+}
+
+// NewCalendar 根据配置创建工作日历
+func NewCalendar(name string, cfg config.CalendarConfig) (*Calendar, error) {
+	loc := time.Local
+	if cfg.Timezone != "" {
+		l, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("calendar %s: invalid timezone %q: %w", name, cfg.Timezone, err)
+		}
+		loc = l
+	}
+
+	c := &Calendar{
+		Name:          name,
+		loc:           loc,
+		workdays:      make(map[time.Weekday]bool),
+		holidays:      make(map[string]bool),
+		extraWorkdays: make(map[string]bool),
+	}
+
+	workdays := cfg.Workdays
+	if len(workdays) == 0 {
+		workdays = []string{"mon", "tue", "wed", "thu", "fri"}
+	}
+	for _, d := range workdays {
+		key := strings.ToLower(strings.TrimSpace(d))
+		if len(key) > 3 {
+			key = key[:3]
+		}
+		wd, ok := weekdayNames[key]
+		if !ok {
+			return nil, fmt.Errorf("calendar %s: invalid workday %q", name, d)
+		}
+		c.workdays[wd] = true
+	}
+
+	for _, d := range cfg.Holidays {
+		if _, err := time.Parse(dateLayout, d); err != nil {
+			return nil, fmt.Errorf("calendar %s: invalid holiday %q", name, d)
+		}
+		c.holidays[d] = true
+	}
+	for _, d := range cfg.ExtraWorkdays {
+		if _, err := time.Parse(dateLayout, d); err != nil {
+			return nil, fmt.Errorf("calendar %s: invalid extra workday %q", name, d)
+		}
+		c.extraWorkdays[d] = true
+	}
+
+	if cfg.BusinessHours != "" {
+		start, end, err := parseTimeRange(cfg.BusinessHours)
+		if err != nil {
+			return nil, fmt.Errorf("calendar %s: %w", name, err)
+		}
+		c.startMinute, c.endMinute = start, end
+	}
+
+	return c, nil
+}
+
+// parseTimeRange 解析 "09:00-18:00" 格式的时段, 返回距零点的分钟数
+func parseTimeRange(s string) (int, int, error) {
+	parts := strings.SplitN(s, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid time range %q, expected HH:MM-HH:MM", s)
+	}
+	start, err := parseClock(parts[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := parseClock(parts[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	if end <= start {
+		return 0, 0, fmt.Errorf("invalid time range %q: end must be after start", s)
+	}
+	return start, end, nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid clock %q, expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Location 日历所在时区
+func (c *Calendar) Location() *time.Location {
+	return c.loc
+}
+
+// IsBusinessDay 判断给定时间所在日期是否为工作日
+func (c *Calendar) IsBusinessDay(t time.Time) bool {
+	day := t.In(c.loc).Format(dateLayout)
+	if c.extraWorkdays[day] {
+		return true
+	}
+	if c.holidays[day] {
+		return false
+	}
+	return c.workdays[t.In(c.loc).Weekday()]
+}
+
+// InBusinessHours 判断给定时间是否处于工作时间
+func (c *Calendar) InBusinessHours(t time.Time) bool {
+	if !c.IsBusinessDay(t) {
+		return false
+	}
+	start, end := c.dayWindow(t)
+	return !t.Before(start) && t.Before(end)
+}
+
+// dayWindow 返回给定时间所在日期的工作时段
+func (c *Calendar) dayWindow(t time.Time) (time.Time, time.Time) {
+	local := t.In(c.loc)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, c.loc)
+	if c.startMinute == c.endMinute {
+		return midnight, midnight.AddDate(0, 0, 1)
+	}
+	return midnight.Add(time.Duration(c.startMinute) * time.Minute),
+		midnight.Add(time.Duration(c.endMinute) * time.Minute)
+}
+
+// BusinessDuration 计算 [from, to) 区间内的工作时长, 非工作时间不计时
+func (c *Calendar) BusinessDuration(from, to time.Time) time.Duration {
+	if !to.After(from) {
+		return 0
+	}
+
+	var total time.Duration
+	local := from.In(c.loc)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, c.loc)
+	for day.Before(to) {
+		if c.IsBusinessDay(day) {
+			start, end := c.dayWindow(day)
+			if start.Before(from) {
+				start = from
+			}
+			if end.After(to) {
+				end = to
+			}
+			if end.After(start) {
+				total += end.Sub(start)
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return total
+}
@@ -0,0 +1,72 @@
+package secops
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func newTestCalendar(t *testing.T) *Calendar {
+	t.Helper()
+	cal, err := NewCalendar("cn", config.CalendarConfig{
+		Timezone:      "Asia/Shanghai",
+		BusinessHours: "09:00-18:00",
+		Holidays:      []string{"2026-10-01"},
+		ExtraWorkdays: []string{"2026-10-10"},
+	})
+	if err != nil {
+		t.Fatalf("NewCalendar failed: %v", err)
+	}
+	return cal
+}
+
+func TestCalendar_IsBusinessDay(t *testing.T) {
+	cal := newTestCalendar(t)
+	loc := cal.Location()
+
+	tests := []struct {
+		name string
+		day  time.Time
+		want bool
+	}{
+		{"weekday", time.Date(2026, 10, 14, 10, 0, 0, 0, loc), true},
+		{"weekend", time.Date(2026, 10, 17, 10, 0, 0, 0, loc), false},
+		{"holiday", time.Date(2026, 10, 1, 10, 0, 0, 0, loc), false},
+		{"extra workday on saturday", time.Date(2026, 10, 10, 10, 0, 0, 0, loc), true},
+	}
+
+	for _, tt := range tests {
+		if got := cal.IsBusinessDay(tt.day); got != tt.want {
+			t.Errorf("%s: IsBusinessDay = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCalendar_BusinessDurationPausesOutsideHours(t *testing.T) {
+	cal := newTestCalendar(t)
+	loc := cal.Location()
+
+	// Friday 17:00 -> Monday 10:00: 1h on Friday + 1h on Monday
+	from := time.Date(2026, 10, 16, 17, 0, 0, 0, loc)
+	to := time.Date(2026, 10, 19, 10, 0, 0, 0, loc)
+
+	if got := cal.BusinessDuration(from, to); got != 2*time.Hour {
+		t.Errorf("BusinessDuration = %v, want 2h", got)
+	}
+}
+
+func TestNewCalendar_InvalidConfig(t *testing.T) {
+	cases := []config.CalendarConfig{
+		{Timezone: "Mars/Olympus"},
+		{Workdays: []string{"funday"}},
+		{BusinessHours: "18:00-09:00"},
+		{Holidays: []string{"10/01/2026"}},
+	}
+
+	for i, c := range cases {
+		if _, err := NewCalendar("bad", c); err == nil {
+			t.Errorf("case %d: expected error for %+v", i, c)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package secops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/tools/secops"
+)
+
+// defaultChaosDelay Sheikah 超时和 LLM 延迟的默认挂起时长
+const defaultChaosDelay = 30 * time.Second
+
+// errChaosDropped 注入的通知投递失败, 按普通网络错误处理, 会触发重试
+var errChaosDropped = errors.New("chaos: notification dropped")
+
+// chaos 故障注入, 按配置的比例让 ClickHouse 返回 500、Sheikah 超时、LLM 延迟响应、通知投递失败
+type chaos struct {
+	cfg            config.ChaosConfig
+	sheikahTimeout time.Duration
+	llmDelay       time.Duration
+	rand           func() float64 // 返回 [0, 1) 的随机数, 测试时替换
+}
+
+// newChaos 校验故障注入配置, 未启用时返回 nil
+func newChaos(cfg config.ChaosConfig) (*chaos, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	for name, rate := range map[string]float64{
+		"clickhouse_error_rate":  cfg.ClickHouseErrorRate,
+		"sheikah_timeout_rate":   cfg.SheikahTimeoutRate,
+		"llm_delay_rate":         cfg.LLMDelayRate,
+		"notification_drop_rate": cfg.NotificationDropRate,
+	} {
+		if rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("%s must be between 0 and 1, got %v", name, rate)
+		}
+	}
+
+	c := &chaos{cfg: cfg, sheikahTimeout: defaultChaosDelay, llmDelay: defaultChaosDelay, rand: rand.Float64}
+	for _, d := range []struct {
+		name  string
+		value string
+		dst   *time.Duration
+	}{
+		{"sheikah_timeout", cfg.SheikahTimeout, &c.sheikahTimeout},
+		{"llm_delay", cfg.LLMDelay, &c.llmDelay},
+	} {
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("invalid %s %q", d.name, d.value)
+		}
+		*d.dst = v
+	}
+	return c, nil
+}
+
+// hit 按比例抽样是否注入
+func (c *chaos) hit(rate float64) bool {
+	return rate > 0 && c.rand() < rate
+}
+
+// dropNotification 本次通知投递是否注入失败
+func (c *chaos) dropNotification() bool {
+	return c != nil && c.hit(c.cfg.NotificationDropRate)
+}
+
+// sleepContext 挂起 d, ctx 结束时提前返回其错误
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// chaosClickHouseTransport 按比例返回 ClickHouse 风格的 500 错误, 其余请求交给 next
+type chaosClickHouseTransport struct {
+	chaos *chaos
+	next  http.RoundTripper
+}
+
+func (t chaosClickHouseTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.chaos.hit(t.chaos.cfg.ClickHouseErrorRate) {
+		return t.next.RoundTrip(req)
+	}
+	logger.WarnC("secops", "Chaos: injected ClickHouse error")
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return &http.Response{
+		StatusCode: http.StatusInternalServerError,
+		Status:     http.StatusText(http.StatusInternalServerError),
+		Header:     http.Header{"Content-Type": []string{"text/plain"}},
+		Body:       io.NopCloser(strings.NewReader("Code: 1002. DB::Exception: chaos: injected failure. (UNKNOWN_EXCEPTION)")),
+		Request:    req,
+	}, nil
+}
+
+// chaosSheikahTransport 按比例挂起请求, 到时返回超时错误, 不发送到 Sheikah
+type chaosSheikahTransport struct {
+	chaos *chaos
+	next  http.RoundTripper
+}
+
+func (t chaosSheikahTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.chaos.hit(t.chaos.cfg.SheikahTimeoutRate) {
+		return t.next.RoundTrip(req)
+	}
+	logger.WarnCF("secops", "Chaos: injected Sheikah timeout",
+		map[string]interface{}{
+			"path":    req.URL.Path,
+			"timeout": t.chaos.sheikahTimeout.String(),
+		})
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	if err := sleepContext(req.Context(), t.chaos.sheikahTimeout); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("chaos: sheikah request timed out after %s: %w", t.chaos.sheikahTimeout, os.ErrDeadlineExceeded)
+}
+
+// chaosProvider 按比例延迟 LLM 响应
+type chaosProvider struct {
+	providers.LLMProvider
+	chaos *chaos
+}
+
+func (p *chaosProvider) delay(ctx context.Context) error {
+	if !p.chaos.hit(p.chaos.cfg.LLMDelayRate) {
+		return nil
+	}
+	logger.WarnCF("secops", "Chaos: delaying LLM response",
+		map[string]interface{}{
+			"delay": p.chaos.llmDelay.String(),
+		})
+	return sleepContext(ctx, p.chaos.llmDelay)
+}
+
+func (p *chaosProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]interface{}) (*providers.LLMResponse, error) {
+	if err := p.delay(ctx); err != nil {
+		return nil, err
+	}
+	return p.LLMProvider.Chat(ctx, messages, tools, model, options)
+}
+
+// ChatStream 保留原 provider 的流式输出; 不支持流式时一次性返回全部内容
+func (p *chaosProvider) ChatStream(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]interface{}, onDelta func(string)) (*providers.LLMResponse, error) {
+	if err := p.delay(ctx); err != nil {
+		return nil, err
+	}
+	if sp, ok := p.LLMProvider.(providers.StreamingProvider); ok {
+		return sp.ChatStream(ctx, messages, tools, model, options, onDelta)
+	}
+	resp, err := p.LLMProvider.Chat(ctx, messages, tools, model, options)
+	if err == nil && resp.Content != "" {
+		onDelta(resp.Content)
+	}
+	return resp, err
+}
+
+// initChaos 在 ClickHouse、Sheikah 传输层和 LLM provider 外层注入故障, 需在演示模式替换传输层之后调用
+func (s *Service) initChaos() {
+	if s.chaos == nil {
+		return
+	}
+	cfg := s.chaos.cfg
+	logger.WarnCF("secops", "Chaos fault injection is enabled, do not use in production",
+		map[string]interface{}{
+			"clickhouse_error_rate":  cfg.ClickHouseErrorRate,
+			"sheikah_timeout_rate":   cfg.SheikahTimeoutRate,
+			"llm_delay_rate":         cfg.LLMDelayRate,
+			"notification_drop_rate": cfg.NotificationDropRate,
+		})
+
+	s.wrapChaosTransports(s.queryTool, s.apiTool)
+	if cfg.LLMDelayRate > 0 {
+		s.agentLoop.WrapProvider(func(p providers.LLMProvider) providers.LLMProvider {
+			return &chaosProvider{LLMProvider: p, chaos: s.chaos}
+		})
+	}
+}
+
+// wrapChaosTransports 在 ClickHouse 和 Sheikah 传输层外层注入故障, 需在演示模式替换传输层之后调用
+func (s *Service) wrapChaosTransports(queryTool *secops.SecOpsQueryDataTool, apiTool *secops.SecOpsSheikahAPITool) {
+	if s.chaos == nil {
+		return
+	}
+	if s.chaos.cfg.ClickHouseErrorRate > 0 {
+		queryTool.SetTransport(chaosClickHouseTransport{chaos: s.chaos, next: queryTool.Transport()})
+	}
+	if s.chaos.cfg.SheikahTimeoutRate > 0 {
+		apiTool.SetTransport(chaosSheikahTransport{chaos: s.chaos, next: apiTool.Transport()})
+	}
+}
+
+// Chaos 是否启用了故障注入
+func (s *Service) Chaos() bool {
+	return s.chaos != nil
+}
@@ -0,0 +1,133 @@
+package secops
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// sequence 依次返回给定的随机数, 用于控制每次是否注入
+func sequence(values ...float64) func() float64 {
+	var mu sync.Mutex
+	return func() float64 {
+		mu.Lock()
+		defer mu.Unlock()
+		v := values[0]
+		if len(values) > 1 {
+			values = values[1:]
+		}
+		return v
+	}
+}
+
+func TestNewChaos(t *testing.T) {
+	if c, err := newChaos(config.ChaosConfig{ClickHouseErrorRate: 1}); c != nil || err != nil {
+		t.Errorf("disabled chaos = %v, %v", c, err)
+	}
+	for _, cfg := range []config.ChaosConfig{
+		{Enabled: true, SheikahTimeoutRate: 1.5},
+		{Enabled: true, NotificationDropRate: -0.1},
+		{Enabled: true, LLMDelay: "soon"},
+	} {
+		if _, err := newChaos(cfg); err == nil {
+			t.Errorf("newChaos(%+v) should fail", cfg)
+		}
+	}
+	c, err := newChaos(config.ChaosConfig{Enabled: true, SheikahTimeout: "5s"})
+	if err != nil || c.sheikahTimeout != 5*time.Second || c.llmDelay != defaultChaosDelay {
+		t.Errorf("newChaos = %+v, %v", c, err)
+	}
+}
+
+func TestChaosTransports(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer srv.Close()
+
+	c := &chaos{
+		cfg:            config.ChaosConfig{ClickHouseErrorRate: 0.5, SheikahTimeoutRate: 0.5},
+		sheikahTimeout: 10 * time.Millisecond,
+		rand:           sequence(0.1, 0.9, 0.1, 0.9),
+	}
+	ch := &http.Client{Transport: chaosClickHouseTransport{chaos: c, next: http.DefaultTransport}}
+	resp, err := ch.Get(srv.URL)
+	if err != nil || resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("injected ClickHouse response = %v, %v", resp, err)
+	}
+	if resp, err := ch.Get(srv.URL); err != nil || resp.StatusCode != http.StatusOK || calls != 1 {
+		t.Fatalf("passthrough = %v, %v (calls %d)", resp, err, calls)
+	}
+
+	sheikah := &http.Client{Transport: chaosSheikahTransport{chaos: c, next: http.DefaultTransport}}
+	if _, err := sheikah.Get(srv.URL); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("injected Sheikah error = %v, want a timeout", err)
+	}
+	if _, err := sheikah.Get(srv.URL); err != nil || calls != 2 {
+		t.Errorf("passthrough = %v (calls %d)", err, calls)
+	}
+}
+
+type stubProvider struct{ calls int }
+
+func (p *stubProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]interface{}) (*providers.LLMResponse, error) {
+	p.calls++
+	return &providers.LLMResponse{Content: "ok"}, nil
+}
+
+func (p *stubProvider) GetDefaultModel() string { return "stub" }
+
+func TestChaosProvider(t *testing.T) {
+	inner := &stubProvider{}
+	p := &chaosProvider{LLMProvider: inner, chaos: &chaos{
+		cfg:      config.ChaosConfig{LLMDelayRate: 1},
+		llmDelay: time.Hour,
+		rand:     sequence(0),
+	}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.Chat(ctx, nil, nil, "", nil); !errors.Is(err, context.DeadlineExceeded) || inner.calls != 0 {
+		t.Errorf("delayed Chat = %v (calls %d), want the caller's deadline to fire", err, inner.calls)
+	}
+
+	p.chaos.llmDelay = time.Millisecond
+	var streamed string
+	resp, err := p.ChatStream(context.Background(), nil, nil, "", nil, func(s string) { streamed += s })
+	if err != nil || resp.Content != "ok" || streamed != "ok" {
+		t.Errorf("ChatStream = %+v, %v, streamed %q", resp, err, streamed)
+	}
+}
+
+func TestNotifierChaosDrop(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer srv.Close()
+
+	n, err := NewNotifier(config.NotificationConfig{
+		Targets: map[string]config.NotifyTargetConfig{"hook": {Type: NotifyTargetWebhook, URL: srv.URL}},
+		Routes:  []config.NotifyRouteConfig{{Targets: []string{"hook"}}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.retryBackoff = time.Millisecond
+	n.faults = &chaos{cfg: config.ChaosConfig{NotificationDropRate: 0.5}, rand: sequence(0.1, 0.1, 0.9)}
+
+	// 前两次投递被丢弃, 第三次重试送达
+	n.Notify(context.Background(), &Proposal{ID: "a", Type: "risk"})
+	if calls != 1 {
+		t.Errorf("calls = %d, want delivery after 2 dropped attempts", calls)
+	}
+}
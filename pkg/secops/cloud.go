@@ -0,0 +1,237 @@
+package secops
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const (
+	defaultCloudInterval = 15 * time.Minute
+	// cloudBackfill 首次同步时回溯的时长
+	cloudBackfill = 24 * time.Hour
+	// maxCloudPages 单次同步每个云厂商最多读取的页数
+	maxCloudPages = 10
+)
+
+// CloudFinding 云安全发现, 各云厂商的发现统一映射为该结构后生成 cloud 提案
+type CloudFinding struct {
+	Provider       string // aws 或 gcp
+	ID             string // 厂商侧的发现标识 (Security Hub 的 Id, SCC 的 name)
+	Title          string
+	Description    string
+	Severity       string // 已映射为本地严重级别
+	Account        string // AWS 账号或 GCP 项目
+	Region         string
+	ResourceType   string
+	Resource       string
+	Category       string
+	Remediation    string
+	RemediationURL string
+	Link           string // 控制台深链接
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Raw            json.RawMessage
+}
+
+// findingSource 云安全发现来源
+type findingSource interface {
+	provider() string
+	// fetch 拉取 since 之后更新过的活跃发现
+	fetch(ctx context.Context, since time.Time) ([]CloudFinding, error)
+}
+
+// cloudSync 定期将云安全发现同步为 cloud 提案, 已存在或已被删除、归档的发现不会重复创建
+type cloudSync struct {
+	sources     []findingSource
+	interval    time.Duration
+	minSeverity string
+	since       map[string]time.Time // 每个来源已同步到的更新时间
+	now         func() time.Time
+	mu          sync.Mutex
+}
+
+// newCloudSync 按配置创建启用的来源; 均未启用时返回 nil
+func newCloudSync(cfg config.CloudFindingsConfig) (*cloudSync, error) {
+	cs := &cloudSync{
+		interval:    defaultCloudInterval,
+		minSeverity: SeverityMedium,
+		since:       make(map[string]time.Time),
+		now:         time.Now,
+	}
+	if cfg.Interval != "" {
+		d, err := time.ParseDuration(cfg.Interval)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid interval %q", cfg.Interval)
+		}
+		cs.interval = d
+	}
+	if cfg.MinSeverity != "" {
+		if !validSeverities[cfg.MinSeverity] {
+			return nil, fmt.Errorf("invalid min_severity %q", cfg.MinSeverity)
+		}
+		cs.minSeverity = cfg.MinSeverity
+	}
+
+	if cfg.AWS.Enabled {
+		src, err := newSecurityHubSource(cfg.AWS)
+		if err != nil {
+			return nil, fmt.Errorf("aws_security_hub: %w", err)
+		}
+		cs.sources = append(cs.sources, src)
+	}
+	if cfg.GCP.Enabled {
+		src, err := newCommandCenterSource(cfg.GCP)
+		if err != nil {
+			return nil, fmt.Errorf("gcp_scc: %w", err)
+		}
+		cs.sources = append(cs.sources, src)
+	}
+	if len(cs.sources) == 0 {
+		return nil, nil
+	}
+	return cs, nil
+}
+
+// runCloudSync 按间隔同步云安全发现, 直到服务停止
+func (s *Service) runCloudSync() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.cloud.interval)
+	defer ticker.Stop()
+
+	for {
+		s.syncCloudFindings(s.ctx)
+
+		select {
+		case <-ticker.C:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// syncCloudFindings 拉取各来源的新发现并创建提案, 返回新建的提案数
+func (s *Service) syncCloudFindings(ctx context.Context) int {
+	cs := s.cloud
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	created := 0
+	for _, src := range cs.sources {
+		name := src.provider()
+		since, ok := cs.since[name]
+		if !ok {
+			since = cs.now().Add(-cloudBackfill)
+		}
+
+		findings, err := src.fetch(ctx, since)
+		if err != nil {
+			logger.WarnCF("secops", "Cloud findings sync failed",
+				map[string]interface{}{
+					"provider": name,
+					"error":    err.Error(),
+				})
+			continue
+		}
+
+		n := 0
+		for _, f := range findings {
+			if f.UpdatedAt.After(since) {
+				since = f.UpdatedAt
+			}
+			if severityRank[f.Severity] < severityRank[cs.minSeverity] {
+				continue
+			}
+			p := cloudProposal(f)
+			if s.proposalService.exists(p.ID) || s.proposalService.tombstoned(p.ID) {
+				continue
+			}
+			p.CreatedBy = &Actor{Name: "cloud_sync/" + name, Via: ViaSystem}
+			s.CreateProposal(p)
+			n++
+		}
+		cs.since[name] = since
+		created += n
+
+		if n > 0 {
+			logger.InfoCF("secops", "Cloud findings synced",
+				map[string]interface{}{
+					"provider": name,
+					"fetched":  len(findings),
+					"created":  n,
+				})
+		}
+	}
+	return created
+}
+
+// cloudProviderNames 提案摘要中的控制台名称
+var cloudProviderNames = map[string]string{
+	"aws": "AWS Security Hub",
+	"gcp": "GCP Security Command Center",
+}
+
+// cloudProposal 将云安全发现映射为提案; ID 由厂商和发现标识生成, 重复同步时保持不变
+func cloudProposal(f CloudFinding) *Proposal {
+	var sb strings.Builder
+	if f.Description != "" {
+		sb.WriteString(f.Description + "\n\n")
+	}
+	if f.Resource != "" {
+		sb.WriteString(fmt.Sprintf("- **资源**: %s `%s`\n", f.ResourceType, f.Resource))
+	}
+	if f.Account != "" {
+		sb.WriteString(fmt.Sprintf("- **账号/项目**: %s\n", f.Account))
+	}
+	if f.Region != "" {
+		sb.WriteString(fmt.Sprintf("- **区域**: %s\n", f.Region))
+	}
+	if f.Remediation != "" || f.RemediationURL != "" {
+		sb.WriteString("- **修复建议**: " + f.Remediation)
+		if f.RemediationURL != "" {
+			sb.WriteString(fmt.Sprintf(" ([文档](%s))", f.RemediationURL))
+		}
+		sb.WriteString("\n")
+	}
+	if f.Link != "" {
+		sb.WriteString(fmt.Sprintf("- **控制台**: [在 %s 中查看](%s)\n", cloudProviderNames[f.Provider], f.Link))
+	}
+
+	details := map[string]interface{}{
+		"provider":   f.Provider,
+		"finding_id": f.ID,
+	}
+	for k, v := range map[string]string{
+		"account":       f.Account,
+		"region":        f.Region,
+		"resource":      f.Resource,
+		"resource_type": f.ResourceType,
+		"category":      f.Category,
+		"link":          f.Link,
+	} {
+		if v != "" {
+			details[k] = v
+		}
+	}
+
+	p := NewProposal("cloud", f.Title, strings.TrimSpace(sb.String()), details)
+	sum := sha1.Sum([]byte(f.Provider + "\x00" + f.ID))
+	p.ID = f.Provider + "-" + hex.EncodeToString(sum[:8])
+	p.Severity = f.Severity
+	if !f.CreatedAt.IsZero() {
+		p.CreatedAt = f.CreatedAt
+	}
+	if len(f.Raw) > 0 {
+		p.AddEvidence("原始发现", string(f.Raw))
+	}
+	return p
+}
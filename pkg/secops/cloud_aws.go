@@ -0,0 +1,192 @@
+package secops
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/objectstore"
+)
+
+// securityHubSeverities Security Hub 严重级别标签到本地严重级别
+var securityHubSeverities = map[string]string{
+	"CRITICAL":      SeverityCritical,
+	"HIGH":          SeverityHigh,
+	"MEDIUM":        SeverityMedium,
+	"LOW":           SeverityLow,
+	"INFORMATIONAL": SeverityInfo,
+}
+
+// securityHubSource 通过 GetFindings 拉取 AWS Security Hub 的活跃发现
+type securityHubSource struct {
+	cfg      config.SecurityHubConfig
+	endpoint string
+	client   *http.Client
+	now      func() time.Time
+}
+
+func newSecurityHubSource(cfg config.SecurityHubConfig) (*securityHubSource, error) {
+	if cfg.Region == "" {
+		return nil, fmt.Errorf("region is required")
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, fmt.Errorf("access_key and secret_key are required")
+	}
+	endpoint := strings.TrimRight(cfg.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://securityhub.%s.amazonaws.com", cfg.Region)
+	}
+	return &securityHubSource{
+		cfg:      cfg,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		now:      time.Now,
+	}, nil
+}
+
+func (src *securityHubSource) provider() string {
+	return "aws"
+}
+
+// securityHubFinding ASFF 格式的发现, 只解析需要的字段
+type securityHubFinding struct {
+	ID           string `json:"Id"`
+	AwsAccountID string `json:"AwsAccountId"`
+	Region       string `json:"Region"`
+	Title        string `json:"Title"`
+	Description  string `json:"Description"`
+	Severity     struct {
+		Label string `json:"Label"`
+	} `json:"Severity"`
+	Types     []string `json:"Types"`
+	Resources []struct {
+		Type   string `json:"Type"`
+		ID     string `json:"Id"`
+		Region string `json:"Region"`
+	} `json:"Resources"`
+	Remediation struct {
+		Recommendation struct {
+			Text string `json:"Text"`
+			URL  string `json:"Url"`
+		} `json:"Recommendation"`
+	} `json:"Remediation"`
+	CreatedAt string `json:"CreatedAt"`
+	UpdatedAt string `json:"UpdatedAt"`
+}
+
+// fetch 拉取 since 之后更新的活跃且未处理 (NEW/NOTIFIED) 的发现
+func (src *securityHubSource) fetch(ctx context.Context, since time.Time) ([]CloudFinding, error) {
+	filters := map[string]interface{}{
+		"RecordState": []map[string]string{{"Value": "ACTIVE", "Comparison": "EQUALS"}},
+		"WorkflowStatus": []map[string]string{
+			{"Value": "NEW", "Comparison": "EQUALS"},
+			{"Value": "NOTIFIED", "Comparison": "EQUALS"},
+		},
+		"UpdatedAt": []map[string]string{{
+			"Start": since.UTC().Format(time.RFC3339),
+			"End":   src.now().UTC().Format(time.RFC3339),
+		}},
+	}
+
+	var findings []CloudFinding
+	token := ""
+	for page := 0; page < maxCloudPages; page++ {
+		body := map[string]interface{}{"Filters": filters, "MaxResults": 100}
+		if token != "" {
+			body["NextToken"] = token
+		}
+		var resp struct {
+			Findings  []json.RawMessage `json:"Findings"`
+			NextToken string            `json:"NextToken"`
+		}
+		if err := src.post(ctx, "/findings", body, &resp); err != nil {
+			return nil, err
+		}
+		for _, raw := range resp.Findings {
+			var f securityHubFinding
+			if err := json.Unmarshal(raw, &f); err != nil {
+				return nil, fmt.Errorf("failed to parse security hub finding: %w", err)
+			}
+			findings = append(findings, src.finding(f, raw))
+		}
+		if resp.NextToken == "" {
+			break
+		}
+		token = resp.NextToken
+	}
+	return findings, nil
+}
+
+// finding 映射为 CloudFinding, 深链接打开控制台中按 Id 过滤的发现列表
+func (src *securityHubSource) finding(f securityHubFinding, raw json.RawMessage) CloudFinding {
+	region := f.Region
+	if region == "" {
+		region = src.cfg.Region
+	}
+	cf := CloudFinding{
+		Provider:       "aws",
+		ID:             f.ID,
+		Title:          f.Title,
+		Description:    f.Description,
+		Severity:       securityHubSeverities[f.Severity.Label],
+		Account:        f.AwsAccountID,
+		Region:         region,
+		Remediation:    f.Remediation.Recommendation.Text,
+		RemediationURL: f.Remediation.Recommendation.URL,
+		Link: fmt.Sprintf("https://%s.console.aws.amazon.com/securityhub/home?region=%s#/findings?search=%s",
+			region, region, url.QueryEscape(`Id=\operator\:EQUALS\:`+f.ID)),
+		Raw: raw,
+	}
+	if cf.Severity == "" {
+		cf.Severity = SeverityInfo
+	}
+	if len(f.Resources) > 0 {
+		cf.ResourceType = f.Resources[0].Type
+		cf.Resource = f.Resources[0].ID
+	}
+	if len(f.Types) > 0 {
+		cf.Category = f.Types[0]
+	}
+	cf.CreatedAt, _ = time.Parse(time.RFC3339, f.CreatedAt)
+	cf.UpdatedAt, _ = time.Parse(time.RFC3339, f.UpdatedAt)
+	return cf
+}
+
+// post 发送 SigV4 签名的 JSON 请求
+func (src *securityHubSource) post(ctx context.Context, path string, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, src.endpoint+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	objectstore.SignV4(req, objectstore.PayloadHash(data), objectstore.Credentials{
+		AccessKey:    src.cfg.AccessKey,
+		SecretKey:    src.cfg.SecretKey,
+		SessionToken: src.cfg.SessionToken,
+	}, src.cfg.Region, "securityhub", src.now())
+
+	resp, err := src.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("security hub error %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return json.Unmarshal(respBody, out)
+}
@@ -0,0 +1,211 @@
+package secops
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/jwt"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// commandCenterSeverities SCC 严重级别到本地严重级别, 未设置时为 info
+var commandCenterSeverities = map[string]string{
+	"CRITICAL": SeverityCritical,
+	"HIGH":     SeverityHigh,
+	"MEDIUM":   SeverityMedium,
+	"LOW":      SeverityLow,
+}
+
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// commandCenterSource 通过 findings.list 拉取 GCP Security Command Center 的活跃发现
+type commandCenterSource struct {
+	cfg      config.CommandCenterConfig
+	endpoint string
+	client   *http.Client
+}
+
+func newCommandCenterSource(cfg config.CommandCenterConfig) (*commandCenterSource, error) {
+	if !strings.HasPrefix(cfg.Parent, "organizations/") && !strings.HasPrefix(cfg.Parent, "projects/") &&
+		!strings.HasPrefix(cfg.Parent, "folders/") {
+		return nil, fmt.Errorf("parent must be organizations/<id>, folders/<id> or projects/<id>: %q", cfg.Parent)
+	}
+
+	var ts oauth2.TokenSource
+	switch {
+	case cfg.AccessToken != "":
+		ts = oauth2.StaticTokenSource(&oauth2.Token{AccessToken: cfg.AccessToken})
+	case cfg.CredentialsFile != "":
+		data, err := os.ReadFile(cfg.CredentialsFile)
+		if err != nil {
+			return nil, err
+		}
+		var key struct {
+			ClientEmail  string `json:"client_email"`
+			PrivateKey   string `json:"private_key"`
+			PrivateKeyID string `json:"private_key_id"`
+			TokenURI     string `json:"token_uri"`
+		}
+		if err := json.Unmarshal(data, &key); err != nil || key.ClientEmail == "" || key.PrivateKey == "" {
+			return nil, fmt.Errorf("invalid service account key %s", cfg.CredentialsFile)
+		}
+		if key.TokenURI == "" {
+			key.TokenURI = "https://oauth2.googleapis.com/token"
+		}
+		ts = (&jwt.Config{
+			Email:        key.ClientEmail,
+			PrivateKey:   []byte(key.PrivateKey),
+			PrivateKeyID: key.PrivateKeyID,
+			TokenURL:     key.TokenURI,
+			Scopes:       []string{cloudPlatformScope},
+		}).TokenSource(context.Background())
+	default:
+		return nil, fmt.Errorf("credentials_file or access_token is required")
+	}
+
+	endpoint := strings.TrimRight(cfg.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = "https://securitycenter.googleapis.com"
+	}
+	client := oauth2.NewClient(context.Background(), ts)
+	client.Timeout = 30 * time.Second
+	return &commandCenterSource{cfg: cfg, endpoint: endpoint, client: client}, nil
+}
+
+func (src *commandCenterSource) provider() string {
+	return "gcp"
+}
+
+// commandCenterResult findings.list 的一条结果
+type commandCenterResult struct {
+	Finding struct {
+		Name         string `json:"name"`
+		Category     string `json:"category"`
+		ResourceName string `json:"resourceName"`
+		Severity     string `json:"severity"`
+		Description  string `json:"description"`
+		NextSteps    string `json:"nextSteps"`
+		ExternalURI  string `json:"externalUri"`
+		EventTime    string `json:"eventTime"`
+		CreateTime   string `json:"createTime"`
+	} `json:"finding"`
+	Resource struct {
+		DisplayName        string `json:"displayName"`
+		Type               string `json:"type"`
+		ProjectDisplayName string `json:"projectDisplayName"`
+		Location           string `json:"location"`
+	} `json:"resource"`
+}
+
+// fetch 拉取 since 之后发生的活跃发现
+func (src *commandCenterSource) fetch(ctx context.Context, since time.Time) ([]CloudFinding, error) {
+	filter := fmt.Sprintf(`state="ACTIVE" AND event_time >= "%s"`, since.UTC().Format(time.RFC3339))
+
+	var findings []CloudFinding
+	token := ""
+	for page := 0; page < maxCloudPages; page++ {
+		q := url.Values{"filter": {filter}, "pageSize": {"100"}}
+		if token != "" {
+			q.Set("pageToken", token)
+		}
+		var resp struct {
+			Results       []json.RawMessage `json:"listFindingsResults"`
+			NextPageToken string            `json:"nextPageToken"`
+		}
+		if err := src.get(ctx, "/v1/"+src.cfg.Parent+"/sources/-/findings?"+q.Encode(), &resp); err != nil {
+			return nil, err
+		}
+		for _, raw := range resp.Results {
+			var r commandCenterResult
+			if err := json.Unmarshal(raw, &r); err != nil {
+				return nil, fmt.Errorf("failed to parse scc finding: %w", err)
+			}
+			findings = append(findings, src.finding(r, raw))
+		}
+		if resp.NextPageToken == "" {
+			break
+		}
+		token = resp.NextPageToken
+	}
+	return findings, nil
+}
+
+// finding 映射为 CloudFinding, 深链接打开控制台中的发现详情
+func (src *commandCenterSource) finding(r commandCenterResult, raw json.RawMessage) CloudFinding {
+	f := r.Finding
+	resource := r.Resource.DisplayName
+	if resource == "" {
+		resource = f.ResourceName
+	}
+	title := f.Category
+	if r.Resource.DisplayName != "" {
+		title += ": " + r.Resource.DisplayName
+	}
+
+	cf := CloudFinding{
+		Provider:       "gcp",
+		ID:             f.Name,
+		Title:          title,
+		Description:    f.Description,
+		Severity:       commandCenterSeverities[f.Severity],
+		Account:        r.Resource.ProjectDisplayName,
+		Region:         r.Resource.Location,
+		ResourceType:   r.Resource.Type,
+		Resource:       resource,
+		Category:       f.Category,
+		Remediation:    f.NextSteps,
+		RemediationURL: f.ExternalURI,
+		Link:           src.link(f.Name),
+		Raw:            raw,
+	}
+	if cf.Severity == "" {
+		cf.Severity = SeverityInfo
+	}
+	cf.CreatedAt, _ = time.Parse(time.RFC3339Nano, f.CreateTime)
+	cf.UpdatedAt, _ = time.Parse(time.RFC3339Nano, f.EventTime)
+	return cf
+}
+
+// link 控制台中按发现名称打开详情, 并带上组织或项目上下文
+func (src *commandCenterSource) link(name string) string {
+	q := url.Values{"resourceId": {name}}
+	kind, id, _ := strings.Cut(src.cfg.Parent, "/")
+	switch kind {
+	case "organizations":
+		q.Set("organizationId", id)
+	case "projects":
+		q.Set("project", id)
+	case "folders":
+		q.Set("folder", id)
+	}
+	return "https://console.cloud.google.com/security/command-center/findings?" + q.Encode()
+}
+
+func (src *commandCenterSource) get(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src.endpoint+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := src.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("security command center error %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, out)
+}
@@ -0,0 +1,199 @@
+package secops
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestCloudSync(t *testing.T) {
+	var hubBodies []map[string]interface{}
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if r.URL.Path != "/findings" || !strings.Contains(auth, "/us-east-1/securityhub/aws4_request") ||
+			r.Header.Get("X-Amz-Security-Token") != "session" {
+			http.Error(w, "bad request "+auth, http.StatusForbidden)
+			return
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		hubBodies = append(hubBodies, body)
+		if body["NextToken"] == nil {
+			fmt.Fprint(w, `{"NextToken":"p2","Findings":[
+				{"Id":"arn:aws:securityhub:us-east-1:123456789012:finding/s3-public","AwsAccountId":"123456789012",
+					"Region":"us-east-1","Title":"S3 bucket allows public read","Description":"Bucket policy grants s3:GetObject to *",
+					"Severity":{"Label":"HIGH"},"Types":["Effects/Data Exposure"],
+					"Resources":[{"Type":"AwsS3Bucket","Id":"arn:aws:s3:::billing-exports"}],
+					"Remediation":{"Recommendation":{"Text":"Block public access","Url":"https://docs.aws.amazon.com/s3"}},
+					"CreatedAt":"2026-10-16T10:00:00Z","UpdatedAt":"2026-10-17T08:00:00Z"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"Findings":[
+			{"Id":"low-1","AwsAccountId":"123456789012","Title":"MFA recommended","Severity":{"Label":"LOW"},
+				"UpdatedAt":"2026-10-17T08:30:00Z"}]}`)
+	}))
+	defer hub.Close()
+
+	var sccFilters []string
+	scc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/organizations/42/sources/-/findings" || r.Header.Get("Authorization") != "Bearer ya29.token" {
+			http.Error(w, "bad request", http.StatusForbidden)
+			return
+		}
+		sccFilters = append(sccFilters, r.URL.Query().Get("filter"))
+		fmt.Fprint(w, `{"listFindingsResults":[{
+			"finding":{"name":"organizations/42/sources/7/findings/f1","category":"OPEN_FIREWALL",
+				"resourceName":"//compute.googleapis.com/projects/shop/global/firewalls/allow-all","severity":"CRITICAL",
+				"description":"Firewall allows 0.0.0.0/0","nextSteps":"Restrict source ranges",
+				"eventTime":"2026-10-17T07:00:00.123Z","createTime":"2026-10-17T07:00:01Z"},
+			"resource":{"displayName":"allow-all","type":"google.compute.Firewall","projectDisplayName":"shop"}}]}`)
+	}))
+	defer scc.Close()
+
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	cs, err := newCloudSync(config.CloudFindingsConfig{
+		AWS: config.SecurityHubConfig{Enabled: true, Region: "us-east-1", AccessKey: "AKID", SecretKey: "secret",
+			SessionToken: "session", Endpoint: hub.URL},
+		GCP: config.CommandCenterConfig{Enabled: true, Parent: "organizations/42", AccessToken: "ya29.token", Endpoint: scc.URL},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs.now = func() time.Time { return now }
+	cs.sources[0].(*securityHubSource).now = cs.now
+	svc := &Service{config: &config.SecOpsConfig{}, proposalService: NewProposalService(), cloud: cs}
+
+	// 低于 medium 的 Security Hub 发现被过滤
+	if n := svc.syncCloudFindings(context.Background()); n != 2 {
+		t.Fatalf("first sync created %d proposals", n)
+	}
+	if n := svc.syncCloudFindings(context.Background()); n != 0 {
+		t.Errorf("second sync created %d proposals, want 0", n)
+	}
+	if len(hubBodies) != 4 || hubBodies[1]["NextToken"] != "p2" {
+		t.Fatalf("security hub requests = %v", hubBodies)
+	}
+	start := func(body map[string]interface{}) interface{} {
+		return body["Filters"].(map[string]interface{})["UpdatedAt"].([]interface{})[0].(map[string]interface{})["Start"]
+	}
+	if start(hubBodies[0]) != "2026-10-16T09:00:00Z" || start(hubBodies[2]) != "2026-10-17T08:30:00Z" {
+		t.Errorf("security hub cursor = %v, %v", start(hubBodies[0]), start(hubBodies[2]))
+	}
+	if len(sccFilters) != 2 || !strings.Contains(sccFilters[1], `event_time >= "2026-10-17T07:00:00Z"`) {
+		t.Errorf("scc filters = %v", sccFilters)
+	}
+
+	proposals := svc.proposalService.GetAll()
+	if len(proposals) != 2 {
+		t.Fatalf("proposals = %d", len(proposals))
+	}
+	byProvider := map[string]*Proposal{}
+	for _, p := range proposals {
+		if p.Type != "cloud" {
+			t.Errorf("type = %q", p.Type)
+		}
+		byProvider[p.Details["provider"].(string)] = p
+	}
+
+	aws := byProvider["aws"]
+	if aws == nil || aws.Severity != SeverityHigh || aws.Details["account"] != "123456789012" ||
+		!strings.HasPrefix(aws.ID, "aws-") {
+		t.Fatalf("aws proposal = %+v", aws)
+	}
+	wantLink := "https://us-east-1.console.aws.amazon.com/securityhub/home?region=us-east-1#/findings?search=" +
+		"Id%3D%5Coperator%5C%3AEQUALS%5C%3Aarn%3Aaws%3Asecurityhub%3Aus-east-1%3A123456789012%3Afinding%2Fs3-public"
+	if aws.Details["link"] != wantLink || !strings.Contains(aws.Summary, wantLink) {
+		t.Errorf("aws link = %v", aws.Details["link"])
+	}
+	if !strings.Contains(aws.Summary, "Block public access") || len(aws.Evidence) != 1 {
+		t.Errorf("aws summary = %q, evidence = %d", aws.Summary, len(aws.Evidence))
+	}
+
+	gcp := byProvider["gcp"]
+	if gcp == nil || gcp.Severity != SeverityCritical || gcp.Title != "OPEN_FIREWALL: allow-all" {
+		t.Fatalf("gcp proposal = %+v", gcp)
+	}
+	if link := gcp.Details["link"].(string); !strings.Contains(link, "organizationId=42") ||
+		!strings.Contains(link, "resourceId=organizations%2F42%2Fsources%2F7%2Ffindings%2Ff1") {
+		t.Errorf("gcp link = %s", link)
+	}
+}
+
+func TestNewCloudSyncConfig(t *testing.T) {
+	cs, err := newCloudSync(config.CloudFindingsConfig{})
+	if err != nil || cs != nil {
+		t.Errorf("disabled: cs=%v err=%v", cs, err)
+	}
+	bad := []config.CloudFindingsConfig{
+		{Interval: "soon", AWS: config.SecurityHubConfig{Enabled: true, Region: "us-east-1", AccessKey: "a", SecretKey: "s"}},
+		{MinSeverity: "urgent", AWS: config.SecurityHubConfig{Enabled: true, Region: "us-east-1", AccessKey: "a", SecretKey: "s"}},
+		{AWS: config.SecurityHubConfig{Enabled: true, AccessKey: "a", SecretKey: "s"}},
+		{GCP: config.CommandCenterConfig{Enabled: true, Parent: "42", AccessToken: "t"}},
+		{GCP: config.CommandCenterConfig{Enabled: true, Parent: "projects/shop"}},
+	}
+	for i, cfg := range bad {
+		if _, err := newCloudSync(cfg); err == nil {
+			t.Errorf("config %d: expected error", i)
+		}
+	}
+}
+
+func TestCloudSyncSkipsDeletedFindings(t *testing.T) {
+	findings := []CloudFinding{
+		{Provider: "aws", ID: "finding/a", Title: "a", Severity: SeverityHigh},
+		{Provider: "aws", ID: "finding/b", Title: "b", Severity: SeverityHigh},
+	}
+	path := t.TempDir() + "/proposals.json"
+	ps := NewProposalService()
+	if err := ps.EnablePersistence(path); err != nil {
+		t.Fatal(err)
+	}
+	svc := &Service{config: &config.SecOpsConfig{}, proposalService: ps, cloud: &cloudSync{
+		sources:     []findingSource{staticFindings(findings)},
+		minSeverity: SeverityMedium,
+		since:       make(map[string]time.Time),
+		now:         time.Now,
+	}}
+	if n := svc.syncCloudFindings(context.Background()); n != 2 {
+		t.Fatalf("first sync created %d proposals", n)
+	}
+
+	deleted := cloudProposal(findings[0]).ID
+	if !ps.Delete(deleted) {
+		t.Fatal("delete failed")
+	}
+	if n := svc.syncCloudFindings(context.Background()); n != 0 {
+		t.Errorf("sync after delete created %d proposals, want 0", n)
+	}
+
+	// 墓碑随持久化文件保留, 重启后同步同样跳过
+	if err := ps.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	svc.proposalService = NewProposalService()
+	if err := svc.proposalService.EnablePersistence(path); err != nil {
+		t.Fatal(err)
+	}
+	if n := svc.syncCloudFindings(context.Background()); n != 0 {
+		t.Errorf("sync after reload created %d proposals, want 0", n)
+	}
+	if _, ok := svc.proposalService.Get(deleted); ok {
+		t.Error("deleted finding was re-created")
+	}
+}
+
+// staticFindings 每次同步返回相同的发现
+type staticFindings []CloudFinding
+
+func (f staticFindings) provider() string { return "aws" }
+
+func (f staticFindings) fetch(ctx context.Context, since time.Time) ([]CloudFinding, error) {
+	return f, nil
+}
@@ -0,0 +1,173 @@
+package secops
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// 提案标题和摘要的默认长度上限 (字符数)
+const (
+	defaultMaxTitleLength   = 80
+	defaultMaxSummaryLength = 3000
+)
+
+// defaultForbiddenMarkdown 摘要中默认禁止的 Markdown 元素: 破坏列表排版或导出解析
+var defaultForbiddenMarkdown = []string{"heading", "table", "html", "image"}
+
+// markdownPatterns 可禁止的 Markdown 元素
+var markdownPatterns = map[string]*regexp.Regexp{
+	"heading":    regexp.MustCompile(`(?m)^ {0,3}#{1,6}(\s|$)`),
+	"table":      regexp.MustCompile(`(?m)^\s*\|?\s*:?-{3,}:?\s*(\|\s*:?-{3,}:?\s*)+\|?\s*$`),
+	"html":       regexp.MustCompile(`</?[A-Za-z][A-Za-z0-9-]*(\s[^<>]*)?/?>`),
+	"image":      regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)`),
+	"link":       regexp.MustCompile(`(^|[^!])\[[^\]]+\]\([^)]+\)`),
+	"code_block": regexp.MustCompile("(?m)^ {0,3}(```|~~~)"),
+}
+
+// markdownNames 元素的说明, 写入违规提示
+var markdownNames = map[string]string{
+	"heading":    "headings (#)",
+	"table":      "tables",
+	"html":       "raw HTML tags (wrap payloads in `inline code`)",
+	"image":      "images",
+	"link":       "links",
+	"code_block": "fenced code blocks (put raw data in evidence)",
+}
+
+// titleMarkup 标题中不允许的 Markdown 标记
+var titleMarkup = regexp.MustCompile("\\*\\*|__|`|^#|\\]\\(|</?[A-Za-z]")
+
+// codeSpan 行内代码, 检查 HTML 前去除, 允许以行内代码引用攻击载荷
+var codeSpan = regexp.MustCompile("`[^`\n]*`")
+
+// requiredFields 可配置为必填的提案字段, details.<key> 表示详情中的字段
+var requiredFields = map[string]bool{
+	"severity":       true,
+	"confidence":     true,
+	"recommendation": true,
+	"evidence":       true,
+	"techniques":     true,
+	"case_id":        true,
+}
+
+// proposalConstraints Agent 创建提案时的服务端约束, 违反时工具返回错误, Agent 按提示修改后重新提交
+type proposalConstraints struct {
+	maxTitle   int                 // 0 表示不限制
+	maxSummary int                 // 0 表示不限制
+	required   map[string][]string // 提案类型或 "*" -> 必填字段
+	forbidden  []string            // 摘要中禁止的 Markdown 元素
+}
+
+// newProposalConstraints 按配置创建约束, 校验字段名和 Markdown 元素名
+func newProposalConstraints(cfg config.ProposalConstraintsConfig) (*proposalConstraints, error) {
+	c := &proposalConstraints{
+		maxTitle:   limitOrDefault(cfg.MaxTitleLength, defaultMaxTitleLength),
+		maxSummary: limitOrDefault(cfg.MaxSummaryLength, defaultMaxSummaryLength),
+		required:   cfg.Required,
+		forbidden:  defaultForbiddenMarkdown,
+	}
+
+	for typ, fields := range cfg.Required {
+		if typ != "*" && !validProposalTypes[typ] {
+			return nil, fmt.Errorf("required: unknown proposal type %q", typ)
+		}
+		for _, f := range fields {
+			if !requiredFields[f] && !(strings.HasPrefix(f, "details.") && len(f) > len("details.")) {
+				return nil, fmt.Errorf("required.%s: unknown field %q", typ, f)
+			}
+		}
+	}
+
+	if cfg.ForbiddenMarkdown != nil {
+		c.forbidden = cfg.ForbiddenMarkdown
+	}
+	for _, name := range c.forbidden {
+		if _, ok := markdownPatterns[name]; !ok {
+			return nil, fmt.Errorf("forbidden_markdown: unknown element %q", name)
+		}
+	}
+	return c, nil
+}
+
+// limitOrDefault 0 取默认值, 负数表示不限制 (返回 0)
+func limitOrDefault(n, def int) int {
+	switch {
+	case n == 0:
+		return def
+	case n < 0:
+		return 0
+	}
+	return n
+}
+
+// check 返回提案违反的约束, 为空表示通过; c 为 nil 时不检查
+func (c *proposalConstraints) check(p *Proposal) []string {
+	if c == nil {
+		return nil
+	}
+	var violations []string
+
+	title := strings.TrimSpace(p.Title)
+	if strings.ContainsAny(title, "\r\n") {
+		violations = append(violations, "title must be a single line")
+	}
+	if titleMarkup.MatchString(title) {
+		violations = append(violations, "title must be plain text without Markdown or HTML")
+	}
+	if n := utf8.RuneCountInString(title); c.maxTitle > 0 && n > c.maxTitle {
+		violations = append(violations, fmt.Sprintf("title is %d characters, limit is %d", n, c.maxTitle))
+	}
+
+	if n := utf8.RuneCountInString(p.Summary); c.maxSummary > 0 && n > c.maxSummary {
+		violations = append(violations, fmt.Sprintf("summary is %d characters, limit is %d; keep the key findings and move raw data to evidence", n, c.maxSummary))
+	}
+	for _, name := range c.forbidden {
+		text := p.Summary
+		if name == "html" {
+			text = codeSpan.ReplaceAllString(text, "")
+		}
+		if markdownPatterns[name].MatchString(text) {
+			violations = append(violations, "summary must not contain "+markdownNames[name])
+		}
+	}
+
+	var missing []string
+	for _, key := range []string{"*", p.Type} {
+		for _, f := range c.required[key] {
+			if !hasProposalField(p, f) && !slices.Contains(missing, f) {
+				missing = append(missing, f)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		violations = append(violations, fmt.Sprintf("missing required fields for %s proposals: %s", p.Type, strings.Join(missing, ", ")))
+	}
+	return violations
+}
+
+// hasProposalField 提案是否填写了字段
+func hasProposalField(p *Proposal, field string) bool {
+	switch field {
+	case "severity":
+		return p.Severity != ""
+	case "confidence":
+		return p.Confidence > 0
+	case "recommendation":
+		return p.Recommendation != ""
+	case "evidence":
+		return len(p.Evidence) > 0
+	case "techniques":
+		return len(p.Techniques) > 0
+	case "case_id":
+		return p.CaseID != ""
+	}
+	v, ok := p.Details[strings.TrimPrefix(field, "details.")]
+	return ok && v != nil && fmt.Sprint(v) != ""
+}
@@ -0,0 +1,90 @@
+package secops
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestProposalConstraints(t *testing.T) {
+	c, err := newProposalConstraints(config.ProposalConstraintsConfig{
+		MaxTitleLength: 20,
+		Required:       map[string][]string{"*": {"severity"}, "risk": {"evidence", "details.src_ip"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok := &Proposal{
+		Type: "risk", Title: "撞库攻击 203.0.113.7", Severity: SeverityHigh,
+		Summary:  "- **来源**: 203.0.113.7\n- 载荷 `<script>alert(1)</script>`",
+		Details:  map[string]interface{}{"src_ip": "203.0.113.7"},
+		Evidence: []Evidence{{Content: "SELECT 1"}},
+	}
+	if v := c.check(ok); len(v) != 0 {
+		t.Errorf("valid proposal violations = %v", v)
+	}
+
+	bad := &Proposal{
+		Type:    "risk",
+		Title:   "**撞库** 攻击来自同一 IP 的大量登录尝试",
+		Summary: "## 结论\n\n| ip | 次数 |\n|---|---|\n| 1.2.3.4 | 300 |\n<b>高危</b>",
+		Details: map[string]interface{}{"src_ip": ""},
+	}
+	got := strings.Join(c.check(bad), "\n")
+	for _, want := range []string{
+		"title must be plain text",
+		"title is 24 characters, limit is 20",
+		"headings", "tables", "raw HTML",
+		"missing required fields for risk proposals: details.src_ip, evidence, severity",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("violations missing %q:\n%s", want, got)
+		}
+	}
+
+	// 其他类型只检查 "*" 的必填字段; 配置为空列表时不限制 Markdown
+	c, _ = newProposalConstraints(config.ProposalConstraintsConfig{MaxSummaryLength: -1, ForbiddenMarkdown: []string{}})
+	if v := c.check(&Proposal{Type: "weak", Title: "弱口令", Summary: "## 结论\n" + strings.Repeat("很长", 5000)}); len(v) != 0 {
+		t.Errorf("unlimited violations = %v", v)
+	}
+	if v := (*proposalConstraints)(nil).check(bad); v != nil {
+		t.Errorf("nil constraints = %v", v)
+	}
+
+	for _, cfg := range []config.ProposalConstraintsConfig{
+		{Required: map[string][]string{"unknown": {"severity"}}},
+		{Required: map[string][]string{"*": {"owner"}}},
+		{Required: map[string][]string{"*": {"details."}}},
+		{ForbiddenMarkdown: []string{"emoji"}},
+	} {
+		if _, err := newProposalConstraints(cfg); err == nil {
+			t.Errorf("%+v: expected error", cfg)
+		}
+	}
+}
+
+func TestProposalToolConstraints(t *testing.T) {
+	c, _ := newProposalConstraints(config.ProposalConstraintsConfig{Required: map[string][]string{"host": {"severity"}}})
+	svc := &Service{config: &config.SecOpsConfig{}, proposalService: NewProposalService(), runs: newRunStore(), constraints: c}
+	tool := NewProposalTool(svc)
+	tool.SetContext("secops", "host_analysis")
+
+	args := map[string]interface{}{"type": "host", "title": "WebShell 落地", "summary": "# 结论\nweb-01 上发现可疑 PHP 文件"}
+	res := tool.Execute(context.Background(), args)
+	if !res.IsError || !strings.Contains(res.ForLLM, "call secops_proposal again") ||
+		!strings.Contains(res.ForLLM, "headings") || !strings.Contains(res.ForLLM, "severity") {
+		t.Fatalf("result = %q", res.ForLLM)
+	}
+	if n := len(svc.proposalService.GetAll()); n != 0 {
+		t.Fatalf("rejected proposal stored: %d", n)
+	}
+
+	// 按提示修改后重新提交
+	args["summary"], args["severity"] = "web-01 上发现可疑 PHP 文件", SeverityHigh
+	if res := tool.Execute(context.Background(), args); res.IsError {
+		t.Fatalf("fixed proposal rejected: %s", res.ForLLM)
+	}
+}
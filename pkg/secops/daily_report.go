@@ -0,0 +1,354 @@
+package secops
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// 风险趋势的统计参数
+const (
+	trendBaselineDays = 7  // 基线为此前 7 天的日均数量
+	minTrendCount     = 2  // 当天至少出现的次数
+	maxRiskTrends     = 10 // 日报中列出的趋势上限
+)
+
+// RiskTrend 当天明显增多或首次出现的风险特征
+type RiskTrend struct {
+	Signature string  `json:"signature"` // 风险特征, 与提案分组的 signature 相同
+	Count     int     `json:"count"`     // 当天新增的提案数
+	Baseline  float64 `json:"baseline"`  // 此前 7 天的日均提案数
+	New       bool    `json:"new,omitempty"`
+}
+
+// DailyReportData 日报依据的结构化数据, 时间范围为 [Since, Until)
+type DailyReportData struct {
+	Since           time.Time          `json:"since"`
+	Until           time.Time          `json:"until"`
+	Created         int                `json:"created"`         // 新增提案数
+	PreviousCreated int                `json:"previousCreated"` // 前一天新增提案数
+	BySeverity      map[string]int     `json:"bySeverity"`      // 新增提案按严重级别计数
+	ByType          map[string]int     `json:"byType"`          // 新增提案按类型计数
+	Accepted        int                `json:"accepted"`        // 当天确认的提案数
+	Ignored         int                `json:"ignored"`         // 当天忽略的提案数
+	ExecutionFailed int                `json:"executionFailed"` // 当天决策后执行失败的提案数
+	Pending         int                `json:"pending"`         // 截至 Until 仍待处理的提案数
+	Notable         []HandoverProposal `json:"notable"`         // 当天新增的 critical、high 提案
+	Trends          []RiskTrend        `json:"trends"`
+	Truncated       bool               `json:"truncated,omitempty"`
+}
+
+// DailyReport 每日运营日报
+type DailyReport struct {
+	Report      string          `json:"report"`   // 日报正文 (Markdown)
+	Composed    bool            `json:"composed"` // 由 Agent 撰写; false 表示 Agent 不可用, 按模板生成
+	Data        DailyReportData `json:"data"`
+	File        string          `json:"file,omitempty"` // 报告文件名, 位于 <workspace>/secops/reports
+	GeneratedAt time.Time       `json:"generatedAt"`
+	PostedTo    []string        `json:"postedTo,omitempty"` // 已发送的通知目标
+}
+
+// newDailyReportSchedule 校验日报配置, 复用交班时刻的计算; 未配置生成时刻时返回只含通知目标的日程
+func newDailyReportSchedule(cfg config.DailyReportConfig, timezone string, targets map[string]config.NotifyTargetConfig) (*handoverSchedule, error) {
+	if cfg.Timezone != "" {
+		timezone = cfg.Timezone
+	}
+	loc, err := loadTimezone(timezone)
+	if err != nil {
+		return nil, err
+	}
+	hs := &handoverSchedule{loc: loc, targets: cfg.Targets}
+	if cfg.Time != "" {
+		m, err := parseClock(cfg.Time)
+		if err != nil {
+			return nil, err
+		}
+		hs.minutes = []int{m}
+	}
+	for _, name := range cfg.Targets {
+		if _, ok := targets[name]; !ok {
+			return nil, fmt.Errorf("unknown notify target %q", name)
+		}
+	}
+	return hs, nil
+}
+
+// DailyReportData 汇总 [until-24h, until) 的日报数据
+func (s *Service) DailyReportData(until time.Time) DailyReportData {
+	since := until.Add(-24 * time.Hour)
+	baselineSince := since.Add(-trendBaselineDays * 24 * time.Hour)
+	data := DailyReportData{
+		Since:      since,
+		Until:      until,
+		BySeverity: make(map[string]int),
+		ByType:     make(map[string]int),
+		Notable:    []HandoverProposal{},
+		Trends:     []RiskTrend{},
+	}
+
+	today := make(map[string]int)
+	baseline := make(map[string]int)
+	for _, p := range s.proposalService.GetAll() {
+		if p.Status == ProposalStatusPending && p.CreatedAt.Before(until) {
+			data.Pending++
+		}
+		if d := p.Decision; d != nil && !d.DecidedAt.Before(since) && d.DecidedAt.Before(until) {
+			switch d.Action {
+			case ActionAccept:
+				data.Accepted++
+			case ActionIgnore:
+				data.Ignored++
+			}
+			if p.Execution != nil && p.Execution.Status == ExecutionFailed {
+				data.ExecutionFailed++
+			}
+		}
+
+		if p.CreatedAt.Before(baselineSince) || !p.CreatedAt.Before(until) {
+			continue
+		}
+		signature := proposalGroupKeys(p, GroupBySignature)[0]
+		if p.CreatedAt.Before(since) {
+			baseline[signature]++
+			if !p.CreatedAt.Before(since.Add(-24 * time.Hour)) {
+				data.PreviousCreated++
+			}
+			continue
+		}
+
+		today[signature]++
+		data.Created++
+		data.ByType[p.Type]++
+		if p.Severity != "" {
+			data.BySeverity[p.Severity]++
+		}
+		if p.Severity == SeverityCritical || p.Severity == SeverityHigh {
+			data.Notable = append(data.Notable, HandoverProposal{
+				ID:       p.ID,
+				Type:     p.Type,
+				Title:    p.Title,
+				Severity: p.Severity,
+				Assignee: p.Assignee,
+				At:       p.CreatedAt,
+			})
+		}
+	}
+
+	// 当天数量不少于基线的两倍视为上升, 基线为 0 视为首次出现
+	for signature, count := range today {
+		avg := float64(baseline[signature]) / trendBaselineDays
+		if signature == "" || count < minTrendCount || float64(count) < 2*avg {
+			continue
+		}
+		data.Trends = append(data.Trends, RiskTrend{Signature: signature, Count: count, Baseline: avg, New: avg == 0})
+	}
+	sort.Slice(data.Trends, func(i, j int) bool {
+		a, b := data.Trends[i], data.Trends[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Signature < b.Signature
+	})
+	sort.Slice(data.Notable, func(i, j int) bool {
+		a, b := data.Notable[i], data.Notable[j]
+		if ra, rb := severityRank[a.Severity], severityRank[b.Severity]; ra != rb {
+			return ra > rb
+		}
+		return a.At.Before(b.At)
+	})
+
+	if len(data.Trends) > maxRiskTrends {
+		data.Trends = data.Trends[:maxRiskTrends]
+		data.Truncated = true
+	}
+	if len(data.Notable) > maxHandoverItems {
+		data.Notable = data.Notable[:maxHandoverItems]
+		data.Truncated = true
+	}
+	return data
+}
+
+// GenerateDailyReport 由 Agent 根据 [until-24h, until) 的数据撰写日报并写入报告目录, until 为零值时取当前时间;
+// post 为 true 时发往配置的通知目标。Agent 不可用时按模板生成。
+func (s *Service) GenerateDailyReport(ctx context.Context, until time.Time, post bool) (*DailyReport, error) {
+	if post && len(s.dailyReport.targets) == 0 {
+		return nil, fmt.Errorf("no daily report targets configured")
+	}
+
+	now := time.Now()
+	if until.IsZero() {
+		until = now
+	}
+	if until.After(now) {
+		return nil, fmt.Errorf("until must not be in the future")
+	}
+
+	r := &DailyReport{Data: s.DailyReportData(until), GeneratedAt: now}
+	if s.agentLoop != nil {
+		prompt := buildDailyReportPrompt(r.Data) + languageInstruction(s.outputLanguage(""))
+		response, err := s.agentLoop.ProcessDirect(ctx, prompt, "secops:daily_report:"+until.Format(time.RFC3339))
+		if err == nil && strings.TrimSpace(response) != "" {
+			r.Report = strings.TrimSpace(response)
+			r.Composed = true
+		} else if err != nil {
+			logger.WarnCF("secops", "Agent failed to compose daily report, using template",
+				map[string]interface{}{
+					"error": err.Error(),
+				})
+		}
+	}
+	if r.Report == "" {
+		r.Report = formatDailyReport(r.Data)
+	}
+
+	if s.workspace != "" {
+		name := "daily-report-" + until.In(s.dailyReport.loc).Format("20060102-150405") + ".md"
+		if err := writeReport(s.workspace, name, r.Report); err != nil {
+			return nil, fmt.Errorf("failed to write daily report: %w", err)
+		}
+		r.File = name
+	}
+
+	if post {
+		payload := map[string]interface{}{
+			"event":  "report.daily",
+			"report": r,
+		}
+		for _, target := range s.dailyReport.targets {
+			s.notifier.send(ctx, target, r.Report, payload)
+		}
+		r.PostedTo = s.dailyReport.targets
+	}
+
+	logger.InfoCF("secops", "Daily report generated",
+		map[string]interface{}{
+			"until":    until.Format(time.RFC3339),
+			"created":  r.Data.Created,
+			"notable":  len(r.Data.Notable),
+			"trends":   len(r.Data.Trends),
+			"file":     r.File,
+			"composed": r.Composed,
+			"posted":   post,
+		})
+	return r, nil
+}
+
+// writeReport 写入 <workspace>/secops/reports, 与 report 钩子的报告一起在 Debug UI 中列出
+func writeReport(workspace, name, content string) error {
+	dir := filepath.Join(workspace, "secops", "reports")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, name), []byte(content), 0600)
+}
+
+// runDailyReport 每天在生成时刻生成日报并发送
+func (s *Service) runDailyReport() {
+	defer s.wg.Done()
+
+	for {
+		slot := s.dailyReport.next(time.Now())
+		timer := time.NewTimer(time.Until(slot))
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if _, err := s.GenerateDailyReport(s.ctx, slot, len(s.dailyReport.targets) > 0); err != nil {
+			logger.WarnCF("secops", "Scheduled daily report failed",
+				map[string]interface{}{
+					"slot":  slot.Format(time.RFC3339),
+					"error": err.Error(),
+				})
+		}
+	}
+}
+
+// dailyReportCommand 处理 /report 命令: --post 同时发往通知目标
+func (s *Service) dailyReportCommand(ctx context.Context, msg bus.InboundMessage, args []string) string {
+	post := false
+	for _, a := range args {
+		if a != "--post" {
+			return "Usage: /report [--post]"
+		}
+		post = true
+	}
+
+	r, err := s.GenerateDailyReport(ctx, time.Time{}, post)
+	if err != nil {
+		return "生成日报失败: " + err.Error()
+	}
+	return r.Report
+}
+
+// buildDailyReportPrompt 构建撰写日报的 prompt
+func buildDailyReportPrompt(data DailyReportData) string {
+	var sb strings.Builder
+	sb.WriteString("你是安全运营负责人, 请根据以下过去 24 小时的结构化数据撰写运营日报, 供团队和管理层阅读。\n")
+	sb.WriteString("不要调用任何工具, 直接输出 Markdown, 按以下顺序分节: 总体情况、重点提案、决策与执行、风险趋势、建议关注。\n")
+	sb.WriteString("总体情况与前一天对比; 重点提案附提案 ID; 风险趋势说明哪些风险特征明显增多或首次出现。某一节没有数据时写 \"无\"。不要编造数据中没有的信息。\n")
+	if data.Truncated {
+		sb.WriteString("部分条目较多, 数据已截断, 请在日报中提示到 Debug UI 查看完整列表。\n")
+	}
+	fmt.Fprintf(&sb, "\n统计时段: %s 至 %s\n", data.Since.Format("2006-01-02 15:04"), data.Until.Format("2006-01-02 15:04"))
+
+	body, _ := json.MarshalIndent(data, "", "  ")
+	sb.WriteString("\n数据:\n" + string(body) + "\n")
+	return sb.String()
+}
+
+// formatDailyReport Agent 不可用时按模板生成的日报
+func formatDailyReport(data DailyReportData) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# [SecOps] 运营日报 %s ~ %s\n", data.Since.Format("01-02 15:04"), data.Until.Format("01-02 15:04"))
+
+	sb.WriteString("\n## 总体情况\n")
+	fmt.Fprintf(&sb, "- 新增提案 %d (前一天 %d)", data.Created, data.PreviousCreated)
+	if len(data.BySeverity) > 0 {
+		var parts []string
+		for _, sev := range []string{SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow, SeverityInfo} {
+			if n := data.BySeverity[sev]; n > 0 {
+				parts = append(parts, fmt.Sprintf("%s %d", sev, n))
+			}
+		}
+		fmt.Fprintf(&sb, ": %s", strings.Join(parts, ", "))
+	}
+	sb.WriteString("\n")
+	fmt.Fprintf(&sb, "- 确认 %d, 忽略 %d, 执行失败 %d\n", data.Accepted, data.Ignored, data.ExecutionFailed)
+	fmt.Fprintf(&sb, "- 待处理 %d\n", data.Pending)
+
+	fmt.Fprintf(&sb, "\n## 重点提案 (%d)\n", len(data.Notable))
+	if len(data.Notable) == 0 {
+		sb.WriteString("无\n")
+	}
+	for _, p := range data.Notable {
+		fmt.Fprintf(&sb, "- [%s] %s (%s)\n", p.Severity, p.Title, p.ID)
+	}
+
+	fmt.Fprintf(&sb, "\n## 风险趋势 (%d)\n", len(data.Trends))
+	if len(data.Trends) == 0 {
+		sb.WriteString("无\n")
+	}
+	for _, t := range data.Trends {
+		if t.New {
+			fmt.Fprintf(&sb, "- %s: %d (首次出现)\n", t.Signature, t.Count)
+		} else {
+			fmt.Fprintf(&sb, "- %s: %d (7 日均值 %.1f)\n", t.Signature, t.Count, t.Baseline)
+		}
+	}
+
+	if data.Truncated {
+		sb.WriteString("\n部分条目已截断, 完整列表见 Debug UI。\n")
+	}
+	return sb.String()
+}
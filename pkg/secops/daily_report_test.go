@@ -0,0 +1,100 @@
+package secops
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestDailyReportSchedule(t *testing.T) {
+	targets := map[string]config.NotifyTargetConfig{"slack_soc": {Type: NotifyTargetChannel, Channel: "slack", ChatID: "C1"}}
+	rs, err := newDailyReportSchedule(config.DailyReportConfig{Time: "08:00"}, "Asia/Shanghai", targets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, rs.loc)
+	if got, want := rs.next(now), time.Date(2026, 3, 3, 8, 0, 0, 0, rs.loc); !got.Equal(want) {
+		t.Errorf("next = %v, want %v", got, want)
+	}
+
+	for _, cfg := range []config.DailyReportConfig{
+		{Time: "8am"},
+		{Targets: []string{"unknown"}},
+		{Timezone: "Mars/Olympus"},
+	} {
+		if _, err := newDailyReportSchedule(cfg, "", targets); err == nil {
+			t.Errorf("%+v: expected error", cfg)
+		}
+	}
+}
+
+func TestDailyReport(t *testing.T) {
+	workspace := t.TempDir()
+	svc := &Service{
+		config:          &config.SecOpsConfig{},
+		proposalService: NewProposalService(),
+		runs:            newRunStore(),
+		workspace:       workspace,
+	}
+	svc.dailyReport, _ = newDailyReportSchedule(config.DailyReportConfig{}, "", nil)
+	ps := svc.proposalService
+	now := time.Now()
+
+	create := func(title, severity, signature string, age time.Duration) *Proposal {
+		p := NewProposal("risk", title, "", map[string]interface{}{"signature": signature})
+		p.Severity = severity
+		p.CreatedAt = now.Add(-age)
+		ps.Create(p)
+		return p
+	}
+	// 撞库当天 3 次, 此前 7 天共 1 次; 扫描每天都有, 当天没有明显增多; 新出现的 WebShell 2 次
+	for i := 0; i < 3; i++ {
+		create("撞库攻击", SeverityHigh, "credential_stuffing", time.Duration(i+1)*time.Hour)
+	}
+	create("撞库攻击", SeverityHigh, "credential_stuffing", 3*24*time.Hour)
+	for day := 0; day < 8; day++ {
+		create("端口扫描", SeverityLow, "port_scan", time.Duration(day)*24*time.Hour+time.Hour)
+	}
+	create("WebShell 落地", SeverityCritical, "webshell", 2*time.Hour)
+	decided := create("WebShell 落地", SeverityCritical, "webshell", 3*time.Hour)
+	if err := ps.Accept(decided.ID, DecisionRequest{Reason: "已确认", By: Actor{Name: "bob"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	data := svc.DailyReportData(time.Now())
+	if data.Created != 6 || data.PreviousCreated != 1 || data.BySeverity[SeverityHigh] != 3 || data.Accepted != 1 {
+		t.Errorf("data = %+v", data)
+	}
+	if len(data.Notable) != 5 || data.Notable[0].Severity != SeverityCritical {
+		t.Errorf("notable = %+v", data.Notable)
+	}
+	if len(data.Trends) != 2 || data.Trends[0].Signature != "credential_stuffing" || data.Trends[0].New ||
+		data.Trends[1].Signature != "webshell" || !data.Trends[1].New {
+		t.Errorf("trends = %+v", data.Trends)
+	}
+
+	// 没有 Agent 时按模板生成, 写入报告目录
+	r, err := svc.GenerateDailyReport(context.Background(), now, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Composed || !strings.Contains(r.Report, "credential_stuffing") || !strings.Contains(r.Report, "首次出现") {
+		t.Errorf("report = %q", r.Report)
+	}
+	content, err := os.ReadFile(filepath.Join(workspace, "secops", "reports", r.File))
+	if err != nil || string(content) != r.Report {
+		t.Errorf("report file %q: %v", r.File, err)
+	}
+
+	if _, err := svc.GenerateDailyReport(context.Background(), now, true); err == nil {
+		t.Error("post without targets: expected error")
+	}
+	if _, err := svc.GenerateDailyReport(context.Background(), now.Add(time.Hour), false); err == nil {
+		t.Error("future until: expected error")
+	}
+}
@@ -0,0 +1,203 @@
+package secops
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// DatasetOptions 训练数据导出选项
+type DatasetOptions struct {
+	Since  time.Time // 仅导出该时间之后决策的提案, 零值表示全部
+	Types  []string  // 提案类型过滤, 为空表示全部
+	Redact bool      // 脱敏个人信息
+}
+
+// DatasetRecord 一条标注样本: (证据, Agent 结论, 人工决策)
+type DatasetRecord struct {
+	ID       string            `json:"id"`
+	Type     string            `json:"type"`
+	Input    DatasetInput      `json:"input"`
+	Agent    DatasetVerdict    `json:"agent"`
+	Human    DatasetVerdict    `json:"human"`
+	Label    string            `json:"label"` // 以人工决策为准: accept, ignore
+	Agree    *bool             `json:"agree,omitempty"`
+	Meta     map[string]string `json:"meta,omitempty"`
+	Redacted bool              `json:"redacted"`
+}
+
+// DatasetInput 样本输入
+type DatasetInput struct {
+	Title    string                 `json:"title"`
+	Summary  string                 `json:"summary"`
+	Details  map[string]interface{} `json:"details,omitempty"`
+	Evidence []Evidence             `json:"evidence,omitempty"`
+}
+
+// DatasetVerdict 结论
+type DatasetVerdict struct {
+	Action string `json:"action,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// ExportDataset 将已决策的提案导出为 JSONL 标注数据, 返回导出条数
+func ExportDataset(w io.Writer, proposals []*Proposal, opts DatasetOptions) (int, error) {
+	sort.Slice(proposals, func(i, j int) bool {
+		if proposals[i].CreatedAt.Equal(proposals[j].CreatedAt) {
+			return proposals[i].ID < proposals[j].ID
+		}
+		return proposals[i].CreatedAt.Before(proposals[j].CreatedAt)
+	})
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+
+	count := 0
+	for _, p := range proposals {
+		if p.Decision == nil || p.Decision.DecidedAt.Before(opts.Since) {
+			continue
+		}
+		if len(opts.Types) > 0 && !containsString(opts.Types, p.Type) {
+			continue
+		}
+
+		if err := enc.Encode(datasetRecord(p, opts.Redact)); err != nil {
+			return count, fmt.Errorf("failed to write record %s: %w", p.ID, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+func datasetRecord(p *Proposal, redact bool) DatasetRecord {
+	// 已重新生成摘要时使用 Agent 的原始输出
+	title, summary := p.Title, p.Summary
+	if p.OriginalSummary != nil {
+		title, summary = p.OriginalSummary.Title, p.OriginalSummary.Summary
+	}
+
+	rec := DatasetRecord{
+		ID:   p.ID,
+		Type: p.Type,
+		Input: DatasetInput{
+			Title:    title,
+			Summary:  summary,
+			Details:  p.Details,
+			Evidence: p.Evidence,
+		},
+		Agent: DatasetVerdict{Action: p.Recommendation},
+		Human: DatasetVerdict{Action: p.Decision.Action, Reason: p.Decision.Reason},
+		Label: p.Decision.Action,
+		Meta: map[string]string{
+			"createdAt": p.CreatedAt.UTC().Format(time.RFC3339),
+			"decidedAt": p.Decision.DecidedAt.UTC().Format(time.RFC3339),
+		},
+		Redacted: redact,
+	}
+	if p.Severity != "" {
+		rec.Meta["severity"] = p.Severity
+	}
+	if p.Confidence > 0 {
+		rec.Meta["confidence"] = fmt.Sprint(p.Confidence)
+	}
+	if p.Decision.Template != "" {
+		rec.Meta["template"] = p.Decision.Template
+	}
+	if p.Recommendation != "" {
+		agree := p.Recommendation == p.Decision.Action
+		rec.Agree = &agree
+	}
+
+	if redact {
+		r := newRedactor()
+		rec.Input.Title = r.redact(rec.Input.Title)
+		rec.Input.Summary = r.redact(rec.Input.Summary)
+		rec.Input.Details = r.redactMap(rec.Input.Details)
+		evidence := make([]Evidence, len(rec.Input.Evidence))
+		for i, ev := range rec.Input.Evidence {
+			ev.Label = r.redact(ev.Label)
+			ev.Content = r.redact(ev.Content)
+			evidence[i] = ev
+		}
+		rec.Input.Evidence = evidence
+		rec.Human.Reason = r.redact(rec.Human.Reason)
+	}
+	return rec
+}
+
+// piiPatterns 需要脱敏的个人信息, 按顺序替换 (凭证优先, 避免其中的片段被识别为其他类型)
+var piiPatterns = []struct {
+	kind    string
+	pattern *regexp.Regexp
+}{
+	{"TOKEN", regexp.MustCompile(`(?i)\b(?:bearer|basic)\s+[A-Za-z0-9._~+/=-]{8,}`)},
+	{"JWT", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)},
+	{"COOKIE", regexp.MustCompile(`(?i)(?:cookie|set-cookie):[^\r\n]*`)},
+	{"SECRET", regexp.MustCompile(`(?i)\b(?:password|passwd|pwd|token|secret|api_?key|access_?key)=[^&\s"']+`)},
+	{"EMAIL", regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+	{"ID_CARD", regexp.MustCompile(`\b\d{17}[\dXx]\b`)},
+	{"CARD", regexp.MustCompile(`\b\d{16,19}\b`)},
+	{"PHONE", regexp.MustCompile(`\b1[3-9]\d{9}\b`)},
+	{"IP", regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)},
+}
+
+// redactor 将个人信息替换为占位符; 同一样本内相同的值使用相同的占位符, 保留关联关系
+type redactor struct {
+	seen   map[string]string
+	counts map[string]int
+}
+
+func newRedactor() *redactor {
+	return &redactor{seen: make(map[string]string), counts: make(map[string]int)}
+}
+
+func (r *redactor) redact(s string) string {
+	for _, pii := range piiPatterns {
+		s = pii.pattern.ReplaceAllStringFunc(s, func(m string) string {
+			return r.placeholder(pii.kind, m)
+		})
+	}
+	return s
+}
+
+// placeholder 值 v 的占位符, 如 <PHONE_1>; 相同的值返回相同的占位符
+func (r *redactor) placeholder(kind, v string) string {
+	if ph, ok := r.seen[v]; ok {
+		return ph
+	}
+	r.counts[kind]++
+	ph := fmt.Sprintf("<%s_%d>", kind, r.counts[kind])
+	r.seen[v] = ph
+	return ph
+}
+
+func (r *redactor) redactMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = r.redactValue(v)
+	}
+	return out
+}
+
+func (r *redactor) redactValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case string:
+		return r.redact(val)
+	case map[string]interface{}:
+		return r.redactMap(val)
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = r.redactValue(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -0,0 +1,63 @@
+package secops
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportDataset(t *testing.T) {
+	decided := NewProposal("risk", "撞库 203.0.113.7", "来自 203.0.113.7 的登录尝试, 账号 alice@example.com", map[string]interface{}{
+		"ip":    "203.0.113.7",
+		"phone": "13812345678",
+		"count": 300.0,
+	})
+	decided.ID = "p1"
+	decided.Recommendation = ActionAccept
+	decided.AddEvidence("请求报文", "POST /login HTTP/1.1\r\nAuthorization: Bearer abcdefghijklmnop\r\nCookie: sid=123\r\n\r\nuser=alice@example.com&password=hunter2")
+	decided.Decision = &Decision{Action: ActionIgnore, Reason: "203.0.113.7 是内部扫描器", DecidedAt: time.Now()}
+
+	pending := NewProposal("risk", "pending", "", nil)
+	pending.ID = "p2"
+
+	old := NewProposal("weak", "old", "", nil)
+	old.ID = "p3"
+	old.Decision = &Decision{Action: ActionAccept, DecidedAt: time.Now().Add(-48 * time.Hour)}
+
+	var buf bytes.Buffer
+	n, err := ExportDataset(&buf, []*Proposal{decided, pending, old}, DatasetOptions{Since: time.Now().Add(-24 * time.Hour), Redact: true})
+	if err != nil {
+		t.Fatalf("ExportDataset: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("exported %d records, want 1", n)
+	}
+
+	out := buf.String()
+	for _, leak := range []string{"203.0.113.7", "alice@example.com", "13812345678", "abcdefghijklmnop", "hunter2", "sid=123"} {
+		if strings.Contains(out, leak) {
+			t.Errorf("export leaks %q:\n%s", leak, out)
+		}
+	}
+
+	var rec DatasetRecord
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if rec.Label != ActionIgnore || rec.Agent.Action != ActionAccept || rec.Agree == nil || *rec.Agree {
+		t.Errorf("record verdicts = %+v", rec)
+	}
+	// 同一样本内相同的值使用相同占位符
+	if rec.Input.Details["ip"] != "<IP_1>" || !strings.Contains(rec.Human.Reason, "<IP_1>") {
+		t.Errorf("inconsistent placeholders: details=%v reason=%q", rec.Input.Details, rec.Human.Reason)
+	}
+	if rec.Input.Details["count"] != 300.0 {
+		t.Errorf("non-string detail changed: %v", rec.Input.Details["count"])
+	}
+	// 原提案不受脱敏影响
+	if decided.Details["ip"] != "203.0.113.7" {
+		t.Error("redaction modified the proposal")
+	}
+}
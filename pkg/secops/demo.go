@@ -0,0 +1,256 @@
+package secops
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// demoProposalPrefix 演示提案 ID 前缀, 关闭演示模式时据此清理
+const demoProposalPrefix = "demo-"
+
+// demoSheikahBaseURL 演示模式下 Sheikah API 的占位地址, 请求不会离开进程
+const demoSheikahBaseURL = "http://sheikah.demo"
+
+var sqlFromTable = regexp.MustCompile(`(?i)\bFROM\s+([A-Za-z0-9_.]+)`)
+
+var sqlSelectList = regexp.MustCompile(`(?is)^\s*SELECT\s+(.*?)\s+FROM\b`)
+
+// demoTables 演示模式下各数据表的示例数据, 列顺序与内置 SQL 模板一致
+var demoTables = map[string][][]interface{}{
+	"risk_events": {
+		{"SQL注入", "shop.example.com", "id=1' OR '1'='1", "2026-10-17 09:12:03"},
+		{"目录遍历", "files.example.com", "../../etc/passwd", "2026-10-17 09:05:41"},
+		{"扫描器探测", "www.example.com", "/.git/config", "2026-10-17 08:58:20"},
+	},
+	"weak_events": {
+		{"敏感信息泄露", "api.example.com", "GET", "/api/v1/users/export", "web"},
+		{"未授权访问", "admin.example.com", "POST", "/admin/config/save", "web"},
+	},
+	"access": {
+		{"203.0.113.45", "2026-10-17 09:12:03", "GET", "/product?id=1' OR '1'='1", 200, "sqli"},
+		{"203.0.113.45", "2026-10-17 09:11:57", "GET", "/product?id=1 AND SLEEP(5)", 200, "sqli"},
+		{"203.0.113.45", "2026-10-17 09:11:40", "GET", "/product?id=1", 200, ""},
+	},
+	"access_raw": {
+		{"GET /product?id=1'%20OR%20'1'='1 HTTP/1.1\nHost: shop.example.com\nUser-Agent: sqlmap/1.7.2",
+			"HTTP/1.1 200 OK\nContent-Type: text/html\n\n<html>... 12 products ...</html>"},
+	},
+	"weak": {
+		{"GET /api/v1/users/export HTTP/1.1\nHost: api.example.com",
+			"HTTP/1.1 200 OK\nContent-Type: application/json\n\n[{\"name\":\"张三\",\"phone\":\"13800000000\",\"id_card\":\"110101199001011234\"}]"},
+	},
+	"api_sample": {
+		{"POST", "pay.example.com", "/api/order/refund", `{"orderId":"O1001","amount":99}`, `{"code":0}`, 0, "web"},
+	},
+	"app_sample": {
+		{"app-001", "pay.example.com", "/api/order/create,/api/order/refund,/api/order/query"},
+	},
+}
+
+// demoClickHouseTransport 按 SQL 中的表名返回示例数据, 不连接 ClickHouse
+type demoClickHouseTransport struct{}
+
+func (demoClickHouseTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := req.ParseForm(); err != nil {
+		return demoResponse(req, http.StatusBadRequest, err.Error()), nil
+	}
+	sql := req.PostForm.Get("query")
+	if sql == "" {
+		sql = req.URL.Query().Get("query")
+	}
+
+	rows := [][]interface{}{}
+	if m := sqlFromTable.FindStringSubmatch(sql); m != nil {
+		table := m[1]
+		if i := strings.LastIndex(table, "."); i >= 0 {
+			table = table[i+1:]
+		}
+		if data, ok := demoTables[table]; ok {
+			rows = data
+		}
+		// risk_top20 为聚合查询, 补上类型和计数列
+		if table == "risk_events" && strings.Contains(strings.ToLower(sql), "count()") {
+			rows = make([][]interface{}, 0, len(demoTables[table]))
+			for i, r := range demoTables[table] {
+				rows = append(rows, []interface{}{r[0], r[1], r[2], "attack", 30 - i*10})
+			}
+		}
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{"meta": demoMeta(sql, rows), "data": rows, "rows": len(rows)})
+	return demoResponse(req, http.StatusOK, string(body)), nil
+}
+
+// demoMeta 按 SELECT 列表和首行数据生成 JSONCompact 的 meta; 列数对不上时使用 col1..colN
+func demoMeta(sql string, rows [][]interface{}) []map[string]string {
+	if len(rows) == 0 {
+		return []map[string]string{}
+	}
+	var names []string
+	if m := sqlSelectList.FindStringSubmatch(sql); m != nil {
+		for _, expr := range strings.Split(m[1], ",") {
+			fields := strings.Fields(expr)
+			if len(fields) == 0 {
+				continue
+			}
+			name := fields[len(fields)-1]
+			if i := strings.LastIndex(name, "."); i >= 0 {
+				name = name[i+1:]
+			}
+			names = append(names, name)
+		}
+	}
+
+	meta := make([]map[string]string, len(rows[0]))
+	for i, v := range rows[0] {
+		name := fmt.Sprintf("col%d", i+1)
+		if len(names) == len(rows[0]) {
+			name = names[i]
+		}
+		typ := "String"
+		if _, ok := v.(int); ok {
+			typ = "UInt64"
+		}
+		meta[i] = map[string]string{"name": name, "type": typ}
+	}
+	return meta
+}
+
+// demoSheikahTransport 对所有处置请求返回成功, 不调用 Sheikah
+type demoSheikahTransport struct{}
+
+func (demoSheikahTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"code":    0,
+		"message": "演示模式: 请求未发送到 Sheikah",
+		"data":    map[string]string{"method": req.Method, "path": req.URL.Path},
+	})
+	return demoResponse(req, http.StatusOK, string(body)), nil
+}
+
+func demoResponse(req *http.Request, status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewBufferString(body)),
+		Request:    req,
+	}
+}
+
+// demoProposals 示例提案, 覆盖确认/忽略/不同严重级别和同一案件的关联提案
+func demoProposals() []*Proposal {
+	now := time.Now()
+
+	sqli := NewProposal("risk", "shop.example.com 遭受 SQL 注入攻击", "来自 203.0.113.45 的 sqlmap 扫描, 命中 `/product` 的 `id` 参数, 响应长度异常, **疑似注入成功**。", map[string]interface{}{
+		"host":    "shop.example.com",
+		"ip":      "203.0.113.45",
+		"url":     "/product",
+		"risk":    "SQL注入",
+		"content": "id=1' OR '1'='1",
+	})
+	sqli.ID = demoProposalPrefix + "risk-sqli"
+	sqli.Severity = SeverityHigh
+	sqli.Recommendation = ActionAccept
+	sqli.CaseID = demoProposalPrefix + "case-203.0.113.45"
+	sqli.AddEvidence("溯源查询", "SELECT ip, ts, method, url, status, req_risk FROM access WHERE ip = '203.0.113.45' AND ts > now() - INTERVAL 1 DAY")
+	sqli.AddEvidence("请求报文", "GET /product?id=1'%20OR%20'1'='1 HTTP/1.1\nHost: shop.example.com\nUser-Agent: sqlmap/1.7.2")
+	sqli.CreatedAt = now.Add(-40 * time.Minute)
+
+	sleep := NewProposal("risk", "同一来源的时间盲注尝试", "203.0.113.45 在 SQL 注入后继续尝试 `SLEEP(5)` 时间盲注, 与上一提案属于同一攻击链。", map[string]interface{}{
+		"host": "shop.example.com",
+		"ip":   "203.0.113.45",
+		"url":  "/product",
+		"risk": "SQL注入",
+	})
+	sleep.ID = demoProposalPrefix + "risk-sleep"
+	sleep.Severity = SeverityHigh
+	sleep.Recommendation = ActionAccept
+	sleep.CaseID = sqli.CaseID
+	sleep.CreatedAt = now.Add(-35 * time.Minute)
+
+	scanner := NewProposal("risk", "内部扫描器访问 /.git/config", "来源为安全部漏洞扫描网段, 属于例行扫描, 建议忽略。", map[string]interface{}{
+		"host": "www.example.com",
+		"ip":   "10.0.8.15",
+		"url":  "/.git/config",
+		"risk": "扫描器探测",
+	})
+	scanner.ID = demoProposalPrefix + "risk-scanner"
+	scanner.Severity = SeverityLow
+	scanner.Recommendation = ActionIgnore
+	scanner.CreatedAt = now.Add(-25 * time.Minute)
+
+	leak := NewProposal("weak", "用户导出接口泄露手机号和身份证号", "`/api/v1/users/export` 未脱敏返回手机号、身份证号, 且无需二次鉴权。", map[string]interface{}{
+		"host":      "api.example.com",
+		"url":       "/api/v1/users/export",
+		"method":    "GET",
+		"weak_name": "敏感信息泄露",
+		"channel":   "web",
+	})
+	leak.ID = demoProposalPrefix + "weak-export"
+	leak.Severity = SeverityMedium
+	leak.Recommendation = ActionAccept
+	leak.AddEvidence("响应样例", `[{"name":"张三","phone":"13800000000","id_card":"110101199001011234"}]`)
+	leak.CreatedAt = now.Add(-15 * time.Minute)
+
+	refund := NewProposal("api_biz", "退款接口: 订单退款", "`POST /api/order/refund` 用于订单退款, 涉及资金操作, 建议重要性定为高。", map[string]interface{}{
+		"host":   "pay.example.com",
+		"url":    "/api/order/refund",
+		"method": "POST",
+	})
+	refund.ID = demoProposalPrefix + "api-refund"
+	refund.Severity = SeverityInfo
+	refund.Recommendation = ActionAccept
+	refund.CreatedAt = now.Add(-5 * time.Minute)
+
+	proposals := []*Proposal{sqli, sleep, scanner, leak, refund}
+	for _, p := range proposals {
+		p.UpdatedAt = p.CreatedAt
+	}
+	return proposals
+}
+
+// initDemo 演示模式下用示例数据替代 ClickHouse 和 Sheikah, 并预置示例提案;
+// 关闭演示模式后启动时清理之前预置的示例提案
+func (s *Service) initDemo() {
+	if !s.config.Demo {
+		removed := 0
+		for _, p := range s.proposalService.GetAll() {
+			if strings.HasPrefix(p.ID, demoProposalPrefix) && s.proposalService.Delete(p.ID) {
+				removed++
+			}
+		}
+		if removed > 0 {
+			logger.InfoCF("secops", "Demo proposals removed", map[string]interface{}{"count": removed})
+		}
+		return
+	}
+
+	if s.config.ClickHouse.Addr != "" || s.config.Sheikah.BaseURL != "" {
+		logger.WarnCF("secops", "Demo mode is enabled, ClickHouse and Sheikah settings are ignored; set secops.demo to false to use them",
+			map[string]interface{}{
+				"clickhouse": redactURL("http://" + s.config.ClickHouse.Addr),
+				"sheikah":    redactURL(s.config.Sheikah.BaseURL),
+			})
+	}
+
+	s.queryTool.SetTransport(demoClickHouseTransport{})
+	s.apiTool.SetTransport(demoSheikahTransport{})
+
+	// 直接导入, 不触发新提案通知
+	seeded := s.proposalService.importProposals(demoProposals(), Actor{Name: "demo", Via: ViaImport})
+	logger.InfoCF("secops", "Demo mode enabled", map[string]interface{}{"seeded_proposals": seeded})
+}
+
+// Demo 是否处于演示模式
+func (s *Service) Demo() bool {
+	return s.config.Demo
+}
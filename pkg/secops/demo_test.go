@@ -0,0 +1,89 @@
+package secops
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/tools/secops"
+)
+
+func newDemoTestService(demo bool) *Service {
+	return &Service{
+		config:          &config.SecOpsConfig{Demo: demo},
+		proposalService: NewProposalService(),
+		queryTool:       secops.NewSecOpsQueryDataTool(map[string]string{"top": "SELECT risk, host, content, type, count() as cnt FROM risk_events GROUP BY risk"}, "http://127.0.0.1:1", "", ""),
+		apiTool: secops.NewSecOpsSheikahAPITool(map[string]secops.APIConfig{
+			"confirm_risk": {Method: "POST", Path: "/risk/confirm", Body: `{"host": "$host"}`},
+		}, demoSheikahBaseURL, ""),
+	}
+}
+
+func TestDemoTransports(t *testing.T) {
+	svc := newDemoTestService(true)
+	svc.initDemo()
+
+	res := svc.queryTool.Execute(context.Background(), map[string]interface{}{
+		"raw_sql": "SELECT ip, ts, method, url, status, req_risk FROM default.access WHERE ip = '203.0.113.45'",
+	})
+	if res.IsError || !strings.Contains(res.ForLLM, "203.0.113.45") || !strings.Contains(res.ForLLM, "| ip (String) | ts (String) |") {
+		t.Errorf("access query = %+v, want demo rows", res)
+	}
+
+	res = svc.queryTool.Execute(context.Background(), map[string]interface{}{"sql_id": "top"})
+	if res.IsError || !strings.Contains(res.ForLLM, "attack") {
+		t.Errorf("top20 query = %+v, want aggregated demo rows", res)
+	}
+
+	res = svc.queryTool.Execute(context.Background(), map[string]interface{}{"raw_sql": "SELECT 1 FROM unknown_table"})
+	if res.IsError || !strings.Contains(res.ForLLM, "查询结果为空") {
+		t.Errorf("unknown table = %+v, want empty result", res)
+	}
+
+	res = svc.apiTool.Execute(context.Background(), map[string]interface{}{"api": "confirm_risk", "params": `{"host": "a"}`})
+	if res.IsError || !strings.Contains(res.ForLLM, "演示模式") {
+		t.Errorf("sheikah call = %+v, want demo response", res)
+	}
+}
+
+func TestDemoProposalsSeedAndPurge(t *testing.T) {
+	svc := newDemoTestService(true)
+	svc.initDemo()
+	seeded := len(svc.proposalService.GetAll())
+	if seeded == 0 {
+		t.Fatal("no demo proposals seeded")
+	}
+
+	// 再次启动不重复写入, 已处理的示例提案保持原状
+	first := svc.proposalService.GetAll()[0]
+	first.Status = ProposalStatusAccepted
+	svc.initDemo()
+	if got := len(svc.proposalService.GetAll()); got != seeded {
+		t.Errorf("after restart got %d proposals, want %d", got, seeded)
+	}
+	if p, _ := svc.proposalService.Get(first.ID); p.Status != ProposalStatusAccepted {
+		t.Errorf("demo proposal %s was overwritten", first.ID)
+	}
+
+	// 关闭演示模式后只清理示例提案
+	svc.proposalService.Create(NewProposal("risk", "real", "", nil))
+	svc.config.Demo = false
+	svc.initDemo()
+	all := svc.proposalService.GetAll()
+	if len(all) != 1 || all[0].Title != "real" {
+		t.Errorf("after disabling demo got %d proposals, want only the real one", len(all))
+	}
+}
+
+func TestDemoDependencyHealth(t *testing.T) {
+	svc := newDemoTestService(true)
+	for _, h := range svc.DependencyHealth(context.Background()) {
+		if h.Name == "object_store" {
+			continue
+		}
+		if h.Status != HealthDemo {
+			t.Errorf("%s = %+v, want demo", h.Name, h)
+		}
+	}
+}
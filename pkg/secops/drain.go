@@ -0,0 +1,78 @@
+package secops
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// defaultDrainTimeout 停止服务时等待执行中的活动结束的默认时长, 通常足够完成当前一轮 LLM 调用和提案创建
+const defaultDrainTimeout = 30 * time.Second
+
+// parseDrainTimeout 解析排空时长, 为空取默认值, 0 表示不等待
+func parseDrainTimeout(v string) (time.Duration, error) {
+	if v == "" {
+		return defaultDrainTimeout, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid drain_timeout %q", v)
+	}
+	return d, nil
+}
+
+// drain 等待执行中的活动结束; 超过排空时长仍未结束时中断执行, 被中断的执行记录续跑点。
+// 调用前需已取消服务上下文, 调度和排队中的执行不再开始。
+func (s *Service) drain() {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	if active := s.runs.activeActivities(); len(active) > 0 {
+		logger.InfoCF("secops", "Draining in-flight activity runs",
+			map[string]interface{}{
+				"activities": active,
+				"timeout":    s.drainTimeout.String(),
+			})
+	}
+
+	timer := time.NewTimer(s.drainTimeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		if active := s.runs.activeActivities(); len(active) > 0 {
+			logger.WarnCF("secops", "Drain timeout exceeded, interrupting activity runs",
+				map[string]interface{}{
+					"activities": active,
+				})
+		}
+		s.runCancel()
+		<-done
+	}
+	s.runCancel()
+}
+
+// resumeNote 接续被中断的执行时附加到 prompt 的说明, 列出已创建的提案以免重复创建
+func (s *Service) resumeNote(rp *ResumePoint) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n\n注意: 上一次执行 (%s) 于 %s 服务停止时被中断, 本次为接续执行。",
+		rp.RunID, rp.InterruptedAt.Format("2006-01-02 15:04:05"))
+	if len(rp.ProposalIDs) == 0 {
+		b.WriteString("中断前未创建提案。")
+		return b.String()
+	}
+	b.WriteString("中断前已为以下事件创建提案, 请跳过这些事件, 不要重复创建:\n")
+	for _, id := range rp.ProposalIDs {
+		if p, ok := s.proposalService.Get(id); ok {
+			fmt.Fprintf(&b, "- %s: %s\n", id, p.Title)
+		} else {
+			fmt.Fprintf(&b, "- %s\n", id)
+		}
+	}
+	return b.String()
+}
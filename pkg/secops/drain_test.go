@@ -0,0 +1,143 @@
+package secops
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestParseDrainTimeout(t *testing.T) {
+	for v, want := range map[string]time.Duration{"": defaultDrainTimeout, "0": 0, "2m": 2 * time.Minute} {
+		if got, err := parseDrainTimeout(v); err != nil || got != want {
+			t.Errorf("parseDrainTimeout(%q) = %v, %v; want %v", v, got, err, want)
+		}
+	}
+	for _, v := range []string{"soon", "-1s"} {
+		if _, err := parseDrainTimeout(v); err == nil {
+			t.Errorf("parseDrainTimeout(%q): expected error", v)
+		}
+	}
+}
+
+func TestDrain(t *testing.T) {
+	newService := func(timeout time.Duration) *Service {
+		runCtx, runCancel := context.WithCancel(context.Background())
+		return &Service{runs: newRunStore(), runCtx: runCtx, runCancel: runCancel, drainTimeout: timeout}
+	}
+
+	// 排空时长内结束的执行不被中断
+	svc := newService(time.Second)
+	svc.wg.Add(1)
+	go func() {
+		defer svc.wg.Done()
+		select {
+		case <-time.After(20 * time.Millisecond):
+		case <-svc.runCtx.Done():
+			t.Error("run cancelled before drain timeout")
+		}
+	}()
+	svc.drain()
+	if svc.runCtx.Err() == nil {
+		t.Error("run context should be cancelled after drain")
+	}
+
+	// 超时后取消执行上下文
+	svc = newService(20 * time.Millisecond)
+	svc.wg.Add(1)
+	go func() {
+		defer svc.wg.Done()
+		<-svc.runCtx.Done()
+	}()
+	start := time.Now()
+	svc.drain()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("drain took %v", elapsed)
+	}
+}
+
+func TestRunResume(t *testing.T) {
+	rs := newRunStore()
+
+	first := rs.start("risk_analysis")
+	rs.attachProposal("risk_analysis", "p-1")
+	rs.interrupt(first, "", context.Canceled)
+	if first.Status != RunStatusInterrupted || rs.running("risk_analysis") {
+		t.Fatalf("interrupted run = %+v", first)
+	}
+
+	// 接续执行再次被中断时, 续跑点汇总两次执行创建的提案
+	second := rs.start("risk_analysis")
+	second.StartedAt = first.StartedAt.Add(time.Second)
+	rp := rs.resume(second)
+	if rp == nil || rp.RunID != first.ID || second.ResumeOf != first.ID || len(rp.ProposalIDs) != 1 {
+		t.Fatalf("resume point = %+v", rp)
+	}
+	rs.attachProposal("risk_analysis", "p-2")
+	rs.interrupt(second, "", context.Canceled)
+
+	third := rs.start("risk_analysis")
+	third.StartedAt = second.StartedAt.Add(time.Second)
+	if rp := rs.resume(third); rp == nil || rp.RunID != second.ID || strings.Join(rp.ProposalIDs, ",") != "p-2,p-1" {
+		t.Fatalf("chained resume point = %+v", rp)
+	}
+	rs.finish(third, "done", nil)
+
+	// 上一次执行正常结束时不接续; 其他活动的中断不影响
+	other := rs.start("weak_analysis")
+	rs.interrupt(other, "", context.Canceled)
+	fourth := rs.start("risk_analysis")
+	fourth.StartedAt = third.StartedAt.Add(time.Second)
+	if rp := rs.resume(fourth); rp != nil || fourth.ResumeOf != "" {
+		t.Errorf("unexpected resume point %+v", rp)
+	}
+}
+
+func TestRunStoreLoadMarksInterrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "runs.json")
+	os.WriteFile(path, []byte(`[{"id":"r1","activity":"risk_analysis","status":"running","startedAt":"2026-03-01T10:00:00Z","proposalIds":["p-1"]}]`), 0600)
+
+	rs := newRunStore()
+	if err := rs.load(path); err != nil {
+		t.Fatal(err)
+	}
+	next := rs.start("risk_analysis")
+	rp := rs.resume(next)
+	if rp == nil || rp.RunID != "r1" || len(rp.ProposalIDs) != 1 || !rp.InterruptedAt.Equal(time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("resume point = %+v", rp)
+	}
+}
+
+func TestPlaybookResume(t *testing.T) {
+	svc := &Service{config: &config.SecOpsConfig{}, runs: newRunStore(), proposalService: NewProposalService()}
+	path := filepath.Join(t.TempDir(), "pb.yaml")
+	os.WriteFile(path, []byte(`
+steps:
+  - id: first
+    proposal:
+      type: risk
+      title: first
+  - id: second
+    proposal:
+      type: risk
+      title: 'after {{.Steps.first.id}}'
+`), 0600)
+
+	// 上次执行在 first 完成后被中断, 接续时 first 沿用输出, 不重复创建提案
+	rp := &ResumePoint{RunID: "r1", Steps: []StepResult{{ID: "first", Type: StepProposal, Status: RunStatusSucceeded, Output: `{"id":"p-1"}`}}}
+	run := svc.runs.start("risk_analysis")
+	if _, err := svc.runPlaybook(t.Context(), run, path, rp); err != nil {
+		t.Fatal(err)
+	}
+	if len(run.Steps) != 2 || run.Steps[0].Status != StepStatusResumed || run.Steps[1].Status != RunStatusSucceeded {
+		t.Errorf("steps = %+v", run.Steps)
+	}
+	proposals := svc.proposalService.GetAll()
+	if len(proposals) != 1 || proposals[0].Title != "after p-1" {
+		t.Errorf("proposals = %+v", proposals)
+	}
+}
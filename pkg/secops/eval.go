@@ -0,0 +1,231 @@
+package secops
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// Completer 发送单次无状态请求并返回原始响应 (含 token 用量)
+type Completer func(ctx context.Context, prompt string) (*providers.LLMResponse, error)
+
+// EvalOptions 离线评估选项
+type EvalOptions struct {
+	Activity string  // 评估的活动, 决定任务说明和样本类型过滤
+	Limit    int     // 最多评估的样本数, 0 表示全部
+	PriceIn  float64 // 每百万输入 token 的价格, 用于估算成本
+	PriceOut float64 // 每百万输出 token 的价格
+}
+
+// EvalReport 离线评估报告
+type EvalReport struct {
+	Activity         string                    `json:"activity"`
+	Total            int                       `json:"total"`     // 数据集中匹配的样本数
+	Evaluated        int                       `json:"evaluated"` // 得到有效结论的样本数
+	Correct          int                       `json:"correct"`
+	Errors           int                       `json:"errors"` // 请求失败或结论无法解析
+	Accuracy         float64                   `json:"accuracy"`
+	PromptTokens     int                       `json:"promptTokens"`
+	CompletionTokens int                       `json:"completionTokens"`
+	Cost             float64                   `json:"cost"`
+	DurationMs       int64                     `json:"durationMs"`
+	Confusion        map[string]map[string]int `json:"confusion"` // 人工标签 -> 模型结论 -> 数量
+	Classes          map[string]EvalClass      `json:"classes"`
+	Failures         []EvalFailure             `json:"failures,omitempty"`
+}
+
+// EvalClass 单个类别的指标
+type EvalClass struct {
+	Precision float64 `json:"precision"`
+	Recall    float64 `json:"recall"`
+	Support   int     `json:"support"`
+}
+
+// EvalFailure 结论与人工标签不一致或评估失败的样本
+type EvalFailure struct {
+	ID        string `json:"id"`
+	Label     string `json:"label"`
+	Predicted string `json:"predicted,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Evaluate 使用当前 prompt 配置重放标注样本 (由 ExportDataset 导出的 JSONL), 统计准确率、成本和混淆矩阵
+//
+// 重放时不调用工具, 样本中的证据即为全部输入, 因此不会对外部系统产生任何操作。
+func Evaluate(ctx context.Context, r io.Reader, complete Completer, opts EvalOptions) (*EvalReport, error) {
+	proposalType, ok := activityProposalTypes[opts.Activity]
+	if !ok {
+		return nil, fmt.Errorf("unknown activity: %q", opts.Activity)
+	}
+
+	records, err := readDataset(r, proposalType, opts.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &EvalReport{
+		Activity:  opts.Activity,
+		Total:     len(records),
+		Confusion: make(map[string]map[string]int),
+		Classes:   make(map[string]EvalClass),
+	}
+
+	start := time.Now()
+	for _, rec := range records {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		resp, err := complete(ctx, buildEvalPrompt(opts.Activity, rec))
+		if err != nil {
+			report.Errors++
+			report.Failures = append(report.Failures, EvalFailure{ID: rec.ID, Label: rec.Label, Error: err.Error()})
+			continue
+		}
+		if resp.Usage != nil {
+			report.PromptTokens += resp.Usage.PromptTokens
+			report.CompletionTokens += resp.Usage.CompletionTokens
+		}
+
+		action, reason, err := parseEvalResponse(resp.Content)
+		if err != nil {
+			report.Errors++
+			report.Failures = append(report.Failures, EvalFailure{ID: rec.ID, Label: rec.Label, Error: err.Error()})
+			continue
+		}
+
+		report.Evaluated++
+		if report.Confusion[rec.Label] == nil {
+			report.Confusion[rec.Label] = make(map[string]int)
+		}
+		report.Confusion[rec.Label][action]++
+		if action == rec.Label {
+			report.Correct++
+		} else {
+			report.Failures = append(report.Failures, EvalFailure{ID: rec.ID, Label: rec.Label, Predicted: action, Reason: reason})
+		}
+	}
+	report.DurationMs = time.Since(start).Milliseconds()
+
+	if report.Evaluated > 0 {
+		report.Accuracy = float64(report.Correct) / float64(report.Evaluated)
+	}
+	report.Cost = (float64(report.PromptTokens)*opts.PriceIn + float64(report.CompletionTokens)*opts.PriceOut) / 1e6
+
+	for _, class := range []string{ActionAccept, ActionIgnore} {
+		var tp, predicted, support int
+		for label, row := range report.Confusion {
+			predicted += row[class]
+			if label == class {
+				tp = row[class]
+				for _, n := range row {
+					support += n
+				}
+			}
+		}
+		c := EvalClass{Support: support}
+		if predicted > 0 {
+			c.Precision = float64(tp) / float64(predicted)
+		}
+		if support > 0 {
+			c.Recall = float64(tp) / float64(support)
+		}
+		report.Classes[class] = c
+	}
+
+	return report, nil
+}
+
+// readDataset 读取指定提案类型的标注样本
+func readDataset(r io.Reader, proposalType string, limit int) ([]DatasetRecord, error) {
+	var records []DatasetRecord
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var rec DatasetRecord
+		if err := json.Unmarshal([]byte(text), &rec); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if rec.Type != proposalType {
+			continue
+		}
+		if rec.Label != ActionAccept && rec.Label != ActionIgnore {
+			return nil, fmt.Errorf("line %d: invalid label %q", line, rec.Label)
+		}
+
+		records = append(records, rec)
+		if limit > 0 && len(records) >= limit {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// buildEvalPrompt 使用活动的任务说明和样本证据构建评估 prompt
+func buildEvalPrompt(activity string, rec DatasetRecord) string {
+	var sb strings.Builder
+	sb.WriteString("以下是运营活动的任务说明:\n\n")
+	sb.WriteString(buildActivityPrompt(activity))
+	sb.WriteString("\n\n现在进行离线评估: 事件数据已全部给出, 不要调用任何工具, 仅根据下列信息给出研判结论。\n\n")
+
+	fmt.Fprintf(&sb, "## 事件\n标题: %s\n\n%s\n", rec.Input.Title, rec.Input.Summary)
+	if len(rec.Input.Details) > 0 {
+		keys := make([]string, 0, len(rec.Input.Details))
+		for k := range rec.Input.Details {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		sb.WriteString("\n## 详情\n")
+		for _, k := range keys {
+			fmt.Fprintf(&sb, "- %s: %v\n", k, rec.Input.Details[k])
+		}
+	}
+	for _, ev := range rec.Input.Evidence {
+		fmt.Fprintf(&sb, "\n## 证据: %s (%s)\n```\n%s\n```\n", ev.Label, ev.ContentType, ev.Content)
+	}
+
+	sb.WriteString("\n仅输出一个 JSON 对象: {\"action\": \"accept 或 ignore\", \"reason\": \"一句话理由\"}。accept 表示确认为真实问题, ignore 表示误报或无需处置。")
+	return sb.String()
+}
+
+// parseEvalResponse 解析模型结论
+func parseEvalResponse(response string) (string, string, error) {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start < 0 || end <= start {
+		return "", "", fmt.Errorf("response contains no JSON object")
+	}
+
+	var result struct {
+		Action string `json:"action"`
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal([]byte(response[start:end+1]), &result); err != nil {
+		return "", "", fmt.Errorf("invalid response: %w", err)
+	}
+
+	action := strings.ToLower(strings.TrimSpace(result.Action))
+	if action != ActionAccept && action != ActionIgnore {
+		return "", "", fmt.Errorf("invalid action %q", result.Action)
+	}
+	return action, strings.TrimSpace(result.Reason), nil
+}
@@ -0,0 +1,78 @@
+package secops
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func TestEvaluate(t *testing.T) {
+	var proposals []*Proposal
+	for i, c := range []struct{ title, label string }{
+		{"scanner", ActionIgnore},
+		{"sqli", ActionAccept},
+		{"xss", ActionAccept},
+		{"broken", ActionIgnore},
+	} {
+		p := NewProposal("risk", c.title, "", nil)
+		p.ID = c.title
+		p.CreatedAt = time.Unix(int64(i), 0)
+		p.Decision = &Decision{Action: c.label, DecidedAt: time.Now()}
+		proposals = append(proposals, p)
+	}
+	weak := NewProposal("weak", "other activity", "", nil)
+	weak.Decision = &Decision{Action: ActionAccept, DecidedAt: time.Now()}
+	proposals = append(proposals, weak)
+
+	var dataset bytes.Buffer
+	if _, err := ExportDataset(&dataset, proposals, DatasetOptions{}); err != nil {
+		t.Fatalf("ExportDataset: %v", err)
+	}
+
+	// 模型: scanner 判断正确, sqli 正确, xss 误判为 ignore, broken 无法解析
+	complete := func(ctx context.Context, prompt string) (*providers.LLMResponse, error) {
+		if !strings.Contains(prompt, "风险事件研判") {
+			return nil, errors.New("activity instructions missing from prompt")
+		}
+		usage := &providers.UsageInfo{PromptTokens: 1000, CompletionTokens: 100}
+		switch {
+		case strings.Contains(prompt, "标题: scanner"):
+			return &providers.LLMResponse{Content: `{"action": "ignore", "reason": "内部扫描"}`, Usage: usage}, nil
+		case strings.Contains(prompt, "标题: sqli"):
+			return &providers.LLMResponse{Content: "结论:\n```json\n{\"action\": \"accept\"}\n```", Usage: usage}, nil
+		case strings.Contains(prompt, "标题: xss"):
+			return &providers.LLMResponse{Content: `{"action": "IGNORE", "reason": "payload 未回显"}`, Usage: usage}, nil
+		}
+		return &providers.LLMResponse{Content: "无法判断", Usage: usage}, nil
+	}
+
+	report, err := Evaluate(context.Background(), &dataset, complete, EvalOptions{Activity: "risk_analysis", PriceIn: 1, PriceOut: 10})
+	if err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+
+	if report.Total != 4 || report.Evaluated != 3 || report.Correct != 2 || report.Errors != 1 {
+		t.Errorf("report counts = %+v", report)
+	}
+	if report.Confusion[ActionAccept][ActionIgnore] != 1 || report.Confusion[ActionIgnore][ActionIgnore] != 1 {
+		t.Errorf("confusion = %v", report.Confusion)
+	}
+	if c := report.Classes[ActionAccept]; c.Precision != 1 || c.Recall != 0.5 || c.Support != 2 {
+		t.Errorf("accept class = %+v", c)
+	}
+	if report.PromptTokens != 4000 || report.Cost != 0.008 {
+		t.Errorf("tokens = %d, cost = %v", report.PromptTokens, report.Cost)
+	}
+	if len(report.Failures) != 2 {
+		t.Errorf("failures = %+v", report.Failures)
+	}
+
+	if _, err := Evaluate(context.Background(), strings.NewReader(""), complete, EvalOptions{Activity: "nope"}); err == nil {
+		t.Error("expected error for unknown activity")
+	}
+}
@@ -0,0 +1,104 @@
+package secops
+
+import (
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// ProposalTopicPrefix 提案生命周期事件在消息总线上的主题前缀, 完整主题为前缀加审计动作, 如 secops.proposal.created;
+// 订阅 secops.proposal.* 可收到全部事件
+const ProposalTopicPrefix = "secops.proposal."
+
+// maxPendingBusEvents 等待发布到消息总线的事件上限, 超出时丢弃
+const maxPendingBusEvents = 256
+
+// ProposalBusEvent 发布到消息总线的提案生命周期事件
+type ProposalBusEvent struct {
+	ProposalEvent
+	Proposal *ProposalBrief `json:"proposal,omitempty"` // 发布时的提案快照, 提案已归档删除时为空
+}
+
+// ProposalBrief 提案快照, 供订阅方展示提案并以 ID 发起决策
+type ProposalBrief struct {
+	ID             string           `json:"id"`
+	Type           string           `json:"type"`
+	Title          string           `json:"title"`
+	Summary        string           `json:"summary"`
+	Status         ProposalStatus   `json:"status"`
+	Severity       string           `json:"severity,omitempty"`
+	Confidence     int              `json:"confidence,omitempty"`
+	Recommendation string           `json:"recommendation,omitempty"`
+	Actions        []ProposalAction `json:"actions,omitempty"`
+	Assignee       string           `json:"assignee,omitempty"`
+	CaseID         string           `json:"caseId,omitempty"`
+	CreatedAt      time.Time        `json:"createdAt"`
+}
+
+// SetEventHandler 设置生命周期事件处理函数, 每条审计记录追加后调用; 调用时可能持有提案锁, 处理函数不能访问 ProposalService
+func (s *ProposalService) SetEventHandler(handler func(ProposalEvent)) {
+	s.audit.mu.Lock()
+	defer s.audit.mu.Unlock()
+	s.audit.onRecord = handler
+}
+
+// brief 在读锁下复制提案快照, 不加载已换出的提案
+func (s *ProposalService) brief(id string) (*ProposalBrief, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.proposals[id]
+	if !ok {
+		return nil, false
+	}
+	return &ProposalBrief{
+		ID:             p.ID,
+		Type:           p.Type,
+		Title:          p.Title,
+		Summary:        p.Summary,
+		Status:         p.Status,
+		Severity:       p.Severity,
+		Confidence:     p.Confidence,
+		Recommendation: p.Recommendation,
+		Actions:        append([]ProposalAction(nil), p.Actions...),
+		Assignee:       p.Assignee,
+		CaseID:         p.CaseID,
+		CreatedAt:      p.CreatedAt,
+	}, true
+}
+
+// queueBusEvent 将事件排入发布队列; 审计记录可能在提案锁内产生, 快照在 runBusEvents 中读取
+func (s *Service) queueBusEvent(ev ProposalEvent) {
+	select {
+	case s.busEvents <- ev:
+	default:
+		logger.WarnCF("secops", "Proposal event queue full, bus event dropped",
+			map[string]interface{}{
+				"id":     ev.ProposalID,
+				"action": ev.Action,
+			})
+	}
+}
+
+// runBusEvents 按产生顺序将提案生命周期事件发布到消息总线, 供其他通道订阅
+func (s *Service) runBusEvents() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case ev := <-s.busEvents:
+			s.publishBusEvent(ev)
+		}
+	}
+}
+
+// publishBusEvent 附上提案快照后发布一条事件
+func (s *Service) publishBusEvent(ev ProposalEvent) {
+	payload := ProposalBusEvent{ProposalEvent: ev}
+	if brief, ok := s.proposalService.brief(ev.ProposalID); ok {
+		payload.Proposal = brief
+	}
+	s.msgBus.Publish(bus.Event{Topic: ProposalTopicPrefix + ev.Action, Payload: payload})
+}
@@ -0,0 +1,53 @@
+package secops
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestProposalBusEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	svc := &Service{
+		config:          &config.SecOpsConfig{},
+		msgBus:          bus.NewMessageBus(),
+		proposalService: NewProposalService(),
+		busEvents:       make(chan ProposalEvent, maxPendingBusEvents),
+		ctx:             ctx,
+	}
+	svc.proposalService.SetEventHandler(svc.queueBusEvent)
+	svc.wg.Add(1)
+	go svc.runBusEvents()
+	defer func() {
+		cancel()
+		svc.wg.Wait()
+	}()
+
+	got := make(chan bus.Event, 10)
+	svc.msgBus.Subscribe(ProposalTopicPrefix+"*", func(evt bus.Event) { got <- evt })
+
+	p := NewProposal("risk", "撞库攻击", "同一 IP 短时间内大量登录失败", nil)
+	p.Severity = SeverityHigh
+	id := svc.proposalService.Create(p)
+	if err := svc.proposalService.Accept(id, DecisionRequest{Reason: "已确认", By: Actor{Name: "alice", Via: ViaDebugUI}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"secops.proposal.created", "secops.proposal.accepted"} {
+		select {
+		case evt := <-got:
+			payload, ok := evt.Payload.(ProposalBusEvent)
+			if evt.Topic != want || !ok || payload.ProposalID != id || payload.Proposal == nil || payload.Proposal.Title != "撞库攻击" {
+				t.Fatalf("event = %s %+v, want %s", evt.Topic, evt.Payload, want)
+			}
+			if want == "secops.proposal.accepted" && (payload.Actor.Name != "alice" || payload.To != ProposalStatusAccepted) {
+				t.Errorf("accepted event = %+v", payload.ProposalEvent)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s not published", want)
+		}
+	}
+}
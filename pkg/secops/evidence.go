@@ -0,0 +1,51 @@
+package secops
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+var (
+	sqlPrefixPattern  = regexp.MustCompile(`(?i)^(SELECT|WITH|INSERT|UPDATE|DELETE|SHOW|DESCRIBE|EXPLAIN)\b`)
+	httpRequestLine   = regexp.MustCompile(`^(GET|POST|PUT|DELETE|PATCH|HEAD|OPTIONS) \S+ HTTP/\d(\.\d)?`)
+	httpResponseLine  = regexp.MustCompile(`^HTTP/\d(\.\d)? \d{3}`)
+	knownEvidenceType = map[string]bool{
+		EvidenceTypeSQL:  true,
+		EvidenceTypeJSON: true,
+		EvidenceTypeHTTP: true,
+		EvidenceTypeText: true,
+	}
+)
+
+// DetectEvidenceType 根据内容识别证据类型
+func DetectEvidenceType(content string) string {
+	trimmed := strings.TrimSpace(content)
+	switch {
+	case trimmed == "":
+		return EvidenceTypeText
+	case (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)):
+		return EvidenceTypeJSON
+	case httpRequestLine.MatchString(trimmed) || httpResponseLine.MatchString(trimmed):
+		return EvidenceTypeHTTP
+	case sqlPrefixPattern.MatchString(trimmed):
+		return EvidenceTypeSQL
+	}
+	return EvidenceTypeText
+}
+
+// normalizeEvidence 补全内容类型并格式化 JSON
+func normalizeEvidence(ev *Evidence) {
+	ev.ContentType = strings.ToLower(strings.TrimSpace(ev.ContentType))
+	if !knownEvidenceType[ev.ContentType] {
+		ev.ContentType = DetectEvidenceType(ev.Content)
+	}
+
+	if ev.ContentType == EvidenceTypeJSON {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, []byte(strings.TrimSpace(ev.Content)), "", "  "); err == nil {
+			ev.Content = buf.String()
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package secops
+
+import "testing"
+
+func TestDetectEvidenceType(t *testing.T) {
+	tests := map[string]string{
+		`SELECT ip FROM access WHERE ip = '1.2.3.4'`:  EvidenceTypeSQL,
+		"with t as (select 1) select * from t":        EvidenceTypeSQL,
+		`{"host": "a.example.com", "risk": "sqli"}`:   EvidenceTypeJSON,
+		"POST /login HTTP/1.1\r\nHost: a.example.com": EvidenceTypeHTTP,
+		"HTTP/1.1 200 OK\r\nContent-Type: text/html":  EvidenceTypeHTTP,
+		"{not json":  EvidenceTypeText,
+		"plain note": EvidenceTypeText,
+	}
+	for content, want := range tests {
+		if got := DetectEvidenceType(content); got != want {
+			t.Errorf("DetectEvidenceType(%q) = %q, want %q", content, got, want)
+		}
+	}
+}
+
+func TestAddEvidence_FormatsJSON(t *testing.T) {
+	p := NewProposal("risk", "t", "s", nil)
+	p.AddEvidence("payload", `{"a":1}`)
+
+	if len(p.Evidence) != 1 || p.Evidence[0].ContentType != EvidenceTypeJSON {
+		t.Fatalf("unexpected evidence: %+v", p.Evidence)
+	}
+	if p.Evidence[0].Content != "{\n  \"a\": 1\n}" {
+		t.Errorf("JSON evidence should be indented, got %q", p.Evidence[0].Content)
+	}
+}
@@ -0,0 +1,477 @@
+package secops
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// executionTimeout 单次执行 Sheikah API 的超时时间
+const executionTimeout = 30 * time.Second
+
+// maxExecutionResponse 执行结果中保留的响应长度
+const maxExecutionResponse = 4096
+
+// 执行失败后的自动重试: 间隔从 executionRetryBase 起按次数翻倍, 最长 executionRetryMax;
+// 累计 maxExecutionAttempts 次失败后不再自动重试, 等待人工重试。
+// 同时等待自动重试的提案不超过 maxRetryQueue 个, 超出的只能人工重试。
+const (
+	executionRetryBase   = time.Minute
+	executionRetryMax    = 30 * time.Minute
+	maxExecutionAttempts = 5
+	maxRetryQueue        = 100
+	retryCheckInterval   = 30 * time.Second
+)
+
+// 执行状态
+const (
+	ExecutionQueued    = "queued"
+	ExecutionSucceeded = "succeeded"
+	ExecutionFailed    = "failed"
+)
+
+// ActionBinding 提案决策对应的 Sheikah API, 为空的动作只记录决策不执行
+type ActionBinding struct {
+	Accept string            `json:"accept,omitempty"` // 确认时调用的 API, 如 confirm_risk
+	Ignore string            `json:"ignore,omitempty"` // 忽略时调用的 API, 如 ignore_risk
+	Params map[string]string `json:"params,omitempty"` // 默认参数, 决策参数优先
+}
+
+// api 决策动作对应的 API
+func (b *ActionBinding) api(action string) string {
+	switch action {
+	case ActionAccept:
+		return b.Accept
+	case ActionIgnore:
+		return b.Ignore
+	}
+	return ""
+}
+
+// Execution 决策执行结果
+type Execution struct {
+	Action     string            `json:"action"`             // accept, ignore
+	API        string            `json:"api"`                // 调用的 API 标识
+	Params     map[string]string `json:"params"`             // 实际使用的参数
+	Status     string            `json:"status"`             // queued, succeeded, failed
+	Response   string            `json:"response,omitempty"` // API 响应, 超长时截断
+	Error      string            `json:"error,omitempty"`
+	Attempts   int               `json:"attempts"`              // 累计执行次数
+	NextRetry  *time.Time        `json:"nextRetryAt,omitempty"` // 下次自动重试时间, 为空表示不再自动重试
+	Items      []ItemExecution   `json:"items,omitempty"`       // 批量提案各条目的执行结果
+	StartedAt  time.Time         `json:"startedAt"`
+	FinishedAt time.Time         `json:"finishedAt"` // 零值表示仍在执行
+}
+
+// ItemExecution 批量提案中单个条目的执行结果
+type ItemExecution struct {
+	Index    int               `json:"index"`
+	Params   map[string]string `json:"params"`
+	Status   string            `json:"status,omitempty"` // succeeded, failed; 为空表示尚未执行
+	Response string            `json:"response,omitempty"`
+	Error    string            `json:"error,omitempty"`
+	Attempts int               `json:"attempts"`
+}
+
+// snapshot 复制执行结果, 执行过程中写回提案的是副本, 避免与读取方竞争
+func (e *Execution) snapshot() *Execution {
+	c := *e
+	c.Items = append([]ItemExecution(nil), e.Items...)
+	return &c
+}
+
+// truncateResponse 截断过长的 API 响应
+func truncateResponse(resp string) string {
+	if len(resp) > maxExecutionResponse {
+		return resp[:maxExecutionResponse] + "..."
+	}
+	return resp
+}
+
+// retryBackoff 第 attempts 次失败后的重试间隔
+func retryBackoff(attempts int) time.Duration {
+	d := executionRetryBase
+	for i := 1; i < attempts && d < executionRetryMax; i++ {
+		d *= 2
+	}
+	if d > executionRetryMax {
+		d = executionRetryMax
+	}
+	return d
+}
+
+// decidedStatus 执行成功后提案的状态
+func decidedStatus(action string) ProposalStatus {
+	if action == ActionIgnore {
+		return ProposalStatusIgnored
+	}
+	return ProposalStatusAccepted
+}
+
+// ActionExecutor 调用 Sheikah API, 返回响应内容
+type ActionExecutor func(ctx context.Context, api string, params map[string]string) (string, error)
+
+// defaultBindings 各类型提案默认绑定的 API
+var defaultBindings = map[string]ActionBinding{
+	"risk":    {Accept: "confirm_risk", Ignore: "ignore_risk"},
+	"weak":    {Accept: "confirm_weak", Ignore: "ignore_weak"},
+	"api_biz": {Accept: "create_business"},
+	"app":     {Accept: "create_app"},
+}
+
+// defaultBinding 按提案类型生成默认绑定, 以详情中的字段作为默认参数
+func defaultBinding(p *Proposal) *ActionBinding {
+	def, ok := defaultBindings[p.Type]
+	if !ok {
+		return nil
+	}
+	b := &ActionBinding{Accept: def.Accept, Ignore: def.Ignore, Params: make(map[string]string)}
+	for k, v := range p.Details {
+		switch v.(type) {
+		case string, float64, int, int64, bool:
+			b.Params[k] = fmt.Sprint(v)
+		}
+	}
+	// Sheikah 接口中使用 path 表示 URL 路径
+	if _, ok := b.Params["path"]; !ok && b.Params["url"] != "" {
+		b.Params["path"] = b.Params["url"]
+	}
+	return b
+}
+
+// SetExecutor 设置决策执行器, 未设置时只记录决策
+func (s *ProposalService) SetExecutor(executor ActionExecutor) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.executor = executor
+}
+
+// executeDecision 按提案绑定的 API 执行已记录的决策, 结果写回提案
+//
+// 参数优先级: 绑定默认参数 < 决策参数 (提案参数 < 模板 < 分析师填写);
+// 未填写 note 时使用决策理由。失败时提案进入 execution_failed 并按退避间隔排队重试。
+// 批量提案逐条执行, 条目参数优先; 重新执行时跳过已成功的条目, 每完成一条即更新进度。
+// 同一提案同时只执行一次, 正在执行时返回 nil。
+func (s *ProposalService) executeDecision(id string) *Execution {
+	s.mu.Lock()
+	p, ok := s.proposals[id]
+	executor := s.executor
+	if !ok || p.Decision == nil || executor == nil || s.executing[id] {
+		s.mu.Unlock()
+		return nil
+	}
+	action := p.Decision.Action
+	api, params := executionParams(p, action, p.Decision.Params, p.Decision.Reason)
+	if api == "" {
+		s.mu.Unlock()
+		return nil
+	}
+	attempts := 1
+	var prev *Execution
+	if p.Execution != nil && p.Execution.Action == action {
+		attempts = p.Execution.Attempts + 1
+		prev = p.Execution
+	}
+	items := p.Items
+	if s.executing == nil {
+		s.executing = make(map[string]bool)
+	}
+	s.executing[id] = true
+	s.mu.Unlock()
+
+	exec := &Execution{Action: action, API: api, Params: params, Attempts: attempts, StartedAt: time.Now()}
+	var err error
+	if len(items) == 0 {
+		var resp string
+		resp, err = callExecutor(executor, api, params)
+		exec.Response = truncateResponse(resp)
+	} else {
+		err = s.executeItems(id, executor, exec, items, prev)
+	}
+	exec.FinishedAt = time.Now()
+	if err != nil {
+		exec.Status = ExecutionFailed
+		exec.Error = err.Error()
+	} else {
+		exec.Status = ExecutionSucceeded
+	}
+
+	s.mu.Lock()
+	delete(s.executing, id)
+	var from, to ProposalStatus
+	if p, ok := s.proposals[id]; ok {
+		from = p.Status
+		if err != nil {
+			p.Status = ProposalStatusExecutionFailed
+			if attempts < maxExecutionAttempts && s.retryQueueLenLocked() < maxRetryQueue {
+				next := exec.FinishedAt.Add(retryBackoff(attempts))
+				exec.NextRetry = &next
+			}
+		} else {
+			p.Status = decidedStatus(action)
+		}
+		to = p.Status
+		p.Execution = exec
+		p.UpdatedAt = exec.FinishedAt
+		s.changed(id)
+	}
+	s.mu.Unlock()
+
+	executed := ProposalEvent{
+		ProposalID: id,
+		Action:     AuditExecuted,
+		Actor:      systemActor("executor"),
+		From:       from,
+		To:         to,
+		Params:     params,
+		Detail:     fmt.Sprintf("%s (attempt %d)", api, attempts),
+		At:         exec.FinishedAt,
+	}
+	if err != nil {
+		executed.Action = AuditExecutionFailed
+		executed.Detail += ": " + exec.Error
+	}
+	s.audit.record(executed)
+
+	fields := map[string]interface{}{
+		"id":       id,
+		"action":   action,
+		"api":      api,
+		"status":   exec.Status,
+		"attempts": attempts,
+	}
+	if err != nil {
+		fields["error"] = exec.Error
+		if exec.NextRetry != nil {
+			fields["next_retry_at"] = *exec.NextRetry
+		}
+		logger.WarnCF("secops", "Proposal execution failed", fields)
+	} else {
+		logger.InfoCF("secops", "Proposal executed", fields)
+	}
+	return exec
+}
+
+// executionParams 决策动作绑定的 API 及调用参数, 未绑定时 api 为空
+func executionParams(p *Proposal, action string, decisionParams map[string]string, reason string) (string, map[string]string) {
+	binding := p.Binding
+	if binding == nil {
+		binding = defaultBinding(p)
+	}
+	if binding == nil || binding.api(action) == "" {
+		return "", nil
+	}
+	params := make(map[string]string)
+	for k, v := range binding.Params {
+		params[k] = v
+	}
+	for k, v := range decisionParams {
+		params[k] = v
+	}
+	if _, ok := params["note"]; !ok {
+		params["note"] = reason
+	}
+	return binding.api(action), params
+}
+
+// itemParams 批量条目的调用参数, 条目参数优先
+func itemParams(base, item map[string]string) map[string]string {
+	params := make(map[string]string, len(base)+len(item))
+	for k, v := range base {
+		params[k] = v
+	}
+	for k, v := range item {
+		params[k] = v
+	}
+	return params
+}
+
+// callExecutor 带超时调用执行器
+func callExecutor(executor ActionExecutor, api string, params map[string]string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), executionTimeout)
+	defer cancel()
+	return executor(ctx, api, params)
+}
+
+// executeItems 逐条执行批量提案, 跳过上次已成功的条目; 有条目失败时返回汇总错误
+func (s *ProposalService) executeItems(id string, executor ActionExecutor, exec *Execution, items []map[string]string, prev *Execution) error {
+	exec.Items = make([]ItemExecution, len(items))
+	for i, item := range items {
+		exec.Items[i] = ItemExecution{Index: i, Params: itemParams(exec.Params, item)}
+		if prev != nil && i < len(prev.Items) {
+			exec.Items[i].Attempts = prev.Items[i].Attempts
+			if prev.Items[i].Status == ExecutionSucceeded {
+				exec.Items[i] = prev.Items[i]
+			}
+		}
+	}
+
+	failed := 0
+	for i := range exec.Items {
+		ie := &exec.Items[i]
+		if ie.Status == ExecutionSucceeded {
+			continue
+		}
+		resp, err := callExecutor(executor, exec.API, ie.Params)
+		ie.Attempts++
+		ie.Response = truncateResponse(resp)
+		if err != nil {
+			ie.Status = ExecutionFailed
+			ie.Error = err.Error()
+			failed++
+		} else {
+			ie.Status = ExecutionSucceeded
+			ie.Error = ""
+		}
+		s.recordProgress(id, exec)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d/%d items failed", failed, len(exec.Items))
+	}
+	return nil
+}
+
+// recordProgress 写回批量执行的中间进度
+func (s *ProposalService) recordProgress(id string, exec *Execution) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.proposals[id]; ok {
+		p.Execution = exec.snapshot()
+		s.changed(id)
+	}
+}
+
+// queueExecutionLocked 将已记录的决策排入执行队列, 调用方需持有锁; 未绑定 API 或未设置执行器时返回 false
+//
+// 排队期间 Execution 的状态为 queued, 沿用上一次同一动作执行的次数和条目结果, 执行时据此累计次数并跳过已成功的条目
+func (s *ProposalService) queueExecutionLocked(p *Proposal) bool {
+	if p.Decision == nil || s.executor == nil {
+		return false
+	}
+	action := p.Decision.Action
+	api, params := executionParams(p, action, p.Decision.Params, p.Decision.Reason)
+	if api == "" {
+		return false
+	}
+	queued := &Execution{Action: action, API: api, Params: params, Status: ExecutionQueued}
+	if prev := p.Execution; prev != nil && prev.Action == action {
+		queued.Attempts = prev.Attempts
+		queued.Items = append([]ItemExecution(nil), prev.Items...)
+	}
+	p.Execution = queued
+	s.execQueue = append(s.execQueue, p.ID)
+	return true
+}
+
+// wakeExecutions 通知执行重试协程处理执行队列
+func (s *ProposalService) wakeExecutions() {
+	select {
+	case s.execWake <- struct{}{}:
+	default:
+	}
+}
+
+// nextQueued 取出执行队列中的下一个提案
+func (s *ProposalService) nextQueued() (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.execQueue) == 0 {
+		return "", false
+	}
+	id := s.execQueue[0]
+	s.execQueue = s.execQueue[1:]
+	return id, true
+}
+
+// runQueued 依次执行队列中的决策并调用决策处理函数; ctx 取消时停止, 未执行的提案保持 queued, 重启后重新排队
+func (s *ProposalService) runQueued(ctx context.Context) {
+	for ctx.Err() == nil {
+		id, ok := s.nextQueued()
+		if !ok {
+			return
+		}
+		s.executeDecision(id)
+		s.decided(id)
+	}
+}
+
+// requeueLocked 重新排队加载时仍为 queued 的执行, 调用方需持有锁
+func (s *ProposalService) requeueLocked() {
+	for id, p := range s.proposals {
+		if p.Execution != nil && p.Execution.Status == ExecutionQueued {
+			s.execQueue = append(s.execQueue, id)
+		}
+	}
+	if len(s.execQueue) > 0 {
+		s.wakeExecutions()
+	}
+}
+
+// retryQueueLenLocked 等待自动重试的提案数, 调用方需持有锁
+func (s *ProposalService) retryQueueLenLocked() int {
+	n := 0
+	for _, p := range s.proposals {
+		if p.Status == ProposalStatusExecutionFailed && p.Execution != nil && p.Execution.NextRetry != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// dueRetries 到达重试时间的执行失败提案
+func (s *ProposalService) dueRetries(now time.Time) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := make([]string, 0)
+	for id, p := range s.proposals {
+		if p.Status != ProposalStatusExecutionFailed || p.Execution == nil || p.Execution.NextRetry == nil {
+			continue
+		}
+		if !p.Execution.NextRetry.After(now) && !s.executing[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// RetryExecution 立即重新执行失败的决策, 不受自动重试次数限制
+func (s *ProposalService) RetryExecution(id string, by Actor) (*Execution, error) {
+	p, ok := s.Get(id)
+	if !ok {
+		return nil, fmt.Errorf("proposal not found: %s", id)
+	}
+	if p.Status != ProposalStatusExecutionFailed {
+		return nil, fmt.Errorf("proposal execution has not failed: %s", p.Status)
+	}
+	s.audit.record(ProposalEvent{ProposalID: id, Action: AuditRetried, Actor: by})
+	exec := s.executeDecision(id)
+	if exec == nil {
+		return nil, fmt.Errorf("proposal execution already in progress")
+	}
+	return exec, nil
+}
+
+// runExecutionRetries 依次执行排队的决策, 并周期重试到期的失败执行
+func (s *Service) runExecutionRetries() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(retryCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-s.proposalService.execWake:
+			s.proposalService.runQueued(s.ctx)
+		case now := <-ticker.C:
+			for _, id := range s.proposalService.dueRetries(now) {
+				s.proposalService.executeDecision(id)
+			}
+		}
+	}
+}
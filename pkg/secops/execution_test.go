@@ -0,0 +1,293 @@
+package secops
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/tools/secops"
+)
+
+func TestAcceptExecutesBoundAPI(t *testing.T) {
+	s := NewProposalService()
+	var gotAPI string
+	var gotParams map[string]string
+	s.SetExecutor(func(ctx context.Context, api string, params map[string]string) (string, error) {
+		gotAPI, gotParams = api, params
+		return `{"code": 0}`, nil
+	})
+
+	p := NewProposal("risk", "SQL 注入", "", map[string]interface{}{
+		"host": "shop.example.com", "risk": "SQL注入", "content": "id=1'", "count": float64(3),
+	})
+	p.Binding = defaultBinding(p)
+	p.Parameters["content"] = Param{Key: "content", Value: "id=1' OR 1=1"}
+	id := s.Create(p)
+
+	if err := s.Accept(id, DecisionRequest{Reason: "确认注入", Params: map[string]string{"host": "edited.example.com"}}); err != nil {
+		t.Fatalf("Accept: %v", err)
+	}
+	s.runQueued(context.Background())
+	if gotAPI != "confirm_risk" {
+		t.Errorf("api = %q, want confirm_risk", gotAPI)
+	}
+	want := map[string]string{"host": "edited.example.com", "content": "id=1' OR 1=1", "risk": "SQL注入", "count": "3", "note": "确认注入"}
+	for k, v := range want {
+		if gotParams[k] != v {
+			t.Errorf("param %s = %q, want %q", k, gotParams[k], v)
+		}
+	}
+
+	got, _ := s.Get(id)
+	if got.Execution == nil || got.Execution.Status != ExecutionSucceeded || got.Execution.Response != `{"code": 0}` {
+		t.Errorf("execution = %+v", got.Execution)
+	}
+}
+
+func TestExecutionFailureKeepsDecision(t *testing.T) {
+	s := NewProposalService()
+	s.SetExecutor(func(ctx context.Context, api string, params map[string]string) (string, error) {
+		return "", errors.New("API returned error: 500 - boom")
+	})
+
+	// 未设置绑定时按类型使用默认绑定
+	id := s.Create(NewProposal("weak", "敏感信息泄露", "", map[string]interface{}{"url": "/export"}))
+	if err := s.Ignore(id, DecisionRequest{}); err != nil {
+		t.Fatalf("Ignore: %v", err)
+	}
+	s.runQueued(context.Background())
+	got, _ := s.Get(id)
+	if got.Status != ProposalStatusExecutionFailed || got.Decision == nil || got.Decision.Action != ActionIgnore {
+		t.Errorf("status = %s, decision = %+v; want execution_failed with ignore decision", got.Status, got.Decision)
+	}
+	if got.Execution == nil || got.Execution.API != "ignore_weak" || got.Execution.Status != ExecutionFailed || got.Execution.Error == "" {
+		t.Errorf("execution = %+v", got.Execution)
+	}
+	if got.Execution.Params["path"] != "/export" {
+		t.Errorf("path param = %q, want /export", got.Execution.Params["path"])
+	}
+
+	// 没有绑定 API 的动作不执行
+	id = s.Create(NewProposal("app", "新应用", "", nil))
+	if err := s.Ignore(id, DecisionRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.Get(id); got.Execution != nil {
+		t.Errorf("unexpected execution for unbound action: %+v", got.Execution)
+	}
+}
+
+func TestExecutionRetry(t *testing.T) {
+	s := NewProposalService()
+	fail := true
+	s.SetExecutor(func(ctx context.Context, api string, params map[string]string) (string, error) {
+		if fail {
+			return "", errors.New("API returned error: 500 - boom")
+		}
+		return "ok", nil
+	})
+
+	id := s.Create(NewProposal("risk", "SQL 注入", "", map[string]interface{}{"host": "a"}))
+	if err := s.Accept(id, DecisionRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	s.runQueued(context.Background())
+	got, _ := s.Get(id)
+	if got.Execution.Attempts != 1 || got.Execution.NextRetry == nil {
+		t.Fatalf("execution = %+v, want first attempt with retry scheduled", got.Execution)
+	}
+	next := *got.Execution.NextRetry
+	if d := next.Sub(got.Execution.FinishedAt); d != executionRetryBase {
+		t.Errorf("first backoff = %v, want %v", d, executionRetryBase)
+	}
+	if ids := s.dueRetries(next.Add(-time.Second)); len(ids) != 0 {
+		t.Errorf("retry due too early: %v", ids)
+	}
+	if ids := s.dueRetries(next); len(ids) != 1 || ids[0] != id {
+		t.Errorf("dueRetries = %v, want [%s]", ids, id)
+	}
+
+	// 自动重试次数用尽后不再排队, 仍可人工重试
+	for i := 2; i <= maxExecutionAttempts; i++ {
+		s.executeDecision(id)
+	}
+	got, _ = s.Get(id)
+	if got.Execution.Attempts != maxExecutionAttempts || got.Execution.NextRetry != nil {
+		t.Errorf("execution = %+v, want retries exhausted", got.Execution)
+	}
+
+	fail = false
+	exec, err := s.RetryExecution(id, Actor{Via: ViaAPI})
+	if err != nil || exec.Status != ExecutionSucceeded {
+		t.Fatalf("RetryExecution = %+v, %v", exec, err)
+	}
+	got, _ = s.Get(id)
+	if got.Status != ProposalStatusAccepted {
+		t.Errorf("status = %s, want accepted after successful retry", got.Status)
+	}
+	if _, err := s.RetryExecution(id, Actor{Via: ViaAPI}); err == nil {
+		t.Error("expected error retrying a succeeded execution")
+	}
+}
+
+func TestQueuedExecutionRequeuedOnLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "proposals.json")
+	s := NewProposalService()
+	if err := s.EnablePersistence(path); err != nil {
+		t.Fatal(err)
+	}
+	s.SetExecutor(func(ctx context.Context, api string, params map[string]string) (string, error) {
+		return "ok", nil
+	})
+	id := s.Create(NewProposal("weak", "弱口令", "", map[string]interface{}{"host": "a.example.com"}))
+	if _, err := s.DecideBulk([]string{id}, ActionAccept, DecisionRequest{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 停止前未执行的决策重启后重新排队
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := NewProposalService()
+	if err := reloaded.EnablePersistence(path); err != nil {
+		t.Fatal(err)
+	}
+	var calls int
+	reloaded.SetExecutor(func(ctx context.Context, api string, params map[string]string) (string, error) {
+		calls++
+		return "ok", nil
+	})
+	select {
+	case <-reloaded.execWake:
+	default:
+		t.Fatal("reloaded queue not signalled")
+	}
+	reloaded.runQueued(context.Background())
+	if p, _ := reloaded.Get(id); calls != 1 || p.Execution.Status != ExecutionSucceeded || p.Execution.Attempts != 1 {
+		t.Errorf("calls=%d execution=%+v", calls, p.Execution)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	want := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 16 * time.Minute, 30 * time.Minute, 30 * time.Minute}
+	for i, w := range want {
+		if got := retryBackoff(i + 1); got != w {
+			t.Errorf("retryBackoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+}
+
+func TestSheikahCallEscapesParams(t *testing.T) {
+	var body map[string]interface{}
+	var path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.EscapedPath()
+		data, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("invalid JSON body %s: %v", data, err)
+		}
+		w.Write([]byte(`{"code":0}`))
+	}))
+	defer srv.Close()
+
+	tool := secops.NewSecOpsSheikahAPITool(map[string]secops.APIConfig{
+		"update_app": {Method: "PUT", Path: "/app/$app_id", Body: `{"desc": "$app_desc"}`},
+	}, srv.URL, "")
+	if _, err := tool.Call(context.Background(), "update_app", map[string]string{"app_id": "a/1", "app_desc": `含 "引号", 逗号`}); err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if path != "/app/a%2F1" {
+		t.Errorf("path = %q", path)
+	}
+	if body["desc"] != `含 "引号", 逗号` {
+		t.Errorf("desc = %v", body["desc"])
+	}
+
+	if _, err := tool.Call(context.Background(), "missing", nil); err == nil {
+		t.Error("expected error for unknown api")
+	}
+}
+
+func TestExecutionItemsResumeFailed(t *testing.T) {
+	s := NewProposalService()
+	calls := map[string]int{}
+	broken := map[string]bool{"b": true}
+	s.SetExecutor(func(ctx context.Context, api string, params map[string]string) (string, error) {
+		calls[params["host"]]++
+		if broken[params["host"]] {
+			return "", errors.New("API returned error: 500 - boom")
+		}
+		return "ok " + params["host"], nil
+	})
+
+	p := NewProposal("risk", "批量确认", "", map[string]interface{}{"risk": "扫描器探测"})
+	p.Items = []map[string]string{{"host": "a"}, {"host": "b"}, {"host": "c"}}
+	id := s.Create(p)
+	if err := s.Accept(id, DecisionRequest{Reason: "批量"}); err != nil {
+		t.Fatal(err)
+	}
+	s.runQueued(context.Background())
+
+	got, _ := s.Get(id)
+	exec := got.Execution
+	if got.Status != ProposalStatusExecutionFailed || exec.Error != "1/3 items failed" || len(exec.Items) != 3 {
+		t.Fatalf("status = %s, execution = %+v", got.Status, exec)
+	}
+	if exec.Items[0].Status != ExecutionSucceeded || exec.Items[1].Status != ExecutionFailed || exec.Items[2].Status != ExecutionSucceeded {
+		t.Errorf("item statuses = %+v", exec.Items)
+	}
+	// 条目参数与提案详情、决策理由合并
+	if ps := exec.Items[2].Params; ps["host"] != "c" || ps["risk"] != "扫描器探测" || ps["note"] != "批量" {
+		t.Errorf("item params = %v", ps)
+	}
+
+	// 重试只执行失败的条目
+	broken["b"] = false
+	if _, err := s.RetryExecution(id, Actor{Via: ViaAPI}); err != nil {
+		t.Fatal(err)
+	}
+	if calls["a"] != 1 || calls["b"] != 2 || calls["c"] != 1 {
+		t.Errorf("calls = %v, want only b retried", calls)
+	}
+	got, _ = s.Get(id)
+	if got.Status != ProposalStatusAccepted || got.Execution.Items[1].Attempts != 2 || got.Execution.Items[1].Response != "ok b" {
+		t.Errorf("status = %s, items = %+v", got.Status, got.Execution.Items)
+	}
+}
+
+func TestDefaultBodyTemplates(t *testing.T) {
+	tool := secops.NewSecOpsSheikahAPITool(map[string]secops.APIConfig{
+		"confirm_risk": {Method: "POST", Path: "/risk/confirm", Body: riskEventsBody},
+		"ignore_weak":  {Method: "POST", Path: "/apiweak/manage/batch", Body: weakManageBody("ignore")},
+	}, "http://sheikah", "")
+	if err := tool.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	req, err := tool.Render("confirm_risk", map[string]string{"note": "批量", "items": `[{"host": "a", "risk": "r"}, {"host": "b", "risk": "r"}]`})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	var events []map[string]string
+	if err := json.Unmarshal([]byte(req.Body), &events); err != nil || len(events) != 2 || events[1]["host"] != "b" || events[1]["note"] != "批量" {
+		t.Errorf("risk body = %s (%v)", req.Body, err)
+	}
+
+	req, err = tool.Render("ignore_weak", map[string]string{"weak_name": "w", "host": "h"})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	var weak map[string]interface{}
+	if err := json.Unmarshal([]byte(req.Body), &weak); err != nil {
+		t.Fatalf("weak body = %s: %v", req.Body, err)
+	}
+	if _, ok := weak["message"]; ok || weak["tag"] != "ignore" || len(weak["apiWeakMgts"].([]interface{})) != 1 {
+		t.Errorf("weak body = %s, want single item without message", req.Body)
+	}
+}
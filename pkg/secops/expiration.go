@@ -0,0 +1,143 @@
+package secops
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// defaultExpireInterval 过期检查的默认间隔
+const defaultExpireInterval = 10 * time.Minute
+
+// ttlWildcard 未单独配置 TTL 的提案类型使用的键
+const ttlWildcard = "*"
+
+// parseExpiration 解析提案过期配置, 返回按类型的 TTL 和检查间隔; 未配置 TTL 时返回 nil
+func parseExpiration(cfg config.ProposalExpirationConfig) (map[string]time.Duration, time.Duration, error) {
+	if len(cfg.TTL) == 0 {
+		return nil, 0, nil
+	}
+
+	ttl := make(map[string]time.Duration, len(cfg.TTL))
+	for typ, v := range cfg.TTL {
+		if typ != ttlWildcard && !validProposalTypes[typ] {
+			return nil, 0, fmt.Errorf("unknown proposal type %q", typ)
+		}
+		d, err := parseTTL(v)
+		if err != nil {
+			return nil, 0, fmt.Errorf("ttl for %s: %w", typ, err)
+		}
+		ttl[typ] = d
+	}
+
+	interval := defaultExpireInterval
+	if cfg.Interval != "" {
+		d, err := time.ParseDuration(cfg.Interval)
+		if err != nil || d <= 0 {
+			return nil, 0, fmt.Errorf("invalid interval %q", cfg.Interval)
+		}
+		interval = d
+	}
+	return ttl, interval, nil
+}
+
+// parseTTL 解析 Go 时长 (如 "72h") 或天数 (如 "7d")
+func parseTTL(v string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(v, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", v)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", v)
+	}
+	return d, nil
+}
+
+// SetTTL 设置按提案类型的待处理时长, 键 "*" 为未单独配置类型的默认值; 未配置的类型不过期
+func (s *ProposalService) SetTTL(ttl map[string]time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ttl = ttl
+}
+
+// ttlLocked 某类提案的待处理时长, 0 表示不过期; 调用方需持有锁
+func (s *ProposalService) ttlLocked(proposalType string) time.Duration {
+	if d, ok := s.ttl[proposalType]; ok {
+		return d
+	}
+	return s.ttl[ttlWildcard]
+}
+
+// Expire 将创建后超过 TTL 仍未决策的提案标记为 expired, 已知悉且未到期的提案不过期; 返回本次过期的提案
+func (s *ProposalService) Expire(now time.Time) []*Proposal {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expired []*Proposal
+	for _, p := range s.proposals {
+		if p.Status != ProposalStatusPending {
+			continue
+		}
+		ttl := s.ttlLocked(p.Type)
+		if ttl <= 0 || now.Sub(p.CreatedAt) < ttl {
+			continue
+		}
+		if ack := p.Acknowledgement; ack != nil && now.Before(ack.Until) {
+			continue
+		}
+		p.Status = ProposalStatusExpired
+		p.UpdatedAt = now
+		expired = append(expired, p)
+	}
+	if len(expired) > 0 {
+		ids := make([]string, len(expired))
+		for i, p := range expired {
+			ids[i] = p.ID
+		}
+		s.changed(ids...)
+		for _, p := range expired {
+			logger.InfoCF("secops", "Proposal expired",
+				map[string]interface{}{
+					"id":    p.ID,
+					"type":  p.Type,
+					"title": p.Title,
+					"age":   now.Sub(p.CreatedAt).Round(time.Minute).String(),
+				})
+			s.audit.record(ProposalEvent{
+				ProposalID: p.ID,
+				Action:     AuditExpired,
+				Actor:      systemActor("janitor"),
+				From:       ProposalStatusPending,
+				To:         ProposalStatusExpired,
+				Detail:     "ttl " + s.ttlLocked(p.Type).String(),
+				At:         now,
+			})
+		}
+	}
+	return expired
+}
+
+// RunJanitor 按间隔将超时的待处理提案标记为过期, 直到 ctx 结束
+func (s *ProposalService) RunJanitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.Expire(time.Now())
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package secops
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestProposalExpire(t *testing.T) {
+	ttl, interval, err := parseExpiration(config.ProposalExpirationConfig{
+		TTL: map[string]string{"risk": "72h", "*": "7d"},
+	})
+	if err != nil || interval != defaultExpireInterval || ttl["*"] != 7*24*time.Hour {
+		t.Fatalf("parseExpiration: ttl=%v interval=%v err=%v", ttl, interval, err)
+	}
+
+	s := NewProposalService()
+	s.SetTTL(ttl)
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	add := func(id, typ string, age time.Duration) *Proposal {
+		p := NewProposal(typ, id, "", nil)
+		p.ID = id
+		p.CreatedAt = now.Add(-age)
+		s.Create(p)
+		return p
+	}
+	add("old-risk", "risk", 73*time.Hour)
+	add("new-risk", "risk", 60*time.Hour)
+	add("old-weak", "weak", 8*24*time.Hour)
+	add("new-weak", "weak", 73*time.Hour)
+	acked := add("acked", "risk", 100*time.Hour)
+	acked.Acknowledgement = &Acknowledgement{Until: now.Add(time.Hour)}
+	add("decided", "risk", 100*time.Hour)
+	if err := s.Ignore("decided", DecisionRequest{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expired := s.Expire(now)
+	got := map[string]bool{}
+	for _, p := range expired {
+		got[p.ID] = true
+	}
+	if len(expired) != 2 || !got["old-risk"] || !got["old-weak"] {
+		t.Fatalf("expired = %v", got)
+	}
+	if p, _ := s.Get("old-risk"); p.Status != ProposalStatusExpired || !p.UpdatedAt.Equal(now) {
+		t.Errorf("old-risk status=%s updated=%v", p.Status, p.UpdatedAt)
+	}
+	if err := s.Accept("old-risk", DecisionRequest{}); err == nil {
+		t.Error("expected error accepting an expired proposal")
+	}
+	if again := s.Expire(now); len(again) != 0 {
+		t.Errorf("second run expired %d proposals", len(again))
+	}
+
+	page, total, err := s.GetFiltered(ProposalFilter{Statuses: []ProposalStatus{ProposalStatusExpired}, Sort: "title"})
+	if err != nil || total != 2 || page[0].ID != "old-risk" {
+		t.Errorf("status=expired filter: total=%d err=%v", total, err)
+	}
+
+	// 知悉到期后照常过期
+	if expired := s.Expire(now.Add(2 * time.Hour)); len(expired) != 1 || expired[0].ID != "acked" {
+		t.Errorf("after ack expiry: %v", expired)
+	}
+}
+
+func TestParseExpirationInvalid(t *testing.T) {
+	for _, cfg := range []config.ProposalExpirationConfig{
+		{TTL: map[string]string{"bogus": "1h"}},
+		{TTL: map[string]string{"risk": "soon"}},
+		{TTL: map[string]string{"risk": "0d"}},
+		{TTL: map[string]string{"risk": "1h"}, Interval: "-1m"},
+	} {
+		if _, _, err := parseExpiration(cfg); err == nil {
+			t.Errorf("%+v: expected error", cfg)
+		}
+	}
+	if ttl, _, err := parseExpiration(config.ProposalExpirationConfig{}); err != nil || ttl != nil {
+		t.Errorf("empty config: ttl=%v err=%v", ttl, err)
+	}
+}
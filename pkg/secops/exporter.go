@@ -0,0 +1,139 @@
+package secops
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportFields 导出 CSV 的列; 与导入同名的列可直接再导入
+var exportFields = []string{
+	"id", "type", "title", "summary", "status", "severity", "confidence", "recommendation", "techniques",
+	"created_at", "updated_at", "created_by", "run_id", "case_id", "assignee",
+	"decision", "reason", "decided_by", "decided_at", "override", "execution_status", "details",
+}
+
+// ExportProposals 按条件导出全部提案 (含已换出到磁盘的), 逐条写出, 返回导出条数。
+// format 为 csv 或 json; 忽略分页参数, 排序同 ProposalFilter
+func ExportProposals(svc *ProposalService, w io.Writer, f ProposalFilter, format string) (int, error) {
+	if format != "csv" && format != "json" {
+		return 0, fmt.Errorf("unsupported export format %q (csv, json)", format)
+	}
+	f.Offset, f.Limit = 0, 0
+	if err := f.Validate(); err != nil {
+		return 0, err
+	}
+	proposals := svc.exportable(f)
+
+	if format == "json" {
+		if _, err := io.WriteString(w, "["); err != nil {
+			return 0, err
+		}
+		for i, p := range proposals {
+			data, err := json.Marshal(p)
+			if err != nil {
+				return i, err
+			}
+			sep := ",\n"
+			if i == 0 {
+				sep = "\n"
+			}
+			if _, err := io.WriteString(w, sep+string(data)); err != nil {
+				return i, err
+			}
+		}
+		_, err := io.WriteString(w, "\n]\n")
+		return len(proposals), err
+	}
+
+	// 带 BOM, Excel 打开时按 UTF-8 识别
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return 0, err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(exportFields); err != nil {
+		return 0, err
+	}
+	for i, p := range proposals {
+		if err := cw.Write(exportRow(p)); err != nil {
+			return i, err
+		}
+	}
+	cw.Flush()
+	return len(proposals), cw.Error()
+}
+
+// exportable 满足条件的全部提案, 已换出的提案只读取不加载回内存
+func (s *ProposalService) exportable(f ProposalFilter) []*Proposal {
+	s.mu.RLock()
+	result := make([]*Proposal, 0, len(s.proposals))
+	s.eachIndexedLocked(func(p *Proposal) {
+		if !f.match(p) {
+			return
+		}
+		if p, ok := s.resolveLocked(p); ok {
+			result = append(result, p)
+		}
+	})
+	s.mu.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		return f.less(result[i], result[j])
+	})
+	return result
+}
+
+// exportRow 提案的 CSV 行, 列顺序同 exportFields
+func exportRow(p *Proposal) []string {
+	row := []string{
+		p.ID, p.Type, p.Title, p.Summary, string(p.Status), p.Severity, exportConfidence(p.Confidence), p.Recommendation, strings.Join(p.Techniques, ";"),
+		exportTime(p.CreatedAt), exportTime(p.UpdatedAt), exportActor(p.CreatedBy), p.RunID, p.CaseID, p.Assignee,
+	}
+	if d := p.Decision; d != nil {
+		row = append(row, d.Action, d.Reason, exportActor(d.By), exportTime(d.DecidedAt), fmt.Sprint(d.Override))
+	} else {
+		row = append(row, "", "", "", "", "")
+	}
+	if p.Execution != nil {
+		row = append(row, p.Execution.Status)
+	} else {
+		row = append(row, "")
+	}
+	details := ""
+	if len(p.Details) > 0 {
+		data, _ := json.Marshal(p.Details)
+		details = string(data)
+	}
+	return append(row, details)
+}
+
+// exportConfidence 置信度, 未填写时为空
+func exportConfidence(c int) string {
+	if c == 0 {
+		return ""
+	}
+	return strconv.Itoa(c)
+}
+
+func exportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// exportActor 操作者名称, 未记录名称时为来源
+func exportActor(a *Actor) string {
+	if a == nil {
+		return ""
+	}
+	if a.Name != "" {
+		return a.Name
+	}
+	return a.Via
+}
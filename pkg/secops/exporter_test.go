@@ -0,0 +1,73 @@
+package secops
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestExportProposals(t *testing.T) {
+	ps := NewProposalService()
+	ps.SetMemoryLimit(config.MemoryLimitConfig{MaxItems: 2})
+	if err := ps.EnablePersistence(filepath.Join(t.TempDir(), "proposals.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	ignored := NewProposal("risk", "SQL 注入", "扫描器探测", map[string]interface{}{"ip": "203.0.113.45"})
+	ignoredID := ps.Create(ignored)
+	if err := ps.Ignore(ignoredID, DecisionRequest{Reason: "误报", By: Actor{Name: "alice", Via: ViaDebugUI}}); err != nil {
+		t.Fatal(err)
+	}
+	ps.Create(NewProposal("weak", "弱口令", "", nil))
+	ps.Create(NewProposal("host", "WebShell", "", nil))
+	if u := ps.usage(); u.Offloaded != 1 {
+		t.Fatalf("usage = %+v, want the ignored proposal offloaded", u)
+	}
+
+	var buf bytes.Buffer
+	n, err := ExportProposals(ps, &buf, ProposalFilter{Statuses: []ProposalStatus{ProposalStatusIgnored}}, "csv")
+	if err != nil || n != 1 {
+		t.Fatalf("csv export = %d, %v", n, err)
+	}
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(buf.String(), "\ufeff"))).ReadAll()
+	if err != nil || len(records) != 2 {
+		t.Fatalf("csv = %q, %v", buf.String(), err)
+	}
+	row := make(map[string]string)
+	for i, col := range records[0] {
+		row[col] = records[1][i]
+	}
+	if row["id"] != ignoredID || row["decision"] != ActionIgnore || row["reason"] != "误报" || row["decided_by"] != "alice" || row["details"] != `{"ip":"203.0.113.45"}` {
+		t.Errorf("csv row = %v", row)
+	}
+	if _, ok := ps.offloaded[ignoredID]; !ok {
+		t.Error("export must not load offloaded proposals back into memory")
+	}
+
+	// 导出的 CSV 可直接再导入
+	report, err := ImportProposals(NewProposalService(), strings.NewReader(buf.String()), ImportOptions{Format: "csv", DryRun: true})
+	if err != nil || report.Imported != 1 {
+		t.Errorf("re-import = %+v, %v", report, err)
+	}
+
+	buf.Reset()
+	if n, err = ExportProposals(ps, &buf, ProposalFilter{Sort: "title"}, "json"); err != nil || n != 3 {
+		t.Fatalf("json export = %d, %v", n, err)
+	}
+	var items []Proposal
+	if err := json.Unmarshal(buf.Bytes(), &items); err != nil {
+		t.Fatalf("json = %q: %v", buf.String(), err)
+	}
+	if len(items) != 3 || items[0].Title != "SQL 注入" || items[0].Decision == nil || items[0].Details["ip"] != "203.0.113.45" {
+		t.Errorf("json items = %+v", items)
+	}
+
+	if _, err := ExportProposals(ps, &buf, ProposalFilter{}, "xlsx"); err == nil {
+		t.Error("expected unsupported format error")
+	}
+}
@@ -0,0 +1,332 @@
+package secops
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/tools/secops"
+)
+
+// 内置功能开关
+const (
+	FeatureStreamingChat = "streaming_chat" // Debug UI 流式对话, 关闭时前端回退到整体返回
+	FeatureAutoMode      = "auto_mode"      // auto 模式活动真实执行处置, 关闭时该活动以试运行执行
+)
+
+// builtinFeature 内置功能开关的默认值和说明
+type builtinFeature struct {
+	enabled     bool
+	description string
+}
+
+var builtinFeatures = map[string]builtinFeature{
+	FeatureStreamingChat: {enabled: true, description: "Debug UI 流式对话"},
+	FeatureAutoMode:      {enabled: true, description: "auto 模式活动真实执行处置, 关闭的活动以试运行执行"},
+}
+
+// featureScopeInstance 作用于整个实例的运行时覆盖
+const featureScopeInstance = "*"
+
+// 功能开关生效值的来源
+const (
+	FeatureSourceDefault          = "default"
+	FeatureSourceConfig           = "config"
+	FeatureSourceTenant           = "tenant"
+	FeatureSourceOverride         = "override"
+	FeatureSourceActivity         = "activity"
+	FeatureSourceActivityOverride = "activity_override"
+)
+
+// FeatureOverride 运行时覆盖
+type FeatureOverride struct {
+	Activity  string    `json:"activity,omitempty"` // 为空时作用于整个实例
+	Enabled   bool      `json:"enabled"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	UpdatedBy string    `json:"updatedBy,omitempty"`
+}
+
+// FeatureFlagState 功能开关的当前状态
+type FeatureFlagState struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Enabled     bool              `json:"enabled"`              // 不区分活动时的生效值
+	Source      string            `json:"source"`               // 生效值来源: default, config, tenant, override
+	Activities  map[string]bool   `json:"activities,omitempty"` // 配置或运行时覆盖了的活动的生效值
+	Overrides   []FeatureOverride `json:"overrides"`            // 运行时覆盖
+}
+
+// featureFlagStore 功能开关: 配置与运行时覆盖, 覆盖持久化到 workspace/secops/feature_flags.json;
+// 配置文件本身不被改写
+type featureFlagStore struct {
+	tenant    string
+	flags     map[string]config.FeatureFlagConfig
+	overrides map[string]map[string]FeatureOverride // 开关 -> 活动名或 "*" -> 覆盖
+	path      string
+	mu        sync.RWMutex
+}
+
+func newFeatureFlagStore(tenant string, flags map[string]config.FeatureFlagConfig) *featureFlagStore {
+	return &featureFlagStore{
+		tenant:    tenant,
+		flags:     flags,
+		overrides: make(map[string]map[string]FeatureOverride),
+	}
+}
+
+// load 加载已持久化的运行时覆盖
+func (fs *featureFlagStore) load(path string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	fs.path = path
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &fs.overrides); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if fs.overrides == nil {
+		fs.overrides = make(map[string]map[string]FeatureOverride)
+	}
+	return nil
+}
+
+// setConfig 替换配置中的开关, 用于热加载; 运行时覆盖保留
+func (fs *featureFlagStore) setConfig(flags map[string]config.FeatureFlagConfig) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.flags = flags
+}
+
+// known 是否为内置或配置中的开关
+func (fs *featureFlagStore) known(name string) bool {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	return knownFeature(name, fs.flags)
+}
+
+func knownFeature(name string, flags map[string]config.FeatureFlagConfig) bool {
+	if _, ok := builtinFeatures[name]; ok {
+		return true
+	}
+	_, ok := flags[name]
+	return ok
+}
+
+// enabled 开关对活动的生效值, activity 为空时取实例范围
+func (fs *featureFlagStore) enabled(name, activity string) bool {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+	enabled, _ := fs.resolveLocked(name, activity)
+	return enabled
+}
+
+// resolveLocked 按 活动覆盖 > 租户覆盖 > 配置 > 内置默认值 求生效值, 运行时覆盖优先于同一范围的配置
+func (fs *featureFlagStore) resolveLocked(name, activity string) (bool, string) {
+	cfg := fs.flags[name]
+	overrides := fs.overrides[name]
+
+	if activity != "" {
+		if o, ok := overrides[activity]; ok {
+			return o.Enabled, FeatureSourceActivityOverride
+		}
+		if v, ok := cfg.Activities[activity]; ok {
+			return v, FeatureSourceActivity
+		}
+	}
+	if o, ok := overrides[featureScopeInstance]; ok {
+		return o.Enabled, FeatureSourceOverride
+	}
+	if fs.tenant != "" {
+		if v, ok := cfg.Tenants[fs.tenant]; ok {
+			return v, FeatureSourceTenant
+		}
+	}
+	if cfg.Enabled != nil {
+		return *cfg.Enabled, FeatureSourceConfig
+	}
+	return builtinFeatures[name].enabled, FeatureSourceDefault
+}
+
+// setOverride 设置或清除 (enabled 为 nil) 运行时覆盖并持久化
+func (fs *featureFlagStore) setOverride(name, activity string, enabled *bool, actor string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	scope := activity
+	if scope == "" {
+		scope = featureScopeInstance
+	}
+	if enabled == nil {
+		delete(fs.overrides[name], scope)
+		if len(fs.overrides[name]) == 0 {
+			delete(fs.overrides, name)
+		}
+	} else {
+		if fs.overrides[name] == nil {
+			fs.overrides[name] = make(map[string]FeatureOverride)
+		}
+		fs.overrides[name][scope] = FeatureOverride{Enabled: *enabled, UpdatedAt: time.Now(), UpdatedBy: actor}
+	}
+
+	if fs.path == "" {
+		return
+	}
+	if err := saveJSONAtomic(fs.path, fs.overrides); err != nil {
+		logger.ErrorCF("secops", "Failed to persist feature flags",
+			map[string]interface{}{
+				"path":  fs.path,
+				"error": err.Error(),
+			})
+	}
+}
+
+// states 全部开关的当前状态, 按名称排序
+func (fs *featureFlagStore) states() []FeatureFlagState {
+	fs.mu.RLock()
+	defer fs.mu.RUnlock()
+
+	names := make([]string, 0, len(builtinFeatures)+len(fs.flags))
+	for name := range builtinFeatures {
+		names = append(names, name)
+	}
+	for name := range fs.flags {
+		if _, ok := builtinFeatures[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	states := make([]FeatureFlagState, 0, len(names))
+	for _, name := range names {
+		st := FeatureFlagState{Name: name, Description: fs.flags[name].Description, Overrides: []FeatureOverride{}}
+		if st.Description == "" {
+			st.Description = builtinFeatures[name].description
+		}
+		st.Enabled, st.Source = fs.resolveLocked(name, "")
+
+		activities := make(map[string]bool)
+		for activity := range fs.flags[name].Activities {
+			activities[activity] = true
+		}
+		for scope, o := range fs.overrides[name] {
+			if scope != featureScopeInstance {
+				activities[scope] = true
+				o.Activity = scope
+			}
+			st.Overrides = append(st.Overrides, o)
+		}
+		if len(activities) > 0 {
+			st.Activities = make(map[string]bool, len(activities))
+			for activity := range activities {
+				st.Activities[activity], _ = fs.resolveLocked(name, activity)
+			}
+		}
+		sort.Slice(st.Overrides, func(i, j int) bool { return st.Overrides[i].Activity < st.Overrides[j].Activity })
+		states = append(states, st)
+	}
+	return states
+}
+
+// FeatureEnabled 功能开关对活动的生效值, activity 为空时取实例范围; 服务未启用时取内置默认值
+func (s *Service) FeatureEnabled(name, activity string) bool {
+	if s == nil || s.features == nil {
+		return builtinFeatures[name].enabled
+	}
+	return s.features.enabled(name, activity)
+}
+
+// FeatureFlags 全部功能开关的当前状态
+func (s *Service) FeatureFlags() []FeatureFlagState {
+	if s == nil || s.features == nil {
+		return newFeatureFlagStore("", nil).states()
+	}
+	return s.features.states()
+}
+
+// SetFeatureOverride 设置运行时覆盖, 立即生效并在重启后保持; enabled 为 nil 时清除覆盖。
+// activity 为空时作用于整个实例
+func (s *Service) SetFeatureOverride(name, activity string, enabled *bool, actor string) error {
+	if !s.features.known(name) {
+		return fmt.Errorf("feature not found: %s", name)
+	}
+	if activity != "" {
+		if _, ok := s.activityConfig(activity); !ok {
+			return fmt.Errorf("activity not found: %s", activity)
+		}
+	}
+
+	s.features.setOverride(name, activity, enabled, actor)
+
+	fields := map[string]interface{}{
+		"feature":  name,
+		"activity": activity,
+		"actor":    actor,
+	}
+	if enabled != nil {
+		fields["enabled"] = *enabled
+	} else {
+		fields["cleared"] = true
+	}
+	logger.InfoCF("secops", "Feature flag override changed", fields)
+	return nil
+}
+
+// validateFeatures 校验功能开关配置和数据源引用的开关
+func validateFeatures(cfg *config.SecOpsConfig) error {
+	for name := range cfg.Features {
+		if name == "" {
+			return fmt.Errorf("features: empty flag name")
+		}
+	}
+	for name, ds := range cfg.DataSources {
+		if ds.Feature != "" && !knownFeature(ds.Feature, cfg.Features) {
+			return fmt.Errorf("data_sources.%s: unknown feature %q", name, ds.Feature)
+		}
+	}
+	return nil
+}
+
+// activityKey 上下文中正在执行的活动名
+type activityKey struct{}
+
+// withActivity 返回带有活动名的上下文, 供按活动求值的功能开关使用
+func withActivity(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, activityKey{}, name)
+}
+
+// activityFromContext 上下文中正在执行的活动名, 对话中调用时为空
+func activityFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(activityKey{}).(string)
+	return name
+}
+
+// flaggedSource 受功能开关控制的数据源, 开关对当前活动关闭时拒绝查询
+type flaggedSource struct {
+	secops.QuerySource
+	feature  string
+	features *featureFlagStore
+}
+
+func (f flaggedSource) Query(ctx context.Context, query string, params map[string]string) ([]string, [][]interface{}, error) {
+	if !f.features.enabled(f.feature, activityFromContext(ctx)) {
+		return nil, nil, fmt.Errorf("data source disabled by feature flag %q", f.feature)
+	}
+	return f.QuerySource.Query(ctx, query, params)
+}
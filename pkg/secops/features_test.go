@@ -0,0 +1,116 @@
+package secops
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func boolPtr(v bool) *bool { return &v }
+
+func TestFeatureFlagResolve(t *testing.T) {
+	fs := newFeatureFlagStore("acme", map[string]config.FeatureFlagConfig{
+		FeatureAutoMode: {
+			Enabled:    boolPtr(false),
+			Tenants:    map[string]bool{"acme": true, "globex": false},
+			Activities: map[string]bool{"risk_analysis": false},
+		},
+		"splunk_backend": {Activities: map[string]bool{"k8s_audit_analysis": true}},
+	})
+
+	cases := []struct {
+		name, activity string
+		want           bool
+		source         string
+	}{
+		{FeatureStreamingChat, "", true, FeatureSourceDefault},
+		{FeatureAutoMode, "", true, FeatureSourceTenant},
+		{FeatureAutoMode, "weak_analysis", true, FeatureSourceTenant},
+		{FeatureAutoMode, "risk_analysis", false, FeatureSourceActivity},
+		{"splunk_backend", "", false, FeatureSourceDefault},
+		{"splunk_backend", "k8s_audit_analysis", true, FeatureSourceActivity},
+		{"unknown", "", false, FeatureSourceDefault},
+	}
+	for _, c := range cases {
+		fs.mu.RLock()
+		got, source := fs.resolveLocked(c.name, c.activity)
+		fs.mu.RUnlock()
+		if got != c.want || source != c.source {
+			t.Errorf("%s/%s = %v (%s), want %v (%s)", c.name, c.activity, got, source, c.want, c.source)
+		}
+	}
+
+	// 运行时覆盖优先于同一范围的配置, 活动范围优先于实例范围
+	fs.setOverride(FeatureAutoMode, "", boolPtr(false), "alice")
+	fs.setOverride(FeatureAutoMode, "risk_analysis", boolPtr(true), "alice")
+	if fs.enabled(FeatureAutoMode, "weak_analysis") {
+		t.Error("instance override not applied")
+	}
+	if !fs.enabled(FeatureAutoMode, "risk_analysis") {
+		t.Error("activity override not applied")
+	}
+	fs.setOverride(FeatureAutoMode, "", nil, "alice")
+	if !fs.enabled(FeatureAutoMode, "") {
+		t.Error("cleared override still applied")
+	}
+}
+
+func TestFeatureFlagStates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feature_flags.json")
+	fs := newFeatureFlagStore("", map[string]config.FeatureFlagConfig{
+		"splunk_backend": {Description: "Splunk 数据源", Activities: map[string]bool{"k8s_audit_analysis": true}},
+	})
+	if err := fs.load(path); err != nil {
+		t.Fatal(err)
+	}
+	fs.setOverride(FeatureStreamingChat, "", boolPtr(false), "alice")
+
+	// 重启后覆盖仍然生效
+	reloaded := newFeatureFlagStore("", fs.flags)
+	if err := reloaded.load(path); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]FeatureFlagState{}
+	var names []string
+	for _, st := range reloaded.states() {
+		got[st.Name] = st
+		names = append(names, st.Name)
+	}
+	if strings.Join(names, ",") != "auto_mode,splunk_backend,streaming_chat" {
+		t.Errorf("flags = %v", names)
+	}
+	if st := got[FeatureStreamingChat]; st.Enabled || st.Source != FeatureSourceOverride || len(st.Overrides) != 1 || st.Overrides[0].UpdatedBy != "alice" {
+		t.Errorf("streaming_chat = %+v", st)
+	}
+	if st := got["splunk_backend"]; st.Enabled || !st.Activities["k8s_audit_analysis"] || st.Description != "Splunk 数据源" {
+		t.Errorf("splunk_backend = %+v", st)
+	}
+}
+
+func TestFlaggedSource(t *testing.T) {
+	fs := newFeatureFlagStore("", map[string]config.FeatureFlagConfig{
+		"splunk_backend": {Activities: map[string]bool{"k8s_audit_analysis": true}},
+	})
+	src := flaggedSource{QuerySource: hostEventSource{store: newHostEventStore()}, feature: "splunk_backend", features: fs}
+
+	if _, _, err := src.Query(context.Background(), "pending_host_events", nil); err == nil || !strings.Contains(err.Error(), "splunk_backend") {
+		t.Errorf("query outside activity = %v, want disabled", err)
+	}
+	if _, _, err := src.Query(withActivity(context.Background(), "k8s_audit_analysis"), "pending_host_events", nil); err != nil && strings.Contains(err.Error(), "feature flag") {
+		t.Errorf("query in enabled activity = %v", err)
+	}
+}
+
+func TestValidateFeatures(t *testing.T) {
+	cfg := &config.SecOpsConfig{DataSources: map[string]config.DataSourceConfig{"splunk": {Driver: "splunk", Feature: "splunk_backend"}}}
+	if err := validateFeatures(cfg); err == nil {
+		t.Error("expected error for unknown feature")
+	}
+	cfg.Features = map[string]config.FeatureFlagConfig{"splunk_backend": {}}
+	if err := validateFeatures(cfg); err != nil {
+		t.Errorf("validateFeatures: %v", err)
+	}
+}
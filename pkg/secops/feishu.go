@@ -0,0 +1,346 @@
+package secops
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// maxFeishuSummary 卡片中摘要的最大字符数, 完整内容在 Debug UI 中查看
+const maxFeishuSummary = 500
+
+// ErrFeishuToken 卡片回调的 Verification Token 与任何飞书通知目标都不匹配
+var ErrFeishuToken = errors.New("invalid feishu verification token")
+
+// feishuTemplates 严重级别对应的卡片标题颜色
+var feishuTemplates = map[string]string{
+	SeverityCritical: "red",
+	SeverityHigh:     "orange",
+	SeverityMedium:   "yellow",
+	SeverityLow:      "blue",
+	SeverityInfo:     "grey",
+}
+
+// feishuStatusError 飞书接口返回的业务错误, 如签名校验失败、频率限制
+type feishuStatusError struct {
+	Code int
+	Msg  string
+}
+
+func (e *feishuStatusError) Error() string {
+	return fmt.Sprintf("feishu returned code %d: %s", e.Code, e.Msg)
+}
+
+// signFeishu 计算机器人签名: base64(HMAC-SHA256(key = timestamp + "\n" + secret, 空消息))
+func signFeishu(secret, timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(timestamp+"\n"+secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// postFeishu 向群机器人 webhook 发送消息; 配置了 secret 时附带时间戳和签名
+//
+// 飞书在 HTTP 200 的响应体中用 code 表示业务错误, 非 0 时视为失败且不重试 (频率限制除外)。
+func postFeishu(ctx context.Context, client *http.Client, target config.NotifyTargetConfig, msg map[string]interface{}) error {
+	if target.Secret != "" {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		msg["timestamp"] = ts
+		msg["sign"] = signFeishu(target.Secret, ts)
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ = io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &webhookStatusError{Code: resp.StatusCode, Status: resp.Status}
+	}
+	var result struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if json.Unmarshal(body, &result) == nil && result.Code != 0 {
+		// 11232: 发送频率超限, 可以重试
+		if result.Code == 11232 {
+			return &webhookStatusError{Code: http.StatusTooManyRequests, Status: result.Msg}
+		}
+		return &feishuStatusError{Code: result.Code, Msg: result.Msg}
+	}
+	return nil
+}
+
+// feishuMessage 将通知转换为飞书消息: 新提案和提醒发送带按钮的卡片, 决策结果发送不带按钮的卡片, 其他发送文本
+func feishuMessage(content string, payload map[string]interface{}) map[string]interface{} {
+	event, _ := payload["event"].(string)
+	p, _ := payload["proposal"].(*Proposal)
+	if p == nil {
+		return map[string]interface{}{
+			"msg_type": "text",
+			"content":  map[string]string{"text": content},
+		}
+	}
+
+	title := p.Title
+	switch event {
+	case "proposal.reminder":
+		title = "[提醒] " + title
+	case "proposal.accepted":
+		title = "[已确认] " + title
+	case "proposal.ignored":
+		title = "[已忽略] " + title
+	}
+	return map[string]interface{}{
+		"msg_type": "interactive",
+		"card":     feishuProposalCard(p, title, ""),
+	}
+}
+
+// feishuProposalCard 提案卡片; 提案待处理时附带确认/忽略按钮, 按钮回传提案 ID 和动作
+//
+// note 显示在卡片末尾, 用于回调后展示操作结果或失败原因。
+func feishuProposalCard(p *Proposal, title, note string) map[string]interface{} {
+	severity := severityOf(p)
+	template := feishuTemplates[severity]
+	if template == "" {
+		template = "grey"
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "**类型**: %s　**级别**: %s　**状态**: %s\n", p.Type, strings.ToUpper(severity), p.Status)
+	if p.CaseID != "" {
+		fmt.Fprintf(&sb, "**案件**: %s\n", p.CaseID)
+	}
+	if p.Recommendation != "" {
+		fmt.Fprintf(&sb, "**Agent 建议**: %s\n", p.Recommendation)
+	}
+	if len(p.Techniques) > 0 {
+		fmt.Fprintf(&sb, "**ATT&CK**: %s\n", strings.Join(p.Techniques, ", "))
+	}
+	if summary := []rune(p.Summary); len(summary) > maxFeishuSummary {
+		fmt.Fprintf(&sb, "\n%s...\n", string(summary[:maxFeishuSummary]))
+	} else if p.Summary != "" {
+		fmt.Fprintf(&sb, "\n%s\n", p.Summary)
+	}
+	fmt.Fprintf(&sb, "\n提案 ID: %s", p.ID)
+
+	elements := []interface{}{
+		map[string]interface{}{
+			"tag":  "div",
+			"text": map[string]string{"tag": "lark_md", "content": sb.String()},
+		},
+	}
+
+	if p.Status == ProposalStatusPending {
+		button := func(label, style, action string) map[string]interface{} {
+			return map[string]interface{}{
+				"tag":   "button",
+				"text":  map[string]string{"tag": "plain_text", "content": label},
+				"type":  style,
+				"value": map[string]string{"proposal_id": p.ID, "action": action},
+			}
+		}
+		elements = append(elements, map[string]interface{}{
+			"tag": "action",
+			"actions": []interface{}{
+				button("确认", "primary", ActionAccept),
+				button("忽略", "default", ActionIgnore),
+			},
+		})
+	} else if p.Decision != nil {
+		decided := "已确认"
+		if p.Decision.Action == ActionIgnore {
+			decided = "已忽略"
+		}
+		if p.Decision.By != nil && p.Decision.By.Name != "" {
+			decided += " (" + p.Decision.By.Name + ")"
+		}
+		if p.Decision.Reason != "" {
+			decided += ": " + p.Decision.Reason
+		}
+		if p.Execution != nil && p.Execution.Error != "" {
+			decided += "\n执行失败: " + p.Execution.Error
+		}
+		note = strings.TrimSpace(decided + "\n" + note)
+	}
+
+	if note != "" {
+		elements = append(elements, map[string]interface{}{
+			"tag":      "note",
+			"elements": []interface{}{map[string]string{"tag": "plain_text", "content": note}},
+		})
+	}
+
+	return map[string]interface{}{
+		"config": map[string]interface{}{"wide_screen_mode": true, "update_multi": true},
+		"header": map[string]interface{}{
+			"title":    map[string]string{"tag": "plain_text", "content": title},
+			"template": template,
+		},
+		"elements": elements,
+	}
+}
+
+// feishuCallback 卡片回调请求体, 兼容旧版卡片回调和 2.0 版 card.action.trigger 事件
+type feishuCallback struct {
+	Type      string `json:"type"`      // url_verification 时为地址校验请求
+	Challenge string `json:"challenge"` // 地址校验需原样返回
+	Token     string `json:"token"`
+	Encrypt   string `json:"encrypt"` // 配置了 Encrypt Key 时的加密请求体
+
+	// 旧版卡片回调
+	OpenID string           `json:"open_id"`
+	Action feishuCardAction `json:"action"`
+
+	// 2.0 版回调
+	Schema string             `json:"schema"`
+	Header feishuEventHeader  `json:"header"`
+	Event  *feishuActionEvent `json:"event"`
+}
+
+type feishuEventHeader struct {
+	EventType string `json:"event_type"`
+	Token     string `json:"token"`
+}
+
+type feishuActionEvent struct {
+	Operator struct {
+		OpenID string `json:"open_id"`
+	} `json:"operator"`
+	Action feishuCardAction `json:"action"`
+}
+
+type feishuCardAction struct {
+	Tag   string            `json:"tag"`
+	Value map[string]string `json:"value"`
+}
+
+// feishuTokenValid Verification Token 是否与某个飞书通知目标匹配
+func (n *Notifier) feishuTokenValid(token string) bool {
+	if token == "" {
+		return false
+	}
+	for _, t := range n.cfg.Targets {
+		if t.Type != NotifyTargetFeishu || t.VerificationToken == "" {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(t.VerificationToken)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// FeishuCallback 处理飞书消息卡片回调, 将按钮操作映射为提案的确认/忽略
+//
+// 返回值直接作为 HTTP 响应体: 地址校验返回 challenge, 旧版回调返回更新后的卡片,
+// 2.0 版回调返回 toast 和卡片。决策失败 (如已被处理、需要填写理由) 不返回错误, 而是在卡片上提示原因;
+// 只有 Verification Token 不匹配或请求体无法解析时返回错误。
+func (s *Service) FeishuCallback(body []byte) (interface{}, error) {
+	var cb feishuCallback
+	if err := json.Unmarshal(body, &cb); err != nil {
+		return nil, fmt.Errorf("invalid feishu callback: %w", err)
+	}
+	if cb.Encrypt != "" {
+		return nil, fmt.Errorf("encrypted feishu callbacks are not supported, leave Encrypt Key empty")
+	}
+
+	token, openID, action := cb.Token, cb.OpenID, cb.Action
+	v2 := cb.Schema == "2.0"
+	if v2 {
+		token = cb.Header.Token
+		if cb.Event != nil {
+			openID, action = cb.Event.Operator.OpenID, cb.Event.Action
+		}
+	}
+	if s.notifier == nil || !s.notifier.feishuTokenValid(token) {
+		return nil, ErrFeishuToken
+	}
+	if cb.Type == "url_verification" {
+		return map[string]string{"challenge": cb.Challenge}, nil
+	}
+
+	id, act := action.Value["proposal_id"], action.Value["action"]
+	if id == "" || (act != ActionAccept && act != ActionIgnore) {
+		return nil, fmt.Errorf("invalid feishu card action")
+	}
+
+	by := Actor{Name: "feishu", Via: ViaFeishu}
+	if openID != "" {
+		by.Name += ":" + openID
+	}
+	req := DecisionRequest{By: by}
+	var err error
+	if act == ActionAccept {
+		err = s.proposalService.Accept(id, req)
+	} else {
+		err = s.proposalService.Ignore(id, req)
+	}
+
+	note := ""
+	if err != nil {
+		note = "操作失败: " + err.Error()
+		if errors.Is(err, ErrOverrideReasonRequired) {
+			note = "操作失败: 与 Agent 建议相反, 请在 Debug UI 中填写理由后决策"
+		}
+		logger.WarnCF("secops", "Feishu card action failed",
+			map[string]interface{}{
+				"proposal_id": id,
+				"action":      act,
+				"actor":       by.Name,
+				"error":       err.Error(),
+			})
+	} else {
+		logger.InfoCF("secops", "Feishu card action applied",
+			map[string]interface{}{
+				"proposal_id": id,
+				"action":      act,
+				"actor":       by.Name,
+			})
+	}
+
+	p, ok := s.proposalService.Get(id)
+	if !ok {
+		if v2 {
+			return map[string]interface{}{"toast": map[string]string{"type": "error", "content": "提案不存在: " + id}}, nil
+		}
+		return map[string]interface{}{}, nil
+	}
+	card := feishuProposalCard(p, p.Title, note)
+	if !v2 {
+		return card, nil
+	}
+	toast := map[string]string{"type": "success", "content": "已处理"}
+	if err != nil {
+		toast = map[string]string{"type": "error", "content": note}
+	}
+	return map[string]interface{}{
+		"toast": toast,
+		"card":  map[string]interface{}{"type": "raw", "data": card},
+	}, nil
+}
@@ -0,0 +1,147 @@
+package secops
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestNotifierFeishuCard(t *testing.T) {
+	var mu sync.Mutex
+	var msgs []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&msg)
+		mu.Lock()
+		msgs = append(msgs, msg)
+		mu.Unlock()
+		if strings.HasSuffix(r.URL.Path, "/bad") {
+			w.Write([]byte(`{"code":19021,"msg":"sign match fail or timestamp is not within one hour from current time"}`))
+			return
+		}
+		w.Write([]byte(`{"code":0,"msg":"success"}`))
+	}))
+	defer srv.Close()
+
+	n, err := NewNotifier(config.NotificationConfig{
+		Targets: map[string]config.NotifyTargetConfig{
+			"lark": {Type: NotifyTargetFeishu, URL: srv.URL + "/hook", Secret: "s3cret"},
+			"bad":  {Type: NotifyTargetFeishu, URL: srv.URL + "/bad"},
+		},
+		Routes: []config.NotifyRouteConfig{{Targets: []string{"lark"}}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewNotifier: %v", err)
+	}
+
+	p := &Proposal{ID: "p1", Type: "risk", Title: "SQL 注入", Severity: SeverityHigh, Status: ProposalStatusPending, Summary: "来自 203.0.113.45"}
+	n.Notify(context.Background(), p)
+
+	if len(msgs) != 1 {
+		t.Fatalf("messages = %d", len(msgs))
+	}
+	msg := msgs[0]
+	ts, _ := msg["timestamp"].(string)
+	if msg["msg_type"] != "interactive" || ts == "" || msg["sign"] != signFeishu("s3cret", ts) {
+		t.Fatalf("message = %v", msg)
+	}
+	raw, _ := json.Marshal(msg["card"])
+	for _, want := range []string{`"template":"orange"`, `"content":"SQL 注入"`, `"action":"accept","proposal_id":"p1"`, `"action":"ignore","proposal_id":"p1"`} {
+		if !strings.Contains(string(raw), want) {
+			t.Errorf("card missing %s: %s", want, raw)
+		}
+	}
+
+	// 业务错误 (签名校验失败) 不重试, 测试通知报告失败原因
+	n.retryBackoff = 0
+	result, err := n.Test(context.Background(), "bad")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != NotifyTestFailed || !strings.Contains(result.Error, "19021") {
+		t.Errorf("result = %+v", result)
+	}
+	if len(msgs) != 2 || msgs[1]["msg_type"] != "text" {
+		t.Errorf("messages = %v", msgs)
+	}
+	var se *feishuStatusError
+	if err := postFeishu(context.Background(), n.client, n.cfg.Targets["bad"], map[string]interface{}{}); !errors.As(err, &se) || retryable(err) {
+		t.Errorf("postFeishu error = %v", err)
+	}
+
+	if _, err := NewNotifier(config.NotificationConfig{
+		Targets: map[string]config.NotifyTargetConfig{"lark": {Type: NotifyTargetFeishu}},
+	}, nil); err == nil {
+		t.Error("expected error for feishu target without url")
+	}
+}
+
+func TestFeishuCallback(t *testing.T) {
+	n, err := NewNotifier(config.NotificationConfig{
+		Targets: map[string]config.NotifyTargetConfig{
+			"lark": {Type: NotifyTargetFeishu, URL: "https://open.feishu.cn/open-apis/bot/v2/hook/x", VerificationToken: "vt"},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := &Service{proposalService: NewProposalService(), notifier: n}
+	a := svc.proposalService.Create(&Proposal{Type: "risk", Title: "撞库", Status: ProposalStatusPending})
+	b := svc.proposalService.Create(&Proposal{Type: "risk", Title: "扫描", Status: ProposalStatusPending})
+
+	call := func(body string) (string, error) {
+		resp, err := svc.FeishuCallback([]byte(body))
+		raw, _ := json.Marshal(resp)
+		return string(raw), err
+	}
+
+	if out, err := call(`{"type":"url_verification","challenge":"c1","token":"vt"}`); err != nil || out != `{"challenge":"c1"}` {
+		t.Errorf("url_verification = %s, %v", out, err)
+	}
+	if _, err := call(`{"type":"url_verification","challenge":"c1","token":"wrong"}`); !errors.Is(err, ErrFeishuToken) {
+		t.Errorf("wrong token: err = %v", err)
+	}
+	if _, err := call(`{"encrypt":"abc"}`); err == nil {
+		t.Error("expected error for encrypted callback")
+	}
+
+	// 旧版回调返回更新后的卡片, 按钮被移除
+	v1 := `{"open_id":"ou_alice","token":"vt","action":{"tag":"button","value":{"proposal_id":"` + a + `","action":"accept"}}}`
+	out, err := call(v1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := svc.proposalService.Get(a)
+	if p.Status != ProposalStatusAccepted || p.Decision.By == nil || *p.Decision.By != (Actor{Name: "feishu:ou_alice", Via: ViaFeishu}) {
+		t.Errorf("proposal = %+v, decision = %+v", p, p.Decision)
+	}
+	if strings.Contains(out, `"tag":"button"`) || !strings.Contains(out, "已确认 (feishu:ou_alice)") {
+		t.Errorf("card = %s", out)
+	}
+
+	// 重复点击: 卡片提示失败原因
+	if out, err = call(v1); err != nil || !strings.Contains(out, "操作失败") {
+		t.Errorf("repeat = %s, %v", out, err)
+	}
+
+	// 2.0 版回调返回 toast 和卡片
+	v2 := `{"schema":"2.0","header":{"event_type":"card.action.trigger","token":"vt"},
+		"event":{"operator":{"open_id":"ou_bob"},"action":{"tag":"button","value":{"proposal_id":"` + b + `","action":"ignore"}}}}`
+	if out, err = call(v2); err != nil || !strings.Contains(out, `"toast":{"content":"已处理","type":"success"}`) || !strings.Contains(out, `"type":"raw"`) {
+		t.Errorf("v2 = %s, %v", out, err)
+	}
+	if p, _ := svc.proposalService.Get(b); p.Status != ProposalStatusIgnored {
+		t.Errorf("status = %s", p.Status)
+	}
+
+	if _, err := call(`{"token":"vt","action":{"value":{"proposal_id":"` + b + `","action":"delete"}}}`); err == nil || errors.Is(err, ErrFeishuToken) {
+		t.Errorf("invalid action: err = %v", err)
+	}
+}
@@ -0,0 +1,519 @@
+package secops
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// 产物类型
+const (
+	ArtifactWAFRule     = "waf_rule"
+	ArtifactSigmaRule   = "sigma_rule"
+	ArtifactSuppression = "suppression"
+	ArtifactSQLTemplate = "sql_template"
+)
+
+// artifactLayout 各类产物在仓库中的默认目录和扩展名
+var artifactLayout = map[string]struct{ dir, ext string }{
+	ArtifactWAFRule:     {"waf", ".conf"},
+	ArtifactSigmaRule:   {"sigma", ".yml"},
+	ArtifactSuppression: {"suppressions", ".txt"},
+	ArtifactSQLTemplate: {"sql", ".sql"},
+}
+
+var artifactNameReplacer = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+const (
+	defaultGitRepo        = "secops/config-repo"
+	defaultGitBranch      = "main"
+	defaultGitAuthorName  = "soclaw"
+	defaultGitAuthorEmail = "soclaw@localhost"
+	defaultGitHubAPI      = "https://api.github.com"
+
+	// gitBranchPrefix 开启 Pull Request 时每个提案的变更分支前缀
+	gitBranchPrefix = "soclaw/proposal-"
+
+	maxCommitSubject = 72
+)
+
+// Artifact 提案附带的规则或配置, 分析师确认后提交到 Git 仓库
+type Artifact struct {
+	Kind    string `json:"kind"`    // 产物类型: waf_rule, sigma_rule, suppression, sql_template
+	Name    string `json:"name"`    // 文件名, 未带扩展名时按类型补全
+	Content string `json:"content"` // 文件内容
+}
+
+// ArtifactChange 产物提交结果
+type ArtifactChange struct {
+	Branch      string    `json:"branch,omitempty"`      // 提交所在分支
+	Commit      string    `json:"commit,omitempty"`      // 提交哈希; 为空且无错误时表示内容与仓库一致, 无需提交
+	Files       []string  `json:"files,omitempty"`       // 仓库内相对路径
+	PullRequest string    `json:"pullRequest,omitempty"` // Pull Request 地址
+	Error       string    `json:"error,omitempty"`
+	At          time.Time `json:"at"`
+}
+
+// validateArtifact 校验产物类型、文件名和内容
+func validateArtifact(a Artifact) error {
+	if _, ok := artifactLayout[a.Kind]; !ok {
+		return fmt.Errorf("unknown artifact kind %q", a.Kind)
+	}
+	if strings.TrimSpace(a.Content) == "" {
+		return fmt.Errorf("artifact %q has no content", a.Name)
+	}
+	_, err := artifactFileName(a)
+	return err
+}
+
+// artifactFileName 规范化文件名: 去除目录部分防止写出仓库, 未带扩展名时按类型补全
+func artifactFileName(a Artifact) (string, error) {
+	name := path.Base(strings.ReplaceAll(a.Name, `\`, "/"))
+	name = strings.Trim(artifactNameReplacer.ReplaceAllString(name, "-"), "-.")
+	if name == "" {
+		return "", fmt.Errorf("invalid artifact name %q", a.Name)
+	}
+	if path.Ext(name) == "" {
+		name += artifactLayout[a.Kind].ext
+	}
+	return name, nil
+}
+
+// gitOps 将产物写入 Git 工作副本并提交, 按配置推送到远程仓库或创建 Pull Request
+type gitOps struct {
+	dir         string
+	remote      string
+	branch      string
+	paths       map[string]string
+	authorName  string
+	authorEmail string
+	pr          config.GitPullRequestConfig
+	client      *http.Client
+
+	// mu 工作副本同时只处理一个提案
+	mu sync.Mutex
+}
+
+// newGitOps 校验配置并创建 Git 集成, 未开启时返回 nil; 工作副本在首次提交时初始化或克隆
+func newGitOps(cfg config.GitOpsConfig, workspace string) (*gitOps, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	g := &gitOps{
+		dir:         cfg.Repo,
+		remote:      cfg.Remote,
+		branch:      cfg.Branch,
+		paths:       make(map[string]string, len(artifactLayout)),
+		authorName:  cfg.AuthorName,
+		authorEmail: cfg.AuthorEmail,
+		pr:          cfg.PullRequest,
+		client:      &http.Client{Timeout: 30 * time.Second},
+	}
+	if g.dir == "" {
+		g.dir = defaultGitRepo
+	}
+	if !filepath.IsAbs(g.dir) && workspace != "" {
+		g.dir = filepath.Join(workspace, g.dir)
+	}
+	if g.branch == "" {
+		g.branch = defaultGitBranch
+	}
+	if strings.HasPrefix(g.branch, "-") || strings.ContainsAny(g.branch, " ~^:?*[\\") {
+		return nil, fmt.Errorf("invalid branch %q", g.branch)
+	}
+	if g.authorName == "" {
+		g.authorName = defaultGitAuthorName
+	}
+	if g.authorEmail == "" {
+		g.authorEmail = defaultGitAuthorEmail
+	}
+
+	for kind, layout := range artifactLayout {
+		g.paths[kind] = layout.dir
+	}
+	for kind, dir := range cfg.Paths {
+		if _, ok := artifactLayout[kind]; !ok {
+			return nil, fmt.Errorf("unknown artifact kind %q in paths", kind)
+		}
+		if dir != "" && !filepath.IsLocal(dir) {
+			return nil, fmt.Errorf("path for %s must be relative to the repository: %q", kind, dir)
+		}
+		g.paths[kind] = filepath.ToSlash(filepath.Clean(dir))
+	}
+
+	if g.pr.Enabled {
+		switch g.pr.Provider {
+		case "":
+			g.pr.Provider = "github"
+		case "github", "gitea", "gitlab":
+		default:
+			return nil, fmt.Errorf("unknown pull_request.provider %q", g.pr.Provider)
+		}
+		if g.pr.APIURL == "" && g.pr.Provider == "github" {
+			g.pr.APIURL = defaultGitHubAPI
+		}
+		if g.remote == "" || g.pr.Repo == "" || g.pr.Token == "" {
+			return nil, fmt.Errorf("pull_request requires remote, pull_request.repo and pull_request.token")
+		}
+		u, err := url.Parse(g.pr.APIURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid pull_request.api_url: %q", g.pr.APIURL)
+		}
+	}
+	return g, nil
+}
+
+// run 在 dir 中执行 git 命令, 返回去除首尾空白的输出
+func (g *gitOps) run(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// git 在工作副本中执行 git 命令
+func (g *gitOps) git(ctx context.Context, args ...string) (string, error) {
+	return g.run(ctx, g.dir, args...)
+}
+
+// ensureRepoLocked 工作副本不存在时克隆远程仓库, 未配置远程仓库时初始化空仓库
+func (g *gitOps) ensureRepoLocked(ctx context.Context) error {
+	if _, err := os.Stat(filepath.Join(g.dir, ".git")); err == nil {
+		return nil
+	}
+	if g.remote != "" {
+		parent := filepath.Dir(g.dir)
+		if err := os.MkdirAll(parent, 0700); err != nil {
+			return err
+		}
+		_, err := g.run(ctx, parent, "clone", "-q", g.remote, g.dir)
+		return err
+	}
+	if err := os.MkdirAll(g.dir, 0700); err != nil {
+		return err
+	}
+	if _, err := g.git(ctx, "init", "-q"); err != nil {
+		return err
+	}
+	_, err := g.git(ctx, "symbolic-ref", "HEAD", "refs/heads/"+g.branch)
+	return err
+}
+
+// refExists 引用是否存在
+func (g *gitOps) refExists(ctx context.Context, ref string) bool {
+	_, err := g.git(ctx, "rev-parse", "--verify", "-q", ref)
+	return err == nil
+}
+
+// checkoutLocked 切换到本次提交的分支
+//
+// 直接提交到目标分支时, 先在远程分支之上变基此前推送失败的本地提交;
+// 开启 Pull Request 时从远程目标分支新建变更分支
+func (g *gitOps) checkoutLocked(ctx context.Context, branch string) error {
+	remoteBase := ""
+	if g.remote != "" {
+		if _, err := g.git(ctx, "fetch", "-q", "--prune", "origin"); err != nil {
+			return err
+		}
+		if g.refExists(ctx, "refs/remotes/origin/"+g.branch) {
+			remoteBase = "origin/" + g.branch
+		}
+	}
+
+	if branch != g.branch {
+		if remoteBase == "" {
+			return fmt.Errorf("base branch %s not found on remote", g.branch)
+		}
+		_, err := g.git(ctx, "checkout", "-q", "-f", "-B", branch, remoteBase)
+		return err
+	}
+
+	switch {
+	case g.refExists(ctx, "refs/heads/"+g.branch):
+		if _, err := g.git(ctx, "checkout", "-q", "-f", g.branch); err != nil {
+			return err
+		}
+		if remoteBase != "" {
+			if _, err := g.git(ctx, "rebase", "-q", remoteBase); err != nil {
+				g.git(ctx, "rebase", "--abort")
+				return err
+			}
+		}
+		return nil
+	case remoteBase != "":
+		_, err := g.git(ctx, "checkout", "-q", "-f", "-B", g.branch, remoteBase)
+		return err
+	default:
+		// 空仓库, 首次提交创建目标分支
+		_, err := g.git(ctx, "symbolic-ref", "HEAD", "refs/heads/"+g.branch)
+		return err
+	}
+}
+
+// files 各产物在仓库中的相对路径
+func (g *gitOps) files(artifacts []Artifact) ([]string, error) {
+	files := make([]string, len(artifacts))
+	for i, a := range artifacts {
+		if err := validateArtifact(a); err != nil {
+			return nil, err
+		}
+		name, _ := artifactFileName(a)
+		files[i] = path.Join(g.paths[a.Kind], name)
+	}
+	return files, nil
+}
+
+// commit 写入提案的产物并提交, 按配置推送或创建 Pull Request
+//
+// 内容与仓库一致时不产生提交, 返回的 Commit 为空。出错时返回已完成部分的结果
+func (g *gitOps) commit(ctx context.Context, p *Proposal) (*ArtifactChange, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	branch := g.branch
+	if g.pr.Enabled {
+		branch = gitBranchPrefix + strings.Trim(artifactNameReplacer.ReplaceAllString(p.ID, "-"), "-.")
+	}
+	change := &ArtifactChange{Branch: branch, At: time.Now()}
+
+	files, err := g.files(p.Artifacts)
+	if err != nil {
+		return change, err
+	}
+	change.Files = files
+
+	if err := g.ensureRepoLocked(ctx); err != nil {
+		return change, err
+	}
+	if err := g.checkoutLocked(ctx, branch); err != nil {
+		return change, err
+	}
+
+	for i, a := range p.Artifacts {
+		target := filepath.Join(g.dir, filepath.FromSlash(files[i]))
+		if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+			return change, err
+		}
+		content := a.Content
+		if !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		if err := os.WriteFile(target, []byte(content), 0644); err != nil {
+			return change, err
+		}
+	}
+	if _, err := g.git(ctx, append([]string{"add", "--"}, files...)...); err != nil {
+		return change, err
+	}
+	if _, err := g.git(ctx, "diff", "--cached", "--quiet"); err == nil {
+		return change, nil
+	}
+
+	subject, body := commitMessage(p, files)
+	if _, err := g.git(ctx, "-c", "user.name="+g.authorName, "-c", "user.email="+g.authorEmail,
+		"commit", "-q", "-m", subject, "-m", body); err != nil {
+		return change, err
+	}
+	if change.Commit, err = g.git(ctx, "rev-parse", "HEAD"); err != nil {
+		return change, err
+	}
+
+	if g.remote == "" {
+		return change, nil
+	}
+	push := []string{"push", "-q", "origin", branch}
+	if g.pr.Enabled {
+		// 变更分支每次从目标分支重建, 重新提交时覆盖
+		push = []string{"push", "-q", "-f", "origin", branch}
+	}
+	if _, err := g.git(ctx, push...); err != nil {
+		return change, err
+	}
+	if g.pr.Enabled {
+		if change.PullRequest, err = g.createPullRequest(ctx, branch, subject, body); err != nil {
+			return change, err
+		}
+	}
+	return change, nil
+}
+
+// commitMessage 生成提交说明: 标题为提案类型和标题, 正文包含提案、决策和文件清单
+func commitMessage(p *Proposal, files []string) (string, string) {
+	subject := fmt.Sprintf("secops(%s): %s", p.Type, strings.Join(strings.Fields(p.Title), " "))
+	if utf8.RuneCountInString(subject) > maxCommitSubject {
+		subject = string([]rune(subject)[:maxCommitSubject-3]) + "..."
+	}
+
+	var sb strings.Builder
+	if p.Summary != "" {
+		sb.WriteString(strings.TrimSpace(p.Summary))
+		sb.WriteString("\n\n")
+	}
+	fmt.Fprintf(&sb, "Proposal: %s\n", p.ID)
+	if p.Severity != "" {
+		fmt.Fprintf(&sb, "Severity: %s\n", p.Severity)
+	}
+	if len(p.Techniques) > 0 {
+		fmt.Fprintf(&sb, "ATT&CK: %s\n", strings.Join(p.Techniques, ", "))
+	}
+	if d := p.Decision; d != nil {
+		fmt.Fprintf(&sb, "Decision: %s at %s\n", d.Action, d.DecidedAt.UTC().Format(time.RFC3339))
+		if d.By != nil {
+			fmt.Fprintf(&sb, "Approved-by: %s (%s)\n", d.By.Name, d.By.Via)
+		}
+		if d.Reason != "" {
+			fmt.Fprintf(&sb, "Reason: %s\n", d.Reason)
+		}
+	}
+	sb.WriteString("\nFiles:\n")
+	for _, f := range files {
+		fmt.Fprintf(&sb, "- %s\n", f)
+	}
+	return subject, strings.TrimRight(sb.String(), "\n")
+}
+
+// createPullRequest 创建 Pull Request (GitLab 为 Merge Request), 返回其地址
+func (g *gitOps) createPullRequest(ctx context.Context, branch, title, description string) (string, error) {
+	api := strings.TrimRight(g.pr.APIURL, "/")
+	var endpoint string
+	var payload map[string]string
+	if g.pr.Provider == "gitlab" {
+		endpoint = api + "/projects/" + url.PathEscape(g.pr.Repo) + "/merge_requests"
+		payload = map[string]string{"source_branch": branch, "target_branch": g.branch, "title": title, "description": description}
+	} else {
+		endpoint = api + "/repos/" + g.pr.Repo + "/pulls"
+		payload = map[string]string{"head": branch, "base": g.branch, "title": title, "body": description}
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	switch g.pr.Provider {
+	case "gitlab":
+		req.Header.Set("PRIVATE-TOKEN", g.pr.Token)
+	case "gitea":
+		req.Header.Set("Authorization", "token "+g.pr.Token)
+	default:
+		req.Header.Set("Authorization", "Bearer "+g.pr.Token)
+		req.Header.Set("Accept", "application/vnd.github+json")
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("%s pull request error %d: %s", g.pr.Provider, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	var created struct {
+		HTMLURL string `json:"html_url"`
+		WebURL  string `json:"web_url"`
+	}
+	json.Unmarshal(respBody, &created)
+	if created.WebURL != "" {
+		return created.WebURL, nil
+	}
+	return created.HTMLURL, nil
+}
+
+// recordChange 记录产物提交结果并写入审计
+func (s *ProposalService) recordChange(id string, change *ArtifactChange, by Actor) {
+	s.mu.Lock()
+	if p, ok := s.lookupLocked(id); ok {
+		p.Change = change
+		p.UpdatedAt = change.At
+		s.changed(id)
+	}
+	s.mu.Unlock()
+
+	ev := ProposalEvent{ProposalID: id, Action: AuditCommitted, Actor: by, At: change.At}
+	switch {
+	case change.Error != "":
+		ev.Action = AuditCommitFailed
+		ev.Detail = change.Error
+	case change.Commit == "":
+		ev.Detail = "no changes: " + strings.Join(change.Files, ", ")
+	default:
+		ev.Detail = change.Branch + "@" + change.Commit
+		if change.PullRequest != "" {
+			ev.Detail += " " + change.PullRequest
+		}
+	}
+	s.audit.record(ev)
+}
+
+// commitArtifacts 提交已确认提案附带的产物, 未附带产物或未确认时跳过
+func (s *Service) commitArtifacts(p *Proposal) {
+	if p.Decision == nil || p.Decision.Action != ActionAccept || len(p.Artifacts) == 0 {
+		return
+	}
+	s.CommitArtifacts(p.ID, systemActor("git"))
+}
+
+// CommitArtifacts 将已确认提案附带的规则和配置提交到 Git 仓库, 结果记录到提案; 可用于重新提交
+func (s *Service) CommitArtifacts(id string, by Actor) (*ArtifactChange, error) {
+	if s.gitops == nil {
+		return nil, fmt.Errorf("git integration not enabled")
+	}
+	p, ok := s.proposalService.Get(id)
+	if !ok {
+		return nil, fmt.Errorf("proposal not found: %s", id)
+	}
+	if p.Decision == nil || p.Decision.Action != ActionAccept {
+		return nil, fmt.Errorf("proposal is not accepted: %s", p.Status)
+	}
+	if len(p.Artifacts) == 0 {
+		return nil, fmt.Errorf("proposal has no artifacts")
+	}
+
+	change, err := s.gitops.commit(s.ctx, p)
+	fields := map[string]interface{}{
+		"id":     id,
+		"branch": change.Branch,
+		"files":  change.Files,
+	}
+	if err != nil {
+		change.Error = err.Error()
+		fields["error"] = change.Error
+		logger.WarnCF("secops", "Proposal artifact commit failed", fields)
+	} else {
+		fields["commit"] = change.Commit
+		fields["pull_request"] = change.PullRequest
+		logger.InfoCF("secops", "Proposal artifacts committed", fields)
+	}
+	s.proposalService.recordChange(id, change, by)
+	return change, err
+}
+
+// GitEnabled 是否开启 Git 变更管理
+func (s *Service) GitEnabled() bool {
+	return s.gitops != nil
+}
@@ -0,0 +1,203 @@
+package secops
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func requireGit(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+}
+
+func acceptedWithArtifacts(id string, artifacts ...Artifact) *Proposal {
+	p := NewProposal("risk", "SQL 注入攻击 /product", "拦截 203.0.113.7 的注入请求", nil)
+	p.ID = id
+	p.Severity = SeverityHigh
+	p.Techniques = []string{"T1190"}
+	p.Status = ProposalStatusAccepted
+	p.Decision = &Decision{
+		Action:    ActionAccept,
+		Reason:    "确认注入",
+		DecidedAt: time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC),
+		By:        &Actor{Name: "password:alice", Via: ViaDebugUI},
+	}
+	p.Artifacts = artifacts
+	return p
+}
+
+func TestArtifactFileName(t *testing.T) {
+	cases := map[Artifact]string{
+		{Kind: ArtifactWAFRule, Name: "block-sqli"}:                   "block-sqli.conf",
+		{Kind: ArtifactSigmaRule, Name: "../../etc/passwd"}:           "passwd.yml",
+		{Kind: ArtifactSQLTemplate, Name: `C:\rules\login burst.sql`}: "login-burst.sql",
+		{Kind: ArtifactSuppression, Name: ".."}:                       "",
+	}
+	for a, want := range cases {
+		got, err := artifactFileName(a)
+		if got != want || (err != nil) != (want == "") {
+			t.Errorf("artifactFileName(%q) = %q, %v; want %q", a.Name, got, err, want)
+		}
+	}
+	if err := validateArtifact(Artifact{Kind: "yara", Name: "x", Content: "rule x {}"}); err == nil {
+		t.Error("expected unknown kind to be rejected")
+	}
+	if err := validateArtifact(Artifact{Kind: ArtifactWAFRule, Name: "x", Content: " "}); err == nil {
+		t.Error("expected empty content to be rejected")
+	}
+}
+
+func TestGitOpsLocalCommit(t *testing.T) {
+	requireGit(t)
+	workspace := t.TempDir()
+	g, err := newGitOps(config.GitOpsConfig{
+		Enabled: true,
+		Paths:   map[string]string{ArtifactSigmaRule: "detections/sigma"},
+	}, workspace)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := acceptedWithArtifacts("p1",
+		Artifact{Kind: ArtifactWAFRule, Name: "block-sqli", Content: `SecRule ARGS "@detectSQLi" "id:100001,deny"`},
+		Artifact{Kind: ArtifactSigmaRule, Name: "sqli.yml", Content: "title: SQLi on /product"},
+	)
+	change, err := g.commit(context.Background(), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.Commit == "" || change.Branch != "main" ||
+		strings.Join(change.Files, ",") != "waf/block-sqli.conf,detections/sigma/sqli.yml" {
+		t.Fatalf("change = %+v", change)
+	}
+
+	dir := filepath.Join(workspace, "secops", "config-repo")
+	data, err := os.ReadFile(filepath.Join(dir, "waf", "block-sqli.conf"))
+	if err != nil || string(data) != `SecRule ARGS "@detectSQLi" "id:100001,deny"`+"\n" {
+		t.Errorf("waf rule = %q, %v", data, err)
+	}
+	msg, _ := g.git(context.Background(), "log", "-1", "--format=%an%n%B")
+	for _, want := range []string{"soclaw\nsecops(risk): SQL 注入攻击 /product", "Proposal: p1", "Approved-by: password:alice (debugui)",
+		"Reason: 确认注入", "- detections/sigma/sqli.yml"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("commit message missing %q:\n%s", want, msg)
+		}
+	}
+
+	// 内容未变化时不产生提交
+	again, err := g.commit(context.Background(), p)
+	if err != nil || again.Commit != "" {
+		t.Errorf("unchanged commit = %+v, %v", again, err)
+	}
+}
+
+func TestGitOpsPullRequest(t *testing.T) {
+	requireGit(t)
+	ctx := context.Background()
+	tmp := t.TempDir()
+	remote := filepath.Join(tmp, "rules.git")
+	if out, err := exec.Command("git", "init", "-q", "--bare", remote).CombinedOutput(); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+
+	var created map[string]string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/secops/rules/pulls" || r.Header.Get("Authorization") != "Bearer t0ken" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&created)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"html_url": "https://github.example.com/secops/rules/pull/7"}`))
+	}))
+	defer api.Close()
+
+	// 目标分支需先存在于远程仓库
+	seed, err := newGitOps(config.GitOpsConfig{Enabled: true, Repo: filepath.Join(tmp, "seed"), Remote: remote}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := seed.commit(ctx, acceptedWithArtifacts("seed",
+		Artifact{Kind: ArtifactSuppression, Name: "scanners", Content: "198.51.100.0/24"})); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := newGitOps(config.GitOpsConfig{
+		Enabled: true,
+		Repo:    filepath.Join(tmp, "work"),
+		Remote:  remote,
+		PullRequest: config.GitPullRequestConfig{
+			Enabled: true,
+			APIURL:  api.URL,
+			Repo:    "secops/rules",
+			Token:   "t0ken",
+		},
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	change, err := g.commit(ctx, acceptedWithArtifacts("p2",
+		Artifact{Kind: ArtifactSQLTemplate, Name: "login-burst", Content: "SELECT ip, count() FROM login_events GROUP BY ip"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.Branch != "soclaw/proposal-p2" || change.PullRequest != "https://github.example.com/secops/rules/pull/7" {
+		t.Errorf("change = %+v", change)
+	}
+	if created["head"] != "soclaw/proposal-p2" || created["base"] != "main" || !strings.Contains(created["body"], "- sql/login-burst.sql") {
+		t.Errorf("pull request = %+v", created)
+	}
+
+	// 变更分支基于目标分支, 已推送到远程仓库
+	out, err := exec.Command("git", "--git-dir", remote, "ls-tree", "-r", "--name-only", "soclaw/proposal-p2").CombinedOutput()
+	if err != nil || strings.Join(strings.Fields(string(out)), ",") != "sql/login-burst.sql,suppressions/scanners.txt" {
+		t.Errorf("remote branch files = %q, %v", out, err)
+	}
+}
+
+func TestGitOpsAudit(t *testing.T) {
+	s := NewProposalService()
+	id := s.Create(acceptedWithArtifacts("p3"))
+	s.recordChange(id, &ArtifactChange{Branch: "main", Commit: "abc123", At: time.Now()}, systemActor("git"))
+	s.recordChange(id, &ArtifactChange{Branch: "main", Error: "git push: rejected", At: time.Now()}, Actor{Name: "token", Via: ViaAPI})
+
+	events := s.History(id)
+	if len(events) != 3 || events[1].Action != AuditCommitted || events[1].Detail != "main@abc123" ||
+		events[2].Action != AuditCommitFailed || events[2].Actor.Via != ViaAPI {
+		t.Errorf("history = %+v", events)
+	}
+	if p, _ := s.Get(id); p.Change == nil || p.Change.Error == "" {
+		t.Errorf("change = %+v", p.Change)
+	}
+}
+
+func TestNewGitOpsConfig(t *testing.T) {
+	if g, err := newGitOps(config.GitOpsConfig{}, ""); g != nil || err != nil {
+		t.Errorf("disabled: g=%v err=%v", g, err)
+	}
+	bad := []config.GitOpsConfig{
+		{Enabled: true, Paths: map[string]string{"yara": "yara"}},
+		{Enabled: true, Paths: map[string]string{ArtifactWAFRule: "../waf"}},
+		{Enabled: true, Branch: "--force"},
+		{Enabled: true, PullRequest: config.GitPullRequestConfig{Enabled: true, Repo: "a/b", Token: "t"}},
+		{Enabled: true, Remote: "git@example.com:a/b.git", PullRequest: config.GitPullRequestConfig{Enabled: true, Provider: "bitbucket", Repo: "a/b", Token: "t"}},
+		{Enabled: true, Remote: "git@example.com:a/b.git", PullRequest: config.GitPullRequestConfig{Enabled: true, Provider: "gitea", Repo: "a/b", Token: "t"}},
+	}
+	for i, cfg := range bad {
+		if _, err := newGitOps(cfg, ""); err == nil {
+			t.Errorf("config %d: expected error", i)
+		}
+	}
+}
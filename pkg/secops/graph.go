@@ -0,0 +1,430 @@
+package secops
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 实体节点类型
+const (
+	NodeIP       = "ip"
+	NodeUser     = "user"
+	NodeDevice   = "device"
+	NodeHost     = "host"
+	NodeURL      = "url"
+	NodeProposal = "proposal"
+)
+
+// 实体关系类型
+const (
+	EdgeObservedWith = "observed_with" // 实体出现在提案的详情或证据中: 实体 -> 提案
+	EdgeTargeted     = "targeted"      // 同一提案中来源实体 (IP/用户/设备) 指向目标资产 (主机/URL/目的 IP)
+	EdgeDecided      = "decided"       // 分析师对提案做出决策: 用户 -> 提案
+)
+
+const (
+	defaultGraphDepth = 1
+	maxGraphDepth     = 3
+	defaultGraphLimit = 50
+	maxGraphLimit     = 200
+
+	// maxEvidenceEntities 单个提案从证据内容中提取的实体上限
+	maxEvidenceEntities = 20
+	// maxEdgeProposals targeted 边记录的最近提案数
+	maxEdgeProposals = 10
+)
+
+// ErrGraphNodeNotFound 关系图中不存在该实体
+var ErrGraphNodeNotFound = errors.New("graph node not found")
+
+// graphSourceKeys 提案详情中表示来源实体的字段
+var graphSourceKeys = map[string]string{
+	"ip":          NodeIP,
+	"src_ip":      NodeIP,
+	"source_ip":   NodeIP,
+	"attacker_ip": NodeIP,
+	"client_ip":   NodeIP,
+	"user":        NodeUser,
+	"user_id":     NodeUser,
+	"uid":         NodeUser,
+	"username":    NodeUser,
+	"device":      NodeDevice,
+	"device_id":   NodeDevice,
+}
+
+// graphTargetKeys 提案详情中表示目标资产的字段
+var graphTargetKeys = map[string]string{
+	"host":     NodeHost,
+	"hostname": NodeHost,
+	"domain":   NodeHost,
+	"dst_ip":   NodeIP,
+	"dest_ip":  NodeIP,
+	"url":      NodeURL,
+}
+
+var (
+	graphIPPattern  = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	graphURLPattern = regexp.MustCompile(`https?://[^\s"'<>()\[\]{}]+`)
+)
+
+// GraphNode 关系图节点
+type GraphNode struct {
+	ID        string    `json:"id"`                 // 类型:值, 如 ip:203.0.113.45
+	Type      string    `json:"type"`               // 见 Node* 常量
+	Value     string    `json:"value"`              // 实体值或提案 ID
+	Label     string    `json:"label,omitempty"`    // 提案标题
+	Status    string    `json:"status,omitempty"`   // 提案状态
+	Severity  string    `json:"severity,omitempty"` // 提案严重级别
+	Proposals int       `json:"proposals"`          // 涉及该实体的提案数
+	LastSeen  time.Time `json:"lastSeen"`           // 最近涉及该实体的提案时间
+	Distance  int       `json:"distance"`           // 与中心实体的距离, 仅查询结果中有效
+}
+
+// GraphEdge 关系图的边
+type GraphEdge struct {
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Type      string    `json:"type"`                // 见 Edge* 常量
+	Count     int       `json:"count"`               // 支撑该关系的提案数
+	Proposals []string  `json:"proposals,omitempty"` // targeted 边的最近提案, 最近的在后
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// Subgraph 以某个实体为中心的邻域
+type Subgraph struct {
+	Root      string      `json:"root"`
+	Depth     int         `json:"depth"`
+	Nodes     []GraphNode `json:"nodes"` // 按距离排序, 首个为中心实体
+	Edges     []GraphEdge `json:"edges"`
+	Truncated bool        `json:"truncated"` // 节点数达到上限, 部分邻居未展开
+}
+
+// entityGraph 由提案构建的实体关系图
+type entityGraph struct {
+	nodes map[string]*GraphNode
+	edges map[string]*GraphEdge   // from|type|to -> 边
+	adj   map[string][]*GraphEdge // 节点 -> 相连的边 (不区分方向)
+}
+
+// graphEntity 从提案中提取的实体
+type graphEntity struct {
+	id, kind, value string
+}
+
+// ParseGraphNode 解析实体引用, 返回节点 ID
+//
+// 形如 ip:203.0.113.45、host:shop.example.com、user:alice、proposal:<id>; 裸 IP 和 URL 自动识别类型
+func ParseGraphNode(ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return "", fmt.Errorf("node is required")
+	}
+	lower := strings.ToLower(ref)
+	if strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") {
+		return graphNodeID(NodeURL, ref)
+	}
+	if net.ParseIP(ref) != nil {
+		return graphNodeID(NodeIP, ref)
+	}
+	kind, value, ok := strings.Cut(ref, ":")
+	if !ok {
+		return "", fmt.Errorf("invalid node %q: use type:value, e.g. ip:203.0.113.45", ref)
+	}
+	switch kind = strings.ToLower(strings.TrimSpace(kind)); kind {
+	case NodeIP, NodeUser, NodeDevice, NodeHost, NodeURL, NodeProposal:
+		return graphNodeID(kind, value)
+	}
+	return "", fmt.Errorf("invalid node type %q (ip, user, device, host, url, proposal)", kind)
+}
+
+// graphNodeID 规范化实体值并生成节点 ID
+func graphNodeID(kind, value string) (string, error) {
+	value = normalizeGraphValue(kind, value)
+	if value == "" {
+		return "", fmt.Errorf("invalid %s value", kind)
+	}
+	return kind + ":" + value, nil
+}
+
+// normalizeGraphValue 规范化实体值, 无效时返回空
+func normalizeGraphValue(kind, value string) string {
+	value = strings.TrimSpace(value)
+	switch kind {
+	case NodeIP:
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return ""
+		}
+		return ip.String()
+	case NodeHost:
+		return strings.TrimSuffix(strings.ToLower(value), ".")
+	case NodeURL:
+		return strings.TrimRight(value, ".,;")
+	}
+	return value
+}
+
+// Graph 获取以 ref 为中心、深度不超过 depth 的关系图, 最多 limit 个节点
+//
+// 关系图由内存中的提案详情、批量条目和证据构建, 提案变更后按需重建
+func (s *ProposalService) Graph(ref string, depth, limit int) (*Subgraph, error) {
+	root, err := ParseGraphNode(ref)
+	if err != nil {
+		return nil, err
+	}
+	if depth <= 0 {
+		depth = defaultGraphDepth
+	}
+	depth = min(depth, maxGraphDepth)
+	if limit <= 0 {
+		limit = defaultGraphLimit
+	}
+	limit = min(limit, maxGraphLimit)
+
+	g := s.entityGraph()
+	if g.nodes[root] == nil {
+		return nil, fmt.Errorf("%w: %s", ErrGraphNodeNotFound, root)
+	}
+
+	// 广度优先展开, 最近的关系优先
+	sub := &Subgraph{Root: root, Depth: depth, Nodes: []GraphNode{*g.nodes[root]}, Edges: []GraphEdge{}}
+	visited := map[string]bool{root: true}
+	frontier := []string{root}
+	for d := 0; d < depth && len(frontier) > 0; d++ {
+		var next []string
+		for _, id := range frontier {
+			for _, e := range g.adj[id] {
+				other := e.To
+				if other == id {
+					other = e.From
+				}
+				if visited[other] {
+					continue
+				}
+				if len(visited) >= limit {
+					sub.Truncated = true
+					continue
+				}
+				visited[other] = true
+				n := *g.nodes[other]
+				n.Distance = d + 1
+				sub.Nodes = append(sub.Nodes, n)
+				next = append(next, other)
+			}
+		}
+		frontier = next
+	}
+
+	for _, e := range g.edges {
+		if visited[e.From] && visited[e.To] {
+			c := *e
+			c.Proposals = append([]string(nil), e.Proposals...)
+			sub.Edges = append(sub.Edges, c)
+		}
+	}
+	sort.Slice(sub.Edges, func(i, j int) bool {
+		if !sub.Edges[i].LastSeen.Equal(sub.Edges[j].LastSeen) {
+			return sub.Edges[i].LastSeen.After(sub.Edges[j].LastSeen)
+		}
+		return sub.Edges[i].From+sub.Edges[i].To < sub.Edges[j].From+sub.Edges[j].To
+	})
+	return sub, nil
+}
+
+// entityGraph 获取当前版本的关系图, 提案存储变更后重建
+func (s *ProposalService) entityGraph() *entityGraph {
+	s.graphMu.Lock()
+	defer s.graphMu.Unlock()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.graph != nil && s.graphVersion == s.version {
+		return s.graph
+	}
+	proposals := make([]*Proposal, 0, len(s.proposals))
+	for _, p := range s.proposals {
+		proposals = append(proposals, p)
+	}
+	s.graph = buildEntityGraph(proposals)
+	s.graphVersion = s.version
+	return s.graph
+}
+
+// buildEntityGraph 由提案构建关系图
+func buildEntityGraph(proposals []*Proposal) *entityGraph {
+	g := &entityGraph{
+		nodes: make(map[string]*GraphNode),
+		edges: make(map[string]*GraphEdge),
+		adj:   make(map[string][]*GraphEdge),
+	}
+	sort.Slice(proposals, func(i, j int) bool {
+		return proposals[i].CreatedAt.Before(proposals[j].CreatedAt)
+	})
+
+	for _, p := range proposals {
+		pid := NodeProposal + ":" + p.ID
+		g.nodes[pid] = &GraphNode{
+			ID:        pid,
+			Type:      NodeProposal,
+			Value:     p.ID,
+			Label:     p.Title,
+			Status:    string(p.Status),
+			Severity:  p.Severity,
+			Proposals: 1,
+			LastSeen:  p.CreatedAt,
+		}
+
+		sources, targets, observed := proposalEntities(p)
+		for _, e := range observed {
+			g.addNode(e, p.CreatedAt)
+			g.addEdge(e.id, pid, EdgeObservedWith, "", p.CreatedAt)
+		}
+		for _, src := range sources {
+			for _, dst := range targets {
+				if src.id != dst.id {
+					g.addEdge(src.id, dst.id, EdgeTargeted, p.ID, p.CreatedAt)
+				}
+			}
+		}
+
+		if p.Decision != nil && p.Decision.By != nil && p.Decision.By.Name != "" {
+			if id, err := graphNodeID(NodeUser, p.Decision.By.Name); err == nil {
+				g.addNode(graphEntity{id: id, kind: NodeUser, value: normalizeGraphValue(NodeUser, p.Decision.By.Name)}, p.Decision.DecidedAt)
+				g.addEdge(id, pid, EdgeDecided, "", p.Decision.DecidedAt)
+			}
+		}
+	}
+
+	for id := range g.adj {
+		edges := g.adj[id]
+		sort.Slice(edges, func(i, j int) bool {
+			return edges[i].LastSeen.After(edges[j].LastSeen)
+		})
+	}
+	return g
+}
+
+// proposalEntities 提取提案涉及的实体: 来源实体、目标资产, 以及包含证据中实体在内的全部实体
+func proposalEntities(p *Proposal) (sources, targets, observed []graphEntity) {
+	seen := make(map[string]bool)
+	add := func(kind, value string) (graphEntity, bool) {
+		id, err := graphNodeID(kind, value)
+		if err != nil {
+			return graphEntity{}, false
+		}
+		e := graphEntity{id: id, kind: kind, value: strings.TrimPrefix(id, kind+":")}
+		if !seen[id] {
+			seen[id] = true
+			observed = append(observed, e)
+		}
+		return e, true
+	}
+
+	fields := []map[string]string{detailStrings(p.Details)}
+	fields = append(fields, p.Items...)
+	for _, f := range fields {
+		for _, key := range sortedKeys(f) {
+			if kind, ok := graphSourceKeys[key]; ok {
+				if e, ok := add(kind, f[key]); ok {
+					sources = appendEntity(sources, e)
+				}
+			} else if kind, ok := graphTargetKeys[key]; ok {
+				if e, ok := add(kind, f[key]); ok {
+					targets = appendEntity(targets, e)
+				}
+			}
+		}
+	}
+
+	extracted := 0
+	for _, ev := range p.Evidence {
+		for _, m := range graphURLPattern.FindAllString(ev.Content, -1) {
+			if extracted >= maxEvidenceEntities {
+				return
+			}
+			if _, ok := add(NodeURL, m); ok {
+				extracted++
+			}
+		}
+		for _, m := range graphIPPattern.FindAllString(ev.Content, -1) {
+			if extracted >= maxEvidenceEntities {
+				return
+			}
+			if _, ok := add(NodeIP, m); ok {
+				extracted++
+			}
+		}
+	}
+	return
+}
+
+// appendEntity 追加未出现过的实体
+func appendEntity(list []graphEntity, e graphEntity) []graphEntity {
+	for _, cur := range list {
+		if cur.id == e.id {
+			return list
+		}
+	}
+	return append(list, e)
+}
+
+// detailStrings 提案详情中的字符串和数值字段
+func detailStrings(details map[string]interface{}) map[string]string {
+	result := make(map[string]string, len(details))
+	for k, v := range details {
+		switch v.(type) {
+		case string, float64, int, int64:
+			result[k] = fmt.Sprint(v)
+		}
+	}
+	return result
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// addNode 添加实体节点, 同一提案只计数一次由调用方保证
+func (g *entityGraph) addNode(e graphEntity, at time.Time) {
+	n, ok := g.nodes[e.id]
+	if !ok {
+		n = &GraphNode{ID: e.id, Type: e.kind, Value: e.value}
+		g.nodes[e.id] = n
+	}
+	n.Proposals++
+	if at.After(n.LastSeen) {
+		n.LastSeen = at
+	}
+}
+
+// addEdge 添加或累加一条边; proposalID 非空时记录到边上
+func (g *entityGraph) addEdge(from, to, kind, proposalID string, at time.Time) {
+	key := from + "|" + kind + "|" + to
+	e, ok := g.edges[key]
+	if !ok {
+		e = &GraphEdge{From: from, To: to, Type: kind}
+		g.edges[key] = e
+		g.adj[from] = append(g.adj[from], e)
+		g.adj[to] = append(g.adj[to], e)
+	}
+	e.Count++
+	if proposalID != "" {
+		e.Proposals = append(e.Proposals, proposalID)
+		if len(e.Proposals) > maxEdgeProposals {
+			e.Proposals = e.Proposals[len(e.Proposals)-maxEdgeProposals:]
+		}
+	}
+	if at.After(e.LastSeen) {
+		e.LastSeen = at
+	}
+}
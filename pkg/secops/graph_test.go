@@ -0,0 +1,117 @@
+package secops
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEntityGraph(t *testing.T) {
+	ps := NewProposalService()
+	base := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	ps.Create(&Proposal{
+		ID: "p1", Type: "risk", Title: "扫描 /admin", Status: ProposalStatusPending, CreatedAt: base,
+		Details:  map[string]interface{}{"src_ip": "203.0.113.45", "host": "Shop.Example.com"},
+		Evidence: []Evidence{{Content: "GET https://shop.example.com/admin HTTP/1.1\nX-Forwarded-For: 198.51.100.7"}},
+	})
+	ps.Create(&Proposal{
+		ID: "p2", Type: "risk", Title: "撞库", Status: ProposalStatusPending, CreatedAt: base.Add(time.Hour),
+		Details: map[string]interface{}{"src_ip": "203.0.113.45", "user": "alice", "host": "shop.example.com"},
+	})
+	ps.Create(&Proposal{
+		ID: "p3", Type: "host", Title: "异常登录", Status: ProposalStatusPending, CreatedAt: base.Add(2 * time.Hour),
+		Details: map[string]interface{}{"user": "alice", "hostname": "web-01"},
+	})
+
+	sub, err := ps.Graph("203.0.113.45", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.Root != "ip:203.0.113.45" || sub.Nodes[0].Proposals != 2 || len(sub.Nodes) != 4 {
+		t.Fatalf("depth 1 nodes = %+v", sub.Nodes)
+	}
+	var targeted *GraphEdge
+	for i, e := range sub.Edges {
+		if e.Type == EdgeTargeted && e.To == "host:shop.example.com" {
+			targeted = &sub.Edges[i]
+		}
+	}
+	if targeted == nil || targeted.Count != 2 || strings.Join(targeted.Proposals, ",") != "p1,p2" {
+		t.Errorf("targeted edge = %+v", targeted)
+	}
+
+	// 深度 2 经提案 p2 关联到 alice, 深度 3 再到 p3
+	sub, _ = ps.Graph("ip:203.0.113.45", 2, 0)
+	if !hasGraphNode(sub, "user:alice") || hasGraphNode(sub, "proposal:p3") {
+		t.Errorf("depth 2 nodes = %+v", sub.Nodes)
+	}
+	sub, _ = ps.Graph("ip:203.0.113.45", 3, 0)
+	if !hasGraphNode(sub, "proposal:p3") || !hasGraphNode(sub, "host:web-01") {
+		t.Errorf("depth 3 nodes = %+v", sub.Nodes)
+	}
+
+	// 证据中的 URL 和 IP
+	sub, _ = ps.Graph("proposal:p1", 1, 0)
+	if !hasGraphNode(sub, "url:https://shop.example.com/admin") || !hasGraphNode(sub, "ip:198.51.100.7") {
+		t.Errorf("evidence nodes = %+v", sub.Nodes)
+	}
+
+	// 节点上限
+	sub, _ = ps.Graph("ip:203.0.113.45", 3, 3)
+	if len(sub.Nodes) != 3 || !sub.Truncated {
+		t.Errorf("limited = %d nodes, truncated %v", len(sub.Nodes), sub.Truncated)
+	}
+
+	// 决策后重建, 分析师关联到提案
+	if err := ps.Accept("p2", DecisionRequest{Reason: "确认撞库", By: Actor{Name: "bob", Via: ViaDebugUI}}); err != nil {
+		t.Fatal(err)
+	}
+	sub, _ = ps.Graph("user:bob", 1, 0)
+	if sub == nil || len(sub.Edges) != 1 || sub.Edges[0].Type != EdgeDecided || sub.Edges[0].To != "proposal:p2" {
+		t.Errorf("decided = %+v", sub)
+	}
+	sub, _ = ps.Graph("proposal:p2", 1, 0)
+	if sub.Nodes[0].Status != string(ProposalStatusAccepted) {
+		t.Errorf("proposal node = %+v", sub.Nodes[0])
+	}
+
+	if _, err := ps.Graph("ip:192.0.2.1", 1, 0); !errors.Is(err, ErrGraphNodeNotFound) {
+		t.Errorf("missing node err = %v", err)
+	}
+	for _, ref := range []string{"", "alice", "mac:00:11", "ip:not-an-ip"} {
+		if _, err := ps.Graph(ref, 1, 0); err == nil || errors.Is(err, ErrGraphNodeNotFound) {
+			t.Errorf("%q: err = %v, want parse error", ref, err)
+		}
+	}
+}
+
+func TestGraphTool(t *testing.T) {
+	svc := &Service{proposalService: NewProposalService()}
+	svc.proposalService.Create(&Proposal{
+		ID: "p1", Title: "扫描 /admin", Status: ProposalStatusPending, Severity: SeverityHigh,
+		Details: map[string]interface{}{"src_ip": "203.0.113.45", "host": "shop.example.com"},
+	})
+	tool := NewGraphTool(svc)
+
+	res := tool.Execute(t.Context(), map[string]interface{}{"node": "ip:203.0.113.45", "depth": float64(1)})
+	if res.IsError || !strings.Contains(res.ForLLM, `proposal:p1 "扫描 /admin" [pending, high]`) ||
+		!strings.Contains(res.ForLLM, "ip:203.0.113.45 -targeted-> host:shop.example.com") {
+		t.Errorf("result = %q", res.ForLLM)
+	}
+	if res := tool.Execute(t.Context(), map[string]interface{}{"node": "user:nobody"}); res.IsError || !strings.Contains(res.ForLLM, "no proposals involve") {
+		t.Errorf("missing = %q", res.ForLLM)
+	}
+	if res := tool.Execute(t.Context(), map[string]interface{}{"node": "ip:203.0.113.45", "depth": 1.5}); !res.IsError {
+		t.Errorf("fractional depth should fail: %q", res.ForLLM)
+	}
+}
+
+func hasGraphNode(sub *Subgraph, id string) bool {
+	for _, n := range sub.Nodes {
+		if n.ID == id {
+			return true
+		}
+	}
+	return false
+}
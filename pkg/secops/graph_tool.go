@@ -0,0 +1,132 @@
+package secops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// GraphTool 查询 IP、用户、设备、主机、URL 与提案之间的关联, 用于调查时横向关联
+type GraphTool struct {
+	service *Service
+}
+
+// NewGraphTool 创建关系图查询工具
+func NewGraphTool(service *Service) *GraphTool {
+	return &GraphTool{service: service}
+}
+
+// Name 工具名称
+func (t *GraphTool) Name() string {
+	return "graph_query"
+}
+
+// Description 工具描述
+func (t *GraphTool) Description() string {
+	return `查询实体关系图: 由历史提案的详情和证据构建, 节点为 IP、用户、设备、主机、URL 和提案。
+研判时可查看某个 IP 还攻击过哪些主机、出现在哪些提案中、由谁做过决策, 再沿返回的节点继续展开。
+- node: 实体, 形如 ip:203.0.113.45、host:shop.example.com、user:alice、device:xxx、url:https://...、proposal:<id>; 裸 IP 和 URL 自动识别
+- depth: 展开深度 1-3, 默认 1
+- limit: 返回的节点数上限, 默认 50, 最多 200
+关系: observed_with (实体出现在提案中)、targeted (来源实体指向目标资产)、decided (分析师对提案做出决策)`
+}
+
+// Parameters 参数定义
+func (t *GraphTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"node": map[string]interface{}{
+				"type": "string",
+			},
+			"depth": map[string]interface{}{
+				"type":    "integer",
+				"minimum": 1,
+				"maximum": maxGraphDepth,
+			},
+			"limit": map[string]interface{}{
+				"type":    "integer",
+				"minimum": 1,
+				"maximum": maxGraphLimit,
+			},
+		},
+		"required": []string{"node"},
+	}
+}
+
+// Execute 查询实体的邻域
+func (t *GraphTool) Execute(ctx context.Context, args map[string]interface{}) *tools.ToolResult {
+	node, _ := args["node"].(string)
+	depth, err := intArg(args, "depth")
+	if err != nil {
+		return tools.ErrorResult(err.Error())
+	}
+	limit, err := intArg(args, "limit")
+	if err != nil {
+		return tools.ErrorResult(err.Error())
+	}
+
+	sub, err := t.service.proposalService.Graph(node, depth, limit)
+	if errors.Is(err, ErrGraphNodeNotFound) {
+		return tools.SilentResult(fmt.Sprintf("no proposals involve %s", strings.TrimSpace(node)))
+	}
+	if err != nil {
+		return tools.ErrorResult(err.Error())
+	}
+	return tools.SilentResult(formatSubgraph(sub))
+}
+
+// intArg 读取可选的整数参数, 未提供时返回 0
+func intArg(args map[string]interface{}, key string) (int, error) {
+	switch v := args[key].(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		if v != float64(int(v)) {
+			return 0, fmt.Errorf("%s must be an integer", key)
+		}
+		return int(v), nil
+	case int:
+		return v, nil
+	case string:
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return 0, fmt.Errorf("%s must be an integer", key)
+		}
+		return n, nil
+	}
+	return 0, fmt.Errorf("%s must be an integer", key)
+}
+
+// formatSubgraph 邻域的文本形式: 节点列表和边列表
+func formatSubgraph(sub *Subgraph) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s 的关系图 (深度 %d), 节点 %d 个:\n", sub.Root, sub.Depth, len(sub.Nodes))
+	for _, n := range sub.Nodes {
+		if n.Type == NodeProposal {
+			fmt.Fprintf(&sb, "- %s %q [%s", n.ID, n.Label, n.Status)
+			if n.Severity != "" {
+				sb.WriteString(", " + n.Severity)
+			}
+			fmt.Fprintf(&sb, "] %s\n", n.LastSeen.Format("2006-01-02 15:04"))
+			continue
+		}
+		fmt.Fprintf(&sb, "- %s (%d 个提案, 最近 %s)\n", n.ID, n.Proposals, n.LastSeen.Format("2006-01-02 15:04"))
+	}
+	fmt.Fprintf(&sb, "边 %d 条:\n", len(sub.Edges))
+	for _, e := range sub.Edges {
+		fmt.Fprintf(&sb, "- %s -%s-> %s", e.From, e.Type, e.To)
+		if e.Count > 1 {
+			fmt.Fprintf(&sb, " ×%d", e.Count)
+		}
+		sb.WriteString("\n")
+	}
+	if sub.Truncated {
+		sb.WriteString("... 节点数已达上限, 可缩小深度或沿某个节点继续查询\n")
+	}
+	return sb.String()
+}
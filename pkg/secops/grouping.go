@@ -0,0 +1,128 @@
+package secops
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 提案分组方式
+const (
+	GroupByHost      = "host"      // 目标主机: 详情和批量条目中的 host、hostname、domain
+	GroupBySignature = "signature" // 风险特征: 详情或批量条目中的 signature、risk、rule_id、category, 都没有时按标题
+	GroupByType      = "type"      // 提案类型
+)
+
+// validGroupBy 支持的分组方式
+var validGroupBy = map[string]bool{GroupByHost: true, GroupBySignature: true, GroupByType: true}
+
+// signatureKeys 提案详情中表示风险特征的字段, 按优先级排列
+var signatureKeys = []string{"signature", "risk", "rule_id", "category"}
+
+// ProposalGroup 一组提案的统计, Key 为空表示提案缺少该分组字段
+type ProposalGroup struct {
+	Key         string         `json:"key"`
+	Count       int            `json:"count"`                 // 满足筛选条件的提案数
+	Pending     int            `json:"pending"`               // 其中待处理的提案数
+	Severities  map[string]int `json:"severities,omitempty"`  // 按严重级别计数
+	MaxSeverity string         `json:"maxSeverity,omitempty"` // 组内最高严重级别
+	LatestAt    time.Time      `json:"latestAt"`              // 组内最近一次创建时间
+}
+
+// proposalGroupKeys 提案所属的分组; 批量提案涉及多台主机时属于每台主机的分组
+func proposalGroupKeys(p *Proposal, by string) []string {
+	switch by {
+	case GroupByType:
+		return []string{p.Type}
+	case GroupBySignature:
+		fields := []map[string]string{detailStrings(p.Details)}
+		fields = append(fields, p.Items...)
+		for _, f := range fields {
+			for _, key := range signatureKeys {
+				if v := strings.TrimSpace(f[key]); v != "" {
+					return []string{v}
+				}
+			}
+		}
+		return []string{strings.TrimSpace(p.Title)}
+	}
+
+	var hosts []string
+	fields := []map[string]string{detailStrings(p.Details)}
+	fields = append(fields, p.Items...)
+	for _, f := range fields {
+		for _, key := range sortedKeys(f) {
+			if graphTargetKeys[key] != NodeHost {
+				continue
+			}
+			if h := normalizeGraphValue(NodeHost, f[key]); h != "" && !slices.Contains(hosts, h) {
+				hosts = append(hosts, h)
+			}
+		}
+	}
+	if len(hosts) == 0 {
+		return []string{""}
+	}
+	return hosts
+}
+
+// Groups 按 by 对满足条件的提案分组统计, 忽略 f 的排序和分页; 待处理多、级别高的分组在前
+func (s *ProposalService) Groups(f ProposalFilter, by string) ([]ProposalGroup, error) {
+	if !validGroupBy[by] {
+		return nil, fmt.Errorf("invalid group by: %s (host, signature, type)", by)
+	}
+	f.Sort, f.Offset, f.Limit = "", 0, 0
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	index := make(map[string]*ProposalGroup)
+	s.eachIndexedLocked(func(p *Proposal) {
+		if !f.match(p) {
+			return
+		}
+		for _, key := range proposalGroupKeys(p, by) {
+			g, ok := index[key]
+			if !ok {
+				g = &ProposalGroup{Key: key, Severities: make(map[string]int)}
+				index[key] = g
+			}
+			g.Count++
+			if p.Status == ProposalStatusPending {
+				g.Pending++
+			}
+			if p.Severity != "" {
+				g.Severities[p.Severity]++
+				if severityRank[p.Severity] > severityRank[g.MaxSeverity] {
+					g.MaxSeverity = p.Severity
+				}
+			}
+			if p.CreatedAt.After(g.LatestAt) {
+				g.LatestAt = p.CreatedAt
+			}
+		}
+	})
+	s.mu.RUnlock()
+
+	groups := make([]ProposalGroup, 0, len(index))
+	for _, g := range index {
+		groups = append(groups, *g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		if a.Pending != b.Pending {
+			return a.Pending > b.Pending
+		}
+		if ra, rb := severityRank[a.MaxSeverity], severityRank[b.MaxSeverity]; ra != rb {
+			return ra > rb
+		}
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Key < b.Key
+	})
+	return groups, nil
+}
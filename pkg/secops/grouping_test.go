@@ -0,0 +1,61 @@
+package secops
+
+import (
+	"testing"
+)
+
+func TestProposalGroups(t *testing.T) {
+	s := NewProposalService()
+	create := func(typ, severity string, details map[string]interface{}, items ...map[string]string) string {
+		p := NewProposal(typ, "告警", "", details)
+		p.Severity = severity
+		p.Items = items
+		return s.Create(p)
+	}
+	create("risk", SeverityMedium, map[string]interface{}{"host": "Payments.example.com", "risk": "SQL注入"})
+	create("risk", SeverityHigh, map[string]interface{}{"host": "payments.example.com.", "risk": "XSS"})
+	create("risk", SeverityLow, nil,
+		map[string]string{"host": "payments.example.com", "risk": "SQL注入"},
+		map[string]string{"host": "shop.example.com", "risk": "SQL注入"})
+	ignored := create("weak", SeverityCritical, map[string]interface{}{"hostname": "shop.example.com"})
+	create("app", "", nil)
+	if err := s.Ignore(ignored, DecisionRequest{}); err != nil {
+		t.Fatal(err)
+	}
+
+	groups, err := s.Groups(ProposalFilter{}, GroupByHost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 3 {
+		t.Fatalf("groups = %+v", groups)
+	}
+	if g := groups[0]; g.Key != "payments.example.com" || g.Count != 3 || g.Pending != 3 || g.MaxSeverity != SeverityHigh {
+		t.Errorf("first group = %+v", g)
+	}
+	if g := groups[1]; g.Key != "shop.example.com" || g.Count != 2 || g.Pending != 1 || g.MaxSeverity != SeverityCritical {
+		t.Errorf("second group = %+v", g)
+	}
+	if g := groups[2]; g.Key != "" || g.Count != 1 {
+		t.Errorf("ungrouped = %+v", g)
+	}
+
+	// 风险特征取详情或批量条目中的 risk, 缺少时按标题; 可与其他筛选组合
+	groups, _ = s.Groups(ProposalFilter{Types: []string{"risk", "app"}}, GroupBySignature)
+	if len(groups) != 3 || groups[0].Key != "SQL注入" || groups[0].Pending != 2 || groups[1].Key != "XSS" || groups[2].Key != "告警" {
+		t.Errorf("signature groups = %+v", groups)
+	}
+
+	// 下钻
+	page, total, _ := s.GetFiltered(ProposalFilter{GroupBy: GroupByHost, Group: "shop.example.com", Statuses: []ProposalStatus{ProposalStatusPending}})
+	if total != 1 || page[0].Type != "risk" {
+		t.Errorf("drill-down = %d %+v", total, page)
+	}
+
+	if _, err := s.Groups(ProposalFilter{}, "owner"); err == nil {
+		t.Error("expected error for unknown group by")
+	}
+	if _, _, err := s.GetFiltered(ProposalFilter{Group: "x"}); err == nil {
+		t.Error("expected error for group without group by")
+	}
+}
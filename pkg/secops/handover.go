@@ -0,0 +1,392 @@
+package secops
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// defaultShiftLength 未配置交班时刻时一个班次的时长
+const defaultShiftLength = 12 * time.Hour
+
+// maxHandoverItems 交接数据中每一类条目的上限, 避免 prompt 过长
+const maxHandoverItems = 30
+
+// HandoverProposal 交接数据中的一条提案
+type HandoverProposal struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Title     string    `json:"title"`
+	Severity  string    `json:"severity,omitempty"`
+	Assignee  string    `json:"assignee,omitempty"`
+	Action    string    `json:"action,omitempty"`    // 本班次的决策: accept, ignore
+	By        string    `json:"by,omitempty"`        // 决策者
+	Note      string    `json:"note,omitempty"`      // 决策理由或知悉说明
+	Execution string    `json:"execution,omitempty"` // 决策后的执行结果
+	At        time.Time `json:"at"`                  // 创建、决策或知悉时间
+}
+
+// HandoverActivity 本班次执行失败的活动
+type HandoverActivity struct {
+	Name       string     `json:"name"`
+	Runs       int        `json:"runs"`     // 本班次的执行次数
+	Failures   int        `json:"failures"` // 其中失败的次数
+	LastStatus string     `json:"lastStatus,omitempty"`
+	LastError  string     `json:"lastError,omitempty"`
+	LastRunAt  *time.Time `json:"lastRunAt,omitempty"`
+}
+
+// HandoverData 交接班摘要依据的结构化数据, 时间范围为 [Since, Until)
+type HandoverData struct {
+	Since             time.Time          `json:"since"`
+	Until             time.Time          `json:"until"`
+	OpenCritical      []HandoverProposal `json:"openCritical"`      // 待处理的 critical、high 提案
+	Decisions         []HandoverProposal `json:"decisions"`         // 本班次决策的提案
+	FailingActivities []HandoverActivity `json:"failingActivities"` // 本班次有失败执行或最近一次执行失败的活动
+	AwaitingExternal  []HandoverProposal `json:"awaitingExternal"`  // 已知悉、等待外部团队回复的待处理提案
+	Truncated         bool               `json:"truncated,omitempty"`
+}
+
+// Handover 交接班摘要
+type Handover struct {
+	Note        string       `json:"note"`     // 交接说明 (Markdown)
+	Composed    bool         `json:"composed"` // 由 Agent 撰写; false 表示 Agent 不可用, 按模板生成
+	Data        HandoverData `json:"data"`
+	GeneratedAt time.Time    `json:"generatedAt"`
+	PostedTo    []string     `json:"postedTo,omitempty"` // 已发送的通知目标
+}
+
+// handoverSchedule 交班时刻
+type handoverSchedule struct {
+	loc     *time.Location
+	minutes []int // 距零点的分钟数, 升序
+	targets []string
+}
+
+// newHandoverSchedule 校验交接班配置, 未指定时区时使用 timezone; 未配置交班时刻时返回只含通知目标的日程
+func newHandoverSchedule(cfg config.HandoverConfig, timezone string, targets map[string]config.NotifyTargetConfig) (*handoverSchedule, error) {
+	if cfg.Timezone != "" {
+		timezone = cfg.Timezone
+	}
+	loc, err := loadTimezone(timezone)
+	if err != nil {
+		return nil, err
+	}
+	hs := &handoverSchedule{loc: loc, targets: cfg.Targets}
+	for _, t := range cfg.Times {
+		m, err := parseClock(t)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(hs.minutes, m) {
+			hs.minutes = append(hs.minutes, m)
+		}
+	}
+	sort.Ints(hs.minutes)
+	for _, name := range cfg.Targets {
+		if _, ok := targets[name]; !ok {
+			return nil, fmt.Errorf("unknown notify target %q", name)
+		}
+	}
+	if len(hs.minutes) > 0 && len(hs.targets) == 0 {
+		return nil, fmt.Errorf("times require at least one target")
+	}
+	return hs, nil
+}
+
+// next t 之后的第一个交班时刻, 未配置交班时刻时返回零值
+func (hs *handoverSchedule) next(t time.Time) time.Time {
+	local := t.In(hs.loc)
+	for day := 0; day <= 2; day++ {
+		for _, m := range hs.minutes {
+			slot := time.Date(local.Year(), local.Month(), local.Day()+day, m/60, m%60, 0, 0, hs.loc)
+			if slot.After(t) {
+				return slot
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// prev t 之前 (不含 t) 的最近一个交班时刻, 即 t 所在班次的开始; 未配置交班时刻时取 t 往前一个默认班次时长
+func (hs *handoverSchedule) prev(t time.Time) time.Time {
+	local := t.In(hs.loc)
+	for day := 0; day >= -2; day-- {
+		for i := len(hs.minutes) - 1; i >= 0; i-- {
+			m := hs.minutes[i]
+			slot := time.Date(local.Year(), local.Month(), local.Day()+day, m/60, m%60, 0, 0, hs.loc)
+			if slot.Before(t) {
+				return slot
+			}
+		}
+	}
+	return t.Add(-defaultShiftLength)
+}
+
+// HandoverData 汇总 [since, until) 班次的交接数据
+func (s *Service) HandoverData(since, until time.Time) HandoverData {
+	data := HandoverData{
+		Since:             since,
+		Until:             until,
+		OpenCritical:      []HandoverProposal{},
+		Decisions:         []HandoverProposal{},
+		FailingActivities: []HandoverActivity{},
+		AwaitingExternal:  []HandoverProposal{},
+	}
+
+	for _, p := range s.proposalService.GetAll() {
+		item := HandoverProposal{
+			ID:       p.ID,
+			Type:     p.Type,
+			Title:    p.Title,
+			Severity: p.Severity,
+			Assignee: p.Assignee,
+			At:       p.CreatedAt,
+		}
+		if p.Status == ProposalStatusPending {
+			if p.Severity == SeverityCritical || p.Severity == SeverityHigh {
+				data.OpenCritical = append(data.OpenCritical, item)
+			}
+			if ack := p.Acknowledgement; ack != nil && until.Before(ack.Until) {
+				item.Note = ack.Reason
+				item.At = ack.At
+				data.AwaitingExternal = append(data.AwaitingExternal, item)
+			}
+			continue
+		}
+		if d := p.Decision; d != nil && !d.DecidedAt.Before(since) && d.DecidedAt.Before(until) {
+			item.Action = d.Action
+			item.Note = d.Reason
+			item.At = d.DecidedAt
+			if d.By != nil {
+				item.By = d.By.Name
+			}
+			if p.Execution != nil {
+				item.Execution = p.Execution.Status
+			}
+			data.Decisions = append(data.Decisions, item)
+		}
+	}
+
+	sort.Slice(data.OpenCritical, func(i, j int) bool {
+		a, b := data.OpenCritical[i], data.OpenCritical[j]
+		if ra, rb := severityRank[a.Severity], severityRank[b.Severity]; ra != rb {
+			return ra > rb
+		}
+		return a.At.Before(b.At)
+	})
+	sort.Slice(data.Decisions, func(i, j int) bool {
+		return data.Decisions[i].At.Before(data.Decisions[j].At)
+	})
+	sort.Slice(data.AwaitingExternal, func(i, j int) bool {
+		return data.AwaitingExternal[i].At.Before(data.AwaitingExternal[j].At)
+	})
+
+	for _, sum := range s.ActivitySummaries() {
+		act := HandoverActivity{Name: sum.Name, LastStatus: sum.LastStatus, LastError: sum.LastError, LastRunAt: sum.LastRunAt}
+		for _, r := range s.runs.list(sum.Name) {
+			if r.StartedAt.Before(since) || !r.StartedAt.Before(until) {
+				continue
+			}
+			act.Runs++
+			if r.Status == RunStatusFailed {
+				act.Failures++
+			}
+		}
+		if act.Failures > 0 || sum.LastStatus == RunStatusFailed {
+			data.FailingActivities = append(data.FailingActivities, act)
+		}
+	}
+
+	for _, list := range []*[]HandoverProposal{&data.OpenCritical, &data.Decisions, &data.AwaitingExternal} {
+		if len(*list) > maxHandoverItems {
+			*list = (*list)[:maxHandoverItems]
+			data.Truncated = true
+		}
+	}
+	return data
+}
+
+// GenerateHandover 由 Agent 根据 [since, now) 的交接数据撰写交接班摘要, since 为零值时取当前班次的开始;
+// post 为 true 时发往配置的通知目标。Agent 不可用时按模板生成, 保证交接不中断。
+func (s *Service) GenerateHandover(ctx context.Context, since time.Time, post bool) (*Handover, error) {
+	if post && len(s.handover.targets) == 0 {
+		return nil, fmt.Errorf("no handover targets configured")
+	}
+
+	now := time.Now()
+	if since.IsZero() {
+		since = s.handover.prev(now)
+	}
+	if !since.Before(now) {
+		return nil, fmt.Errorf("since must be before now")
+	}
+
+	h := &Handover{Data: s.HandoverData(since, now), GeneratedAt: now}
+	if s.agentLoop != nil {
+		prompt := buildHandoverPrompt(h.Data) + languageInstruction(s.outputLanguage(""))
+		response, err := s.agentLoop.ProcessDirect(ctx, prompt, "secops:handover:"+now.Format(time.RFC3339))
+		if err == nil && strings.TrimSpace(response) != "" {
+			h.Note = strings.TrimSpace(response)
+			h.Composed = true
+		} else if err != nil {
+			logger.WarnCF("secops", "Agent failed to compose handover, using template",
+				map[string]interface{}{
+					"error": err.Error(),
+				})
+		}
+	}
+	if h.Note == "" {
+		h.Note = formatHandover(h.Data)
+	}
+
+	if post {
+		payload := map[string]interface{}{
+			"event":    "shift.handover",
+			"handover": h,
+		}
+		for _, target := range s.handover.targets {
+			s.notifier.send(ctx, target, h.Note, payload)
+		}
+		h.PostedTo = s.handover.targets
+	}
+
+	logger.InfoCF("secops", "Handover generated",
+		map[string]interface{}{
+			"since":    since.Format(time.RFC3339),
+			"critical": len(h.Data.OpenCritical),
+			"decided":  len(h.Data.Decisions),
+			"failing":  len(h.Data.FailingActivities),
+			"awaiting": len(h.Data.AwaitingExternal),
+			"composed": h.Composed,
+			"posted":   post,
+		})
+	return h, nil
+}
+
+// runHandover 在每个交班时刻生成上一班次的交接班摘要并发送
+func (s *Service) runHandover() {
+	defer s.wg.Done()
+
+	for {
+		slot := s.handover.next(time.Now())
+		timer := time.NewTimer(time.Until(slot))
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if _, err := s.GenerateHandover(s.ctx, s.handover.prev(slot), true); err != nil {
+			logger.WarnCF("secops", "Scheduled handover failed",
+				map[string]interface{}{
+					"slot":  slot.Format(time.RFC3339),
+					"error": err.Error(),
+				})
+		}
+	}
+}
+
+// handoverCommand 处理 /handover 命令: 可选参数为班次时长 (如 8h), --post 同时发往通知目标
+func (s *Service) handoverCommand(ctx context.Context, msg bus.InboundMessage, args []string) string {
+	var since time.Time
+	post := false
+	for _, a := range args {
+		if a == "--post" {
+			post = true
+			continue
+		}
+		d, err := time.ParseDuration(a)
+		if err != nil || d <= 0 {
+			return "Usage: /handover [duration, e.g. 8h] [--post]"
+		}
+		since = time.Now().Add(-d)
+	}
+
+	h, err := s.GenerateHandover(ctx, since, post)
+	if err != nil {
+		return "生成交接班摘要失败: " + err.Error()
+	}
+	return h.Note
+}
+
+// buildHandoverPrompt 构建撰写交接班摘要的 prompt
+func buildHandoverPrompt(data HandoverData) string {
+	var sb strings.Builder
+	sb.WriteString("你是安全运营值班分析师, 请根据以下本班次的结构化数据撰写交接班说明, 供下一班分析师阅读。\n")
+	sb.WriteString("不要调用任何工具, 直接输出 Markdown, 按以下顺序分节: 未处理的高危提案、本班决策、异常的运营活动、等待外部团队的事项。\n")
+	sb.WriteString("每节先给出一句结论, 再逐条列出需要下一班跟进的内容并附提案 ID; 某一节没有数据时写 \"无\"。不要编造数据中没有的信息。\n")
+	if data.Truncated {
+		sb.WriteString("部分条目较多, 数据已截断, 请在说明中提示下一班到 Debug UI 查看完整列表。\n")
+	}
+	fmt.Fprintf(&sb, "\n班次: %s 至 %s\n", data.Since.Format("2006-01-02 15:04"), data.Until.Format("2006-01-02 15:04"))
+
+	body, _ := json.MarshalIndent(data, "", "  ")
+	sb.WriteString("\n数据:\n" + string(body) + "\n")
+	return sb.String()
+}
+
+// formatHandover Agent 不可用时按模板生成的交接说明
+func formatHandover(data HandoverData) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[SecOps] 交接班摘要 %s ~ %s\n", data.Since.Format("01-02 15:04"), data.Until.Format("01-02 15:04"))
+
+	section := func(title string, n int) {
+		fmt.Fprintf(&sb, "\n## %s (%d)\n", title, n)
+		if n == 0 {
+			sb.WriteString("无\n")
+		}
+	}
+
+	section("未处理的高危提案", len(data.OpenCritical))
+	for _, p := range data.OpenCritical {
+		fmt.Fprintf(&sb, "- [%s] %s (%s)", p.Severity, p.Title, p.ID)
+		if p.Assignee != "" {
+			fmt.Fprintf(&sb, " 负责人: %s", p.Assignee)
+		}
+		sb.WriteString("\n")
+	}
+
+	section("本班决策", len(data.Decisions))
+	for _, p := range data.Decisions {
+		fmt.Fprintf(&sb, "- %s %s (%s)", p.Action, p.Title, p.ID)
+		if p.By != "" {
+			fmt.Fprintf(&sb, " by %s", p.By)
+		}
+		if p.Execution == ExecutionFailed {
+			sb.WriteString(" 执行失败")
+		}
+		sb.WriteString("\n")
+	}
+
+	section("异常的运营活动", len(data.FailingActivities))
+	for _, a := range data.FailingActivities {
+		fmt.Fprintf(&sb, "- %s: 本班失败 %d/%d", a.Name, a.Failures, a.Runs)
+		if a.LastError != "" {
+			fmt.Fprintf(&sb, ", 最近错误: %s", truncateText(a.LastError))
+		}
+		sb.WriteString("\n")
+	}
+
+	section("等待外部团队的事项", len(data.AwaitingExternal))
+	for _, p := range data.AwaitingExternal {
+		fmt.Fprintf(&sb, "- %s (%s)", p.Title, p.ID)
+		if p.Note != "" {
+			fmt.Fprintf(&sb, ": %s", p.Note)
+		}
+		sb.WriteString("\n")
+	}
+
+	if data.Truncated {
+		sb.WriteString("\n部分条目已截断, 完整列表见 Debug UI。\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,123 @@
+package secops
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestHandoverSchedule(t *testing.T) {
+	targets := map[string]config.NotifyTargetConfig{"slack_soc": {Type: NotifyTargetChannel, Channel: "slack", ChatID: "C1"}}
+	hs, err := newHandoverSchedule(config.HandoverConfig{
+		Times:    []string{"21:00", "09:00"},
+		Timezone: "Asia/Shanghai",
+		Targets:  []string{"slack_soc"},
+	}, "", targets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc := hs.loc
+	at := func(day, hour, min int) time.Time { return time.Date(2026, 3, day, hour, min, 0, 0, loc) }
+
+	for _, tc := range []struct {
+		now, next, prev time.Time
+	}{
+		{at(2, 15, 0), at(2, 21, 0), at(2, 9, 0)},
+		{at(2, 21, 0), at(3, 9, 0), at(2, 9, 0)},
+		{at(2, 23, 30), at(3, 9, 0), at(2, 21, 0)},
+		{at(2, 3, 0), at(2, 9, 0), at(1, 21, 0)},
+	} {
+		if got := hs.next(tc.now); !got.Equal(tc.next) {
+			t.Errorf("next(%v) = %v, want %v", tc.now, got, tc.next)
+		}
+		if got := hs.prev(tc.now); !got.Equal(tc.prev) {
+			t.Errorf("prev(%v) = %v, want %v", tc.now, got, tc.prev)
+		}
+	}
+
+	// 未配置交班时刻时班次为默认时长
+	hs, _ = newHandoverSchedule(config.HandoverConfig{}, "", nil)
+	if now := at(2, 15, 0); !hs.prev(now).Equal(now.Add(-defaultShiftLength)) || !hs.next(now).IsZero() {
+		t.Errorf("default prev = %v, next = %v", hs.prev(now), hs.next(now))
+	}
+
+	for _, cfg := range []config.HandoverConfig{
+		{Times: []string{"25:00"}, Targets: []string{"slack_soc"}},
+		{Times: []string{"09:00"}},
+		{Targets: []string{"unknown"}},
+		{Timezone: "Mars/Olympus"},
+	} {
+		if _, err := newHandoverSchedule(cfg, "", targets); err == nil {
+			t.Errorf("%+v: expected error", cfg)
+		}
+	}
+}
+
+func TestHandoverData(t *testing.T) {
+	svc := &Service{
+		config: &config.SecOpsConfig{Activities: map[string]config.ActivityConfig{
+			"risk_analysis": {Enabled: true, Mode: "manual", Schedule: "30m"},
+			"host_analysis": {Enabled: true, Mode: "manual", Schedule: "30m"},
+		}},
+		proposalService: NewProposalService(),
+		runs:            newRunStore(),
+	}
+	ps := svc.proposalService
+	since := time.Now().Add(-time.Hour)
+
+	critical := NewProposal("risk", "撞库攻击", "", nil)
+	critical.Severity = SeverityCritical
+	ps.Create(critical)
+	low := NewProposal("risk", "端口扫描", "", nil)
+	low.Severity = SeverityLow
+	ps.Create(low)
+	if _, err := ps.Acknowledge(low.ID, 24*time.Hour, "等待网络组确认防火墙策略", Actor{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	decided := ps.Create(NewProposal("host", "WebShell 落地", "", nil))
+	if err := ps.Accept(decided, DecisionRequest{Reason: "已确认", By: Actor{Name: "bob"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	run := svc.runs.start("risk_analysis")
+	svc.runs.finish(run, "", errors.New("clickhouse timeout"))
+	run = svc.runs.start("host_analysis")
+	svc.runs.finish(run, "ok", nil)
+
+	data := svc.HandoverData(since, time.Now())
+	if len(data.OpenCritical) != 1 || data.OpenCritical[0].ID != critical.ID {
+		t.Errorf("open critical = %+v", data.OpenCritical)
+	}
+	if len(data.AwaitingExternal) != 1 || data.AwaitingExternal[0].Note != "等待网络组确认防火墙策略" {
+		t.Errorf("awaiting = %+v", data.AwaitingExternal)
+	}
+	if len(data.Decisions) != 1 || data.Decisions[0].Action != ActionAccept || data.Decisions[0].By != "bob" {
+		t.Errorf("decisions = %+v", data.Decisions)
+	}
+	if len(data.FailingActivities) != 1 || data.FailingActivities[0].Name != "risk_analysis" || data.FailingActivities[0].Failures != 1 {
+		t.Errorf("failing = %+v", data.FailingActivities)
+	}
+
+	// 班次之前的决策不计入
+	if data := svc.HandoverData(time.Now(), time.Now().Add(time.Hour)); len(data.Decisions) != 0 {
+		t.Errorf("decisions outside shift = %+v", data.Decisions)
+	}
+
+	// 没有 Agent 时按模板生成
+	svc.handover, _ = newHandoverSchedule(config.HandoverConfig{}, "", nil)
+	h, err := svc.GenerateHandover(context.Background(), since, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Composed || !strings.Contains(h.Note, "撞库攻击") || !strings.Contains(h.Note, "等待网络组确认防火墙策略") ||
+		!strings.Contains(h.Note, "clickhouse timeout") {
+		t.Errorf("note = %q", h.Note)
+	}
+	if _, err := svc.GenerateHandover(context.Background(), since, true); err == nil {
+		t.Error("post without targets: expected error")
+	}
+}
@@ -0,0 +1,220 @@
+package secops
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// maxHookDepth activity 钩子链式触发的最大深度, 防止循环触发
+const maxHookDepth = 3
+
+// 钩子类型
+const (
+	HookNotify   = "notify"
+	HookActivity = "activity"
+	HookReport   = "report"
+	HookWebhook  = "webhook"
+)
+
+// hookData 钩子模板可用的数据
+type hookData struct {
+	Activity  string
+	Run       *Run
+	Proposals []*Proposal
+}
+
+// defaultHookMessage notify 钩子的默认消息模板
+const defaultHookMessage = `[SecOps] 活动 {{.Activity}} 执行{{if eq .Run.Status "succeeded"}}成功{{else}}失败: {{.Run.Error}}{{end}}, 新提案 {{len .Proposals}} 条`
+
+// validateHooks 校验活动钩子配置
+func (s *Service) validateHooks() error {
+	for name, act := range s.config.Activities {
+		for i, h := range act.Hooks {
+			prefix := fmt.Sprintf("activity %s hook #%d", name, i+1)
+			switch h.On {
+			case "", "always", "success", "failure":
+			default:
+				return fmt.Errorf("%s: invalid on %q", prefix, h.On)
+			}
+
+			switch h.Type {
+			case HookNotify:
+				if h.Channel == "" || h.ChatID == "" {
+					return fmt.Errorf("%s: notify requires channel and chat_id", prefix)
+				}
+				if h.Message != "" {
+					if _, err := template.New("message").Parse(h.Message); err != nil {
+						return fmt.Errorf("%s: invalid message template: %w", prefix, err)
+					}
+				}
+			case HookActivity:
+				if _, ok := s.config.Activities[h.Activity]; !ok {
+					return fmt.Errorf("%s: unknown activity %q", prefix, h.Activity)
+				}
+			case HookReport:
+				if h.Template == "" {
+					return fmt.Errorf("%s: report requires template", prefix)
+				}
+			case HookWebhook:
+				if !strings.HasPrefix(h.URL, "http://") && !strings.HasPrefix(h.URL, "https://") {
+					return fmt.Errorf("%s: webhook requires an http(s) url", prefix)
+				}
+			default:
+				return fmt.Errorf("%s: unknown type %q", prefix, h.Type)
+			}
+		}
+	}
+	return nil
+}
+
+// runHooks 按配置顺序执行活动结束后的钩子, 结果记录到执行历史
+func (s *Service) runHooks(run *Run, depth int) {
+	act, ok := s.activityConfig(run.Activity)
+	if !ok || len(act.Hooks) == 0 {
+		return
+	}
+
+	data := hookData{Activity: run.Activity, Run: run, Proposals: make([]*Proposal, 0, len(run.ProposalIDs))}
+	for _, id := range run.ProposalIDs {
+		if p, ok := s.proposalService.Get(id); ok {
+			data.Proposals = append(data.Proposals, p)
+		}
+	}
+
+	for _, h := range act.Hooks {
+		if !hookApplies(h.On, run.Status) {
+			continue
+		}
+
+		start := time.Now()
+		result := HookResult{Type: h.Type, Status: RunStatusSucceeded}
+		var err error
+		switch h.Type {
+		case HookNotify:
+			result.Target = h.Channel + ":" + h.ChatID
+			err = s.notifyHook(h, data)
+		case HookActivity:
+			result.Target = h.Activity
+			if depth >= maxHookDepth {
+				err = fmt.Errorf("hook chain depth limit (%d) reached", maxHookDepth)
+				break
+			}
+			// 沿用上游活动的执行名额, 被触发的活动已在执行时跳过
+			if !s.beginActivity(h.Activity) {
+				s.pool.skip(h.Activity, SkipOverlap, time.Now())
+				err = ErrActivityRunning
+				break
+			}
+			result.Output = s.execute(h.Activity, depth+1).ID
+			s.pool.end(h.Activity)
+		case HookReport:
+			result.Target = h.Template
+			result.Output, err = s.reportHook(h, data)
+		case HookWebhook:
+			result.Target = h.URL
+			err = s.webhookHook(h, run)
+		}
+
+		result.DurationMs = time.Since(start).Milliseconds()
+		if err != nil {
+			result.Status = RunStatusFailed
+			result.Error = err.Error()
+			logger.WarnCF("secops", "Activity hook failed",
+				map[string]interface{}{
+					"activity": run.Activity,
+					"hook":     h.Type,
+					"target":   result.Target,
+					"error":    err.Error(),
+				})
+		}
+		s.runs.addHookResult(run, result)
+	}
+}
+
+func hookApplies(on, status string) bool {
+	switch on {
+	case "success":
+		return status == RunStatusSucceeded
+	case "failure":
+		return status == RunStatusFailed
+	}
+	return true
+}
+
+// notifyHook 向消息通道发送执行摘要
+func (s *Service) notifyHook(h config.HookConfig, data hookData) error {
+	if s.msgBus == nil {
+		return fmt.Errorf("message bus not available")
+	}
+	msg := h.Message
+	if msg == "" {
+		msg = defaultHookMessage
+	}
+	content, err := renderHookTemplate(msg, data)
+	if err != nil {
+		return err
+	}
+	s.msgBus.PublishOutbound(bus.OutboundMessage{Channel: h.Channel, ChatID: h.ChatID, Content: content})
+	return nil
+}
+
+// reportHook 使用模板生成报告, 写入 <workspace>/secops/reports, 返回报告路径
+func (s *Service) reportHook(h config.HookConfig, data hookData) (string, error) {
+	tmplPath := h.Template
+	if !filepath.IsAbs(tmplPath) {
+		tmplPath = filepath.Join(s.workspace, tmplPath)
+	}
+	tmpl, err := os.ReadFile(tmplPath)
+	if err != nil {
+		return "", err
+	}
+	content, err := renderHookTemplate(string(tmpl), data)
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(s.workspace, "secops", "reports")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	ext := filepath.Ext(tmplPath)
+	if ext == "" || ext == ".tmpl" {
+		ext = ".md"
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s%s", data.Activity, data.Run.StartedAt.Format("20060102-150405"), ext))
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// webhookHook 以 JSON 推送执行记录
+func (s *Service) webhookHook(h config.HookConfig, run *Run) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	return postJSON(s.runCtx, client, h.URL, h.Headers, map[string]interface{}{
+		"event": "run.finished",
+		"run":   run,
+	})
+}
+
+func renderHookTemplate(text string, data hookData) (string, error) {
+	tmpl, err := template.New("hook").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
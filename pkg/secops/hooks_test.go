@@ -0,0 +1,92 @@
+package secops
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestValidateHooks(t *testing.T) {
+	cases := []struct {
+		hook    config.HookConfig
+		wantErr bool
+	}{
+		{config.HookConfig{Type: HookActivity, Activity: "weak_analysis"}, false},
+		{config.HookConfig{Type: HookActivity, Activity: "missing"}, true},
+		{config.HookConfig{Type: HookNotify, Channel: "telegram"}, true},
+		{config.HookConfig{Type: HookWebhook, URL: "ftp://x"}, true},
+		{config.HookConfig{On: "sometimes", Type: HookReport, Template: "r.tmpl"}, true},
+		{config.HookConfig{Type: "email"}, true},
+	}
+
+	for _, c := range cases {
+		svc := &Service{config: &config.SecOpsConfig{Activities: map[string]config.ActivityConfig{
+			"risk_analysis": {Hooks: []config.HookConfig{c.hook}},
+			"weak_analysis": {},
+		}}}
+		if err := svc.validateHooks(); (err != nil) != c.wantErr {
+			t.Errorf("%+v: err = %v, wantErr %v", c.hook, err, c.wantErr)
+		}
+	}
+}
+
+func TestRunHooks_RecordsResults(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+
+	workspace := t.TempDir()
+	os.WriteFile(filepath.Join(workspace, "report.tmpl"), []byte("# {{.Activity}} {{.Run.Status}} {{len .Proposals}}"), 0600)
+
+	svc := &Service{
+		ctx:       context.Background(),
+		runCtx:    context.Background(),
+		workspace: workspace,
+		config: &config.SecOpsConfig{Activities: map[string]config.ActivityConfig{
+			"risk_analysis": {Hooks: []config.HookConfig{
+				{On: "failure", Type: HookWebhook, URL: srv.URL},
+				{On: "success", Type: HookWebhook, URL: srv.URL, Headers: map[string]string{"X-Token": "secret"}},
+				{Type: HookReport, Template: "report.tmpl"},
+				{Type: HookWebhook, URL: srv.URL},
+			}},
+		}},
+		proposalService: NewProposalService(),
+		runs:            newRunStore(),
+	}
+
+	run := svc.runs.start("risk_analysis")
+	svc.runs.finish(run, "ok", nil)
+	svc.runHooks(run, 0)
+
+	if len(run.Hooks) != 3 {
+		t.Fatalf("hooks = %+v, want 3 results (failure hook skipped)", run.Hooks)
+	}
+	if run.Hooks[0].Status != RunStatusSucceeded || got["event"] != "run.finished" {
+		t.Errorf("webhook result = %+v, payload = %v", run.Hooks[0], got)
+	}
+
+	report, err := os.ReadFile(run.Hooks[1].Output)
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	if string(report) != "# risk_analysis succeeded 0" {
+		t.Errorf("report = %q", report)
+	}
+
+	if run.Hooks[2].Status != RunStatusFailed || !strings.Contains(run.Hooks[2].Error, "401") {
+		t.Errorf("unauthorized webhook result = %+v", run.Hooks[2])
+	}
+}
@@ -0,0 +1,274 @@
+package secops
+
+import (
+	"crypto/sha1"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// importFields 可导入的提案字段
+var importFields = []string{"id", "type", "title", "summary", "status", "severity", "confidence", "recommendation", "reason", "created_at"}
+
+// validProposalTypes 提案类型
+var validProposalTypes = map[string]bool{"risk": true, "weak": true, "api_biz": true, "app": true, "host": true, "k8s": true, "cloud": true}
+
+// importTimeLayouts 导入时支持的时间格式
+var importTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006/01/02 15:04:05", dateLayout, "2006/01/02"}
+
+// ImportOptions 历史提案导入选项
+type ImportOptions struct {
+	Format  string            // csv 或 json
+	Mapping map[string]string // 提案字段 -> 外部列名, 未配置的字段使用同名列
+	DryRun  bool              // 只校验并生成报告, 不写入
+	Source  string            // 导入来源 (如文件名), 记录在审计日志中
+}
+
+// ImportError 导入失败的行
+type ImportError struct {
+	Row     int    `json:"row"` // CSV 为文件行号 (含表头), JSON 为数组下标 + 1
+	Message string `json:"message"`
+}
+
+// ImportReport 导入报告
+type ImportReport struct {
+	Total      int           `json:"total"`
+	Imported   int           `json:"imported"`
+	Duplicates int           `json:"duplicates"`
+	Invalid    int           `json:"invalid"`
+	Errors     []ImportError `json:"errors,omitempty"`
+	DryRun     bool          `json:"dry_run"`
+}
+
+// ImportProposals 从 CSV/JSON 导入历史提案; 按 ID 去重, 未提供 ID 时由记录内容生成稳定 ID
+func ImportProposals(svc *ProposalService, r io.Reader, opts ImportOptions) (*ImportReport, error) {
+	rows, err := readImportRows(r, opts.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{Total: len(rows), DryRun: opts.DryRun}
+	seen := make(map[string]bool)
+	proposals := make([]*Proposal, 0, len(rows))
+
+	for _, row := range rows {
+		p, err := mapImportRow(row.values, opts.Mapping)
+		if err != nil {
+			report.Invalid++
+			report.Errors = append(report.Errors, ImportError{Row: row.line, Message: err.Error()})
+			continue
+		}
+		if seen[p.ID] || svc.exists(p.ID) {
+			report.Duplicates++
+			continue
+		}
+		seen[p.ID] = true
+		proposals = append(proposals, p)
+	}
+
+	if opts.DryRun {
+		report.Imported = len(proposals)
+		return report, nil
+	}
+	report.Imported = svc.importProposals(proposals, Actor{Name: opts.Source, Via: ViaImport})
+	report.Duplicates += len(proposals) - report.Imported
+	return report, nil
+}
+
+type importRow struct {
+	line   int
+	values map[string]string
+}
+
+func readImportRows(r io.Reader, format string) ([]importRow, error) {
+	switch format {
+	case "csv":
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		records, err := cr.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("invalid csv: %w", err)
+		}
+		if len(records) == 0 {
+			return nil, fmt.Errorf("csv has no header row")
+		}
+		header := records[0]
+		header[0] = strings.TrimPrefix(header[0], "\ufeff") // Excel 导出的 BOM
+		rows := make([]importRow, 0, len(records)-1)
+		for i, rec := range records[1:] {
+			values := make(map[string]string, len(header))
+			for j, col := range header {
+				if j < len(rec) {
+					values[strings.TrimSpace(col)] = strings.TrimSpace(rec[j])
+				}
+			}
+			rows = append(rows, importRow{line: i + 2, values: values})
+		}
+		return rows, nil
+
+	case "json":
+		var items []map[string]interface{}
+		if err := json.NewDecoder(r).Decode(&items); err != nil {
+			return nil, fmt.Errorf("invalid json: expected an array of objects: %w", err)
+		}
+		rows := make([]importRow, 0, len(items))
+		for i, item := range items {
+			values := make(map[string]string, len(item))
+			for k, v := range item {
+				switch v := v.(type) {
+				case nil:
+				case string:
+					values[k] = strings.TrimSpace(v)
+				case map[string]interface{}, []interface{}:
+					data, _ := json.Marshal(v)
+					values[k] = string(data)
+				default:
+					values[k] = fmt.Sprint(v)
+				}
+			}
+			rows = append(rows, importRow{line: i + 1, values: values})
+		}
+		return rows, nil
+	}
+	return nil, fmt.Errorf("unsupported import format %q (csv, json)", format)
+}
+
+// mapImportRow 按映射将一行转换为提案, 未映射的列保存在 Details 中
+func mapImportRow(values map[string]string, mapping map[string]string) (*Proposal, error) {
+	used := make(map[string]bool)
+	get := func(field string) string {
+		col := field
+		if m, ok := mapping[field]; ok {
+			col = m
+		}
+		used[col] = true
+		return values[col]
+	}
+
+	fields := make(map[string]string, len(importFields))
+	for _, f := range importFields {
+		fields[f] = get(f)
+	}
+
+	if fields["title"] == "" {
+		return nil, fmt.Errorf("title is required")
+	}
+	ptype := strings.ToLower(fields["type"])
+	if !validProposalTypes[ptype] {
+		return nil, fmt.Errorf("invalid type %q", fields["type"])
+	}
+
+	createdAt := time.Now()
+	if v := fields["created_at"]; v != "" {
+		t, err := parseImportTime(v)
+		if err != nil {
+			return nil, err
+		}
+		createdAt = t
+	}
+
+	status := ProposalStatus(strings.ToLower(fields["status"]))
+	switch status {
+	case "":
+		status = ProposalStatusAccepted
+	case ProposalStatusPending, ProposalStatusAccepted, ProposalStatusIgnored, ProposalStatusModified:
+	default:
+		return nil, fmt.Errorf("invalid status %q", fields["status"])
+	}
+
+	severity := strings.ToLower(fields["severity"])
+	if severity != "" && !validSeverities[severity] {
+		return nil, fmt.Errorf("invalid severity %q", fields["severity"])
+	}
+
+	confidence := 0
+	if v := fields["confidence"]; v != "" {
+		c, err := strconv.Atoi(strings.TrimSuffix(v, "%"))
+		if err != nil || c < 0 || c > 100 {
+			return nil, fmt.Errorf("invalid confidence %q", v)
+		}
+		confidence = c
+	}
+
+	recommendation := strings.ToLower(fields["recommendation"])
+	if recommendation != "" && recommendation != ActionAccept && recommendation != ActionIgnore {
+		return nil, fmt.Errorf("invalid recommendation %q", fields["recommendation"])
+	}
+
+	details := make(map[string]interface{})
+	cols := make([]string, 0, len(values))
+	for col := range values {
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+	for _, col := range cols {
+		if !used[col] && values[col] != "" {
+			details[col] = values[col]
+		}
+	}
+
+	p := NewProposal(ptype, fields["title"], fields["summary"], details)
+	p.ID = fields["id"]
+	if p.ID != "" {
+		if err := validateExternalID(p.ID); err != nil {
+			return nil, err
+		}
+	} else {
+		p.ID = importID(ptype, fields["title"], fields["created_at"], createdAt, values)
+	}
+	p.Status = status
+	p.Severity = severity
+	p.Confidence = confidence
+	p.Recommendation = recommendation
+	p.CreatedAt = createdAt
+	p.UpdatedAt = createdAt
+
+	if status == ProposalStatusAccepted || status == ProposalStatusIgnored {
+		action := ActionAccept
+		if status == ProposalStatusIgnored {
+			action = ActionIgnore
+		}
+		p.Decision = &Decision{
+			Action:    action,
+			Reason:    fields["reason"],
+			Override:  recommendation != "" && recommendation != action,
+			DecidedAt: createdAt,
+		}
+	}
+	return p, nil
+}
+
+// importID 为未提供 ID 的记录生成稳定 ID: 有创建时间时按类型+标题+创建时间,
+// 否则按记录的全部列, 同一份历史重复导入得到相同的 ID
+func importID(ptype, title, rawTime string, createdAt time.Time, values map[string]string) string {
+	key := ptype + "\x00" + title + "\x00"
+	if rawTime != "" {
+		key += createdAt.UTC().Format(time.RFC3339)
+	} else {
+		cols := make([]string, 0, len(values))
+		for col := range values {
+			cols = append(cols, col)
+		}
+		sort.Strings(cols)
+		for _, col := range cols {
+			key += "\x00" + col + "=" + values[col]
+		}
+	}
+	sum := sha1.Sum([]byte(key))
+	return "import-" + hex.EncodeToString(sum[:8])
+}
+
+func parseImportTime(v string) (time.Time, error) {
+	for _, layout := range importTimeLayouts {
+		if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid created_at %q", v)
+}
@@ -0,0 +1,108 @@
+package secops
+
+import (
+	"strings"
+	"testing"
+)
+
+const importCSV = "\ufeff事件,类型,结论,理由,时间,主机\n" +
+	"SQL 注入,risk,ignored,扫描器,2025-03-01 10:00:00,10.0.0.1\n" +
+	"SQL 注入,risk,ignored,扫描器,2025-03-01 10:00:00,10.0.0.1\n" +
+	",weak,accepted,,2025-03-02,\n" +
+	"越权访问,api,accepted,,2025-03-02,\n" +
+	"弱口令,weak,accepted,,bad-date,\n" +
+	"弱口令,weak,,,2025-03-03,10.0.0.2\n"
+
+var importMapping = map[string]string{
+	"title":      "事件",
+	"type":       "类型",
+	"status":     "结论",
+	"reason":     "理由",
+	"created_at": "时间",
+}
+
+func TestImportProposals_CSV(t *testing.T) {
+	s := NewProposalService()
+
+	report, err := ImportProposals(s, strings.NewReader(importCSV), ImportOptions{Format: "csv", Mapping: importMapping, DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if report.Total != 6 || report.Imported != 2 || report.Duplicates != 1 || report.Invalid != 3 {
+		t.Errorf("unexpected dry run report: %+v", report)
+	}
+	if len(s.GetAll()) != 0 {
+		t.Fatal("dry run must not write proposals")
+	}
+	if report.Errors[0].Row != 4 {
+		t.Errorf("expected first error on line 4, got %+v", report.Errors[0])
+	}
+
+	report, err = ImportProposals(s, strings.NewReader(importCSV), ImportOptions{Format: "csv", Mapping: importMapping})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if report.Imported != 2 {
+		t.Errorf("expected 2 imported, got %+v", report)
+	}
+
+	var ignored *Proposal
+	for _, p := range s.GetAll() {
+		if p.Status == ProposalStatusIgnored {
+			ignored = p
+		}
+	}
+	if ignored == nil || ignored.Decision == nil || ignored.Decision.Reason != "扫描器" || ignored.Details["主机"] != "10.0.0.1" {
+		t.Fatalf("unexpected imported proposal: %+v", ignored)
+	}
+
+	// 再次导入全部视为重复
+	report, _ = ImportProposals(s, strings.NewReader(importCSV), ImportOptions{Format: "csv", Mapping: importMapping})
+	if report.Imported != 0 || report.Duplicates != 3 {
+		t.Errorf("expected re-import to dedup, got %+v", report)
+	}
+}
+
+func TestImportProposals_JSON(t *testing.T) {
+	s := NewProposalService()
+	input := `[{"id": "h-1", "type": "app", "title": "OA 系统", "status": "pending", "score": 3}]`
+
+	report, err := ImportProposals(s, strings.NewReader(input), ImportOptions{Format: "json"})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if report.Imported != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	p, ok := s.Get("h-1")
+	if !ok || p.Status != ProposalStatusPending || p.Decision != nil || p.Details["score"] != "3" {
+		t.Errorf("unexpected proposal: %+v", p)
+	}
+}
+
+func TestImportProposals_StableIDWithoutTime(t *testing.T) {
+	s := NewProposalService()
+	input := `[{"type": "risk", "title": "暴力破解", "host": "10.0.0.3"}, {"type": "risk", "title": "暴力破解", "host": "10.0.0.4"}]`
+
+	report, err := ImportProposals(s, strings.NewReader(input), ImportOptions{Format: "json"})
+	if err != nil || report.Imported != 2 {
+		t.Fatalf("import failed: %+v, %v", report, err)
+	}
+	report, _ = ImportProposals(s, strings.NewReader(input), ImportOptions{Format: "json"})
+	if report.Imported != 0 || report.Duplicates != 2 {
+		t.Errorf("expected re-import without created_at to dedup, got %+v", report)
+	}
+}
+
+func TestImportProposals_InvalidID(t *testing.T) {
+	s := NewProposalService()
+	input := `[{"id": "../../x", "type": "risk", "title": "a"}, {"id": "a?b", "type": "risk", "title": "b"}, {"id": "ok-1", "type": "risk", "title": "c"}]`
+
+	report, err := ImportProposals(s, strings.NewReader(input), ImportOptions{Format: "json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Imported != 1 || report.Invalid != 2 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
@@ -0,0 +1,208 @@
+package secops
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// ErrInvalidProposal 外部提交的提案未通过校验
+var ErrInvalidProposal = errors.New("invalid proposal")
+
+// ErrProposalExists 外部提交的提案 ID 已存在, 用于调用方重试时去重
+var ErrProposalExists = errors.New("proposal already exists")
+
+// maxExternalIDLength 外部指定的提案 ID 长度上限
+const maxExternalIDLength = 128
+
+// validateExternalID 校验外部系统或导入文件指定的提案 ID: ID 会出现在 URL 路径和换出文件名中
+func validateExternalID(id string) error {
+	if id == "" || len(id) > maxExternalIDLength || strings.ContainsAny(id, "/\\?#") || strings.Contains(id, "..") {
+		return fmt.Errorf("id must be 1-%d characters without / \\ ? # or ..", maxExternalIDLength)
+	}
+	return nil
+}
+
+// validParamTypes 可调整参数的类型
+var validParamTypes = map[string]bool{"string": true, "number": true, "select": true}
+
+// validActionTypes 提案可选操作的类型
+var validActionTypes = map[string]bool{ActionAccept: true, ActionIgnore: true, "modify": true}
+
+// ExternalProposal SOAR、脚本等外部系统提交的提案, 与 Agent 创建的提案进入同一审批队列
+type ExternalProposal struct {
+	ID             string                 `json:"id,omitempty"` // 可选, 指定后重复提交返回 ErrProposalExists
+	Type           string                 `json:"type"`
+	Title          string                 `json:"title"`
+	Summary        string                 `json:"summary"`
+	Severity       string                 `json:"severity,omitempty"`
+	Confidence     int                    `json:"confidence,omitempty"`
+	Recommendation string                 `json:"recommendation,omitempty"`
+	Details        map[string]interface{} `json:"details,omitempty"`
+	Parameters     map[string]Param       `json:"parameters,omitempty"`
+	Actions        []ProposalAction       `json:"actions,omitempty"`
+	Evidence       []Evidence             `json:"evidence,omitempty"`
+	Techniques     []string               `json:"techniques,omitempty"`
+	CaseID         string                 `json:"caseId,omitempty"`
+	Items          []map[string]string    `json:"items,omitempty"`
+	AcceptAPI      string                 `json:"acceptApi,omitempty"`
+	IgnoreAPI      string                 `json:"ignoreApi,omitempty"`
+	Source         string                 `json:"source,omitempty"` // 来源系统名称, 记为创建者
+}
+
+// IngestProposal 校验外部提交的提案并加入审批队列, 返回提案 ID; 校验失败返回 ErrInvalidProposal
+func (s *Service) IngestProposal(in ExternalProposal, by Actor) (string, error) {
+	p, err := s.buildExternalProposal(in)
+	if err != nil {
+		return "", err
+	}
+
+	s.ingestMu.Lock()
+	defer s.ingestMu.Unlock()
+	if p.ID != "" && s.proposalService.exists(p.ID) {
+		return "", fmt.Errorf("%w: %s", ErrProposalExists, p.ID)
+	}
+
+	if source := strings.TrimSpace(in.Source); source != "" {
+		by.Name = source
+	}
+	p.CreatedBy = &by
+	id := s.CreateProposal(p)
+
+	logger.InfoCF("secops", "External proposal ingested",
+		map[string]interface{}{
+			"id":     id,
+			"type":   p.Type,
+			"title":  p.Title,
+			"source": by.Name,
+			"via":    by.Via,
+		})
+	return id, nil
+}
+
+// buildExternalProposal 校验字段并生成提案; 与 Agent 提交的提案执行相同的格式约束
+func (s *Service) buildExternalProposal(in ExternalProposal) (*Proposal, error) {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrInvalidProposal, fmt.Sprintf(format, args...))
+	}
+
+	if !validProposalTypes[in.Type] {
+		return nil, invalid("unknown type %q", in.Type)
+	}
+	if strings.TrimSpace(in.Title) == "" {
+		return nil, invalid("title is required")
+	}
+	if in.Severity != "" && !validSeverities[in.Severity] {
+		return nil, invalid("unknown severity %q", in.Severity)
+	}
+	if in.Confidence < 0 || in.Confidence > 100 {
+		return nil, invalid("confidence must be between 0 and 100")
+	}
+	if in.Recommendation != "" && in.Recommendation != ActionAccept && in.Recommendation != ActionIgnore {
+		return nil, invalid("unknown recommendation %q", in.Recommendation)
+	}
+	if in.ID != "" {
+		if err := validateExternalID(in.ID); err != nil {
+			return nil, invalid("%v", err)
+		}
+	}
+
+	techniques, err := normalizeTechniques(in.Techniques)
+	if err != nil {
+		return nil, invalid("%v", err)
+	}
+	params, err := validateExternalParams(in.Parameters)
+	if err != nil {
+		return nil, invalid("%v", err)
+	}
+	for i, a := range in.Actions {
+		if !validActionTypes[a.Type] {
+			return nil, invalid("actions[%d]: unknown type %q (accept, ignore, modify)", i, a.Type)
+		}
+		if strings.TrimSpace(a.Label) == "" {
+			return nil, invalid("actions[%d]: label is required", i)
+		}
+	}
+	for i, ev := range in.Evidence {
+		if strings.TrimSpace(ev.Content) == "" {
+			return nil, invalid("evidence[%d]: content is required", i)
+		}
+	}
+	_, apiTool := s.tools()
+	for _, api := range []string{in.AcceptAPI, in.IgnoreAPI} {
+		if api != "" && apiTool != nil && !apiTool.HasAPI(api) {
+			return nil, invalid("unknown sheikah api %q", api)
+		}
+	}
+
+	p := NewProposal(in.Type, strings.TrimSpace(in.Title), in.Summary, in.Details)
+	p.ID = in.ID
+	p.Severity = in.Severity
+	p.Confidence = in.Confidence
+	p.Recommendation = in.Recommendation
+	p.CaseID = in.CaseID
+	p.Techniques = techniques
+	p.Parameters = params
+	p.Actions = in.Actions
+	p.Evidence = in.Evidence
+	p.Items = in.Items
+	if in.AcceptAPI != "" || in.IgnoreAPI != "" {
+		b := defaultBinding(p)
+		if b == nil {
+			b = &ActionBinding{}
+		}
+		if in.AcceptAPI != "" {
+			b.Accept = in.AcceptAPI
+		}
+		if in.IgnoreAPI != "" {
+			b.Ignore = in.IgnoreAPI
+		}
+		p.Binding = b
+	}
+
+	if violations := s.constraints.check(p); len(violations) > 0 {
+		return nil, invalid("%s", strings.Join(violations, "; "))
+	}
+	return p, nil
+}
+
+// validateExternalParams 校验可调整参数的定义: 类型、select 的可选值和默认值; Key 为空时取 map 的键
+func validateExternalParams(params map[string]Param) (map[string]Param, error) {
+	out := make(map[string]Param, len(params))
+	for key, param := range params {
+		if param.Key == "" {
+			param.Key = key
+		}
+		if param.Key != key {
+			return nil, fmt.Errorf("parameters.%s: key %q does not match", key, param.Key)
+		}
+		if param.Type == "" {
+			param.Type = "string"
+		}
+		if !validParamTypes[param.Type] {
+			return nil, fmt.Errorf("parameters.%s: unknown type %q (string, number, select)", key, param.Type)
+		}
+		if param.Label == "" {
+			param.Label = key
+		}
+		switch param.Type {
+		case "number":
+			if _, err := strconv.ParseFloat(param.Value, 64); param.Value != "" && err != nil {
+				return nil, fmt.Errorf("parameters.%s: value %q is not a number", key, param.Value)
+			}
+		case "select":
+			if len(param.Options) == 0 {
+				return nil, fmt.Errorf("parameters.%s: select requires options", key)
+			}
+			if param.Value != "" && !slices.Contains(param.Options, param.Value) {
+				return nil, fmt.Errorf("parameters.%s: value %q is not one of the options", key, param.Value)
+			}
+		}
+		out[key] = param
+	}
+	return out, nil
+}
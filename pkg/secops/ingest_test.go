@@ -0,0 +1,88 @@
+package secops
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestService_IngestProposal(t *testing.T) {
+	svc := &Service{config: &config.SecOpsConfig{}, proposalService: NewProposalService(), runs: newRunStore()}
+
+	in := ExternalProposal{
+		ID:       "soar-1001",
+		Type:     "risk",
+		Title:    "撞库攻击",
+		Summary:  "同一 IP 短时间内尝试 300 个账号",
+		Severity: SeverityHigh,
+		Details:  map[string]interface{}{"ip": "203.0.113.7"},
+		Parameters: map[string]Param{
+			"duration": {Type: "select", Value: "1h", Options: []string{"1h", "24h"}},
+		},
+		Actions: []ProposalAction{{Type: ActionAccept, Label: "封禁"}},
+		Source:  "soar",
+	}
+	id, err := svc.IngestProposal(in, Actor{Via: ViaAPI})
+	if err != nil {
+		t.Fatalf("IngestProposal: %v", err)
+	}
+	if id != "soar-1001" {
+		t.Errorf("id = %q", id)
+	}
+	p, ok := svc.GetProposal(id)
+	if !ok {
+		t.Fatal("proposal not created")
+	}
+	if p.Status != ProposalStatusPending || p.CreatedBy == nil || p.CreatedBy.Name != "soar" {
+		t.Errorf("status = %q, created by = %+v", p.Status, p.CreatedBy)
+	}
+	if param := p.Parameters["duration"]; param.Key != "duration" || param.Label != "duration" {
+		t.Errorf("param = %+v", param)
+	}
+
+	if _, err := svc.IngestProposal(in, Actor{Via: ViaAPI}); !errors.Is(err, ErrProposalExists) {
+		t.Errorf("duplicate: err = %v", err)
+	}
+
+	in.ID = ""
+	if id, err := svc.IngestProposal(in, Actor{Via: ViaAPI}); err != nil || id == "" {
+		t.Errorf("generated id: id = %q, err = %v", id, err)
+	}
+}
+
+func TestService_IngestProposal_Invalid(t *testing.T) {
+	svc := &Service{config: &config.SecOpsConfig{}, proposalService: NewProposalService(), runs: newRunStore()}
+
+	valid := func() ExternalProposal {
+		return ExternalProposal{Type: "risk", Title: "撞库攻击", Summary: "同一 IP 尝试 300 个账号"}
+	}
+	cases := map[string]func(*ExternalProposal){
+		"type":       func(in *ExternalProposal) { in.Type = "unknown" },
+		"title":      func(in *ExternalProposal) { in.Title = " " },
+		"severity":   func(in *ExternalProposal) { in.Severity = "urgent" },
+		"confidence": func(in *ExternalProposal) { in.Confidence = 120 },
+		"id":         func(in *ExternalProposal) { in.ID = "a/b" },
+		"id dots":    func(in *ExternalProposal) { in.ID = "..x" },
+		"param type": func(in *ExternalProposal) { in.Parameters = map[string]Param{"x": {Type: "bool"}} },
+		"param number": func(in *ExternalProposal) {
+			in.Parameters = map[string]Param{"x": {Type: "number", Value: "abc"}}
+		},
+		"param option": func(in *ExternalProposal) {
+			in.Parameters = map[string]Param{"x": {Type: "select", Value: "7d", Options: []string{"1h"}}}
+		},
+		"action type":  func(in *ExternalProposal) { in.Actions = []ProposalAction{{Type: "delete", Label: "删除"}} },
+		"action label": func(in *ExternalProposal) { in.Actions = []ProposalAction{{Type: ActionAccept}} },
+		"technique":    func(in *ExternalProposal) { in.Techniques = []string{"not-a-technique"} },
+	}
+	for name, mutate := range cases {
+		in := valid()
+		mutate(&in)
+		if _, err := svc.IngestProposal(in, Actor{Via: ViaAPI}); !errors.Is(err, ErrInvalidProposal) {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+	if n := len(svc.proposalService.GetAll()); n != 0 {
+		t.Errorf("proposals = %d, want 0", n)
+	}
+}
@@ -0,0 +1,134 @@
+package secops
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// defaultLanguage 未配置工作语言时的默认值, 与内置 prompt 一致
+const defaultLanguage = "zh"
+
+// languageNames 工作语言的显示名称, 写入 prompt 帮助模型理解语言代码
+var languageNames = map[string]string{
+	"zh": "中文",
+	"en": "English",
+	"ja": "日本語",
+	"ko": "한국어",
+	"fr": "Français",
+	"de": "Deutsch",
+	"es": "Español",
+	"pt": "Português",
+	"ru": "Русский",
+}
+
+// latinLanguages 使用拉丁字母的语言, 按文字校验时共用一条规则
+var latinLanguages = map[string]bool{"en": true, "fr": true, "de": true, "es": true, "pt": true}
+
+// minLanguageLetters 文本中字母少于该数量时不校验语言, 如只有 IP 或编号的标题
+const minLanguageLetters = 4
+
+// maxForeignScriptRatio 拉丁或西里尔字母语言的文本中允许的中日韩字符比例, 容纳引用的原始数据
+const maxForeignScriptRatio = 0.1
+
+// languageBase 语言代码的主语言部分, 如 zh-CN -> zh
+func languageBase(lang string) string {
+	base, _, _ := strings.Cut(strings.ToLower(lang), "-")
+	return base
+}
+
+// languageName 语言的显示名称, 未知语言返回代码本身
+func languageName(lang string) string {
+	if name, ok := languageNames[languageBase(lang)]; ok {
+		return name
+	}
+	return lang
+}
+
+// validateLanguages 校验工作语言和各活动的覆盖
+func validateLanguages(cfg *config.SecOpsConfig) error {
+	if cfg.Language != "" && !ValidLanguage(cfg.Language) {
+		return fmt.Errorf("invalid language: %q", cfg.Language)
+	}
+	for name, act := range cfg.Activities {
+		if act.Language != "" && !ValidLanguage(act.Language) {
+			return fmt.Errorf("activities.%s: invalid language: %q", name, act.Language)
+		}
+	}
+	return nil
+}
+
+// outputLanguage 活动产出内容的工作语言: 活动覆盖 > secops.language > 中文; activity 为空时不看覆盖
+func (s *Service) outputLanguage(activity string) string {
+	if activity != "" {
+		if actCfg, ok := s.activityConfig(activity); ok && actCfg.Language != "" {
+			return actCfg.Language
+		}
+	}
+	if s.config.Language != "" {
+		return s.config.Language
+	}
+	return defaultLanguage
+}
+
+// proposalLanguage 提案应使用的工作语言, 由活动创建的提案按该活动的覆盖
+func (s *Service) proposalLanguage(p *Proposal) string {
+	if p.CreatedBy != nil && p.CreatedBy.Via == ViaActivity {
+		return s.outputLanguage(p.CreatedBy.Name)
+	}
+	return s.outputLanguage("")
+}
+
+// languageInstruction 追加到 prompt 的工作语言要求; 内置 prompt 为中文, 工作语言为中文时不追加
+func languageInstruction(lang string) string {
+	if languageBase(lang) == defaultLanguage {
+		return ""
+	}
+	return fmt.Sprintf("\n\n工作语言: %s (%s)。提案的标题、摘要、证据说明和最终回复必须使用该语言撰写, "+
+		"工具名、sql_id、参数名、字段名以及 IP、URL、报文等原始数据保持原样。\n"+
+		"Working language: %s. Write all proposal titles, summaries and replies in this language.",
+		languageName(lang), lang, languageName(lang))
+}
+
+// matchesLanguage 粗略判断文本是否使用了指定语言: 按文字 (汉字、假名、谚文、拉丁、西里尔字母) 统计;
+// 字母过少或无法按文字判断的语言视为匹配
+func matchesLanguage(text, lang string) bool {
+	var han, kana, hangul, latin, cyrillic int
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		}
+	}
+	cjk := han + kana + hangul
+	total := cjk + latin + cyrillic
+	if total < minLanguageLetters {
+		return true
+	}
+
+	base := languageBase(lang)
+	switch {
+	case base == "zh":
+		// 技术文本中常夹杂英文术语, 有汉字且没有假名、谚文即可
+		return han > 0 && kana == 0 && hangul == 0
+	case base == "ja":
+		return kana > 0
+	case base == "ko":
+		return hangul > 0
+	case base == "ru":
+		return cyrillic > 0 && float64(cjk) <= float64(total)*maxForeignScriptRatio
+	case latinLanguages[base]:
+		return cyrillic == 0 && float64(cjk) <= float64(total)*maxForeignScriptRatio
+	}
+	return true
+}
@@ -0,0 +1,75 @@
+package secops
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestMatchesLanguage(t *testing.T) {
+	tests := []struct {
+		text, lang string
+		want       bool
+	}{
+		{"SQL 注入 /product", "zh", true},
+		{"SQL injection on /product", "zh", false},
+		{"SQL injection on /product", "en", true},
+		{"Credential stuffing from 203.0.113.7, payload: 用户名", "en-US", true},
+		{"撞库攻击: 同一 IP 尝试 300 个账号", "en", false},
+		{"クレデンシャルスタッフィング攻撃", "ja", true},
+		{"撞库攻击", "ja", false},
+		{"크리덴셜 스터핑", "ko", true},
+		{"Подбор паролей", "ru", true},
+		{"203.0.113.7", "en", true}, // 字母过少不校验
+		{"任意内容", "ar", true},        // 无法按文字判断的语言不校验
+	}
+	for _, tt := range tests {
+		if got := matchesLanguage(tt.text, tt.lang); got != tt.want {
+			t.Errorf("matchesLanguage(%q, %s) = %v, want %v", tt.text, tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestOutputLanguage(t *testing.T) {
+	cfg := &config.SecOpsConfig{Language: "en", Activities: map[string]config.ActivityConfig{
+		"risk_analysis": {Enabled: true},
+		"host_analysis": {Enabled: true, Language: "ja"},
+	}}
+	svc := &Service{config: cfg, proposalService: NewProposalService(), runs: newRunStore()}
+
+	if got := svc.outputLanguage("risk_analysis"); got != "en" {
+		t.Errorf("risk_analysis = %s, want en", got)
+	}
+	if got := svc.outputLanguage("host_analysis"); got != "ja" {
+		t.Errorf("host_analysis = %s, want ja", got)
+	}
+	if got := (&Service{config: &config.SecOpsConfig{}}).outputLanguage(""); got != defaultLanguage {
+		t.Errorf("default = %s", got)
+	}
+	if languageInstruction("zh-CN") != "" || !strings.Contains(languageInstruction("en"), "English") {
+		t.Errorf("instruction = %q", languageInstruction("en"))
+	}
+
+	// 标题和摘要与工作语言不符时拒绝, 提示 Agent 改写
+	tool := NewProposalTool(svc)
+	tool.SetContext("secops", "host_analysis")
+	args := map[string]interface{}{"type": "host", "title": "WebShell 落地", "summary": "web-01 上发现可疑 PHP 文件"}
+	if res := tool.Execute(context.Background(), args); !res.IsError || !strings.Contains(res.ForLLM, "日本語") {
+		t.Errorf("mismatched language = %q", res.ForLLM)
+	}
+	args["title"], args["summary"] = "WebShell の設置", "web-01 で不審な PHP ファイルを検出しました"
+	if res := tool.Execute(withActivity(context.Background(), "host_analysis"), args); res.IsError {
+		t.Errorf("matching language rejected: %s", res.ForLLM)
+	}
+
+	for _, bad := range []*config.SecOpsConfig{
+		{Language: "english!"},
+		{Activities: map[string]config.ActivityConfig{"risk_analysis": {Language: "x"}}},
+	} {
+		if err := validateLanguages(bad); err == nil {
+			t.Errorf("%+v: expected error", bad)
+		}
+	}
+}
@@ -0,0 +1,373 @@
+package secops
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/kvstore"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// memoryLimit 内存存储的容量上限, 0 表示不限制
+type memoryLimit struct {
+	maxItems int
+	maxBytes int64
+}
+
+func newMemoryLimit(cfg config.MemoryLimitConfig) memoryLimit {
+	return memoryLimit{maxItems: cfg.MaxItems, maxBytes: int64(cfg.MaxMB) << 20}
+}
+
+// set 是否配置了任一上限
+func (l memoryLimit) set() bool {
+	return l.maxItems > 0 || l.maxBytes > 0
+}
+
+// exceeded 当前用量是否超出上限
+func (l memoryLimit) exceeded(items int, bytes int64) bool {
+	return (l.maxItems > 0 && items > l.maxItems) || (l.maxBytes > 0 && bytes > l.maxBytes)
+}
+
+// StoreUsage 内存存储的用量, 通过 /api/stats 和 Prometheus 指标暴露
+type StoreUsage struct {
+	Name      string `json:"name"`
+	Items     int    `json:"items"`
+	Bytes     int64  `json:"bytes"` // 按 JSON 序列化后的大小估算
+	MaxItems  int    `json:"maxItems,omitempty"`
+	MaxBytes  int64  `json:"maxBytes,omitempty"`
+	Evicted   uint64 `json:"evicted"`             // 启动以来因超出上限而淘汰的条数
+	Offloaded int    `json:"offloaded,omitempty"` // 已换出到磁盘、访问时再加载的条数
+}
+
+// MemoryUsage 各内存存储的当前用量
+func (s *Service) MemoryUsage() []StoreUsage {
+	usage := []StoreUsage{
+		s.proposalService.usage(),
+		s.runs.usage(),
+	}
+	if u, ok := kvstore.MemoryUsage(s.cache); ok {
+		usage = append(usage, StoreUsage{
+			Name:     "cache",
+			Items:    u.Items,
+			Bytes:    u.Bytes,
+			MaxItems: u.MaxItems,
+			MaxBytes: u.MaxBytes,
+			Evicted:  u.Evicted,
+		})
+	}
+	return usage
+}
+
+// jsonSize 序列化后的大小, 用于估算内存占用
+func jsonSize(v interface{}) int64 {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return int64(len(data))
+}
+
+// SetMemoryLimit 设置内存中的提案上限; 启用持久化后已决策的提案换出到磁盘, 否则直接丢弃
+func (s *ProposalService) SetMemoryLimit(cfg config.MemoryLimitConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limit = newMemoryLimit(cfg)
+	s.changed()
+}
+
+// touch 将提案标记为最近访问, 读锁下也可调用
+func (s *ProposalService) touch(id string) {
+	s.lruMu.Lock()
+	defer s.lruMu.Unlock()
+	if e, ok := s.elems[id]; ok {
+		s.recent.MoveToFront(e)
+		return
+	}
+	s.elems[id] = s.recent.PushFront(id)
+}
+
+// forget 从访问顺序中移除提案
+func (s *ProposalService) forget(id string) {
+	s.lruMu.Lock()
+	defer s.lruMu.Unlock()
+	if e, ok := s.elems[id]; ok {
+		s.recent.Remove(e)
+		delete(s.elems, id)
+	}
+}
+
+// offloadable 待处理、排队执行、执行中和等待重试的提案不换出
+func (s *ProposalService) offloadable(p *Proposal) bool {
+	switch p.Status {
+	case ProposalStatusPending, ProposalStatusExecutionFailed:
+		return false
+	}
+	if p.Execution != nil && p.Execution.Status == ExecutionQueued {
+		return false
+	}
+	// 文件名与 ID 不一致时无法按 ID 找回, 不合法的 ID 留在内存中
+	if validateExternalID(p.ID) != nil {
+		return false
+	}
+	return !s.executing[p.ID]
+}
+
+// offloadDir 换出提案的目录, 与持久化文件同名 (去掉扩展名)
+func (s *ProposalService) offloadDir() string {
+	return strings.TrimSuffix(s.path, filepath.Ext(s.path))
+}
+
+// offloadPath 换出文件路径; 只取 ID 的最后一段, 避免 ID 中的路径分隔符写到换出目录之外
+func (s *ProposalService) offloadPath(id string) string {
+	return filepath.Join(s.offloadDir(), filepath.Base(filepath.Clean("/"+id))+".json")
+}
+
+// loadOffloaded 扫描已换出的提案; 持久化文件中已有的视为残留并删除
+func (s *ProposalService) loadOffloaded() {
+	entries, err := os.ReadDir(s.offloadDir())
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || e.IsDir() {
+			continue
+		}
+		if _, ok := s.proposals[id]; ok {
+			os.Remove(s.offloadPath(id))
+			continue
+		}
+		p, err := s.readOffloaded(id)
+		if err != nil {
+			logger.WarnCF("secops", "Skipping unreadable offloaded proposal",
+				map[string]interface{}{
+					"id":    id,
+					"error": err.Error(),
+				})
+			continue
+		}
+		s.offloaded[id] = offloadStub(p)
+	}
+}
+
+// offloadStub 已换出提案留在内存中的索引项, 只保留筛选、排序和分组用到的字段
+func offloadStub(p *Proposal) *Proposal {
+	return &Proposal{
+		ID:         p.ID,
+		Type:       p.Type,
+		Title:      p.Title,
+		Details:    p.Details,
+		Status:     p.Status,
+		Severity:   p.Severity,
+		Confidence: p.Confidence,
+		Techniques: p.Techniques,
+		Items:      p.Items,
+		Assignee:   p.Assignee,
+		CreatedAt:  p.CreatedAt,
+		UpdatedAt:  p.UpdatedAt,
+	}
+}
+
+// eachIndexedLocked 依次处理内存中的提案和已换出提案的索引项; 调用方需持有锁
+func (s *ProposalService) eachIndexedLocked(fn func(p *Proposal)) {
+	for _, p := range s.proposals {
+		fn(p)
+	}
+	for _, p := range s.offloaded {
+		fn(p)
+	}
+}
+
+// resolveLocked 索引项替换为从磁盘读取的完整提案, 不加载回内存; 调用方需持有锁
+func (s *ProposalService) resolveLocked(p *Proposal) (*Proposal, bool) {
+	if s.offloaded[p.ID] != p {
+		return p, true
+	}
+	full, err := s.readOffloaded(p.ID)
+	if err != nil {
+		logger.WarnCF("secops", "Failed to read offloaded proposal",
+			map[string]interface{}{
+				"id":    p.ID,
+				"error": err.Error(),
+			})
+		return nil, false
+	}
+	return full, true
+}
+
+// resizeLocked 重新估算提案的占用, 提案已不在内存中时移除; 调用方需持有写锁
+func (s *ProposalService) resizeLocked(id string) {
+	var size int64
+	if p, ok := s.proposals[id]; ok {
+		size = jsonSize(p)
+	}
+	s.bytes += size - s.sizes[id]
+	if size == 0 {
+		delete(s.sizes, id)
+	} else {
+		s.sizes[id] = size
+	}
+}
+
+// enforceLimitLocked 超出上限时从最久未访问的提案开始换出, 最近访问的提案始终保留; 调用方需持有写锁
+func (s *ProposalService) enforceLimitLocked() {
+	if !s.limit.set() {
+		return
+	}
+	s.lruMu.Lock()
+	defer s.lruMu.Unlock()
+	for e := s.recent.Back(); e != nil && e != s.recent.Front() && s.limit.exceeded(len(s.proposals), s.bytes); {
+		prev := e.Prev()
+		id := e.Value.(string)
+		p, ok := s.proposals[id]
+		if !ok {
+			// 访问与删除并发时残留的元素
+			s.recent.Remove(e)
+			delete(s.elems, id)
+		} else if s.offloadable(p) {
+			if s.path != "" {
+				err := os.MkdirAll(s.offloadDir(), 0700)
+				if err == nil {
+					err = saveJSONAtomic(s.offloadPath(id), p)
+				}
+				if err != nil {
+					logger.ErrorCF("secops", "Failed to offload proposal",
+						map[string]interface{}{
+							"id":    id,
+							"error": err.Error(),
+						})
+					return
+				}
+				s.offloaded[id] = offloadStub(p)
+				delete(s.promoted, id)
+			}
+			delete(s.proposals, id)
+			s.recent.Remove(e)
+			delete(s.elems, id)
+			s.resizeLocked(id)
+			s.evicted++
+		}
+		e = prev
+	}
+}
+
+// promote 将已换出的提案加载回内存, 返回副本
+func (s *ProposalService) promote(id string) (*Proposal, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.lookupLocked(id)
+	if !ok {
+		return nil, false
+	}
+	return p.snapshot(), true
+}
+
+// lookupLocked 获取提案, 已换出的提案加载回内存; 调用方需持有写锁
+func (s *ProposalService) lookupLocked(id string) (*Proposal, bool) {
+	if p, ok := s.proposals[id]; ok {
+		s.touch(id)
+		return p, true
+	}
+	if s.offloaded[id] == nil {
+		return nil, false
+	}
+	p, err := s.readOffloaded(id)
+	if err != nil {
+		logger.ErrorCF("secops", "Failed to load offloaded proposal",
+			map[string]interface{}{
+				"id":    id,
+				"error": err.Error(),
+			})
+		return nil, false
+	}
+	s.proposals[id] = p
+	delete(s.offloaded, id)
+	s.touch(id)
+	s.promoted[id] = true
+	s.changed(id)
+	return p, true
+}
+
+func (s *ProposalService) readOffloaded(id string) (*Proposal, error) {
+	data, err := os.ReadFile(s.offloadPath(id))
+	if err != nil {
+		return nil, err
+	}
+	var p Proposal
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// offloadedRecords 已换出的提案, 参与归档
+func (s *ProposalService) offloadedRecords() []retentionRecord {
+	ids := make([]string, 0, len(s.offloaded))
+	for id := range s.offloaded {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	records := make([]retentionRecord, 0, len(ids))
+	for _, id := range ids {
+		data, err := os.ReadFile(s.offloadPath(id))
+		if err != nil {
+			continue
+		}
+		var p Proposal
+		if err := json.Unmarshal(data, &p); err != nil {
+			continue
+		}
+		records = append(records, retentionRecord{ID: id, Time: p.UpdatedAt, Data: data})
+	}
+	return records
+}
+
+// usage 内存中的提案用量
+func (s *ProposalService) usage() StoreUsage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u := StoreUsage{
+		Name:      "proposals",
+		Items:     len(s.proposals),
+		MaxItems:  s.limit.maxItems,
+		MaxBytes:  s.limit.maxBytes,
+		Evicted:   s.evicted,
+		Offloaded: len(s.offloaded),
+		Bytes:     s.bytes,
+	}
+	return u
+}
+
+// usage 内存中的执行记录用量
+func (rs *runStore) usage() StoreUsage {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	u := StoreUsage{
+		Name:     "runs",
+		Items:    len(rs.runs),
+		MaxItems: rs.limit.maxItems,
+		MaxBytes: rs.limit.maxBytes,
+		Evicted:  rs.evicted,
+	}
+	for _, r := range rs.runs {
+		u.Bytes += jsonSize(r)
+	}
+	return u
+}
+
+// recentOrder 按持久化数据中的更新时间建立初始访问顺序
+func recentOrder(proposals map[string]*Proposal) []string {
+	ids := make([]string, 0, len(proposals))
+	for id := range proposals {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return proposals[ids[i]].UpdatedAt.Before(proposals[ids[j]].UpdatedAt)
+	})
+	return ids
+}
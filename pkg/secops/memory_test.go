@@ -0,0 +1,189 @@
+package secops
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestProposalMemoryLimitOffloads(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "proposals.json")
+	ps := NewProposalService()
+	ps.SetMemoryLimit(config.MemoryLimitConfig{MaxItems: 2})
+	if err := ps.EnablePersistence(path); err != nil {
+		t.Fatal(err)
+	}
+
+	pending := ps.Create(NewProposal("risk", "待处理", "", nil))
+	old := ps.Create(NewProposal("risk", "已忽略", "", nil))
+	if err := ps.Ignore(old, DecisionRequest{Reason: "误报"}); err != nil {
+		t.Fatal(err)
+	}
+	ps.Get(pending)
+	ps.Create(NewProposal("risk", "新提案", "", nil))
+
+	// 已决策的提案被换出, 待处理提案即使最久未访问也保留
+	if u := ps.usage(); u.Items != 2 || u.Offloaded != 1 || u.Evicted != 1 {
+		t.Fatalf("usage = %+v", u)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "proposals", old+".json")); err != nil {
+		t.Fatalf("offloaded proposal not on disk: %v", err)
+	}
+	// 列表、筛选和导出仍包含已换出的提案, 但不加载回内存
+	if len(ps.GetAll()) != 3 {
+		t.Errorf("GetAll() = %d proposals, want 3 including the offloaded one", len(ps.GetAll()))
+	}
+	page, total, err := ps.GetFiltered(ProposalFilter{Statuses: []ProposalStatus{ProposalStatusIgnored}})
+	if err != nil || total != 1 || len(page) != 1 || page[0].ID != old || page[0].Decision == nil {
+		t.Errorf("GetFiltered(ignored) = %+v, %d, %v", page, total, err)
+	}
+	if n := len(ps.exportable(ProposalFilter{})); n != 3 {
+		t.Errorf("exportable() = %d proposals, want 3", n)
+	}
+	if u := ps.usage(); u.Offloaded != 1 {
+		t.Errorf("listing must not reload offloaded proposals: usage = %+v", u)
+	}
+	if len(ps.retentionRecords()) != 1 {
+		t.Error("offloaded proposals must still be archived by retention")
+	}
+
+	// 访问时加载回内存; 其余提案都是待处理的, 不可换出, 暂时超出上限
+	p, ok := ps.Get(old)
+	if !ok || p.Title != "已忽略" || p.Status != ProposalStatusIgnored {
+		t.Fatalf("Get(offloaded) = %+v, %v", p, ok)
+	}
+	if u := ps.usage(); u.Items != 3 || u.Offloaded != 0 {
+		t.Errorf("after reload: usage = %+v", u)
+	}
+	// 主文件写入前保留换出文件, 避免中途崩溃丢失
+	offloadFile := filepath.Join(dir, "proposals", old+".json")
+	if _, err := os.Stat(offloadFile); err != nil {
+		t.Errorf("offload file removed before the store was saved: %v", err)
+	}
+	if err := ps.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(offloadFile); !os.IsNotExist(err) {
+		t.Errorf("offload file kept after save: %v", err)
+	}
+
+	// 重启后换出的提案仍可按 ID 访问
+	ps.Create(NewProposal("risk", "再一条", "", nil))
+	if err := ps.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	restarted := NewProposalService()
+	restarted.SetMemoryLimit(config.MemoryLimitConfig{MaxItems: 2})
+	if err := restarted.EnablePersistence(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := restarted.Get(old); !ok {
+		t.Error("offloaded proposal lost after restart")
+	}
+	if !restarted.Delete(pending) || restarted.exists(pending) {
+		t.Error("Delete(pending) failed")
+	}
+}
+
+func TestDeleteOffloadedBumpsVersion(t *testing.T) {
+	ps := NewProposalService()
+	ps.SetMemoryLimit(config.MemoryLimitConfig{MaxItems: 1})
+	if err := ps.EnablePersistence(filepath.Join(t.TempDir(), "proposals.json")); err != nil {
+		t.Fatal(err)
+	}
+	old := ps.Create(NewProposal("risk", "a", "", nil))
+	if err := ps.Ignore(old, DecisionRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	ps.Create(NewProposal("risk", "b", "", nil))
+	if ps.offloaded[old] == nil {
+		t.Fatal("proposal was not offloaded")
+	}
+
+	before := ps.Version()
+	if !ps.Delete(old) || ps.Version() == before {
+		t.Error("Delete(offloaded) did not bump the version")
+	}
+}
+
+func TestProposalBytesTrackedIncrementally(t *testing.T) {
+	ps := NewProposalService()
+	id := ps.Create(NewProposal("risk", "a", "", nil))
+	ps.Create(NewProposal("risk", "b", "", nil))
+
+	total := func() int64 {
+		var n int64
+		for _, p := range ps.proposals {
+			n += jsonSize(p)
+		}
+		return n
+	}
+	if u := ps.usage(); u.Bytes != total() {
+		t.Errorf("bytes = %d, want %d", u.Bytes, total())
+	}
+	if _, err := ps.AddComment(id, strings.Repeat("x", 500), Actor{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if u := ps.usage(); u.Bytes != total() {
+		t.Errorf("after comment: bytes = %d, want %d", u.Bytes, total())
+	}
+	ps.Delete(id)
+	if u := ps.usage(); u.Bytes != total() {
+		t.Errorf("after delete: bytes = %d, want %d", u.Bytes, total())
+	}
+}
+
+func TestOffloadPathStaysInDir(t *testing.T) {
+	ps := NewProposalService()
+	ps.path = filepath.Join("data", "proposals.json")
+	for _, id := range []string{"../../x", "a/../../b", "/etc/passwd"} {
+		if got := ps.offloadPath(id); filepath.Dir(got) != filepath.Join("data", "proposals") {
+			t.Errorf("offloadPath(%q) = %s", id, got)
+		}
+	}
+	if ps.offloadable(&Proposal{ID: "../x", Status: ProposalStatusAccepted}) {
+		t.Error("proposals with unsafe ids must stay in memory")
+	}
+}
+
+func TestProposalMemoryLimitWithoutPersistence(t *testing.T) {
+	ps := NewProposalService()
+	ps.SetMemoryLimit(config.MemoryLimitConfig{MaxItems: 1})
+	id := ps.Create(NewProposal("risk", "a", "", nil))
+	ps.Ignore(id, DecisionRequest{})
+	ps.Create(NewProposal("risk", "b", "", nil))
+	if _, ok := ps.Get(id); ok {
+		t.Error("without persistence evicted proposals are dropped")
+	}
+}
+
+func TestRunStoreLimit(t *testing.T) {
+	rs := newRunStore()
+	rs.setLimit(config.MemoryLimitConfig{MaxItems: 2})
+	first := rs.start("a")
+	rs.finish(first, "", nil)
+	time.Sleep(time.Millisecond)
+	running := rs.start("b")
+	second := rs.start("c")
+	rs.finish(second, "", nil)
+
+	if _, ok := rs.get(first.ID); ok {
+		t.Error("oldest finished run was not pruned")
+	}
+	if _, ok := rs.get(running.ID); !ok {
+		t.Error("running run must not be pruned")
+	}
+	if u := rs.usage(); u.Items != 2 || u.Evicted != 1 || u.Bytes == 0 {
+		t.Errorf("usage = %+v", u)
+	}
+
+	rs = newRunStore()
+	if rs.limit.maxItems != maxRunHistory {
+		t.Errorf("default run limit = %d", rs.limit.maxItems)
+	}
+}
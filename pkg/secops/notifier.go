@@ -0,0 +1,530 @@
+package secops
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// 通知目标类型
+const (
+	NotifyTargetChannel = "channel"
+	NotifyTargetWebhook = "webhook"
+	NotifyTargetFeishu  = "feishu" // 飞书/Lark 群机器人, 发送带确认/忽略按钮的消息卡片
+)
+
+// 通知紧急程度
+const (
+	UrgencyImmediate = "immediate"
+	UrgencyDigest    = "digest"
+)
+
+// 通知事件, 用于路由规则的 events
+const (
+	NotifyEventCreated  = "created"
+	NotifyEventAccepted = "accepted"
+	NotifyEventIgnored  = "ignored"
+)
+
+// defaultDigestSchedule 汇总通知的默认发送周期
+const defaultDigestSchedule = 24 * time.Hour
+
+// defaultWebhookRetries webhook 默认重试次数
+const defaultWebhookRetries = 3
+
+// Notifier 提案通知, 按 (提案类型 × 严重级别) 路由到通知目标
+type Notifier struct {
+	cfg            config.NotificationConfig
+	msgBus         *bus.MessageBus
+	client         *http.Client
+	digestInterval time.Duration
+	remindAfter    time.Duration // 为 0 时不提醒
+	retryBackoff   time.Duration // webhook 首次重试前的等待时间, 之后每次翻倍
+	faults         *chaos        // 故障注入, 为 nil 时不注入
+
+	mu       sync.Mutex
+	digest   map[string][]*Proposal // 按目标缓存待汇总的提案
+	reminded map[string]time.Time   // 提案最近一次提醒时间
+}
+
+// Route 路由结果
+type Route struct {
+	Targets []string
+	Urgency string
+}
+
+// NewNotifier 创建通知器并校验路由配置
+func NewNotifier(cfg config.NotificationConfig, msgBus *bus.MessageBus) (*Notifier, error) {
+	for name, t := range cfg.Targets {
+		switch t.Type {
+		case NotifyTargetChannel:
+			if t.Channel == "" || t.ChatID == "" {
+				return nil, fmt.Errorf("notify target %s: channel and chat_id are required", name)
+			}
+		case NotifyTargetWebhook, NotifyTargetFeishu:
+			if !strings.HasPrefix(t.URL, "http://") && !strings.HasPrefix(t.URL, "https://") {
+				return nil, fmt.Errorf("notify target %s: %s requires an http(s) url", name, t.Type)
+			}
+			if t.Retries < -1 {
+				return nil, fmt.Errorf("notify target %s: invalid retries %d", name, t.Retries)
+			}
+		default:
+			return nil, fmt.Errorf("notify target %s: unknown type %q", name, t.Type)
+		}
+	}
+
+	for i, r := range cfg.Routes {
+		if len(r.Targets) == 0 {
+			return nil, fmt.Errorf("notify route #%d: no targets", i+1)
+		}
+		for _, name := range r.Targets {
+			if _, ok := cfg.Targets[name]; !ok {
+				return nil, fmt.Errorf("notify route #%d: unknown target %q", i+1, name)
+			}
+		}
+		for _, typ := range r.Types {
+			if !validProposalTypes[typ] {
+				return nil, fmt.Errorf("notify route #%d: unknown proposal type %q", i+1, typ)
+			}
+		}
+		for _, sev := range r.Severities {
+			if !validSeverities[sev] {
+				return nil, fmt.Errorf("notify route #%d: unknown severity %q", i+1, sev)
+			}
+		}
+		if r.Urgency != "" && r.Urgency != UrgencyImmediate && r.Urgency != UrgencyDigest {
+			return nil, fmt.Errorf("notify route #%d: unknown urgency %q", i+1, r.Urgency)
+		}
+		for _, ev := range r.Events {
+			if ev != NotifyEventCreated && ev != NotifyEventAccepted && ev != NotifyEventIgnored {
+				return nil, fmt.Errorf("notify route #%d: unknown event %q", i+1, ev)
+			}
+		}
+	}
+
+	interval := defaultDigestSchedule
+	if cfg.DigestSchedule != "" {
+		d, err := time.ParseDuration(cfg.DigestSchedule)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid digest_schedule: %q", cfg.DigestSchedule)
+		}
+		interval = d
+	}
+
+	var remindAfter time.Duration
+	if cfg.RemindAfter != "" {
+		d, err := time.ParseDuration(cfg.RemindAfter)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid remind_after: %q", cfg.RemindAfter)
+		}
+		remindAfter = d
+	}
+
+	return &Notifier{
+		cfg:            cfg,
+		msgBus:         msgBus,
+		client:         &http.Client{Timeout: 10 * time.Second},
+		digestInterval: interval,
+		remindAfter:    remindAfter,
+		retryBackoff:   time.Second,
+		digest:         make(map[string][]*Proposal),
+		reminded:       make(map[string]time.Time),
+	}, nil
+}
+
+// severityOf 提案的严重级别, 未设置时视为 medium
+func severityOf(p *Proposal) string {
+	if p.Severity == "" {
+		return SeverityMedium
+	}
+	return p.Severity
+}
+
+// Route 返回新提案命中的第一条路由规则, 未命中时 ok 为 false
+func (n *Notifier) Route(p *Proposal) (Route, bool) {
+	return n.routeFor(p, NotifyEventCreated)
+}
+
+// routeFor 返回提案在指定事件下命中的第一条路由规则; 未配置 events 的规则只匹配 created
+func (n *Notifier) routeFor(p *Proposal, event string) (Route, bool) {
+	severity := severityOf(p)
+	for _, r := range n.cfg.Routes {
+		if len(r.Events) == 0 && event != NotifyEventCreated {
+			continue
+		}
+		if len(r.Events) > 0 && !containsString(r.Events, event) {
+			continue
+		}
+		if len(r.Types) > 0 && !containsString(r.Types, p.Type) {
+			continue
+		}
+		if len(r.Severities) > 0 && !containsString(r.Severities, severity) {
+			continue
+		}
+		urgency := r.Urgency
+		if urgency == "" {
+			urgency = UrgencyImmediate
+		}
+		return Route{Targets: r.Targets, Urgency: urgency}, true
+	}
+	return Route{}, false
+}
+
+// Notify 按路由发送新提案通知; digest 级别的提案缓存到下一次汇总
+func (n *Notifier) Notify(ctx context.Context, p *Proposal) {
+	route, ok := n.Route(p)
+	if !ok {
+		return
+	}
+
+	if route.Urgency == UrgencyDigest {
+		n.mu.Lock()
+		for _, target := range route.Targets {
+			n.digest[target] = append(n.digest[target], p)
+		}
+		n.mu.Unlock()
+		return
+	}
+
+	content := formatProposalNotification(p)
+	for _, target := range route.Targets {
+		n.send(ctx, target, content, map[string]interface{}{
+			"event":    "proposal.created",
+			"urgency":  route.Urgency,
+			"proposal": p,
+		})
+	}
+}
+
+// NotifyDecision 按路由发送提案决策通知; 决策通知不参与汇总, 总是立即发送
+func (n *Notifier) NotifyDecision(ctx context.Context, p *Proposal) {
+	var event, verb string
+	switch p.Status {
+	case ProposalStatusAccepted:
+		event, verb = NotifyEventAccepted, "接受"
+	case ProposalStatusIgnored:
+		event, verb = NotifyEventIgnored, "忽略"
+	default:
+		return
+	}
+	route, ok := n.routeFor(p, event)
+	if !ok {
+		return
+	}
+
+	content := fmt.Sprintf("[SecOps] 提案已%s: %s (%s)", verb, p.Title, p.ID)
+	if p.Decision != nil && p.Decision.Reason != "" {
+		content += ", 理由: " + p.Decision.Reason
+	}
+	if p.Execution != nil && p.Execution.Error != "" {
+		content += ", 执行失败: " + p.Execution.Error
+	}
+	for _, target := range route.Targets {
+		n.send(ctx, target, content, map[string]interface{}{
+			"event":    "proposal." + event,
+			"proposal": p,
+		})
+	}
+}
+
+// Remind 对超时未决策的待处理提案再次发送通知, 返回是否已提醒
+//
+// 仅 immediate 级别的路由会提醒; 同一提案在 remind_after 内最多提醒一次。
+func (n *Notifier) Remind(ctx context.Context, p *Proposal, now time.Time) bool {
+	if n.remindAfter == 0 {
+		return false
+	}
+	route, ok := n.Route(p)
+	if !ok || route.Urgency != UrgencyImmediate {
+		return false
+	}
+
+	n.mu.Lock()
+	last, ok := n.reminded[p.ID]
+	if !ok {
+		last = p.CreatedAt
+	}
+	if now.Sub(last) < n.remindAfter {
+		n.mu.Unlock()
+		return false
+	}
+	n.reminded[p.ID] = now
+	n.mu.Unlock()
+
+	content := "[提醒] " + formatProposalNotification(p)
+	for _, target := range route.Targets {
+		n.send(ctx, target, content, map[string]interface{}{
+			"event":    "proposal.reminder",
+			"urgency":  route.Urgency,
+			"proposal": p,
+		})
+	}
+	return true
+}
+
+// forget 清理已处理提案的提醒状态
+func (n *Notifier) forget(keep map[string]bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for id := range n.reminded {
+		if !keep[id] {
+			delete(n.reminded, id)
+		}
+	}
+}
+
+// FlushDigest 发送各目标缓存的汇总通知
+func (n *Notifier) FlushDigest(ctx context.Context) {
+	n.mu.Lock()
+	pending := n.digest
+	n.digest = make(map[string][]*Proposal)
+	n.mu.Unlock()
+
+	targets := make([]string, 0, len(pending))
+	for target := range pending {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	for _, target := range targets {
+		proposals := pending[target]
+		n.send(ctx, target, formatDigest(proposals), map[string]interface{}{
+			"event":     "proposal.digest",
+			"urgency":   UrgencyDigest,
+			"proposals": proposals,
+		})
+	}
+}
+
+// hasDigestRoutes 是否存在汇总级别的路由
+func (n *Notifier) hasDigestRoutes() bool {
+	for _, r := range n.cfg.Routes {
+		if r.Urgency == UrgencyDigest {
+			return true
+		}
+	}
+	return false
+}
+
+// runDigest 周期发送汇总通知
+func (n *Notifier) runDigest(ctx context.Context) {
+	ticker := time.NewTicker(n.digestInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n.FlushDigest(ctx)
+		}
+	}
+}
+
+// send 向单个目标发送通知; webhook/feishu 失败时按指数退避重试, 重试使用同一投递 ID, 最终失败只记录日志
+func (n *Notifier) send(ctx context.Context, name, content string, payload map[string]interface{}) {
+	target := n.cfg.Targets[name]
+	attempts := 1
+	if target.Type == NotifyTargetWebhook || target.Type == NotifyTargetFeishu {
+		switch {
+		case target.Retries > 0:
+			attempts += target.Retries
+		case target.Retries == 0:
+			attempts += defaultWebhookRetries
+		}
+	}
+
+	delivery := uuid.New().String()
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(n.retryBackoff << (i - 1)):
+			}
+		}
+		if err = n.deliver(ctx, name, delivery, content, payload); err == nil || !retryable(err) {
+			break
+		}
+	}
+	if err != nil {
+		logger.WarnCF("secops", "Notification failed",
+			map[string]interface{}{
+				"target":   name,
+				"delivery": delivery,
+				"attempts": attempts,
+				"error":    err.Error(),
+			})
+	}
+}
+
+// deliver 向单个目标发送一次通知, channel 目标发送文本, webhook 目标发送 JSON, feishu 目标发送消息卡片
+func (n *Notifier) deliver(ctx context.Context, name, delivery, content string, payload map[string]interface{}) error {
+	target := n.cfg.Targets[name]
+	if n.faults.dropNotification() {
+		logger.WarnCF("secops", "Chaos: dropped notification",
+			map[string]interface{}{
+				"target":   name,
+				"delivery": delivery,
+			})
+		return errChaosDropped
+	}
+
+	switch target.Type {
+	case NotifyTargetChannel:
+		if n.msgBus == nil {
+			return fmt.Errorf("message bus not available")
+		}
+		n.msgBus.PublishOutbound(bus.OutboundMessage{Channel: target.Channel, ChatID: target.ChatID, Content: content})
+	case NotifyTargetWebhook:
+		event, _ := payload["event"].(string)
+		return postWebhook(ctx, n.client, target, event, delivery, payload)
+	case NotifyTargetFeishu:
+		return postFeishu(ctx, n.client, target, feishuMessage(content, payload))
+	}
+	return nil
+}
+
+// 测试通知结果
+const (
+	NotifyTestSent   = "sent"   // webhook 返回 2xx, 飞书返回成功
+	NotifyTestQueued = "queued" // 已交给消息通道, 是否送达以通道为准
+	NotifyTestFailed = "failed"
+)
+
+// NotifyTargetInfo 通知目标概况; URL 只保留协议和主机, 不含请求头
+type NotifyTargetInfo struct {
+	Name    string                     `json:"name"`
+	Type    string                     `json:"type"`
+	Channel string                     `json:"channel,omitempty"`
+	ChatID  string                     `json:"chatId,omitempty"`
+	URL     string                     `json:"url,omitempty"`
+	Signed  bool                       `json:"signed,omitempty"` // webhook/feishu 是否配置了签名密钥
+	Routes  []config.NotifyRouteConfig `json:"routes"`           // 引用该目标的路由规则
+}
+
+// NotifyTestResult 测试通知结果
+type NotifyTestResult struct {
+	Target    string    `json:"target"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	LatencyMs int64     `json:"latencyMs"`
+	SentAt    time.Time `json:"sentAt"`
+}
+
+// Targets 返回所有通知目标及引用它们的路由, 按名称排序
+func (n *Notifier) Targets() []NotifyTargetInfo {
+	infos := make([]NotifyTargetInfo, 0, len(n.cfg.Targets))
+	for name, t := range n.cfg.Targets {
+		info := NotifyTargetInfo{
+			Name:    name,
+			Type:    t.Type,
+			Channel: t.Channel,
+			ChatID:  t.ChatID,
+			URL:     redactURL(t.URL),
+			Signed:  t.Secret != "",
+			Routes:  []config.NotifyRouteConfig{},
+		}
+		for _, r := range n.cfg.Routes {
+			if containsString(r.Targets, name) {
+				info.Routes = append(info.Routes, r)
+			}
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// Test 向指定目标发送一条测试通知, 用于验证凭据和地址; 目标不存在时返回错误
+func (n *Notifier) Test(ctx context.Context, name string) (*NotifyTestResult, error) {
+	target, ok := n.cfg.Targets[name]
+	if !ok {
+		return nil, fmt.Errorf("notify target not found: %s", name)
+	}
+
+	now := time.Now()
+	content := fmt.Sprintf("[SecOps] 测试通知: 目标 %s 配置正常 (%s)", name, now.Format(time.RFC3339))
+	err := n.deliver(ctx, name, uuid.New().String(), content, map[string]interface{}{
+		"event":   "notification.test",
+		"target":  name,
+		"message": content,
+		"sentAt":  now,
+	})
+
+	result := &NotifyTestResult{
+		Target:    name,
+		Status:    NotifyTestSent,
+		LatencyMs: time.Since(now).Milliseconds(),
+		SentAt:    now,
+	}
+	if target.Type == NotifyTargetChannel {
+		result.Status = NotifyTestQueued
+	}
+	if err != nil {
+		// url.Error 会带上完整地址, 只保留底层错误
+		if ue, ok := err.(*url.Error); ok {
+			err = ue.Err
+		}
+		result.Status = NotifyTestFailed
+		result.Error = err.Error()
+	}
+
+	logger.InfoCF("secops", "Test notification sent",
+		map[string]interface{}{
+			"target": name,
+			"status": result.Status,
+		})
+	return result, nil
+}
+
+// postJSON 以 JSON 推送数据, 非 2xx 响应视为失败
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, payload interface{}) error {
+	return postWebhook(ctx, client, config.NotifyTargetConfig{URL: url, Headers: headers}, "", "", payload)
+}
+
+// NotifyTargets 通知目标及引用它们的路由
+func (s *Service) NotifyTargets() []NotifyTargetInfo {
+	return s.notifier.Targets()
+}
+
+// TestNotification 向指定通知目标发送测试通知
+func (s *Service) TestNotification(ctx context.Context, name string) (*NotifyTestResult, error) {
+	return s.notifier.Test(ctx, name)
+}
+
+func formatProposalNotification(p *Proposal) string {
+	return fmt.Sprintf("[SecOps][%s] %s 提案: %s (%s)", strings.ToUpper(severityOf(p)), p.Type, p.Title, p.ID)
+}
+
+func formatDigest(proposals []*Proposal) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[SecOps] 提案汇总: %d 条\n", len(proposals))
+	for _, p := range proposals {
+		fmt.Fprintf(&sb, "- [%s] %s: %s (%s)\n", severityOf(p), p.Type, p.Title, p.ID)
+	}
+	return sb.String()
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,269 @@
+package secops
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestNotifierRoute(t *testing.T) {
+	n, err := NewNotifier(config.NotificationConfig{
+		Targets: map[string]config.NotifyTargetConfig{
+			"pager": {Type: NotifyTargetWebhook, URL: "http://pager.local"},
+			"slack": {Type: NotifyTargetChannel, Channel: "slack", ChatID: "C1"},
+		},
+		Routes: []config.NotifyRouteConfig{
+			{Types: []string{"risk"}, Severities: []string{SeverityCritical}, Targets: []string{"pager", "slack"}},
+			{Severities: []string{SeverityCritical, SeverityHigh}, Targets: []string{"slack"}},
+			{Types: []string{"weak"}, Targets: []string{"slack"}, Urgency: UrgencyDigest},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewNotifier: %v", err)
+	}
+
+	cases := []struct {
+		typ, severity string
+		targets       int
+		urgency       string
+	}{
+		{"risk", SeverityCritical, 2, UrgencyImmediate},
+		{"weak", SeverityCritical, 1, UrgencyImmediate},
+		{"weak", SeverityLow, 1, UrgencyDigest},
+		{"weak", "", 1, UrgencyDigest},
+		{"app", SeverityLow, 0, ""},
+	}
+	for _, c := range cases {
+		route, ok := n.Route(&Proposal{Type: c.typ, Severity: c.severity})
+		if ok != (c.targets > 0) || len(route.Targets) != c.targets || route.Urgency != c.urgency {
+			t.Errorf("%s/%s: route = %+v, ok = %v", c.typ, c.severity, route, ok)
+		}
+	}
+}
+
+func TestNewNotifier_InvalidRoute(t *testing.T) {
+	_, err := NewNotifier(config.NotificationConfig{
+		Routes: []config.NotifyRouteConfig{{Targets: []string{"missing"}}},
+	}, nil)
+	if err == nil {
+		t.Error("expected error for unknown target")
+	}
+
+	_, err = NewNotifier(config.NotificationConfig{
+		Targets: map[string]config.NotifyTargetConfig{"x": {Type: NotifyTargetWebhook, URL: "http://x"}},
+		Routes:  []config.NotifyRouteConfig{{Severities: []string{"urgent"}, Targets: []string{"x"}}},
+	}, nil)
+	if err == nil {
+		t.Error("expected error for unknown severity")
+	}
+}
+
+func TestNotifierDigest(t *testing.T) {
+	var mu sync.Mutex
+	var events []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		events = append(events, payload)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	n, err := NewNotifier(config.NotificationConfig{
+		Targets: map[string]config.NotifyTargetConfig{"hook": {Type: NotifyTargetWebhook, URL: srv.URL}},
+		Routes: []config.NotifyRouteConfig{
+			{Severities: []string{SeverityCritical}, Targets: []string{"hook"}},
+			{Targets: []string{"hook"}, Urgency: UrgencyDigest},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewNotifier: %v", err)
+	}
+
+	ctx := context.Background()
+	n.Notify(ctx, &Proposal{ID: "a", Type: "risk", Severity: SeverityCritical})
+	n.Notify(ctx, &Proposal{ID: "b", Type: "weak", Severity: SeverityLow})
+	n.Notify(ctx, &Proposal{ID: "c", Type: "weak"})
+
+	if len(events) != 1 || events[0]["event"] != "proposal.created" {
+		t.Fatalf("events before flush = %v, want one immediate notification", events)
+	}
+
+	n.FlushDigest(ctx)
+	if len(events) != 2 || events[1]["event"] != "proposal.digest" {
+		t.Fatalf("events after flush = %v", events)
+	}
+	if got := events[1]["proposals"].([]interface{}); len(got) != 2 {
+		t.Errorf("digest proposals = %d, want 2", len(got))
+	}
+
+	n.FlushDigest(ctx)
+	if len(events) != 2 {
+		t.Errorf("empty flush sent %d extra events", len(events)-2)
+	}
+}
+
+func TestNotifierTest(t *testing.T) {
+	var got map[string]interface{}
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer ok.Close()
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer bad.Close()
+
+	n, err := NewNotifier(config.NotificationConfig{
+		Targets: map[string]config.NotifyTargetConfig{
+			"pager":   {Type: NotifyTargetWebhook, URL: ok.URL + "/hook?token=secret"},
+			"expired": {Type: NotifyTargetWebhook, URL: bad.URL},
+			"slack":   {Type: NotifyTargetChannel, Channel: "slack", ChatID: "C1"},
+		},
+		Routes: []config.NotifyRouteConfig{
+			{Severities: []string{SeverityCritical}, Targets: []string{"pager", "slack"}},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewNotifier: %v", err)
+	}
+
+	if _, err := n.Test(context.Background(), "missing"); err == nil {
+		t.Error("expected error for unknown target")
+	}
+
+	res, err := n.Test(context.Background(), "pager")
+	if err != nil || res.Status != NotifyTestSent {
+		t.Errorf("pager = %+v, %v; want sent", res, err)
+	}
+	if got["event"] != "notification.test" || got["target"] != "pager" {
+		t.Errorf("webhook payload = %v", got)
+	}
+
+	if res, _ := n.Test(context.Background(), "expired"); res.Status != NotifyTestFailed || res.Error == "" {
+		t.Errorf("expired = %+v, want failed with error", res)
+	}
+	// 没有消息总线时 channel 目标无法投递
+	if res, _ := n.Test(context.Background(), "slack"); res.Status != NotifyTestFailed {
+		t.Errorf("slack = %+v, want failed without message bus", res)
+	}
+
+	targets := n.Targets()
+	if len(targets) != 3 || targets[0].Name != "expired" || len(targets[0].Routes) != 0 {
+		t.Fatalf("targets = %+v", targets)
+	}
+	if targets[1].URL != ok.URL || len(targets[1].Routes) != 1 {
+		t.Errorf("pager info = %+v, want redacted url and one route", targets[1])
+	}
+}
+
+func TestNotifierWebhookRetryAndSignature(t *testing.T) {
+	var mu sync.Mutex
+	var calls int
+	var deliveries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		deliveries = append(deliveries, r.Header.Get("X-Picoclaw-Delivery"))
+
+		want := "sha256=" + signWebhook("s3cret", r.Header.Get("X-Picoclaw-Timestamp"), body)
+		if r.Header.Get("X-Picoclaw-Signature") != want || r.Header.Get("X-Picoclaw-Event") != "proposal.accepted" {
+			t.Errorf("headers = %v", r.Header)
+		}
+		if calls < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+	rejected := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer rejected.Close()
+
+	n, err := NewNotifier(config.NotificationConfig{
+		Targets: map[string]config.NotifyTargetConfig{
+			"hook":     {Type: NotifyTargetWebhook, URL: srv.URL, Secret: "s3cret"},
+			"rejected": {Type: NotifyTargetWebhook, URL: rejected.URL},
+		},
+		Routes: []config.NotifyRouteConfig{
+			{Types: []string{"risk"}, Targets: []string{"hook"}, Events: []string{NotifyEventAccepted}},
+			{Types: []string{"weak"}, Targets: []string{"rejected"}, Events: []string{NotifyEventAccepted}},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewNotifier: %v", err)
+	}
+	n.retryBackoff = time.Millisecond
+
+	n.NotifyDecision(context.Background(), &Proposal{ID: "a", Type: "risk", Status: ProposalStatusAccepted})
+	if calls != 3 {
+		t.Fatalf("calls = %d, want 2 retries after 502", calls)
+	}
+	if deliveries[0] == "" || deliveries[0] != deliveries[2] {
+		t.Errorf("retries should reuse the delivery id: %v", deliveries)
+	}
+
+	// 4xx 不重试
+	n.NotifyDecision(context.Background(), &Proposal{ID: "b", Type: "weak", Status: ProposalStatusAccepted})
+	if calls != 4 {
+		t.Errorf("calls = %d, want no retry after 400", calls)
+	}
+}
+
+func TestNotifierDecisionEvents(t *testing.T) {
+	var mu sync.Mutex
+	var events []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		events = append(events, r.Header.Get("X-Picoclaw-Event"))
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	n, err := NewNotifier(config.NotificationConfig{
+		Targets: map[string]config.NotifyTargetConfig{"hook": {Type: NotifyTargetWebhook, URL: srv.URL}},
+		Routes: []config.NotifyRouteConfig{
+			{Targets: []string{"hook"}},
+			{Targets: []string{"hook"}, Events: []string{NotifyEventIgnored}},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewNotifier: %v", err)
+	}
+
+	ps := NewProposalService()
+	ps.SetDecisionHandler(func(p *Proposal) { n.NotifyDecision(context.Background(), p) })
+	a := ps.Create(&Proposal{Type: "risk", Status: ProposalStatusPending})
+	b := ps.Create(&Proposal{Type: "risk", Status: ProposalStatusPending})
+	if err := ps.Accept(a, DecisionRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ps.Ignore(b, DecisionRequest{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 未配置 events 的规则只匹配 created, accepted 没有命中任何规则
+	if len(events) != 1 || events[0] != "proposal.ignored" {
+		t.Errorf("events = %v, want only proposal.ignored", events)
+	}
+
+	if _, err := NewNotifier(config.NotificationConfig{
+		Targets: map[string]config.NotifyTargetConfig{"hook": {Type: NotifyTargetWebhook, URL: srv.URL}},
+		Routes:  []config.NotifyRouteConfig{{Targets: []string{"hook"}, Events: []string{"deleted"}}},
+	}, nil); err == nil {
+		t.Error("expected error for unknown event")
+	}
+}
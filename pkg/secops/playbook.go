@@ -0,0 +1,619 @@
+package secops
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/tools"
+	"gopkg.in/yaml.v3"
+)
+
+// 剧本步骤类型
+const (
+	StepQuery    = "query"
+	StepTool     = "tool"
+	StepAnalyze  = "analyze"
+	StepBranch   = "branch"
+	StepProposal = "proposal"
+)
+
+// StepStatusSkipped 被分支跳过的步骤
+const StepStatusSkipped = "skipped"
+
+// StepStatusResumed 接续被中断的执行时沿用上次输出、未重复执行的步骤
+const StepStatusResumed = "resumed"
+
+// playbookEnd 分支跳转到剧本结束
+const playbookEnd = "end"
+
+// maxStepOutputChars 执行记录中每个步骤输出保留的最大字符数
+const maxStepOutputChars = 4000
+
+// playbookTools 剧本 tool 步骤可调用的工具
+var playbookTools = map[string]bool{"query_data": true, "sheikah_api": true}
+
+// analyzeFieldTypes analyze 步骤输出字段支持的类型
+var analyzeFieldTypes = map[string]bool{"string": true, "number": true, "bool": true, "array": true, "object": true}
+
+// playbook 声明式调查剧本: 按顺序执行的步骤, 步骤间通过模板引用此前步骤的输出
+type playbook struct {
+	Name        string         `yaml:"name"`
+	Description string         `yaml:"description"`
+	Steps       []playbookStep `yaml:"steps"`
+}
+
+// playbookStep 剧本步骤, query/tool/analyze/branch/proposal 中有且只有一项
+type playbookStep struct {
+	ID       string            `yaml:"id"`
+	Query    *playbookQuery    `yaml:"query"`
+	Tool     *playbookTool     `yaml:"tool"`
+	Analyze  *playbookAnalyze  `yaml:"analyze"`
+	Branch   *playbookBranch   `yaml:"branch"`
+	Proposal *playbookProposal `yaml:"proposal"`
+}
+
+// playbookQuery 按 SQL 模板查询数据源, 输出 {columns, rows, count}, rows 为列名到值的映射
+type playbookQuery struct {
+	Source string            `yaml:"source"` // 为空时查询 ClickHouse
+	SQLID  string            `yaml:"sql_id"`
+	Params map[string]string `yaml:"params"` // 值为模板
+}
+
+// playbookTool 调用 query_data 或 sheikah_api, 输出 {output}
+type playbookTool struct {
+	Name string                 `yaml:"name"`
+	Args map[string]interface{} `yaml:"args"` // 字符串值为模板
+}
+
+// playbookAnalyze 请 LLM 按给定字段输出 JSON, 输出为解析后的对象
+type playbookAnalyze struct {
+	Prompt string            `yaml:"prompt"` // 模板
+	Schema map[string]string `yaml:"schema"` // 字段名 -> 类型: string, number, bool, array, object
+}
+
+// playbookBranch 条件成立时跳转到后续步骤或剧本结束, 否则继续下一步
+type playbookBranch struct {
+	If   string `yaml:"if"`   // 模板, 渲染结果为空、false、0 或 <no value> 时不成立
+	Goto string `yaml:"goto"` // 后续步骤 ID 或 end
+}
+
+// playbookProposal 创建提案, 输出 {id}
+type playbookProposal struct {
+	Type           string            `yaml:"type"`
+	Title          string            `yaml:"title"`   // 模板
+	Summary        string            `yaml:"summary"` // 模板
+	Severity       string            `yaml:"severity"`
+	Recommendation string            `yaml:"recommendation"`
+	Details        map[string]string `yaml:"details"` // 值为模板
+}
+
+// playbookData 剧本模板可用的数据
+type playbookData struct {
+	Activity string
+	Steps    map[string]interface{} // 步骤 ID -> 输出
+}
+
+// playbookFuncs 剧本模板函数
+var playbookFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// kind 步骤类型
+func (st playbookStep) kind() string {
+	switch {
+	case st.Query != nil:
+		return StepQuery
+	case st.Tool != nil:
+		return StepTool
+	case st.Analyze != nil:
+		return StepAnalyze
+	case st.Branch != nil:
+		return StepBranch
+	case st.Proposal != nil:
+		return StepProposal
+	}
+	return ""
+}
+
+// templates 步骤中的全部模板, 用于校验
+func (st playbookStep) templates() []string {
+	var texts []string
+	switch {
+	case st.Query != nil:
+		for _, v := range st.Query.Params {
+			texts = append(texts, v)
+		}
+	case st.Tool != nil:
+		texts = appendArgTemplates(texts, st.Tool.Args)
+	case st.Analyze != nil:
+		texts = append(texts, st.Analyze.Prompt)
+	case st.Branch != nil:
+		texts = append(texts, st.Branch.If)
+	case st.Proposal != nil:
+		texts = append(texts, st.Proposal.Title, st.Proposal.Summary)
+		for _, v := range st.Proposal.Details {
+			texts = append(texts, v)
+		}
+	}
+	return texts
+}
+
+func appendArgTemplates(texts []string, v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		texts = append(texts, v)
+	case map[string]interface{}:
+		for _, item := range v {
+			texts = appendArgTemplates(texts, item)
+		}
+	case []interface{}:
+		for _, item := range v {
+			texts = appendArgTemplates(texts, item)
+		}
+	}
+	return texts
+}
+
+// playbookPath 剧本文件路径, 相对路径基于 workspace
+func playbookPath(workspace, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(workspace, path)
+}
+
+// loadPlaybook 读取并校验剧本文件
+func loadPlaybook(path string) (*playbook, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parsePlaybook(data, path)
+}
+
+// parsePlaybook 解析并校验剧本, path 用于默认名称和错误信息
+func parsePlaybook(data []byte, path string) (*playbook, error) {
+	var pb playbook
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&pb); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if pb.Name == "" {
+		pb.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if err := pb.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &pb, nil
+}
+
+// validate 校验剧本结构: 步骤 ID 唯一, 每步只有一种动作, 分支只能向后跳转, 模板可解析
+func (pb *playbook) validate() error {
+	if len(pb.Steps) == 0 {
+		return fmt.Errorf("playbook has no steps")
+	}
+
+	index := make(map[string]int, len(pb.Steps))
+	for i, st := range pb.Steps {
+		if st.ID == "" {
+			return fmt.Errorf("step #%d: id is required", i+1)
+		}
+		if st.ID == playbookEnd {
+			return fmt.Errorf("step #%d: id %q is reserved", i+1, playbookEnd)
+		}
+		if _, dup := index[st.ID]; dup {
+			return fmt.Errorf("step #%d: duplicate id %q", i+1, st.ID)
+		}
+		index[st.ID] = i
+	}
+
+	for i, st := range pb.Steps {
+		prefix := fmt.Sprintf("step %s", st.ID)
+		actions := 0
+		for _, set := range []bool{st.Query != nil, st.Tool != nil, st.Analyze != nil, st.Branch != nil, st.Proposal != nil} {
+			if set {
+				actions++
+			}
+		}
+		if actions != 1 {
+			return fmt.Errorf("%s: exactly one of query, tool, analyze, branch, proposal is required", prefix)
+		}
+
+		switch {
+		case st.Query != nil:
+			if st.Query.SQLID == "" {
+				return fmt.Errorf("%s: query requires sql_id", prefix)
+			}
+		case st.Tool != nil:
+			if !playbookTools[st.Tool.Name] {
+				return fmt.Errorf("%s: tool must be query_data or sheikah_api, got %q", prefix, st.Tool.Name)
+			}
+		case st.Analyze != nil:
+			if strings.TrimSpace(st.Analyze.Prompt) == "" {
+				return fmt.Errorf("%s: analyze requires prompt", prefix)
+			}
+			if len(st.Analyze.Schema) == 0 {
+				return fmt.Errorf("%s: analyze requires schema", prefix)
+			}
+			for field, typ := range st.Analyze.Schema {
+				if !analyzeFieldTypes[typ] {
+					return fmt.Errorf("%s: invalid type %q for field %s", prefix, typ, field)
+				}
+			}
+		case st.Branch != nil:
+			if st.Branch.If == "" {
+				return fmt.Errorf("%s: branch requires if", prefix)
+			}
+			if st.Branch.Goto != playbookEnd {
+				target, ok := index[st.Branch.Goto]
+				if !ok {
+					return fmt.Errorf("%s: unknown goto %q", prefix, st.Branch.Goto)
+				}
+				if target <= i {
+					return fmt.Errorf("%s: goto %q must jump forward", prefix, st.Branch.Goto)
+				}
+			}
+		case st.Proposal != nil:
+			p := st.Proposal
+			if !validProposalTypes[p.Type] {
+				return fmt.Errorf("%s: invalid proposal type %q", prefix, p.Type)
+			}
+			if strings.TrimSpace(p.Title) == "" {
+				return fmt.Errorf("%s: proposal requires title", prefix)
+			}
+			if p.Severity != "" && !validSeverities[p.Severity] {
+				return fmt.Errorf("%s: invalid severity %q", prefix, p.Severity)
+			}
+			if p.Recommendation != "" && p.Recommendation != ActionAccept && p.Recommendation != ActionIgnore {
+				return fmt.Errorf("%s: invalid recommendation %q", prefix, p.Recommendation)
+			}
+		}
+
+		for _, text := range st.templates() {
+			if _, err := template.New(st.ID).Funcs(playbookFuncs).Parse(text); err != nil {
+				return fmt.Errorf("%s: invalid template: %w", prefix, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validatePlaybooks 校验活动引用的剧本文件
+func validatePlaybooks(cfg *config.SecOpsConfig, workspace string) error {
+	for name, act := range cfg.Activities {
+		if act.Playbook == "" {
+			continue
+		}
+		if _, err := loadPlaybook(playbookPath(workspace, act.Playbook)); err != nil {
+			return fmt.Errorf("activity %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// runPlaybook 按剧本执行活动, 每个步骤的结果记录到执行历史; 剧本文件在每次执行时重新读取。
+// 任一步骤失败时终止执行; rp 不为空时接续被中断的执行, 上次已成功的步骤沿用其输出, 分支步骤重新求值
+func (s *Service) runPlaybook(ctx context.Context, run *Run, path string, rp *ResumePoint) (string, error) {
+	pb, err := loadPlaybook(playbookPath(s.workspace, path))
+	if err != nil {
+		return "", err
+	}
+
+	data := playbookData{Activity: run.Activity, Steps: make(map[string]interface{}, len(pb.Steps))}
+	restored := resumedStepOutputs(rp)
+	executed := 0
+	for i := 0; i < len(pb.Steps); {
+		st := pb.Steps[i]
+		if output, ok := restored[st.ID]; ok && st.Branch == nil {
+			data.Steps[st.ID] = output
+			s.runs.addStepResult(run, StepResult{ID: st.ID, Type: st.kind(), Status: StepStatusResumed, Output: stepOutput(output)})
+			i++
+			continue
+		}
+		start := time.Now()
+		result := StepResult{ID: st.ID, Type: st.kind(), Status: RunStatusSucceeded}
+
+		output, jump, err := s.runPlaybookStep(ctx, run, st, data)
+		result.DurationMs = time.Since(start).Milliseconds()
+		if err != nil {
+			result.Status = RunStatusFailed
+			result.Error = err.Error()
+			s.runs.addStepResult(run, result)
+			logger.WarnCF("secops", "Playbook step failed",
+				map[string]interface{}{
+					"activity": run.Activity,
+					"playbook": pb.Name,
+					"step":     st.ID,
+					"error":    err.Error(),
+				})
+			return "", fmt.Errorf("playbook %s step %s: %w", pb.Name, st.ID, err)
+		}
+		data.Steps[st.ID] = output
+		result.Output = stepOutput(output)
+		s.runs.addStepResult(run, result)
+		executed++
+
+		next := i + 1
+		if jump != "" {
+			next = len(pb.Steps)
+			for j := i + 1; j < len(pb.Steps); j++ {
+				if pb.Steps[j].ID == jump {
+					next = j
+					break
+				}
+			}
+			for _, skipped := range pb.Steps[i+1 : next] {
+				s.runs.addStepResult(run, StepResult{ID: skipped.ID, Type: skipped.kind(), Status: StepStatusSkipped})
+			}
+		}
+		i = next
+	}
+
+	return fmt.Sprintf("playbook %s completed: %d of %d steps executed", pb.Name, executed, len(pb.Steps)), nil
+}
+
+// runPlaybookStep 执行单个步骤, 返回步骤输出和分支跳转目标 (不跳转时为空)
+func (s *Service) runPlaybookStep(ctx context.Context, run *Run, st playbookStep, data playbookData) (interface{}, string, error) {
+	render := func(text string) (string, error) {
+		return renderPlaybookTemplate(st.ID, text, data)
+	}
+
+	switch {
+	case st.Query != nil:
+		params := make(map[string]string, len(st.Query.Params))
+		for k, v := range st.Query.Params {
+			rendered, err := render(v)
+			if err != nil {
+				return nil, "", err
+			}
+			params[k] = rendered
+		}
+		queryTool, _ := s.tools()
+		if queryTool == nil {
+			return nil, "", fmt.Errorf("query_data tool not available")
+		}
+		columns, rows, err := queryTool.QueryTemplate(ctx, st.Query.Source, st.Query.SQLID, params)
+		if err != nil {
+			return nil, "", err
+		}
+		records := make([]map[string]interface{}, 0, len(rows))
+		for _, row := range rows {
+			record := make(map[string]interface{}, len(columns))
+			for i, col := range columns {
+				if i < len(row) {
+					record[col] = row[i]
+				}
+			}
+			records = append(records, record)
+		}
+		return map[string]interface{}{"columns": columns, "rows": records, "count": len(records)}, "", nil
+
+	case st.Tool != nil:
+		args, err := renderPlaybookArgs(st.Tool.Args, render)
+		if err != nil {
+			return nil, "", err
+		}
+		queryTool, apiTool := s.tools()
+		var tool tools.Tool
+		if st.Tool.Name == "query_data" && queryTool != nil {
+			tool = queryTool
+		} else if st.Tool.Name == "sheikah_api" && apiTool != nil {
+			tool = apiTool
+		} else {
+			return nil, "", fmt.Errorf("%s tool not available", st.Tool.Name)
+		}
+		res := tool.Execute(ctx, args.(map[string]interface{}))
+		if res.IsError {
+			return nil, "", fmt.Errorf("%s", res.ForLLM)
+		}
+		output := res.ForUser
+		if output == "" {
+			output = res.ForLLM
+		}
+		return map[string]interface{}{"output": output}, "", nil
+
+	case st.Analyze != nil:
+		prompt, err := render(st.Analyze.Prompt)
+		if err != nil {
+			return nil, "", err
+		}
+		response, err := s.agentLoop.Complete(ctx, buildAnalyzePrompt(prompt, st.Analyze.Schema))
+		if err != nil {
+			return nil, "", fmt.Errorf("llm analysis failed: %w", err)
+		}
+		result, err := parseAnalyzeResponse(response, st.Analyze.Schema)
+		if err != nil {
+			return nil, "", err
+		}
+		return result, "", nil
+
+	case st.Branch != nil:
+		cond, err := render(st.Branch.If)
+		if err != nil {
+			return nil, "", err
+		}
+		taken := truthy(cond)
+		output := map[string]interface{}{"taken": taken}
+		if taken {
+			return output, st.Branch.Goto, nil
+		}
+		return output, "", nil
+
+	case st.Proposal != nil:
+		title, err := render(st.Proposal.Title)
+		if err != nil {
+			return nil, "", err
+		}
+		summary, err := render(st.Proposal.Summary)
+		if err != nil {
+			return nil, "", err
+		}
+		details := make(map[string]interface{}, len(st.Proposal.Details))
+		for k, v := range st.Proposal.Details {
+			if details[k], err = render(v); err != nil {
+				return nil, "", err
+			}
+		}
+
+		p := NewProposal(st.Proposal.Type, strings.TrimSpace(title), strings.TrimSpace(summary), details)
+		p.ID = uuid.New().String()
+		p.Severity = st.Proposal.Severity
+		p.Recommendation = st.Proposal.Recommendation
+		p.RunID = s.runs.attachProposal(run.Activity, p.ID)
+		p.CreatedBy = &Actor{Name: run.Activity, Via: ViaActivity}
+		return map[string]interface{}{"id": s.CreateProposal(p)}, "", nil
+	}
+	return nil, "", fmt.Errorf("step has no action")
+}
+
+// renderPlaybookTemplate 渲染步骤模板
+func renderPlaybookTemplate(name, text string, data playbookData) (string, error) {
+	tmpl, err := template.New(name).Funcs(playbookFuncs).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// renderPlaybookArgs 递归渲染工具参数中的字符串
+func renderPlaybookArgs(v interface{}, render func(string) (string, error)) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		return render(v)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			rendered, err := renderPlaybookArgs(item, render)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = rendered
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			rendered, err := renderPlaybookArgs(item, render)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = rendered
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// truthy 分支条件的渲染结果是否成立
+func truthy(s string) bool {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "false", "0", "<no value>":
+		return false
+	}
+	return true
+}
+
+// buildAnalyzePrompt 在分析 prompt 后附加输出格式要求
+func buildAnalyzePrompt(prompt string, schema map[string]string) string {
+	fields := make([]string, 0, len(schema))
+	for field := range schema {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var sb strings.Builder
+	sb.WriteString(strings.TrimSpace(prompt))
+	sb.WriteString("\n\n不要调用任何工具, 只输出一个 JSON 对象, 包含以下字段:\n")
+	for _, field := range fields {
+		sb.WriteString(fmt.Sprintf("- %s (%s)\n", field, schema[field]))
+	}
+	return sb.String()
+}
+
+// parseAnalyzeResponse 从 LLM 回复中解析 JSON 对象并按 schema 校验字段类型, 兼容代码块包裹和前后说明文字
+func parseAnalyzeResponse(response string, schema map[string]string) (map[string]interface{}, error) {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start < 0 || end <= start {
+		return nil, fmt.Errorf("llm response contains no JSON object")
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal([]byte(response[start:end+1]), &result); err != nil {
+		return nil, fmt.Errorf("invalid llm response: %w", err)
+	}
+
+	for field, typ := range schema {
+		v, ok := result[field]
+		if !ok {
+			return nil, fmt.Errorf("llm response missing field %s", field)
+		}
+		var valid bool
+		switch typ {
+		case "string":
+			_, valid = v.(string)
+		case "number":
+			_, valid = v.(float64)
+		case "bool":
+			_, valid = v.(bool)
+		case "array":
+			_, valid = v.([]interface{})
+		case "object":
+			_, valid = v.(map[string]interface{})
+		}
+		if !valid {
+			return nil, fmt.Errorf("llm response field %s is not %s", field, typ)
+		}
+	}
+	return result, nil
+}
+
+// resumedStepOutputs 续跑点中已成功步骤的输出; 输出被截断无法还原的步骤需重新执行
+func resumedStepOutputs(rp *ResumePoint) map[string]interface{} {
+	if rp == nil {
+		return nil
+	}
+	outputs := make(map[string]interface{})
+	for _, st := range rp.Steps {
+		if st.Status != RunStatusSucceeded && st.Status != StepStatusResumed {
+			continue
+		}
+		var output interface{}
+		if err := json.Unmarshal([]byte(st.Output), &output); err != nil {
+			continue
+		}
+		outputs[st.ID] = output
+	}
+	return outputs
+}
+
+// stepOutput 步骤输出的 JSON 形式, 超长时截断
+func stepOutput(output interface{}) string {
+	data, err := json.Marshal(output)
+	if err != nil {
+		return fmt.Sprint(output)
+	}
+	if len(data) > maxStepOutputChars {
+		return string(data[:maxStepOutputChars]) + "...(truncated)"
+	}
+	return string(data)
+}
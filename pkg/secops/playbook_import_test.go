@@ -0,0 +1,225 @@
+package secops
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+const bundlePlaybook = "steps:\n  - id: pending\n    query: {sql_id: pending_risk_events}\n"
+
+func tarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func TestImportPlaybookBundleHTTPS(t *testing.T) {
+	pub, priv, err := GeneratePlaybookKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle := tarGz(t, map[string]string{"triage.yaml": bundlePlaybook, "templates/report.md": "# 报告"})
+	sig, err := SignPlaybookBundle(bundle, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string][]byte{"/triage.tar.gz": bundle, "/triage.tar.gz.sig": []byte(sig + "\n")}
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	defer srv.Close()
+
+	workspace := t.TempDir()
+	source := srv.URL + "/triage.tar.gz"
+	opts := PlaybookImportOptions{Client: srv.Client()}
+
+	if _, err := ImportPlaybookBundle(t.Context(), workspace, source, config.PlaybookTrustConfig{}, opts); err == nil || !strings.Contains(err.Error(), "no playbooks.trusted_keys") {
+		t.Fatalf("signed bundle without trusted keys: err = %v", err)
+	}
+	otherPub, _, _ := GeneratePlaybookKey()
+	if _, err := ImportPlaybookBundle(t.Context(), workspace, source, config.PlaybookTrustConfig{TrustedKeys: []string{otherPub}}, opts); err == nil || !strings.Contains(err.Error(), "trusted key") {
+		t.Fatalf("untrusted signature: err = %v", err)
+	}
+
+	trust := config.PlaybookTrustConfig{TrustedKeys: []string{pub}, RequireSignature: true}
+	b, err := ImportPlaybookBundle(t.Context(), workspace, source, trust, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(bundle)
+	if b.Name != "triage" || b.Digest != "sha256:"+hex.EncodeToString(sum[:]) || b.SignedBy == "" || len(b.Files) != 2 {
+		t.Errorf("bundle = %+v", b)
+	}
+	if _, err := loadPlaybook(playbookPath(workspace, "playbooks/triage/triage.yaml")); err != nil {
+		t.Errorf("installed playbook: %v", err)
+	}
+
+	// 远端内容变化: 未加 Update 时按锁定的摘要拒绝
+	changed := tarGz(t, map[string]string{"triage.yaml": bundlePlaybook + "  - id: more\n    query: {sql_id: q}\n"})
+	files["/triage.tar.gz"] = changed
+	changedSig, _ := SignPlaybookBundle(changed, priv)
+	files["/triage.tar.gz.sig"] = []byte(changedSig)
+	if _, err := ImportPlaybookBundle(t.Context(), workspace, source, trust, opts); err == nil || !strings.Contains(err.Error(), "digest mismatch") {
+		t.Fatalf("changed bundle: err = %v", err)
+	}
+	if _, err := SyncPlaybookBundles(t.Context(), workspace, trust, srv.Client()); err == nil {
+		t.Error("sync must refuse content that differs from the lock")
+	}
+	opts.Update = true
+	if b, err = ImportPlaybookBundle(t.Context(), workspace, source, trust, opts); err != nil || len(b.Files) != 1 {
+		t.Fatalf("update = %+v, %v", b, err)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "playbooks", "triage", "templates")); !os.IsNotExist(err) {
+		t.Error("update must replace the whole bundle directory")
+	}
+
+	installed, err := InstalledPlaybookBundles(workspace)
+	if err != nil || len(installed) != 1 || installed[0].Digest != b.Digest {
+		t.Errorf("installed = %+v, %v", installed, err)
+	}
+
+	// 未签名的包在 RequireSignature 或配置了 TrustedKeys 时被拒绝
+	delete(files, "/triage.tar.gz.sig")
+	if _, err := ImportPlaybookBundle(t.Context(), workspace, source, trust, opts); err == nil || !strings.Contains(err.Error(), "not signed") {
+		t.Errorf("unsigned bundle: err = %v", err)
+	}
+	if _, err := ImportPlaybookBundle(t.Context(), workspace, source, config.PlaybookTrustConfig{TrustedKeys: []string{pub}}, opts); err == nil || !strings.Contains(err.Error(), "not signed") {
+		t.Errorf("unsigned bundle with trusted keys: err = %v", err)
+	}
+	if _, err := SignPlaybookBundle(changed, pub[:20]); err == nil {
+		t.Error("expected invalid private key error")
+	}
+}
+
+func TestImportPlaybookBundleOCI(t *testing.T) {
+	pub, priv, _ := GeneratePlaybookKey()
+	layer := []byte(bundlePlaybook)
+	layerSum := sha256.Sum256(layer)
+	layerDigest := "sha256:" + hex.EncodeToString(layerSum[:])
+	sig, _ := SignPlaybookBundle(layer, priv)
+	manifest, _ := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"mediaType":     "application/vnd.oci.image.manifest.v1+json",
+		"layers":        []map[string]string{{"mediaType": "application/vnd.soclaw.playbook.v1+yaml", "digest": layerDigest}},
+		"annotations":   map[string]string{ociSignatureAnnotation: sig},
+	})
+	manifestSum := sha256.Sum256(manifest)
+
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			w.Write([]byte(`{"token":"anon"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer anon" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+srv.URL+`/token",service="registry",scope="repository:acme/triage:pull"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/acme/triage/manifests/1.0", "/v2/acme/triage/manifests/sha256:" + hex.EncodeToString(manifestSum[:]):
+			w.Write(manifest)
+		case "/v2/acme/triage/blobs/" + layerDigest:
+			w.Write(layer)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	registry := strings.TrimPrefix(srv.URL, "https://")
+	workspace := t.TempDir()
+	trust := config.PlaybookTrustConfig{TrustedKeys: []string{pub}}
+	opts := PlaybookImportOptions{Client: srv.Client()}
+
+	b, err := ImportPlaybookBundle(t.Context(), workspace, "oci://"+registry+"/acme/triage:1.0", trust, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Name != "triage" || b.Digest != layerDigest || b.SignedBy == "" || len(b.Files) != 1 || b.Files[0] != "triage.yaml" {
+		t.Errorf("bundle = %+v", b)
+	}
+
+	opts.Name = "pinned"
+	if _, err := ImportPlaybookBundle(t.Context(), workspace, "oci://"+registry+"/acme/triage@sha256:"+hex.EncodeToString(manifestSum[:]), trust, opts); err != nil {
+		t.Errorf("digest reference: %v", err)
+	}
+	if _, err := ImportPlaybookBundle(t.Context(), workspace, "oci://"+registry+"/acme/triage@sha256:"+strings.Repeat("0", 64), trust, opts); err == nil {
+		t.Error("expected error for unknown manifest digest")
+	}
+}
+
+func TestImportPlaybookBundleOCIRejectsPlainRealm(t *testing.T) {
+	var tokenRequests int
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests++
+		w.Write([]byte(`{"token":"anon"}`))
+	}))
+	defer plain.Close()
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="`+plain.URL+`/token",service="registry"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	registry := strings.TrimPrefix(srv.URL, "https://")
+	_, err := ImportPlaybookBundle(t.Context(), t.TempDir(), "oci://"+registry+"/acme/triage:1.0",
+		config.PlaybookTrustConfig{}, PlaybookImportOptions{Client: srv.Client()})
+	if err == nil || !strings.Contains(err.Error(), "unsupported realm") {
+		t.Errorf("err = %v, want unsupported realm", err)
+	}
+	if tokenRequests != 0 {
+		t.Errorf("token requested over plain http %d times", tokenRequests)
+	}
+}
+
+func TestReadPlaybookBundleRejects(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{"traversal", map[string]string{"../evil.yaml": bundlePlaybook}, "escapes"},
+		{"absolute", map[string]string{"/etc/evil.yaml": bundlePlaybook}, "escapes"},
+		{"file type", map[string]string{"triage.yaml": bundlePlaybook, "run.sh": "rm -rf /"}, "unsupported file type"},
+		{"no playbooks", map[string]string{"README.md": "x"}, "no playbooks"},
+		{"invalid playbook", map[string]string{"triage.yaml": "steps: []"}, "no steps"},
+	} {
+		if _, err := readPlaybookBundle(tarGz(t, c.files), "bundle.tar.gz"); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: err = %v, want %q", c.name, err, c.want)
+		}
+	}
+
+	if _, err := ImportPlaybookBundle(t.Context(), t.TempDir(), "http://example.com/triage.yaml", config.PlaybookTrustConfig{}, PlaybookImportOptions{}); err == nil || !strings.Contains(err.Error(), "unsupported playbook source") {
+		t.Errorf("plain http: err = %v", err)
+	}
+}
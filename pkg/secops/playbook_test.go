@@ -0,0 +1,173 @@
+package secops
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/agent"
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+const testPlaybook = `
+name: risk_triage
+steps:
+  - id: pending
+    query:
+      sql_id: pending_risk_events
+      params:
+        batch_size: 5
+  - id: empty
+    branch:
+      if: '{{eq .Steps.pending.count 0}}'
+      goto: end
+  - id: verdict
+    analyze:
+      prompt: '研判以下风险事件: {{json .Steps.pending.rows}}'
+      schema:
+        malicious: bool
+        reason: string
+  - id: benign
+    branch:
+      if: '{{not .Steps.verdict.malicious}}'
+      goto: end
+  - id: propose
+    proposal:
+      type: risk
+      title: '{{(index .Steps.pending.rows 0).host}} 存在 {{(index .Steps.pending.rows 0).risk}}'
+      summary: '{{.Steps.verdict.reason}}'
+      severity: high
+      recommendation: accept
+      details:
+        host: '{{(index .Steps.pending.rows 0).host}}'
+`
+
+func TestRunPlaybook(t *testing.T) {
+	ch := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"meta":[{"name":"risk","type":"String"},{"name":"host","type":"String"}],"data":[["sql_injection","shop.example.com"]]}`))
+	}))
+	defer ch.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	if err := os.WriteFile(filepath.Join(cfg.WorkspacePath(), "triage.yaml"), []byte(testPlaybook), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg.SecOps = config.SecOpsConfig{
+		Enabled:    true,
+		ClickHouse: config.ClickHouseConfig{Addr: strings.TrimPrefix(ch.URL, "http://")},
+		Sheikah:    config.SheikahConfig{BaseURL: "http://127.0.0.1:1"},
+		Activities: map[string]config.ActivityConfig{
+			"risk_analysis": {Enabled: true, Schedule: "1h", Playbook: "triage.yaml"},
+		},
+	}
+	provider, err := providers.NewScriptedProvider(providers.ScriptedScript{
+		Default: "```json\n{\"malicious\": true, \"reason\": \"参数中包含 SQL 注入载荷\"}\n```",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgBus := bus.NewMessageBus()
+	svc, err := NewService(&cfg.SecOps, agent.NewAgentLoop(cfg, msgBus, provider), msgBus, cfg.WorkspacePath())
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	defer svc.Stop()
+
+	run := svc.execute("risk_analysis", 0)
+	if run.Status != RunStatusSucceeded {
+		t.Fatalf("run status = %s (%s), want succeeded", run.Status, run.Error)
+	}
+	var trace []string
+	for _, st := range run.Steps {
+		trace = append(trace, st.ID+":"+st.Status)
+	}
+	if got, want := strings.Join(trace, ","), "pending:succeeded,empty:succeeded,verdict:succeeded,benign:succeeded,propose:succeeded"; got != want {
+		t.Errorf("steps = %s, want %s", got, want)
+	}
+
+	if len(run.ProposalIDs) != 1 {
+		t.Fatalf("proposals = %v, want 1", run.ProposalIDs)
+	}
+	p, _ := svc.GetProposal(run.ProposalIDs[0])
+	if p.Title != "shop.example.com 存在 sql_injection" || p.Summary != "参数中包含 SQL 注入载荷" || p.RunID != run.ID {
+		t.Errorf("proposal = %+v", p)
+	}
+	if p.Details["host"] != "shop.example.com" || p.Severity != SeverityHigh {
+		t.Errorf("proposal details = %v, severity = %s", p.Details, p.Severity)
+	}
+}
+
+func TestPlaybookBranchSkipsSteps(t *testing.T) {
+	svc := &Service{runs: newRunStore(), proposalService: NewProposalService()}
+	path := filepath.Join(t.TempDir(), "pb.yaml")
+	os.WriteFile(path, []byte(`
+steps:
+  - id: always
+    branch:
+      if: 'true'
+      goto: end
+  - id: never
+    proposal:
+      type: risk
+      title: unreachable
+`), 0600)
+
+	run := svc.runs.start("risk_analysis")
+	response, err := svc.runPlaybook(t.Context(), run, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(response, "1 of 2 steps") {
+		t.Errorf("response = %q", response)
+	}
+	if len(run.Steps) != 2 || run.Steps[1].Status != StepStatusSkipped || len(svc.proposalService.GetAll()) != 0 {
+		t.Errorf("steps = %+v", run.Steps)
+	}
+}
+
+func TestPlaybookValidate(t *testing.T) {
+	for _, c := range []struct {
+		name, yaml, want string
+	}{
+		{"no steps", `name: x`, "no steps"},
+		{"two actions", "steps:\n  - id: a\n    query: {sql_id: q}\n    branch: {if: x, goto: end}", "exactly one"},
+		{"duplicate id", "steps:\n  - id: a\n    query: {sql_id: q}\n  - id: a\n    query: {sql_id: q}", "duplicate"},
+		{"backward goto", "steps:\n  - id: a\n    query: {sql_id: q}\n  - id: b\n    branch: {if: x, goto: a}", "forward"},
+		{"unknown tool", "steps:\n  - id: a\n    tool: {name: exec}", "query_data or sheikah_api"},
+		{"schema type", "steps:\n  - id: a\n    analyze: {prompt: p, schema: {x: date}}", "invalid type"},
+		{"proposal type", "steps:\n  - id: a\n    proposal: {type: foo, title: t}", "invalid proposal type"},
+		{"template", "steps:\n  - id: a\n    analyze: {prompt: '{{.Steps', schema: {x: string}}", "invalid template"},
+		{"unknown field", "steps:\n  - id: a\n    qeury: {sql_id: q}", "not found"},
+	} {
+		path := filepath.Join(t.TempDir(), "pb.yaml")
+		os.WriteFile(path, []byte(c.yaml), 0600)
+		if _, err := loadPlaybook(path); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: err = %v, want %q", c.name, err, c.want)
+		}
+	}
+}
+
+func TestParseAnalyzeResponse(t *testing.T) {
+	schema := map[string]string{"score": "number", "tags": "array"}
+	if _, err := parseAnalyzeResponse(`结果: {"score": 0.8, "tags": ["a"]}`, schema); err != nil {
+		t.Errorf("valid response: %v", err)
+	}
+	if _, err := parseAnalyzeResponse(`{"score": "high", "tags": []}`, schema); err == nil {
+		t.Error("expected type mismatch error")
+	}
+	if _, err := parseAnalyzeResponse(`{"score": 1}`, schema); err == nil {
+		t.Error("expected missing field error")
+	}
+}
+
+func TestExamplePlaybook(t *testing.T) {
+	if _, err := loadPlaybook("../../workspace/playbooks/risk_triage.yaml"); err != nil {
+		t.Fatal(err)
+	}
+}
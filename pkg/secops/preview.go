@@ -0,0 +1,96 @@
+package secops
+
+import (
+	"fmt"
+
+	"github.com/sipeed/picoclaw/pkg/tools/secops"
+)
+
+// RequestRenderer 渲染 Sheikah API 请求但不发送
+type RequestRenderer func(api string, params map[string]string) (*secops.RenderedRequest, error)
+
+// ExecutionPreview 决策将触发的 Sheikah 请求, 供分析师确认前核对
+type ExecutionPreview struct {
+	Action   string           `json:"action"`
+	API      string           `json:"api,omitempty"` // 为空表示该动作只记录决策, 不调用 API
+	Requests []RequestPreview `json:"requests"`
+}
+
+// RequestPreview 单个请求的预览, 批量提案每个条目一个
+type RequestPreview struct {
+	Index   int  `json:"index"`
+	Skipped bool `json:"skipped,omitempty"` // 上次已执行成功, 重试时跳过
+	*secops.RenderedRequest
+	Error string `json:"error,omitempty"`
+}
+
+// SetRenderer 设置执行前预览使用的请求渲染器
+func (s *ProposalService) SetRenderer(renderer RequestRenderer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.renderer = renderer
+}
+
+// Preview 渲染决策将发送的请求, 参数合并规则与实际执行一致
+//
+// 待审批的提案按 action 和 req 预演决策; 已决策的提案预览重新执行时的请求, 忽略 action 和 req。
+func (s *ProposalService) Preview(id, action string, req DecisionRequest) (*ExecutionPreview, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.proposals[id]
+	if !ok {
+		return nil, fmt.Errorf("proposal not found: %s", id)
+	}
+
+	var params map[string]string
+	var reason string
+	var prev *Execution
+	switch {
+	case p.Status == ProposalStatusPending:
+		if action != ActionAccept && action != ActionIgnore {
+			return nil, fmt.Errorf("invalid action: %q", action)
+		}
+		var err error
+		if params, reason, err = s.resolveDecisionLocked(p, action, req); err != nil {
+			return nil, err
+		}
+	case p.Decision != nil:
+		action, params, reason = p.Decision.Action, p.Decision.Params, p.Decision.Reason
+		if p.Execution != nil && p.Execution.Action == action {
+			prev = p.Execution
+		}
+	default:
+		return nil, fmt.Errorf("proposal has no decision: %s", p.Status)
+	}
+
+	preview := &ExecutionPreview{Action: action, Requests: []RequestPreview{}}
+	api, params := executionParams(p, action, params, reason)
+	if api == "" {
+		return preview, nil
+	}
+	if s.renderer == nil {
+		return nil, fmt.Errorf("request preview not available: Sheikah API not configured")
+	}
+	preview.API = api
+
+	if len(p.Items) == 0 {
+		preview.Requests = append(preview.Requests, s.renderLocked(0, api, params))
+		return preview, nil
+	}
+	for i, item := range p.Items {
+		rp := s.renderLocked(i, api, itemParams(params, item))
+		rp.Skipped = prev != nil && i < len(prev.Items) && prev.Items[i].Status == ExecutionSucceeded
+		preview.Requests = append(preview.Requests, rp)
+	}
+	return preview, nil
+}
+
+// renderLocked 渲染单个请求, 渲染失败记录在预览中; 调用方需持有锁
+func (s *ProposalService) renderLocked(index int, api string, params map[string]string) RequestPreview {
+	req, err := s.renderer(api, params)
+	if err != nil {
+		return RequestPreview{Index: index, Error: err.Error()}
+	}
+	return RequestPreview{Index: index, RenderedRequest: req}
+}
@@ -1,30 +1,86 @@
 package secops
 
 import (
+	"container/list"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/logger"
 )
 
 // ProposalService 提案服务
 type ProposalService struct {
 	proposals map[string]*Proposal
-	channel   chan *Proposal // 新提案通知
+	version   uint64 // 每次变更递增, 用于 ETag
+	path      string // 持久化文件, 为空时仅保存在内存中
 	mu        sync.RWMutex
+
+	requireOverrideReason bool                                     // 与 Agent 建议相反的决策必须填写理由
+	templates             map[string][]config.ActionTemplateConfig // 按提案类型预置的决策模板
+	executor              ActionExecutor                           // 决策后调用 Sheikah API, 为空时只记录决策
+	renderer              RequestRenderer                          // 渲染执行前预览的请求
+	executing             map[string]bool                          // 正在执行决策的提案
+	execQueue             []string                                 // 等待执行决策的提案, 由执行重试协程依次处理
+	execWake              chan struct{}                            // 执行队列有新提案时通知执行重试协程
+	onDecision            func(*Proposal)                          // 决策及执行完成后调用, 用于通知
+	ttl                   map[string]time.Duration                 // 按提案类型的待处理时长, 超过后标记为过期
+	audit                 *proposalAudit                           // 生命周期审计记录
+
+	limit     memoryLimit              // 内存中的提案上限, 超出时换出最久未访问的已决策提案
+	recent    *list.List               // 提案 ID, 最近访问的在前
+	elems     map[string]*list.Element // 提案 ID -> recent 中的元素
+	lruMu     sync.Mutex               // recent 在读锁下也会更新
+	offloaded map[string]*Proposal     // 已换出到磁盘的提案 -> 索引项, 供筛选、分组和导出使用
+	evicted   uint64                   // 启动以来换出的提案数
+	sizes     map[string]int64         // 内存中各提案 JSON 大小的估算, 在 changed 中按变更的提案增量更新
+	bytes     int64                    // sizes 之和
+
+	tombstones map[string]bool // 已删除或归档的 cloud 提案, 云同步不再重新创建
+
+	saveMu    sync.Mutex      // 串行化持久化文件的写入
+	dirty     bool            // 有尚未写入持久化文件的变更
+	saveTimer *time.Timer     // 合并写入的定时器, 为空表示没有待执行的写入
+	promoted  map[string]bool // 已加载回内存的换出提案, 主文件写入后再删除换出文件
+
+	graphMu      sync.Mutex   // 保护关系图缓存
+	graph        *entityGraph // 实体关系图, 提案变更后按需重建
+	graphVersion uint64       // graph 对应的提案存储版本号
 }
 
+// ErrOverrideReasonRequired 决策与 Agent 建议相反但未填写理由
+var ErrOverrideReasonRequired = errors.New("decision contradicts the agent recommendation: reason is required")
+
+// ErrInvalidParam 决策参数不符合提案声明的参数类型或可选值
+var ErrInvalidParam = errors.New("invalid decision parameter")
+
 // NewProposalService 创建提案服务
 func NewProposalService() *ProposalService {
 	return &ProposalService{
-		proposals: make(map[string]*Proposal),
-		channel:   make(chan *Proposal, 10),
+		proposals:  make(map[string]*Proposal),
+		audit:      newProposalAudit(),
+		execWake:   make(chan struct{}, 1),
+		recent:     list.New(),
+		elems:      make(map[string]*list.Element),
+		offloaded:  make(map[string]*Proposal),
+		sizes:      make(map[string]int64),
+		tombstones: make(map[string]bool),
+		promoted:   make(map[string]bool),
 	}
 }
 
-// Create 创建提案
+// Create 创建提案, 未指定创建者时记为系统创建
 func (s *ProposalService) Create(proposal *Proposal) string {
 	if proposal.ID == "" {
 		proposal.ID = uuid.New().String()
@@ -32,12 +88,32 @@ func (s *ProposalService) Create(proposal *Proposal) string {
 	if proposal.CreatedAt.IsZero() {
 		proposal.CreatedAt = time.Now()
 	}
+	if proposal.CreatedBy == nil {
+		proposal.CreatedBy = &Actor{Via: ViaSystem}
+	}
 	proposal.UpdatedAt = time.Now()
+	for i := range proposal.Evidence {
+		normalizeEvidence(&proposal.Evidence[i])
+	}
 
 	s.mu.Lock()
 	s.proposals[proposal.ID] = proposal
+	s.touch(proposal.ID)
+	s.changed(proposal.ID)
 	s.mu.Unlock()
 
+	created := ProposalEvent{
+		ProposalID: proposal.ID,
+		Action:     AuditCreated,
+		Actor:      *proposal.CreatedBy,
+		To:         proposal.Status,
+		At:         proposal.CreatedAt,
+	}
+	if proposal.RunID != "" {
+		created.Detail = "run " + proposal.RunID
+	}
+	s.audit.record(created)
+
 	logger.InfoCF("secops", "Proposal created",
 		map[string]interface{}{
 			"id":    proposal.ID,
@@ -45,33 +121,40 @@ func (s *ProposalService) Create(proposal *Proposal) string {
 			"title": proposal.Title,
 		})
 
-	// 通知新提案
-	select {
-	case s.channel <- proposal:
-	default:
-		logger.WarnC("secops", "Proposal channel full, notification skipped")
-	}
-
 	return proposal.ID
 }
 
-// Get 获取提案
+// Get 获取提案副本, 已换出到磁盘的提案加载回内存; 执行在后台进行, 返回副本避免调用方与执行协程竞争
 func (s *ProposalService) Get(id string) (*Proposal, bool) {
 	s.mu.RLock()
-	defer s.mu.RUnlock()
 	p, ok := s.proposals[id]
-	return p, ok
+	offloaded := s.offloaded[id] != nil
+	var c *Proposal
+	if ok {
+		c = p.snapshot()
+	}
+	s.mu.RUnlock()
+	if ok {
+		s.touch(id)
+		return c, true
+	}
+	if offloaded {
+		return s.promote(id)
+	}
+	return nil, false
 }
 
-// GetAll 获取所有提案
+// GetAll 获取所有提案, 已换出的提案从磁盘读取但不加载回内存
 func (s *ProposalService) GetAll() []*Proposal {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	result := make([]*Proposal, 0, len(s.proposals))
-	for _, p := range s.proposals {
-		result = append(result, p)
-	}
+	result := make([]*Proposal, 0, len(s.proposals)+len(s.offloaded))
+	s.eachIndexedLocked(func(p *Proposal) {
+		if p, ok := s.resolveLocked(p); ok {
+			result = append(result, p)
+		}
+	})
 	return result
 }
 
@@ -89,64 +172,559 @@ func (s *ProposalService) GetPending() []*Proposal {
 	return result
 }
 
-// Accept 接受提案
-func (s *ProposalService) Accept(id string, params map[string]string) error {
+// ProposalFilter 提案列表的筛选、排序和分页条件, 零值表示不限制
+type ProposalFilter struct {
+	Statuses   []ProposalStatus // 为空时不限状态
+	Types      []string         // 为空时不限类型
+	Techniques []string         // ATT&CK 技术编号, 命中任一即可; 父技术同时匹配其子技术
+	Severities []string         // 为空时不限严重级别
+	Assignee   string           // 负责人, "-" 表示未指派; 为空时不限
+	GroupBy    string           // 分组方式 host、signature、type, 与 Group 一起筛选某个分组; 为空时不限
+	Group      string           // 分组键, 为空表示缺少该分组字段的提案
+	Confidence int              // 置信度下限 (含), 0 表示不限; 未填写置信度的提案不满足下限
+	Since      time.Time        // 创建时间下限 (含)
+	Until      time.Time        // 创建时间上限 (不含)
+	Sort       string           // created_at、updated_at、severity、confidence、title, 前缀 - 表示倒序; 默认 -created_at
+	Offset     int
+	Limit      int // 0 表示不分页
+}
+
+// UnassignedFilter ProposalFilter.Assignee 取该值时筛选未指派的提案
+const UnassignedFilter = "-"
+
+// proposalSortKeys 支持的排序字段
+var proposalSortKeys = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+	"severity":   true,
+	"confidence": true,
+	"title":      true,
+}
+
+// validProposalStatuses 合法的提案状态
+var validProposalStatuses = map[ProposalStatus]bool{
+	ProposalStatusPending:         true,
+	ProposalStatusAccepted:        true,
+	ProposalStatusIgnored:         true,
+	ProposalStatusModified:        true,
+	ProposalStatusExecutionFailed: true,
+	ProposalStatusExpired:         true,
+}
+
+// severityRank 严重级别的排序权重, 未设置时最低
+var severityRank = map[string]int{
+	SeverityInfo:     1,
+	SeverityLow:      2,
+	SeverityMedium:   3,
+	SeverityHigh:     4,
+	SeverityCritical: 5,
+}
+
+// Validate 检查状态、类型、时间范围和排序字段
+func (f ProposalFilter) Validate() error {
+	for _, st := range f.Statuses {
+		if !validProposalStatuses[st] {
+			return fmt.Errorf("invalid status: %s", st)
+		}
+	}
+	for _, t := range f.Types {
+		if !validProposalTypes[t] {
+			return fmt.Errorf("invalid type: %s", t)
+		}
+	}
+	for _, t := range f.Techniques {
+		if !attackTechniqueID.MatchString(t) {
+			return fmt.Errorf("invalid technique: %s", t)
+		}
+	}
+	for _, sev := range f.Severities {
+		if !validSeverities[sev] {
+			return fmt.Errorf("invalid severity: %s", sev)
+		}
+	}
+	if f.Confidence < 0 || f.Confidence > 100 {
+		return fmt.Errorf("confidence must be between 0 and 100")
+	}
+	if f.GroupBy != "" && !validGroupBy[f.GroupBy] {
+		return fmt.Errorf("invalid group by: %s", f.GroupBy)
+	}
+	if f.GroupBy == "" && f.Group != "" {
+		return fmt.Errorf("group requires group by")
+	}
+	if !f.Since.IsZero() && !f.Until.IsZero() && !f.Since.Before(f.Until) {
+		return fmt.Errorf("since must be before until")
+	}
+	if f.Sort != "" && !proposalSortKeys[strings.TrimPrefix(f.Sort, "-")] {
+		return fmt.Errorf("invalid sort: %s", f.Sort)
+	}
+	if f.Offset < 0 || f.Limit < 0 {
+		return fmt.Errorf("offset and limit must not be negative")
+	}
+	return nil
+}
+
+// match 判断提案是否满足筛选条件
+func (f ProposalFilter) match(p *Proposal) bool {
+	if len(f.Statuses) > 0 && !slices.Contains(f.Statuses, p.Status) {
+		return false
+	}
+	if len(f.Types) > 0 && !slices.Contains(f.Types, p.Type) {
+		return false
+	}
+	if len(f.Techniques) > 0 && !slices.ContainsFunc(f.Techniques, func(t string) bool {
+		return matchTechnique(p.Techniques, t)
+	}) {
+		return false
+	}
+	if len(f.Severities) > 0 && !slices.Contains(f.Severities, p.Severity) {
+		return false
+	}
+	if f.Confidence > 0 && p.Confidence < f.Confidence {
+		return false
+	}
+	switch f.Assignee {
+	case "":
+	case UnassignedFilter:
+		if p.Assignee != "" {
+			return false
+		}
+	default:
+		if p.Assignee != f.Assignee {
+			return false
+		}
+	}
+	if f.GroupBy != "" && !slices.Contains(proposalGroupKeys(p, f.GroupBy), f.Group) {
+		return false
+	}
+	if !f.Since.IsZero() && p.CreatedAt.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !p.CreatedAt.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// less 按排序字段比较, 相同时按 ID 升序以保证分页稳定
+func (f ProposalFilter) less(a, b *Proposal) bool {
+	key, desc := f.Sort, false
+	if key == "" {
+		key = "-created_at"
+	}
+	if strings.HasPrefix(key, "-") {
+		key, desc = key[1:], true
+	}
+
+	var cmp int
+	switch key {
+	case "updated_at":
+		cmp = a.UpdatedAt.Compare(b.UpdatedAt)
+	case "severity":
+		// 同级别时置信度高的在前 (按排序方向)
+		if cmp = severityRank[a.Severity] - severityRank[b.Severity]; cmp == 0 {
+			cmp = a.Confidence - b.Confidence
+		}
+	case "confidence":
+		cmp = a.Confidence - b.Confidence
+	case "title":
+		cmp = strings.Compare(a.Title, b.Title)
+	default:
+		cmp = a.CreatedAt.Compare(b.CreatedAt)
+	}
+	if cmp == 0 {
+		return a.ID < b.ID
+	}
+	if desc {
+		return cmp > 0
+	}
+	return cmp < 0
+}
+
+// GetFiltered 按条件筛选、排序并分页, 返回该页提案和满足条件的总数
+func (s *ProposalService) GetFiltered(f ProposalFilter) ([]*Proposal, int, error) {
+	if err := f.Validate(); err != nil {
+		return nil, 0, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]*Proposal, 0)
+	s.eachIndexedLocked(func(p *Proposal) {
+		if f.match(p) {
+			result = append(result, p)
+		}
+	})
+
+	sort.Slice(result, func(i, j int) bool {
+		return f.less(result[i], result[j])
+	})
+
+	total := len(result)
+	if f.Offset >= total {
+		return []*Proposal{}, total, nil
+	}
+	end := total
+	if f.Limit > 0 && f.Offset+f.Limit < end {
+		end = f.Offset + f.Limit
+	}
+	// 只有当前页中已换出的提案才从磁盘读取完整内容
+	page := make([]*Proposal, 0, end-f.Offset)
+	for _, p := range result[f.Offset:end] {
+		if p, ok := s.resolveLocked(p); ok {
+			page = append(page, p)
+		}
+	}
+	return page, total, nil
+}
+
+// Accept 接受提案, 绑定的 API 排入执行队列后立即返回; 执行失败不影响决策, 结果记录在 Execution 中
+func (s *ProposalService) Accept(id string, req DecisionRequest) error {
+	return s.decide(id, ProposalStatusAccepted, ActionAccept, req)
+}
+
+// Ignore 忽略提案, 绑定的 API 排入执行队列后立即返回
+func (s *ProposalService) Ignore(id string, req DecisionRequest) error {
+	return s.decide(id, ProposalStatusIgnored, ActionIgnore, req)
+}
+
+// SetDecisionHandler 设置决策处理函数, 在决策及绑定 API 执行完成后调用
+func (s *ProposalService) SetDecisionHandler(handler func(*Proposal)) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.onDecision = handler
+}
+
+// decided 调用决策处理函数; 处理函数在锁外异步读取提案, 传入在读锁下复制的快照
+func (s *ProposalService) decided(id string) {
+	s.mu.RLock()
+	handler := s.onDecision
+	var snap *Proposal
+	if p, ok := s.proposals[id]; ok {
+		snap = p.snapshot()
+	}
+	s.mu.RUnlock()
+	if handler != nil && snap != nil {
+		handler(snap)
+	}
+}
+
+// snapshot 复制提案, 锁内原地修改的详情、证据、评论和译文等一并复制
+func (p *Proposal) snapshot() *Proposal {
+	c := *p
+	c.Details = maps.Clone(p.Details)
+	c.Actions = slices.Clone(p.Actions)
+	c.Evidence = slices.Clone(p.Evidence)
+	c.Items = slices.Clone(p.Items)
+	c.Artifacts = slices.Clone(p.Artifacts)
+	c.Comments = slices.Clone(p.Comments)
+	c.Translations = maps.Clone(p.Translations)
+	if p.Execution != nil {
+		c.Execution = p.Execution.snapshot()
+	}
+	return &c
+}
+
+// decide 记录分析师决策并将绑定的 API 排入执行队列; 与 Agent 建议相反且开启强制理由时, 理由必填。
+// 无需执行时立即调用决策处理函数, 否则由执行重试协程在执行后调用
+func (s *ProposalService) decide(id string, status ProposalStatus, action string, req DecisionRequest) error {
+	s.mu.Lock()
+	d, err := s.checkDecisionLocked(id, action, req)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.applyDecisionLocked(d, status, action, req, time.Now())
+	queued := s.queueExecutionLocked(d.p)
+	s.changed(id)
+	s.mu.Unlock()
+
+	if queued {
+		s.wakeExecutions()
+	} else {
+		s.decided(id)
+	}
+	return nil
+}
+
+// pendingDecision 已通过校验、尚未写入的决策
+type pendingDecision struct {
+	p         *Proposal
+	params    map[string]string
+	overrides []ParamOverride
+	reason    string
+	override  bool
+}
 
+// checkDecisionLocked 校验提案可以决策并解析参数和理由, 调用方需持有锁
+func (s *ProposalService) checkDecisionLocked(id, action string, req DecisionRequest) (pendingDecision, error) {
 	p, ok := s.proposals[id]
 	if !ok {
-		return fmt.Errorf("proposal not found: %s", id)
+		return pendingDecision{}, fmt.Errorf("proposal not found: %s", id)
 	}
 
 	if p.Status != ProposalStatusPending {
-		return fmt.Errorf("proposal already processed: %s", p.Status)
+		return pendingDecision{}, fmt.Errorf("proposal already processed: %s", p.Status)
 	}
 
-	p.Status = ProposalStatusAccepted
-	p.UpdatedAt = time.Now()
+	params, reason, err := s.resolveDecisionLocked(p, action, req)
+	if err != nil {
+		return pendingDecision{}, err
+	}
+
+	override := p.Recommendation != "" && p.Recommendation != action
+	if override && s.requireOverrideReason && reason == "" {
+		return pendingDecision{}, ErrOverrideReasonRequired
+	}
+	return pendingDecision{p: p, params: params, overrides: paramOverrides(p, params), reason: reason, override: override}, nil
+}
+
+// applyDecisionLocked 写入已校验的决策并记录审计, 调用方需持有锁并在之后持久化
+func (s *ProposalService) applyDecisionLocked(d pendingDecision, status ProposalStatus, action string, req DecisionRequest, now time.Time) {
+	p := d.p
+	from := p.Status
+	p.Status = status
+	p.Decision = &Decision{
+		Action:    action,
+		Reason:    d.reason,
+		Template:  req.Template,
+		Params:    d.params,
+		Overrides: d.overrides,
+		Override:  d.override,
+		DecidedAt: now,
+	}
+	if req.By != (Actor{}) {
+		by := req.By
+		p.Decision.By = &by
+	}
+	p.UpdatedAt = now
 
-	logger.InfoCF("secops", "Proposal accepted",
+	logger.InfoCF("secops", fmt.Sprintf("Proposal %s", status),
 		map[string]interface{}{
-			"id":     p.ID,
-			"type":   p.Type,
-			"title":  p.Title,
-			"params": params,
+			"id":       p.ID,
+			"type":     p.Type,
+			"title":    p.Title,
+			"params":   d.params,
+			"edited":   len(d.overrides),
+			"template": req.Template,
+			"reason":   d.reason,
+			"override": d.override,
+			"by":       req.By.Name,
+			"via":      req.By.Via,
 		})
 
+	var details []string
+	if req.Template != "" {
+		details = append(details, "template: "+req.Template)
+	}
+	if len(d.overrides) > 0 {
+		changes := make([]string, len(d.overrides))
+		for i, o := range d.overrides {
+			changes[i] = fmt.Sprintf("%s: %q → %q", o.Key, o.From, o.To)
+		}
+		details = append(details, "edited: "+strings.Join(changes, ", "))
+	}
+	detail := strings.Join(details, "; ")
+	s.audit.record(ProposalEvent{
+		ProposalID: p.ID,
+		Action:     string(status),
+		Actor:      req.By,
+		From:       from,
+		To:         status,
+		Params:     d.params,
+		Reason:     d.reason,
+		Detail:     detail,
+		At:         now,
+	})
+}
+
+// resolveDecisionLocked 合并决策参数并确定理由, 调用方需持有锁
+//
+// 参数优先级: 提案默认值 < 模板 < 分析师填写; 合并后的值按提案声明的参数类型校验
+func (s *ProposalService) resolveDecisionLocked(p *Proposal, action string, req DecisionRequest) (map[string]string, string, error) {
+	params := make(map[string]string)
+	for key, param := range p.Parameters {
+		if param.Value != "" {
+			params[key] = param.Value
+		}
+	}
+
+	reason := strings.TrimSpace(req.Reason)
+	if req.Template != "" {
+		tmpl, ok := s.findTemplate(p.Type, action, req.Template)
+		if !ok {
+			return nil, "", fmt.Errorf("template %q not available for %s/%s", req.Template, p.Type, action)
+		}
+		for k, v := range tmpl.Params {
+			params[k] = v
+		}
+		if reason == "" {
+			reason = strings.TrimSpace(tmpl.Note)
+		}
+	}
+	for k, v := range req.Params {
+		params[k] = v
+	}
+	if err := validateParams(p, params); err != nil {
+		return nil, "", err
+	}
+	return params, reason, nil
+}
+
+// decimalNumber number 参数接受的十进制数, 不接受 NaN、Inf 和十六进制
+var decimalNumber = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?$`)
+
+// validateParams 按提案声明的参数校验取值: number 须为有限的十进制数, 去掉首尾空白后写回; select 须为可选值之一;
+// 与 Agent 建议值相同的取值和未声明的参数不校验
+func validateParams(p *Proposal, params map[string]string) error {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := params[k]
+		param, ok := p.Parameters[k]
+		if !ok || v == param.Value {
+			continue
+		}
+		label := k
+		if param.Label != "" {
+			label = fmt.Sprintf("%s (%s)", param.Label, k)
+		}
+		switch param.Type {
+		case "number":
+			trimmed := strings.TrimSpace(v)
+			if !decimalNumber.MatchString(trimmed) {
+				return fmt.Errorf("%w: %s must be a number, got %q", ErrInvalidParam, label, v)
+			}
+			if _, err := strconv.ParseFloat(trimmed, 64); err != nil {
+				return fmt.Errorf("%w: %s must be a finite number, got %q", ErrInvalidParam, label, v)
+			}
+			params[k] = trimmed
+		case "select":
+			if len(param.Options) > 0 && !slices.Contains(param.Options, v) {
+				return fmt.Errorf("%w: %s must be one of %s, got %q", ErrInvalidParam, label, strings.Join(param.Options, ", "), v)
+			}
+		}
+	}
 	return nil
 }
 
-// Ignore 忽略提案
-func (s *ProposalService) Ignore(id string, params map[string]string) error {
+// paramOverrides 生效参数与 Agent 建议值的差异, 按参数名排序
+func paramOverrides(p *Proposal, params map[string]string) []ParamOverride {
+	var overrides []ParamOverride
+	for k, v := range params {
+		if from := p.Parameters[k].Value; v != from {
+			overrides = append(overrides, ParamOverride{Key: k, From: from, To: v})
+		}
+	}
+	sort.Slice(overrides, func(i, j int) bool { return overrides[i].Key < overrides[j].Key })
+	return overrides
+}
+
+// SetTemplates 设置按提案类型预置的决策模板
+func (s *ProposalService) SetTemplates(templates map[string][]config.ActionTemplateConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.templates = templates
+}
+
+// Templates 获取某类提案可用的决策模板
+func (s *ProposalService) Templates(proposalType string) []config.ActionTemplateConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]config.ActionTemplateConfig, len(s.templates[proposalType]))
+	copy(result, s.templates[proposalType])
+	return result
+}
+
+func (s *ProposalService) findTemplate(proposalType, action, name string) (config.ActionTemplateConfig, bool) {
+	for _, t := range s.templates[proposalType] {
+		if t.Name == name && (t.Action == "" || t.Action == action) {
+			return t, true
+		}
+	}
+	return config.ActionTemplateConfig{}, false
+}
+
+// SetRequireOverrideReason 设置与 Agent 建议相反的决策是否必须填写理由
+func (s *ProposalService) SetRequireOverrideReason(require bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requireOverrideReason = require
+}
+
+// Overrides 获取 since 之后与 Agent 建议相反的决策, proposalType 为空时返回全部类型, 按决策时间倒序
+func (s *ProposalService) Overrides(proposalType string, since time.Time) []*Proposal {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*Proposal, 0)
+	for _, p := range s.proposals {
+		if p.Decision == nil || !p.Decision.Override || p.Decision.DecidedAt.Before(since) {
+			continue
+		}
+		if proposalType != "" && p.Type != proposalType {
+			continue
+		}
+		result = append(result, p)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Decision.DecidedAt.After(result[j].Decision.DecidedAt)
+	})
+	return result
+}
+
+// Acknowledge 知悉待处理提案, 在 d 时间内不再提醒
+func (s *ProposalService) Acknowledge(id string, d time.Duration, reason string, by Actor) (*Proposal, error) {
+	if d <= 0 {
+		return nil, fmt.Errorf("acknowledge duration must be positive")
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	p, ok := s.proposals[id]
 	if !ok {
-		return fmt.Errorf("proposal not found: %s", id)
+		return nil, fmt.Errorf("proposal not found: %s", id)
 	}
-
 	if p.Status != ProposalStatusPending {
-		return fmt.Errorf("proposal already processed: %s", p.Status)
+		return nil, fmt.Errorf("proposal already processed: %s", p.Status)
 	}
 
-	p.Status = ProposalStatusIgnored
-	p.UpdatedAt = time.Now()
+	now := time.Now()
+	p.Acknowledgement = &Acknowledgement{
+		Reason: strings.TrimSpace(reason),
+		At:     now,
+		Until:  now.Add(d),
+	}
+	p.UpdatedAt = now
+	s.changed(p.ID)
 
-	logger.InfoCF("secops", "Proposal ignored",
+	logger.InfoCF("secops", "Proposal acknowledged",
 		map[string]interface{}{
 			"id":     p.ID,
 			"type":   p.Type,
-			"title":  p.Title,
-			"params": params,
+			"until":  p.Acknowledgement.Until,
+			"reason": p.Acknowledgement.Reason,
 		})
 
-	return nil
+	s.audit.record(ProposalEvent{
+		ProposalID: p.ID,
+		Action:     AuditAcknowledged,
+		Actor:      by,
+		Reason:     p.Acknowledgement.Reason,
+		Detail:     "until " + p.Acknowledgement.Until.Format(time.RFC3339),
+		At:         now,
+	})
+
+	return p, nil
 }
 
 // Resubmit 重新分析 - 使用修改后的参数
-func (s *ProposalService) Resubmit(id string, params map[string]string) (*Proposal, error) {
+func (s *ProposalService) Resubmit(id string, params map[string]string, by Actor) (*Proposal, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -163,8 +741,10 @@ func (s *ProposalService) Resubmit(id string, params map[string]string) (*Propos
 		}
 	}
 
+	from := p.Status
 	p.Status = ProposalStatusModified
 	p.UpdatedAt = time.Now()
+	s.changed(p.ID)
 
 	logger.InfoCF("secops", "Proposal resubmitted with modified params",
 		map[string]interface{}{
@@ -174,22 +754,173 @@ func (s *ProposalService) Resubmit(id string, params map[string]string) (*Propos
 			"params": params,
 		})
 
+	s.audit.record(ProposalEvent{
+		ProposalID: p.ID,
+		Action:     AuditResubmitted,
+		Actor:      by,
+		From:       from,
+		To:         p.Status,
+		Params:     params,
+		At:         p.UpdatedAt,
+	})
+
+	return p, nil
+}
+
+// UpdateSummary 以重新生成的标题和摘要替换当前版本, 首次替换时保留原始版本
+func (s *ProposalService) UpdateSummary(id, title, summary string, by Actor) (*Proposal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.lookupLocked(id)
+	if !ok {
+		return nil, fmt.Errorf("proposal not found: %s", id)
+	}
+
+	now := time.Now()
+	if p.OriginalSummary == nil {
+		p.OriginalSummary = &SummaryVersion{
+			Title:     p.Title,
+			Summary:   p.Summary,
+			CreatedAt: p.CreatedAt,
+		}
+	}
+	p.Title = title
+	p.Summary = summary
+	p.SummaryRegenerated = true
+	p.Translations = nil // 原文已变化, 缓存的译文失效
+	p.UpdatedAt = now
+	s.changed(p.ID)
+
+	logger.InfoCF("secops", "Proposal summary regenerated",
+		map[string]interface{}{
+			"id":    p.ID,
+			"type":  p.Type,
+			"title": p.Title,
+		})
+
+	s.audit.record(ProposalEvent{
+		ProposalID: p.ID,
+		Action:     AuditRegenerated,
+		Actor:      by,
+		Detail:     title,
+		At:         now,
+	})
+
 	return p, nil
 }
 
-// Channel 获取提案通知通道
-func (s *ProposalService) Channel() <-chan *Proposal {
-	return s.channel
+// Translation 获取缓存的译文
+func (s *ProposalService) Translation(id, lang string) (*SummaryVersion, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.proposals[id]
+	if !ok {
+		return nil, false
+	}
+	t, ok := p.Translations[lang]
+	return t, ok
+}
+
+// SetTranslation 缓存译文
+func (s *ProposalService) SetTranslation(id, lang string, t *SummaryVersion) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.lookupLocked(id)
+	if !ok {
+		return fmt.Errorf("proposal not found: %s", id)
+	}
+	if p.Translations == nil {
+		p.Translations = make(map[string]*SummaryVersion)
+	}
+	p.Translations[lang] = t
+	s.changed(id)
+	return nil
+}
+
+// retentionRecords 已处理提案的归档记录, 待处理提案不参与归档
+func (s *ProposalService) retentionRecords() []retentionRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records := make([]retentionRecord, 0)
+	for _, p := range s.proposals {
+		if p.Status == ProposalStatusPending {
+			continue
+		}
+		data, err := json.Marshal(p)
+		if err != nil {
+			continue
+		}
+		records = append(records, retentionRecord{ID: p.ID, Time: p.UpdatedAt, Data: data})
+	}
+	return append(records, s.offloadedRecords()...)
+}
+
+// evidenceRecords 尚未归档的提案证据
+func (s *ProposalService) evidenceRecords() []retentionRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records := make([]retentionRecord, 0)
+	for _, p := range s.proposals {
+		if len(p.Evidence) == 0 {
+			continue
+		}
+		data, err := json.Marshal(p.Evidence)
+		if err != nil {
+			continue
+		}
+		records = append(records, retentionRecord{ID: p.ID, Time: p.CreatedAt, Data: data})
+	}
+	return records
+}
+
+// archiveEvidence 清理已归档的证据, 记录归档文件位置
+func (s *ProposalService) archiveEvidence(ids []string, archive string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		if p, ok := s.proposals[id]; ok {
+			p.Evidence = []Evidence{}
+			p.EvidenceArchive = archive
+		}
+	}
+	s.changed(ids...)
 }
 
-// Delete 删除提案
+// Delete 删除提案; cloud 提案留下墓碑, 避免同一发现再次同步时被重新创建
 func (s *ProposalService) Delete(id string) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, ok := s.proposals[id]; ok {
+	if p, ok := s.proposals[id]; ok {
+		if p.Type == "cloud" {
+			s.buryLocked(id)
+		}
 		delete(s.proposals, id)
+		s.forget(id)
+		s.changed(id)
+		return true
+	}
+	if stub := s.offloaded[id]; stub != nil {
+		if stub.Type == "cloud" {
+			s.buryLocked(id)
+		}
+		delete(s.offloaded, id)
+		os.Remove(s.offloadPath(id))
+		s.changed()
 		return true
 	}
 	return false
 }
+
+// Version 获取提案存储版本号, 任何变更都会使其递增
+func (s *ProposalService) Version() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.version
+}
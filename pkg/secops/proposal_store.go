@@ -0,0 +1,234 @@
+package secops
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// EnablePersistence 将提案持久化到 JSON 文件, 并加载已有的提案
+func (s *ProposalService) EnablePersistence(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		var proposals []*Proposal
+		if err := json.Unmarshal(data, &proposals); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		for _, p := range proposals {
+			s.proposals[p.ID] = p
+		}
+	}
+	for _, id := range recentOrder(s.proposals) {
+		s.touch(id)
+		s.resizeLocked(id)
+	}
+
+	s.path = path
+	s.loadOffloaded()
+	if err := s.loadTombstones(); err != nil {
+		return err
+	}
+	s.requeueLocked()
+	if s.limit.set() {
+		s.changed()
+	} else {
+		s.version++
+	}
+	return nil
+}
+
+// proposalSaveDelay 变更后延迟写入持久化文件的时间, 期间的变更合并为一次写入
+const proposalSaveDelay = 500 * time.Millisecond
+
+// changed 标记存储已变更: 更新 ids 对应提案的占用估算, 递增版本号, 超出内存上限时换出提案, 然后安排持久化;
+// ids 为新增、修改或删除的内存中提案, 调用方需持有写锁
+func (s *ProposalService) changed(ids ...string) {
+	for _, id := range ids {
+		s.resizeLocked(id)
+	}
+	s.version++
+	s.enforceLimitLocked()
+	if s.path == "" {
+		return
+	}
+	s.dirty = true
+	if s.saveTimer == nil {
+		s.saveTimer = time.AfterFunc(proposalSaveDelay, s.flushInBackground)
+	}
+}
+
+// Flush 立即写入尚未持久化的变更。写入时不持有存储锁: 只在锁内复制提案快照, 编码和写文件在锁外完成;
+// 退出前应调用, 否则最后 proposalSaveDelay 内的变更会丢失
+func (s *ProposalService) Flush() error {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	s.mu.Lock()
+	if s.saveTimer != nil {
+		s.saveTimer.Stop()
+		s.saveTimer = nil
+	}
+	if !s.dirty || s.path == "" {
+		s.mu.Unlock()
+		return nil
+	}
+	s.dirty = false
+	path := s.path
+	proposals := make([]*Proposal, 0, len(s.proposals))
+	for _, p := range s.proposals {
+		proposals = append(proposals, p.snapshot())
+	}
+	promoted := s.promoted
+	s.promoted = make(map[string]bool)
+	s.mu.Unlock()
+
+	err := saveJSONAtomic(path, proposals)
+
+	// 主文件写入成功后才删除已加载回内存的换出文件, 期间再次换出的保留; 失败时下次变更或 Flush 重试
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.dirty = true
+	}
+	for id := range promoted {
+		switch {
+		case s.offloaded[id] != nil:
+		case err != nil:
+			s.promoted[id] = true
+		default:
+			os.Remove(s.offloadPath(id))
+		}
+	}
+	return err
+}
+
+// flushInBackground 定时器触发的写入, 失败时记录日志
+func (s *ProposalService) flushInBackground() {
+	if err := s.Flush(); err != nil {
+		logger.ErrorCF("secops", "Failed to persist proposals",
+			map[string]interface{}{
+				"path":  s.path,
+				"error": err.Error(),
+			})
+	}
+}
+
+// saveJSONAtomic 先写临时文件再重命名, 保证文件不会因进程崩溃而损坏
+func saveJSONAtomic(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// tombstonePath 墓碑文件, 与持久化文件同目录
+func (s *ProposalService) tombstonePath() string {
+	return s.offloadDir() + ".tombstones.json"
+}
+
+// loadTombstones 加载已删除的 cloud 提案 ID
+func (s *ProposalService) loadTombstones() error {
+	data, err := os.ReadFile(s.tombstonePath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var ids []string
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", s.tombstonePath(), err)
+	}
+	for _, id := range ids {
+		s.tombstones[id] = true
+	}
+	return nil
+}
+
+// buryLocked 记录已删除的 cloud 提案并持久化; 调用方需持有写锁
+func (s *ProposalService) buryLocked(id string) {
+	s.tombstones[id] = true
+	if s.path == "" {
+		return
+	}
+	ids := make([]string, 0, len(s.tombstones))
+	for id := range s.tombstones {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	if err := saveJSONAtomic(s.tombstonePath(), ids); err != nil {
+		logger.ErrorCF("secops", "Failed to persist proposal tombstones",
+			map[string]interface{}{
+				"path":  s.tombstonePath(),
+				"error": err.Error(),
+			})
+	}
+}
+
+// tombstoned 提案是否已被删除或归档
+func (s *ProposalService) tombstoned(id string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tombstones[id]
+}
+
+// exists 提案是否已存在
+func (s *ProposalService) exists(id string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.proposals[id]
+	return ok || s.offloaded[id] != nil
+}
+
+// importProposals 批量写入导入的提案, 跳过已存在的 ID, 不发送新提案通知; 返回写入数量
+func (s *ProposalService) importProposals(proposals []*Proposal, by Actor) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []string
+	for _, p := range proposals {
+		if _, ok := s.proposals[p.ID]; ok || s.offloaded[p.ID] != nil {
+			continue
+		}
+		p.CreatedBy = &by
+		s.proposals[p.ID] = p
+		s.touch(p.ID)
+		s.audit.record(ProposalEvent{ProposalID: p.ID, Action: AuditImported, Actor: by, To: p.Status})
+		ids = append(ids, p.ID)
+	}
+	n := len(ids)
+	if n > 0 {
+		s.changed(ids...)
+	}
+
+	logger.InfoCF("secops", "Proposals imported",
+		map[string]interface{}{
+			"count": n,
+		})
+	return n
+}
@@ -0,0 +1,310 @@
+package secops
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestProposalService_OverrideRequiresReason(t *testing.T) {
+	s := NewProposalService()
+	s.SetRequireOverrideReason(true)
+
+	p := NewProposal("risk", "SQL 注入告警", "", nil)
+	p.Recommendation = ActionAccept
+	id := s.Create(p)
+
+	if err := s.Ignore(id, DecisionRequest{Reason: "  "}); !errors.Is(err, ErrOverrideReasonRequired) {
+		t.Fatalf("expected ErrOverrideReasonRequired, got %v", err)
+	}
+	if err := s.Ignore(id, DecisionRequest{Reason: "内部扫描器流量"}); err != nil {
+		t.Fatalf("Ignore failed: %v", err)
+	}
+
+	got, _ := s.Get(id)
+	if got.Decision == nil || !got.Decision.Override || got.Decision.Reason != "内部扫描器流量" {
+		t.Fatalf("unexpected decision: %+v", got.Decision)
+	}
+
+	overrides := s.Overrides("risk", time.Now().Add(-time.Hour))
+	if len(overrides) != 1 || overrides[0].ID != id {
+		t.Errorf("expected 1 override, got %d", len(overrides))
+	}
+	if len(s.Overrides("weak", time.Time{})) != 0 {
+		t.Errorf("expected no weak overrides")
+	}
+}
+
+func TestProposalService_AgreeingDecisionNeedsNoReason(t *testing.T) {
+	s := NewProposalService()
+	s.SetRequireOverrideReason(true)
+
+	p := NewProposal("risk", "SQL 注入告警", "", nil)
+	p.Recommendation = ActionAccept
+	id := s.Create(p)
+
+	if err := s.Accept(id, DecisionRequest{}); err != nil {
+		t.Fatalf("Accept failed: %v", err)
+	}
+	got, _ := s.Get(id)
+	if got.Decision == nil || got.Decision.Override {
+		t.Errorf("expected non-override decision, got %+v", got.Decision)
+	}
+}
+
+func TestProposalService_TemplateDefaults(t *testing.T) {
+	s := NewProposalService()
+	s.SetRequireOverrideReason(true)
+	s.SetTemplates(map[string][]config.ActionTemplateConfig{
+		"weak": {{
+			Name:   "scanner",
+			Action: ActionIgnore,
+			Note:   "扫描器噪声",
+			Params: map[string]string{"ignore_days": "30", "scope": "source_ip"},
+		}},
+	})
+
+	p := NewProposal("weak", "弱口令", "", nil)
+	p.Recommendation = ActionAccept
+	p.Parameters = map[string]Param{
+		"ignore_days": {Key: "ignore_days", Value: "7"},
+		"comment":     {Key: "comment", Value: "default"},
+	}
+	id := s.Create(p)
+
+	if err := s.Accept(id, DecisionRequest{Template: "scanner"}); err == nil {
+		t.Fatal("expected ignore-only template to be rejected for accept")
+	}
+
+	err := s.Ignore(id, DecisionRequest{
+		Template: "scanner",
+		Params:   map[string]string{"scope": "asset"},
+	})
+	if err != nil {
+		t.Fatalf("Ignore failed: %v", err)
+	}
+
+	got, _ := s.Get(id)
+	d := got.Decision
+	if d.Reason != "扫描器噪声" || d.Template != "scanner" {
+		t.Errorf("unexpected decision: %+v", d)
+	}
+	want := map[string]string{"ignore_days": "30", "scope": "asset", "comment": "default"}
+	for k, v := range want {
+		if d.Params[k] != v {
+			t.Errorf("param %s = %q, want %q", k, d.Params[k], v)
+		}
+	}
+}
+
+func TestProposalService_AcceptWithEditedParams(t *testing.T) {
+	s := NewProposalService()
+	p := NewProposal("risk", "撞库攻击", "", nil)
+	p.Parameters = map[string]Param{
+		"block_hours": {Key: "block_hours", Label: "封禁时长", Type: "number", Value: "24"},
+		"scope":       {Key: "scope", Type: "select", Value: "ip", Options: []string{"ip", "subnet"}},
+		"comment":     {Key: "comment", Value: "auto"},
+	}
+	id := s.Create(p)
+
+	for _, params := range []map[string]string{
+		{"block_hours": "一天"},
+		{"block_hours": "NaN"},
+		{"block_hours": "+Inf"},
+		{"block_hours": "0x48"},
+		{"block_hours": "1e999"},
+		{"scope": "asn"},
+	} {
+		if err := s.Accept(id, DecisionRequest{Params: params}); !errors.Is(err, ErrInvalidParam) {
+			t.Errorf("%v: expected ErrInvalidParam, got %v", params, err)
+		}
+	}
+	if got, _ := s.Get(id); got.Status != ProposalStatusPending {
+		t.Fatalf("invalid params decided %s", got.Status)
+	}
+
+	err := s.Accept(id, DecisionRequest{Params: map[string]string{"block_hours": " 72 ", "scope": "subnet", "comment": "auto"}})
+	if err != nil {
+		t.Fatalf("Accept failed: %v", err)
+	}
+	got, _ := s.Get(id)
+	d := got.Decision
+	if d.Params["block_hours"] != "72" || d.Params["scope"] != "subnet" {
+		t.Errorf("params = %v", d.Params)
+	}
+	want := []ParamOverride{{Key: "block_hours", From: "24", To: "72"}, {Key: "scope", From: "ip", To: "subnet"}}
+	if len(d.Overrides) != len(want) || d.Overrides[0] != want[0] || d.Overrides[1] != want[1] {
+		t.Errorf("overrides = %+v", d.Overrides)
+	}
+	history := s.History(id)
+	if last := history[len(history)-1]; last.Detail != `edited: block_hours: "24" → "72", scope: "ip" → "subnet"` {
+		t.Errorf("audit detail = %q", last.Detail)
+	}
+}
+
+func TestProposalService_DecisionHandlerSnapshot(t *testing.T) {
+	s := NewProposalService()
+	var got *Proposal
+	s.SetDecisionHandler(func(p *Proposal) { got = p })
+
+	id := s.Create(NewProposal("app", "新应用", "", map[string]interface{}{"host": "a.example.com"}))
+	if err := s.Accept(id, DecisionRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	// 处理函数拿到的是快照, 之后的修改不影响它
+	if _, err := s.AddComment(id, "已通知应用负责人", Actor{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := s.Get(id)
+	if got == nil || got == stored || len(got.Comments) != 0 || got.Status != ProposalStatusAccepted {
+		t.Errorf("handler proposal = %+v", got)
+	}
+}
+
+func TestProposalService_SavesBatched(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "proposals.json")
+	s := NewProposalService()
+	if err := s.EnablePersistence(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// 连续变更合并为一次写入, 写入在锁外的定时器中完成
+	for i := 0; i < 20; i++ {
+		s.Create(NewProposal("risk", "批量", "", nil))
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("store written synchronously: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if data, err := os.ReadFile(path); err == nil {
+			var saved []*Proposal
+			if err := json.Unmarshal(data, &saved); err != nil || len(saved) != 20 {
+				t.Fatalf("saved %d proposals, err %v", len(saved), err)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("store not saved after the batching delay")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 没有未写入的变更时 Flush 不重写文件
+	os.Remove(path)
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("clean store rewritten: %v", err)
+	}
+}
+
+func TestProposalService_PersistenceReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secops", "proposals.json")
+
+	s := NewProposalService()
+	if err := s.EnablePersistence(path); err != nil {
+		t.Fatalf("EnablePersistence failed: %v", err)
+	}
+	id := s.Create(NewProposal("risk", "持久化", "", nil))
+	if err := s.Accept(id, DecisionRequest{Reason: "确认"}); err != nil {
+		t.Fatalf("Accept failed: %v", err)
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	reloaded := NewProposalService()
+	if err := reloaded.EnablePersistence(path); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	p, ok := reloaded.Get(id)
+	if !ok {
+		t.Fatal("proposal not reloaded")
+	}
+	if p.Status != ProposalStatusAccepted || p.Decision == nil || p.Decision.Reason != "确认" {
+		t.Errorf("unexpected reloaded proposal: status=%s decision=%+v", p.Status, p.Decision)
+	}
+}
+
+func TestProposalService_GetFiltered(t *testing.T) {
+	s := NewProposalService()
+	base := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	for i, spec := range []struct {
+		typ, severity string
+		confidence    int
+	}{
+		{"risk", SeverityLow, 90}, {"weak", SeverityCritical, 60}, {"risk", SeverityHigh, 0}, {"app", "", 0}, {"risk", SeverityMedium, 80},
+	} {
+		p := NewProposal(spec.typ, "p"+string(rune('a'+i)), "", nil)
+		p.ID = "id" + string(rune('0'+i))
+		p.Severity = spec.severity
+		p.Confidence = spec.confidence
+		p.CreatedAt = base.Add(time.Duration(i) * time.Hour)
+		s.Create(p)
+	}
+	if err := s.Ignore("id2", DecisionRequest{}); err != nil {
+		t.Fatal(err)
+	}
+
+	ids := func(ps []*Proposal) string {
+		var out string
+		for _, p := range ps {
+			out += p.ID + " "
+		}
+		return out
+	}
+
+	// 默认按创建时间倒序
+	page, total, err := s.GetFiltered(ProposalFilter{Limit: 2})
+	if err != nil || total != 5 || ids(page) != "id4 id3 " {
+		t.Errorf("default: %s total=%d err=%v", ids(page), total, err)
+	}
+	page, _, _ = s.GetFiltered(ProposalFilter{Offset: 4, Limit: 2})
+	if ids(page) != "id0 " {
+		t.Errorf("last page: %s", ids(page))
+	}
+
+	page, total, _ = s.GetFiltered(ProposalFilter{Types: []string{"risk"}, Statuses: []ProposalStatus{ProposalStatusPending}})
+	if total != 2 || ids(page) != "id4 id0 " {
+		t.Errorf("type+status: %s total=%d", ids(page), total)
+	}
+
+	page, _, _ = s.GetFiltered(ProposalFilter{Since: base.Add(time.Hour), Until: base.Add(3 * time.Hour), Sort: "created_at"})
+	if ids(page) != "id1 id2 " {
+		t.Errorf("range: %s", ids(page))
+	}
+
+	page, _, _ = s.GetFiltered(ProposalFilter{Sort: "-severity"})
+	if ids(page) != "id1 id2 id4 id0 id3 " {
+		t.Errorf("severity: %s", ids(page))
+	}
+
+	page, _, _ = s.GetFiltered(ProposalFilter{Severities: []string{SeverityCritical, SeverityMedium}, Confidence: 70})
+	if ids(page) != "id4 " {
+		t.Errorf("severity+confidence: %s", ids(page))
+	}
+	page, _, _ = s.GetFiltered(ProposalFilter{Sort: "-confidence", Limit: 3})
+	if ids(page) != "id0 id4 id1 " {
+		t.Errorf("confidence: %s", ids(page))
+	}
+
+	for _, f := range []ProposalFilter{
+		{Statuses: []ProposalStatus{"done"}},
+		{Types: []string{"nope"}},
+		{Severities: []string{"urgent"}},
+		{Confidence: 101},
+		{Sort: "-priority"},
+		{Since: base, Until: base},
+	} {
+		if _, _, err := s.GetFiltered(f); err == nil {
+			t.Errorf("%+v: expected error", f)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package secops
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/tools"
+)
+
+// ProposalTool 供 Agent 在本地提案队列中创建待人工确认的提案
+type ProposalTool struct {
+	service *Service
+	channel string
+	chatID  string
+}
+
+// NewProposalTool 创建提案工具
+func NewProposalTool(service *Service) *ProposalTool {
+	return &ProposalTool{service: service}
+}
+
+// Name 工具名称
+func (t *ProposalTool) Name() string {
+	return "secops_proposal"
+}
+
+// Description 工具描述
+func (t *ProposalTool) Description() string {
+	return `在本地提案队列中创建提案, 交由分析师在 Debug UI 中确认或忽略 (manual 模式使用)。
+- type: 提案类型 risk, weak, api_biz, app, host (Wazuh 主机告警), k8s (Kubernetes 审计), cloud (云安全发现)
+- title / summary: 单行纯文本标题和 Markdown 摘要; 摘要不使用标题、表格、HTML 和图片, 原始数据放在 evidence 中。
+  超出长度、缺少必填字段或格式不符时工具返回具体问题, 按提示修改后重新调用
+- severity: 严重级别 critical, high, medium, low, info, 决定通知的路由和分析师的处理顺序
+- confidence: 对研判结论的置信度 0-100, 如证据充分的已确认攻击 90, 仅凭单条日志推测 40
+- recommendation: 建议的处置 accept 或 ignore
+- details: 结构化详情, 如 host、ip、url
+- evidence: 证据列表, 每项包含 label 和 content (SQL、HTTP 报文或 JSON); weak 提案请附上触发弱点的完整 HTTP 请求报文,
+  据此生成去除凭据的 curl 复现命令
+- case_id: 可选, 同一案件的提案使用相同 case_id
+- techniques: 可选, 涉及的 MITRE ATT&CK 技术编号, 如 ["T1190", "T1059.004"]; 战术由技术推导, 未填写时按关键词自动分类
+- items: 可选, 一个提案覆盖多条事件时 (如批量确认 20 条风险) 每条事件的 API 参数, 决策后逐条执行
+- accept_api / ignore_api: 可选, 分析师确认/忽略后调用的 sheikah_api 标识, 默认按类型绑定 (如 risk 为 confirm_risk / ignore_risk);
+  details 中的字段作为 API 参数
+- artifacts: 可选, 确认后提交到 Git 仓库的规则和配置, 每项包含 kind (waf_rule, sigma_rule, suppression, sql_template)、
+  name (文件名) 和 content (完整文件内容); 需开启 secops.git`
+}
+
+// Parameters 参数定义
+func (t *ProposalTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"type": map[string]interface{}{
+				"type": "string",
+				"enum": []string{"risk", "weak", "api_biz", "app", "host", "k8s", "cloud"},
+			},
+			"title": map[string]interface{}{
+				"type": "string",
+			},
+			"summary": map[string]interface{}{
+				"type":        "string",
+				"description": "Markdown 摘要, 说明研判依据",
+			},
+			"severity": map[string]interface{}{
+				"type": "string",
+				"enum": []string{SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow, SeverityInfo},
+			},
+			"confidence": map[string]interface{}{
+				"type":        "integer",
+				"minimum":     0,
+				"maximum":     100,
+				"description": "研判结论的置信度 0-100",
+			},
+			"recommendation": map[string]interface{}{
+				"type": "string",
+				"enum": []string{ActionAccept, ActionIgnore},
+			},
+			"details": map[string]interface{}{
+				"type": "object",
+			},
+			"evidence": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"label":   map[string]interface{}{"type": "string"},
+						"content": map[string]interface{}{"type": "string"},
+					},
+					"required": []string{"content"},
+				},
+			},
+			"case_id": map[string]interface{}{
+				"type": "string",
+			},
+			"techniques": map[string]interface{}{
+				"type":        "array",
+				"description": "MITRE ATT&CK 技术编号, 如 T1190、T1110、T1059.004",
+				"items":       map[string]interface{}{"type": "string"},
+			},
+			"items": map[string]interface{}{
+				"type":        "array",
+				"description": "批量提案的各条目参数, 如 [{\"host\": \"...\", \"content\": \"...\", \"risk\": \"...\"}]",
+				"items":       map[string]interface{}{"type": "object"},
+			},
+			"artifacts": map[string]interface{}{
+				"type":        "array",
+				"description": "确认后提交到 Git 仓库的规则和配置",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"kind": map[string]interface{}{
+							"type": "string",
+							"enum": []string{ArtifactWAFRule, ArtifactSigmaRule, ArtifactSuppression, ArtifactSQLTemplate},
+						},
+						"name":    map[string]interface{}{"type": "string"},
+						"content": map[string]interface{}{"type": "string"},
+					},
+					"required": []string{"kind", "name", "content"},
+				},
+			},
+			"accept_api": map[string]interface{}{
+				"type":        "string",
+				"description": "确认后调用的 sheikah_api 标识",
+			},
+			"ignore_api": map[string]interface{}{
+				"type":        "string",
+				"description": "忽略后调用的 sheikah_api 标识",
+			},
+		},
+		"required": []string{"type", "title", "summary"},
+	}
+}
+
+// SetContext 记录调用方会话, 活动执行时 chatID 为活动名
+func (t *ProposalTool) SetContext(channel, chatID string) {
+	t.channel = channel
+	t.chatID = chatID
+}
+
+// Execute 创建提案
+func (t *ProposalTool) Execute(ctx context.Context, args map[string]interface{}) *tools.ToolResult {
+	proposalType, _ := args["type"].(string)
+	title, _ := args["title"].(string)
+	summary, _ := args["summary"].(string)
+	severity, _ := args["severity"].(string)
+	recommendation, _ := args["recommendation"].(string)
+	caseID, _ := args["case_id"].(string)
+	details, _ := args["details"].(map[string]interface{})
+
+	if !validProposalTypes[proposalType] {
+		return tools.ErrorResult(fmt.Sprintf("invalid proposal type: %q", proposalType))
+	}
+	if strings.TrimSpace(title) == "" {
+		return tools.ErrorResult("title is required")
+	}
+	if severity != "" && !validSeverities[severity] {
+		return tools.ErrorResult(fmt.Sprintf("invalid severity: %q", severity))
+	}
+	confidence, err := confidenceArg(args["confidence"])
+	if err != nil {
+		return tools.ErrorResult(err.Error())
+	}
+	if recommendation != "" && recommendation != ActionAccept && recommendation != ActionIgnore {
+		return tools.ErrorResult(fmt.Sprintf("invalid recommendation: %q", recommendation))
+	}
+	activity := activityFromContext(ctx)
+	if activity == "" && t.channel == "secops" {
+		activity = t.chatID
+	}
+	if lang := t.service.outputLanguage(activity); !matchesLanguage(title+"\n"+summary, lang) {
+		return tools.ErrorResult(fmt.Sprintf("title and summary must be written in the working language %s (%s); rewrite them in %s and call again",
+			lang, languageName(lang), languageName(lang)))
+	}
+
+	var techniques []string
+	if items, ok := args["techniques"].([]interface{}); ok {
+		for _, item := range items {
+			if s, ok := item.(string); ok {
+				techniques = append(techniques, s)
+			}
+		}
+	}
+	techniques, err = normalizeTechniques(techniques)
+	if err != nil {
+		return tools.ErrorResult(err.Error())
+	}
+
+	p := NewProposal(proposalType, title, summary, details)
+	p.ID = uuid.New().String()
+	p.Techniques = techniques
+	p.Severity = severity
+	p.Confidence = confidence
+	p.Recommendation = recommendation
+	p.CaseID = caseID
+
+	if items, ok := args["items"].([]interface{}); ok {
+		for _, item := range items {
+			fields, _ := item.(map[string]interface{})
+			if len(fields) == 0 {
+				continue
+			}
+			params := make(map[string]string, len(fields))
+			for k, v := range fields {
+				params[k] = fmt.Sprint(v)
+			}
+			p.Items = append(p.Items, params)
+		}
+	}
+
+	acceptAPI, _ := args["accept_api"].(string)
+	ignoreAPI, _ := args["ignore_api"].(string)
+	if acceptAPI != "" || ignoreAPI != "" {
+		b := defaultBinding(p)
+		if b == nil {
+			b = &ActionBinding{}
+		}
+		if acceptAPI != "" {
+			b.Accept = acceptAPI
+		}
+		if ignoreAPI != "" {
+			b.Ignore = ignoreAPI
+		}
+		p.Binding = b
+	}
+
+	if items, ok := args["evidence"].([]interface{}); ok {
+		for _, item := range items {
+			ev, _ := item.(map[string]interface{})
+			label, _ := ev["label"].(string)
+			content, _ := ev["content"].(string)
+			if content != "" {
+				p.AddEvidence(label, content)
+			}
+		}
+	}
+
+	if items, ok := args["artifacts"].([]interface{}); ok {
+		for _, item := range items {
+			fields, _ := item.(map[string]interface{})
+			a := Artifact{}
+			a.Kind, _ = fields["kind"].(string)
+			a.Name, _ = fields["name"].(string)
+			a.Content, _ = fields["content"].(string)
+			if err := validateArtifact(a); err != nil {
+				return tools.ErrorResult(err.Error())
+			}
+			p.Artifacts = append(p.Artifacts, a)
+		}
+	}
+
+	if violations := t.service.constraints.check(p); len(violations) > 0 {
+		logger.WarnCF("secops", "Proposal rejected by constraints",
+			map[string]interface{}{
+				"type":       p.Type,
+				"title":      p.Title,
+				"violations": strings.Join(violations, "; "),
+			})
+		return tools.ErrorResult("proposal rejected, fix the following and call secops_proposal again:\n- " + strings.Join(violations, "\n- "))
+	}
+
+	if t.channel == "secops" {
+		p.RunID = t.service.runs.attachProposal(t.chatID, p.ID)
+		p.CreatedBy = &Actor{Name: t.chatID, Via: ViaActivity}
+	} else {
+		p.CreatedBy = &Actor{Name: t.channel + ":" + t.chatID, Via: ViaChat}
+	}
+	id := t.service.CreateProposal(p)
+
+	return tools.SilentResult(fmt.Sprintf("proposal created: %s", id))
+}
+
+// confidenceArg 解析置信度参数, 模型可能以数字或字符串传入; 未传时为 0
+func confidenceArg(v interface{}) (int, error) {
+	var f float64
+	switch n := v.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		f = n
+	case int:
+		f = float64(n)
+	case string:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(n, "%")), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid confidence: %q, use an integer 0-100", n)
+		}
+		f = parsed
+	default:
+		return 0, fmt.Errorf("invalid confidence: %v, use an integer 0-100", v)
+	}
+	if f < 0 || f > 100 || f != math.Trunc(f) {
+		return 0, fmt.Errorf("invalid confidence: %v, use an integer 0-100", v)
+	}
+	return int(f), nil
+}
@@ -0,0 +1,70 @@
+package secops
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestProposalTool_LinksActiveRun(t *testing.T) {
+	svc := &Service{config: &config.SecOpsConfig{}, proposalService: NewProposalService(), runs: newRunStore()}
+	run := svc.runs.start("risk_analysis")
+
+	tool := NewProposalTool(svc)
+	tool.SetContext("secops", "risk_analysis")
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"type":           "risk",
+		"title":          "撞库攻击",
+		"summary":        "同一 IP 短时间内尝试 300 个账号",
+		"recommendation": "accept",
+		"details":        map[string]interface{}{"ip": "203.0.113.7"},
+		"evidence": []interface{}{
+			map[string]interface{}{"label": "登录日志", "content": "SELECT * FROM login_events"},
+		},
+	})
+	if result.IsError {
+		t.Fatalf("Execute failed: %s", result.ForLLM)
+	}
+
+	svc.runs.finish(run, "done", nil)
+	got, _ := svc.Run(run.ID)
+	if len(got.ProposalIDs) != 1 {
+		t.Fatalf("expected proposal linked to run, got %+v", got)
+	}
+	p, ok := svc.proposalService.Get(got.ProposalIDs[0])
+	if !ok || p.RunID != run.ID || p.Recommendation != ActionAccept || len(p.Evidence) != 1 {
+		t.Errorf("unexpected proposal: %+v", p)
+	}
+	if p.Evidence[0].ContentType != EvidenceTypeSQL {
+		t.Errorf("evidence type = %s, want sql", p.Evidence[0].ContentType)
+	}
+
+	bad := tool.Execute(context.Background(), map[string]interface{}{"type": "other", "title": "x", "summary": "y"})
+	if !bad.IsError {
+		t.Error("expected invalid type to be rejected")
+	}
+}
+
+func TestProposalTool_Confidence(t *testing.T) {
+	svc := &Service{config: &config.SecOpsConfig{}, proposalService: NewProposalService(), runs: newRunStore()}
+	tool := NewProposalTool(svc)
+	tool.SetContext("secops", "risk_analysis")
+
+	args := map[string]interface{}{"type": "risk", "title": "撞库攻击", "summary": "同一 IP 尝试 300 个账号", "severity": SeverityHigh}
+	for _, v := range []interface{}{150.0, 85.5, "很高"} {
+		args["confidence"] = v
+		if res := tool.Execute(context.Background(), args); !res.IsError {
+			t.Errorf("confidence %v: expected error", v)
+		}
+	}
+
+	args["confidence"] = "85"
+	if res := tool.Execute(context.Background(), args); res.IsError {
+		t.Fatalf("Execute failed: %s", res.ForLLM)
+	}
+	all := svc.proposalService.GetAll()
+	if len(all) != 1 || all[0].Confidence != 85 || all[0].Severity != SeverityHigh {
+		t.Errorf("proposals = %+v", all)
+	}
+}
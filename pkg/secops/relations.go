@@ -0,0 +1,76 @@
+package secops
+
+import (
+	"fmt"
+	"sort"
+)
+
+// relatedKeys 用于关联提案的详情字段, 任一字段取值相同即视为相关
+var relatedKeys = []string{"host", "ip", "src_ip", "dst_ip", "url", "api", "app"}
+
+// Related 获取与提案指向同一目标 (主机/IP/URL 等) 的其他提案, 按创建时间倒序
+func (s *ProposalService) Related(id string, limit int) []*Proposal {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.proposals[id]
+	if !ok {
+		return nil
+	}
+
+	keys := relationKeys(p)
+	result := make([]*Proposal, 0)
+	if len(keys) == 0 {
+		return result
+	}
+	for _, other := range s.proposals {
+		if other.ID == p.ID {
+			continue
+		}
+		for k := range relationKeys(other) {
+			if keys[k] {
+				result = append(result, other)
+				break
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// Case 获取同一案件下的全部提案, 按创建时间升序
+func (s *ProposalService) Case(caseID string) []*Proposal {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*Proposal, 0)
+	if caseID == "" {
+		return result
+	}
+	for _, p := range s.proposals {
+		if p.CaseID == caseID {
+			result = append(result, p)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result
+}
+
+// relationKeys 提取提案的关联键, 形如 "host=example.com"
+func relationKeys(p *Proposal) map[string]bool {
+	keys := make(map[string]bool)
+	for _, k := range relatedKeys {
+		if v, ok := p.Details[k]; ok && v != nil && v != "" {
+			keys[k+"="+fmt.Sprint(v)] = true
+		}
+	}
+	return keys
+}
@@ -0,0 +1,277 @@
+package secops
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/tools/secops"
+)
+
+// reloadableSections 可热加载的配置段 (JSON 键名), 其余配置段变更后需重启生效
+var reloadableSections = map[string]bool{
+	"activities":   true,
+	"calendars":    true,
+	"clickhouse":   true,
+	"data_sources": true,
+	"features":     true,
+	"playbooks":    true,
+	"sheikah":      true,
+}
+
+// toolSections 变更后需要重建 query_data 和 sheikah_api 工具的配置段
+var toolSections = []string{"clickhouse", "data_sources", "sheikah"}
+
+// toolRetireDelay 热加载替换下的旧工具延迟关闭, 进行中的分析仍可完成当前的工具调用
+const toolRetireDelay = 5 * time.Minute
+
+// ReloadResult 配置热加载结果
+type ReloadResult struct {
+	Started         []string `json:"started"`         // 新增或在配置中启用的活动
+	Restarted       []string `json:"restarted"`       // 调度、模式或工作日历变更后重启调度的活动
+	Stopped         []string `json:"stopped"`         // 删除或在配置中停用的活动
+	Updated         []string `json:"updated"`         // 只变更了钩子、试运行或剧本, 下次执行时生效
+	ToolsReloaded   bool     `json:"toolsReloaded"`   // 重新注册了 query_data 和 sheikah_api
+	RestartRequired []string `json:"restartRequired"` // 已变更但需重启才生效的配置段
+}
+
+// Reload 热加载安全运营配置: 只重启调度有变化的活动, 进行中的执行不受影响;
+// 数据源、SQL 模板或 API 定义变更时重建工具并整体替换。
+// 全部校验通过后才生效, 校验失败时保持原配置不变。
+func (s *Service) Reload(cfg *config.SecOpsConfig) (*ReloadResult, error) {
+	if s == nil {
+		return nil, fmt.Errorf("secops service is not running")
+	}
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	changed, err := changedSections(s.config, cfg)
+	if err != nil {
+		return nil, err
+	}
+	result := &ReloadResult{
+		Started:         []string{},
+		Restarted:       []string{},
+		Stopped:         []string{},
+		Updated:         []string{},
+		RestartRequired: []string{},
+	}
+	for _, section := range changed {
+		if !reloadableSections[section] {
+			result.RestartRequired = append(result.RestartRequired, section)
+		}
+	}
+
+	// 只替换可热加载的配置段, 其余保持启动时的值
+	next := *s.config
+	next.Activities = cfg.Activities
+	next.Calendars = cfg.Calendars
+	next.ClickHouse = cfg.ClickHouse
+	next.DataSources = cfg.DataSources
+	next.Sheikah = cfg.Sheikah
+	next.Features = cfg.Features
+	next.Playbooks = cfg.Playbooks
+
+	calendars, err := newCalendars(&next)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secops calendars: %w", err)
+	}
+	if err := (&Service{config: &next}).validateHooks(); err != nil {
+		return nil, fmt.Errorf("invalid secops hooks: %w", err)
+	}
+	if err := validateFeatures(&next); err != nil {
+		return nil, fmt.Errorf("invalid secops features: %w", err)
+	}
+	if err := validatePlaybooks(&next, s.workspace); err != nil {
+		return nil, fmt.Errorf("invalid secops playbook: %w", err)
+	}
+	if err := validateLanguages(&next); err != nil {
+		return nil, fmt.Errorf("invalid secops language: %w", err)
+	}
+	if err := validateWindows(&next); err != nil {
+		return nil, fmt.Errorf("invalid secops window: %w", err)
+	}
+
+	var queryTool *secops.SecOpsQueryDataTool
+	var apiTool *secops.SecOpsSheikahAPITool
+	if slices.ContainsFunc(changed, func(section string) bool { return slices.Contains(toolSections, section) }) {
+		queryTool, apiTool, err = s.newTools(&next)
+		if err != nil {
+			return nil, fmt.Errorf("failed to init secops tools: %w", err)
+		}
+		if next.Demo {
+			queryTool.SetTransport(demoClickHouseTransport{})
+			apiTool.SetTransport(demoSheikahTransport{})
+		}
+		s.wrapChaosTransports(queryTool, apiTool)
+	}
+
+	// 原地替换可热加载的配置段, 其余配置段的读取不受影响
+	prev := s.activityConfigs()
+	s.configMu.Lock()
+	s.config.Activities = next.Activities
+	s.config.Calendars = next.Calendars
+	s.config.ClickHouse = next.ClickHouse
+	s.config.DataSources = next.DataSources
+	s.config.Sheikah = next.Sheikah
+	s.config.Features = next.Features
+	s.config.Playbooks = next.Playbooks
+	s.calendars = calendars
+	s.configMu.Unlock()
+	s.features.setConfig(next.Features)
+
+	s.mu.Lock()
+	if s.started && s.ctx.Err() == nil {
+		s.reloadActivitiesLocked(prev, result)
+	}
+	s.mu.Unlock()
+
+	if queryTool != nil {
+		oldQuery, oldAPI := s.tools()
+		s.registerTools(queryTool, apiTool)
+		s.retireTools(oldQuery, oldAPI)
+		result.ToolsReloaded = true
+	}
+
+	logger.InfoCF("secops", "SecOps config reloaded",
+		map[string]interface{}{
+			"started":          result.Started,
+			"restarted":        result.Restarted,
+			"stopped":          result.Stopped,
+			"updated":          result.Updated,
+			"tools_reloaded":   result.ToolsReloaded,
+			"restart_required": result.RestartRequired,
+		})
+	if len(result.RestartRequired) > 0 {
+		logger.WarnCF("secops", "Some secops config changes take effect only after restart",
+			map[string]interface{}{"sections": result.RestartRequired})
+	}
+	return result, nil
+}
+
+// reloadActivitiesLocked 按新旧配置调整活动调度, 调用方需持有 s.mu;
+// 重启的活动不立即执行, 避免与尚未结束的执行重叠
+func (s *Service) reloadActivitiesLocked(prev map[string]config.ActivityConfig, result *ReloadResult) {
+	activities := s.activityConfigs()
+	names := make(map[string]bool)
+	for name := range prev {
+		names[name] = true
+	}
+	for name := range activities {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	for _, name := range sorted {
+		actCfg, exists := activities[name]
+		want := exists && s.activityEnabled(name)
+		activity, running := s.activities[name]
+		old := prev[name]
+
+		switch {
+		case !want && running:
+			close(activity.stopCh)
+			delete(s.activities, name)
+			result.Stopped = append(result.Stopped, name)
+		case want && !running:
+			s.startActivityLocked(name, actCfg, true)
+			result.Started = append(result.Started, name)
+		case want && running:
+			if old.Schedule != actCfg.Schedule || old.Mode != actCfg.Mode || old.Calendar != actCfg.Calendar ||
+				old.Window != actCfg.Window {
+				close(activity.stopCh)
+				s.startActivityLocked(name, actCfg, false)
+				result.Restarted = append(result.Restarted, name)
+			} else if !reflect.DeepEqual(old, actCfg) {
+				result.Updated = append(result.Updated, name)
+			}
+		}
+	}
+}
+
+// activityConfigs 当前生效的活动配置; 热加载时整体替换, 返回的 map 不会再被修改
+func (s *Service) activityConfigs() map[string]config.ActivityConfig {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	return s.config.Activities
+}
+
+// activityConfig 当前生效的单个活动配置
+func (s *Service) activityConfig(name string) (config.ActivityConfig, bool) {
+	actCfg, ok := s.activityConfigs()[name]
+	return actCfg, ok
+}
+
+// tools 当前注册的 query_data 和 sheikah_api 工具
+func (s *Service) tools() (*secops.SecOpsQueryDataTool, *secops.SecOpsSheikahAPITool) {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	return s.queryTool, s.apiTool
+}
+
+// retireTools 延迟关闭被替换的工具, 服务停止时立即关闭
+func (s *Service) retireTools(queryTool *secops.SecOpsQueryDataTool, apiTool *secops.SecOpsSheikahAPITool) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		timer := time.NewTimer(toolRetireDelay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-s.ctx.Done():
+		}
+		if queryTool != nil {
+			queryTool.Close()
+		}
+		if apiTool != nil {
+			apiTool.Close()
+		}
+	}()
+}
+
+// changedSections 比较两份配置, 返回内容不同的配置段 (JSON 键名), 按名称排序
+func changedSections(a, b *config.SecOpsConfig) ([]string, error) {
+	sectionsOf := func(cfg *config.SecOpsConfig) (map[string]json.RawMessage, error) {
+		data, err := json.Marshal(cfg)
+		if err != nil {
+			return nil, err
+		}
+		var sections map[string]json.RawMessage
+		if err := json.Unmarshal(data, &sections); err != nil {
+			return nil, err
+		}
+		return sections, nil
+	}
+	before, err := sectionsOf(a)
+	if err != nil {
+		return nil, err
+	}
+	after, err := sectionsOf(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for key, v := range after {
+		if !bytes.Equal(before[key], v) {
+			changed = append(changed, key)
+		}
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
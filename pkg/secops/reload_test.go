@@ -0,0 +1,107 @@
+package secops
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/agent"
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+func TestReload(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	cfg.SecOps = config.SecOpsConfig{
+		Enabled:    true,
+		ClickHouse: config.ClickHouseConfig{Addr: "127.0.0.1:1"},
+		Sheikah:    config.SheikahConfig{BaseURL: "http://127.0.0.1:1"},
+		Activities: map[string]config.ActivityConfig{
+			"risk_analysis": {Enabled: true, Schedule: "1h"},
+			"weak_analysis": {Enabled: true, Schedule: "1h"},
+			"api_analysis":  {Enabled: false, Schedule: "1h"},
+			"app_analysis":  {Enabled: true, Schedule: "1h"},
+		},
+	}
+	provider, err := providers.NewScriptedProvider(providers.ScriptedScript{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgBus := bus.NewMessageBus()
+	svc, err := NewService(&cfg.SecOps, agent.NewAgentLoop(cfg, msgBus, provider), msgBus, cfg.WorkspacePath())
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	if err := svc.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer svc.Stop()
+	oldAPITool := svc.apiTool
+
+	next := cfg.SecOps
+	next.Activities = map[string]config.ActivityConfig{
+		"risk_analysis": {Enabled: true, Schedule: "2h"},
+		"api_analysis":  {Enabled: true, Schedule: "1h"},
+		"app_analysis":  {Enabled: true, Schedule: "1h", DryRun: true},
+	}
+	next.Sheikah.APIs = map[string]config.SheikahAPIConfig{
+		"block_ip": {Method: "POST", Path: "/block", Body: `{"ip": {{str .ip}}}`},
+	}
+	next.Playbooks = config.PlaybookTrustConfig{RequireSignature: true}
+	next.DebugUI.Port = 18790
+
+	res, err := svc.Reload(&next)
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	for _, c := range []struct {
+		name string
+		got  []string
+		want string
+	}{
+		{"started", res.Started, "api_analysis"},
+		{"restarted", res.Restarted, "risk_analysis"},
+		{"stopped", res.Stopped, "weak_analysis"},
+		{"updated", res.Updated, "app_analysis"},
+		{"restart required", res.RestartRequired, "debugui"},
+	} {
+		if got := strings.Join(c.got, ","); got != c.want {
+			t.Errorf("%s = %q, want %q", c.name, got, c.want)
+		}
+	}
+	if _, apiTool := svc.tools(); !res.ToolsReloaded || apiTool == oldAPITool {
+		t.Error("sheikah_api not re-registered")
+	}
+	if _, err := svc.apiTool.Render("block_ip", map[string]string{"ip": "1.2.3.4"}); err != nil {
+		t.Errorf("new api definition not available: %v", err)
+	}
+	if !svc.config.Playbooks.RequireSignature {
+		t.Error("playbooks trust config not reloaded")
+	}
+
+	svc.mu.RLock()
+	if a := svc.activities["risk_analysis"]; a == nil || a.interval.Hours() != 2 || a.runNow {
+		t.Errorf("risk_analysis = %+v, want restarted with 2h interval", a)
+	}
+	if _, ok := svc.activities["weak_analysis"]; ok {
+		t.Error("weak_analysis still scheduled")
+	}
+	svc.mu.RUnlock()
+
+	// 重复加载相同配置不做任何变更
+	res, err = svc.Reload(&next)
+	if err != nil || len(res.Started)+len(res.Restarted)+len(res.Stopped)+len(res.Updated) != 0 || res.ToolsReloaded {
+		t.Errorf("second reload = %+v, %v", res, err)
+	}
+
+	// 校验失败时保持原配置
+	bad := next
+	bad.Activities = map[string]config.ActivityConfig{"risk_analysis": {Enabled: true, Schedule: "1h", Calendar: "missing"}}
+	if _, err := svc.Reload(&bad); err == nil {
+		t.Fatal("expected error for unknown calendar")
+	}
+	if actCfg, _ := svc.activityConfig("risk_analysis"); actCfg.Schedule != "2h" {
+		t.Error("invalid reload changed the config")
+	}
+}
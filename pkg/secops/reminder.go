@@ -0,0 +1,62 @@
+package secops
+
+import (
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// reminderInterval 检查提醒和静默到期的周期
+const reminderInterval = time.Minute
+
+// Acknowledge 知悉提案但暂不决策, d 时间内不再提醒
+func (s *Service) Acknowledge(id string, d time.Duration, reason string, by Actor) (*Proposal, error) {
+	return s.proposalService.Acknowledge(id, d, reason, by)
+}
+
+// runReminders 周期解除到期的静默, 并提醒超时未决策的提案
+func (s *Service) runReminders() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(reminderInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.silences.expire(now)
+			s.sendReminders(now)
+		}
+	}
+}
+
+// sendReminders 提醒超时未决策的提案, 跳过已知悉和被静默的提案
+func (s *Service) sendReminders(now time.Time) int {
+	pending := s.proposalService.GetPending()
+	keep := make(map[string]bool, len(pending))
+
+	sent := 0
+	for _, p := range pending {
+		keep[p.ID] = true
+		if ack := p.Acknowledgement; ack != nil && now.Before(ack.Until) {
+			continue
+		}
+		if s.silences.match(p, now) != nil {
+			continue
+		}
+		if s.notifier.Remind(s.ctx, p, now) {
+			sent++
+		}
+	}
+	s.notifier.forget(keep)
+
+	if sent > 0 {
+		logger.InfoCF("secops", "Sent proposal reminders",
+			map[string]interface{}{
+				"count": sent,
+			})
+	}
+	return sent
+}
@@ -0,0 +1,305 @@
+package secops
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 复现命令的生成依据
+const (
+	ReproductionFromEvidence = "evidence" // 证据中的 HTTP 请求报文
+	ReproductionFromDetails  = "details"  // 详情中的 host、method、url
+)
+
+// Reproduction 弱点的复现命令, 凭据和个人信息已替换为占位符
+//
+// 接收工单的应用团队通常无法访问原始流量, 按占位符填入自己测试账号的凭据即可验证问题。
+type Reproduction struct {
+	Curl         string   `json:"curl"`
+	Placeholders []string `json:"placeholders,omitempty"` // 命令中需由验证方填入的占位符
+	Source       string   `json:"source"`                 // 生成依据: evidence 或 details
+}
+
+var (
+	// sensitiveName 携带凭据的请求头、查询参数和 JSON 字段名
+	sensitiveName = regexp.MustCompile(`(?i)^(auth|sign|sid|key|pass|pin)$|authoriz|token|secret|passw|pwd|session|cookie|csrf|xsrf|signature|api[_-]?key|access[_-]?key|private[_-]?key|credential`)
+	// httpMethod 可直接写入命令行的请求方法, 其余取值加引号后使用
+	httpMethod = regexp.MustCompile(`^[A-Z]+$`)
+	// authScheme Authorization 等请求头中保留的认证方式, 只替换其后的凭据
+	authScheme = regexp.MustCompile(`(?i)^(bearer|basic|digest|token)\s+`)
+	// embeddedResponse 证据中紧跟请求之后的响应报文
+	embeddedResponse = regexp.MustCompile(`(?m)^HTTP/\d(\.\d)? \d{3}`)
+)
+
+// droppedHeaders 复现时不需要携带的请求头, 由 curl 自行生成
+var droppedHeaders = map[string]bool{
+	"host":              true,
+	"content-length":    true,
+	"connection":        true,
+	"keep-alive":        true,
+	"accept-encoding":   true,
+	"transfer-encoding": true,
+	"te":                true,
+	"upgrade":           true,
+}
+
+// attachReproduction 为弱点提案生成复现命令; 已有复现命令或缺少请求信息时不生成
+func attachReproduction(p *Proposal) {
+	if p.Type != "weak" || p.Reproduction != nil {
+		return
+	}
+	p.Reproduction = buildReproduction(p)
+}
+
+// buildReproduction 优先使用证据中的 HTTP 请求报文, 没有时按详情中的 method、url 生成
+func buildReproduction(p *Proposal) *Reproduction {
+	details := detailStrings(p.Details)
+	for _, ev := range p.Evidence {
+		if ev.ContentType != EvidenceTypeHTTP {
+			continue
+		}
+		if req, ok := parseHTTPRequest(ev.Content); ok {
+			if req.host == "" {
+				req.host = details["host"]
+			}
+			return req.reproduction(ReproductionFromEvidence)
+		}
+	}
+
+	if details["url"] == "" {
+		return nil
+	}
+	req := &rawRequest{method: strings.ToUpper(details["method"]), host: details["host"]}
+	if req.method == "" {
+		req.method = "GET"
+	}
+	req.setTarget(details["url"])
+	return req.reproduction(ReproductionFromDetails)
+}
+
+// rawRequest 从证据中解析的 HTTP 请求
+type rawRequest struct {
+	method  string
+	scheme  string
+	host    string
+	target  string      // 路径和查询参数
+	headers [][2]string // 按报文顺序
+	body    string
+}
+
+// parseHTTPRequest 解析 HTTP/1.x 请求报文, 忽略其后附带的响应报文
+func parseHTTPRequest(content string) (*rawRequest, bool) {
+	content = strings.ReplaceAll(strings.TrimSpace(content), "\r\n", "\n")
+	if !httpRequestLine.MatchString(content) {
+		return nil, false
+	}
+	head, body, _ := strings.Cut(content, "\n\n")
+	if loc := embeddedResponse.FindStringIndex(body); loc != nil {
+		body = body[:loc[0]]
+	}
+
+	lines := strings.Split(head, "\n")
+	fields := strings.Fields(lines[0])
+	req := &rawRequest{method: fields[0], body: strings.TrimSpace(body)}
+	req.setTarget(fields[1])
+	for _, line := range lines[1:] {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if strings.EqualFold(name, "host") {
+			req.host = value
+			continue
+		}
+		req.headers = append(req.headers, [2]string{name, value})
+	}
+	return req, true
+}
+
+// setTarget 设置请求目标, 绝对地址形式同时给出协议和主机
+func (req *rawRequest) setTarget(target string) {
+	if u, err := url.Parse(target); err == nil && u.Host != "" {
+		req.scheme, req.host = u.Scheme, u.Host
+		target = u.RequestURI()
+	}
+	if !strings.HasPrefix(target, "/") {
+		target = "/" + target
+	}
+	req.target = target
+}
+
+// reproduction 生成脱敏后的 curl 命令
+func (req *rawRequest) reproduction(source string) *Reproduction {
+	r := newRedactor()
+	host := req.host
+	if host == "" {
+		host = "<HOST>"
+	}
+	scheme := req.scheme
+	if scheme == "" {
+		scheme = "https"
+		if strings.HasSuffix(host, ":80") {
+			scheme = "http"
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("curl")
+	if method := req.method; method != "GET" {
+		if !httpMethod.MatchString(method) {
+			method = shellQuote(method)
+		}
+		b.WriteString(" -X " + method)
+	}
+	b.WriteString(" " + shellQuote(scheme+"://"+host+sanitizeTarget(r, req.target)))
+
+	var contentType string
+	for _, h := range req.headers {
+		name, value := h[0], h[1]
+		if droppedHeaders[strings.ToLower(name)] {
+			continue
+		}
+		if strings.EqualFold(name, "content-type") {
+			contentType = value
+		}
+		b.WriteString(" \\\n  -H " + shellQuote(name+": "+sanitizeHeader(r, name, value)))
+	}
+	if req.body != "" {
+		b.WriteString(" \\\n  --data-raw " + shellQuote(sanitizeBody(r, contentType, req.body)))
+	}
+
+	rp := &Reproduction{Curl: b.String(), Source: source}
+	if req.host == "" {
+		rp.Placeholders = append(rp.Placeholders, host)
+	}
+	for _, ph := range r.seen {
+		rp.Placeholders = append(rp.Placeholders, ph)
+	}
+	sort.Strings(rp.Placeholders)
+	return rp
+}
+
+// sanitizeHeader 替换请求头中的凭据; Cookie 保留名称, 认证头保留认证方式
+func sanitizeHeader(r *redactor, name, value string) string {
+	switch {
+	case strings.EqualFold(name, "cookie"):
+		parts := strings.Split(value, ";")
+		for i, part := range parts {
+			part = strings.TrimSpace(part)
+			k, _, _ := strings.Cut(part, "=")
+			parts[i] = k + "=" + r.placeholder("COOKIE", part)
+		}
+		return strings.Join(parts, "; ")
+	case sensitiveName.MatchString(name):
+		kind := placeholderKind(name)
+		if scheme := authScheme.FindString(value); scheme != "" {
+			return scheme + r.placeholder(kind, value[len(scheme):])
+		}
+		return r.placeholder(kind, value)
+	}
+	return r.redact(value)
+}
+
+// sanitizeTarget 脱敏路径和查询参数, 保留原有的编码
+func sanitizeTarget(r *redactor, target string) string {
+	path, query, ok := strings.Cut(target, "?")
+	path = r.redact(path)
+	if !ok {
+		return path
+	}
+	return path + "?" + sanitizeForm(r, query)
+}
+
+// sanitizeForm 脱敏 a=1&b=2 形式的参数, 凭据参数整体替换, 其余只替换其中的个人信息
+func sanitizeForm(r *redactor, form string) string {
+	pairs := strings.Split(form, "&")
+	for i, pair := range pairs {
+		k, v, ok := strings.Cut(pair, "=")
+		switch {
+		case !ok || v == "":
+			pairs[i] = r.redact(pair)
+		case sensitiveName.MatchString(k):
+			pairs[i] = k + "=" + r.placeholder(placeholderKind(k), v)
+		default:
+			pairs[i] = k + "=" + r.redact(v)
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// sanitizeBody 按内容类型脱敏请求体, 无法解析时按文本替换个人信息
+func sanitizeBody(r *redactor, contentType, body string) string {
+	if strings.Contains(strings.ToLower(contentType), "x-www-form-urlencoded") {
+		return sanitizeForm(r, body)
+	}
+
+	dec := json.NewDecoder(strings.NewReader(body))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err == nil && !dec.More() {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(sanitizeJSON(r, "", v)); err == nil {
+			return strings.TrimSpace(buf.String())
+		}
+	}
+	return r.redact(body)
+}
+
+// sanitizeJSON 凭据字段整体替换, 其余字符串只替换其中的个人信息;
+// secretKey 为最近一层凭据字段名, 凭据字段下嵌套的对象和数组同样整体替换
+func sanitizeJSON(r *redactor, secretKey string, v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			key := secretKey
+			if sensitiveName.MatchString(k) {
+				key = k
+			}
+			out[k] = sanitizeJSON(r, key, item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = sanitizeJSON(r, secretKey, item)
+		}
+		return out
+	case string, json.Number:
+		if secretKey != "" {
+			return r.placeholder(placeholderKind(secretKey), fmt.Sprint(val))
+		}
+		if s, ok := val.(string); ok {
+			return r.redact(s)
+		}
+	}
+	return v
+}
+
+// placeholderKind 由字段名生成占位符类型, 如 X-Api-Key -> X_API_KEY
+func placeholderKind(name string) string {
+	kind := strings.Map(func(c rune) rune {
+		if c < unicode.MaxASCII && (unicode.IsLetter(c) || unicode.IsDigit(c)) {
+			return unicode.ToUpper(c)
+		}
+		return '_'
+	}, name)
+	if kind = strings.Trim(kind, "_"); kind == "" {
+		return "SECRET"
+	}
+	return kind
+}
+
+// shellQuote 单引号包裹, 可直接粘贴到 shell 执行
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
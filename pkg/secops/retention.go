@@ -0,0 +1,249 @@
+package secops
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// retentionRecord 一条待归档的记录
+type retentionRecord struct {
+	ID   string
+	Time time.Time // 用于判断是否过期的时间
+	Data []byte    // 归档内容 (JSON)
+}
+
+// retentionSource 一类可归档的数据
+type retentionSource struct {
+	records func() []retentionRecord
+	prune   func(ids []string, archive string) // 归档成功后清理在线数据
+}
+
+// initRetention 注册可归档的数据类别并校验配置
+func (s *Service) initRetention() error {
+	s.retention = map[string]retentionSource{
+		"proposals": {
+			records: s.proposalService.retentionRecords,
+			prune: func(ids []string, archive string) {
+				for _, id := range ids {
+					if s.proposalService.Delete(id) {
+						s.proposalService.audit.record(ProposalEvent{
+							ProposalID: id,
+							Action:     AuditArchived,
+							Actor:      systemActor("retention"),
+							Detail:     archive,
+						})
+					}
+				}
+			},
+		},
+		"evidence": {
+			records: s.proposalService.evidenceRecords,
+			prune:   s.proposalService.archiveEvidence,
+		},
+		"runs": {
+			records: s.runs.retentionRecords,
+			prune:   s.runs.archive,
+		},
+		"audit": {
+			records: s.proposalService.audit.retentionRecords,
+			prune:   s.proposalService.audit.prune,
+		},
+	}
+
+	for class, policy := range s.config.Retention.Classes {
+		if _, ok := s.retention[class]; !ok {
+			return fmt.Errorf("unknown retention class %q", class)
+		}
+		if policy.Days < 0 || policy.MaxSizeMB < 0 {
+			return fmt.Errorf("retention class %s: days and max_size_mb must not be negative", class)
+		}
+	}
+	return nil
+}
+
+// archiveDir 归档目录
+func (s *Service) archiveDir() string {
+	if s.config.Retention.ArchiveDir != "" {
+		return s.config.Retention.ArchiveDir
+	}
+	return filepath.Join(s.workspace, "archive")
+}
+
+// runRetention 定期执行归档任务
+func (s *Service) runRetention() {
+	defer s.wg.Done()
+
+	interval := 24 * time.Hour
+	if s.config.Retention.Schedule != "" {
+		interval = s.parseSchedule(s.config.Retention.Schedule)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.RunRetention(time.Now()); err != nil {
+				logger.ErrorC("secops", fmt.Sprintf("Retention run failed: %v", err))
+			}
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// RunRetention 将超出保留策略的记录压缩归档后从在线存储中清理
+func (s *Service) RunRetention(now time.Time) error {
+	classes := make([]string, 0, len(s.config.Retention.Classes))
+	for class := range s.config.Retention.Classes {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+
+	dir := s.archiveDir()
+	for _, class := range classes {
+		source := s.retention[class]
+		expired := selectExpired(source.records(), s.config.Retention.Classes[class], now)
+		if len(expired) == 0 {
+			continue
+		}
+
+		path, err := writeArchive(dir, class, expired, now)
+		if err != nil {
+			return fmt.Errorf("archive %s: %w", class, err)
+		}
+
+		location, err := s.storeArchive(dir, path)
+		if err != nil {
+			return fmt.Errorf("upload %s archive: %w", class, err)
+		}
+
+		ids := make([]string, len(expired))
+		for i, r := range expired {
+			ids[i] = r.ID
+		}
+		source.prune(ids, location)
+
+		logger.InfoCF("secops", "Records archived",
+			map[string]interface{}{
+				"class":   class,
+				"count":   len(ids),
+				"archive": location,
+			})
+	}
+	return nil
+}
+
+// storeArchive 启用对象存储时上传归档文件, 返回归档位置 (对象 URI 或相对归档目录的路径)
+func (s *Service) storeArchive(dir, path string) (string, error) {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		rel = filepath.Base(path)
+	}
+	if s.objectStore == nil {
+		return rel, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	key := "archive/" + filepath.ToSlash(rel)
+	if err := s.objectStore.Put(s.ctx, key, f, "application/gzip"); err != nil {
+		return "", err
+	}
+	if !s.config.Retention.KeepLocal {
+		os.Remove(path)
+	}
+	return s.objectStore.Location(key), nil
+}
+
+// selectExpired 选出超过保留天数的记录, 以及为满足容量上限需要清理的最旧记录
+func selectExpired(records []retentionRecord, policy config.RetentionPolicy, now time.Time) []retentionRecord {
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Time.Before(records[j].Time)
+	})
+
+	n := 0
+	if policy.Days > 0 {
+		cutoff := now.AddDate(0, 0, -policy.Days)
+		for n < len(records) && records[n].Time.Before(cutoff) {
+			n++
+		}
+	}
+
+	if policy.MaxSizeMB > 0 {
+		limit := int64(policy.MaxSizeMB) << 20
+		var total int64
+		for _, r := range records[n:] {
+			total += int64(len(r.Data))
+		}
+		for n < len(records) && total > limit {
+			total -= int64(len(records[n].Data))
+			n++
+		}
+	}
+
+	return records[:n]
+}
+
+// writeArchive 将记录写入按日期命名的 tar.gz, 先写临时文件再重命名
+func writeArchive(dir, class string, records []retentionRecord, now time.Time) (string, error) {
+	dayDir := filepath.Join(dir, now.Format(dateLayout))
+	if err := os.MkdirAll(dayDir, 0700); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dayDir, fmt.Sprintf("%s-%s.tar.gz", class, now.Format("150405")))
+	tmp, err := os.CreateTemp(dayDir, ".archive-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	gz := gzip.NewWriter(tmp)
+	tw := tar.NewWriter(gz)
+	for _, r := range records {
+		hdr := &tar.Header{
+			Name:    fmt.Sprintf("%s/%s.json", class, r.ID),
+			Mode:    0600,
+			Size:    int64(len(r.Data)),
+			ModTime: r.Time,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			tmp.Close()
+			return "", err
+		}
+		if _, err := tw.Write(r.Data); err != nil {
+			tmp.Close()
+			return "", err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := gz.Close(); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
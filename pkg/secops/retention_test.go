@@ -0,0 +1,264 @@
+package secops
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/objectstore"
+)
+
+func TestSelectExpired(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	records := []retentionRecord{
+		{ID: "new", Time: now.Add(-time.Hour), Data: make([]byte, 600<<10)},
+		{ID: "old", Time: now.AddDate(0, 0, -40), Data: []byte("{}")},
+		{ID: "mid", Time: now.AddDate(0, 0, -10), Data: make([]byte, 600<<10)},
+	}
+
+	got := selectExpired(records, config.RetentionPolicy{Days: 30}, now)
+	if len(got) != 1 || got[0].ID != "old" {
+		t.Errorf("days policy: got %v", ids(got))
+	}
+
+	got = selectExpired(records, config.RetentionPolicy{Days: 30, MaxSizeMB: 1}, now)
+	if len(got) != 2 || got[1].ID != "mid" {
+		t.Errorf("size policy: got %v", ids(got))
+	}
+}
+
+func TestRunRetention_ArchivesAndPrunes(t *testing.T) {
+	cfg := &config.SecOpsConfig{
+		Retention: config.RetentionConfig{
+			Classes: map[string]config.RetentionPolicy{
+				"proposals": {Days: 30},
+				"evidence":  {Days: 7},
+			},
+		},
+	}
+	svc := &Service{config: cfg, proposalService: NewProposalService(), workspace: t.TempDir()}
+	if err := svc.initRetention(); err != nil {
+		t.Fatalf("initRetention failed: %v", err)
+	}
+
+	now := time.Now()
+	oldDecided := NewProposal("risk", "old", "", nil)
+	oldDecided.Status = ProposalStatusAccepted
+	oldDecided.CreatedAt = now.AddDate(0, 0, -60)
+	svc.proposalService.Create(oldDecided)
+	oldDecided.UpdatedAt = now.AddDate(0, 0, -60)
+
+	oldPending := NewProposal("risk", "pending", "", nil)
+	oldPending.CreatedAt = now.AddDate(0, 0, -60)
+	oldPending.AddEvidence("请求", "GET / HTTP/1.1")
+	svc.proposalService.Create(oldPending)
+
+	if err := svc.RunRetention(now); err != nil {
+		t.Fatalf("RunRetention failed: %v", err)
+	}
+
+	if _, ok := svc.proposalService.Get(oldDecided.ID); ok {
+		t.Error("expected decided proposal to be pruned")
+	}
+	p, ok := svc.proposalService.Get(oldPending.ID)
+	if !ok {
+		t.Fatal("pending proposal must not be pruned")
+	}
+	if len(p.Evidence) != 0 || p.EvidenceArchive == "" {
+		t.Errorf("expected evidence to be archived, got %d items, archive %q", len(p.Evidence), p.EvidenceArchive)
+	}
+
+	names := readArchive(t, filepath.Join(svc.archiveDir(), p.EvidenceArchive))
+	if len(names) != 1 || names[0] != "evidence/"+oldPending.ID+".json" {
+		t.Errorf("unexpected archive entries: %v", names)
+	}
+}
+
+func TestRunRetention_RunsAndAudit(t *testing.T) {
+	cfg := &config.SecOpsConfig{
+		Retention: config.RetentionConfig{
+			Classes: map[string]config.RetentionPolicy{
+				"runs":  {Days: 30},
+				"audit": {Days: 30},
+			},
+		},
+	}
+	workspace := t.TempDir()
+	svc := &Service{config: cfg, proposalService: NewProposalService(), runs: newRunStore(), workspace: workspace}
+	if err := svc.runs.load(filepath.Join(workspace, "runs.json")); err != nil {
+		t.Fatal(err)
+	}
+	auditPath := filepath.Join(workspace, "proposal_audit.jsonl")
+	if err := svc.proposalService.EnableAudit(auditPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.initRetention(); err != nil {
+		t.Fatalf("initRetention failed: %v", err)
+	}
+
+	now := time.Now()
+	oldRun := svc.runs.start("daily")
+	svc.runs.finish(oldRun, "", nil)
+	oldRun.StartedAt = now.AddDate(0, 0, -60)
+	running := svc.runs.start("daily")
+	running.StartedAt = now.AddDate(0, 0, -60)
+
+	audit := svc.proposalService.audit
+	audit.record(ProposalEvent{ProposalID: "p1", Action: AuditCreated, At: now.AddDate(0, 0, -60)})
+	audit.record(ProposalEvent{ProposalID: "p1", Action: AuditAccepted, At: now.AddDate(0, 0, -1)})
+
+	if err := svc.RunRetention(now); err != nil {
+		t.Fatalf("RunRetention failed: %v", err)
+	}
+
+	if _, ok := svc.runs.get(oldRun.ID); ok {
+		t.Error("old finished run should be archived")
+	}
+	if _, ok := svc.runs.get(running.ID); !ok {
+		t.Error("running run must not be archived")
+	}
+	if h := svc.proposalService.History("p1"); len(h) != 1 || h[0].Action != AuditAccepted {
+		t.Errorf("history after retention = %+v", h)
+	}
+
+	// 审计日志重写后仍可追加, 重新加载只包含未归档的记录
+	audit.record(ProposalEvent{ProposalID: "p1", Action: AuditCommented})
+	reloaded := newProposalAudit()
+	if err := reloaded.open(auditPath); err != nil {
+		t.Fatal(err)
+	}
+	if h := reloaded.history("p1"); len(h) != 2 || h[0].Action != AuditAccepted {
+		t.Errorf("reloaded history = %+v", h)
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(workspace, "archive", "*", "*.tar.gz"))
+	if len(matches) != 2 {
+		t.Fatalf("expected run and audit archives, got %v", matches)
+	}
+	var names []string
+	for _, m := range matches {
+		names = append(names, readArchive(t, m)...)
+	}
+	want := map[string]bool{"runs/" + oldRun.ID + ".json": true, "audit/p1-0000.json": true}
+	if len(names) != 2 || !want[names[0]] || !want[names[1]] {
+		t.Errorf("archive entries = %v", names)
+	}
+}
+
+func TestInitRetention_UnknownClass(t *testing.T) {
+	cfg := &config.SecOpsConfig{
+		Retention: config.RetentionConfig{
+			Classes: map[string]config.RetentionPolicy{"nope": {Days: 1}},
+		},
+	}
+	svc := &Service{config: cfg, proposalService: NewProposalService()}
+	if err := svc.initRetention(); err == nil {
+		t.Error("expected error for unknown retention class")
+	}
+}
+
+func ids(records []retentionRecord) []string {
+	result := make([]string, len(records))
+	for i, r := range records {
+		result[i] = r.ID
+	}
+	return result
+}
+
+func readArchive(t *testing.T, path string) []string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open archive: %v", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, hdr.Name)
+	}
+	return names
+}
+
+// memStore 内存对象存储
+type memStore struct {
+	objects map[string][]byte
+}
+
+func (m *memStore) Put(_ context.Context, key string, body io.ReadSeeker, _ string) error {
+	data, err := io.ReadAll(body)
+	m.objects[key] = data
+	return err
+}
+
+func (m *memStore) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	data, ok := m.objects[key]
+	if !ok {
+		return nil, objectstore.ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *memStore) Delete(_ context.Context, key string) error {
+	delete(m.objects, key)
+	return nil
+}
+
+func (m *memStore) Location(key string) string {
+	return "mem://" + key
+}
+
+func TestRunRetention_UploadsToObjectStore(t *testing.T) {
+	cfg := &config.SecOpsConfig{
+		Retention: config.RetentionConfig{
+			Classes: map[string]config.RetentionPolicy{"evidence": {Days: 1}},
+		},
+	}
+	store := &memStore{objects: make(map[string][]byte)}
+	svc := &Service{
+		config:          cfg,
+		proposalService: NewProposalService(),
+		workspace:       t.TempDir(),
+		objectStore:     store,
+		ctx:             context.Background(),
+	}
+	if err := svc.initRetention(); err != nil {
+		t.Fatalf("initRetention failed: %v", err)
+	}
+
+	p := NewProposal("weak", "old", "", nil)
+	p.CreatedAt = time.Now().AddDate(0, 0, -3)
+	p.AddEvidence("SQL", "SELECT 1")
+	svc.proposalService.Create(p)
+
+	if err := svc.RunRetention(time.Now()); err != nil {
+		t.Fatalf("RunRetention failed: %v", err)
+	}
+
+	if len(store.objects) != 1 {
+		t.Fatalf("expected 1 uploaded archive, got %d", len(store.objects))
+	}
+	if !strings.HasPrefix(p.EvidenceArchive, "mem://archive/") {
+		t.Errorf("unexpected archive location: %s", p.EvidenceArchive)
+	}
+	local, _ := filepath.Glob(filepath.Join(svc.archiveDir(), "*", "*.tar.gz"))
+	if len(local) != 0 {
+		t.Errorf("expected local archive to be removed, found %v", local)
+	}
+}
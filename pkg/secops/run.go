@@ -0,0 +1,398 @@
+package secops
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// 活动执行状态
+const (
+	RunStatusRunning   = "running"
+	RunStatusSucceeded = "succeeded"
+	RunStatusFailed    = "failed"
+
+	RunStatusInterrupted = "interrupted" // 服务停止或进程退出时未执行完, 下次执行时接续
+)
+
+// maxRunHistory 默认保留的执行记录条数
+const maxRunHistory = 500
+
+// Run 一次活动执行记录
+type Run struct {
+	ID          string       `json:"id"`
+	Activity    string       `json:"activity"`
+	Status      string       `json:"status"`
+	StartedAt   time.Time    `json:"startedAt"`
+	FinishedAt  *time.Time   `json:"finishedAt,omitempty"`
+	Error       string       `json:"error,omitempty"`
+	Response    string       `json:"response,omitempty"`    // Agent 最终回复
+	ProposalIDs []string     `json:"proposalIds,omitempty"` // 本次执行创建的提案
+	Hooks       []HookResult `json:"hooks,omitempty"`       // 执行后钩子的结果
+	DryRun      bool         `json:"dryRun,omitempty"`      // 试运行, 修改类 Sheikah API 调用未发送
+	Steps       []StepResult `json:"steps,omitempty"`       // 剧本执行时每个步骤的结果
+	ResumeOf    string       `json:"resumeOf,omitempty"`    // 接续的被中断执行 ID
+}
+
+// ResumePoint 被中断执行的续跑点, 取自活动最近一次被中断的执行记录
+type ResumePoint struct {
+	RunID         string       // 被中断的执行 ID
+	InterruptedAt time.Time    // 中断时间, 进程异常退出时为开始时间
+	ProposalIDs   []string     // 中断前已创建的提案, 含被接续的更早执行创建的
+	Steps         []StepResult // 剧本执行中断前的步骤结果
+}
+
+// HookResult 钩子执行结果
+type HookResult struct {
+	Type       string `json:"type"`
+	Target     string `json:"target,omitempty"`
+	Status     string `json:"status"` // succeeded, failed
+	Error      string `json:"error,omitempty"`
+	Output     string `json:"output,omitempty"` // 如生成的报告路径、触发的执行 ID
+	DurationMs int64  `json:"durationMs"`
+}
+
+// StepResult 剧本步骤执行结果
+type StepResult struct {
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	Status     string `json:"status"` // succeeded, failed, skipped
+	Error      string `json:"error,omitempty"`
+	Output     string `json:"output,omitempty"` // 步骤输出的 JSON, 超长时截断
+	DurationMs int64  `json:"durationMs"`
+}
+
+// runStore 执行历史, 按开始时间保留最近的记录
+type runStore struct {
+	runs    map[string]*Run
+	active  map[string]*Run // 活动名 -> 正在执行的记录
+	path    string
+	limit   memoryLimit
+	evicted uint64 // 启动以来因超出上限而删除的记录数
+	mu      sync.RWMutex
+}
+
+func newRunStore() *runStore {
+	return &runStore{
+		runs:   make(map[string]*Run),
+		active: make(map[string]*Run),
+		limit:  memoryLimit{maxItems: maxRunHistory},
+	}
+}
+
+// setLimit 设置执行记录上限, 未配置条数时使用默认值
+func (rs *runStore) setLimit(cfg config.MemoryLimitConfig) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.limit = newMemoryLimit(cfg)
+	if rs.limit.maxItems == 0 {
+		rs.limit.maxItems = maxRunHistory
+	}
+	rs.pruneLocked()
+}
+
+// load 加载已持久化的执行记录
+func (rs *runStore) load(path string) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	rs.path = path
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var runs []*Run
+	if err := json.Unmarshal(data, &runs); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for _, r := range runs {
+		// 进程退出时仍在执行的记录视为被中断, 下次执行时接续
+		if r.Status == RunStatusRunning {
+			r.Status = RunStatusInterrupted
+			r.Error = "interrupted"
+		}
+		rs.runs[r.ID] = r
+	}
+	rs.pruneLocked()
+	return nil
+}
+
+// start 开始一次执行
+func (rs *runStore) start(activity string) *Run {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return rs.startLocked(activity)
+}
+
+// startLocked 开始一次执行, 调用方需持有锁
+func (rs *runStore) startLocked(activity string) *Run {
+	r := &Run{
+		ID:        uuid.New().String(),
+		Activity:  activity,
+		Status:    RunStatusRunning,
+		StartedAt: time.Now(),
+	}
+	rs.runs[r.ID] = r
+	rs.active[activity] = r
+	rs.pruneLocked()
+	return r
+}
+
+// markDryRun 标记为试运行
+func (rs *runStore) markDryRun(r *Run) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	r.DryRun = true
+}
+
+// running 活动是否正在执行
+func (rs *runStore) running(activity string) bool {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+	_, ok := rs.active[activity]
+	return ok
+}
+
+// finish 结束一次执行
+func (rs *runStore) finish(r *Run, response string, err error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	now := time.Now()
+	r.FinishedAt = &now
+	r.Response = response
+	r.Status = RunStatusSucceeded
+	if err != nil {
+		r.Status = RunStatusFailed
+		r.Error = err.Error()
+	}
+	if rs.active[r.Activity] == r {
+		delete(rs.active, r.Activity)
+	}
+	rs.pruneLocked()
+	rs.saveLocked()
+}
+
+// interrupt 结束一次被中断的执行, 保留已完成的步骤和提案作为续跑点
+func (rs *runStore) interrupt(r *Run, response string, err error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	now := time.Now()
+	r.FinishedAt = &now
+	r.Response = response
+	r.Status = RunStatusInterrupted
+	r.Error = err.Error()
+	if rs.active[r.Activity] == r {
+		delete(rs.active, r.Activity)
+	}
+	rs.saveLocked()
+}
+
+// resume 活动上一次执行被中断时将 r 标记为接续执行并返回续跑点, 否则返回 nil
+func (rs *runStore) resume(r *Run) *ResumePoint {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	var prev *Run
+	for _, other := range rs.runs {
+		if other == r || other.Activity != r.Activity || other.StartedAt.After(r.StartedAt) {
+			continue
+		}
+		if prev == nil || other.StartedAt.After(prev.StartedAt) {
+			prev = other
+		}
+	}
+	if prev == nil || prev.Status != RunStatusInterrupted {
+		return nil
+	}
+
+	rp := &ResumePoint{
+		RunID:         prev.ID,
+		InterruptedAt: prev.StartedAt,
+		Steps:         append([]StepResult(nil), prev.Steps...),
+	}
+	if prev.FinishedAt != nil {
+		rp.InterruptedAt = *prev.FinishedAt
+	}
+	// 连续被中断时沿接续链汇总提案
+	seen := make(map[string]bool)
+	for cur := prev; cur != nil && !seen[cur.ID]; cur = rs.runs[cur.ResumeOf] {
+		seen[cur.ID] = true
+		rp.ProposalIDs = append(rp.ProposalIDs, cur.ProposalIDs...)
+	}
+	r.ResumeOf = prev.ID
+	rs.saveLocked()
+	return rp
+}
+
+// activeActivities 正在执行的活动名, 按名称排序
+func (rs *runStore) activeActivities() []string {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	names := make([]string, 0, len(rs.active))
+	for name := range rs.active {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// addHookResult 记录钩子执行结果
+func (rs *runStore) addHookResult(r *Run, result HookResult) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	r.Hooks = append(r.Hooks, result)
+	rs.saveLocked()
+}
+
+// addStepResult 记录剧本步骤执行结果
+func (rs *runStore) addStepResult(r *Run, result StepResult) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	r.Steps = append(r.Steps, result)
+	rs.saveLocked()
+}
+
+// attachProposal 将提案关联到活动当前的执行, 返回执行 ID
+func (rs *runStore) attachProposal(activity, proposalID string) string {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	r, ok := rs.active[activity]
+	if !ok {
+		return ""
+	}
+	r.ProposalIDs = append(r.ProposalIDs, proposalID)
+	return r.ID
+}
+
+// get 获取执行记录副本
+func (rs *runStore) get(id string) (*Run, bool) {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	r, ok := rs.runs[id]
+	if !ok {
+		return nil, false
+	}
+	cp := *r
+	return &cp, true
+}
+
+// list 获取执行记录, activity 为空时返回全部, 按开始时间倒序
+func (rs *runStore) list(activity string) []*Run {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	result := make([]*Run, 0, len(rs.runs))
+	for _, r := range rs.runs {
+		if activity != "" && r.Activity != activity {
+			continue
+		}
+		cp := *r
+		result = append(result, &cp)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].StartedAt.After(result[j].StartedAt)
+	})
+	return result
+}
+
+// retentionRecords 已结束的执行记录, 参与归档
+func (rs *runStore) retentionRecords() []retentionRecord {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	records := make([]retentionRecord, 0, len(rs.runs))
+	for _, r := range rs.runs {
+		if r.Status == RunStatusRunning {
+			continue
+		}
+		data, err := json.Marshal(r)
+		if err != nil {
+			continue
+		}
+		records = append(records, retentionRecord{ID: r.ID, Time: r.StartedAt, Data: data})
+	}
+	return records
+}
+
+// archive 删除已归档的执行记录
+func (rs *runStore) archive(ids []string, archive string) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	for _, id := range ids {
+		delete(rs.runs, id)
+	}
+	rs.saveLocked()
+}
+
+// pruneLocked 超出条数或占用上限时删除最早的已结束记录
+func (rs *runStore) pruneLocked() {
+	var bytes int64
+	sizes := make(map[string]int64)
+	if rs.limit.maxBytes > 0 {
+		for id, r := range rs.runs {
+			sizes[id] = jsonSize(r)
+			bytes += sizes[id]
+		}
+	}
+	if !rs.limit.exceeded(len(rs.runs), bytes) {
+		return
+	}
+	runs := make([]*Run, 0, len(rs.runs))
+	for _, r := range rs.runs {
+		if r.Status != RunStatusRunning {
+			runs = append(runs, r)
+		}
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].StartedAt.Before(runs[j].StartedAt)
+	})
+	for _, r := range runs {
+		if !rs.limit.exceeded(len(rs.runs), bytes) {
+			break
+		}
+		delete(rs.runs, r.ID)
+		bytes -= sizes[r.ID]
+		rs.evicted++
+	}
+}
+
+func (rs *runStore) saveLocked() {
+	if rs.path == "" {
+		return
+	}
+	runs := make([]*Run, 0, len(rs.runs))
+	for _, r := range rs.runs {
+		runs = append(runs, r)
+	}
+	if err := saveJSONAtomic(rs.path, runs); err != nil {
+		logger.ErrorCF("secops", "Failed to persist runs",
+			map[string]interface{}{
+				"path":  rs.path,
+				"error": err.Error(),
+			})
+	}
+}
@@ -0,0 +1,437 @@
+// Package schema 安全运营持久化数据和配置文件的版本迁移。
+//
+// 工作区 secops 目录下的 schema.json 记录当前数据版本, 缺失时视为版本 1 (引入迁移工具前的格式)。
+// 存储或配置格式变化时递增 CurrentVersion, 并在 Steps 末尾追加从上一版本升级的步骤;
+// 步骤只读写原始 JSON, 不依赖当前代码中的结构体, 以便旧步骤长期可用。
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CurrentVersion 当前代码使用的数据版本
+const CurrentVersion = 1
+
+// ConfigFile 步骤中代表配置文件的名称, 其余名称为相对工作区 secops 目录的路径
+const ConfigFile = "@config"
+
+// markerFile 记录数据版本的文件, 位于工作区 secops 目录
+const markerFile = "schema.json"
+
+// backupDir 迁移前备份的目录, 位于工作区 secops 目录
+const backupDir = "backups"
+
+// Step 从 From 升级到 From+1 的迁移步骤
+type Step struct {
+	From        int
+	Description string
+	Migrate     func(tx *Tx) error
+}
+
+// Steps 已发布的迁移步骤, 按 From 递增排列
+var Steps []Step
+
+// marker schema.json 内容
+type marker struct {
+	Version   int       `json:"version"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Options 迁移参数
+type Options struct {
+	Workspace  string // 工作区根目录, 数据位于其下的 secops 目录
+	ConfigPath string // 配置文件, 为空时不迁移配置
+	DryRun     bool   // 只报告将要修改的文件, 不写入
+	NoBackup   bool   // 不备份被修改的文件
+	Steps      []Step // 默认 Steps
+	Target     int    // 目标版本, 默认 CurrentVersion
+}
+
+// Result 迁移结果
+type Result struct {
+	From      int          `json:"from"`
+	To        int          `json:"to"`
+	DryRun    bool         `json:"dryRun"`
+	Applied   []string     `json:"applied"` // 执行的步骤说明
+	Changes   []FileChange `json:"changes"`
+	BackupDir string       `json:"backupDir,omitempty"`
+}
+
+// FileChange 被修改的文件
+type FileChange struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Before  int    `json:"before"` // 修改前字节数, -1 表示新建
+	After   int    `json:"after"`  // 修改后字节数, -1 表示删除
+	Created bool   `json:"created,omitempty"`
+	Removed bool   `json:"removed,omitempty"`
+}
+
+// ReadVersion 工作区数据版本; 没有 schema.json 时为 1
+func ReadVersion(workspace string) (int, error) {
+	data, err := os.ReadFile(filepath.Join(workspace, "secops", markerFile))
+	if os.IsNotExist(err) {
+		return 1, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var m marker
+	if err := json.Unmarshal(data, &m); err != nil || m.Version < 1 {
+		return 0, fmt.Errorf("invalid %s: %s", markerFile, strings.TrimSpace(string(data)))
+	}
+	return m.Version, nil
+}
+
+// Check 启动前检查工作区版本: 比当前代码新时拒绝启动, 避免旧版本改写新格式的数据; 较旧时提示执行迁移
+func Check(workspace string) error {
+	version, err := ReadVersion(workspace)
+	if err != nil {
+		return err
+	}
+	switch {
+	case version > CurrentVersion:
+		return fmt.Errorf("workspace data schema version %d is newer than supported version %d; upgrade picoclaw or restore a backup from %s",
+			version, CurrentVersion, filepath.Join(workspace, "secops", backupDir))
+	case version < CurrentVersion:
+		return fmt.Errorf("workspace data schema version %d is older than %d; run `picoclaw migrate schema` to upgrade (use --dry-run to preview)",
+			version, CurrentVersion)
+	}
+	return nil
+}
+
+// Run 把工作区数据和配置文件升级到目标版本。所有步骤在内存中完成后才写入,
+// 写入前备份被修改的文件, 写入失败时从备份恢复已写入的文件
+func Run(opts Options) (*Result, error) {
+	if opts.Workspace == "" {
+		return nil, errors.New("workspace is required")
+	}
+	if opts.Steps == nil {
+		opts.Steps = Steps
+	}
+	if opts.Target == 0 {
+		opts.Target = CurrentVersion
+	}
+
+	from, err := ReadVersion(opts.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{From: from, To: from, DryRun: opts.DryRun, Applied: []string{}, Changes: []FileChange{}}
+	if from > opts.Target {
+		return nil, fmt.Errorf("workspace data schema version %d is newer than target version %d", from, opts.Target)
+	}
+	if from == opts.Target {
+		return res, nil
+	}
+
+	tx := &Tx{dir: filepath.Join(opts.Workspace, "secops"), configPath: opts.ConfigPath, files: make(map[string]*txFile)}
+	for v := from; v < opts.Target; v++ {
+		step, ok := findStep(opts.Steps, v)
+		if !ok {
+			return nil, fmt.Errorf("no migration step from version %d to %d", v, v+1)
+		}
+		if err := step.Migrate(tx); err != nil {
+			return nil, fmt.Errorf("migrate %d -> %d (%s): %w", v, v+1, step.Description, err)
+		}
+		res.Applied = append(res.Applied, fmt.Sprintf("%d -> %d: %s", v, v+1, step.Description))
+	}
+	res.To = opts.Target
+
+	markerData, _ := json.MarshalIndent(marker{Version: opts.Target, UpdatedAt: time.Now().UTC()}, "", "  ")
+	if err := tx.Write(markerFile, markerData); err != nil {
+		return nil, err
+	}
+	res.Changes = tx.changes()
+	if opts.DryRun {
+		return res, nil
+	}
+
+	if !opts.NoBackup {
+		res.BackupDir = filepath.Join(tx.dir, backupDir, fmt.Sprintf("schema-v%d-%s", from, time.Now().UTC().Format("20060102T150405Z")))
+		if err := tx.backup(res.BackupDir); err != nil {
+			return nil, fmt.Errorf("backup: %w", err)
+		}
+	}
+	if err := tx.commit(res.BackupDir); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func findStep(steps []Step, from int) (Step, bool) {
+	for _, s := range steps {
+		if s.From == from {
+			return s, true
+		}
+	}
+	return Step{}, false
+}
+
+// Tx 迁移步骤读写文件的暂存区: 读取返回最近一次写入的内容, 写入只在全部步骤成功后落盘
+type Tx struct {
+	dir        string
+	configPath string
+	files      map[string]*txFile
+}
+
+type txFile struct {
+	path     string
+	original []byte // nil 表示原文件不存在
+	existed  bool
+	data     []byte // nil 表示删除
+	dirty    bool
+}
+
+// path 文件名对应的路径
+func (tx *Tx) path(name string) (string, error) {
+	if name == ConfigFile {
+		if tx.configPath == "" {
+			return "", errors.New("config file is not set")
+		}
+		return tx.configPath, nil
+	}
+	clean := filepath.Clean(name)
+	if filepath.IsAbs(clean) || clean == "." || strings.HasPrefix(clean, "..") {
+		return "", fmt.Errorf("invalid file name %q", name)
+	}
+	return filepath.Join(tx.dir, clean), nil
+}
+
+func (tx *Tx) file(name string) (*txFile, error) {
+	if f, ok := tx.files[name]; ok {
+		return f, nil
+	}
+	path, err := tx.path(name)
+	if err != nil {
+		return nil, err
+	}
+	f := &txFile{path: path}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		f.original, f.data, f.existed = data, data, true
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+	tx.files[name] = f
+	return f, nil
+}
+
+// Read 读取文件; 文件不存在时 ok 为 false。未配置配置文件时读取 ConfigFile 视为不存在
+func (tx *Tx) Read(name string) (data []byte, ok bool, err error) {
+	if name == ConfigFile && tx.configPath == "" {
+		return nil, false, nil
+	}
+	f, err := tx.file(name)
+	if err != nil {
+		return nil, false, err
+	}
+	return f.data, f.data != nil, nil
+}
+
+// Write 暂存文件的新内容
+func (tx *Tx) Write(name string, data []byte) error {
+	f, err := tx.file(name)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		data = []byte{}
+	}
+	f.data, f.dirty = data, true
+	return nil
+}
+
+// Remove 暂存删除文件
+func (tx *Tx) Remove(name string) error {
+	f, err := tx.file(name)
+	if err != nil {
+		return err
+	}
+	f.data, f.dirty = nil, true
+	return nil
+}
+
+// UpdateJSON 以 JSON 解析文件, 交给 fn 修改后写回; 文件不存在时跳过。
+// v 为 map[string]interface{} / []interface{} 等原始结构, 不使用当前代码的结构体
+func (tx *Tx) UpdateJSON(name string, fn func(v interface{}) (interface{}, error)) error {
+	data, ok, err := tx.Read(name)
+	if err != nil || !ok {
+		return err
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("parse %s: %w", name, err)
+	}
+	if v, err = fn(v); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return tx.Write(name, out)
+}
+
+// UpdateJSONLines 逐行修改 JSONL 文件 (如审计日志); fn 返回 nil 时删除该行
+func (tx *Tx) UpdateJSONLines(name string, fn func(v map[string]interface{}) (map[string]interface{}, error)) error {
+	data, ok, err := tx.Read(name)
+	if err != nil || !ok {
+		return err
+	}
+	var out strings.Builder
+	for i, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var v map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &v); err != nil {
+			return fmt.Errorf("parse %s line %d: %w", name, i+1, err)
+		}
+		if v, err = fn(v); err != nil {
+			return fmt.Errorf("%s line %d: %w", name, i+1, err)
+		}
+		if v == nil {
+			continue
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		out.Write(b)
+		out.WriteByte('\n')
+	}
+	return tx.Write(name, []byte(out.String()))
+}
+
+// changes 内容有变化的文件, 按名称排序
+func (tx *Tx) changes() []FileChange {
+	changes := []FileChange{}
+	for _, name := range tx.changedNames() {
+		f := tx.files[name]
+		c := FileChange{Name: name, Path: f.path, Before: -1, After: -1}
+		if f.existed {
+			c.Before = len(f.original)
+		} else {
+			c.Created = true
+		}
+		if f.data != nil {
+			c.After = len(f.data)
+		} else {
+			c.Removed = true
+		}
+		changes = append(changes, c)
+	}
+	return changes
+}
+
+func (tx *Tx) changedNames() []string {
+	var names []string
+	for name, f := range tx.files {
+		if !f.dirty {
+			continue
+		}
+		if f.existed == (f.data != nil) && string(f.original) == string(f.data) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// backup 把将被修改且原本存在的文件复制到 dir, 配置文件保存为 dir/config/<文件名>
+func (tx *Tx) backup(dir string) error {
+	for _, name := range tx.changedNames() {
+		f := tx.files[name]
+		if !f.existed {
+			continue
+		}
+		if err := writeFile(tx.backupPath(dir, name), f.original); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tx *Tx) backupPath(dir, name string) string {
+	if name == ConfigFile {
+		return filepath.Join(dir, "config", filepath.Base(tx.configPath))
+	}
+	return filepath.Join(dir, filepath.FromSlash(name))
+}
+
+// commit 写入所有修改, 最后写入 schema.json; 中途失败时恢复已写入的文件
+func (tx *Tx) commit(backupDir string) error {
+	names := tx.changedNames()
+	// schema.json 最后写入, 失败时版本号仍是旧值, 可以重新执行
+	sort.SliceStable(names, func(i, j int) bool { return names[j] == markerFile && names[i] != markerFile })
+
+	var done []string
+	for _, name := range names {
+		f := tx.files[name]
+		var err error
+		if f.data == nil {
+			err = os.Remove(f.path)
+		} else {
+			err = writeFile(f.path, f.data)
+		}
+		if err != nil {
+			rollbackErr := tx.rollback(done)
+			if rollbackErr != nil {
+				return fmt.Errorf("write %s: %w (rollback failed: %v; backup in %s)", name, err, rollbackErr, backupDir)
+			}
+			return fmt.Errorf("write %s: %w (changes rolled back)", name, err)
+		}
+		done = append(done, name)
+	}
+	return nil
+}
+
+func (tx *Tx) rollback(names []string) error {
+	var errs []error
+	for _, name := range names {
+		f := tx.files[name]
+		if f.existed {
+			errs = append(errs, writeFile(f.path, f.original))
+		} else {
+			errs = append(errs, os.Remove(f.path))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// writeFile 原子写入, 保留原文件权限
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	mode := os.FileMode(0600)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,182 @@
+package schema
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testSteps 1 -> 2 给提案补充 schemaNote 字段并重命名配置项; 2 -> 3 清理审计日志中的 noop 记录并删除旧的索引文件
+var testSteps = []Step{
+	{From: 1, Description: "add proposal note", Migrate: func(tx *Tx) error {
+		if err := tx.UpdateJSON("proposals.json", func(v interface{}) (interface{}, error) {
+			for _, p := range v.([]interface{}) {
+				p.(map[string]interface{})["schemaNote"] = "v2"
+			}
+			return v, nil
+		}); err != nil {
+			return err
+		}
+		return tx.UpdateJSON(ConfigFile, func(v interface{}) (interface{}, error) {
+			cfg := v.(map[string]interface{})
+			cfg["new_key"] = cfg["old_key"]
+			delete(cfg, "old_key")
+			return cfg, nil
+		})
+	}},
+	{From: 2, Description: "drop legacy index", Migrate: func(tx *Tx) error {
+		if err := tx.UpdateJSONLines("proposal_audit.jsonl", func(v map[string]interface{}) (map[string]interface{}, error) {
+			if v["action"] == "noop" {
+				return nil, nil
+			}
+			return v, nil
+		}); err != nil {
+			return err
+		}
+		return tx.Remove("legacy_index.json")
+	}},
+}
+
+func writeFixture(t *testing.T) (workspace, configPath string) {
+	t.Helper()
+	workspace = t.TempDir()
+	dir := filepath.Join(workspace, "secops")
+	os.MkdirAll(dir, 0700)
+	files := map[string]string{
+		"proposals.json":       `[{"id":"p1"},{"id":"p2"}]`,
+		"proposal_audit.jsonl": "{\"action\":\"accept\"}\n{\"action\":\"noop\"}\n",
+		"legacy_index.json":    `{}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	configPath = filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"old_key":"x"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return workspace, configPath
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRunDryRun(t *testing.T) {
+	workspace, configPath := writeFixture(t)
+	res, err := Run(Options{Workspace: workspace, ConfigPath: configPath, DryRun: true, Steps: testSteps, Target: 3})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if res.From != 1 || res.To != 3 || len(res.Applied) != 2 || res.BackupDir != "" {
+		t.Errorf("result = %+v", res)
+	}
+
+	var names []string
+	for _, c := range res.Changes {
+		names = append(names, c.Name)
+	}
+	if got := strings.Join(names, ","); got != "@config,legacy_index.json,proposal_audit.jsonl,proposals.json,schema.json" {
+		t.Errorf("changed files = %s", got)
+	}
+
+	// 试运行不写入任何文件
+	if v, _ := ReadVersion(workspace); v != 1 {
+		t.Errorf("version after dry run = %d", v)
+	}
+	if got := readFile(t, configPath); got != `{"old_key":"x"}` {
+		t.Errorf("config changed by dry run: %s", got)
+	}
+	if _, err := os.Stat(filepath.Join(workspace, "secops", "legacy_index.json")); err != nil {
+		t.Errorf("dry run removed file: %v", err)
+	}
+}
+
+func TestRunWithBackup(t *testing.T) {
+	workspace, configPath := writeFixture(t)
+	dir := filepath.Join(workspace, "secops")
+	res, err := Run(Options{Workspace: workspace, ConfigPath: configPath, Steps: testSteps, Target: 3})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	var proposals []map[string]interface{}
+	json.Unmarshal([]byte(readFile(t, filepath.Join(dir, "proposals.json"))), &proposals)
+	if len(proposals) != 2 || proposals[1]["schemaNote"] != "v2" {
+		t.Errorf("proposals = %v", proposals)
+	}
+	if got := readFile(t, configPath); !strings.Contains(got, `"new_key": "x"`) || strings.Contains(got, "old_key") {
+		t.Errorf("config = %s", got)
+	}
+	if got := readFile(t, filepath.Join(dir, "proposal_audit.jsonl")); got != "{\"action\":\"accept\"}\n" {
+		t.Errorf("audit = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "legacy_index.json")); !os.IsNotExist(err) {
+		t.Errorf("legacy index not removed: %v", err)
+	}
+	if v, _ := ReadVersion(workspace); v != 3 {
+		t.Errorf("version = %d, want 3", v)
+	}
+
+	// 备份保存修改前的内容
+	if got := readFile(t, filepath.Join(res.BackupDir, "proposals.json")); got != `[{"id":"p1"},{"id":"p2"}]` {
+		t.Errorf("backup proposals = %s", got)
+	}
+	if got := readFile(t, filepath.Join(res.BackupDir, "config", "config.json")); got != `{"old_key":"x"}` {
+		t.Errorf("backup config = %s", got)
+	}
+	if _, err := os.Stat(filepath.Join(res.BackupDir, "legacy_index.json")); err != nil {
+		t.Errorf("removed file not backed up: %v", err)
+	}
+
+	// 已是目标版本时不做任何事
+	again, err := Run(Options{Workspace: workspace, ConfigPath: configPath, Steps: testSteps, Target: 3})
+	if err != nil || again.From != 3 || len(again.Changes) != 0 {
+		t.Errorf("second run = %+v, %v", again, err)
+	}
+	if _, err := Run(Options{Workspace: workspace, Steps: testSteps, Target: 2}); err == nil {
+		t.Error("expected error downgrading")
+	}
+}
+
+func TestRunFailedStepWritesNothing(t *testing.T) {
+	workspace, configPath := writeFixture(t)
+	steps := []Step{testSteps[0], {From: 2, Description: "broken", Migrate: func(tx *Tx) error {
+		return tx.UpdateJSON("legacy_index.json", func(v interface{}) (interface{}, error) {
+			return nil, os.ErrInvalid
+		})
+	}}}
+	if _, err := Run(Options{Workspace: workspace, ConfigPath: configPath, Steps: steps, Target: 3}); err == nil {
+		t.Fatal("expected error from broken step")
+	}
+	if got := readFile(t, filepath.Join(workspace, "secops", "proposals.json")); got != `[{"id":"p1"},{"id":"p2"}]` {
+		t.Errorf("proposals written despite failure: %s", got)
+	}
+	if _, err := Run(Options{Workspace: workspace, Steps: testSteps[:1], Target: 3}); err == nil || !strings.Contains(err.Error(), "no migration step from version 2") {
+		t.Errorf("missing step error = %v", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	workspace := t.TempDir()
+	if err := Check(workspace); err != nil {
+		t.Errorf("workspace without marker: %v", err)
+	}
+	os.MkdirAll(filepath.Join(workspace, "secops"), 0700)
+	os.WriteFile(filepath.Join(workspace, "secops", markerFile), []byte(`{"version":99}`), 0600)
+	if err := Check(workspace); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("newer workspace: %v", err)
+	}
+	os.WriteFile(filepath.Join(workspace, "secops", markerFile), []byte(`not json`), 0600)
+	if err := Check(workspace); err == nil {
+		t.Error("expected error for invalid marker")
+	}
+}
@@ -0,0 +1,355 @@
+package secopstest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ClickHouseEnv 设置后使用该地址 (host:port) 上的真实 ClickHouse, 而不是启动容器;
+// 种子表会被删除后重建, 请使用专用实例
+const ClickHouseEnv = "SOCLAW_TEST_CLICKHOUSE"
+
+// ClickHouseImageEnv 以 integration 标签构建时启动的 ClickHouse 镜像, 默认 clickhouse/clickhouse-server:24.8
+const ClickHouseImageEnv = "SOCLAW_TEST_CLICKHOUSE_IMAGE"
+
+// Column 表的列定义, Type 为 ClickHouse 类型
+type Column struct {
+	Name string
+	Type string
+}
+
+// Table 种子数据表
+type Table struct {
+	Name    string
+	Columns []Column
+	Rows    [][]interface{}
+}
+
+// DefaultTables 内置 SQL 模板用到的安全数据表及少量待处理事件
+func DefaultTables() []Table {
+	return []Table{
+		{
+			Name: "risk_events",
+			Columns: []Column{
+				{"risk", "String"}, {"host", "String"}, {"content", "String"}, {"type", "String"},
+				{"status", "String"}, {"ts", "DateTime"},
+			},
+			Rows: [][]interface{}{
+				{"SQL注入", "shop.example.com", "id=1' OR '1'='1", "attack", "pending", "2026-10-17 09:12:03"},
+				{"扫描器探测", "www.example.com", "/.git/config", "scan", "pending", "2026-10-17 08:58:20"},
+				{"目录遍历", "files.example.com", "../../etc/passwd", "attack", "confirmed", "2026-10-16 21:05:41"},
+			},
+		},
+		{
+			Name: "weak_events",
+			Columns: []Column{
+				{"weak_name", "String"}, {"host", "String"}, {"method", "String"}, {"url", "String"},
+				{"channel", "String"}, {"status", "String"}, {"ts", "DateTime"},
+			},
+			Rows: [][]interface{}{
+				{"敏感信息泄露", "api.example.com", "GET", "/api/v1/users/export", "web", "pending", "2026-10-17 09:00:00"},
+			},
+		},
+		{
+			Name: "access",
+			Columns: []Column{
+				{"ip", "String"}, {"uid", "String"}, {"sid", "String"}, {"ts", "DateTime"}, {"method", "String"},
+				{"url", "String"}, {"status", "UInt16"}, {"req_risk", "String"},
+			},
+			Rows: [][]interface{}{
+				{"203.0.113.45", "", "", "2026-10-17 09:12:03", "GET", "/product?id=1' OR '1'='1", 200, "sqli"},
+				{"10.0.8.15", "", "", "2026-10-17 08:58:20", "GET", "/.git/config", 404, "scan"},
+			},
+		},
+		{
+			Name:    "access_raw",
+			Columns: []Column{{"id", "String"}, {"req", "String"}, {"res", "String"}},
+			Rows: [][]interface{}{
+				{"r1", "GET /product?id=1'%20OR%20'1'='1 HTTP/1.1\nHost: shop.example.com", "HTTP/1.1 200 OK\n\n<html>12 products</html>"},
+			},
+		},
+		{
+			Name: "weak",
+			Columns: []Column{
+				{"weak_name", "String"}, {"channel", "String"}, {"method", "String"}, {"url", "String"},
+				{"req", "String"}, {"res", "String"},
+			},
+			Rows: [][]interface{}{
+				{"敏感信息泄露", "web", "GET", "/api/v1/users/export", "GET /api/v1/users/export HTTP/1.1", `HTTP/1.1 200 OK` + "\n\n" + `[{"phone":"13800000000"}]`},
+			},
+		},
+		{
+			Name: "api_sample",
+			Columns: []Column{
+				{"method", "String"}, {"host", "String"}, {"url", "String"}, {"req", "String"}, {"res", "String"},
+				{"biz_type", "UInt8"}, {"channel", "String"}, {"analyzed", "UInt8"},
+			},
+			Rows: [][]interface{}{
+				{"POST", "pay.example.com", "/api/order/refund", `{"orderId":"O1001"}`, `{"code":0}`, 0, "web", 0},
+			},
+		},
+		{
+			Name:    "app_sample",
+			Columns: []Column{{"app_id", "String"}, {"host", "String"}, {"api_list", "String"}, {"analyzed", "UInt8"}},
+			Rows: [][]interface{}{
+				{"app-001", "pay.example.com", "/api/order/create,/api/order/refund", 0},
+			},
+		},
+	}
+}
+
+// ClickHouse 种子化的 ClickHouse, 真实实例或进程内模拟
+type ClickHouse struct {
+	Addr    string // host:port, 用作 secops.clickhouse.addr
+	real    bool
+	tables  map[string]Table
+	mu      sync.Mutex
+	queries []string
+}
+
+// StartClickHouse 启动带种子数据的 ClickHouse, 测试结束时自动关闭。依次尝试:
+//   - 设置了 SOCLAW_TEST_CLICKHOUSE 时, 在该实例中建表写入;
+//   - 以 integration 标签构建 (make test-integration) 时, 用 testcontainers 启动容器, 启动失败即测试失败;
+//   - 未使用 integration 标签或带 -short 时, 退回进程内模拟服务, 只支持简单的 SELECT, 见 serve
+func StartClickHouse(t testing.TB, tables []Table) *ClickHouse {
+	t.Helper()
+	ch := &ClickHouse{tables: make(map[string]Table)}
+	for _, tbl := range tables {
+		ch.tables[tbl.Name] = tbl
+	}
+
+	addr := os.Getenv(ClickHouseEnv)
+	if addr == "" {
+		addr = startContainer(t)
+	}
+	if addr != "" {
+		ch.Addr, ch.real = addr, true
+		if err := ch.seed(tables); err != nil {
+			t.Fatalf("seed ClickHouse at %s: %v", addr, err)
+		}
+		return ch
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(ch.serve))
+	t.Cleanup(srv.Close)
+	ch.Addr = strings.TrimPrefix(srv.URL, "http://")
+	return ch
+}
+
+// Real 是否连接的是真实 ClickHouse
+func (ch *ClickHouse) Real() bool {
+	return ch.real
+}
+
+// Queries 模拟服务收到的 SQL; 真实实例时为空
+func (ch *ClickHouse) Queries() []string {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	return append([]string(nil), ch.queries...)
+}
+
+// seed 在真实实例中重建种子表并写入数据
+func (ch *ClickHouse) seed(tables []Table) error {
+	client := &http.Client{Timeout: 30 * time.Second}
+	exec := func(query string, body []byte) error {
+		req, err := http.NewRequest(http.MethodPost, "http://"+ch.Addr+"/?query="+url.QueryEscape(query), bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			msg, _ := io.ReadAll(resp.Body)
+			return fmt.Errorf("%s: %d %s", query, resp.StatusCode, strings.TrimSpace(string(msg)))
+		}
+		return nil
+	}
+
+	for _, tbl := range tables {
+		cols := make([]string, len(tbl.Columns))
+		for i, c := range tbl.Columns {
+			cols[i] = fmt.Sprintf("`%s` %s", c.Name, c.Type)
+		}
+		if err := exec("DROP TABLE IF EXISTS `"+tbl.Name+"`", nil); err != nil {
+			return err
+		}
+		if err := exec(fmt.Sprintf("CREATE TABLE `%s` (%s) ENGINE = Memory", tbl.Name, strings.Join(cols, ", ")), nil); err != nil {
+			return err
+		}
+		if len(tbl.Rows) == 0 {
+			continue
+		}
+		var body bytes.Buffer
+		for _, row := range tbl.Rows {
+			line, err := json.Marshal(row)
+			if err != nil {
+				return err
+			}
+			body.Write(line)
+			body.WriteByte('\n')
+		}
+		if err := exec("INSERT INTO `"+tbl.Name+"` FORMAT JSONCompactEachRow", body.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	fakeSelect = regexp.MustCompile(`(?is)^\s*SELECT\s+(.*?)\s+FROM\s+` + "`?" + `([A-Za-z0-9_.]+)`)
+	fakeWhere  = regexp.MustCompile(`(?is)\bWHERE\s+(.*?)(?:\bGROUP\s+BY\b|\bORDER\s+BY\b|\bLIMIT\b|\bFORMAT\b|$)`)
+	fakeEq     = regexp.MustCompile(`^\s*([A-Za-z0-9_]+)\s*=\s*(?:'([^']*)'|\{([A-Za-z0-9_]+):\w+\}|(\d+))\s*$`)
+	fakeLimit  = regexp.MustCompile(`(?i)\bLIMIT\s+(?:(\d+)|\{([A-Za-z0-9_]+):\w+\})`)
+	fakeAnd    = regexp.MustCompile(`(?i)\s+AND\s+`)
+)
+
+// serve 模拟 ClickHouse HTTP 接口: 按 SELECT 列表的列名取值, 支持 AND 连接的等值条件 (字面量或 {name:Type} 参数)
+// 和 LIMIT; 其他条件、排序和聚合被忽略, 聚合列返回 null
+func (ch *ClickHouse) serve(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	sql := r.Form.Get("query")
+	ch.mu.Lock()
+	ch.queries = append(ch.queries, sql)
+	ch.mu.Unlock()
+
+	m := fakeSelect.FindStringSubmatch(sql)
+	if m == nil {
+		http.Error(w, "Code: 62. DB::Exception: Syntax error (mock supports SELECT only)", http.StatusBadRequest)
+		return
+	}
+	name := m[2]
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	tbl, ok := ch.tables[name]
+	if !ok {
+		http.Error(w, fmt.Sprintf("Code: 60. DB::Exception: Table %s does not exist. (UNKNOWN_TABLE)", name), http.StatusNotFound)
+		return
+	}
+	index := make(map[string]int, len(tbl.Columns))
+	for i, c := range tbl.Columns {
+		index[c.Name] = i
+	}
+
+	// 列: 取别名或末尾的标识符
+	var cols []string
+	for _, expr := range splitSelectList(m[1]) {
+		fields := strings.Fields(expr)
+		if len(fields) == 0 {
+			continue
+		}
+		col := fields[len(fields)-1]
+		if col == "*" {
+			for _, c := range tbl.Columns {
+				cols = append(cols, c.Name)
+			}
+			continue
+		}
+		cols = append(cols, col)
+	}
+
+	// 条件
+	type cond struct {
+		col   int
+		value string
+	}
+	var conds []cond
+	if wm := fakeWhere.FindStringSubmatch(sql); wm != nil {
+		for _, part := range fakeAnd.Split(wm[1], -1) {
+			em := fakeEq.FindStringSubmatch(part)
+			if em == nil {
+				continue
+			}
+			i, ok := index[em[1]]
+			if !ok {
+				continue
+			}
+			value := em[2] + em[4]
+			if em[3] != "" {
+				value = r.URL.Query().Get("param_" + em[3])
+			}
+			conds = append(conds, cond{i, value})
+		}
+	}
+
+	limit := -1
+	if lm := fakeLimit.FindStringSubmatch(sql); lm != nil {
+		v := lm[1]
+		if lm[2] != "" {
+			v = r.URL.Query().Get("param_" + lm[2])
+		}
+		if n, err := strconv.Atoi(v); err == nil {
+			limit = n
+		}
+	}
+
+	data := [][]interface{}{}
+	for _, row := range tbl.Rows {
+		match := true
+		for _, c := range conds {
+			if fmt.Sprint(row[c.col]) != c.value {
+				match = false
+				break
+			}
+		}
+		if !match {
+			continue
+		}
+		if limit >= 0 && len(data) >= limit {
+			break
+		}
+		out := make([]interface{}, len(cols))
+		for i, c := range cols {
+			if j, ok := index[c]; ok {
+				out[i] = row[j]
+			}
+		}
+		data = append(data, out)
+	}
+
+	meta := make([]map[string]string, len(cols))
+	for i, c := range cols {
+		typ := "Nullable(String)"
+		if j, ok := index[c]; ok {
+			typ = tbl.Columns[j].Type
+		}
+		meta[i] = map[string]string{"name": c, "type": typ}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"meta": meta, "data": data, "rows": len(data)})
+}
+
+// splitSelectList 按顶层逗号拆分 SELECT 列表, 忽略函数参数中的逗号
+func splitSelectList(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, strings.TrimSpace(s[start:]))
+}
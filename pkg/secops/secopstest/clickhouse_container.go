@@ -0,0 +1,56 @@
+//go:build integration
+
+package secopstest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// defaultClickHouseImage 未设置 ClickHouseImageEnv 时使用的镜像
+const defaultClickHouseImage = "clickhouse/clickhouse-server:24.8"
+
+// startContainer 用 testcontainers 启动一个 ClickHouse 容器, 返回 HTTP 接口地址 (host:port);
+// 测试结束时自动删除容器。-short 时不启动, 返回空串, 由调用方退回进程内模拟服务
+func startContainer(t testing.TB) string {
+	t.Helper()
+	if testing.Short() {
+		return ""
+	}
+
+	image := os.Getenv(ClickHouseImageEnv)
+	if image == "" {
+		image = defaultClickHouseImage
+	}
+	ctx := context.Background()
+	ctr, err := runContainer(ctx, image)
+	testcontainers.CleanupContainer(t, ctr)
+	if err != nil {
+		t.Fatalf("start ClickHouse container %s (needs Docker; use -short or drop -tags integration for the in-process mock): %v", image, err)
+	}
+	addr, err := ctr.PortEndpoint(ctx, "8123/tcp", "")
+	if err != nil {
+		t.Fatalf("ClickHouse container endpoint: %v", err)
+	}
+	return addr
+}
+
+// runContainer 启动容器; 找不到 Docker 时 testcontainers 会 panic, 这里转为错误
+func runContainer(ctx context.Context, image string) (ctr *testcontainers.DockerContainer, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return testcontainers.Run(ctx, image,
+		testcontainers.WithExposedPorts("8123/tcp"),
+		testcontainers.WithEnv(map[string]string{"CLICKHOUSE_SKIP_USER_SETUP": "1"}),
+		testcontainers.WithWaitStrategy(wait.ForHTTP("/ping").WithPort("8123/tcp").WithStartupTimeout(2*time.Minute)),
+	)
+}
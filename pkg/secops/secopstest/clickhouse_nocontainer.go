@@ -0,0 +1,10 @@
+//go:build !integration
+
+package secopstest
+
+import "testing"
+
+// startContainer 未使用 integration 构建标签时不启动容器, 由调用方退回进程内模拟服务
+func startContainer(t testing.TB) string {
+	return ""
+}
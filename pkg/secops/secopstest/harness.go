@@ -0,0 +1,177 @@
+// Package secopstest 安全运营端到端测试工具: 启动带种子数据的 ClickHouse (真实实例或进程内模拟)、
+// 模拟 Sheikah 和脚本化 LLM, 以真实的 Agent 循环和 secops.Service 执行活动, 便于对提案和执行结果断言。
+//
+//	h := secopstest.New(t, secopstest.Options{})
+//	run := h.RunActivity("risk_analysis",
+//		secopstest.Call("query_data", map[string]interface{}{"sql_id": "pending_risk_events", "params": "batch_size=5"}),
+//		secopstest.Call("secops_proposal", map[string]interface{}{"type": "risk", "title": "...", "summary": "..."}),
+//		secopstest.Reply("done"),
+//	)
+package secopstest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/agent"
+	"github.com/sipeed/picoclaw/pkg/bus"
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+// TestAPIKey 发往模拟 Sheikah 的 API Key
+const TestAPIKey = "secopstest-key"
+
+// runTimeout 等待单次活动执行完成的时长
+const runTimeout = 30 * time.Second
+
+// Options 测试环境配置
+type Options struct {
+	Tables     []Table                          // 种子数据, 默认 DefaultTables()
+	Activities map[string]config.ActivityConfig // 默认只有 manual 模式的 risk_analysis; 调度一律关闭, 由 RunActivity 触发
+	Configure  func(*config.Config)             // 创建服务前调整配置, 如开启 dry_run、故障注入
+	Provider   providers.LLMProvider            // 替代脚本化 LLM, 如按 prompt 匹配的 providers.ScriptedProvider; 设置后 RunActivity 无需传入 steps
+}
+
+// Harness 端到端测试环境
+type Harness struct {
+	t          testing.TB
+	Config     *config.Config
+	ClickHouse *ClickHouse
+	Sheikah    *Sheikah
+	LLM        *LLM
+	Agent      *agent.AgentLoop
+	Service    *secops.Service
+}
+
+// New 启动测试环境, 工作目录为临时目录; 测试结束时停止服务
+func New(t testing.TB, opts Options) *Harness {
+	t.Helper()
+	if opts.Tables == nil {
+		opts.Tables = DefaultTables()
+	}
+	if opts.Activities == nil {
+		opts.Activities = map[string]config.ActivityConfig{
+			"risk_analysis": {Schedule: "30m", Mode: "manual"},
+		}
+	}
+
+	h := &Harness{
+		t:          t,
+		ClickHouse: StartClickHouse(t, opts.Tables),
+		Sheikah:    StartSheikah(t),
+		LLM:        &LLM{},
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Workspace = t.TempDir()
+	cfg.Agents.Defaults.Model = "scripted"
+	cfg.SecOps = config.SecOpsConfig{
+		Enabled:    true,
+		ClickHouse: config.ClickHouseConfig{Addr: h.ClickHouse.Addr},
+		Sheikah:    config.SheikahConfig{BaseURL: h.Sheikah.URL, APIKey: TestAPIKey},
+		Activities: make(map[string]config.ActivityConfig, len(opts.Activities)),
+	}
+	for name, act := range opts.Activities {
+		act.Enabled = false
+		cfg.SecOps.Activities[name] = act
+	}
+	if opts.Configure != nil {
+		opts.Configure(cfg)
+	}
+	h.Config = cfg
+
+	var provider providers.LLMProvider = h.LLM
+	if opts.Provider != nil {
+		provider = opts.Provider
+	}
+	msgBus := bus.NewMessageBus()
+	h.Agent = agent.NewAgentLoop(cfg, msgBus, provider)
+	svc, err := secops.NewService(&cfg.SecOps, h.Agent, msgBus, cfg.WorkspacePath())
+	if err != nil {
+		t.Fatalf("secops.NewService: %v", err)
+	}
+	if err := svc.Start(); err != nil {
+		t.Fatalf("secops.Service.Start: %v", err)
+	}
+	t.Cleanup(svc.Stop)
+	h.Service = svc
+	return h
+}
+
+// RunActivity 追加 LLM 脚本后执行一次活动, 等待执行结束并返回执行记录; 脚本未用完视为测试失败
+func (h *Harness) RunActivity(name string, steps ...Step) *secops.Run {
+	h.t.Helper()
+	h.LLM.Script(steps...)
+	started, err := h.Service.TriggerActivity(name)
+	if err != nil {
+		h.t.Fatalf("trigger %s: %v", name, err)
+	}
+
+	deadline := time.Now().Add(runTimeout)
+	for time.Now().Before(deadline) {
+		if run, ok := h.Service.Run(started.ID); ok && run.Status != secops.RunStatusRunning {
+			if n := h.LLM.Remaining(); n > 0 {
+				h.t.Errorf("%s finished with %d unused LLM steps", name, n)
+			}
+			return run
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	h.t.Fatalf("%s did not finish within %s", name, runTimeout)
+	return nil
+}
+
+// Proposals 执行记录中创建的提案
+func (h *Harness) Proposals(run *secops.Run) []*secops.Proposal {
+	h.t.Helper()
+	proposals := make([]*secops.Proposal, 0, len(run.ProposalIDs))
+	for _, id := range run.ProposalIDs {
+		p, ok := h.Service.GetProposal(id)
+		if !ok {
+			h.t.Fatalf("proposal %s of run %s not found", id, run.ID)
+		}
+		proposals = append(proposals, p)
+	}
+	return proposals
+}
+
+// Accept 以分析师身份确认提案, 返回执行后的提案
+func (h *Harness) Accept(id string, params map[string]string) *secops.Proposal {
+	h.t.Helper()
+	return h.decide(id, secops.ActionAccept, params)
+}
+
+// Ignore 以分析师身份忽略提案, 返回执行后的提案
+func (h *Harness) Ignore(id string, params map[string]string) *secops.Proposal {
+	h.t.Helper()
+	return h.decide(id, secops.ActionIgnore, params)
+}
+
+func (h *Harness) decide(id, action string, params map[string]string) *secops.Proposal {
+	h.t.Helper()
+	ps := h.Service.ProposalService()
+	req := secops.DecisionRequest{Params: params, By: secops.Actor{Name: "secopstest"}}
+	var err error
+	if action == secops.ActionAccept {
+		err = ps.Accept(id, req)
+	} else {
+		err = ps.Ignore(id, req)
+	}
+	if err != nil {
+		h.t.Fatalf("%s %s: %v", action, id, err)
+	}
+
+	// 绑定的 API 由执行重试协程在后台执行, 等待执行结束
+	deadline := time.Now().Add(runTimeout)
+	for time.Now().Before(deadline) {
+		p, _ := h.Service.GetProposal(id)
+		if e := p.Execution; e == nil || (e.Status != secops.ExecutionQueued && !e.FinishedAt.IsZero()) {
+			return p
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	h.t.Fatalf("%s %s: execution did not finish within %s", action, id, runTimeout)
+	return nil
+}
@@ -0,0 +1,125 @@
+package secopstest
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/providers"
+	"github.com/sipeed/picoclaw/pkg/secops"
+)
+
+func TestRiskAnalysisManual(t *testing.T) {
+	h := New(t, Options{})
+
+	run := h.RunActivity("risk_analysis",
+		Call("query_data", map[string]interface{}{"sql_id": "pending_risk_events", "params": "batch_size=5"}),
+		Call("secops_proposal", map[string]interface{}{
+			"type":           "risk",
+			"title":          "shop.example.com SQL 注入",
+			"summary":        "sqlmap 扫描命中 id 参数",
+			"severity":       "high",
+			"recommendation": "accept",
+			"details":        map[string]interface{}{"host": "shop.example.com", "risk": "SQL注入", "content": "id=1' OR '1'='1"},
+		}),
+		Reply("已创建 1 个提案"),
+	)
+	if run.Status != secops.RunStatusSucceeded || run.Response != "已创建 1 个提案" {
+		t.Fatalf("run = %+v", run)
+	}
+
+	// 只返回待处理事件
+	results := h.LLM.ToolResults()
+	if len(results) != 2 || !strings.Contains(results[0], "SQL注入") || strings.Contains(results[0], "目录遍历") {
+		t.Errorf("tool results = %q", results)
+	}
+
+	proposals := h.Proposals(run)
+	if len(proposals) != 1 || proposals[0].Status != secops.ProposalStatusPending || proposals[0].RunID != run.ID {
+		t.Fatalf("proposals = %+v", proposals)
+	}
+	if n := len(h.Sheikah.Requests()); n != 0 {
+		t.Fatalf("manual mode sent %d Sheikah requests before a decision", n)
+	}
+
+	p := h.Accept(proposals[0].ID, nil)
+	if p.Status != secops.ProposalStatusAccepted || p.Execution == nil || p.Execution.Status != secops.ExecutionSucceeded {
+		t.Fatalf("accepted proposal = %+v, execution %+v", p, p.Execution)
+	}
+	reqs := h.Sheikah.RequestsTo(http.MethodPost, "/risk/confirm")
+	if len(reqs) != 1 || !strings.Contains(reqs[0].Body, `"host": "shop.example.com"`) || reqs[0].APIKey != TestAPIKey {
+		t.Errorf("confirm requests = %+v", reqs)
+	}
+}
+
+func TestExecutionFailure(t *testing.T) {
+	h := New(t, Options{})
+	h.Sheikah.Respond(http.MethodPost, "/risk/filter", http.StatusBadGateway, `{"message":"upstream down"}`)
+
+	run := h.RunActivity("risk_analysis",
+		Call("secops_proposal", map[string]interface{}{
+			"type":    "risk",
+			"title":   "内部扫描器",
+			"summary": "例行扫描",
+			"details": map[string]interface{}{"host": "www.example.com", "risk": "扫描器探测", "content": "/.git/config"},
+		}),
+		Reply("done"),
+	)
+	p := h.Ignore(h.Proposals(run)[0].ID, nil)
+	if p.Status != secops.ProposalStatusExecutionFailed || p.Execution.Status != secops.ExecutionFailed {
+		t.Errorf("proposal = %s, execution %+v", p.Status, p.Execution)
+	}
+}
+
+func TestAutoModeDryRun(t *testing.T) {
+	confirm := Call("sheikah_api", map[string]interface{}{
+		"api": "confirm_weak",
+		"params": map[string]interface{}{
+			"weak_name": "敏感信息泄露", "host": "api.example.com", "method": "GET", "url": "/api/v1/users/export",
+		},
+	})
+	activities := map[string]config.ActivityConfig{"weak_analysis": {Schedule: "60m", Mode: "auto"}}
+
+	h := New(t, Options{Activities: activities})
+	h.RunActivity("weak_analysis", confirm, Reply("done"))
+	if reqs := h.Sheikah.RequestsTo(http.MethodPost, "/apiweak/manage/batch"); len(reqs) != 1 || !strings.Contains(reqs[0].Body, `"tag": "todo"`) {
+		t.Errorf("auto mode requests = %+v", reqs)
+	}
+
+	dry := New(t, Options{Activities: activities, Configure: func(cfg *config.Config) {
+		act := cfg.SecOps.Activities["weak_analysis"]
+		act.DryRun = true
+		cfg.SecOps.Activities["weak_analysis"] = act
+	}})
+	run := dry.RunActivity("weak_analysis", confirm, Reply("done"))
+	if !run.DryRun || len(dry.Sheikah.Requests()) != 0 {
+		t.Errorf("dry run: run %+v, requests %+v", run, dry.Sheikah.Requests())
+	}
+	if results := dry.LLM.ToolResults(); len(results) != 1 || !strings.Contains(results[0], `"dryRun": true`) {
+		t.Errorf("dry run tool results = %q", results)
+	}
+}
+
+func TestScriptedProviderExample(t *testing.T) {
+	provider, err := providers.LoadScriptedProvider("../../../config/scripted.example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := New(t, Options{Provider: provider})
+
+	run := h.RunActivity("risk_analysis")
+	if run.Status != secops.RunStatusSucceeded || !strings.Contains(run.Response, "创建 2 个提案") {
+		t.Fatalf("run = %+v", run)
+	}
+	proposals := h.Proposals(run)
+	if len(proposals) != 2 || proposals[0].Details["host"] != "shop.example.com" {
+		t.Fatalf("proposals = %+v", proposals)
+	}
+
+	// 重复执行结果一致
+	again := h.RunActivity("risk_analysis")
+	if again.Response != run.Response || len(again.ProposalIDs) != 2 {
+		t.Errorf("second run = %+v", again)
+	}
+}
@@ -0,0 +1,99 @@
+package secopstest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/sipeed/picoclaw/pkg/providers"
+)
+
+// Step 脚本化 LLM 的一轮回复: 有 ToolCalls 时调用工具, 否则以 Content 作为最终回复
+type Step struct {
+	ToolCalls []ToolCall
+	Content   string
+}
+
+// ToolCall 脚本中的工具调用
+type ToolCall struct {
+	Name string
+	Args map[string]interface{}
+}
+
+// Call 调用单个工具的一轮
+func Call(name string, args map[string]interface{}) Step {
+	return Step{ToolCalls: []ToolCall{{Name: name, Args: args}}}
+}
+
+// Reply 最终回复
+func Reply(content string) Step {
+	return Step{Content: content}
+}
+
+// LLM 按脚本逐轮回复的 LLM provider, 记录工具返回的内容; 脚本用完后返回错误
+type LLM struct {
+	mu          sync.Mutex
+	steps       []Step
+	calls       int
+	toolResults []string
+}
+
+// Script 追加脚本, 可在每次执行活动前设置
+func (l *LLM) Script(steps ...Step) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.steps = append(l.steps, steps...)
+}
+
+// Remaining 尚未消费的脚本轮数
+func (l *LLM) Remaining() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.steps)
+}
+
+// ToolResults 工具返回给 LLM 的内容, 按调用顺序
+func (l *LLM) ToolResults() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.toolResults...)
+}
+
+// Chat 返回脚本的下一轮
+func (l *LLM) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, options map[string]interface{}) (*providers.LLMResponse, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// 上一轮工具调用的结果位于消息末尾
+	start := len(messages)
+	for start > 0 && messages[start-1].Role == "tool" {
+		start--
+	}
+	for _, m := range messages[start:] {
+		l.toolResults = append(l.toolResults, m.Content)
+	}
+
+	if len(l.steps) == 0 {
+		return nil, fmt.Errorf("secopstest: LLM script exhausted after %d calls", l.calls)
+	}
+	step := l.steps[0]
+	l.steps = l.steps[1:]
+	l.calls++
+
+	resp := &providers.LLMResponse{Content: step.Content, FinishReason: "stop"}
+	for i, tc := range step.ToolCalls {
+		resp.ToolCalls = append(resp.ToolCalls, providers.ToolCall{
+			ID:        fmt.Sprintf("call_%d_%d", l.calls, i),
+			Name:      tc.Name,
+			Arguments: tc.Args,
+		})
+	}
+	if len(resp.ToolCalls) > 0 {
+		resp.FinishReason = "tool_calls"
+	}
+	return resp, nil
+}
+
+// GetDefaultModel 模型名
+func (l *LLM) GetDefaultModel() string {
+	return "scripted"
+}
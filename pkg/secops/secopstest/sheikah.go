@@ -0,0 +1,86 @@
+package secopstest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// SheikahRequest 模拟 Sheikah 收到的请求
+type SheikahRequest struct {
+	Method string
+	Path   string
+	Body   string
+	APIKey string // sw-api-key 请求头
+}
+
+// Sheikah 模拟 Sheikah API, 记录所有请求; 默认对任意路径返回 {"code":0}
+type Sheikah struct {
+	URL string
+
+	mu        sync.Mutex
+	requests  []SheikahRequest
+	responses map[string]sheikahResponse
+}
+
+type sheikahResponse struct {
+	status int
+	body   string
+}
+
+// StartSheikah 启动模拟 Sheikah, 测试结束时自动关闭
+func StartSheikah(t testing.TB) *Sheikah {
+	t.Helper()
+	s := &Sheikah{responses: make(map[string]sheikahResponse)}
+	srv := httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(srv.Close)
+	s.URL = srv.URL
+	return s
+}
+
+// Respond 设置 method + path 的响应, 用于模拟 5xx 等失败
+func (s *Sheikah) Respond(method, path string, status int, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[method+" "+path] = sheikahResponse{status, body}
+}
+
+// Requests 已收到的请求, 按到达顺序
+func (s *Sheikah) Requests() []SheikahRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SheikahRequest(nil), s.requests...)
+}
+
+// RequestsTo 发往 method + path 的请求
+func (s *Sheikah) RequestsTo(method, path string) []SheikahRequest {
+	var matched []SheikahRequest
+	for _, r := range s.Requests() {
+		if r.Method == method && r.Path == path {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
+
+func (s *Sheikah) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	s.requests = append(s.requests, SheikahRequest{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Body:   string(body),
+		APIKey: r.Header.Get("sw-api-key"),
+	})
+	resp, ok := s.responses[r.Method+" "+r.URL.Path]
+	s.mu.Unlock()
+
+	if !ok {
+		resp = sheikahResponse{http.StatusOK, `{"code":0,"message":"ok"}`}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(resp.status)
+	io.WriteString(w, resp.body)
+}
@@ -3,13 +3,19 @@ package secops
 import (
 	"context"
 	"fmt"
+	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sipeed/picoclaw/pkg/agent"
 	"github.com/sipeed/picoclaw/pkg/bus"
 	"github.com/sipeed/picoclaw/pkg/config"
 	"github.com/sipeed/picoclaw/pkg/logger"
+	"github.com/sipeed/picoclaw/pkg/kvstore"
+	"github.com/sipeed/picoclaw/pkg/objectstore"
+	"github.com/sipeed/picoclaw/pkg/secops/schema"
 	"github.com/sipeed/picoclaw/pkg/tools/secops"
 )
 
@@ -22,9 +28,42 @@ type Service struct {
 	apiTool         *secops.SecOpsSheikahAPITool
 	proposalService *ProposalService
 	activities      map[string]*Activity
+	calendars       map[string]*Calendar
+	workspace       string
+	retention       map[string]retentionSource
+	objectStore     objectstore.Store
+	cache           kvstore.Store
+	runs            *runStore
+	pool            activityPool // 活动执行的并发上限和单活动互斥
+	notifier        *Notifier
+	busEvents       chan ProposalEvent // 等待发布到消息总线的提案生命周期事件
+	handover        *handoverSchedule
+	dailyReport     *handoverSchedule // 日报生成时刻
+	silences        *silenceStore
+	annotations     *annotationStore
+	health          healthCache
+	activityState   *activityStateStore
+	features        *featureFlagStore
+	hostEvents      *hostEventStore
+	wazuh           *wazuhPuller
+	anomaly         *anomalyDetector
+	breaker         *secops.CircuitBreaker
+	constraints     *proposalConstraints
+	cloud           *cloudSync
+	stix            *stixExport
+	gitops          *gitOps
+	chaos           *chaos
+	expireInterval  time.Duration // 提案过期检查间隔, 为 0 时不启动
+	started         bool
 	mu              sync.RWMutex
+	configMu        sync.RWMutex // 保护可热加载的配置段、工作日历和工具
+	reloadMu        sync.Mutex   // 串行化配置热加载
+	ingestMu        sync.Mutex   // 串行化外部提案写入, 保证指定 ID 的去重
 	ctx             context.Context
 	cancel          context.CancelFunc
+	runCtx          context.Context // 活动执行的上下文, 停止时排空超时后才取消
+	runCancel       context.CancelFunc
+	drainTimeout    time.Duration // 停止时等待执行中的活动结束的时长
 	wg              sync.WaitGroup
 }
 
@@ -33,25 +72,277 @@ type Activity struct {
 	Name     string
 	Config   *config.ActivityConfig
 	stopCh   chan struct{}
+	interval time.Duration   // 调度间隔
+	since    time.Time       // 调度开始时间, 用于推算下次执行时间
+	runNow   bool            // 启动后立即执行一次, 热加载重启调度时为 false
+	window   *activityWindow // 执行时段, 为 nil 时不限制
+
+	deferredUntil atomic.Pointer[time.Time] // 时段外的调度推迟到的时间, 未推迟时为 nil
 }
 
 // NewService 创建安全运营服务
-func NewService(cfg *config.SecOpsConfig, agentLoop *agent.AgentLoop, msgBus *bus.MessageBus) (*Service, error) {
+func NewService(cfg *config.SecOpsConfig, agentLoop *agent.AgentLoop, msgBus *bus.MessageBus, workspace string) (*Service, error) {
 	if !cfg.Enabled {
 		logger.InfoC("secops", "SecOps service is disabled")
 		return nil, nil
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
+	runCtx, runCancel := context.WithCancel(context.Background())
 	svc := &Service{
 		config:          cfg,
 		agentLoop:       agentLoop,
 		msgBus:          msgBus,
 		proposalService: NewProposalService(),
 		activities:      make(map[string]*Activity),
+		calendars:       make(map[string]*Calendar),
+		workspace:       workspace,
+		runs:            newRunStore(),
+		silences:        newSilenceStore(),
+		annotations:     newAnnotationStore(),
+		activityState:   newActivityStateStore(),
+		features:        newFeatureFlagStore(cfg.Tenant, cfg.Features),
+		hostEvents:      newHostEventStore(),
 		ctx:             ctx,
 		cancel:          cancel,
+		runCtx:          runCtx,
+		runCancel:       runCancel,
+	}
+	svc.proposalService.SetRequireOverrideReason(cfg.RequireOverrideReason)
+	svc.proposalService.SetTemplates(cfg.ActionTemplates)
+	ttl, expireInterval, err := parseExpiration(cfg.Expiration)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("invalid secops expiration config: %w", err)
+	}
+	svc.proposalService.SetTTL(ttl)
+	svc.expireInterval = expireInterval
+	if svc.drainTimeout, err = parseDrainTimeout(cfg.DrainTimeout); err != nil {
+		cancel()
+		return nil, fmt.Errorf("invalid secops config: %w", err)
+	}
+
+	// 初始化工作日历
+	if err := svc.initCalendars(); err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to init secops calendars: %w", err)
+	}
+
+	// 内存上限需在加载持久化数据前设置, 加载后立即换出超出的部分
+	svc.proposalService.SetMemoryLimit(cfg.Memory.Proposals)
+	svc.runs.setLimit(cfg.Memory.Runs)
+	svc.pool.setLimit(cfg.MaxConcurrentActivities)
+
+	// 持久化提案和执行记录
+	if workspace != "" {
+		// 数据版本与当前代码不一致时不加载, 避免按错误的格式读写
+		if err := schema.Check(workspace); err != nil {
+			cancel()
+			return nil, err
+		}
+		if err := svc.proposalService.EnablePersistence(filepath.Join(workspace, "secops", "proposals.json")); err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to load secops proposals: %w", err)
+		}
+		if err := svc.proposalService.EnableAudit(filepath.Join(workspace, "secops", "proposal_audit.jsonl")); err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to load secops proposal audit: %w", err)
+		}
+		if err := svc.runs.load(filepath.Join(workspace, "secops", "runs.json")); err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to load secops runs: %w", err)
+		}
+		if err := svc.silences.load(filepath.Join(workspace, "secops", "silences.json")); err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to load secops silences: %w", err)
+		}
+		if err := svc.annotations.load(filepath.Join(workspace, "secops", "annotations.json")); err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to load secops annotations: %w", err)
+		}
+		if err := svc.activityState.load(filepath.Join(workspace, "secops", "activity_state.json")); err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to load secops activity state: %w", err)
+		}
+		if err := svc.features.load(filepath.Join(workspace, "secops", "feature_flags.json")); err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to load secops feature flags: %w", err)
+		}
+		if err := svc.hostEvents.load(filepath.Join(workspace, "secops", "host_events.json")); err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to load secops host events: %w", err)
+		}
+	}
+
+	// 初始化对象存储
+	if cfg.ObjectStore.Enabled {
+		store, err := objectstore.NewS3Store(cfg.ObjectStore)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to init secops object store: %w", err)
+		}
+		svc.objectStore = store
+	}
+
+	// 初始化共享缓存, Redis 不可达时不阻止启动, 先退回进程内存储
+	cache, err := kvstore.New(cfg.Cache, cfg.Memory.Cache)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to init secops cache: %w", err)
+	}
+	svc.cache = cache
+
+	// 初始化故障注入
+	faults, err := newChaos(cfg.Chaos)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("invalid secops chaos config: %w", err)
+	}
+	svc.chaos = faults
+
+	// 初始化提案通知
+	notifier, err := NewNotifier(cfg.Notifications, msgBus)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("invalid secops notifications: %w", err)
+	}
+	notifier.faults = faults
+	svc.notifier = notifier
+	svc.proposalService.SetDecisionHandler(func(p *Proposal) {
+		go svc.notifier.NotifyDecision(svc.ctx, p)
+		if svc.stix != nil {
+			go svc.publishIndicators(p)
+		}
+		if svc.gitops != nil {
+			go svc.commitArtifacts(p)
+		}
+	})
+
+	// 提案生命周期事件发布到消息总线
+	if msgBus != nil {
+		svc.busEvents = make(chan ProposalEvent, maxPendingBusEvents)
+		svc.proposalService.SetEventHandler(svc.queueBusEvent)
+	}
+
+	// 初始化交接班摘要
+	handover, err := newHandoverSchedule(cfg.Handover, cfg.Timezone, cfg.Notifications.Targets)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("invalid secops handover config: %w", err)
+	}
+	svc.handover = handover
+
+	// 初始化每日运营日报
+	dailyReport, err := newDailyReportSchedule(cfg.DailyReport, cfg.Timezone, cfg.Notifications.Targets)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("invalid secops daily_report config: %w", err)
+	}
+	svc.dailyReport = dailyReport
+
+	// 初始化 Wazuh 告警拉取
+	if cfg.Wazuh.Enabled {
+		puller, err := newWazuhPuller(cfg.Wazuh, svc.hostEvents)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("invalid secops wazuh config: %w", err)
+		}
+		svc.wazuh = puller
+	}
+
+	// 初始化统计异常检测
+	if cfg.Anomaly.Enabled {
+		detector, err := newAnomalyDetector(cfg.Anomaly, newAnomalyStore())
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("invalid secops anomaly config: %w", err)
+		}
+		if workspace != "" {
+			if err := detector.store.load(filepath.Join(workspace, "secops", "anomaly.json")); err != nil {
+				cancel()
+				return nil, fmt.Errorf("failed to load secops anomaly state: %w", err)
+			}
+		}
+		svc.anomaly = detector
+	}
+
+	// 初始化云安全发现同步
+	cloud, err := newCloudSync(cfg.Cloud)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("invalid secops cloud_findings config: %w", err)
+	}
+	svc.cloud = cloud
+
+	// 初始化 STIX 指标发布
+	stix, err := newSTIXExport(cfg.STIX, workspace)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("invalid secops stix config: %w", err)
+	}
+	if stix != nil && workspace != "" {
+		if err := stix.load(filepath.Join(workspace, "secops", "indicators.json")); err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to load secops indicators: %w", err)
+		}
+	}
+	svc.stix = stix
+
+	// 初始化 Git 变更管理
+	gitops, err := newGitOps(cfg.Git, workspace)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("invalid secops git config: %w", err)
+	}
+	svc.gitops = gitops
+
+	// 校验活动钩子
+	if err := svc.validateHooks(); err != nil {
+		cancel()
+		return nil, fmt.Errorf("invalid secops hooks: %w", err)
+	}
+
+	// 校验功能开关
+	if err := validateFeatures(cfg); err != nil {
+		cancel()
+		return nil, fmt.Errorf("invalid secops features: %w", err)
+	}
+
+	// 校验活动剧本
+	if err := validatePlaybooks(cfg, workspace); err != nil {
+		cancel()
+		return nil, fmt.Errorf("invalid secops playbook: %w", err)
+	}
+
+	// 校验工作语言
+	if err := validateLanguages(cfg); err != nil {
+		cancel()
+		return nil, fmt.Errorf("invalid secops language: %w", err)
+	}
+
+	// 校验时区和活动执行时段
+	if err := validateWindows(cfg); err != nil {
+		cancel()
+		return nil, fmt.Errorf("invalid secops window: %w", err)
+	}
+
+	// 初始化提案约束
+	constraints, err := newProposalConstraints(cfg.Constraints)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("invalid secops proposal_constraints: %w", err)
 	}
+	svc.constraints = constraints
+
+	// 初始化数据保留策略
+	if err := svc.initRetention(); err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to init secops retention: %w", err)
+	}
+
+	// ClickHouse 熔断器在热加载重建工具时沿用
+	svc.breaker = secops.NewCircuitBreaker(0)
+	svc.breaker.OnStateChange(svc.onBreakerChange)
 
 	// 初始化工具
 	if err := svc.initTools(); err != nil {
@@ -59,6 +350,11 @@ func NewService(cfg *config.SecOpsConfig, agentLoop *agent.AgentLoop, msgBus *bu
 		return nil, fmt.Errorf("failed to init secops tools: %w", err)
 	}
 
+	// 注册对话命令
+	svc.agentLoop.RegisterCommand("/timeline", svc.timelineCommand)
+	svc.agentLoop.RegisterCommand("/handover", svc.handoverCommand)
+	svc.agentLoop.RegisterCommand("/report", svc.dailyReportCommand)
+
 	return svc, nil
 }
 
@@ -69,7 +365,25 @@ func (s *Service) ProposalService() *ProposalService {
 
 // CreateProposal 创建提案
 func (s *Service) CreateProposal(proposal *Proposal) string {
-	return s.proposalService.Create(proposal)
+	if proposal.Binding == nil {
+		proposal.Binding = defaultBinding(proposal)
+	}
+	tagAttack(proposal)
+	attachReproduction(proposal)
+	id := s.proposalService.Create(proposal)
+	s.autoTranslate(id)
+	if s.notifier != nil {
+		if sl := s.silences.match(proposal, time.Now()); sl != nil {
+			logger.InfoCF("secops", "Proposal notification silenced",
+				map[string]interface{}{
+					"id":      id,
+					"silence": sl.ID,
+				})
+		} else {
+			go s.notifier.Notify(s.runCtx, proposal)
+		}
+	}
+	return id
 }
 
 // GetProposal 获取提案
@@ -77,93 +391,333 @@ func (s *Service) GetProposal(id string) (*Proposal, bool) {
 	return s.proposalService.Get(id)
 }
 
+// initCalendars 初始化工作日历并校验引用
+func (s *Service) initCalendars() error {
+	calendars, err := newCalendars(s.config)
+	if err != nil {
+		return err
+	}
+	s.calendars = calendars
+	return nil
+}
+
+// newCalendars 按配置创建工作日历并校验活动和 SLA 的引用
+func newCalendars(cfg *config.SecOpsConfig) (map[string]*Calendar, error) {
+	calendars := make(map[string]*Calendar, len(cfg.Calendars))
+	for name, calCfg := range cfg.Calendars {
+		cal, err := NewCalendar(name, calCfg)
+		if err != nil {
+			return nil, err
+		}
+		calendars[name] = cal
+	}
+
+	for name, actCfg := range cfg.Activities {
+		if actCfg.Calendar != "" && calendars[actCfg.Calendar] == nil {
+			return nil, fmt.Errorf("activity %s references unknown calendar %q", name, actCfg.Calendar)
+		}
+	}
+	if cfg.SLA.Calendar != "" && calendars[cfg.SLA.Calendar] == nil {
+		return nil, fmt.Errorf("sla references unknown calendar %q", cfg.SLA.Calendar)
+	}
+
+	return calendars, nil
+}
+
+// Calendar 获取指定名称的工作日历
+func (s *Service) Calendar(name string) (*Calendar, bool) {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	cal, ok := s.calendars[name]
+	return cal, ok
+}
+
+// Runs 获取活动执行记录, activity 为空时返回全部, 按开始时间倒序
+func (s *Service) Runs(activity string) []*Run {
+	return s.runs.list(activity)
+}
+
+// Run 获取单次执行记录
+func (s *Service) Run(id string) (*Run, bool) {
+	return s.runs.get(id)
+}
+
+// ObjectStore 获取对象存储, 未启用时返回 nil
+func (s *Service) ObjectStore() objectstore.Store {
+	return s.objectStore
+}
+
+// Cache 获取共享缓存, 多实例部署时用于共享登录会话等状态
+func (s *Service) Cache() kvstore.Store {
+	return s.cache
+}
+
+// HasSLA 是否配置了任何提案 SLA 时限
+func (s *Service) HasSLA() bool {
+	return len(s.config.SLA.Hours) > 0
+}
+
+// SLAStatus 计算提案的 SLA 计时, 使用 SLA 日历时非工作时间暂停计时
+// 未为该提案类型配置时限时 ok 为 false
+func (s *Service) SLAStatus(p *Proposal, now time.Time) (elapsed, limit time.Duration, ok bool) {
+	hours, exists := s.config.SLA.Hours[p.Type]
+	if !exists || hours <= 0 {
+		return 0, 0, false
+	}
+	limit = time.Duration(hours * float64(time.Hour))
+
+	end := now
+	if p.Status != ProposalStatusPending {
+		end = p.UpdatedAt
+	}
+	if cal, found := s.Calendar(s.config.SLA.Calendar); found {
+		elapsed = cal.BusinessDuration(p.CreatedAt, end)
+	} else {
+		elapsed = end.Sub(p.CreatedAt)
+	}
+	return elapsed, limit, true
+}
+
+// riskEventsBody 风险确认/忽略请求体; 传入 items (JSON 数组) 时一次提交多条, 条目未指定 note 时使用外层 note
+const riskEventsBody = `[
+{{- with .items}}{{range $i, $e := fromJSON .}}{{if $i}},{{end}}
+  {"content": {{str $e.content}}, "host": {{str $e.host}}, "risk": {{str $e.risk}}, "note": {{str (default $.note $e.note)}}}
+{{- end}}{{else}}
+  {"content": {{str .content}}, "host": {{str .host}}, "risk": {{str .risk}}, "note": {{str .note}}}
+{{- end}}
+]`
+
+// weakManageBody 弱点批量管理请求体; 传入 items (JSON 数组) 时一次提交多条, 未填写 note 时不带 message
+func weakManageBody(tag string) string {
+	return `{"tag": "` + tag + `", "apiWeakMgts": [
+{{- with .items}}{{range $i, $e := fromJSON .}}{{if $i}},{{end}}
+  {"defectId": {{str $e.weak_name}}, "host": {{str $e.host}}, "method": {{str $e.method}}, "url": {{str $e.url}}}
+{{- end}}{{else}}
+  {"defectId": {{str .weak_name}}, "host": {{str .host}}, "method": {{str .method}}, "url": {{str .url}}}
+{{- end}}
+]{{with .note}}, "message": {{str .}}{{end}}}`
+}
+
 // initTools 初始化安全运营工具
+// openDataSource 按驱动创建 query_data 的数据源
+func openDataSource(ds config.DataSourceConfig) (secops.QuerySource, error) {
+	var timeout time.Duration
+	if ds.Timeout != "" {
+		d, err := time.ParseDuration(ds.Timeout)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid timeout %q", ds.Timeout)
+		}
+		timeout = d
+	}
+
+	switch ds.Driver {
+	case "splunk":
+		return secops.NewSplunkSource(secops.SplunkConfig{
+			BaseURL:            ds.DSN,
+			Token:              ds.Token,
+			Username:           ds.Username,
+			Password:           ds.Password,
+			Timeout:            timeout,
+			MaxResults:         ds.MaxResults,
+			InsecureSkipVerify: ds.InsecureSkipVerify,
+		}, ds.Queries)
+	case "loki":
+		return secops.NewLokiSource(secops.LokiConfig{
+			BaseURL:            ds.DSN,
+			Token:              ds.Token,
+			Username:           ds.Username,
+			Password:           ds.Password,
+			Tenant:             ds.Tenant,
+			Timeout:            timeout,
+			Limit:              ds.MaxResults,
+			InsecureSkipVerify: ds.InsecureSkipVerify,
+		}, ds.Queries)
+	case "clickhouse":
+		return secops.NewClickHouseSource(secops.ClickHouseSourceConfig{
+			BaseURL:  ds.DSN,
+			Username: ds.Username,
+			Password: ds.Password,
+			Timeout:  timeout,
+		}, ds.Queries)
+	}
+	return secops.OpenSQLSource(ds.Driver, ds.DSN, ds.Queries)
+}
+
 func (s *Service) initTools() error {
+	queryTool, apiTool, err := s.newTools(s.config)
+	if err != nil {
+		return err
+	}
+	s.registerTools(queryTool, apiTool)
+
+	// 初始化本地提案工具
+	s.agentLoop.RegisterTool(NewProposalTool(s))
+
+	// 初始化标注查询工具
+	s.agentLoop.RegisterTool(NewAnnotationTool(s))
+
+	// 初始化活动间共享的黑板工具
+	s.agentLoop.RegisterTool(NewBlackboardTool(s))
+
+	// 初始化实体关系图查询工具
+	s.agentLoop.RegisterTool(NewGraphTool(s))
+
+	// 演示模式替换数据源并预置示例提案
+	s.initDemo()
+
+	// 故障注入包装在演示或真实传输层之外
+	s.initChaos()
+
+	return nil
+}
+
+// newTools 按配置创建 query_data 和 sheikah_api 工具, 只校验不注册
+func (s *Service) newTools(cfg *config.SecOpsConfig) (*secops.SecOpsQueryDataTool, *secops.SecOpsSheikahAPITool, error) {
 	// 初始化 SQL 模板
 	queries := map[string]string{
-		"pending_risk_events": `SELECT risk, host, content, ts FROM risk_events WHERE status = 'pending' ORDER BY ts DESC LIMIT $batch_size`,
-		"pending_weak_events": `SELECT weak_name, host, method, url, channel FROM weak_events WHERE status = 'pending' ORDER BY ts DESC LIMIT $batch_size`,
-		"access_by_ip": `SELECT ip, ts, method, url, status, req_risk FROM access WHERE ip = '$ip' AND ts > now() - INTERVAL 1 DAY ORDER BY ts DESC LIMIT 30`,
-		"access_by_user": `SELECT ip, ts, method, url, status, req_risk FROM access WHERE uid = '$user_id' AND ts > now() - INTERVAL 1 DAY ORDER BY ts DESC LIMIT 30`,
-		"access_by_device": `SELECT ip, ts, method, url, status, req_risk FROM access WHERE sid = '$device_id' AND ts > now() - INTERVAL 1 DAY ORDER BY ts DESC LIMIT 30`,
-		"http_details": `SELECT req, res FROM access_raw WHERE id = '$id' LIMIT 3`,
+		"pending_risk_events": `SELECT risk, host, content, ts FROM risk_events WHERE status = 'pending' ORDER BY ts DESC LIMIT {batch_size:UInt32}`,
+		"pending_weak_events": `SELECT weak_name, host, method, url, channel FROM weak_events WHERE status = 'pending' ORDER BY ts DESC LIMIT {batch_size:UInt32}`,
+		"access_by_ip": `SELECT ip, ts, method, url, status, req_risk FROM access WHERE ip = {ip:String} AND ts > now() - INTERVAL 1 DAY ORDER BY ts DESC LIMIT 30`,
+		"access_by_user": `SELECT ip, ts, method, url, status, req_risk FROM access WHERE uid = {user_id:String} AND ts > now() - INTERVAL 1 DAY ORDER BY ts DESC LIMIT 30`,
+		"access_by_device": `SELECT ip, ts, method, url, status, req_risk FROM access WHERE sid = {device_id:String} AND ts > now() - INTERVAL 1 DAY ORDER BY ts DESC LIMIT 30`,
+		"http_details": `SELECT req, res FROM access_raw WHERE id = {id:String} LIMIT 3`,
 		"risk_top20": `SELECT risk, host, content, type, count() as cnt FROM risk_events WHERE ts > today() AND status = 'pending' GROUP BY risk, host, content, type ORDER BY cnt DESC LIMIT 20`,
-		"weak_http_sample": `SELECT req, res FROM weak WHERE weak_name = '$weak_name' AND channel = '$channel' AND method = '$method' AND url = '$url' LIMIT 1`,
-		"pending_api_list": `SELECT method, host, url, req, res, biz_type, channel FROM api_sample WHERE analyzed = 0 LIMIT $batch_size`,
-		"api_sample": `SELECT method, host, url, req, res FROM api_sample WHERE host = '$host' AND url = '$url' LIMIT 1`,
-		"pending_app_list": `SELECT app_id, host, api_list FROM app_sample WHERE analyzed = 0 LIMIT $batch_size`,
-		"app_api_list": `SELECT api_list FROM app_sample WHERE app_id = '$app_id' LIMIT 1`,
+		"weak_http_sample": `SELECT req, res FROM weak WHERE weak_name = {weak_name:String} AND channel = {channel:String} AND method = {method:String} AND url = {url:String} LIMIT 1`,
+		"pending_api_list": `SELECT method, host, url, req, res, biz_type, channel FROM api_sample WHERE analyzed = 0 LIMIT {batch_size:UInt32}`,
+		"api_sample": `SELECT method, host, url, req, res FROM api_sample WHERE host = {host:String} AND url = {url:String} LIMIT 1`,
+		"pending_app_list": `SELECT app_id, host, api_list FROM app_sample WHERE analyzed = 0 LIMIT {batch_size:UInt32}`,
+		"app_api_list": `SELECT api_list FROM app_sample WHERE app_id = {app_id:String} LIMIT 1`,
+		"k8s_rbac_changes": `SELECT ts, user, verb, resource, namespace, name, source_ip, response_code, request_object FROM k8s_audit WHERE resource IN ('roles', 'clusterroles', 'rolebindings', 'clusterrolebindings') AND verb IN ('create', 'update', 'patch', 'delete') AND ts > now() - INTERVAL 1 HOUR ORDER BY ts DESC LIMIT 50`,
+		"k8s_pod_exec": `SELECT ts, user, verb, subresource, namespace, name, source_ip, user_agent, response_code FROM k8s_audit WHERE resource = 'pods' AND subresource IN ('exec', 'attach', 'portforward') AND ts > now() - INTERVAL 1 HOUR ORDER BY ts DESC LIMIT 50`,
+		"k8s_secret_access": `SELECT ts, user, verb, namespace, name, source_ip, user_agent, response_code FROM k8s_audit WHERE resource = 'secrets' AND verb IN ('get', 'list', 'watch') AND user NOT LIKE 'system:%' AND ts > now() - INTERVAL 1 HOUR ORDER BY ts DESC LIMIT 50`,
+		"k8s_user_activity": `SELECT ts, verb, resource, subresource, namespace, name, source_ip, response_code FROM k8s_audit WHERE user = {user:String} AND ts > now() - INTERVAL 1 DAY ORDER BY ts DESC LIMIT 50`,
+		"host_traffic_stats": `SELECT host, count() AS requests, countIf(status >= 400) AS errors FROM access WHERE ts > now() - INTERVAL {window_seconds:UInt32} SECOND GROUP BY host`,
 	}
 
 	// 初始化 ClickHouse 查询工具
-	chAddr := s.config.ClickHouse.Addr
+	chAddr := cfg.ClickHouse.Addr
 	if chAddr == "" {
 		chAddr = "localhost:8123"
 	}
 	chBaseURL := fmt.Sprintf("http://%s", chAddr)
-	s.queryTool = secops.NewSecOpsQueryDataTool(
+	threshold, _, err := breakerSettings(cfg.ClickHouse.Breaker)
+	if err != nil {
+		return nil, nil, fmt.Errorf("clickhouse.breaker: %w", err)
+	}
+	queryTool := secops.NewSecOpsQueryDataTool(
 		queries,
 		chBaseURL,
-		s.config.ClickHouse.Username,
-		s.config.ClickHouse.Password,
+		cfg.ClickHouse.Username,
+		cfg.ClickHouse.Password,
 	)
-	s.agentLoop.RegisterTool(s.queryTool)
+	if s.breaker != nil {
+		queryTool.SetBreaker(s.breaker)
+	}
+	if len(cfg.ClickHouse.Replicas) > 0 {
+		replicas := make([]string, 0, len(cfg.ClickHouse.Replicas))
+		seen := map[string]bool{chAddr: true}
+		for i, addr := range cfg.ClickHouse.Replicas {
+			addr = strings.TrimSpace(addr)
+			if addr == "" || seen[addr] {
+				return nil, nil, fmt.Errorf("clickhouse.replicas[%d]: empty or duplicate address %q", i, addr)
+			}
+			seen[addr] = true
+			replicas = append(replicas, fmt.Sprintf("http://%s", addr))
+		}
+		queryTool.SetReplicas(replicas, cfg.ClickHouse.ReadFromReplicas)
+	}
+	for name, ds := range cfg.DataSources {
+		src, err := openDataSource(ds)
+		if err != nil {
+			return nil, nil, fmt.Errorf("data_sources.%s: %w", name, err)
+		}
+		if ds.Feature != "" && s.features != nil {
+			src = flaggedSource{QuerySource: src, feature: ds.Feature, features: s.features}
+		}
+		queryTool.AddSource(name, src)
+	}
+	if s.wazuh != nil {
+		if _, ok := cfg.DataSources["wazuh"]; ok {
+			return nil, nil, fmt.Errorf("data_sources.wazuh: name is reserved when wazuh is enabled")
+		}
+		queryTool.AddSource("wazuh", hostEventSource{store: s.hostEvents})
+	}
+	if s.anomaly != nil {
+		if _, ok := cfg.DataSources["anomaly"]; ok {
+			return nil, nil, fmt.Errorf("data_sources.anomaly: name is reserved when anomaly detection is enabled")
+		}
+		queryTool.AddSource("anomaly", preAlertSource{store: s.anomaly.store})
+	}
+	for name, ds := range cfg.DataSources {
+		for _, id := range ds.Templates {
+			if err := queryTool.RouteTemplate(id, name); err != nil {
+				return nil, nil, fmt.Errorf("data_sources.%s.templates: %w", name, err)
+			}
+		}
+	}
 
-	// 初始化 API 调用工具
+	// 初始化 API 调用工具, 请求体为 Go text/template, 配置中的同名 API 覆盖内置定义
 	apis := map[string]secops.APIConfig{
 		"confirm_risk": {
 			Method: "POST",
 			Path:   "/risk/confirm",
-			Body:   `[{"content": "$content", "host": "$host", "risk": "$risk", "note": "$note"}]`,
+			Body:   riskEventsBody,
 		},
 		"ignore_risk": {
 			Method: "POST",
 			Path:   "/risk/filter",
-			Body:   `[{"content": "$content", "host": "$host", "risk": "$risk", "note": "$note"}]`,
+			Body:   riskEventsBody,
 		},
 		"confirm_weak": {
 			Method: "POST",
 			Path:   "/apiweak/manage/batch",
-			Body:   `{"tag": "todo", "apiWeakMgts": [{"defectId": "$weak_name", "host": "$host", "method": "$method", "url": "$url"}], "message": "$note"}`,
+			Body:   weakManageBody("todo"),
 		},
 		"ignore_weak": {
 			Method: "POST",
 			Path:   "/apiweak/manage/batch",
-			Body:   `{"tag": "ignore", "apiWeakMgts": [{"defectId": "$weak_name", "host": "$host", "method": "$method", "url": "$url"}], "message": "$note"}`,
+			Body:   weakManageBody("ignore"),
 		},
 		"create_business": {
 			Method: "POST",
 			Path:   "/antibot/api_data_property",
-			Body:   `{"method": "$method", "path": "$path", "host": "$host", "bizType": 0, "bizDesc": "$biz_desc", "bizLevel": $biz_level, "bizName": "$biz_name", "mode": 1, "ruleSet": []}`,
+			Body:   `{"method": {{str .method}}, "path": {{str .path}}, "host": {{str .host}}, "bizType": 0, "bizDesc": {{str .biz_desc}}, "bizLevel": {{default 0 .biz_level}}, "bizName": {{str .biz_name}}, "mode": 1, "ruleSet": []}`,
 		},
 		"save_api_analysis": {
 			Method: "POST",
 			Path:   "/antibot/internal_api/api_analysis",
-			Body:   `{"host": "$host", "method": "$method", "path": "$path", "biz_analysis": "$biz_analysis", "importance_analysis": "$importance_analysis", "param_analysis": "$param_analysis", "importance": "$importance", "skip_if_exist": true}`,
+			Body:   `{"host": {{str .host}}, "method": {{str .method}}, "path": {{str .path}}, "biz_analysis": {{str .biz_analysis}}, "importance_analysis": {{str .importance_analysis}}, "param_analysis": {{str .param_analysis}}, "importance": {{str .importance}}, "skip_if_exist": true}`,
 		},
 		"create_app": {
 			Method: "POST",
 			Path:   "/antibot/internal_app",
-			Body:   `{"name": "$app_name", "domainList": ["$host"], "urlPrefix": "/", "isMirror": true, "desc": "$app_desc"}`,
+			Body:   `{"name": {{str .app_name}}, "domainList": [{{range $i, $h := split "," .host}}{{if $i}}, {{end}}{{str $h}}{{end}}], "urlPrefix": "/", "isMirror": true, "desc": {{str .app_desc}}}`,
 		},
 		"update_app": {
 			Method: "PUT",
 			Path:   "/antibot/internal_app/$app_id",
-			Body:   `{"desc": "$app_desc"}`,
+			Body:   `{"desc": {{str .app_desc}}}`,
 		},
 		"create_proposal": {
 			Method: "POST",
 			Path:   "/secops/proposal",
-			Body:   `{"type": "$type", "title": "$title", "content": "$content", "data": $data}`,
+			Body:   `{"type": {{str .type}}, "title": {{str .title}}, "content": {{str .content}}, "data": {{default "null" .data}}}`,
 		},
 	}
+	for id, api := range cfg.Sheikah.APIs {
+		apis[id] = secops.APIConfig{Method: api.Method, Path: api.Path, Body: api.Body}
+	}
 
-	baseURL := s.config.Sheikah.BaseURL
-	if baseURL == "" {
+	baseURL := cfg.Sheikah.BaseURL
+	if cfg.Demo {
+		baseURL = demoSheikahBaseURL
+	} else if baseURL == "" {
 		baseURL = "http://localhost:8080"
 	}
-	s.apiTool = secops.NewSecOpsSheikahAPITool(apis, baseURL, s.config.Sheikah.APIKey)
-	s.agentLoop.RegisterTool(s.apiTool)
+	apiTool := secops.NewSecOpsSheikahAPITool(apis, baseURL, cfg.Sheikah.APIKey)
+	if err := apiTool.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("invalid sheikah api config: %w", err)
+	}
 
 	logger.InfoCF("secops", "SecOps tools registered",
 		map[string]interface{}{
@@ -171,7 +725,25 @@ func (s *Service) initTools() error {
 			"apis_count":   len(apis),
 		})
 
-	return nil
+	if s.breaker != nil {
+		s.breaker.SetThreshold(threshold)
+	}
+	return queryTool, apiTool, nil
+}
+
+// registerTools 注册 query_data 和 sheikah_api 工具, 同名工具整体替换
+func (s *Service) registerTools(queryTool *secops.SecOpsQueryDataTool, apiTool *secops.SecOpsSheikahAPITool) {
+	s.configMu.Lock()
+	s.queryTool = queryTool
+	s.apiTool = apiTool
+	s.configMu.Unlock()
+
+	s.agentLoop.RegisterTool(queryTool)
+	s.agentLoop.RegisterTool(apiTool)
+
+	// 分析师决策后调用绑定的 API
+	s.proposalService.SetExecutor(apiTool.Call)
+	s.proposalService.SetRenderer(apiTool.Render)
 }
 
 // Start 启动安全运营服务
@@ -182,25 +754,105 @@ func (s *Service) Start() error {
 
 	logger.InfoCF("secops", "Starting SecOps service",
 		map[string]interface{}{
-			"activities": len(s.config.Activities),
+			"activities": len(s.activityConfigs()),
 		})
 
-	// 启动所有启用的活动
-	for name, actCfg := range s.config.Activities {
-		if !actCfg.Enabled {
+	// 启动所有启用的活动, 运行时启停覆盖优先于配置
+	s.mu.Lock()
+	for name, actCfg := range s.activityConfigs() {
+		if !s.activityEnabled(name) {
 			logger.InfoC("secops", fmt.Sprintf("Activity %s is disabled", name))
 			continue
 		}
+		s.startActivityLocked(name, actCfg, true)
+	}
+	s.started = true
+	s.mu.Unlock()
 
-		activity := &Activity{
-			Name:   name,
-			Config: &actCfg,
-			stopCh: make(chan struct{}),
-		}
-		s.activities[name] = activity
+	// 启动数据归档任务
+	if len(s.config.Retention.Classes) > 0 {
+		s.wg.Add(1)
+		go s.runRetention()
+	}
+
+	// 启动待处理提案过期检查
+	if s.expireInterval > 0 {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.proposalService.RunJanitor(s.ctx, s.expireInterval)
+		}()
+	}
+
+	// 启动 Wazuh 告警拉取
+	if s.wazuh != nil {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.wazuh.run(s.ctx)
+		}()
+	}
+
+	// 启动统计异常检测
+	if s.anomaly != nil {
+		s.wg.Add(1)
+		go s.runAnomalyDetection()
+	}
 
+	// 启动 ClickHouse 熔断探测
+	if s.breaker != nil {
 		s.wg.Add(1)
-		go s.runActivity(activity)
+		go s.runClickHouseProbe()
+	}
+
+	// 启动云安全发现同步
+	if s.cloud != nil {
+		s.wg.Add(1)
+		go s.runCloudSync()
+	}
+
+	// 发布此前确认的提案的 STIX 指标
+	if s.stix != nil {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.backfillIndicators()
+		}()
+	}
+
+	// 启动提醒和静默到期任务
+	s.wg.Add(1)
+	go s.runReminders()
+
+	// 启动失败执行的重试任务
+	s.wg.Add(1)
+	go s.runExecutionRetries()
+
+	// 启动汇总通知任务
+	if s.notifier.hasDigestRoutes() {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.notifier.runDigest(s.ctx)
+		}()
+	}
+
+	// 启动提案事件发布
+	if s.busEvents != nil {
+		s.wg.Add(1)
+		go s.runBusEvents()
+	}
+
+	// 启动交接班摘要任务
+	if len(s.handover.minutes) > 0 {
+		s.wg.Add(1)
+		go s.runHandover()
+	}
+
+	// 启动每日运营日报任务
+	if len(s.dailyReport.minutes) > 0 {
+		s.wg.Add(1)
+		go s.runDailyReport()
 	}
 
 	return nil
@@ -210,11 +862,7 @@ func (s *Service) Start() error {
 func (s *Service) runActivity(activity *Activity) {
 	defer s.wg.Done()
 
-	// 解析调度间隔
-	interval := s.parseSchedule(activity.Config.Schedule)
-	if interval <= 0 {
-		interval = 30 * time.Minute // 默认30分钟
-	}
+	interval := activity.interval
 
 	logger.InfoCF("secops", fmt.Sprintf("Activity %s started with interval %v", activity.Name, interval),
 		map[string]interface{}{
@@ -224,13 +872,36 @@ func (s *Service) runActivity(activity *Activity) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
+	// 执行时段外的调度合并为下一个时段开始时的一次执行
+	var deferTimer *time.Timer
+	defer func() {
+		if deferTimer != nil {
+			deferTimer.Stop()
+		}
+	}()
+	tick := func() {
+		if !s.deferOutsideWindow(activity, &deferTimer) {
+			s.runScheduled(activity)
+		}
+	}
+
 	// 立即执行一次
-	s.executeActivity(activity.Name)
+	if activity.runNow {
+		tick()
+	}
 
 	for {
+		var deferred <-chan time.Time
+		if deferTimer != nil {
+			deferred = deferTimer.C
+		}
 		select {
 		case <-ticker.C:
-			s.executeActivity(activity.Name)
+			tick()
+		case <-deferred:
+			deferTimer = nil
+			activity.deferredUntil.Store(nil)
+			s.runScheduled(activity)
 		case <-activity.stopCh:
 			logger.InfoC("secops", fmt.Sprintf("Activity %s stopped", activity.Name))
 			return
@@ -240,6 +911,53 @@ func (s *Service) runActivity(activity *Activity) {
 	}
 }
 
+// deferOutsideWindow 当前不在活动的执行时段内时推迟到下一个时段开始, 已推迟时只计数; 返回是否推迟
+func (s *Service) deferOutsideWindow(activity *Activity, timer **time.Timer) bool {
+	now := time.Now()
+	if activity.window == nil || activity.window.contains(now) {
+		return false
+	}
+	s.pool.skip(activity.Name, SkipOutsideWindow, now)
+	if *timer != nil {
+		return true
+	}
+
+	next := activity.window.next(now)
+	*timer = time.NewTimer(next.Sub(now))
+	activity.deferredUntil.Store(&next)
+	logger.InfoCF("secops", fmt.Sprintf("Activity %s deferred: outside window", activity.Name),
+		map[string]interface{}{
+			"window": activity.window.spec,
+			"until":  next.Format(time.RFC3339),
+		})
+	return true
+}
+
+// runScheduled 按调度触发活动, 配置了工作日历的活动在非工作日跳过;
+// 执行在后台排队, 不阻塞调度
+func (s *Service) runScheduled(activity *Activity) {
+	if name := activity.Config.Calendar; name != "" {
+		if cal, ok := s.Calendar(name); ok && !cal.IsBusinessDay(time.Now()) {
+			s.pool.skip(activity.Name, SkipNonBusinessDay, time.Now())
+			logger.InfoCF("secops", fmt.Sprintf("Activity %s skipped: not a business day", activity.Name),
+				map[string]interface{}{
+					"calendar": name,
+				})
+			return
+		}
+	}
+	if s.clickHouseDown() && !activity.Config.IgnoreBreaker {
+		s.pool.skip(activity.Name, SkipClickHouseDown, time.Now())
+		logger.WarnCF("secops", fmt.Sprintf("Activity %s skipped: ClickHouse is unavailable (circuit breaker open)", activity.Name),
+			map[string]interface{}{
+				"activity": activity.Name,
+			})
+		return
+	}
+
+	s.dispatch(activity.Name)
+}
+
 // parseSchedule 解析调度表达式
 func (s *Service) parseSchedule(schedule string) time.Duration {
 	// 简单解析：支持 "*/30 * * * *" 格式的 cron 和 "30m" 格式的间隔
@@ -267,28 +985,86 @@ func (s *Service) parseSchedule(schedule string) time.Duration {
 	return 30 * time.Minute
 }
 
-// executeActivity 执行活动
-func (s *Service) executeActivity(activityName string) {
+// execute 执行活动并运行执行后钩子, depth 为钩子链式触发的深度
+func (s *Service) execute(activityName string, depth int) *Run {
+	return s.executeRun(s.runs.start(activityName), depth)
+}
+
+// executeRun 执行已登记的活动执行记录
+func (s *Service) executeRun(run *Run, depth int) *Run {
+	activityName := run.Activity
 	logger.InfoC("secops", fmt.Sprintf("Executing activity: %s", activityName))
 
-	// 构建执行 prompt
-	prompt := s.buildActivityPrompt(activityName)
+	// 试运行时拦截修改类 Sheikah API 调用; 执行使用独立的上下文, 停止服务时可在排空时长内完成
+	ctx := withActivity(s.runCtx, activityName)
+	actCfg, _ := s.activityConfig(activityName)
+	canary := actCfg.Mode == "auto" && !actCfg.DryRun && !s.FeatureEnabled(FeatureAutoMode, activityName)
+	if canary {
+		logger.InfoCF("secops", "Auto mode disabled by feature flag, running as dry run",
+			map[string]interface{}{
+				"activity": activityName,
+				"feature":  FeatureAutoMode,
+			})
+	}
+	if actCfg.DryRun || canary {
+		s.runs.markDryRun(run)
+		ctx = secops.WithDryRun(ctx)
+	}
 
-	// 使用 agent loop 执行
-	channel := "secops"
-	chatID := activityName
+	// 上一次执行被中断时从续跑点接续
+	rp := s.runs.resume(run)
+	if rp != nil {
+		logger.InfoCF("secops", "Resuming interrupted activity run",
+			map[string]interface{}{
+				"activity":  activityName,
+				"run_id":    run.ID,
+				"resume_of": rp.RunID,
+				"proposals": len(rp.ProposalIDs),
+				"steps":     len(rp.Steps),
+			})
+	}
 
-	_, err := s.agentLoop.ProcessHeartbeat(s.ctx, prompt, channel, chatID)
+	var response string
+	var err error
+	if actCfg.Playbook != "" {
+		// 按剧本执行确定的步骤
+		response, err = s.runPlaybook(ctx, run, actCfg.Playbook, rp)
+	} else {
+		// 使用 agent loop 执行, prompt 附带分析师近期的否决理由作为反馈和工作语言要求
+		// 工具调用记录以执行 ID 作为 trace ID, 可按执行查看调用过程
+		prompt := buildActivityPrompt(activityName) + s.overrideFeedback(activityName) +
+			languageInstruction(s.outputLanguage(activityName))
+		if rp != nil {
+			prompt += s.resumeNote(rp)
+		}
+		response, err = s.agentLoop.ProcessHeartbeat(agent.WithTraceID(ctx, run.ID), prompt, "secops", activityName)
+	}
+	if err != nil && s.runCtx.Err() != nil {
+		// 排空超时被中断, 不执行钩子
+		s.runs.interrupt(run, response, err)
+		s.releaseHostEvents(run)
+		logger.WarnCF("secops", "Activity run interrupted by shutdown",
+			map[string]interface{}{
+				"activity":  activityName,
+				"run_id":    run.ID,
+				"proposals": len(run.ProposalIDs),
+			})
+		return run
+	}
+	s.runs.finish(run, response, err)
 	if err != nil {
 		logger.ErrorC("secops", fmt.Sprintf("Activity %s failed: %v", activityName, err))
-		return
+		s.releaseHostEvents(run)
+	} else {
+		logger.InfoC("secops", fmt.Sprintf("Activity %s completed", activityName))
 	}
 
-	logger.InfoC("secops", fmt.Sprintf("Activity %s completed", activityName))
+	s.runHooks(run, depth)
+	return run
 }
 
 // buildActivityPrompt 构建活动执行 prompt
-func (s *Service) buildActivityPrompt(activityName string) string {
+func buildActivityPrompt(activityName string) string {
 	switch activityName {
 	case "risk_analysis":
 		return `请执行风险事件研判分析：
@@ -326,11 +1102,87 @@ func (s *Service) buildActivityPrompt(activityName string) string {
 
 请开始执行应用识别。`
 
+	case "host_analysis":
+		return `请执行主机告警研判：
+1. 使用 query_data 工具领取待研判的 Wazuh 主机告警 (source: wazuh, sql_id: pending_host_events, params: batch_size=5), 领取后不会再次返回
+2. 必要时查询同一主机的近期告警 (source: wazuh, sql_id: host_events_by_agent, params: agent=<主机名>), 并结合 src_ip 查询网络侧访问记录
+3. 分析告警是否为真实入侵、误报或已知运维操作, 同一主机的相关告警合并为一个提案
+4. 使用 secops_proposal 工具创建 host 类型提案, 按规则级别参考严重级别 (12 及以上 critical, 10-11 high, 7-9 medium),
+   techniques 填写涉及的 ATT&CK 技术编号 (可参考告警的 mitre 列)
+
+请开始执行主机告警研判。`
+
+	case "k8s_audit_analysis":
+		return `请执行 Kubernetes 审计日志分析：
+1. 使用 query_data 工具查询最近 1 小时的 RBAC 变更 (sql_id: k8s_rbac_changes)、进入 Pod 的 exec/attach/portforward (sql_id: k8s_pod_exec) 和非系统账号的 Secret 读取 (sql_id: k8s_secret_access);
+   审计日志存放在 Loki 时, 对 Loki 数据源 (source) 执行同名查询
+2. 对可疑操作查询该账号最近的其他操作 (sql_id: k8s_user_activity, params: user=<账号>), 判断是否为正常运维、CI/CD 或越权行为
+3. 重点关注: 绑定 cluster-admin 等高权限角色、匿名或非预期账号的操作、生产命名空间中的 exec、批量读取 Secret、被拒绝 (403) 后重试成功的操作
+4. 使用 secops_proposal 工具创建 k8s 类型提案, details 填写 user、namespace、resource、name, 在摘要中给出建议的策略响应
+   (如回收 RoleBinding、收紧 Role 权限、通过准入策略禁止生产环境 exec、轮换泄露的 Secret);
+   techniques 填写涉及的 ATT&CK 技术编号 (如 exec 为 T1609, 绑定高权限角色为 T1098, 读取 Secret 为 T1552.007)
+
+请开始执行 Kubernetes 审计日志分析。`
+
+	case "anomaly_analysis":
+		return `请执行流量异常研判：
+1. 使用 query_data 工具领取统计检测产生的预警 (source: anomaly, sql_id: pending_anomalies, params: batch_size=5), 领取后不会再次返回;
+   每条预警给出主机、指标 (requests 请求量, error_rate 错误率)、方向 (spike 突增, drop 骤降)、本窗口取值、基线 (expected) 和偏离程度 (score)
+2. 查询该主机的近期预警 (source: anomaly, sql_id: anomalies_by_host, params: host=<主机>) 判断是否持续偏离, 并查询窗口内的访问记录和风险事件,
+   找出贡献最多的来源 IP、用户和 URL
+3. 判断偏离的原因: 扫描、爬虫、撞库、CC 攻击等恶意流量, 还是发布、促销活动、上游故障等业务原因; 请求量骤降可能意味着服务中断或流量被劫持
+4. 确认为攻击时使用 secops_proposal 工具创建 risk 类型提案, details 填写 host 和主要来源 (src_ip/user), 证据附上聚合查询和样本;
+   可解释的业务波动不创建提案, 必要时通过 blackboard 工具发布到 change 命名空间供其他活动参考
+
+请开始执行流量异常研判。`
+
 	default:
 		return fmt.Sprintf(`请执行安全运营活动: %s`, activityName)
 	}
 }
 
+// activityProposalTypes 活动与其产出提案类型的对应关系
+var activityProposalTypes = map[string]string{
+	"risk_analysis":      "risk",
+	"weak_analysis":      "weak",
+	"api_biz_explain":    "api_biz",
+	"app_explain":        "app",
+	"host_analysis":      "host",
+	"k8s_audit_analysis": "k8s",
+	"anomaly_analysis":   "risk",
+}
+
+// maxOverrideFeedback 每次注入 prompt 的否决理由条数
+const maxOverrideFeedback = 5
+
+// overrideFeedback 汇总近 7 天分析师与 Agent 建议相反的决策理由, 供 Agent 修正判断
+func (s *Service) overrideFeedback(activityName string) string {
+	proposalType, ok := activityProposalTypes[activityName]
+	if !ok {
+		return ""
+	}
+
+	overrides := s.proposalService.Overrides(proposalType, time.Now().AddDate(0, 0, -7))
+	if len(overrides) == 0 {
+		return ""
+	}
+	if len(overrides) > maxOverrideFeedback {
+		overrides = overrides[:maxOverrideFeedback]
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\n\n近期分析师否决了以下建议, 请参考其理由调整判断:\n")
+	for _, p := range overrides {
+		reason := p.Decision.Reason
+		if reason == "" {
+			reason = "(未填写理由)"
+		}
+		sb.WriteString(fmt.Sprintf("- %s: 建议 %s, 分析师 %s, 理由: %s\n",
+			p.Title, p.Recommendation, p.Decision.Action, reason))
+	}
+	return sb.String()
+}
+
 // Stop 停止安全运营服务
 func (s *Service) Stop() {
 	if s == nil {
@@ -342,18 +1194,33 @@ func (s *Service) Stop() {
 	s.cancel()
 
 	// 停止所有活动
-	for _, activity := range s.activities {
+	s.mu.Lock()
+	for name, activity := range s.activities {
 		close(activity.stopCh)
+		delete(s.activities, name)
 	}
+	s.mu.Unlock()
 
-	s.wg.Wait()
+	// 等待执行中的活动完成当前的分析和提案创建, 超时后中断
+	s.drain()
 
 	// 关闭工具
-	if s.queryTool != nil {
-		s.queryTool.Close()
+	queryTool, apiTool := s.tools()
+	if queryTool != nil {
+		queryTool.Close()
+	}
+	if apiTool != nil {
+		apiTool.Close()
+	}
+	if err := s.proposalService.Flush(); err != nil {
+		logger.ErrorCF("secops", "Failed to persist proposals",
+			map[string]interface{}{
+				"error": err.Error(),
+			})
 	}
-	if s.apiTool != nil {
-		s.apiTool.Close()
+	s.proposalService.audit.close()
+	if s.cache != nil {
+		s.cache.Close()
 	}
 
 	logger.InfoC("secops", "SecOps service stopped")
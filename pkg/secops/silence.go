@@ -0,0 +1,264 @@
+package secops
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// maxSilenceAudit 静默审计记录的最大保留条数
+const maxSilenceAudit = 1000
+
+// Silence 通知静默规则, 在到期前屏蔽匹配提案的通知和提醒
+type Silence struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type,omitempty"` // 提案类型, 为空表示全部类型
+	Host      string    `json:"host,omitempty"` // 提案 details.host, 为空表示全部主机
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// 静默审计动作
+const (
+	SilenceCreated = "created"
+	SilenceRemoved = "removed"
+	SilenceExpired = "expired"
+)
+
+// SilenceEvent 静默审计记录
+type SilenceEvent struct {
+	Action  string    `json:"action"` // created, removed, expired
+	Silence Silence   `json:"silence"`
+	Reason  string    `json:"reason,omitempty"` // 手动解除时的理由
+	At      time.Time `json:"at"`
+}
+
+// matches 判断提案是否命中静默规则
+func (sl *Silence) matches(p *Proposal) bool {
+	if sl.Type != "" && sl.Type != p.Type {
+		return false
+	}
+	if sl.Host != "" {
+		host, _ := p.Details["host"].(string)
+		if host != sl.Host {
+			return false
+		}
+	}
+	return true
+}
+
+// silenceStore 静默规则及其审计记录
+type silenceStore struct {
+	silences map[string]*Silence
+	audit    []SilenceEvent
+	path     string
+	mu       sync.RWMutex
+}
+
+func newSilenceStore() *silenceStore {
+	return &silenceStore{silences: make(map[string]*Silence)}
+}
+
+// load 加载已持久化的静默规则和审计记录
+func (ss *silenceStore) load(path string) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	ss.path = path
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var state struct {
+		Silences []*Silence     `json:"silences"`
+		Audit    []SilenceEvent `json:"audit"`
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for _, sl := range state.Silences {
+		ss.silences[sl.ID] = sl
+	}
+	ss.audit = state.Audit
+	return nil
+}
+
+// add 新增静默规则
+func (ss *silenceStore) add(proposalType, host string, d time.Duration, reason string) (*Silence, error) {
+	if proposalType == "" && host == "" {
+		return nil, fmt.Errorf("silence requires a proposal type or host")
+	}
+	if proposalType != "" && !validProposalTypes[proposalType] {
+		return nil, fmt.Errorf("invalid proposal type: %q", proposalType)
+	}
+	if d <= 0 {
+		return nil, fmt.Errorf("silence duration must be positive")
+	}
+
+	now := time.Now()
+	sl := &Silence{
+		ID:        uuid.New().String(),
+		Type:      proposalType,
+		Host:      host,
+		Reason:    reason,
+		CreatedAt: now,
+		ExpiresAt: now.Add(d),
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	ss.silences[sl.ID] = sl
+	ss.recordLocked(SilenceCreated, sl, "", now)
+	ss.saveLocked()
+	return sl, nil
+}
+
+// remove 手动解除静默
+func (ss *silenceStore) remove(id, reason string) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	sl, ok := ss.silences[id]
+	if !ok {
+		return fmt.Errorf("silence not found: %s", id)
+	}
+	delete(ss.silences, id)
+	ss.recordLocked(SilenceRemoved, sl, reason, time.Now())
+	ss.saveLocked()
+	return nil
+}
+
+// expire 清理已到期的静默规则, 返回解除的数量
+func (ss *silenceStore) expire(now time.Time) int {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	expired := 0
+	for id, sl := range ss.silences {
+		if !now.Before(sl.ExpiresAt) {
+			delete(ss.silences, id)
+			ss.recordLocked(SilenceExpired, sl, "", sl.ExpiresAt)
+			expired++
+		}
+	}
+	if expired > 0 {
+		ss.saveLocked()
+	}
+	return expired
+}
+
+// match 返回提案命中的第一条有效静默规则
+func (ss *silenceStore) match(p *Proposal, now time.Time) *Silence {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	for _, sl := range ss.silences {
+		if now.Before(sl.ExpiresAt) && sl.matches(p) {
+			return sl
+		}
+	}
+	return nil
+}
+
+// list 有效的静默规则, 按到期时间排序
+func (ss *silenceStore) list(now time.Time) []*Silence {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	result := make([]*Silence, 0, len(ss.silences))
+	for _, sl := range ss.silences {
+		if now.Before(sl.ExpiresAt) {
+			result = append(result, sl)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ExpiresAt.Before(result[j].ExpiresAt)
+	})
+	return result
+}
+
+// auditLog 审计记录, 最新的在前
+func (ss *silenceStore) auditLog() []SilenceEvent {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
+	result := make([]SilenceEvent, len(ss.audit))
+	for i, ev := range ss.audit {
+		result[len(ss.audit)-1-i] = ev
+	}
+	return result
+}
+
+func (ss *silenceStore) recordLocked(action string, sl *Silence, reason string, at time.Time) {
+	ss.audit = append(ss.audit, SilenceEvent{Action: action, Silence: *sl, Reason: reason, At: at})
+	if len(ss.audit) > maxSilenceAudit {
+		ss.audit = ss.audit[len(ss.audit)-maxSilenceAudit:]
+	}
+
+	logger.InfoCF("secops", fmt.Sprintf("Silence %s", action),
+		map[string]interface{}{
+			"id":         sl.ID,
+			"type":       sl.Type,
+			"host":       sl.Host,
+			"expires_at": sl.ExpiresAt,
+			"reason":     reason,
+		})
+}
+
+func (ss *silenceStore) saveLocked() {
+	if ss.path == "" {
+		return
+	}
+	silences := make([]*Silence, 0, len(ss.silences))
+	for _, sl := range ss.silences {
+		silences = append(silences, sl)
+	}
+	state := map[string]interface{}{
+		"silences": silences,
+		"audit":    ss.audit,
+	}
+	if err := saveJSONAtomic(ss.path, state); err != nil {
+		logger.ErrorCF("secops", "Failed to persist silences",
+			map[string]interface{}{
+				"path":  ss.path,
+				"error": err.Error(),
+			})
+	}
+}
+
+// AddSilence 静默某类型或某主机的提案通知 d 时间, 到期自动解除
+func (s *Service) AddSilence(proposalType, host string, d time.Duration, reason string) (*Silence, error) {
+	return s.silences.add(proposalType, host, d, reason)
+}
+
+// RemoveSilence 提前解除静默
+func (s *Service) RemoveSilence(id, reason string) error {
+	return s.silences.remove(id, reason)
+}
+
+// Silences 当前有效的静默规则
+func (s *Service) Silences() []*Silence {
+	return s.silences.list(time.Now())
+}
+
+// SilenceAudit 静默的创建、解除和到期记录, 最新的在前
+func (s *Service) SilenceAudit() []SilenceEvent {
+	return s.silences.auditLog()
+}
@@ -0,0 +1,109 @@
+package secops
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestSilenceStore_MatchAndExpire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "silences.json")
+	ss := newSilenceStore()
+	if err := ss.load(path); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	if _, err := ss.add("", "", time.Hour, ""); err == nil {
+		t.Error("expected error for silence without type or host")
+	}
+	byHost, err := ss.add("", "10.0.0.1", time.Hour, "maintenance")
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if _, err := ss.add("weak", "", 2*time.Hour, ""); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	now := time.Now()
+	onHost := &Proposal{Type: "risk", Details: map[string]interface{}{"host": "10.0.0.1"}}
+	other := &Proposal{Type: "risk", Details: map[string]interface{}{"host": "10.0.0.2"}}
+	if sl := ss.match(onHost, now); sl == nil || sl.ID != byHost.ID {
+		t.Errorf("host silence not matched: %+v", sl)
+	}
+	if sl := ss.match(other, now); sl != nil {
+		t.Errorf("unexpected match: %+v", sl)
+	}
+	if ss.match(&Proposal{Type: "weak"}, now) == nil {
+		t.Error("type silence not matched")
+	}
+
+	// 一小时后主机静默自动解除, 类型静默仍有效
+	later := now.Add(90 * time.Minute)
+	if n := ss.expire(later); n != 1 {
+		t.Errorf("expired = %d, want 1", n)
+	}
+	if ss.match(onHost, later) != nil {
+		t.Error("expired silence still matched")
+	}
+
+	// 重新加载后静默规则和审计记录保留
+	reloaded := newSilenceStore()
+	if err := reloaded.load(path); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if got := len(reloaded.list(later)); got != 1 {
+		t.Errorf("active silences = %d, want 1", got)
+	}
+	audit := reloaded.auditLog()
+	if len(audit) != 3 || audit[0].Action != SilenceExpired || audit[0].Silence.ID != byHost.ID {
+		t.Errorf("audit = %+v", audit)
+	}
+}
+
+func TestSendReminders_SkipsAcknowledgedAndSilenced(t *testing.T) {
+	n, err := NewNotifier(config.NotificationConfig{
+		Targets:     map[string]config.NotifyTargetConfig{"soc": {Type: NotifyTargetChannel, Channel: "slack", ChatID: "C1"}},
+		Routes:      []config.NotifyRouteConfig{{Targets: []string{"soc"}}},
+		RemindAfter: "1h",
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewNotifier: %v", err)
+	}
+	svc := &Service{
+		ctx:             context.Background(),
+		runCtx:          context.Background(),
+		config:          &config.SecOpsConfig{},
+		proposalService: NewProposalService(),
+		notifier:        n,
+		silences:        newSilenceStore(),
+	}
+
+	for _, id := range []string{"plain", "acked", "silenced"} {
+		p := NewProposal("risk", id, "", map[string]interface{}{"host": id})
+		p.ID = id
+		p.CreatedAt = time.Now().Add(-2 * time.Hour)
+		svc.proposalService.Create(p)
+	}
+	if _, err := svc.Acknowledge("acked", 4*time.Hour, "looking into it", Actor{Via: ViaDebugUI}); err != nil {
+		t.Fatalf("Acknowledge: %v", err)
+	}
+	if _, err := svc.AddSilence("", "silenced", time.Hour, ""); err != nil {
+		t.Fatalf("AddSilence: %v", err)
+	}
+
+	now := time.Now()
+	if sent := svc.sendReminders(now); sent != 1 {
+		t.Errorf("reminders = %d, want 1", sent)
+	}
+	// 提醒间隔内不重复提醒
+	if sent := svc.sendReminders(now.Add(30 * time.Minute)); sent != 0 {
+		t.Errorf("repeat reminders = %d, want 0", sent)
+	}
+	// 知悉到期后恢复提醒
+	if sent := svc.sendReminders(now.Add(5 * time.Hour)); sent != 3 {
+		t.Errorf("reminders after expiry = %d, want 3", sent)
+	}
+}
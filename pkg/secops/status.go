@@ -0,0 +1,270 @@
+package secops
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/kvstore"
+)
+
+// 依赖健康状态
+const (
+	HealthOK            = "ok"
+	HealthDown          = "down"
+	HealthNotConfigured = "not_configured"
+	HealthConfigured    = "configured" // 已配置但不做主动探测
+	HealthDemo          = "demo"       // 演示模式, 使用内置示例数据
+)
+
+const (
+	healthTimeout  = 2 * time.Second
+	healthCacheTTL = 30 * time.Second
+)
+
+// ActivitySummary 运营活动概况
+type ActivitySummary struct {
+	Name       string     `json:"name"`
+	Enabled    bool       `json:"enabled"`
+	Overridden bool       `json:"overridden,omitempty"` // 启停状态由界面覆盖, 与配置文件不同
+	Mode       string     `json:"mode"`
+	Schedule   string     `json:"schedule"`
+	Calendar   string     `json:"calendar,omitempty"`
+	Window     string     `json:"window,omitempty"`
+	Hooks      int        `json:"hooks"`
+	DryRun     bool       `json:"dryRun,omitempty"`   // 试运行, 不调用修改类 Sheikah API
+	Playbook   string     `json:"playbook,omitempty"` // 按剧本执行时的剧本文件
+	LastStatus string     `json:"lastStatus,omitempty"`
+	LastRunAt  *time.Time `json:"lastRunAt,omitempty"`
+	LastError  string     `json:"lastError,omitempty"`
+	Running    bool       `json:"running,omitempty"`   // 正在执行
+	Queued     bool       `json:"queued,omitempty"`    // 等待空闲的执行名额
+	Skipped    uint64     `json:"skipped,omitempty"`   // 启动以来被跳过的调度次数
+	NextRunAt  *time.Time `json:"nextRunAt,omitempty"` // 下次调度时间, 未调度时为空
+}
+
+// DependencyHealth 外部依赖健康状态; Target 只包含协议和主机, 不含凭据
+type DependencyHealth struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Target    string `json:"target,omitempty"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latencyMs,omitempty"`
+
+	CircuitOpen bool `json:"circuitOpen,omitempty"` // ClickHouse 熔断中, 查询直接失败、调度的活动跳过
+}
+
+// healthCache 依赖探测结果缓存, 避免频繁刷新页面时反复探测
+type healthCache struct {
+	mu        sync.Mutex
+	checkedAt time.Time
+	results   []DependencyHealth
+	probing   bool // 探测进行中, 其他请求直接返回上一次的结果
+}
+
+// ActivitySummaries 返回所有已配置活动 (含未启用) 及最近一次执行结果
+func (s *Service) ActivitySummaries() []ActivitySummary {
+	now := time.Now()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	activities := s.activityConfigs()
+	summaries := make([]ActivitySummary, 0, len(activities))
+	for name, cfg := range activities {
+		sum := ActivitySummary{
+			Name:     name,
+			Enabled:  s.activityEnabled(name),
+			Mode:     cfg.Mode,
+			Schedule: cfg.Schedule,
+			Calendar: cfg.Calendar,
+			Window:   cfg.Window,
+			Hooks:    len(cfg.Hooks),
+			DryRun:   cfg.DryRun,
+			Playbook: cfg.Playbook,
+		}
+		sum.Overridden = sum.Enabled != cfg.Enabled
+		sum.Running = s.runs.running(name)
+		sum.Queued = !sum.Running && s.pool.pending(name)
+		sum.Skipped = s.pool.skipCount(name)
+		if activity, ok := s.activities[name]; ok {
+			if next := activity.nextRun(now); !next.IsZero() {
+				sum.NextRunAt = &next
+			}
+		}
+		if runs := s.runs.list(name); len(runs) > 0 {
+			last := runs[0]
+			started := last.StartedAt
+			sum.LastStatus = last.Status
+			sum.LastRunAt = &started
+			sum.LastError = last.Error
+		}
+		summaries = append(summaries, sum)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Name < summaries[j].Name
+	})
+	return summaries
+}
+
+// TranslationEnabled 是否自动翻译新提案
+func (s *Service) TranslationEnabled() bool {
+	return s.config.Translation.Enabled
+}
+
+// DependencyHealth 探测 ClickHouse、Sheikah API 等外部依赖, 结果缓存 30 秒;
+// 探测不持有缓存锁, 完成后替换结果, 依赖超时不会阻塞其他请求
+func (s *Service) DependencyHealth(ctx context.Context) []DependencyHealth {
+	s.health.mu.Lock()
+	if s.health.results != nil && (s.health.probing || time.Since(s.health.checkedAt) < healthCacheTTL) {
+		defer s.health.mu.Unlock()
+		return s.health.results
+	}
+	s.health.probing = true
+	s.health.mu.Unlock()
+
+	results := s.probeDependencies(ctx)
+
+	s.health.mu.Lock()
+	defer s.health.mu.Unlock()
+	s.health.results = results
+	s.health.checkedAt = time.Now()
+	s.health.probing = false
+	return results
+}
+
+// probeDependencies 并发探测各依赖
+func (s *Service) probeDependencies(ctx context.Context) []DependencyHealth {
+	if s.config.Demo {
+		return []DependencyHealth{
+			{Name: "clickhouse", Status: HealthDemo},
+			{Name: "sheikah", Status: HealthDemo},
+			s.objectStoreHealth(),
+		}
+	}
+
+	s.configMu.RLock()
+	chAddr, sheikahURL := s.config.ClickHouse.Addr, s.config.Sheikah.BaseURL
+	replicas := s.config.ClickHouse.Replicas
+	s.configMu.RUnlock()
+	if chAddr == "" {
+		chAddr = "localhost:8123"
+	}
+	checks := []func(context.Context) DependencyHealth{
+		func(ctx context.Context) DependencyHealth {
+			h := probeHTTP(ctx, "clickhouse", "http://"+chAddr+"/ping")
+			h.CircuitOpen = s.clickHouseDown()
+			return h
+		},
+		func(ctx context.Context) DependencyHealth {
+			if sheikahURL == "" {
+				return DependencyHealth{Name: "sheikah", Status: HealthNotConfigured}
+			}
+			return probeHTTP(ctx, "sheikah", sheikahURL)
+		},
+		func(ctx context.Context) DependencyHealth {
+			return s.objectStoreHealth()
+		},
+		s.cacheHealth,
+	}
+	for i, addr := range replicas {
+		name, pingURL := fmt.Sprintf("clickhouse_replica_%d", i+1), "http://"+strings.TrimSpace(addr)+"/ping"
+		checks = append(checks, func(ctx context.Context) DependencyHealth {
+			return probeHTTP(ctx, name, pingURL)
+		})
+	}
+
+	results := make([]DependencyHealth, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check func(context.Context) DependencyHealth) {
+			defer wg.Done()
+			results[i] = check(ctx)
+		}(i, check)
+	}
+	wg.Wait()
+	return results
+}
+
+// objectStoreHealth 对象存储只报告是否已配置
+func (s *Service) objectStoreHealth() DependencyHealth {
+	if s.objectStore == nil {
+		return DependencyHealth{Name: "object_store", Status: HealthNotConfigured}
+	}
+	return DependencyHealth{
+		Name:   "object_store",
+		Status: HealthConfigured,
+		Target: redactURL(s.config.ObjectStore.Endpoint),
+	}
+}
+
+// cacheHealth 使用 Redis 时探测连接, 探测成功后从进程内存储切回 Redis
+func (s *Service) cacheHealth(ctx context.Context) DependencyHealth {
+	if s.cache == nil || s.config.Cache.Backend != kvstore.BackendRedis {
+		return DependencyHealth{Name: "cache", Status: HealthNotConfigured}
+	}
+	h := DependencyHealth{Name: "cache", Target: redactURL(s.config.Cache.Redis.URL)}
+
+	ctx, cancel := context.WithTimeout(ctx, healthTimeout)
+	defer cancel()
+	start := time.Now()
+	err := kvstore.Ping(ctx, s.cache)
+	h.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		h.Status = HealthDown
+		h.Error = err.Error()
+		return h
+	}
+	h.Status = HealthOK
+	return h
+}
+
+// probeHTTP 任何 HTTP 响应都视为可达; 仅 5xx 和网络错误视为异常
+func probeHTTP(ctx context.Context, name, rawURL string) DependencyHealth {
+	h := DependencyHealth{Name: name, Target: redactURL(rawURL)}
+
+	ctx, cancel := context.WithTimeout(ctx, healthTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		h.Status = HealthDown
+		h.Error = "invalid url"
+		return h
+	}
+
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	h.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		h.Status = HealthDown
+		// url.Error 会带上完整地址, 只保留底层错误
+		if ue, ok := err.(*url.Error); ok {
+			err = ue.Err
+		}
+		h.Error = err.Error()
+		return h
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		h.Status = HealthDown
+		h.Error = fmt.Sprintf("HTTP %d", resp.StatusCode)
+		return h
+	}
+	h.Status = HealthOK
+	return h
+}
+
+// redactURL 只保留协议和主机, 去掉用户信息、路径和查询参数中可能携带的凭据
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
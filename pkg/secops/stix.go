@@ -0,0 +1,593 @@
+package secops
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const (
+	taxiiMediaType    = "application/taxii+json;version=2.1"
+	defaultStixValid  = 30 * 24 * time.Hour
+	maxStixProposals  = 20 // 每个指标记录的最近确认提案数
+	maxTaxiiPushBatch = 100
+)
+
+// stixNamespace 生成确定性 STIX ID 的命名空间, 相同的 IOC 始终对应同一指标
+var stixNamespace = uuid.MustParse("7c0b3f1e-5d1a-4c65-9c1e-2f0d6b1a8e42")
+
+// defaultStixTypes 默认提取 IOC 的提案类型: 确认后表示存在真实攻击
+var defaultStixTypes = []string{"risk", "host", "k8s"}
+
+// iocKeys 提案详情中存放攻击方 IOC 的字段; details.iocs 可列出任意类型的 IOC
+//
+// host、dst_ip 等为受害方资产, 不提取
+var iocKeys = []string{"ip", "src_ip", "source_ip", "attacker_ip", "c2", "c2_ip", "c2_domain", "c2_url",
+	"malicious_domain", "malicious_url", "file_hash", "hash", "md5", "sha1", "sha256"}
+
+var (
+	iocDomain = regexp.MustCompile(`^(?i)([a-z0-9]([a-z0-9-]*[a-z0-9])?\.)+[a-z]{2,63}$`)
+	iocHash   = regexp.MustCompile(`^(?i)[0-9a-f]+$`)
+)
+
+// stixHashAlgorithms 按长度识别的文件哈希算法
+var stixHashAlgorithms = map[int]string{32: "MD5", 40: "SHA-1", 64: "SHA-256"}
+
+// StixIndicator STIX 2.1 Indicator 对象
+type StixIndicator struct {
+	Type               string                  `json:"type"`
+	SpecVersion        string                  `json:"spec_version"`
+	ID                 string                  `json:"id"`
+	CreatedByRef       string                  `json:"created_by_ref"`
+	Created            time.Time               `json:"created"`
+	Modified           time.Time               `json:"modified"`
+	Name               string                  `json:"name"`
+	Description        string                  `json:"description,omitempty"`
+	IndicatorTypes     []string                `json:"indicator_types"`
+	Pattern            string                  `json:"pattern"`
+	PatternType        string                  `json:"pattern_type"`
+	ValidFrom          time.Time               `json:"valid_from"`
+	ValidUntil         time.Time               `json:"valid_until"`
+	Labels             []string                `json:"labels,omitempty"`
+	KillChainPhases    []StixKillChainPhase    `json:"kill_chain_phases,omitempty"`
+	ExternalReferences []StixExternalReference `json:"external_references,omitempty"`
+	Proposals          []string                `json:"x_soclaw_proposals,omitempty"` // 确认该指标的提案, 最近的在后
+}
+
+// StixKillChainPhase 指标所处的攻击阶段, 由提案的 ATT&CK 战术推导
+type StixKillChainPhase struct {
+	KillChainName string `json:"kill_chain_name"`
+	PhaseName     string `json:"phase_name"`
+}
+
+// StixExternalReference 指向 ATT&CK 技术的外部引用
+type StixExternalReference struct {
+	SourceName string `json:"source_name"`
+	ExternalID string `json:"external_id,omitempty"`
+	URL        string `json:"url,omitempty"`
+}
+
+// StixIdentity 指标的创建者
+type StixIdentity struct {
+	Type          string    `json:"type"`
+	SpecVersion   string    `json:"spec_version"`
+	ID            string    `json:"id"`
+	Created       time.Time `json:"created"`
+	Modified      time.Time `json:"modified"`
+	Name          string    `json:"name"`
+	IdentityClass string    `json:"identity_class"`
+}
+
+// StixBundle STIX 2.1 Bundle
+type StixBundle struct {
+	Type    string        `json:"type"`
+	ID      string        `json:"id"`
+	Objects []interface{} `json:"objects"`
+}
+
+// IndicatorEntry 已发布的指标及其加入时间, 对应 TAXII 的 date_added
+type IndicatorEntry struct {
+	Indicator StixIndicator `json:"indicator"`
+	DateAdded time.Time     `json:"dateAdded"`
+	Pushed    bool          `json:"pushed,omitempty"` // 当前版本已推送到外部 TAXII 集合
+}
+
+// stixIdentity soclaw 自身的 identity, 创建时间固定以保证 ID 和内容稳定
+var stixIdentity = StixIdentity{
+	Type:          "identity",
+	SpecVersion:   "2.1",
+	ID:            "identity--" + uuid.NewSHA1(stixNamespace, []byte("soclaw")).String(),
+	Created:       time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	Modified:      time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	Name:          "soclaw",
+	IdentityClass: "system",
+}
+
+// observable 从提案中提取的一个 IOC
+type observable struct {
+	value   string
+	pattern string
+}
+
+// stixExport 已确认提案的指标存储与发布
+type stixExport struct {
+	types   []string
+	valid   time.Duration
+	file    string // 导出文件, 为空时不导出
+	push    config.TAXIIPushConfig
+	client  *http.Client
+	entries map[string]*IndicatorEntry // 按指标 ID 索引
+	path    string                     // 持久化文件, 为空时仅保存在内存中
+	now     func() time.Time
+	mu      sync.RWMutex
+	pushMu  sync.Mutex
+}
+
+// newSTIXExport 未开启时返回 nil
+func newSTIXExport(cfg config.STIXExportConfig, workspace string) (*stixExport, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	se := &stixExport{
+		types:   cfg.Types,
+		valid:   defaultStixValid,
+		push:    cfg.Push,
+		client:  &http.Client{Timeout: 30 * time.Second},
+		entries: make(map[string]*IndicatorEntry),
+		now:     time.Now,
+	}
+	if len(se.types) == 0 {
+		se.types = defaultStixTypes
+	}
+	for _, t := range se.types {
+		if !validProposalTypes[t] {
+			return nil, fmt.Errorf("unknown proposal type %q", t)
+		}
+	}
+	if cfg.ValidDays < 0 {
+		return nil, fmt.Errorf("valid_days must not be negative")
+	}
+	if cfg.ValidDays > 0 {
+		se.valid = time.Duration(cfg.ValidDays) * 24 * time.Hour
+	}
+	if cfg.File != "" {
+		se.file = cfg.File
+		if !filepath.IsAbs(se.file) && workspace != "" {
+			se.file = filepath.Join(workspace, se.file)
+		}
+	}
+	if cfg.Push.URL != "" {
+		u, err := url.Parse(cfg.Push.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid taxii_push.url: %q", cfg.Push.URL)
+		}
+	}
+	return se, nil
+}
+
+// load 加载已发布的指标
+func (se *stixExport) load(path string) error {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	se.path = path
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var state struct {
+		Indicators []*IndicatorEntry `json:"indicators"`
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for _, e := range state.Indicators {
+		se.entries[e.Indicator.ID] = e
+	}
+	return nil
+}
+
+// persistLocked 持久化指标, 调用方需持有锁
+func (se *stixExport) persistLocked() {
+	if se.path == "" {
+		return
+	}
+	if err := saveJSONAtomic(se.path, map[string]interface{}{"indicators": se.sortedLocked(time.Time{})}); err != nil {
+		logger.ErrorCF("secops", "Failed to persist STIX indicators",
+			map[string]interface{}{
+				"path":  se.path,
+				"error": err.Error(),
+			})
+	}
+}
+
+// exportLocked 将有效期内的指标导出为 bundle 文件, 调用方需持有锁
+func (se *stixExport) exportLocked() {
+	if se.file == "" {
+		return
+	}
+	err := os.MkdirAll(filepath.Dir(se.file), 0700)
+	if err == nil {
+		err = saveJSONAtomic(se.file, se.bundleLocked())
+	}
+	if err != nil {
+		logger.ErrorCF("secops", "Failed to export STIX bundle",
+			map[string]interface{}{
+				"path":  se.file,
+				"error": err.Error(),
+			})
+	}
+}
+
+// sortedLocked date_added 晚于 after 的指标, 按加入时间排序, 调用方需持有锁
+func (se *stixExport) sortedLocked(after time.Time) []*IndicatorEntry {
+	result := make([]*IndicatorEntry, 0, len(se.entries))
+	for _, e := range se.entries {
+		if e.DateAdded.After(after) {
+			result = append(result, e)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].DateAdded.Equal(result[j].DateAdded) {
+			return result[i].DateAdded.Before(result[j].DateAdded)
+		}
+		return result[i].Indicator.ID < result[j].Indicator.ID
+	})
+	return result
+}
+
+// bundleLocked 全部有效期内的指标组成的 bundle, 调用方需持有锁
+func (se *stixExport) bundleLocked() StixBundle {
+	now := se.now()
+	bundle := StixBundle{Type: "bundle", ID: "bundle--" + uuid.New().String(), Objects: []interface{}{stixIdentity}}
+	for _, e := range se.sortedLocked(time.Time{}) {
+		if e.Indicator.ValidUntil.After(now) {
+			bundle.Objects = append(bundle.Objects, e.Indicator)
+		}
+	}
+	return bundle
+}
+
+// add 从已确认的提案中提取 IOC 并发布为指标, 返回新增或更新的指标数
+//
+// 有效期从确认时间起算, 已过有效期的确认不再发布
+func (se *stixExport) add(p *Proposal) int {
+	if p.Decision == nil || p.Decision.Action != ActionAccept || !slices.Contains(se.types, p.Type) {
+		return 0
+	}
+	now := se.now().UTC().Truncate(time.Millisecond)
+	decided := p.Decision.DecidedAt.UTC().Truncate(time.Millisecond)
+	if !decided.Add(se.valid).After(now) {
+		return 0
+	}
+	observables := extractObservables(p)
+	if len(observables) == 0 {
+		return 0
+	}
+
+	se.mu.Lock()
+	defer se.mu.Unlock()
+
+	n := 0
+	for _, ob := range observables {
+		id := "indicator--" + uuid.NewSHA1(stixNamespace, []byte(ob.pattern)).String()
+		e, ok := se.entries[id]
+		if ok && slices.Contains(e.Indicator.Proposals, p.ID) {
+			continue
+		}
+		if !ok {
+			e = &IndicatorEntry{Indicator: StixIndicator{
+				Type:           "indicator",
+				SpecVersion:    "2.1",
+				ID:             id,
+				CreatedByRef:   stixIdentity.ID,
+				Created:        now,
+				Name:           ob.value,
+				IndicatorTypes: []string{"malicious-activity"},
+				Pattern:        ob.pattern,
+				PatternType:    "stix",
+				ValidFrom:      decided,
+			}}
+			se.entries[id] = e
+		}
+		ind := &e.Indicator
+		ind.Modified = now
+		if until := decided.Add(se.valid); until.After(ind.ValidUntil) {
+			ind.ValidUntil = until
+		}
+		ind.Description = p.Title
+		ind.Proposals = append(ind.Proposals, p.ID)
+		if len(ind.Proposals) > maxStixProposals {
+			ind.Proposals = ind.Proposals[len(ind.Proposals)-maxStixProposals:]
+		}
+		if !slices.Contains(ind.Labels, p.Type) {
+			ind.Labels = append(ind.Labels, p.Type)
+		}
+		for _, tactic := range p.Tactics {
+			phase := StixKillChainPhase{KillChainName: "mitre-attack", PhaseName: tacticPhase(tactic)}
+			if phase.PhaseName != "" && !slices.Contains(ind.KillChainPhases, phase) {
+				ind.KillChainPhases = append(ind.KillChainPhases, phase)
+			}
+		}
+		for _, technique := range p.Techniques {
+			ref := StixExternalReference{
+				SourceName: "mitre-attack",
+				ExternalID: technique,
+				URL:        "https://attack.mitre.org/techniques/" + strings.ReplaceAll(technique, ".", "/") + "/",
+			}
+			if !slices.Contains(ind.ExternalReferences, ref) {
+				ind.ExternalReferences = append(ind.ExternalReferences, ref)
+			}
+		}
+		e.DateAdded = now
+		e.Pushed = false
+		n++
+	}
+	if n > 0 {
+		se.persistLocked()
+		se.exportLocked()
+		logger.InfoCF("secops", "STIX indicators published",
+			map[string]interface{}{
+				"id":         p.ID,
+				"type":       p.Type,
+				"indicators": n,
+			})
+	}
+	return n
+}
+
+// indicators date_added 晚于 after 的指标快照, 按加入时间排序
+func (se *stixExport) indicators(after time.Time) []IndicatorEntry {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+
+	sorted := se.sortedLocked(after)
+	result := make([]IndicatorEntry, len(sorted))
+	for i, e := range sorted {
+		result[i] = *e
+	}
+	return result
+}
+
+// pushPending 将未推送的指标推送到外部 TAXII 集合, 成功后标记为已推送; 失败的留待下次推送
+func (se *stixExport) pushPending(ctx context.Context) error {
+	if se.push.URL == "" {
+		return nil
+	}
+	se.pushMu.Lock()
+	defer se.pushMu.Unlock()
+
+	se.mu.RLock()
+	var pending []StixIndicator
+	for _, e := range se.sortedLocked(time.Time{}) {
+		if !e.Pushed {
+			pending = append(pending, e.Indicator)
+		}
+	}
+	se.mu.RUnlock()
+
+	for len(pending) > 0 {
+		batch := pending[:min(len(pending), maxTaxiiPushBatch)]
+		pending = pending[len(batch):]
+		if err := se.pushBatch(ctx, batch); err != nil {
+			return err
+		}
+
+		se.mu.Lock()
+		for _, ind := range batch {
+			// 推送期间指标可能再次更新, 只标记推送的版本
+			if e, ok := se.entries[ind.ID]; ok && e.Indicator.Modified.Equal(ind.Modified) {
+				e.Pushed = true
+			}
+		}
+		se.persistLocked()
+		se.mu.Unlock()
+	}
+	return nil
+}
+
+// pushBatch 调用 TAXII 2.1 Add Objects 接口
+func (se *stixExport) pushBatch(ctx context.Context, batch []StixIndicator) error {
+	objects := make([]interface{}, 0, len(batch)+1)
+	objects = append(objects, stixIdentity)
+	for _, ind := range batch {
+		objects = append(objects, ind)
+	}
+	body, err := json.Marshal(map[string]interface{}{"objects": objects})
+	if err != nil {
+		return err
+	}
+
+	endpoint := strings.TrimRight(se.push.URL, "/") + "/objects/"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", taxiiMediaType)
+	req.Header.Set("Accept", taxiiMediaType)
+	switch {
+	case se.push.Token != "":
+		req.Header.Set("Authorization", "Bearer "+se.push.Token)
+	case se.push.Username != "":
+		req.SetBasicAuth(se.push.Username, se.push.Password)
+	}
+
+	resp, err := se.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("taxii server error %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
+// extractObservables 提取提案详情中的攻击方 IOC; 内网、回环等非公网地址不作为指标
+func extractObservables(p *Proposal) []observable {
+	var values []string
+	for _, key := range iocKeys {
+		if v, ok := p.Details[key].(string); ok {
+			values = append(values, v)
+		}
+	}
+	switch iocs := p.Details["iocs"].(type) {
+	case []interface{}:
+		for _, v := range iocs {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+	case []string:
+		values = append(values, iocs...)
+	case string:
+		values = append(values, strings.Split(iocs, ",")...)
+	}
+
+	var result []observable
+	seen := make(map[string]bool)
+	for _, v := range values {
+		ob, ok := classifyObservable(strings.TrimSpace(v))
+		if ok && !seen[ob.pattern] {
+			seen[ob.pattern] = true
+			result = append(result, ob)
+		}
+	}
+	return result
+}
+
+// classifyObservable 识别 IOC 类型并生成 STIX 模式
+func classifyObservable(v string) (observable, bool) {
+	if v == "" {
+		return observable{}, false
+	}
+	if ip := net.ParseIP(v); ip != nil {
+		if ip.IsPrivate() || ip.IsLoopback() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsMulticast() {
+			return observable{}, false
+		}
+		kind := "ipv6-addr"
+		if ip.To4() != nil {
+			kind = "ipv4-addr"
+		}
+		return observable{value: ip.String(), pattern: fmt.Sprintf("[%s:value = '%s']", kind, ip.String())}, true
+	}
+	if algo, ok := stixHashAlgorithms[len(v)]; ok && iocHash.MatchString(v) {
+		v = strings.ToLower(v)
+		return observable{value: v, pattern: fmt.Sprintf("[file:hashes.'%s' = '%s']", algo, v)}, true
+	}
+	if u, err := url.Parse(v); err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+		return observable{value: v, pattern: fmt.Sprintf("[url:value = '%s']", stixEscape(v))}, true
+	}
+	if iocDomain.MatchString(v) {
+		v = strings.ToLower(v)
+		return observable{value: v, pattern: fmt.Sprintf("[domain-name:value = '%s']", v)}, true
+	}
+	return observable{}, false
+}
+
+// stixEscape 转义 STIX 模式字符串中的反斜杠和单引号
+func stixEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s)
+}
+
+// tacticPhase ATT&CK 战术对应的 kill chain 阶段名, 如 TA0001 -> initial-access
+func tacticPhase(tactic string) string {
+	for _, t := range attackTactics {
+		if t.ID == tactic {
+			return strings.ReplaceAll(strings.ToLower(t.Name), " ", "-")
+		}
+	}
+	return ""
+}
+
+// lastAdded 最近一次发布指标的时间
+func (se *stixExport) lastAdded() time.Time {
+	se.mu.RLock()
+	defer se.mu.RUnlock()
+
+	var last time.Time
+	for _, e := range se.entries {
+		if e.DateAdded.After(last) {
+			last = e.DateAdded
+		}
+	}
+	return last
+}
+
+// backfillIndicators 发布上次发布之后确认的提案 (首次开启时为全部有效期内的确认), 并推送积压的指标
+func (s *Service) backfillIndicators() {
+	last := s.stix.lastAdded()
+	n := 0
+	for _, p := range s.proposalService.GetAll() {
+		if p.Decision != nil && p.Decision.DecidedAt.After(last) {
+			n += s.stix.add(p)
+		}
+	}
+	if n > 0 {
+		logger.InfoCF("secops", "STIX indicators backfilled", map[string]interface{}{"indicators": n})
+	}
+	s.pushIndicators()
+}
+
+// publishIndicators 发布已确认提案的指标, 并推送到外部 TAXII 集合
+func (s *Service) publishIndicators(p *Proposal) {
+	if s.stix.add(p) == 0 {
+		return
+	}
+	s.pushIndicators()
+}
+
+// pushIndicators 推送未推送的指标, 失败时记录日志, 下次发布时重试
+func (s *Service) pushIndicators() {
+	if err := s.stix.pushPending(s.ctx); err != nil {
+		logger.WarnCF("secops", "TAXII push failed",
+			map[string]interface{}{
+				"url":   s.stix.push.URL,
+				"error": err.Error(),
+			})
+	}
+}
+
+// STIXEnabled 是否开启 STIX 指标发布
+func (s *Service) STIXEnabled() bool {
+	return s.stix != nil
+}
+
+// Indicators date_added 晚于 after 的已发布指标, 按加入时间排序; 未开启时返回 nil
+func (s *Service) Indicators(after time.Time) []IndicatorEntry {
+	if s.stix == nil {
+		return nil
+	}
+	return s.stix.indicators(after)
+}
+
+// STIXIdentity 指标的创建者对象
+func STIXIdentity() StixIdentity {
+	return stixIdentity
+}
@@ -0,0 +1,173 @@
+package secops
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestClassifyObservable(t *testing.T) {
+	cases := map[string]string{
+		"203.0.113.7":                      "[ipv4-addr:value = '203.0.113.7']",
+		"2001:db8::1":                      "[ipv6-addr:value = '2001:db8::1']",
+		"10.0.8.15":                        "",
+		"127.0.0.1":                        "",
+		"Evil.Example.COM":                 "[domain-name:value = 'evil.example.com']",
+		"http://evil.example.com/a?b='c'":  `[url:value = 'http://evil.example.com/a?b=\'c\'']`,
+		"44D88612FEA8A8F36DE82E1278ABB02F": "[file:hashes.'MD5' = '44d88612fea8a8f36de82e1278abb02f']",
+		"/product":                         "",
+		"SQL注入":                            "",
+	}
+	for in, want := range cases {
+		ob, ok := classifyObservable(in)
+		if ok != (want != "") || ob.pattern != want {
+			t.Errorf("classifyObservable(%q) = %q, %v; want %q", in, ob.pattern, ok, want)
+		}
+	}
+}
+
+func TestSTIXExport(t *testing.T) {
+	var pushed [][]map[string]interface{}
+	taxii := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api1/collections/c1/objects/" ||
+			r.Header.Get("Authorization") != "Bearer t0ken" || r.Header.Get("Content-Type") != taxiiMediaType {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var env struct {
+			Objects []map[string]interface{} `json:"objects"`
+		}
+		json.NewDecoder(r.Body).Decode(&env)
+		pushed = append(pushed, env.Objects)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer taxii.Close()
+
+	dir := t.TempDir()
+	se, err := newSTIXExport(config.STIXExportConfig{
+		Enabled: true,
+		File:    "stix/bundle.json",
+		Push:    config.TAXIIPushConfig{URL: taxii.URL + "/api1/collections/c1", Token: "t0ken"},
+	}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := se.load(filepath.Join(dir, "secops", "indicators.json")); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	se.now = func() time.Time { return now }
+
+	confirmed := func(id, typ string, details map[string]interface{}) *Proposal {
+		p := NewProposal(typ, "SQL 注入攻击", "", details)
+		p.ID = id
+		p.Techniques = []string{"T1190"}
+		tagAttack(p)
+		p.Status = ProposalStatusAccepted
+		p.Decision = &Decision{Action: ActionAccept, DecidedAt: now.Add(-time.Hour)}
+		return p
+	}
+
+	p1 := confirmed("p1", "risk", map[string]interface{}{
+		"host": "shop.example.com", "ip": "203.0.113.7", "url": "/product",
+		"iocs": []interface{}{"evil.example.com", "10.0.0.1"},
+	})
+	if n := se.add(p1); n != 2 {
+		t.Fatalf("add p1 = %d, want 2", n)
+	}
+	if n := se.add(p1); n != 0 {
+		t.Errorf("re-adding p1 = %d, want 0", n)
+	}
+	if n := se.add(confirmed("weak", "weak", map[string]interface{}{"ip": "198.51.100.1"})); n != 0 {
+		t.Errorf("weak proposal published %d indicators", n)
+	}
+	ignored := confirmed("ignored", "risk", map[string]interface{}{"ip": "198.51.100.1"})
+	ignored.Decision.Action = ActionIgnore
+	if n := se.add(ignored); n != 0 {
+		t.Errorf("ignored proposal published %d indicators", n)
+	}
+	stale := confirmed("stale", "risk", map[string]interface{}{"ip": "198.51.100.1"})
+	stale.Decision.DecidedAt = now.Add(-31 * 24 * time.Hour)
+	if n := se.add(stale); n != 0 {
+		t.Errorf("stale confirmation published %d indicators", n)
+	}
+
+	// 同一 IOC 再次确认时更新而不是新增
+	now = now.Add(time.Hour)
+	if n := se.add(confirmed("p2", "host", map[string]interface{}{"src_ip": "203.0.113.7"})); n != 1 {
+		t.Fatalf("add p2 = %d, want 1", n)
+	}
+	entries := se.indicators(time.Time{})
+	if len(entries) != 2 {
+		t.Fatalf("indicators = %+v", entries)
+	}
+	ip := entries[1].Indicator
+	if ip.Pattern != "[ipv4-addr:value = '203.0.113.7']" || ip.CreatedByRef != stixIdentity.ID ||
+		strings.Join(ip.Proposals, ",") != "p1,p2" || strings.Join(ip.Labels, ",") != "risk,host" ||
+		!ip.Modified.Equal(now) || ip.Created.Equal(ip.Modified) {
+		t.Errorf("ip indicator = %+v", ip)
+	}
+	if len(ip.KillChainPhases) != 1 || ip.KillChainPhases[0].PhaseName != "initial-access" ||
+		ip.ExternalReferences[0].URL != "https://attack.mitre.org/techniques/T1190/" {
+		t.Errorf("ip attack context = %+v %+v", ip.KillChainPhases, ip.ExternalReferences)
+	}
+	if got := se.indicators(now.Add(-time.Minute)); len(got) != 1 || got[0].Indicator.ID != ip.ID {
+		t.Errorf("indicators added after = %+v", got)
+	}
+
+	var bundle struct {
+		Type    string                   `json:"type"`
+		Objects []map[string]interface{} `json:"objects"`
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "stix", "bundle.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	json.Unmarshal(data, &bundle)
+	if bundle.Type != "bundle" || len(bundle.Objects) != 3 || bundle.Objects[0]["type"] != "identity" {
+		t.Errorf("bundle = %s", data)
+	}
+
+	if err := se.pushPending(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(pushed) != 1 || len(pushed[0]) != 3 {
+		t.Fatalf("pushed = %v", pushed)
+	}
+	if err := se.pushPending(context.Background()); err != nil || len(pushed) != 1 {
+		t.Errorf("second push: err=%v batches=%d", err, len(pushed))
+	}
+
+	// 重新加载后保留指标和推送状态
+	reloaded, _ := newSTIXExport(config.STIXExportConfig{Enabled: true}, dir)
+	if err := reloaded.load(filepath.Join(dir, "secops", "indicators.json")); err != nil {
+		t.Fatal(err)
+	}
+	if got := reloaded.indicators(time.Time{}); len(got) != 2 || !got[0].Pushed || !reloaded.lastAdded().Equal(now) {
+		t.Errorf("reloaded = %+v", got)
+	}
+}
+
+func TestNewSTIXExportConfig(t *testing.T) {
+	if se, err := newSTIXExport(config.STIXExportConfig{}, ""); se != nil || err != nil {
+		t.Errorf("disabled: se=%v err=%v", se, err)
+	}
+	bad := []config.STIXExportConfig{
+		{Enabled: true, Types: []string{"vuln"}},
+		{Enabled: true, ValidDays: -1},
+		{Enabled: true, Push: config.TAXIIPushConfig{URL: "taxii.example.com/collections/1"}},
+	}
+	for i, cfg := range bad {
+		if _, err := newSTIXExport(cfg, ""); err == nil {
+			t.Errorf("config %d: expected error", i)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package secops
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// maxSummaryEvidenceChars 重新生成摘要时每条证据保留的最大字符数
+const maxSummaryEvidenceChars = 2000
+
+// RegenerateSummary 请 Agent 基于提案详情和证据重新生成更清晰的标题和摘要
+//
+// instruction 为附加要求 (如 "翻译为英文"), 可为空。原始版本保留在 OriginalSummary 中。
+func (s *Service) RegenerateSummary(ctx context.Context, id, instruction string, by Actor) (*Proposal, error) {
+	p, ok := s.proposalService.Get(id)
+	if !ok {
+		return nil, fmt.Errorf("proposal not found: %s", id)
+	}
+
+	// 附加要求 (如翻译) 优先于工作语言
+	prompt := buildSummaryPrompt(p, instruction)
+	if strings.TrimSpace(instruction) == "" {
+		prompt += languageInstruction(s.proposalLanguage(p))
+	}
+	response, err := s.agentLoop.ProcessDirect(ctx, prompt, "secops:summary:"+id)
+	if err != nil {
+		return nil, fmt.Errorf("agent failed to regenerate summary: %w", err)
+	}
+
+	title, summary, err := parseSummaryResponse(response)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.proposalService.UpdateSummary(id, title, summary, by)
+}
+
+// buildSummaryPrompt 构建重新生成摘要的 prompt
+func buildSummaryPrompt(p *Proposal, instruction string) string {
+	var sb strings.Builder
+	sb.WriteString("请基于以下安全运营提案的详情和证据, 重新撰写更清晰准确的标题和摘要。\n")
+	sb.WriteString("不要调用任何工具, 只输出 JSON: {\"title\": \"...\", \"summary\": \"...\"}, summary 可使用 Markdown。\n")
+	if instruction = strings.TrimSpace(instruction); instruction != "" {
+		sb.WriteString("附加要求: " + instruction + "\n")
+	}
+
+	sb.WriteString(fmt.Sprintf("\n类型: %s\n标题: %s\n摘要:\n%s\n", p.Type, p.Title, p.Summary))
+
+	if len(p.Details) > 0 {
+		details, _ := json.MarshalIndent(p.Details, "", "  ")
+		sb.WriteString("\n详情:\n" + string(details) + "\n")
+	}
+
+	for _, ev := range p.Evidence {
+		content := ev.Content
+		if len(content) > maxSummaryEvidenceChars {
+			content = content[:maxSummaryEvidenceChars] + "\n...(truncated)"
+		}
+		sb.WriteString(fmt.Sprintf("\n证据 [%s] %s:\n%s\n", ev.ContentType, ev.Label, content))
+	}
+
+	return sb.String()
+}
+
+// parseSummaryResponse 从 Agent 回复中解析标题和摘要, 兼容代码块包裹和前后说明文字
+func parseSummaryResponse(response string) (string, string, error) {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start < 0 || end <= start {
+		return "", "", fmt.Errorf("agent response contains no JSON object")
+	}
+
+	var result struct {
+		Title   string `json:"title"`
+		Summary string `json:"summary"`
+	}
+	if err := json.Unmarshal([]byte(response[start:end+1]), &result); err != nil {
+		return "", "", fmt.Errorf("invalid agent response: %w", err)
+	}
+
+	result.Title = strings.TrimSpace(result.Title)
+	result.Summary = strings.TrimSpace(result.Summary)
+	if result.Title == "" || result.Summary == "" {
+		return "", "", fmt.Errorf("agent response missing title or summary")
+	}
+	return result.Title, result.Summary, nil
+}
@@ -0,0 +1,70 @@
+package secops
+
+import "testing"
+
+func TestParseSummaryResponse(t *testing.T) {
+	response := "好的, 结果如下:\n```json\n{\"title\": \"订单接口越权访问\", \"summary\": \"**高风险**: 同一账号遍历订单号\"}\n```"
+	title, summary, err := parseSummaryResponse(response)
+	if err != nil {
+		t.Fatalf("parseSummaryResponse failed: %v", err)
+	}
+	if title != "订单接口越权访问" || summary != "**高风险**: 同一账号遍历订单号" {
+		t.Errorf("unexpected result: %q / %q", title, summary)
+	}
+
+	for _, bad := range []string{"no json here", `{"title": "only title"}`, `{"title": `} {
+		if _, _, err := parseSummaryResponse(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestProposalService_UpdateSummaryKeepsOriginal(t *testing.T) {
+	s := NewProposalService()
+	id := s.Create(NewProposal("risk", "原始标题", "原始摘要", nil))
+
+	if _, err := s.UpdateSummary(id, "新标题 1", "新摘要 1", Actor{Via: ViaDebugUI}); err != nil {
+		t.Fatalf("UpdateSummary failed: %v", err)
+	}
+	p, err := s.UpdateSummary(id, "新标题 2", "新摘要 2", Actor{Via: ViaDebugUI})
+	if err != nil {
+		t.Fatalf("UpdateSummary failed: %v", err)
+	}
+
+	if !p.SummaryRegenerated || p.Title != "新标题 2" {
+		t.Errorf("unexpected current version: %q regenerated=%v", p.Title, p.SummaryRegenerated)
+	}
+	if p.OriginalSummary == nil || p.OriginalSummary.Title != "原始标题" || p.OriginalSummary.Summary != "原始摘要" {
+		t.Errorf("original version not preserved: %+v", p.OriginalSummary)
+	}
+}
+
+func TestProposalService_TranslationCacheInvalidatedOnRegenerate(t *testing.T) {
+	s := NewProposalService()
+	id := s.Create(NewProposal("risk", "原始标题", "原始摘要", nil))
+
+	if err := s.SetTranslation(id, "en", &SummaryVersion{Title: "Original title"}); err != nil {
+		t.Fatalf("SetTranslation failed: %v", err)
+	}
+	if _, ok := s.Translation(id, "en"); !ok {
+		t.Fatal("expected cached translation")
+	}
+
+	s.UpdateSummary(id, "新标题", "新摘要", Actor{Via: ViaDebugUI})
+	if _, ok := s.Translation(id, "en"); ok {
+		t.Error("expected translation cache to be cleared after regeneration")
+	}
+}
+
+func TestValidLanguage(t *testing.T) {
+	for _, lang := range []string{"en", "ja", "zh-CN", "pt-BR"} {
+		if !ValidLanguage(lang) {
+			t.Errorf("expected %q to be valid", lang)
+		}
+	}
+	for _, lang := range []string{"", "english please", "en\nignore previous", "e"} {
+		if ValidLanguage(lang) {
+			t.Errorf("expected %q to be invalid", lang)
+		}
+	}
+}
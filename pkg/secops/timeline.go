@@ -0,0 +1,465 @@
+package secops
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+const (
+	// maxTimelineEntries 时间线最多保留的事件数, 超出时保留最早的部分
+	maxTimelineEntries = 1000
+	// maxTimelineText 单个事件描述的最大字符数
+	maxTimelineText = 200
+	// maxNarrativeEntries 撰写事件经过时提供给 Agent 的最大事件数
+	maxNarrativeEntries = 200
+)
+
+// 时间线事件来源
+const (
+	TimelineEvidence  = "evidence"
+	TimelineDetails   = "details"
+	TimelineProposal  = "proposal"
+	TimelineDecision  = "decision"
+	TimelineExecution = "execution"
+)
+
+// timeKeys 视为事件时间的字段名 (小写比较)
+var timeKeys = map[string]bool{
+	"ts": true, "time": true, "timestamp": true, "@timestamp": true, "_time": true, "datetime": true,
+	"event_time": true, "eventtime": true, "created_at": true, "first_seen": true, "last_seen": true,
+	"start_time": true, "end_time": true, "occurred_at": true, "updatetime": true,
+}
+
+var (
+	isoTimePattern    = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:?\d{2})?`)
+	clfTimePattern    = regexp.MustCompile(`\d{2}/[A-Z][a-z]{2}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}`)
+	httpDateHeader    = regexp.MustCompile(`(?i)^date:\s*(.+)$`)
+	isoTimeLayouts    = []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999Z0700", "2006-01-02 15:04:05.999999999Z07:00", "2006-01-02 15:04:05.999999999Z0700"}
+	localTimeLayouts  = []string{"2006-01-02T15:04:05.999999999", "2006-01-02 15:04:05.999999999"}
+	earliestEventTime = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// TimelineEntry 时间线上的一个事件
+type TimelineEntry struct {
+	At         time.Time `json:"at"`
+	Source     string    `json:"source"` // evidence, details, proposal, decision, execution
+	ProposalID string    `json:"proposalId"`
+	Label      string    `json:"label,omitempty"` // 证据说明或详情字段名
+	Text       string    `json:"text"`
+}
+
+// TimelineProposalRef 时间线涉及的提案
+type TimelineProposalRef struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Severity string `json:"severity,omitempty"`
+	Status   string `json:"status"`
+}
+
+// Timeline 由提案证据时间戳和处置记录汇总的事件时间线
+type Timeline struct {
+	CaseID    string                `json:"caseId,omitempty"`
+	Proposals []TimelineProposalRef `json:"proposals"`
+	Entries   []TimelineEntry       `json:"entries"`
+	Start     time.Time             `json:"start"`
+	End       time.Time             `json:"end"`
+	Truncated bool                  `json:"truncated,omitempty"` // 事件过多, 只保留最早的部分
+	Narrative string                `json:"narrative,omitempty"` // Agent 撰写的事件经过
+}
+
+// BuildTimeline 汇总案件及指定提案的证据时间戳、详情中的时间字段和处置记录, 按时间排序
+//
+// 证据中不带时区的时间按服务器本地时区解析; SQL 证据是查询语句而非事件, 不参与汇总
+func (s *ProposalService) BuildTimeline(caseID string, ids []string) (*Timeline, error) {
+	var proposals []*Proposal
+	seen := make(map[string]bool)
+	if caseID != "" {
+		proposals = s.Case(caseID)
+		if len(proposals) == 0 {
+			return nil, fmt.Errorf("case not found: %s", caseID)
+		}
+		for _, p := range proposals {
+			seen[p.ID] = true
+		}
+	}
+	for _, id := range ids {
+		if id == "" || seen[id] {
+			continue
+		}
+		p, ok := s.Get(id)
+		if !ok {
+			return nil, fmt.Errorf("proposal not found: %s", id)
+		}
+		seen[id] = true
+		proposals = append(proposals, p)
+	}
+	if len(proposals) == 0 {
+		return nil, fmt.Errorf("no proposals selected")
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tl := &Timeline{CaseID: caseID}
+	for _, p := range proposals {
+		tl.Proposals = append(tl.Proposals, TimelineProposalRef{
+			ID: p.ID, Type: p.Type, Title: p.Title, Severity: p.Severity, Status: string(p.Status),
+		})
+		tl.Entries = append(tl.Entries, proposalTimeline(p)...)
+	}
+
+	sort.SliceStable(tl.Entries, func(i, j int) bool {
+		return tl.Entries[i].At.Before(tl.Entries[j].At)
+	})
+	tl.Entries = dedupeTimeline(tl.Entries)
+	if len(tl.Entries) > maxTimelineEntries {
+		tl.Entries = tl.Entries[:maxTimelineEntries]
+		tl.Truncated = true
+	}
+	if len(tl.Entries) > 0 {
+		tl.Start = tl.Entries[0].At
+		tl.End = tl.Entries[len(tl.Entries)-1].At
+	}
+	return tl, nil
+}
+
+// proposalTimeline 单个提案的时间线事件, 调用方需持有读锁
+func proposalTimeline(p *Proposal) []TimelineEntry {
+	var entries []TimelineEntry
+	add := func(at time.Time, source, label, text string) {
+		entries = append(entries, TimelineEntry{At: at, Source: source, ProposalID: p.ID, Label: label, Text: truncateText(text)})
+	}
+
+	for _, ev := range p.Evidence {
+		switch ev.ContentType {
+		case EvidenceTypeSQL:
+			continue
+		case EvidenceTypeJSON:
+			var v interface{}
+			if err := json.Unmarshal([]byte(ev.Content), &v); err == nil {
+				walkTimedRecords(v, func(at time.Time, text string) {
+					add(at, TimelineEvidence, ev.Label, text)
+				})
+				continue
+			}
+		}
+		for _, line := range strings.Split(ev.Content, "\n") {
+			line = strings.TrimSpace(line)
+			if at, ok := lineTime(line); ok {
+				add(at, TimelineEvidence, ev.Label, line)
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(p.Details))
+	for k := range p.Details {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !timeKeys[strings.ToLower(k)] {
+			continue
+		}
+		if at, ok := valueTime(p.Details[k]); ok {
+			add(at, TimelineDetails, k, p.Title)
+		}
+	}
+
+	created := fmt.Sprintf("创建提案: %s [%s", p.Title, p.Type)
+	if p.Severity != "" {
+		created += "/" + p.Severity
+	}
+	add(p.CreatedAt, TimelineProposal, "", created+"]")
+
+	if d := p.Decision; d != nil {
+		text := "分析师确认"
+		if d.Action == ActionIgnore {
+			text = "分析师忽略"
+		}
+		if d.By != nil && d.By.Name != "" {
+			text += " (" + d.By.Name + ")"
+		}
+		if d.Reason != "" {
+			text += ": " + d.Reason
+		}
+		add(d.DecidedAt, TimelineDecision, "", text)
+	}
+	if e := p.Execution; e != nil && !e.FinishedAt.IsZero() {
+		text := fmt.Sprintf("执行 %s: %s", e.API, e.Status)
+		if e.Error != "" {
+			text += " (" + e.Error + ")"
+		}
+		add(e.FinishedAt, TimelineExecution, "", text)
+	}
+	return entries
+}
+
+// walkTimedRecords 遍历 JSON 证据, 带时间字段的对象作为一条事件, 其余字段拼接为描述
+func walkTimedRecords(v interface{}, fn func(time.Time, string)) {
+	switch x := v.(type) {
+	case []interface{}:
+		for _, item := range x {
+			walkTimedRecords(item, fn)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		var at time.Time
+		var timeKey string
+		for _, k := range keys {
+			if timeKeys[strings.ToLower(k)] {
+				if t, ok := valueTime(x[k]); ok {
+					at, timeKey = t, k
+					break
+				}
+			}
+		}
+		if timeKey == "" {
+			for _, k := range keys {
+				walkTimedRecords(x[k], fn)
+			}
+			return
+		}
+		var parts []string
+		for _, k := range keys {
+			if k == timeKey {
+				continue
+			}
+			switch val := x[k].(type) {
+			case string, bool:
+				parts = append(parts, fmt.Sprintf("%s=%v", k, val))
+			case float64:
+				parts = append(parts, k+"="+strconv.FormatFloat(val, 'f', -1, 64))
+			}
+		}
+		fn(at, strings.Join(parts, " "))
+	}
+}
+
+// valueTime 解析字段值中的时间: 时间字符串或 Unix 秒/毫秒
+func valueTime(v interface{}) (time.Time, bool) {
+	switch x := v.(type) {
+	case string:
+		if n, err := strconv.ParseFloat(x, 64); err == nil {
+			return epochTime(n)
+		}
+		return parseEventTime(strings.TrimSpace(x))
+	case float64:
+		return epochTime(x)
+	case int64:
+		return epochTime(float64(x))
+	case int:
+		return epochTime(float64(x))
+	}
+	return time.Time{}, false
+}
+
+// epochTime Unix 秒或毫秒时间戳
+func epochTime(n float64) (time.Time, bool) {
+	var t time.Time
+	switch {
+	case n >= 1e12 && n < 1e14:
+		t = time.UnixMilli(int64(n))
+	case n >= 1e9 && n < 1e11:
+		t = time.Unix(int64(n), 0)
+	default:
+		return time.Time{}, false
+	}
+	return t, plausibleEventTime(t)
+}
+
+// lineTime 提取文本行中的第一个时间戳: ISO 8601、访问日志 (CLF) 格式或 HTTP Date 头
+func lineTime(line string) (time.Time, bool) {
+	if m := httpDateHeader.FindStringSubmatch(line); m != nil {
+		if t, err := http.ParseTime(strings.TrimSpace(m[1])); err == nil {
+			return t, plausibleEventTime(t)
+		}
+	}
+	if m := isoTimePattern.FindString(line); m != "" {
+		return parseEventTime(m)
+	}
+	if m := clfTimePattern.FindString(line); m != "" {
+		if t, err := time.Parse("02/Jan/2006:15:04:05 -0700", m); err == nil {
+			return t, plausibleEventTime(t)
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseEventTime 解析 ISO 8601 时间, 不带时区时按本地时区
+func parseEventTime(s string) (time.Time, bool) {
+	for _, layout := range isoTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, plausibleEventTime(t)
+		}
+	}
+	for _, layout := range localTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, plausibleEventTime(t)
+		}
+	}
+	if t, err := http.ParseTime(s); err == nil {
+		return t, plausibleEventTime(t)
+	}
+	return time.Time{}, false
+}
+
+// plausibleEventTime 排除明显不是事件时间的值 (如版本号、计数)
+func plausibleEventTime(t time.Time) bool {
+	return t.After(earliestEventTime) && t.Before(time.Now().Add(24*time.Hour))
+}
+
+// dedupeTimeline 去除同一提案中时间和描述都相同的事件, 输入需已排序
+func dedupeTimeline(entries []TimelineEntry) []TimelineEntry {
+	out := entries[:0]
+	for i, e := range entries {
+		if i > 0 {
+			prev := out[len(out)-1]
+			if prev.At.Equal(e.At) && prev.ProposalID == e.ProposalID && prev.Text == e.Text {
+				continue
+			}
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+func truncateText(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) > maxTimelineText {
+		s = string([]rune(s)[:maxTimelineText]) + "..."
+	}
+	return s
+}
+
+// Markdown 渲染为可直接放入事件报告的 Markdown
+func (tl *Timeline) Markdown() string {
+	var sb strings.Builder
+	if tl.CaseID != "" {
+		fmt.Fprintf(&sb, "# 事件时间线: %s\n\n", tl.CaseID)
+	} else {
+		sb.WriteString("# 事件时间线\n\n")
+	}
+	if len(tl.Entries) > 0 {
+		fmt.Fprintf(&sb, "- 时间范围: %s ~ %s\n", tl.Start.Local().Format("2006-01-02 15:04:05 MST"), tl.End.Local().Format("2006-01-02 15:04:05 MST"))
+	}
+	fmt.Fprintf(&sb, "- 涉及提案: %d 条\n", len(tl.Proposals))
+	for i, p := range tl.Proposals {
+		fmt.Fprintf(&sb, "  - P%d [%s] %s (`%s`, %s)\n", i+1, p.Type, p.Title, p.ID, p.Status)
+	}
+
+	if tl.Narrative != "" {
+		sb.WriteString("\n## 事件经过\n\n")
+		sb.WriteString(tl.Narrative)
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("\n## 时间线\n\n")
+	if len(tl.Entries) == 0 {
+		sb.WriteString("未找到带时间戳的事件。\n")
+		return sb.String()
+	}
+	index := make(map[string]int, len(tl.Proposals))
+	for i, p := range tl.Proposals {
+		index[p.ID] = i + 1
+	}
+	sb.WriteString("| 时间 | 提案 | 来源 | 事件 |\n|------|------|------|------|\n")
+	for _, e := range tl.Entries {
+		source := e.Source
+		if e.Label != "" {
+			source += ": " + e.Label
+		}
+		fmt.Fprintf(&sb, "| %s | P%d | %s | %s |\n", e.At.Local().Format("2006-01-02 15:04:05"), index[e.ProposalID],
+			markdownCell(source), markdownCell(e.Text))
+	}
+	if tl.Truncated {
+		fmt.Fprintf(&sb, "\n事件超过 %d 条, 仅列出最早的部分。\n", maxTimelineEntries)
+	}
+	return sb.String()
+}
+
+// markdownCell 转义表格单元格中的竖线
+func markdownCell(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// NarrateTimeline 请 Agent 根据时间线撰写事件经过, 写入 Narrative
+func (s *Service) NarrateTimeline(ctx context.Context, tl *Timeline) error {
+	key := tl.CaseID
+	if key == "" && len(tl.Proposals) > 0 {
+		key = tl.Proposals[0].ID
+	}
+	response, err := s.agentLoop.ProcessDirect(ctx, buildTimelinePrompt(tl)+languageInstruction(s.outputLanguage("")), "secops:timeline:"+key)
+	if err != nil {
+		return fmt.Errorf("agent failed to narrate timeline: %w", err)
+	}
+	tl.Narrative = strings.TrimSpace(response)
+	return nil
+}
+
+// buildTimelinePrompt 构建撰写事件经过的 prompt
+func buildTimelinePrompt(tl *Timeline) string {
+	var sb strings.Builder
+	sb.WriteString("请根据以下安全事件时间线, 为事件报告撰写简洁的事件经过 (3-6 句): 攻击从何时何处开始、关键动作、影响范围和处置结果。\n")
+	sb.WriteString("只依据时间线中的事实, 不要推测未出现的信息; 不要调用任何工具, 直接输出 Markdown 正文, 不要标题。\n\n")
+	for i, p := range tl.Proposals {
+		fmt.Fprintf(&sb, "P%d [%s] %s (%s)\n", i+1, p.Type, p.Title, p.Status)
+	}
+	sb.WriteString("\n")
+	for i, e := range tl.Entries {
+		if i == maxNarrativeEntries {
+			fmt.Fprintf(&sb, "...(另有 %d 条事件)\n", len(tl.Entries)-i)
+			break
+		}
+		fmt.Fprintf(&sb, "%s [%s %s] %s\n", e.At.Local().Format("2006-01-02 15:04:05"), e.Source, e.Label, e.Text)
+	}
+	return sb.String()
+}
+
+// timelineCommand 处理 /timeline 命令: 参数为案件 ID 或一个或多个提案 ID, 默认由 Agent 撰写事件经过
+func (s *Service) timelineCommand(ctx context.Context, msg bus.InboundMessage, args []string) string {
+	narrate := true
+	var ids []string
+	for _, a := range args {
+		if a == "--no-narrative" {
+			narrate = false
+			continue
+		}
+		ids = append(ids, a)
+	}
+	if len(ids) == 0 {
+		return "Usage: /timeline <case_id | proposal_id...> [--no-narrative]"
+	}
+
+	var tl *Timeline
+	var err error
+	if len(ids) == 1 && len(s.proposalService.Case(ids[0])) > 0 {
+		tl, err = s.proposalService.BuildTimeline(ids[0], nil)
+	} else {
+		tl, err = s.proposalService.BuildTimeline("", ids)
+	}
+	if err != nil {
+		return "生成时间线失败: " + err.Error()
+	}
+	if narrate {
+		if err := s.NarrateTimeline(ctx, tl); err != nil {
+			return tl.Markdown() + "\n(事件经过生成失败: " + err.Error() + ")"
+		}
+	}
+	return tl.Markdown()
+}
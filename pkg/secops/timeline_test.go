@@ -0,0 +1,104 @@
+package secops
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/bus"
+)
+
+func TestBuildTimeline(t *testing.T) {
+	s := NewProposalService()
+
+	sqli := NewProposal("risk", "SQL 注入 /product", "", map[string]interface{}{
+		"ip": "203.0.113.45", "first_seen": "2026-10-16T01:58:00Z",
+	})
+	sqli.CaseID = "case-1"
+	sqli.AddEvidence("访问记录", `[{"ts": "2026-10-16T02:00:05Z", "url": "/product?id=1'", "status": 500},
+		{"ts": 1792116010000, "url": "/product?id=1 union select", "status": 200}]`)
+	sqli.AddEvidence("溯源查询", "SELECT * FROM access WHERE ts > '2020-01-01 00:00:00'")
+	sqli.AddEvidence("请求报文", "GET /product?id=1 HTTP/1.1\nHost: shop.example.com\nDate: Thu, 16 Oct 2026 02:00:05 GMT")
+	sqliID := s.Create(sqli)
+
+	shell := NewProposal("host", "WebShell 落地", "", nil)
+	shell.CaseID = "case-1"
+	shell.AddEvidence("Wazuh 告警", "2026-10-16T02:10:00+00:00 rule 554 file added /var/www/upload/x.php\nno timestamp here\n"+
+		`203.0.113.45 - - [16/Oct/2026:02:09:58 +0000] "POST /upload HTTP/1.1" 200`)
+	shellID := s.Create(shell)
+
+	if err := s.Accept(sqliID, DecisionRequest{Reason: "确认注入", By: Actor{Name: "password:alice", Via: ViaDebugUI}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tl, err := s.BuildTimeline("case-1", []string{shellID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tl.Proposals) != 2 || tl.Proposals[0].ID != sqliID {
+		t.Fatalf("proposals = %+v", tl.Proposals)
+	}
+
+	var texts []string
+	for i, e := range tl.Entries {
+		if i > 0 && e.At.Before(tl.Entries[i-1].At) {
+			t.Errorf("entries not sorted at %d", i)
+		}
+		texts = append(texts, e.Source+"|"+e.Text)
+	}
+	want := []string{
+		"details|SQL 注入 /product",
+		"evidence|status=500 url=/product?id=1'",
+		"evidence|status=200 url=/product?id=1 union select",
+		`evidence|203.0.113.45 - - [16/Oct/2026:02:09:58 +0000] "POST /upload HTTP/1.1" 200`,
+		"evidence|2026-10-16T02:10:00+00:00 rule 554 file added /var/www/upload/x.php",
+		"proposal|创建提案: SQL 注入 /product [risk]",
+		"proposal|创建提案: WebShell 落地 [host]",
+		"decision|分析师确认 (password:alice): 确认注入",
+	}
+	joined := strings.Join(texts, "\n")
+	if len(tl.Entries) != len(want)+1 {
+		t.Fatalf("entries:\n%s", joined)
+	}
+	for _, w := range want {
+		if !strings.Contains(joined, w) {
+			t.Errorf("missing %q in:\n%s", w, joined)
+		}
+	}
+	if strings.Contains(joined, "2020-01-01") {
+		t.Error("SQL evidence should not produce events")
+	}
+	if !tl.Start.Equal(time.Date(2026, 10, 16, 1, 58, 0, 0, time.UTC)) {
+		t.Errorf("start = %v", tl.Start)
+	}
+
+	md := tl.Markdown()
+	for _, w := range []string{"# 事件时间线: case-1", "P2 [host] WebShell 落地", "| 时间 | 提案 | 来源 | 事件 |",
+		`url=/product?id=1 union select`, "| P1 | evidence: 请求报文 |"} {
+		if !strings.Contains(md, w) {
+			t.Errorf("markdown missing %q:\n%s", w, md)
+		}
+	}
+
+	if _, err := s.BuildTimeline("missing", nil); err == nil {
+		t.Error("expected error for unknown case")
+	}
+	if _, err := s.BuildTimeline("", []string{"missing"}); err == nil {
+		t.Error("expected error for unknown proposal")
+	}
+}
+
+func TestTimelineCommand(t *testing.T) {
+	svc := &Service{proposalService: NewProposalService()}
+	p := NewProposal("risk", "撞库", "", map[string]interface{}{"ts": "2026-10-16 10:00:00"})
+	id := svc.proposalService.Create(p)
+
+	out := svc.timelineCommand(context.Background(), bus.InboundMessage{}, []string{id, "--no-narrative"})
+	if !strings.HasPrefix(out, "# 事件时间线\n") || !strings.Contains(out, "| details: ts | 撞库 |") {
+		t.Errorf("command output:\n%s", out)
+	}
+	if out := svc.timelineCommand(context.Background(), bus.InboundMessage{}, nil); !strings.HasPrefix(out, "Usage:") {
+		t.Errorf("usage = %q", out)
+	}
+}
@@ -0,0 +1,95 @@
+package secops
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// languagePattern 目标语言代码, 如 en, ja, zh-CN
+var languagePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})?$`)
+
+// ValidLanguage 校验目标语言代码
+func ValidLanguage(lang string) bool {
+	return languagePattern.MatchString(lang)
+}
+
+// TranslationLanguage 获取配置的默认目标语言, 未配置时返回空串
+func (s *Service) TranslationLanguage() string {
+	return s.config.Translation.Language
+}
+
+// TranslateProposal 获取提案标题和摘要的译文, 已缓存时直接返回
+func (s *Service) TranslateProposal(ctx context.Context, id, lang string) (*SummaryVersion, error) {
+	if !ValidLanguage(lang) {
+		return nil, fmt.Errorf("invalid language: %q", lang)
+	}
+	if t, ok := s.proposalService.Translation(id, lang); ok {
+		return t, nil
+	}
+
+	p, ok := s.proposalService.Get(id)
+	if !ok {
+		return nil, fmt.Errorf("proposal not found: %s", id)
+	}
+
+	prompt := fmt.Sprintf(`将以下安全运营提案的标题和摘要翻译为语言 "%s"。
+保留 Markdown 格式、代码、IP、URL 和专有名词, 只输出 JSON: {"title": "...", "summary": "..."}
+
+标题: %s
+摘要:
+%s`, lang, p.Title, p.Summary)
+
+	response, err := s.agentLoop.Complete(ctx, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("translation failed: %w", err)
+	}
+
+	title, summary, err := parseSummaryResponse(response)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &SummaryVersion{Title: title, Summary: summary, CreatedAt: time.Now()}
+	if err := s.proposalService.SetTranslation(id, lang, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Translate 翻译任意文本 (如报告), 保留 Markdown 格式
+func (s *Service) Translate(ctx context.Context, text, lang string) (string, error) {
+	if !ValidLanguage(lang) {
+		return "", fmt.Errorf("invalid language: %q", lang)
+	}
+
+	prompt := fmt.Sprintf("将以下内容翻译为语言 \"%s\", 保留 Markdown 格式、代码、IP、URL 和专有名词, 只输出译文:\n\n%s", lang, text)
+	response, err := s.agentLoop.Complete(ctx, prompt)
+	if err != nil {
+		return "", fmt.Errorf("translation failed: %w", err)
+	}
+	return strings.TrimSpace(response), nil
+}
+
+// autoTranslate 开启自动翻译时, 后台将新提案翻译为默认目标语言
+func (s *Service) autoTranslate(id string) {
+	lang := s.config.Translation.Language
+	if !s.config.Translation.Enabled || lang == "" {
+		return
+	}
+
+	go func() {
+		if _, err := s.TranslateProposal(s.ctx, id, lang); err != nil {
+			logger.WarnCF("secops", "Proposal auto translation failed",
+				map[string]interface{}{
+					"id":    id,
+					"lang":  lang,
+					"error": err.Error(),
+				})
+		}
+	}()
+}
@@ -4,34 +4,150 @@ import "time"
 
 // Proposal 提案结构
 type Proposal struct {
-	ID         string                 // 提案ID
-	Type       string                 // 提案类型: risk, weak, api_biz, app
-	Title      string                 // 提案标题
-	Summary    string                 // 简要总结
-	Details    map[string]interface{} // 详细数据
-	Actions    []ProposalAction      // 可选操作
-	Parameters map[string]Param       // 可调整参数
-	Status     ProposalStatus         // 提案状态
-	CreatedAt  time.Time              // 创建时间
-	UpdatedAt  time.Time              // 更新时间
+	ID         string                 `json:"id"`         // 提案ID
+	Type       string                 `json:"type"`       // 提案类型: risk, weak, api_biz, app, host, k8s, cloud, system
+	Title      string                 `json:"title"`      // 提案标题
+	Summary    string                 `json:"summary"`    // 简要总结
+	Details    map[string]interface{} `json:"details"`    // 详细数据
+	Actions    []ProposalAction       `json:"actions"`    // 可选操作
+	Parameters map[string]Param       `json:"parameters"` // 可调整参数
+	Evidence   []Evidence             `json:"evidence"`   // 证据 (SQL/报文/JSON 等)
+	Status     ProposalStatus         `json:"status"`     // 提案状态
+
+	RunID  string `json:"runId,omitempty"`  // 产生该提案的活动执行
+	CaseID string `json:"caseId,omitempty"` // 所属案件, 同一案件的提案一并研判
+
+	Severity       string    `json:"severity,omitempty"`       // 严重级别: critical, high, medium, low, info
+	Confidence     int       `json:"confidence,omitempty"`     // Agent 对研判结论的置信度 0-100, 0 表示未填写
+	Recommendation string    `json:"recommendation,omitempty"` // Agent 建议的处置: accept, ignore
+	Decision       *Decision `json:"decision,omitempty"`       // 分析师决策记录
+
+	Tactics    []string `json:"tactics,omitempty"`    // ATT&CK 战术编号, 如 TA0001, 由技术推导
+	Techniques []string `json:"techniques,omitempty"` // ATT&CK 技术编号, 如 T1190、T1059.004
+
+	Reproduction *Reproduction `json:"reproduction,omitempty"` // 弱点的脱敏复现命令, 供应用团队验证
+
+	Binding   *ActionBinding      `json:"binding,omitempty"`   // 决策后执行的 Sheikah API
+	Items     []map[string]string `json:"items,omitempty"`     // 批量提案的各条目参数, 决策后逐条执行
+	Execution *Execution          `json:"execution,omitempty"` // 最近一次执行结果
+
+	Artifacts []Artifact      `json:"artifacts,omitempty"` // 确认后提交到 Git 仓库的规则和配置
+	Change    *ArtifactChange `json:"change,omitempty"`    // 最近一次提交结果
+
+	SummaryRegenerated bool            `json:"summaryRegenerated,omitempty"` // 当前标题/摘要是否由 Agent 重新生成
+	OriginalSummary    *SummaryVersion `json:"originalSummary,omitempty"`    // 重新生成前的原始版本
+
+	Translations map[string]*SummaryVersion `json:"translations,omitempty"` // 按目标语言缓存的译文
+
+	EvidenceArchive string `json:"evidenceArchive,omitempty"` // 证据已归档时的归档文件
+
+	Acknowledgement *Acknowledgement `json:"acknowledgement,omitempty"` // 分析师已知悉, 到期前不再提醒
+
+	Assignee string    `json:"assignee,omitempty"` // 负责研判的分析师
+	Comments []Comment `json:"comments,omitempty"` // 调查记录, 按时间顺序
+
+	CreatedBy *Actor `json:"createdBy,omitempty"` // 创建者: 运营活动、对话、导入或后台同步
+
+	CreatedAt time.Time `json:"createdAt"` // 创建时间
+	UpdatedAt time.Time `json:"updatedAt"` // 更新时间
 }
 
 // ProposalAction 可选操作
 type ProposalAction struct {
-	Label  string            // 按钮文字: "确认风险", "忽略", "修改参数"
-	Type   string           // accept, ignore, modify
-	Params map[string]string // 操作参数
+	Label  string            `json:"label"`            // 按钮文字: "确认风险", "忽略", "修改参数"
+	Type   string            `json:"type"`             // accept, ignore, modify
+	Params map[string]string `json:"params,omitempty"` // 操作参数
 }
 
 // Param 可调整参数
 type Param struct {
-	Key     string   // 参数名
-	Label   string   // 显示标签
-	Type    string   // string, number, select
-	Value   string   // 当前值
-	Options []string // 可选值 (for select)
+	Key     string   `json:"key"`               // 参数名
+	Label   string   `json:"label"`             // 显示标签
+	Type    string   `json:"type"`              // string, number, select
+	Value   string   `json:"value"`             // 当前值
+	Options []string `json:"options,omitempty"` // 可选值 (for select)
+}
+
+// 严重级别
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+	SeverityInfo     = "info"
+)
+
+// validSeverities 合法的严重级别
+var validSeverities = map[string]bool{
+	SeverityCritical: true,
+	SeverityHigh:     true,
+	SeverityMedium:   true,
+	SeverityLow:      true,
+	SeverityInfo:     true,
+}
+
+// 决策动作
+const (
+	ActionAccept = "accept"
+	ActionIgnore = "ignore"
+)
+
+// Decision 分析师决策记录
+type Decision struct {
+	Action    string            `json:"action"`              // accept, ignore
+	Reason    string            `json:"reason,omitempty"`    // 决策理由
+	Template  string            `json:"template,omitempty"`  // 使用的决策模板
+	Params    map[string]string `json:"params,omitempty"`    // 生效的动作参数, 执行层以此为准
+	Overrides []ParamOverride   `json:"overrides,omitempty"` // 与 Agent 建议值不同的参数
+	Override  bool              `json:"override"`            // 是否与 Agent 建议相反
+	DecidedAt time.Time         `json:"decidedAt"`           // 决策时间
+	By        *Actor            `json:"by,omitempty"`        // 决策者
+}
+
+// ParamOverride 分析师修改的参数: Agent 建议值 From, 生效值 To
+type ParamOverride struct {
+	Key  string `json:"key"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Acknowledgement 提案知悉记录: 暂不决策, 但在 Until 之前屏蔽提醒和升级
+type Acknowledgement struct {
+	Reason string    `json:"reason,omitempty"`
+	At     time.Time `json:"at"`
+	Until  time.Time `json:"until"`
+}
+
+// SummaryVersion 提案标题和摘要的一个版本
+type SummaryVersion struct {
+	Title     string    `json:"title"`
+	Summary   string    `json:"summary"`
+	CreatedAt time.Time `json:"createdAt"`
 }
 
+// DecisionRequest 分析师提交的决策
+type DecisionRequest struct {
+	Params   map[string]string // 动作参数, 优先级高于模板和提案默认值
+	Reason   string            // 决策理由, 为空时使用模板备注
+	Template string            // 决策模板名称
+	By       Actor             // 决策者, 记录在审计日志中
+}
+
+// Evidence 提案证据, ContentType 决定界面上的展示方式
+type Evidence struct {
+	Label       string `json:"label"`       // 证据说明, 如 "溯源查询", "请求报文"
+	ContentType string `json:"contentType"` // sql, json, http, text
+	Content     string `json:"content"`     // 证据内容
+}
+
+// 证据内容类型
+const (
+	EvidenceTypeSQL  = "sql"
+	EvidenceTypeJSON = "json"
+	EvidenceTypeHTTP = "http"
+	EvidenceTypeText = "text"
+)
+
 // ProposalStatus 提案状态
 type ProposalStatus string
 
@@ -40,6 +156,8 @@ const (
 	ProposalStatusAccepted ProposalStatus = "accepted"
 	ProposalStatusIgnored  ProposalStatus = "ignored"
 	ProposalStatusModified ProposalStatus = "modified"
+	ProposalStatusExecutionFailed ProposalStatus = "execution_failed" // 已决策但调用 Sheikah API 失败, 等待重试
+	ProposalStatusExpired         ProposalStatus = "expired"          // 超过该类型的 TTL 仍未决策
 )
 
 // NewProposal 创建新提案
@@ -51,8 +169,16 @@ func NewProposal(proposalType, title, summary string, details map[string]interfa
 		Details:    details,
 		Actions:    []ProposalAction{},
 		Parameters: make(map[string]Param),
+		Evidence:   []Evidence{},
 		Status:     ProposalStatusPending,
 		CreatedAt:  time.Now(),
 		UpdatedAt:  time.Now(),
 	}
 }
+
+// AddEvidence 添加证据, 自动识别内容类型; JSON 内容会被格式化
+func (p *Proposal) AddEvidence(label, content string) {
+	ev := Evidence{Label: label, Content: content}
+	normalizeEvidence(&ev)
+	p.Evidence = append(p.Evidence, ev)
+}
@@ -0,0 +1,540 @@
+package secops
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+const (
+	defaultWazuhIndex     = "wazuh-alerts-*"
+	defaultWazuhInterval  = time.Minute
+	defaultWazuhMinLevel  = 7
+	defaultWazuhBatchSize = 500
+
+	// wazuhBackfill 首次拉取时回溯的时长
+	wazuhBackfill = time.Hour
+	// hostEventRetention 已领取的主机事件保留时长
+	hostEventRetention = 7 * 24 * time.Hour
+	// maxPendingHostEvents 待研判主机事件上限, 超出时丢弃最旧的
+	maxPendingHostEvents = 10000
+)
+
+// 主机事件状态
+const (
+	HostEventPending = "pending" // 等待 host_analysis 活动领取
+	HostEventClaimed = "claimed" // 已交给 Agent 研判
+)
+
+// HostEvent 从 Wazuh 拉取的主机告警
+type HostEvent struct {
+	ID          string    `json:"id"` // Indexer 文档 ID
+	Timestamp   time.Time `json:"timestamp"`
+	Agent       string    `json:"agent"`
+	AgentIP     string    `json:"agentIp,omitempty"`
+	RuleID      string    `json:"ruleId"`
+	Level       int       `json:"level"`
+	Description string    `json:"description"`
+	Groups      []string  `json:"groups,omitempty"`
+	Mitre       []string  `json:"mitre,omitempty"` // 规则关联的 ATT&CK 技术编号
+	SrcIP       string    `json:"srcIp,omitempty"`
+	Location    string    `json:"location,omitempty"`
+	FullLog     string    `json:"fullLog,omitempty"`
+	Status      string    `json:"status"`
+	PulledAt    time.Time `json:"pulledAt"`
+	ClaimedAt   time.Time `json:"claimedAt,omitempty"`
+}
+
+// hostEventStore 主机事件及拉取游标, 持久化到 workspace/secops/host_events.json
+type hostEventStore struct {
+	events map[string]*HostEvent
+	cursor time.Time // 已拉取告警的最新时间戳
+	path   string
+	mu     sync.RWMutex
+}
+
+func newHostEventStore() *hostEventStore {
+	return &hostEventStore{events: make(map[string]*HostEvent)}
+}
+
+// load 加载已持久化的主机事件和游标
+func (hs *hostEventStore) load(path string) error {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	hs.path = path
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var state struct {
+		Cursor time.Time    `json:"cursor"`
+		Events []*HostEvent `json:"events"`
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	hs.cursor = state.Cursor
+	for _, e := range state.Events {
+		hs.events[e.ID] = e
+	}
+	return nil
+}
+
+// add 加入新拉取的告警, 按 ID 去重并推进游标; 返回新增条数
+func (hs *hostEventStore) add(events []*HostEvent, now time.Time) int {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	added := 0
+	for _, e := range events {
+		if e.Timestamp.After(hs.cursor) {
+			hs.cursor = e.Timestamp
+		}
+		if _, ok := hs.events[e.ID]; ok {
+			continue
+		}
+		e.Status = HostEventPending
+		e.PulledAt = now
+		hs.events[e.ID] = e
+		added++
+	}
+	hs.pruneLocked(now)
+	hs.saveLocked()
+	return added
+}
+
+// pruneLocked 清理过期的已领取事件, 并将待研判事件限制在上限以内
+func (hs *hostEventStore) pruneLocked(now time.Time) {
+	var pending []*HostEvent
+	for id, e := range hs.events {
+		switch {
+		case e.Status == HostEventClaimed && now.Sub(e.ClaimedAt) > hostEventRetention:
+			delete(hs.events, id)
+		case e.Status == HostEventPending:
+			pending = append(pending, e)
+		}
+	}
+	if len(pending) <= maxPendingHostEvents {
+		return
+	}
+	sortHostEvents(pending, false)
+	for _, e := range pending[:len(pending)-maxPendingHostEvents] {
+		delete(hs.events, e.ID)
+	}
+	logger.WarnCF("secops", "Too many pending host events, oldest dropped",
+		map[string]interface{}{
+			"dropped": len(pending) - maxPendingHostEvents,
+		})
+}
+
+// since 下次拉取的起始时间; 尚未拉取过时回溯 wazuhBackfill
+func (hs *hostEventStore) since(now time.Time) time.Time {
+	hs.mu.RLock()
+	defer hs.mu.RUnlock()
+	if hs.cursor.IsZero() {
+		return now.Add(-wazuhBackfill)
+	}
+	return hs.cursor
+}
+
+// claim 按级别从高到低领取最多 n 条待研判事件, 领取后不再返回
+func (hs *hostEventStore) claim(n int, now time.Time) []*HostEvent {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	var pending []*HostEvent
+	for _, e := range hs.events {
+		if e.Status == HostEventPending {
+			pending = append(pending, e)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		if pending[i].Level != pending[j].Level {
+			return pending[i].Level > pending[j].Level
+		}
+		return pending[i].Timestamp.Before(pending[j].Timestamp)
+	})
+	if len(pending) > n {
+		pending = pending[:n]
+	}
+	for _, e := range pending {
+		e.Status = HostEventClaimed
+		e.ClaimedAt = now
+	}
+	if len(pending) > 0 {
+		hs.saveLocked()
+	}
+	return pending
+}
+
+// release 将 since 之后领取的事件恢复为待研判; 返回恢复条数
+func (hs *hostEventStore) release(since time.Time) int {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	released := 0
+	for _, e := range hs.events {
+		if e.Status == HostEventClaimed && !e.ClaimedAt.Before(since) {
+			e.Status = HostEventPending
+			e.ClaimedAt = time.Time{}
+			released++
+		}
+	}
+	if released > 0 {
+		hs.saveLocked()
+	}
+	return released
+}
+
+// byAgent 主机最近的事件, 按时间倒序
+func (hs *hostEventStore) byAgent(agent string, n int) []*HostEvent {
+	hs.mu.RLock()
+	defer hs.mu.RUnlock()
+
+	var result []*HostEvent
+	for _, e := range hs.events {
+		if e.Agent == agent {
+			result = append(result, e)
+		}
+	}
+	sortHostEvents(result, true)
+	if len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
+
+// pendingCount 待研判事件数
+func (hs *hostEventStore) pendingCount() int {
+	hs.mu.RLock()
+	defer hs.mu.RUnlock()
+	n := 0
+	for _, e := range hs.events {
+		if e.Status == HostEventPending {
+			n++
+		}
+	}
+	return n
+}
+
+func (hs *hostEventStore) saveLocked() {
+	if hs.path == "" {
+		return
+	}
+	events := make([]*HostEvent, 0, len(hs.events))
+	for _, e := range hs.events {
+		events = append(events, e)
+	}
+	sortHostEvents(events, false)
+	state := map[string]interface{}{
+		"cursor": hs.cursor,
+		"events": events,
+	}
+	if err := saveJSONAtomic(hs.path, state); err != nil {
+		logger.ErrorCF("secops", "Failed to persist host events",
+			map[string]interface{}{
+				"path":  hs.path,
+				"error": err.Error(),
+			})
+	}
+}
+
+func sortHostEvents(events []*HostEvent, desc bool) {
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Timestamp.Equal(events[j].Timestamp) {
+			return events[i].ID < events[j].ID
+		}
+		return events[i].Timestamp.After(events[j].Timestamp) == desc
+	})
+}
+
+// wazuhPuller 定期从 Wazuh Indexer 拉取告警
+type wazuhPuller struct {
+	cfg      config.WazuhConfig
+	interval time.Duration
+	client   *http.Client
+	store    *hostEventStore
+	now      func() time.Time
+}
+
+// newWazuhPuller 校验配置并填充默认值
+func newWazuhPuller(cfg config.WazuhConfig, store *hostEventStore) (*wazuhPuller, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+	cfg.URL = strings.TrimRight(cfg.URL, "/")
+	if cfg.Index == "" {
+		cfg.Index = defaultWazuhIndex
+	}
+	if cfg.MinLevel <= 0 {
+		cfg.MinLevel = defaultWazuhMinLevel
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultWazuhBatchSize
+	}
+	interval := defaultWazuhInterval
+	if cfg.Interval != "" {
+		d, err := time.ParseDuration(cfg.Interval)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid interval %q", cfg.Interval)
+		}
+		interval = d
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.InsecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &wazuhPuller{
+		cfg:      cfg,
+		interval: interval,
+		client:   &http.Client{Transport: transport, Timeout: 30 * time.Second},
+		store:    store,
+		now:      time.Now,
+	}, nil
+}
+
+// run 按间隔拉取, 直到 ctx 取消
+func (wp *wazuhPuller) run(ctx context.Context) {
+	ticker := time.NewTicker(wp.interval)
+	defer ticker.Stop()
+
+	for {
+		if added, err := wp.pull(ctx); err != nil {
+			logger.WarnCF("secops", "Wazuh pull failed",
+				map[string]interface{}{
+					"error": err.Error(),
+				})
+		} else if added > 0 {
+			logger.InfoCF("secops", "Wazuh alerts pulled",
+				map[string]interface{}{
+					"added":   added,
+					"pending": wp.store.pendingCount(),
+				})
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// pull 拉取游标之后、级别不低于 MinLevel 的告警; 游标时刻的告警会重复返回, 按 ID 去重
+func (wp *wazuhPuller) pull(ctx context.Context) (int, error) {
+	query := map[string]interface{}{
+		"size": wp.cfg.BatchSize,
+		"sort": []interface{}{map[string]string{"timestamp": "asc"}},
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": []interface{}{
+					map[string]interface{}{"range": map[string]interface{}{"rule.level": map[string]int{"gte": wp.cfg.MinLevel}}},
+					map[string]interface{}{"range": map[string]interface{}{"timestamp": map[string]string{"gte": wp.store.since(wp.now()).Format(time.RFC3339Nano)}}},
+				},
+			},
+		},
+	}
+	body, _ := json.Marshal(query)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wp.cfg.URL+"/"+wp.cfg.Index+"/_search", bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if wp.cfg.Username != "" {
+		req.SetBasicAuth(wp.cfg.Username, wp.cfg.Password)
+	}
+
+	resp, err := wp.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode >= 400 {
+		return 0, fmt.Errorf("wazuh indexer error %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				ID     string     `json:"_id"`
+				Source wazuhAlert `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return 0, fmt.Errorf("failed to parse wazuh response: %w", err)
+	}
+
+	events := make([]*HostEvent, 0, len(result.Hits.Hits))
+	for _, hit := range result.Hits.Hits {
+		e, err := hit.Source.hostEvent(hit.ID)
+		if err != nil {
+			logger.WarnCF("secops", "Skipping malformed wazuh alert",
+				map[string]interface{}{
+					"id":    hit.ID,
+					"error": err.Error(),
+				})
+			continue
+		}
+		events = append(events, e)
+	}
+	return wp.store.add(events, wp.now()), nil
+}
+
+// wazuhAlert Indexer 中的告警文档
+type wazuhAlert struct {
+	Timestamp string `json:"timestamp"`
+	Agent     struct {
+		Name string `json:"name"`
+		IP   string `json:"ip"`
+	} `json:"agent"`
+	Rule struct {
+		ID          string   `json:"id"`
+		Level       int      `json:"level"`
+		Description string   `json:"description"`
+		Groups      []string `json:"groups"`
+		Mitre       struct {
+			ID []string `json:"id"`
+		} `json:"mitre"`
+	} `json:"rule"`
+	Data struct {
+		SrcIP string `json:"srcip"`
+	} `json:"data"`
+	Location string `json:"location"`
+	FullLog  string `json:"full_log"`
+}
+
+// wazuhTimeLayouts 告警时间格式, Wazuh 默认输出不带冒号的时区偏移
+var wazuhTimeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05.000-0700"}
+
+func (a wazuhAlert) hostEvent(id string) (*HostEvent, error) {
+	var ts time.Time
+	var err error
+	for _, layout := range wazuhTimeLayouts {
+		if ts, err = time.Parse(layout, a.Timestamp); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp %q", a.Timestamp)
+	}
+	return &HostEvent{
+		ID:          id,
+		Timestamp:   ts,
+		Agent:       a.Agent.Name,
+		AgentIP:     a.Agent.IP,
+		RuleID:      a.Rule.ID,
+		Level:       a.Rule.Level,
+		Description: a.Rule.Description,
+		Groups:      a.Rule.Groups,
+		Mitre:       a.Rule.Mitre.ID,
+		SrcIP:       a.Data.SrcIP,
+		Location:    a.Location,
+		FullLog:     a.FullLog,
+	}, nil
+}
+
+// hostEventQueries wazuh 数据源的内置查询, 模板内容为查询类型而非 SQL
+var hostEventQueries = map[string]string{
+	"pending_host_events":  "claim",
+	"host_events_by_agent": "by_agent",
+}
+
+// hostEventSource 以 query_data 数据源的形式提供主机事件, 名称为 wazuh
+type hostEventSource struct {
+	store *hostEventStore
+}
+
+// Driver 数据源类型
+func (hostEventSource) Driver() string {
+	return "wazuh"
+}
+
+// Queries 可用的查询
+func (hostEventSource) Queries() map[string]string {
+	return hostEventQueries
+}
+
+// Query claim 按 batch_size 领取待研判事件, 领取的执行失败时由 releaseHostEvents 恢复; by_agent 查询 agent 主机最近 30 条事件
+func (src hostEventSource) Query(_ context.Context, query string, params map[string]string) ([]string, [][]interface{}, error) {
+	var events []*HostEvent
+	switch query {
+	case "claim":
+		n := 5
+		if v := params["batch_size"]; v != "" {
+			var err error
+			if n, err = strconv.Atoi(v); err != nil || n <= 0 {
+				return nil, nil, fmt.Errorf("invalid batch_size %q", v)
+			}
+		}
+		events = src.store.claim(n, time.Now())
+	case "by_agent":
+		agent := params["agent"]
+		if agent == "" {
+			return nil, nil, fmt.Errorf("missing parameter: agent")
+		}
+		events = src.store.byAgent(agent, 30)
+	default:
+		return nil, nil, fmt.Errorf("wazuh source only supports sql_id: pending_host_events, host_events_by_agent")
+	}
+
+	columns := []string{"id", "timestamp", "agent", "agent_ip", "rule_id", "level", "description", "groups", "src_ip", "location", "full_log", "mitre"}
+	rows := make([][]interface{}, 0, len(events))
+	for _, e := range events {
+		rows = append(rows, []interface{}{
+			e.ID, e.Timestamp.Format(time.RFC3339), e.Agent, e.AgentIP, e.RuleID, e.Level,
+			e.Description, strings.Join(e.Groups, ","), e.SrcIP, e.Location, e.FullLog, strings.Join(e.Mitre, ","),
+		})
+	}
+	return columns, rows, nil
+}
+
+// Close 无需释放资源
+func (hostEventSource) Close() error {
+	return nil
+}
+
+// releaseHostEvents 执行失败或被中断时, 本次执行期间领取的主机事件恢复为待研判, 由下次执行重新领取;
+// 同一活动不会并发执行, 执行开始后领取的事件即为本次领取
+func (s *Service) releaseHostEvents(run *Run) {
+	if s.hostEvents == nil {
+		return
+	}
+	if n := s.hostEvents.release(run.StartedAt); n > 0 {
+		logger.InfoCF("secops", "Host events released after failed run",
+			map[string]interface{}{
+				"activity": run.Activity,
+				"run_id":   run.ID,
+				"released": n,
+			})
+	}
+}
@@ -0,0 +1,118 @@
+package secops
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestWazuhPullAndClaim(t *testing.T) {
+	var queries []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/wazuh-alerts-*/_search" {
+			http.NotFound(w, r)
+			return
+		}
+		if user, pass, _ := r.BasicAuth(); user != "soclaw" || pass != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var q map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&q)
+		queries = append(queries, q)
+		fmt.Fprint(w, `{"hits":{"hits":[
+			{"_id":"a1","_source":{"timestamp":"2026-10-17T08:00:00.000+0000","agent":{"name":"web-01","ip":"10.0.0.5"},
+				"rule":{"id":"5712","level":10,"description":"sshd brute force","groups":["syslog","sshd"],"mitre":{"id":["T1110"]}},
+				"data":{"srcip":"203.0.113.9"},"location":"/var/log/secure","full_log":"Failed password for root"}},
+			{"_id":"a2","_source":{"timestamp":"2026-10-17T08:05:00.000+0000","agent":{"name":"web-01"},
+				"rule":{"id":"550","level":7,"description":"Integrity checksum changed"}}},
+			{"_id":"bad","_source":{"timestamp":"yesterday"}}
+		]}}`)
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "host_events.json")
+	store := newHostEventStore()
+	if err := store.load(path); err != nil {
+		t.Fatal(err)
+	}
+	wp, err := newWazuhPuller(config.WazuhConfig{URL: srv.URL, Username: "soclaw", Password: "secret"}, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	wp.now = func() time.Time { return now }
+
+	if added, err := wp.pull(context.Background()); err != nil || added != 2 {
+		t.Fatalf("first pull: added=%d err=%v", added, err)
+	}
+	// 第二次从游标继续, 同一告警不重复入队
+	if added, err := wp.pull(context.Background()); err != nil || added != 0 {
+		t.Fatalf("second pull: added=%d err=%v", added, err)
+	}
+	gte := func(q map[string]interface{}) interface{} {
+		filters := q["query"].(map[string]interface{})["bool"].(map[string]interface{})["filter"].([]interface{})
+		return filters[1].(map[string]interface{})["range"].(map[string]interface{})["timestamp"].(map[string]interface{})["gte"]
+	}
+	if gte(queries[0]) != "2026-10-17T08:00:00Z" || gte(queries[1]) != "2026-10-17T08:05:00Z" {
+		t.Errorf("cursor = %v, %v", gte(queries[0]), gte(queries[1]))
+	}
+
+	// 重启后从持久化文件恢复
+	restored := newHostEventStore()
+	if err := restored.load(path); err != nil {
+		t.Fatal(err)
+	}
+	src := hostEventSource{store: restored}
+	columns, rows, err := src.Query(context.Background(), src.Queries()["pending_host_events"], map[string]string{"batch_size": "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0][0] != "a1" || columns[5] != "level" || rows[0][8] != "203.0.113.9" || rows[0][11] != "T1110" {
+		t.Errorf("claimed rows = %v", rows)
+	}
+	_, rows, _ = src.Query(context.Background(), "claim", nil)
+	if len(rows) != 1 || rows[0][0] != "a2" {
+		t.Errorf("second claim = %v", rows)
+	}
+	if _, rows, _ = src.Query(context.Background(), "claim", nil); len(rows) != 0 {
+		t.Errorf("claimed events returned again: %v", rows)
+	}
+
+	_, rows, _ = src.Query(context.Background(), "by_agent", map[string]string{"agent": "web-01"})
+	if len(rows) != 2 || rows[0][0] != "a2" {
+		t.Errorf("by_agent = %v", rows)
+	}
+	if _, _, err := src.Query(context.Background(), "SELECT 1", nil); err == nil {
+		t.Error("expected error for unsupported query")
+	}
+}
+
+func TestReleaseHostEventsAfterFailedRun(t *testing.T) {
+	store := newHostEventStore()
+	base := time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)
+	store.add([]*HostEvent{
+		{ID: "a1", Timestamp: base, Level: 10},
+		{ID: "a2", Timestamp: base.Add(time.Minute), Level: 7},
+	}, base)
+	store.claim(1, base.Add(time.Hour))
+
+	svc := &Service{hostEvents: store}
+	run := &Run{ID: "r1", Activity: "host_analysis", StartedAt: base.Add(2 * time.Hour)}
+	store.claim(1, run.StartedAt.Add(time.Second))
+
+	// 只恢复失败的执行期间领取的事件, 之前成功执行领取的保持已领取
+	svc.releaseHostEvents(run)
+	src := hostEventSource{store: store}
+	_, rows, _ := src.Query(context.Background(), "claim", map[string]string{"batch_size": "5"})
+	if len(rows) != 1 || rows[0][0] != "a2" {
+		t.Errorf("claim after release = %v", rows)
+	}
+}
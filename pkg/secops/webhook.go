@@ -0,0 +1,97 @@
+package secops
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// webhook 请求头
+const (
+	headerWebhookEvent     = "X-Picoclaw-Event"
+	headerWebhookDelivery  = "X-Picoclaw-Delivery"
+	headerWebhookTimestamp = "X-Picoclaw-Timestamp"
+	headerWebhookSignature = "X-Picoclaw-Signature"
+)
+
+// webhookStatusError webhook 返回非 2xx 响应
+type webhookStatusError struct {
+	Code   int
+	Status string
+}
+
+func (e *webhookStatusError) Error() string {
+	return "webhook returned " + e.Status
+}
+
+// retryable 网络错误、408、429 和 5xx 可以重试, 其他 4xx 和飞书业务错误重试也不会成功
+func retryable(err error) bool {
+	var se *webhookStatusError
+	if errors.As(err, &se) {
+		return se.Code == http.StatusRequestTimeout || se.Code == http.StatusTooManyRequests || se.Code >= 500
+	}
+	var fe *feishuStatusError
+	if errors.As(err, &fe) {
+		return false
+	}
+	return !errors.Is(err, context.Canceled)
+}
+
+// signWebhook 计算签名: hex(HMAC-SHA256(secret, timestamp + "." + body))
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// postWebhook 以 JSON 推送通知; 配置了 secret 时附带时间戳和签名, 接收方可据此校验来源并拒绝重放
+func postWebhook(ctx context.Context, client *http.Client, target config.NotifyTargetConfig, event, delivery string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if event != "" {
+		req.Header.Set(headerWebhookEvent, event)
+	}
+	if delivery != "" {
+		req.Header.Set(headerWebhookDelivery, delivery)
+	}
+	if target.Secret != "" {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(headerWebhookTimestamp, ts)
+		req.Header.Set(headerWebhookSignature, "sha256="+signWebhook(target.Secret, ts, body))
+	}
+	for k, v := range target.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &webhookStatusError{Code: resp.StatusCode, Status: resp.Status}
+	}
+	return nil
+}
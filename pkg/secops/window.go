@@ -0,0 +1,159 @@
+package secops
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+// activityWindow 活动的执行时段: 指定星期内的每日时段
+type activityWindow struct {
+	spec        string
+	loc         *time.Location
+	days        map[time.Weekday]bool
+	startMinute int // 距零点分钟数
+	endMinute   int // 等于 startMinute 时表示全天
+}
+
+// loadTimezone 加载 IANA 时区, 为空时使用本地时区
+func loadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", name, err)
+	}
+	return loc, nil
+}
+
+// parseActivityWindow 解析执行时段, 如 "09:00-18:00 Mon-Fri"、"09:00-18:00 Asia/Shanghai, Mon-Fri"、"Sat,Sun";
+// 各部分以空格或逗号分隔, 顺序不限。省略时段表示全天, 省略星期表示每天, 省略时区使用 timezone
+func parseActivityWindow(spec, timezone string) (*activityWindow, error) {
+	w := &activityWindow{spec: spec, days: make(map[time.Weekday]bool)}
+	var hasRange bool
+	fields := strings.FieldsFunc(spec, func(r rune) bool { return r == ' ' || r == ',' })
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty window")
+	}
+	for _, f := range fields {
+		switch {
+		case strings.Contains(f, ":"):
+			if hasRange {
+				return nil, fmt.Errorf("window %q: more than one time range", spec)
+			}
+			start, end, err := parseTimeRange(f)
+			if err != nil {
+				return nil, fmt.Errorf("window %q: %w", spec, err)
+			}
+			w.startMinute, w.endMinute, hasRange = start, end, true
+		case strings.Contains(f, "/") || strings.EqualFold(f, "UTC"):
+			if w.loc != nil {
+				return nil, fmt.Errorf("window %q: more than one timezone", spec)
+			}
+			loc, err := loadTimezone(f)
+			if err != nil {
+				return nil, fmt.Errorf("window %q: %w", spec, err)
+			}
+			w.loc = loc
+		default:
+			if err := w.addDays(f); err != nil {
+				return nil, fmt.Errorf("window %q: %w", spec, err)
+			}
+		}
+	}
+
+	if w.loc == nil {
+		loc, err := loadTimezone(timezone)
+		if err != nil {
+			return nil, err
+		}
+		w.loc = loc
+	}
+	if len(w.days) == 0 {
+		for _, d := range weekdayNames {
+			w.days[d] = true
+		}
+	}
+	return w, nil
+}
+
+// addDays 解析星期或星期范围, 如 "mon"、"Mon-Fri"、"Fri-Mon"
+func (w *activityWindow) addDays(s string) error {
+	from, to, isRange := strings.Cut(s, "-")
+	first, err := parseWeekday(from)
+	if err != nil {
+		return err
+	}
+	last := first
+	if isRange {
+		if last, err = parseWeekday(to); err != nil {
+			return err
+		}
+	}
+	for d := first; ; d = (d + 1) % 7 {
+		w.days[d] = true
+		if d == last {
+			return nil
+		}
+	}
+}
+
+// parseWeekday 解析星期名称, 如 mon、Monday
+func parseWeekday(s string) (time.Weekday, error) {
+	key := strings.ToLower(s)
+	if len(key) > 3 {
+		key = key[:3]
+	}
+	d, ok := weekdayNames[key]
+	if !ok {
+		return 0, fmt.Errorf("invalid weekday %q", s)
+	}
+	return d, nil
+}
+
+// contains 判断 t 是否在执行时段内
+func (w *activityWindow) contains(t time.Time) bool {
+	local := t.In(w.loc)
+	if !w.days[local.Weekday()] {
+		return false
+	}
+	if w.startMinute == w.endMinute {
+		return true
+	}
+	minute := local.Hour()*60 + local.Minute()
+	return minute >= w.startMinute && minute < w.endMinute
+}
+
+// next t 所在或之后最近一个执行时段的开始; t 在时段内时返回 t
+func (w *activityWindow) next(t time.Time) time.Time {
+	if w.contains(t) {
+		return t
+	}
+	local := t.In(w.loc)
+	for day := 0; day <= 7; day++ {
+		start := time.Date(local.Year(), local.Month(), local.Day()+day, w.startMinute/60, w.startMinute%60, 0, 0, w.loc)
+		if w.days[start.Weekday()] && start.After(t) {
+			return start
+		}
+	}
+	return time.Time{}
+}
+
+// validateWindows 校验默认时区和各活动的执行时段
+func validateWindows(cfg *config.SecOpsConfig) error {
+	if _, err := loadTimezone(cfg.Timezone); err != nil {
+		return err
+	}
+	for name, act := range cfg.Activities {
+		if act.Window == "" {
+			continue
+		}
+		if _, err := parseActivityWindow(act.Window, cfg.Timezone); err != nil {
+			return fmt.Errorf("activities.%s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package secops
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/config"
+)
+
+func TestParseActivityWindow(t *testing.T) {
+	w, err := parseActivityWindow("09:00-18:00 Asia/Shanghai, Mon-Fri", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc := w.loc
+	if loc.String() != "Asia/Shanghai" {
+		t.Fatalf("loc = %v", loc)
+	}
+	// 2026-03-02 为周一
+	at := func(day, hour, min int) time.Time { return time.Date(2026, 3, day, hour, min, 0, 0, loc) }
+
+	for _, tc := range []struct {
+		t    time.Time
+		in   bool
+		next time.Time
+	}{
+		{at(2, 10, 0), true, at(2, 10, 0)},
+		{at(2, 8, 59), false, at(2, 9, 0)},
+		{at(2, 18, 0), false, at(3, 9, 0)},
+		{at(6, 19, 0), false, at(9, 9, 0)}, // 周五晚推迟到周一
+		{at(7, 12, 0), false, at(9, 9, 0)},
+	} {
+		if got := w.contains(tc.t); got != tc.in {
+			t.Errorf("contains(%v) = %v", tc.t, got)
+		}
+		if got := w.next(tc.t); !got.Equal(tc.next) {
+			t.Errorf("next(%v) = %v, want %v", tc.t, got, tc.next)
+		}
+	}
+
+	// 省略时段表示全天, 星期范围可跨周末, 未指定时区时使用默认时区
+	w, err = parseActivityWindow("Fri-Sun", "UTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.loc != time.UTC || !w.contains(time.Date(2026, 3, 8, 23, 59, 0, 0, time.UTC)) ||
+		w.contains(time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("weekend window = %+v", w)
+	}
+
+	for _, spec := range []string{"", "18:00-09:00", "09:00-18:00 10:00-11:00", "09:00-18:00 Funday", "09:00-18:00 Mars/Olympus"} {
+		if _, err := parseActivityWindow(spec, ""); err == nil {
+			t.Errorf("%q: expected error", spec)
+		}
+	}
+}
+
+func TestValidateWindows(t *testing.T) {
+	cfg := &config.SecOpsConfig{
+		Timezone:   "Asia/Shanghai",
+		Activities: map[string]config.ActivityConfig{"risk_analysis": {Window: "09:00-18:00 Mon-Fri"}},
+	}
+	if err := validateWindows(cfg); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Timezone = "Nowhere/City"
+	if err := validateWindows(cfg); err == nil {
+		t.Error("invalid timezone: expected error")
+	}
+	cfg.Timezone = ""
+	cfg.Activities["risk_analysis"] = config.ActivityConfig{Window: "9-18"}
+	if err := validateWindows(cfg); err == nil {
+		t.Error("invalid window: expected error")
+	}
+}
+
+func TestDeferOutsideWindow(t *testing.T) {
+	svc := &Service{config: &config.SecOpsConfig{}}
+	now := time.Now().In(time.UTC)
+	// 只包含明天的执行时段, 当前时刻一定在时段外
+	tomorrow := now.AddDate(0, 0, 1).Weekday()
+	w, err := parseActivityWindow(tomorrow.String()[:3], "UTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	activity := &Activity{Name: "risk_analysis", interval: time.Minute, since: now, window: w}
+
+	var timer *time.Timer
+	if !svc.deferOutsideWindow(activity, &timer) || timer == nil {
+		t.Fatal("expected deferral")
+	}
+	defer timer.Stop()
+	first := timer
+	want := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	if got := activity.nextRun(now); !got.Equal(want) {
+		t.Errorf("nextRun = %v, want %v", got, want)
+	}
+
+	// 已推迟时只计数, 不重复推迟
+	if !svc.deferOutsideWindow(activity, &timer) || timer != first {
+		t.Error("expected coalesced deferral")
+	}
+	if n := svc.pool.skipCount("risk_analysis"); n != 2 {
+		t.Errorf("skips = %d, want 2", n)
+	}
+
+	activity.window = nil
+	activity.deferredUntil.Store(nil)
+	if svc.deferOutsideWindow(activity, &timer) {
+		t.Error("no window: unexpected deferral")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -145,6 +146,64 @@ func (sm *SessionManager) TruncateHistory(key string, keepLast int) {
 	session.Updated = time.Now()
 }
 
+// SessionInfo describes a session without its messages.
+type SessionInfo struct {
+	Key      string    `json:"key"`
+	Messages int       `json:"messages"`
+	Summary  bool      `json:"summary"` // older messages have been summarized
+	Created  time.Time `json:"created"`
+	Updated  time.Time `json:"updated"`
+}
+
+// List returns the sessions whose key starts with prefix, most recently
+// updated first.
+func (sm *SessionManager) List(prefix string) []SessionInfo {
+	sm.mu.RLock()
+	list := make([]SessionInfo, 0, len(sm.sessions))
+	for key, session := range sm.sessions {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		list = append(list, SessionInfo{
+			Key:      key,
+			Messages: len(session.Messages),
+			Summary:  session.Summary != "",
+			Created:  session.Created,
+			Updated:  session.Updated,
+		})
+	}
+	sm.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].Updated.Equal(list[j].Updated) {
+			return list[i].Updated.After(list[j].Updated)
+		}
+		return list[i].Key < list[j].Key
+	})
+	return list
+}
+
+// Delete removes a session and its file. It reports whether the session existed.
+func (sm *SessionManager) Delete(key string) (bool, error) {
+	sm.mu.Lock()
+	_, ok := sm.sessions[key]
+	delete(sm.sessions, key)
+	sm.mu.Unlock()
+
+	if sm.storage == "" || !ok {
+		return ok, nil
+	}
+	filename := sanitizeFilename(key)
+	if filename == "." || !filepath.IsLocal(filename) || strings.ContainsAny(filename, `/\`) {
+		return ok, nil
+	}
+	err := os.Remove(filepath.Join(sm.storage, filename+".json"))
+	if err != nil && !os.IsNotExist(err) {
+		return ok, err
+	}
+	return ok, nil
+}
+
 // sanitizeFilename converts a session key into a cross-platform safe filename.
 // Session keys use "channel:chatID" (e.g. "telegram:123456") but ':' is the
 // volume separator on Windows, so filepath.Base would misinterpret the key.
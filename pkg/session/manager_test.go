@@ -72,3 +72,32 @@ func TestSave_RejectsPathTraversal(t *testing.T) {
 		}
 	}
 }
+
+func TestListAndDelete(t *testing.T) {
+	tmpDir := t.TempDir()
+	sm := NewSessionManager(tmpDir)
+	sm.AddMessage("debugui:a", "user", "hello")
+	sm.AddMessage("debugui:b", "user", "hello")
+	sm.AddMessage("telegram:1", "user", "hello")
+	if err := sm.Save("debugui:a"); err != nil {
+		t.Fatal(err)
+	}
+
+	list := sm.List("debugui:")
+	if len(list) != 2 || list[0].Updated.Before(list[1].Updated) || list[0].Messages != 1 {
+		t.Fatalf("List = %+v, want the two debugui sessions newest first", list)
+	}
+
+	if ok, err := sm.Delete("debugui:a"); !ok || err != nil {
+		t.Fatalf("Delete = %v, %v", ok, err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "debugui_a.json")); !os.IsNotExist(err) {
+		t.Errorf("expected session file to be removed, stat err = %v", err)
+	}
+	if ok, _ := sm.Delete("debugui:a"); ok {
+		t.Error("Delete of a missing session reported it existed")
+	}
+	if len(NewSessionManager(tmpDir).List("")) != 0 {
+		t.Error("deleted session reloaded from disk")
+	}
+}
@@ -69,6 +69,32 @@ type AsyncTool interface {
 	SetCallback(cb AsyncCallback)
 }
 
+// MutatingTool is an optional interface for tools whose calls may change
+// external state depending on their arguments (e.g. POST/PUT API calls).
+// Mutating calls can be gated behind an approver, see WithApprover.
+type MutatingTool interface {
+	Tool
+	IsMutating(args map[string]interface{}) bool
+}
+
+// ToolApprover is consulted before a mutating tool call runs. Returning an
+// error aborts the call; the error is reported back to the LLM as the result.
+type ToolApprover func(ctx context.Context, toolName string, args map[string]interface{}) error
+
+type approverKey struct{}
+
+// WithApprover returns a context under which mutating tool calls executed via
+// the registry must first be approved by the given approver.
+func WithApprover(ctx context.Context, approver ToolApprover) context.Context {
+	return context.WithValue(ctx, approverKey{}, approver)
+}
+
+// ApproverFromContext returns the approver set by WithApprover, or nil.
+func ApproverFromContext(ctx context.Context) ToolApprover {
+	approver, _ := ctx.Value(approverKey{}).(ToolApprover)
+	return approver
+}
+
 func ToolToSchema(tool Tool) map[string]interface{} {
 	return map[string]interface{}{
 		"type": "function",
@@ -71,6 +71,20 @@ func (r *ToolRegistry) ExecuteWithContext(ctx context.Context, name string, args
 			})
 	}
 
+	// Mutating calls need approval when an approver is attached to the context
+	if approver := ApproverFromContext(ctx); approver != nil {
+		if mt, ok := tool.(MutatingTool); ok && mt.IsMutating(args) {
+			if err := approver(ctx, name, args); err != nil {
+				logger.InfoCF("tool", "Tool call not approved",
+					map[string]interface{}{
+						"tool":   name,
+						"reason": err.Error(),
+					})
+				return ErrorResult(fmt.Sprintf("tool call not approved: %v", err)).WithError(err)
+			}
+		}
+	}
+
 	start := time.Now()
 	result := tool.Execute(ctx, args)
 	duration := time.Since(start)
@@ -0,0 +1,65 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// recordingTool counts executions; calls with mode=write are mutating
+type recordingTool struct {
+	calls int
+}
+
+func (t *recordingTool) Name() string                       { return "recorder" }
+func (t *recordingTool) Description() string                { return "records calls" }
+func (t *recordingTool) Parameters() map[string]interface{} { return map[string]interface{}{} }
+
+func (t *recordingTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	t.calls++
+	return NewToolResult("ok")
+}
+
+func (t *recordingTool) IsMutating(args map[string]interface{}) bool {
+	return args["mode"] == "write"
+}
+
+func TestExecuteWithContext_Approver(t *testing.T) {
+	tool := &recordingTool{}
+	r := NewToolRegistry()
+	r.Register(tool)
+
+	var asked []string
+	ctx := WithApprover(context.Background(), func(ctx context.Context, name string, args map[string]interface{}) error {
+		asked = append(asked, name)
+		if args["deny"] == true {
+			return errors.New("not today")
+		}
+		return nil
+	})
+
+	// 只读调用不需要确认
+	if res := r.Execute(ctx, "recorder", map[string]interface{}{"mode": "read", "deny": true}); res.IsError {
+		t.Errorf("read call failed: %s", res.ForLLM)
+	}
+	if res := r.Execute(ctx, "recorder", map[string]interface{}{"mode": "write"}); res.IsError {
+		t.Errorf("approved call failed: %s", res.ForLLM)
+	}
+	res := r.Execute(ctx, "recorder", map[string]interface{}{"mode": "write", "deny": true})
+	if !res.IsError || !strings.Contains(res.ForLLM, "not today") {
+		t.Errorf("denied call result = %+v", res)
+	}
+
+	if tool.calls != 2 {
+		t.Errorf("tool executed %d times, want 2", tool.calls)
+	}
+	if len(asked) != 2 {
+		t.Errorf("approver asked %d times, want 2", len(asked))
+	}
+
+	// 未设置 approver 时直接执行
+	if res := r.Execute(context.Background(), "recorder", map[string]interface{}{"mode": "write"}); res.IsError || tool.calls != 3 {
+		t.Errorf("call without approver: %+v, calls = %d", res, tool.calls)
+	}
+}
@@ -0,0 +1,154 @@
+package secops
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// legacyParam 旧格式请求体中的 $name 占位符
+var legacyParam = regexp.MustCompile(`\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// bodyFuncs 请求体模板可用的函数
+var bodyFuncs = template.FuncMap{
+	// json 编码为 JSON 字面量, 字符串带引号, 缺失的参数为 null
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	// str 编码为 JSON 字符串字面量 (带引号), 缺失的参数为 ""; 对象和数组先编码为 JSON 文本
+	"str": func(v interface{}) string {
+		return `"` + jsonEscape(text(v)) + `"`
+	},
+	// esc 转义为 JSON 字符串内容 (不含两端引号), 缺失的参数为空串
+	"esc": func(v interface{}) string {
+		return jsonEscape(text(v))
+	},
+	// default 参数缺失或为空时使用默认值
+	"default": func(def, v interface{}) interface{} {
+		if v == nil || v == "" {
+			return def
+		}
+		return v
+	},
+	// fromJSON 解析 JSON 字符串参数, 用于 range 遍历以 JSON 数组传入的批量数据
+	"fromJSON": func(v interface{}) (interface{}, error) {
+		s, ok := v.(string)
+		if !ok {
+			return v, nil
+		}
+		var out interface{}
+		if err := json.Unmarshal([]byte(s), &out); err != nil {
+			return nil, fmt.Errorf("fromJSON: %w", err)
+		}
+		return out, nil
+	},
+	// split 按分隔符拆分字符串参数, 忽略空项
+	"split": func(sep string, v interface{}) []string {
+		if v == nil {
+			return nil
+		}
+		var out []string
+		for _, part := range strings.Split(text(v), sep) {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+		return out
+	},
+}
+
+// text 参数值的文本形式: 字符串原样, 数字和布尔值按字面量, 对象和数组编码为 JSON, 缺失为空串
+func text(v interface{}) string {
+	if s, ok := scalarText(v); ok {
+		return s
+	}
+	if v == nil {
+		return ""
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// scalarText 字符串、数字和布尔值的文本形式; 数字不使用科学计数法
+func scalarText(v interface{}) (string, bool) {
+	switch x := v.(type) {
+	case string:
+		return x, true
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64), true
+	case json.Number:
+		return x.String(), true
+	case bool, int, int64:
+		return fmt.Sprint(x), true
+	}
+	return "", false
+}
+
+// parseBody 解析请求体模板 (Go text/template); 不含 {{ 的旧格式中引号内的 $name 按 {{esc .name}} 处理,
+// 引号外的按 {{json .name}} 处理, 字符串参数输出为带引号的 JSON 字符串, 不能拼接出额外的字段
+func parseBody(apiID, body string) (*template.Template, error) {
+	if body == "" {
+		return nil, nil
+	}
+	if !strings.Contains(body, "{{") {
+		body = legacyBody(body)
+	}
+	tmpl, err := template.New(apiID).Funcs(bodyFuncs).Option("missingkey=zero").Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid body template for %s: %w", apiID, err)
+	}
+	return tmpl, nil
+}
+
+// legacyBody 将旧格式的 $name 转换为模板动作, 按所在位置是否处于 JSON 字符串内选择转义方式
+func legacyBody(body string) string {
+	var sb strings.Builder
+	inString := false
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case c == '\\' && inString && i+1 < len(body):
+			sb.WriteString(body[i : i+2])
+			i++
+			continue
+		case c == '"':
+			inString = !inString
+		case c == '$':
+			if m := legacyParam.FindStringSubmatch(body[i:]); m != nil && strings.HasPrefix(body[i:], m[0]) {
+				fn := "json"
+				if inString {
+					fn = "esc"
+				}
+				sb.WriteString("{{" + fn + " ." + m[1] + "}}")
+				i += len(m[0]) - 1
+				continue
+			}
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+// renderBody 渲染请求体, 结果必须是合法 JSON
+func renderBody(tmpl *template.Template, data map[string]interface{}) (string, error) {
+	if tmpl == nil {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render body: %w", err)
+	}
+	body := strings.TrimSpace(buf.String())
+	if !json.Valid([]byte(body)) {
+		return "", fmt.Errorf("rendered body is not valid JSON: %s", body)
+	}
+	return body, nil
+}
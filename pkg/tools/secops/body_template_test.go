@@ -0,0 +1,161 @@
+package secops
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const testRiskBody = `[
+{{- with .items}}{{range $i, $e := fromJSON .}}{{if $i}},{{end}}
+  {"host": {{str $e.host}}, "note": {{str (default $.note $e.note)}}}
+{{- end}}{{else}}
+  {"host": {{str .host}}{{with .note}}, "note": {{str .}}{{end}}}
+{{- end}}
+]`
+
+func TestBodyTemplate(t *testing.T) {
+	tool := NewSecOpsSheikahAPITool(map[string]APIConfig{
+		"confirm": {Method: "POST", Path: "/risk/confirm", Body: testRiskBody},
+		"legacy":  {Method: "POST", Path: "/legacy/$id", Body: `{"host": "$host", "level": $level}`},
+		"unsafe":  {Method: "POST", Path: "/unsafe", Body: `{"level": {{.level}}}`},
+	}, "http://sheikah", "")
+	if err := tool.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	cases := []struct {
+		api    string
+		params map[string]string
+		want   string
+	}{
+		// 可选字段
+		{"confirm", map[string]string{"host": `a"b`}, `[{"host":"a\"b"}]`},
+		{"confirm", map[string]string{"host": "a", "note": "n"}, `[{"host":"a","note":"n"}]`},
+		// 循环: items 以 JSON 数组传入, 条目未指定 note 时使用外层 note
+		{"confirm", map[string]string{"note": "batch", "items": `[{"host":"a"},{"host":"b","note":"own"}]`},
+			`[{"host":"a","note":"batch"},{"host":"b","note":"own"}]`},
+		// 旧格式 $name 仍然可用; 引号外的字符串参数按 JSON 字符串输出, 不能注入额外字段
+		{"legacy", map[string]string{"id": "1", "host": "h", "level": "3"}, `{"host":"h","level":"3"}`},
+		{"legacy", map[string]string{"host": "h", "level": `3, "admin": true`}, `{"host":"h","level":"3, \"admin\": true"}`},
+	}
+	for _, c := range cases {
+		req, err := tool.Render(c.api, c.params)
+		if err != nil {
+			t.Errorf("Render(%s, %v): %v", c.api, c.params, err)
+			continue
+		}
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, []byte(req.Body)); err != nil {
+			t.Errorf("body %s: %v", req.Body, err)
+			continue
+		}
+		if compact.String() != c.want {
+			t.Errorf("Render(%s, %v) body = %s, want %s", c.api, c.params, compact.String(), c.want)
+		}
+	}
+
+	// 渲染结果不是合法 JSON 时拒绝发送
+	if _, err := tool.Render("unsafe", map[string]string{"level": "high"}); err == nil {
+		t.Error("expected error for invalid rendered JSON")
+	}
+}
+
+func TestBodyTemplateValidate(t *testing.T) {
+	tool := NewSecOpsSheikahAPITool(map[string]APIConfig{
+		"ok":     {Method: "POST", Path: "/ok", Body: `{"a": {{str .a}}}`},
+		"broken": {Method: "POST", Path: "/broken", Body: `{"a": {{str .a}`},
+	}, "http://sheikah", "")
+	err := tool.Validate()
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Fatalf("Validate = %v, want error for broken", err)
+	}
+	if _, err := tool.Render("broken", nil); err == nil {
+		t.Error("expected error rendering an invalid template")
+	}
+	if _, err := tool.Render("ok", nil); err != nil {
+		t.Errorf("Render(ok): %v", err)
+	}
+}
+
+func TestExecuteJSONParams(t *testing.T) {
+	tool := NewSecOpsSheikahAPITool(map[string]APIConfig{
+		"confirm": {Method: "POST", Path: "/risk/confirm", Body: testRiskBody},
+	}, "http://127.0.0.1:1", "")
+	params, err := parseParams(`{"note": "n", "items": [{"host": "a,b"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, body, err := tool.build("confirm", params)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	if !strings.Contains(body, `"host": "a,b", "note": "n"`) {
+		t.Errorf("body = %s", body)
+	}
+
+	res := tool.Execute(context.Background(), map[string]interface{}{"api": "confirm", "params": `{"items": `})
+	if !res.IsError || !strings.Contains(res.ForLLM, "invalid params JSON") {
+		t.Errorf("Execute = %+v, want params error", res)
+	}
+}
+
+func TestExecuteObjectParams(t *testing.T) {
+	var got []string
+	tool := NewSecOpsSheikahAPITool(map[string]APIConfig{
+		"legacy":  {Method: "POST", Path: "/risk/$id/confirm", Body: `{"host": "$host", "note": "$note", "level": $level, "tags": $tags}`},
+		"confirm": {Method: "POST", Path: "/risk/confirm", Body: testRiskBody},
+	}, "http://sheikah", "")
+	tool.SetTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, r.URL.Path+" "+string(body))
+		return &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(`{}`)), Header: http.Header{}}, nil
+	}))
+
+	// 对象参数: 值中的逗号、等号和引号按 JSON 转义, 数字和数组在引号外按 JSON 输出
+	res := tool.Execute(context.Background(), map[string]interface{}{"api": "legacy", "params": map[string]interface{}{
+		"id": float64(42), "host": "a.example.com", "note": `见 "wiki" https://wiki/x?a=1,b=2`,
+		"level": float64(3), "tags": []interface{}{"scanner", "批量"},
+	}})
+	if res.IsError {
+		t.Fatalf("Execute: %s", res.ForLLM)
+	}
+	want := `/risk/42/confirm {"host": "a.example.com", "note": "见 \"wiki\" https://wiki/x?a=1,b=2", "level": 3, "tags": ["scanner","批量"]}`
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("request = %q, want %q", got, want)
+	}
+
+	// items 直接传数组, 无需再编码为 JSON 字符串
+	res = tool.Execute(context.Background(), map[string]interface{}{"api": "confirm", "params": map[string]interface{}{
+		"note": "n", "items": []interface{}{map[string]interface{}{"host": "a,b"}},
+	}})
+	if res.IsError || len(got) != 2 || !strings.Contains(got[1], `"host": "a,b", "note": "n"`) {
+		t.Errorf("items request = %q (%s)", got, res.ForLLM)
+	}
+
+	res = tool.Execute(context.Background(), map[string]interface{}{"api": "confirm", "params": []interface{}{"x"}})
+	if !res.IsError {
+		t.Error("expected error for non-object params")
+	}
+}
+
+func TestParseLegacyParams(t *testing.T) {
+	params, err := parseParams("host=a.example.com, note=见 https://wiki/x?a=1,b,c=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 不以 key= 开头的分段并入前一个值
+	if params["host"] != "a.example.com" || params["note"] != "见 https://wiki/x?a=1,b" || params["c"] != "3" {
+		t.Errorf("params = %v", params)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
@@ -0,0 +1,114 @@
+package secops
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen ClickHouse 熔断中, 查询未发送
+var ErrCircuitOpen = errors.New("ClickHouse is unavailable (circuit breaker open), query not sent")
+
+// BreakerState 熔断器状态
+type BreakerState struct {
+	Open      bool      `json:"open"`
+	Failures  int       `json:"failures"`            // 连续失败次数
+	Threshold int       `json:"threshold"`           // 连续失败多少次后熔断, 0 表示不熔断
+	OpenedAt  time.Time `json:"openedAt"`            // 最近一次熔断时间
+	LastError string    `json:"lastError,omitempty"` // 最近一次失败的错误
+}
+
+// CircuitBreaker ClickHouse 熔断器: 连续失败达到阈值后拒绝查询, 由外部健康探测成功后恢复
+//
+// 只有网络错误和 5xx 计为失败, SQL 错误等 4xx 响应说明 ClickHouse 可用。
+// 热加载重建查询工具时沿用同一个熔断器。
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	failures  int
+	open      bool
+	openedAt  time.Time
+	lastErr   string
+	onChange  func(BreakerState)
+}
+
+// NewCircuitBreaker 创建熔断器, threshold <= 0 时不熔断
+func NewCircuitBreaker(threshold int) *CircuitBreaker {
+	return &CircuitBreaker{threshold: threshold}
+}
+
+// SetThreshold 修改熔断阈值, threshold <= 0 时立即恢复并不再熔断
+func (b *CircuitBreaker) SetThreshold(threshold int) {
+	b.mu.Lock()
+	b.threshold = threshold
+	closed := b.open && threshold <= 0
+	if closed {
+		b.open, b.failures = false, 0
+	}
+	st, fn := b.stateLocked(), b.onChange
+	b.mu.Unlock()
+	if closed && fn != nil {
+		fn(st)
+	}
+}
+
+// OnStateChange 熔断和恢复时回调, 回调在锁外执行
+func (b *CircuitBreaker) OnStateChange(fn func(BreakerState)) {
+	b.mu.Lock()
+	b.onChange = fn
+	b.mu.Unlock()
+}
+
+// Allow 是否允许发送查询
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.open
+}
+
+// Success 记录一次成功, 熔断中时恢复
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	b.failures = 0
+	closed := b.open
+	b.open = false
+	st, fn := b.stateLocked(), b.onChange
+	b.mu.Unlock()
+	if closed && fn != nil {
+		fn(st)
+	}
+}
+
+// Failure 记录一次失败, 连续失败达到阈值时熔断
+func (b *CircuitBreaker) Failure(err error) {
+	b.mu.Lock()
+	b.failures++
+	b.lastErr = err.Error()
+	opened := !b.open && b.threshold > 0 && b.failures >= b.threshold
+	if opened {
+		b.open = true
+		b.openedAt = time.Now()
+	}
+	st, fn := b.stateLocked(), b.onChange
+	b.mu.Unlock()
+	if opened && fn != nil {
+		fn(st)
+	}
+}
+
+// State 当前状态
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.stateLocked()
+}
+
+func (b *CircuitBreaker) stateLocked() BreakerState {
+	return BreakerState{
+		Open:      b.open,
+		Failures:  b.failures,
+		Threshold: max(b.threshold, 0),
+		OpenedAt:  b.openedAt,
+		LastError: b.lastErr,
+	}
+}
@@ -0,0 +1,91 @@
+package secops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestQueryDataCircuitBreaker(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	var queries atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ping" {
+			fmt.Fprint(w, "Ok.\n")
+			return
+		}
+		queries.Add(1)
+		if code := int(status.Load()); code != http.StatusOK {
+			http.Error(w, "boom", code)
+			return
+		}
+		fmt.Fprint(w, `{"meta":[{"name":"n","type":"UInt8"}],"data":[[1]],"rows":1}`)
+	}))
+	defer srv.Close()
+
+	var changes []bool
+	b := NewCircuitBreaker(2)
+	b.OnStateChange(func(st BreakerState) { changes = append(changes, st.Open) })
+	tool := NewSecOpsQueryDataTool(nil, srv.URL, "", "")
+	tool.SetBreaker(b)
+	ctx := context.Background()
+
+	// SQL 错误说明 ClickHouse 可用, 不计入失败
+	status.Store(http.StatusBadRequest)
+	for i := 0; i < 3; i++ {
+		tool.Query(ctx, "SELEC 1")
+	}
+	if st := b.State(); st.Open || st.Failures != 0 {
+		t.Fatalf("4xx state = %+v", st)
+	}
+
+	// 连续 5xx 达到阈值后熔断, 之后的查询不再发送
+	status.Store(http.StatusServiceUnavailable)
+	tool.Query(ctx, "SELECT 1")
+	tool.Query(ctx, "SELECT 1")
+	sent := queries.Load()
+	if _, err := tool.Query(ctx, "SELECT 1"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want ErrCircuitOpen", err)
+	}
+	if queries.Load() != sent {
+		t.Error("query sent while circuit is open")
+	}
+	if st := b.State(); !st.Open || st.Failures != 2 || st.LastError == "" || st.OpenedAt.IsZero() {
+		t.Errorf("open state = %+v", st)
+	}
+	if res := tool.Execute(ctx, map[string]interface{}{"raw_sql": "SELECT 1"}); !res.IsError {
+		t.Errorf("Execute while open = %q", res.ForLLM)
+	}
+
+	// 探测不经过熔断器, 成功后由调用方恢复
+	if err := tool.Ping(ctx); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	b.Success()
+	status.Store(http.StatusOK)
+	if rows, err := tool.Query(ctx, "SELECT 1"); err != nil || len(rows) != 1 {
+		t.Errorf("after recovery = %v, %v", rows, err)
+	}
+	if len(changes) != 2 || !changes[0] || changes[1] {
+		t.Errorf("state changes = %v", changes)
+	}
+
+	// 关闭熔断后立即恢复
+	b.Failure(errors.New("x"))
+	b.Failure(errors.New("x"))
+	b.SetThreshold(0)
+	if !b.Allow() {
+		t.Error("threshold 0 should close the breaker")
+	}
+	for i := 0; i < 5; i++ {
+		b.Failure(errors.New("x"))
+	}
+	if !b.Allow() {
+		t.Error("disabled breaker must not open")
+	}
+}
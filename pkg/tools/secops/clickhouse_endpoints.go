@@ -0,0 +1,204 @@
+package secops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+// chEndpoint 一个 ClickHouse 地址及其健康状态
+type chEndpoint struct {
+	baseURL string
+	replica bool // 只读副本, 不接收写入
+	down    bool
+	downAt  time.Time
+	lastErr string
+}
+
+// EndpointState ClickHouse 地址的健康状态
+type EndpointState struct {
+	URL       string     `json:"url"`
+	Replica   bool       `json:"replica,omitempty"`
+	Healthy   bool       `json:"healthy"`
+	DownAt    *time.Time `json:"downAt,omitempty"`
+	LastError string     `json:"lastError,omitempty"`
+}
+
+// endpointPool 主地址和只读副本; 查询失败的地址标记为不可用, 由健康探测恢复
+type endpointPool struct {
+	mu           sync.Mutex
+	endpoints    []*chEndpoint // 第一个为主地址
+	readReplicas bool          // 读写分离: 只读查询优先发往副本
+}
+
+// readStatement 只读查询的起始关键字, 其余语句 (INSERT、ALTER 等) 视为写入, 只发往主地址
+var readStatement = regexp.MustCompile(`(?i)^\s*\(*\s*(SELECT|WITH|SHOW|DESC|DESCRIBE|EXPLAIN|EXISTS)\b`)
+
+// sqlComment 行注释和块注释
+var sqlComment = regexp.MustCompile(`(?s)--[^\n]*|/\*.*?\*/`)
+
+// isReadQuery 判断语句是否只读
+func isReadQuery(sql string) bool {
+	return readStatement.MatchString(sqlComment.ReplaceAllString(sql, " "))
+}
+
+// SetReplicas 设置只读副本地址; readFromReplicas 为 true 时只读查询优先发往副本, 否则只在主地址不可用时使用
+func (t *SecOpsQueryDataTool) SetReplicas(baseURLs []string, readFromReplicas bool) {
+	t.pool.mu.Lock()
+	defer t.pool.mu.Unlock()
+	t.pool.endpoints = t.pool.endpoints[:1]
+	for _, u := range baseURLs {
+		t.pool.endpoints = append(t.pool.endpoints, &chEndpoint{baseURL: u, replica: true})
+	}
+	t.pool.readReplicas = readFromReplicas && len(baseURLs) > 0
+}
+
+// Endpoints 各 ClickHouse 地址的健康状态, 主地址在前
+func (t *SecOpsQueryDataTool) Endpoints() []EndpointState {
+	t.pool.mu.Lock()
+	defer t.pool.mu.Unlock()
+	states := make([]EndpointState, 0, len(t.pool.endpoints))
+	for _, ep := range t.pool.endpoints {
+		st := EndpointState{URL: ep.baseURL, Replica: ep.replica, Healthy: !ep.down, LastError: ep.lastErr}
+		if ep.down {
+			at := ep.downAt
+			st.DownAt = &at
+		}
+		states = append(states, st)
+	}
+	return states
+}
+
+// Degraded 是否有地址被标记为不可用
+func (t *SecOpsQueryDataTool) Degraded() bool {
+	t.pool.mu.Lock()
+	defer t.pool.mu.Unlock()
+	for _, ep := range t.pool.endpoints {
+		if ep.down {
+			return true
+		}
+	}
+	return false
+}
+
+// candidates 查询依次尝试的地址: 写入只发往主地址; 只读查询按读写分离设置排序,
+// 可用的地址在前, 已标记不可用的地址作为最后的尝试
+func (p *endpointPool) candidates(write bool) []*chEndpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if write {
+		return p.endpoints[:1]
+	}
+
+	ordered := make([]*chEndpoint, 0, len(p.endpoints))
+	if p.readReplicas {
+		ordered = append(ordered, p.endpoints[1:]...)
+		ordered = append(ordered, p.endpoints[0])
+	} else {
+		ordered = append(ordered, p.endpoints...)
+	}
+	healthy := make([]*chEndpoint, 0, len(ordered))
+	var down []*chEndpoint
+	for _, ep := range ordered {
+		if ep.down {
+			down = append(down, ep)
+		} else {
+			healthy = append(healthy, ep)
+		}
+	}
+	return append(healthy, down...)
+}
+
+// failoverStatus 表示地址本身不可用的状态码, 只有这些响应才切换到下一个地址
+func failoverStatus(status int) bool {
+	switch status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// markDown 标记地址不可用
+func (p *endpointPool) markDown(ep *chEndpoint, err error) {
+	p.mu.Lock()
+	changed := !ep.down
+	if changed {
+		ep.down, ep.downAt = true, time.Now()
+	}
+	ep.lastErr = err.Error()
+	multi := len(p.endpoints) > 1
+	p.mu.Unlock()
+
+	if changed && multi {
+		logger.WarnCF("secops", "ClickHouse endpoint unavailable, failing over",
+			map[string]interface{}{
+				"endpoint": ep.baseURL,
+				"replica":  ep.replica,
+				"error":    err.Error(),
+			})
+	}
+}
+
+// markUp 标记地址恢复
+func (p *endpointPool) markUp(ep *chEndpoint) {
+	p.mu.Lock()
+	changed := ep.down
+	ep.down, ep.lastErr = false, ""
+	multi := len(p.endpoints) > 1
+	p.mu.Unlock()
+
+	if changed && multi {
+		logger.InfoCF("secops", "ClickHouse endpoint recovered",
+			map[string]interface{}{
+				"endpoint": ep.baseURL,
+				"replica":  ep.replica,
+			})
+	}
+}
+
+// Ping 探测所有 ClickHouse 地址并更新健康状态, 不经过熔断器; 任一地址可用即返回 nil, 任何非 5xx 响应都视为可用
+func (t *SecOpsQueryDataTool) Ping(ctx context.Context) error {
+	t.pool.mu.Lock()
+	endpoints := append([]*chEndpoint(nil), t.pool.endpoints...)
+	t.pool.mu.Unlock()
+
+	var errs []error
+	for _, ep := range endpoints {
+		if err := t.pingEndpoint(ctx, ep.baseURL); err != nil {
+			t.pool.markDown(ep, err)
+			errs = append(errs, err)
+			continue
+		}
+		t.pool.markUp(ep)
+	}
+	if len(errs) == len(endpoints) {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+// pingEndpoint 探测单个地址的 /ping
+func (t *SecOpsQueryDataTool) pingEndpoint(ctx context.Context, baseURL string) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimRight(baseURL, "/")+"/ping", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("ClickHouse ping returned %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package secops
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeClickHouse 按 fail 返回 502 或空结果, 记录收到的查询数
+type fakeClickHouse struct {
+	*httptest.Server
+	fail    atomic.Bool
+	queries atomic.Int32
+}
+
+func newFakeClickHouse(t *testing.T) *fakeClickHouse {
+	f := &fakeClickHouse{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if f.fail.Load() {
+			http.Error(w, "replica down", http.StatusBadGateway)
+			return
+		}
+		if r.URL.Path == "/ping" {
+			fmt.Fprint(w, "Ok.")
+			return
+		}
+		f.queries.Add(1)
+		fmt.Fprint(w, `{"meta":[],"data":[]}`)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func TestClickHouseFailover(t *testing.T) {
+	primary, replica := newFakeClickHouse(t), newFakeClickHouse(t)
+	tool := NewSecOpsQueryDataTool(nil, primary.URL, "", "")
+	tool.SetReplicas([]string{replica.URL}, false)
+	breaker := NewCircuitBreaker(1)
+	tool.SetBreaker(breaker)
+	ctx := context.Background()
+
+	// 主地址 502: 只读查询切换到副本, 不计入熔断
+	primary.fail.Store(true)
+	if _, err := tool.Query(ctx, "SELECT 1"); err != nil {
+		t.Fatalf("read should fail over: %v", err)
+	}
+	if replica.queries.Load() != 1 || !breaker.Allow() {
+		t.Fatalf("replica queries = %d, breaker open = %v", replica.queries.Load(), !breaker.Allow())
+	}
+	if !tool.Degraded() || tool.Endpoints()[0].Healthy {
+		t.Errorf("primary should be marked down: %+v", tool.Endpoints())
+	}
+
+	// 写入只发往主地址
+	if _, err := tool.Query(ctx, "INSERT INTO t VALUES (1)"); err == nil {
+		t.Error("write should not fail over to replica")
+	}
+	if replica.queries.Load() != 1 {
+		t.Errorf("write reached replica")
+	}
+
+	// 探测成功后主地址恢复
+	primary.fail.Store(false)
+	if err := tool.Ping(ctx); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	if tool.Degraded() {
+		t.Errorf("endpoints should be healthy: %+v", tool.Endpoints())
+	}
+}
+
+func TestClickHouseQueryErrorNoFailover(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Code: 62. DB::Exception: Syntax error", http.StatusInternalServerError)
+	}))
+	defer primary.Close()
+	replica := newFakeClickHouse(t)
+	tool := NewSecOpsQueryDataTool(nil, primary.URL, "", "")
+	tool.SetReplicas([]string{replica.URL}, false)
+
+	// 查询本身出错返回的 500 不切换地址, 也不标记主地址不可用
+	if _, err := tool.Query(context.Background(), "SELEC 1"); err == nil {
+		t.Fatal("expected query error")
+	}
+	if replica.queries.Load() != 0 || tool.Degraded() {
+		t.Errorf("replica queries = %d, endpoints = %+v", replica.queries.Load(), tool.Endpoints())
+	}
+}
+
+func TestClickHouseAllEndpointsDown(t *testing.T) {
+	primary, replica := newFakeClickHouse(t), newFakeClickHouse(t)
+	primary.fail.Store(true)
+	replica.fail.Store(true)
+	tool := NewSecOpsQueryDataTool(nil, primary.URL, "", "")
+	tool.SetReplicas([]string{replica.URL}, false)
+	breaker := NewCircuitBreaker(2)
+	tool.SetBreaker(breaker)
+
+	if _, err := tool.Query(context.Background(), "SELECT 1"); err == nil {
+		t.Fatal("expected error when all endpoints fail")
+	}
+	if st := breaker.State(); st.Failures != 1 {
+		t.Errorf("breaker failures = %d, want 1 per query", st.Failures)
+	}
+	if err := tool.Ping(context.Background()); err == nil {
+		t.Error("Ping should fail when all endpoints are down")
+	}
+}
+
+func TestClickHouseReadFromReplicas(t *testing.T) {
+	primary, replica := newFakeClickHouse(t), newFakeClickHouse(t)
+	tool := NewSecOpsQueryDataTool(nil, primary.URL, "", "")
+	tool.SetReplicas([]string{replica.URL}, true)
+	ctx := context.Background()
+
+	if _, err := tool.Query(ctx, "-- recent\nWITH x AS (SELECT 1) SELECT * FROM x"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tool.Query(ctx, "ALTER TABLE t DELETE WHERE 1"); err != nil {
+		t.Fatal(err)
+	}
+	if primary.queries.Load() != 1 || replica.queries.Load() != 1 {
+		t.Errorf("primary = %d, replica = %d, want read on replica and write on primary",
+			primary.queries.Load(), replica.queries.Load())
+	}
+
+	// 副本不可用时只读查询回到主地址
+	replica.fail.Store(true)
+	if _, err := tool.Query(ctx, "SELECT 1"); err != nil || primary.queries.Load() != 2 {
+		t.Errorf("read should fall back to primary: err=%v primary=%d", err, primary.queries.Load())
+	}
+}
+
+func TestIsReadQuery(t *testing.T) {
+	for sql, want := range map[string]bool{
+		"SELECT 1":                              true,
+		"  select * from t":                     true,
+		"(SELECT 1) UNION ALL (SELECT 2)":       true,
+		"/* r */ WITH a AS (SELECT 1) SELECT 1": true,
+		"SHOW TABLES":                           true,
+		"EXPLAIN SELECT 1":                      true,
+		"INSERT INTO t SELECT * FROM s":         false,
+		"ALTER TABLE t DELETE WHERE 1":          false,
+		"-- SELECT\nTRUNCATE TABLE t":           false,
+		"OPTIMIZE TABLE t FINAL":                false,
+		"SELECTED":                              false,
+	} {
+		if got := isReadQuery(sql); got != want {
+			t.Errorf("isReadQuery(%q) = %v, want %v", sql, got, want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package secops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// defaultClickHouseSourceTimeout ClickHouse 数据源的默认请求超时, 冷数据集群上的历史回溯通常较慢
+const defaultClickHouseSourceTimeout = 5 * time.Minute
+
+// ClickHouseSourceConfig ClickHouse 数据源参数
+type ClickHouseSourceConfig struct {
+	BaseURL  string // HTTP 接口地址, 如 http://ch-archive:8123
+	Username string
+	Password string
+	Timeout  time.Duration // 请求超时, 默认 5m
+}
+
+// ClickHouseSource 另一个 ClickHouse 集群, 如存放历史数据的冷集群
+//
+// 与默认 ClickHouse 相同, 模板中的 {name:Type} 由服务端绑定, 旧式 $name 按字面量转义; 不经过默认集群的熔断器。
+type ClickHouseSource struct {
+	tool *SecOpsQueryDataTool
+}
+
+// NewClickHouseSource 创建 ClickHouse 数据源, queries 为该集群的查询模板
+func NewClickHouseSource(cfg ClickHouseSourceConfig, queries map[string]string) (*ClickHouseSource, error) {
+	u, err := url.Parse(cfg.BaseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.New("clickhouse dsn must be an http(s) url, e.g. http://ch-archive:8123")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultClickHouseSourceTimeout
+	}
+	if queries == nil {
+		queries = map[string]string{}
+	}
+	tool := NewSecOpsQueryDataTool(queries, strings.TrimRight(cfg.BaseURL, "/"), cfg.Username, cfg.Password)
+	tool.client = &http.Client{Timeout: cfg.Timeout}
+	return &ClickHouseSource{tool: tool}, nil
+}
+
+// Driver 数据源类型
+func (s *ClickHouseSource) Driver() string {
+	return "clickhouse"
+}
+
+// Queries 可用的查询模板
+func (s *ClickHouseSource) Queries() map[string]string {
+	return s.tool.queries
+}
+
+// Query 填入参数并执行查询, 返回列名和行
+func (s *ClickHouseSource) Query(ctx context.Context, query string, params map[string]string) ([]string, [][]interface{}, error) {
+	sql, err := replaceParams(query, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	columns, _, rows, err := s.tool.query(ctx, sql, params)
+	return columns, rows, err
+}
+
+// Close 关闭客户端
+func (s *ClickHouseSource) Close() error {
+	return s.tool.Close()
+}
+
+// RouteTemplate 将默认 ClickHouse 的模板 sqlID 路由到 source 数据源执行, 如把历史回溯模板交给冷集群;
+// source 未定义同名模板时使用默认 ClickHouse 的模板
+func (t *SecOpsQueryDataTool) RouteTemplate(sqlID, source string) error {
+	src, ok := t.sources[source]
+	if !ok {
+		return fmt.Errorf("unknown source: %s", source)
+	}
+	if src.Driver() != "clickhouse" {
+		return fmt.Errorf("source %s is %s, only clickhouse sources accept routed templates", source, src.Driver())
+	}
+	_, own := src.Queries()[sqlID]
+	if _, builtin := t.queries[sqlID]; !own && !builtin {
+		return fmt.Errorf("sql_id not found: %s", sqlID)
+	}
+	if prev, ok := t.routes[sqlID]; ok && prev != source {
+		return fmt.Errorf("sql_id %s is already routed to %s", sqlID, prev)
+	}
+	if t.routes == nil {
+		t.routes = make(map[string]string)
+	}
+	t.routes[sqlID] = source
+	return nil
+}
+
+// Routes 已路由到其他集群的模板, sql_id 到数据源名称
+func (t *SecOpsQueryDataTool) Routes() map[string]string {
+	return t.routes
+}
+
+// templateFor 数据源中的模板, 数据源未定义但已路由到该数据源时使用默认 ClickHouse 的模板
+func (t *SecOpsQueryDataTool) templateFor(source, sqlID string) (string, bool) {
+	if q, ok := t.sources[source].Queries()[sqlID]; ok {
+		return q, true
+	}
+	if t.routes[sqlID] == source {
+		q, ok := t.queries[sqlID]
+		return q, ok
+	}
+	return "", false
+}
@@ -0,0 +1,100 @@
+package secops
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// recordingClickHouse 记录收到的查询, 返回一列 cluster 标识所在集群
+func recordingClickHouse(t *testing.T, cluster string, got *[]string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		*got = append(*got, r.PostForm.Get("query"))
+		fmt.Fprintf(w, `{"meta":[{"name":"cluster","type":"String"}],"data":[[%q]]}`, cluster)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClickHouseTemplateRouting(t *testing.T) {
+	var hotQueries, coldQueries []string
+	hot := recordingClickHouse(t, "hot", &hotQueries)
+	cold := recordingClickHouse(t, "cold", &coldQueries)
+
+	tool := NewSecOpsQueryDataTool(map[string]string{
+		"access_by_ip":   "SELECT * FROM access WHERE ip = {ip:String}",
+		"risk_top20":     "SELECT risk FROM risk_events",
+		"access_history": "SELECT * FROM access WHERE ip = {ip:String}",
+	}, hot.URL, "", "")
+	archive, err := NewClickHouseSource(ClickHouseSourceConfig{BaseURL: cold.URL}, map[string]string{
+		"risk_history": "SELECT risk FROM risk_events_archive WHERE ts > now() - INTERVAL {days:UInt32} DAY",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool.AddSource("archive", archive)
+	for _, id := range []string{"access_history", "risk_history"} {
+		if err := tool.RouteTemplate(id, "archive"); err != nil {
+			t.Fatalf("RouteTemplate(%s): %v", id, err)
+		}
+	}
+	ctx := context.Background()
+
+	// 路由的内置模板和冷集群自有模板都在冷集群执行, 未路由的模板留在热集群
+	for id, want := range map[string]string{"access_history": "cold", "risk_history": "cold", "access_by_ip": "hot"} {
+		res := tool.Execute(ctx, map[string]interface{}{"sql_id": id, "params": "ip=1.2.3.4,days=90"})
+		if res.IsError || !strings.Contains(res.ForLLM, "| "+want+" |") {
+			t.Errorf("%s: want %s cluster, got %s", id, want, res.ForLLM)
+		}
+	}
+	if len(coldQueries) != 2 || len(hotQueries) != 1 {
+		t.Errorf("hot = %v, cold = %v", hotQueries, coldQueries)
+	}
+
+	columns, rows, err := tool.QueryTemplate(ctx, "", "access_history", map[string]string{"ip": "1.2.3.4"})
+	if err != nil || len(columns) != 1 || rows[0][0] != "cold" {
+		t.Errorf("QueryTemplate: columns=%v rows=%v err=%v", columns, rows, err)
+	}
+	if !strings.Contains(tool.Description(), "access_history → archive") {
+		t.Errorf("description should list routed templates:\n%s", tool.Description())
+	}
+
+	// raw_sql 不受路由影响
+	hotQueries = nil
+	if res := tool.Execute(ctx, map[string]interface{}{"sql_id": "access_history", "raw_sql": "SELECT 1"}); res.IsError || len(hotQueries) != 1 {
+		t.Errorf("raw_sql should run on hot cluster: %s", res.ForLLM)
+	}
+}
+
+func TestRouteTemplateErrors(t *testing.T) {
+	tool := NewSecOpsQueryDataTool(map[string]string{"risk_top20": "SELECT 1"}, "http://hot:8123", "", "")
+	archive, err := NewClickHouseSource(ClickHouseSourceConfig{BaseURL: "http://cold:8123"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool.AddSource("archive", archive)
+	tool.AddSource("archive2", archive)
+	tool.AddSource("audit", &SQLSource{driver: "postgres"})
+
+	if err := tool.RouteTemplate("risk_top20", "archive"); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct{ id, source string }{
+		{"risk_top20", "missing"},
+		{"risk_top20", "audit"},
+		{"unknown", "archive"},
+		{"risk_top20", "archive2"},
+	} {
+		if err := tool.RouteTemplate(tc.id, tc.source); err == nil {
+			t.Errorf("RouteTemplate(%s, %s): expected error", tc.id, tc.source)
+		}
+	}
+
+	if _, err := NewClickHouseSource(ClickHouseSourceConfig{BaseURL: "ch-archive:8123"}, nil); err == nil {
+		t.Error("dsn without scheme: expected error")
+	}
+}
@@ -0,0 +1,48 @@
+package secops
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/sipeed/picoclaw/pkg/logger"
+)
+
+type dryRunKey struct{}
+
+// WithDryRun 返回试运行上下文, 其下 sheikah_api 的修改类调用不发送, 返回模拟成功; 只读调用照常执行
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+// DryRunFromContext 是否处于试运行
+func DryRunFromContext(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+	return dryRun
+}
+
+// dryRunResponse 模拟 Sheikah 的成功响应, 附带本应发送的请求, 便于核对 Agent 的处置是否符合预期
+func dryRunResponse(apiID, method, path, body string) string {
+	logger.InfoCF("secops", "Dry run: Sheikah API call intercepted",
+		map[string]interface{}{
+			"api":    apiID,
+			"method": method,
+			"path":   path,
+		})
+
+	request := map[string]interface{}{"api": apiID, "method": method, "path": path}
+	if body != "" {
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(body), &parsed); err == nil {
+			request["body"] = parsed
+		} else {
+			request["body"] = body
+		}
+	}
+	resp, _ := json.MarshalIndent(map[string]interface{}{
+		"code":    0,
+		"message": "试运行: 请求未发送到 Sheikah, 视为成功",
+		"dryRun":  true,
+		"request": request,
+	}, "", "  ")
+	return string(resp)
+}
@@ -0,0 +1,48 @@
+package secops
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSheikahDryRun(t *testing.T) {
+	var methods []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		w.Write([]byte(`{"code":0,"data":[]}`))
+	}))
+	defer srv.Close()
+
+	tool := NewSecOpsSheikahAPITool(map[string]APIConfig{
+		"confirm": {Method: "POST", Path: "/risk/confirm", Body: `{"host": {{str .host}}}`},
+		"list":    {Method: "GET", Path: "/risk/list"},
+	}, srv.URL, "")
+	ctx := WithDryRun(context.Background())
+
+	res := tool.Execute(ctx, map[string]interface{}{"api": "confirm", "params": map[string]interface{}{"host": "a.example.com"}})
+	if res.IsError || !strings.Contains(res.ForLLM, `"dryRun": true`) || !strings.Contains(res.ForLLM, `"host": "a.example.com"`) {
+		t.Errorf("dry run result = %+v", res)
+	}
+	if len(methods) != 0 {
+		t.Fatalf("mutating call reached Sheikah in dry run: %v", methods)
+	}
+
+	// 只读调用照常发送, 模板错误照常返回
+	if res := tool.Execute(ctx, map[string]interface{}{"api": "list"}); res.IsError || len(methods) != 1 {
+		t.Errorf("read-only call in dry run = %+v, requests %v", res, methods)
+	}
+	if res := tool.Execute(ctx, map[string]interface{}{"api": "unknown"}); !res.IsError {
+		t.Error("unknown api must still fail in dry run")
+	}
+
+	if DryRunFromContext(context.Background()) {
+		t.Error("plain context must not be a dry run")
+	}
+	tool.Execute(context.Background(), map[string]interface{}{"api": "confirm", "params": map[string]interface{}{"host": "b"}})
+	if len(methods) != 2 || methods[1] != http.MethodPost {
+		t.Errorf("requests = %v, want the POST outside dry run", methods)
+	}
+}
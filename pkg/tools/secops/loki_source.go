@@ -0,0 +1,278 @@
+package secops
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultLokiQueries Loki 的内置查询; 参数写作 $name, 不加引号
+var defaultLokiQueries = map[string]string{
+	"logs_by_ip":    `{job=~".+"} |= $ip`,
+	"logs_by_app":   `{app=$app}`,
+	"errors_by_app": `{app=$app} |~ "(?i)(error|exception|panic)"`,
+
+	// Kubernetes 审计日志, 默认采集为 job="kubernetes-audit", 字段经 json 解析后以下划线展开
+	"k8s_rbac_changes":  `{job="kubernetes-audit"} | json | objectRef_resource=~"(cluster)?role(binding)?s" | verb=~"create|update|patch|delete"`,
+	"k8s_pod_exec":      `{job="kubernetes-audit"} | json | objectRef_resource="pods" | objectRef_subresource=~"exec|attach|portforward"`,
+	"k8s_secret_access": `{job="kubernetes-audit"} | json | objectRef_resource="secrets" | verb=~"get|list|watch" | user_username!~"system:.*"`,
+	"k8s_user_activity": `{job="kubernetes-audit"} | json | user_username=$user`,
+}
+
+const (
+	defaultLokiTimeout = 30 * time.Second
+	defaultLokiLimit   = 100
+	maxLokiLimit       = 5000
+	defaultLokiSince   = time.Hour
+)
+
+// LokiConfig Loki 数据源参数
+type LokiConfig struct {
+	BaseURL            string // 如 http://loki:3100, 经 Grafana 代理时为数据源代理地址
+	Token              string // Bearer 令牌, 优先于用户名密码
+	Username           string
+	Password           string
+	Tenant             string        // 多租户时的 X-Scope-OrgID
+	Timeout            time.Duration // 请求超时, 默认 30s
+	Limit              int           // 默认返回条数, 默认 100, 上限 5000
+	InsecureSkipVerify bool
+}
+
+// LokiSource 通过 query_range 接口执行 LogQL 的数据源
+//
+// 模板中引号外的 $name 替换为 LogQL 字符串字面量。参数 since (如 "6h") 或 start/end
+// (RFC3339 或 Unix 秒) 指定时间范围, 默认最近 1 小时; limit 指定返回条数。
+type LokiSource struct {
+	cfg     LokiConfig
+	queries map[string]string
+	client  *http.Client
+	now     func() time.Time
+}
+
+// NewLokiSource 创建 Loki 数据源; queries 覆盖或补充内置查询
+func NewLokiSource(cfg LokiConfig, queries map[string]string) (*LokiSource, error) {
+	if cfg.BaseURL == "" {
+		return nil, errors.New("loki base url is required")
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultLokiTimeout
+	}
+	if cfg.Limit <= 0 {
+		cfg.Limit = defaultLokiLimit
+	}
+	if cfg.Limit > maxLokiLimit {
+		cfg.Limit = maxLokiLimit
+	}
+
+	merged := make(map[string]string)
+	for id, q := range defaultLokiQueries {
+		merged[id] = q
+	}
+	for id, q := range queries {
+		merged[id] = q
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.InsecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &LokiSource{
+		cfg:     cfg,
+		queries: merged,
+		client:  &http.Client{Transport: transport, Timeout: cfg.Timeout},
+		now:     time.Now,
+	}, nil
+}
+
+// Driver 数据源类型
+func (s *LokiSource) Driver() string {
+	return "loki"
+}
+
+// Queries 可用的查询模板
+func (s *LokiSource) Queries() map[string]string {
+	return s.queries
+}
+
+// Query 执行 LogQL; 日志查询返回 time、labels、line 三列, 按时间倒序; 指标查询返回 time、metric、value
+func (s *LokiSource) Query(ctx context.Context, query string, params map[string]string) ([]string, [][]interface{}, error) {
+	start, end, err := s.timeRange(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	limit := s.cfg.Limit
+	if v := params["limit"]; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return nil, nil, fmt.Errorf("invalid limit %q", v)
+		}
+		limit = min(n, maxLokiLimit)
+	}
+
+	logql, err := expandParams(query, params, func(_, value string) string {
+		return strconv.Quote(value)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	q := url.Values{
+		"query":     {logql},
+		"start":     {strconv.FormatInt(start.UnixNano(), 10)},
+		"end":       {strconv.FormatInt(end.UnixNano(), 10)},
+		"limit":     {strconv.Itoa(limit)},
+		"direction": {"backward"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.cfg.BaseURL+"/loki/api/v1/query_range?"+q.Encode(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if s.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.cfg.Token)
+	} else if s.cfg.Username != "" {
+		req.SetBasicAuth(s.cfg.Username, s.cfg.Password)
+	}
+	if s.cfg.Tenant != "" {
+		req.Header.Set("X-Scope-OrgID", s.cfg.Tenant)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode >= 400 {
+		return nil, nil, fmt.Errorf("loki error %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result struct {
+		Data struct {
+			ResultType string `json:"resultType"`
+			Result     []struct {
+				Stream map[string]string `json:"stream"`
+				Metric map[string]string `json:"metric"`
+				Values [][2]interface{}  `json:"values"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse loki response: %w", err)
+	}
+
+	if result.Data.ResultType == "matrix" {
+		var rows [][]interface{}
+		for _, series := range result.Data.Result {
+			metric := formatLabels(series.Metric)
+			for _, v := range series.Values {
+				ts, _ := v[0].(float64)
+				rows = append(rows, []interface{}{time.Unix(int64(ts), 0).UTC().Format(time.RFC3339), metric, v[1]})
+			}
+		}
+		return []string{"time", "metric", "value"}, rows, nil
+	}
+
+	type entry struct {
+		ts     int64
+		labels string
+		line   interface{}
+	}
+	var entries []entry
+	for _, stream := range result.Data.Result {
+		labels := formatLabels(stream.Stream)
+		for _, v := range stream.Values {
+			raw, _ := v[0].(string)
+			ts, _ := strconv.ParseInt(raw, 10, 64)
+			entries = append(entries, entry{ts: ts, labels: labels, line: v[1]})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].ts > entries[j].ts
+	})
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	rows := make([][]interface{}, 0, len(entries))
+	for _, e := range entries {
+		rows = append(rows, []interface{}{time.Unix(0, e.ts).UTC().Format(time.RFC3339Nano), e.labels, e.line})
+	}
+	return []string{"time", "labels", "line"}, rows, nil
+}
+
+// Close 关闭空闲连接
+func (s *LokiSource) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// timeRange 解析 since 或 start/end, 默认最近 1 小时
+func (s *LokiSource) timeRange(params map[string]string) (time.Time, time.Time, error) {
+	end := s.now()
+	if v := params["end"]; v != "" {
+		t, err := parseLokiTime(v)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid end %q", v)
+		}
+		end = t
+	}
+
+	since := defaultLokiSince
+	if v := params["since"]; v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid since %q", v)
+		}
+		since = d
+	}
+	start := end.Add(-since)
+	if v := params["start"]; v != "" {
+		t, err := parseLokiTime(v)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid start %q", v)
+		}
+		start = t
+	}
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, errors.New("start must be before end")
+	}
+	return start, end, nil
+}
+
+// parseLokiTime 解析 RFC3339 或 Unix 秒
+func parseLokiTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	sec, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(sec, 0), nil
+}
+
+// formatLabels 按名称排序输出 {k="v", ...}
+func formatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+"="+strconv.Quote(labels[k]))
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
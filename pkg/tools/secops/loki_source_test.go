@@ -0,0 +1,64 @@
+package secops
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLokiSourceQuery(t *testing.T) {
+	var got url.Values
+	var tenant string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/loki/api/v1/query_range" {
+			http.NotFound(w, r)
+			return
+		}
+		got = r.URL.Query()
+		tenant = r.Header.Get("X-Scope-OrgID")
+		fmt.Fprint(w, `{"status":"success","data":{"resultType":"streams","result":[
+			{"stream":{"app":"api","pod":"api-1"},"values":[["1700000003000000000","c"],["1700000001000000000","a"]]},
+			{"stream":{"app":"api","pod":"api-2"},"values":[["1700000002000000000","b"]]}
+		]}}`)
+	}))
+	defer srv.Close()
+
+	src, err := NewLokiSource(LokiConfig{BaseURL: srv.URL, Tenant: "soc"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000100, 0)
+	src.now = func() time.Time { return now }
+
+	columns, rows, err := src.Query(context.Background(), src.Queries()["logs_by_app"], map[string]string{
+		"app": `api"} or {app=~".+`, "since": "6h", "limit": "2",
+	})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if q := got.Get("query"); q != `{app="api\"} or {app=~\".+"}` {
+		t.Errorf("query = %s", q)
+	}
+	if got.Get("start") != fmt.Sprint(now.Add(-6*time.Hour).UnixNano()) || got.Get("end") != fmt.Sprint(now.UnixNano()) {
+		t.Errorf("range = %s..%s", got.Get("start"), got.Get("end"))
+	}
+	if got.Get("limit") != "2" || tenant != "soc" {
+		t.Errorf("limit = %s, tenant = %s", got.Get("limit"), tenant)
+	}
+	if strings.Join(columns, ",") != "time,labels,line" {
+		t.Errorf("columns = %v", columns)
+	}
+	// 多个流按时间倒序合并, 截断到 limit
+	if len(rows) != 2 || rows[0][2] != "c" || rows[1][2] != "b" || rows[1][1] != `{app="api", pod="api-2"}` {
+		t.Errorf("rows = %v", rows)
+	}
+
+	if _, _, err := src.Query(context.Background(), `{app=$app}`, map[string]string{"app": "x", "start": "2024-01-02T00:00:00Z", "end": "2024-01-01T00:00:00Z"}); err == nil {
+		t.Error("expected error for start after end")
+	}
+}
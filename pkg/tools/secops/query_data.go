@@ -1,38 +1,56 @@
 package secops
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/sipeed/picoclaw/pkg/tools"
 )
 
-// SecOpsQueryDataTool 从 ClickHouse 查询数据（通过 HTTP API）
+// SecOpsQueryDataTool 从 ClickHouse 查询数据（通过 HTTP API）, 也可通过 source 查询 PostgreSQL/MySQL/Splunk 数据源
 type SecOpsQueryDataTool struct {
 	queries  map[string]string
-	baseURL  string
+	pool     endpointPool // 主地址和只读副本
 	username string
 	password string
 	client   *http.Client
+	sources  map[string]QuerySource
+	routes   map[string]string // 路由到其他 ClickHouse 集群的模板, sql_id 到数据源名称
+	breaker  *CircuitBreaker
 }
 
 // NewSecOpsQueryDataTool 创建查询数据工具
 func NewSecOpsQueryDataTool(queries map[string]string, baseURL, username, password string) *SecOpsQueryDataTool {
 	return &SecOpsQueryDataTool{
 		queries:  queries,
-		baseURL:  baseURL,
+		pool:     endpointPool{endpoints: []*chEndpoint{{baseURL: baseURL}}},
 		username: username,
 		password: password,
 		client:   &http.Client{},
 	}
 }
 
+// AddSource 注册数据源, 调用时以 source 参数选择
+func (t *SecOpsQueryDataTool) AddSource(name string, src QuerySource) {
+	if t.sources == nil {
+		t.sources = make(map[string]QuerySource)
+	}
+	t.sources[name] = src
+}
+
+// SetBreaker 设置 ClickHouse 熔断器, 熔断中的查询直接返回 ErrCircuitOpen
+func (t *SecOpsQueryDataTool) SetBreaker(b *CircuitBreaker) {
+	t.breaker = b
+}
+
 // Name 工具名称
 func (t *SecOpsQueryDataTool) Name() string {
 	return "query_data"
@@ -45,12 +63,46 @@ func (t *SecOpsQueryDataTool) Description() string {
 	for id := range t.queries {
 		ids = append(ids, id)
 	}
-	return fmt.Sprintf(`从 ClickHouse 查询数据。使用方法:
+	for id := range t.routes {
+		if _, ok := t.queries[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	desc := fmt.Sprintf(`从 ClickHouse 查询数据。使用方法:
 - sql_id: SQL 模板 ID (如: %s)
-- params: 参数替换, 格式为 key1=value1,key2=value2
+- params: 模板参数, 格式为 key1=value1,key2=value2, 由 ClickHouse 服务端绑定, 值中无需转义
 - raw_sql: 可选, 直接执行的 SQL (优先级高于 sql_id)
+- format: 可选, 结果格式: markdown (默认, 表格)、csv、json (每行为列名到值的映射, 便于逐字段分析)、compact (制表符分隔, 最省 token)
+- max_rows: 可选, 最多输出的行数, 默认 10, 最大 500
 
 可用 SQL 模板: %s`, strings.Join(ids, ", "), strings.Join(ids, ", "))
+
+	if len(t.sources) > 0 {
+		names := make([]string, 0, len(t.sources))
+		for name := range t.sources {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		desc += "\n\n其他数据源 (通过 source 指定, 参数自动绑定或转义, 模板中无需加引号):"
+		for _, name := range names {
+			src := t.sources[name]
+			srcIDs := make([]string, 0, len(src.Queries()))
+			for id := range src.Queries() {
+				srcIDs = append(srcIDs, id)
+			}
+			sort.Strings(srcIDs)
+			desc += fmt.Sprintf("\n- %s (%s): %s", name, src.Driver(), strings.Join(srcIDs, ", "))
+		}
+	}
+	if len(t.routes) > 0 {
+		routed := make([]string, 0, len(t.routes))
+		for id, name := range t.routes {
+			routed = append(routed, fmt.Sprintf("%s → %s", id, name))
+		}
+		sort.Strings(routed)
+		desc += "\n\n以下模板自动在对应集群执行, 无需指定 source: " + strings.Join(routed, ", ")
+	}
+	return desc
 }
 
 // Parameters 参数定义
@@ -64,12 +116,25 @@ func (t *SecOpsQueryDataTool) Parameters() map[string]interface{} {
 			},
 			"params": map[string]interface{}{
 				"type":        "string",
-				"description": "参数替换, 格式: key1=value1,key2=value2",
+				"description": "模板参数, 格式: key1=value1,key2=value2",
 			},
 			"raw_sql": map[string]interface{}{
 				"type":        "string",
 				"description": "可选, 直接执行的 SQL",
 			},
+			"source": map[string]interface{}{
+				"type":        "string",
+				"description": "可选, 数据源名称, 默认 ClickHouse",
+			},
+			"format": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{FormatMarkdown, FormatCSV, FormatJSON, FormatCompact},
+				"description": "可选, 结果格式, 默认 markdown",
+			},
+			"max_rows": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("可选, 最多输出的行数, 默认 %d, 最大 %d", defaultMaxRows, maxResultRows),
+			},
 		},
 	}
 }
@@ -79,8 +144,21 @@ func (t *SecOpsQueryDataTool) Execute(ctx context.Context, args map[string]inter
 	sqlID, _ := args["sql_id"].(string)
 	paramsStr, _ := args["params"].(string)
 	rawSQL, _ := args["raw_sql"].(string)
+	format, err := parseResultFormat(args)
+	if err != nil {
+		return tools.ErrorResult(err.Error())
+	}
+
+	source, _ := args["source"].(string)
+	if routed, ok := t.routes[sqlID]; ok && rawSQL == "" && (source == "" || source == "clickhouse") {
+		source = routed
+	}
+	if source != "" && source != "clickhouse" {
+		return t.executeSource(ctx, source, sqlID, paramsStr, rawSQL, format)
+	}
 
 	var sql string
+	var params map[string]string
 
 	if rawSQL != "" {
 		sql = rawSQL
@@ -89,95 +167,123 @@ func (t *SecOpsQueryDataTool) Execute(ctx context.Context, args map[string]inter
 		if !ok {
 			return tools.ErrorResult(fmt.Sprintf("sql_id not found: %s. Available: %v", sqlID, t.queries))
 		}
-		sql = t.replaceParams(template, paramsStr)
+		params = parseQueryParams(paramsStr)
+		if sql, err = replaceParams(template, params); err != nil {
+			return tools.ErrorResult(err.Error())
+		}
 	} else {
 		return tools.ErrorResult("sql_id or raw_sql is required")
 	}
 
-	// 构建 HTTP 请求
-	form := url.Values{}
-	form.Set("query", sql)
-	if t.username != "" {
-		form.Set("user", t.username)
-	}
-	if t.password != "" {
-		form.Set("password", t.password)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", t.baseURL, strings.NewReader(form.Encode()))
+	columns, types, rows, err := t.query(ctx, sql, params)
 	if err != nil {
-		return tools.ErrorResult(fmt.Sprintf("failed to create request: %v", err))
+		var raw rawResponseError
+		if errors.As(err, &raw) {
+			// 非 JSON 响应 (如 SHOW CREATE 的文本输出), 直接返回原始内容
+			return tools.UserResult(string(raw))
+		}
+		return tools.ErrorResult(err.Error())
 	}
+	return tools.UserResult(formatResult(columns, types, rows, format))
+}
 
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-
-	resp, err := t.client.Do(req)
-	if err != nil {
-		return tools.ErrorResult(fmt.Sprintf("request failed: %v", err))
+// executeSource 在 source 指定的数据源上执行查询
+func (t *SecOpsQueryDataTool) executeSource(ctx context.Context, source, sqlID, paramsStr, rawSQL string, format resultFormat) *tools.ToolResult {
+	src, ok := t.sources[source]
+	if !ok {
+		names := make([]string, 0, len(t.sources))
+		for name := range t.sources {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return tools.ErrorResult(fmt.Sprintf("unknown source: %s. Available: clickhouse, %s", source, strings.Join(names, ", ")))
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return tools.ErrorResult(fmt.Sprintf("failed to read response: %v", err))
+	query := rawSQL
+	if query == "" {
+		if sqlID == "" {
+			return tools.ErrorResult("sql_id or raw_sql is required")
+		}
+		q, ok := t.templateFor(source, sqlID)
+		if !ok {
+			return tools.ErrorResult(fmt.Sprintf("sql_id not found in %s: %s", source, sqlID))
+		}
+		query = q
 	}
 
-	if resp.StatusCode >= 400 {
-		return tools.ErrorResult(fmt.Sprintf("ClickHouse error %d: %s", resp.StatusCode, string(body)))
+	columns, rows, err := src.Query(ctx, query, parseQueryParams(paramsStr))
+	if err != nil {
+		return tools.ErrorResult(fmt.Sprintf("%s query failed: %v", source, err))
 	}
+	return tools.UserResult(formatResult(columns, nil, rows, format))
+}
 
-	// 解析 JSON 响应
-	var result struct {
-		Data [][]interface{} `json:"data"`
+// formatTable 将查询结果渲染为 Markdown 表格, 最多输出前 maxRows 条; types 非空时表头附带列类型
+func formatTable(columns, types []string, rows [][]interface{}, maxRows int) string {
+	if len(rows) == 0 {
+		return "查询结果为空"
 	}
 
-	if err := json.Unmarshal(body, &result); err != nil {
-		// 如果不是 JSON，直接返回原始响应
-		return tools.UserResult(string(body))
-	}
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("共 %d 条结果:\n\n", len(rows)))
 
-	// 格式化输出
-	if len(result.Data) == 0 {
-		return tools.UserResult("查询结果为空")
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = escapeCell(c)
+		if i < len(types) && types[i] != "" {
+			header[i] += " (" + escapeCell(types[i]) + ")"
+		}
 	}
+	output.WriteString("| " + strings.Join(header, " | ") + " |\n")
+	output.WriteString("|" + strings.Repeat(" --- |", len(columns)) + "\n")
 
-	var output strings.Builder
-	// TODO: 获取列名并输出表头
-	output.WriteString(fmt.Sprintf("共 %d 条结果:\n\n", len(result.Data)))
-
-	// 输出前10条
-	maxRows := 10
-	if len(result.Data) < maxRows {
-		maxRows = len(result.Data)
+	if len(rows) < maxRows {
+		maxRows = len(rows)
 	}
 
 	for i := 0; i < maxRows; i++ {
-		var rowStrs []string
-		for _, v := range result.Data[i] {
-			if v == nil {
-				rowStrs = append(rowStrs, "NULL")
-			} else {
-				rowStrs = append(rowStrs, fmt.Sprintf("%v", v))
+		cells := make([]string, len(columns))
+		for j := range cells {
+			if j >= len(rows[i]) || rows[i][j] == nil {
+				cells[j] = "NULL"
+				continue
 			}
+			cells[j] = escapeCell(formatValue(rows[i][j]))
 		}
-		output.WriteString(strings.Join(rowStrs, "\t"))
-		output.WriteString("\n")
+		output.WriteString("| " + strings.Join(cells, " | ") + " |\n")
 	}
 
-	if len(result.Data) > maxRows {
-		output.WriteString(fmt.Sprintf("\n... 还有 %d 条结果", len(result.Data)-maxRows))
+	if len(rows) > maxRows {
+		output.WriteString(fmt.Sprintf("\n... 还有 %d 条结果", len(rows)-maxRows))
 	}
 
-	return tools.UserResult(output.String())
+	return output.String()
 }
 
-// replaceParams 替换 SQL 参数
-func (t *SecOpsQueryDataTool) replaceParams(template, paramsStr string) string {
-	if paramsStr == "" {
-		return template
+// formatValue 将单元格值转为文本, 数组和对象输出为 JSON
+func formatValue(v interface{}) string {
+	switch v.(type) {
+	case []interface{}, map[string]interface{}:
+		if b, err := json.Marshal(v); err == nil {
+			return string(b)
+		}
 	}
+	return fmt.Sprintf("%v", v)
+}
 
+// escapeCell 转义单元格中的竖线和换行, 避免破坏表格
+func escapeCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	s = strings.ReplaceAll(s, "\r\n", "<br>")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+// parseQueryParams 解析 key1=value1,key2=value2 格式的参数
+func parseQueryParams(paramsStr string) map[string]string {
 	params := make(map[string]string)
+	if paramsStr == "" {
+		return params
+	}
 	pairs := strings.Split(paramsStr, ",")
 	for _, pair := range pairs {
 		kv := strings.SplitN(pair, "=", 2)
@@ -185,27 +291,226 @@ func (t *SecOpsQueryDataTool) replaceParams(template, paramsStr string) string {
 			params[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
 		}
 	}
+	return params
+}
+
+// replaceParams 处理模板中的旧式占位符, ClickHouse 原生参数 {name:Type} 原样保留, 由服务端绑定
+//
+// 单引号内的 $name、{{name}}、{{.name}} 按字符串字面量转义; 引号外以及双引号、反引号标识符内的只接受
+// 数字或标识符, 否则报错, 避免参数值改变 SQL 结构。注释 (--、#、/* */) 内的内容原样保留, 其中的引号
+// 不影响后续的引号状态。
+func replaceParams(template string, params map[string]string) (string, error) {
+	var out strings.Builder
+	var quote byte // 当前所在引号: ', ", ` 或 0
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		if quote != 0 {
+			if c == '\\' && i+1 < len(template) {
+				out.WriteString(template[i : i+2])
+				i++
+				continue
+			}
+			if c == quote {
+				quote = 0
+				out.WriteByte(c)
+				continue
+			}
+		} else {
+			switch {
+			case c == '\'' || c == '"' || c == '`':
+				quote = c
+				out.WriteByte(c)
+				continue
+			case strings.HasPrefix(template[i:], "--") || c == '#':
+				n := sqlLineComment(template[i:])
+				out.WriteString(template[i : i+n])
+				i += n - 1
+				continue
+			case strings.HasPrefix(template[i:], "/*"):
+				n := sqlBlockComment(template[i:])
+				if n < 0 {
+					return "", fmt.Errorf("unterminated comment in query")
+				}
+				out.WriteString(template[i : i+n])
+				i += n - 1
+				continue
+			}
+		}
 
-	result := template
-	for k, v := range params {
-		result = strings.ReplaceAll(result, "{{."+k+"}}", v)
-		result = strings.ReplaceAll(result, "{{"+k+"}}", v)
-		result = strings.ReplaceAll(result, "$"+k, v)
+		name, n := templateParam(template[i:])
+		if n == 0 {
+			out.WriteByte(c)
+			continue
+		}
+		value, ok := params[name]
+		if !ok {
+			return "", fmt.Errorf("missing parameter: %s", name)
+		}
+		if quote == '\'' {
+			out.WriteString(escapeClickHouseString(value))
+		} else {
+			if !safeLiteral.MatchString(value) {
+				return "", fmt.Errorf("parameter %s must be a number or identifier outside quotes: %q", name, value)
+			}
+			out.WriteString(value)
+		}
+		i += n - 1
 	}
+	if quote != 0 {
+		return "", fmt.Errorf("unterminated quote in query")
+	}
+	return out.String(), nil
+}
 
-	return result
+// sqlLineComment s 以 -- 或 # 开头时, 返回注释到行尾 (含换行) 的长度
+func sqlLineComment(s string) int {
+	if end := strings.IndexByte(s, '\n'); end >= 0 {
+		return end + 1
+	}
+	return len(s)
 }
 
-// Close 关闭客户端
+// sqlBlockComment s 以 /* 开头时, 返回注释的长度 (ClickHouse 允许嵌套); 未闭合返回 -1
+func sqlBlockComment(s string) int {
+	depth := 0
+	for i := 0; i+1 < len(s); i++ {
+		switch s[i : i+2] {
+		case "/*":
+			depth++
+			i++
+		case "*/":
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+// safeLiteral 引号外允许直接替换的参数值
+var safeLiteral = regexp.MustCompile(`^[A-Za-z0-9_.]+$`)
+
+// templateParam 识别 s 开头的 $name、{{name}} 或 {{.name}}, 返回参数名和占用的长度
+func templateParam(s string) (string, int) {
+	if len(s) > 1 && s[0] == '$' && isParamStart(s[1]) {
+		j := 1
+		for j < len(s) && isParamChar(s[j]) {
+			j++
+		}
+		return s[1:j], j
+	}
+	if strings.HasPrefix(s, "{{") {
+		end := strings.Index(s, "}}")
+		if end < 0 {
+			return "", 0
+		}
+		name := strings.TrimPrefix(strings.TrimSpace(s[2:end]), ".")
+		if name == "" || !isParamStart(name[0]) {
+			return "", 0
+		}
+		for k := 1; k < len(name); k++ {
+			if !isParamChar(name[k]) {
+				return "", 0
+			}
+		}
+		return name, end + 2
+	}
+	return "", 0
+}
+
+// escapeClickHouseString 转义单引号字符串字面量中的反斜杠和单引号
+func escapeClickHouseString(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	return strings.ReplaceAll(v, `'`, `\'`)
+}
+
+// Close 关闭客户端和 SQL 数据源
 func (t *SecOpsQueryDataTool) Close() error {
 	t.client = nil
+	for _, src := range t.sources {
+		src.Close()
+	}
 	return nil
 }
 
+// SetTransport 替换 HTTP 传输层, 用于演示模式和测试
+func (t *SecOpsQueryDataTool) SetTransport(rt http.RoundTripper) {
+	t.client = &http.Client{Transport: rt}
+}
+
+// Transport 当前的 HTTP 传输层, 供故障注入等在其外层包装
+func (t *SecOpsQueryDataTool) Transport() http.RoundTripper {
+	if t.client == nil || t.client.Transport == nil {
+		return http.DefaultTransport
+	}
+	return t.client.Transport
+}
+
 // Query 执行原始 SQL（供其他工具使用）
 func (t *SecOpsQueryDataTool) Query(ctx context.Context, sql string) ([][]interface{}, error) {
+	_, _, rows, err := t.query(ctx, sql, nil)
+	return rows, err
+}
+
+// QueryTemplate 按 sql_id 执行 SQL 模板, 返回列名和行（供剧本等非对话调用方使用）; source 为空时查询 ClickHouse,
+// 已路由的模板在对应集群执行
+func (t *SecOpsQueryDataTool) QueryTemplate(ctx context.Context, source, sqlID string, params map[string]string) ([]string, [][]interface{}, error) {
+	if routed, ok := t.routes[sqlID]; ok && (source == "" || source == "clickhouse") {
+		source = routed
+	}
+	if source != "" && source != "clickhouse" {
+		src, ok := t.sources[source]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown source: %s", source)
+		}
+		query, ok := t.templateFor(source, sqlID)
+		if !ok {
+			return nil, nil, fmt.Errorf("sql_id not found in %s: %s", source, sqlID)
+		}
+		return src.Query(ctx, query, params)
+	}
+
+	template, ok := t.queries[sqlID]
+	if !ok {
+		return nil, nil, fmt.Errorf("sql_id not found: %s", sqlID)
+	}
+	sql, err := replaceParams(template, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	columns, _, rows, err := t.query(ctx, sql, params)
+	return columns, rows, err
+}
+
+// rawResponseError ClickHouse 返回了非 JSON 内容, 值为原始响应
+type rawResponseError string
+
+func (e rawResponseError) Error() string {
+	return "unexpected non-JSON response from ClickHouse"
+}
+
+// formatClause 匹配查询末尾已有的 FORMAT 子句
+var formatClause = regexp.MustCompile(`(?i)\bFORMAT\s+\w+\s*$`)
+
+// withJSONFormat 未指定输出格式时追加 FORMAT JSONCompact, 以便读取列名和类型
+func withJSONFormat(sql string) string {
+	sql = strings.TrimRight(strings.TrimSpace(sql), ";")
+	if formatClause.MatchString(sql) {
+		return sql
+	}
+	return sql + " FORMAT JSONCompact"
+}
+
+// query 以 JSONCompact 格式执行查询, 返回列名、列类型和行; 也兼容查询自带的 FORMAT JSON
+//
+// params 以 param_<name> 传给 ClickHouse, 绑定到查询中的 {name:Type} 参数。
+// 网络错误或 502/503/504 时将该地址标记为不可用, 只读查询切换到下一个地址重试; 所有地址都失败才计入熔断器。
+// ClickHouse 对查询本身的错误 (如语法错误) 也返回 500, 其他 5xx 直接返回错误, 不切换地址。
+func (t *SecOpsQueryDataTool) query(ctx context.Context, sql string, params map[string]string) ([]string, []string, [][]interface{}, error) {
 	form := url.Values{}
-	form.Set("query", sql)
+	form.Set("query", withJSONFormat(sql))
 	if t.username != "" {
 		form.Set("user", t.username)
 	}
@@ -213,35 +518,107 @@ func (t *SecOpsQueryDataTool) Query(ctx context.Context, sql string) ([][]interf
 		form.Set("password", t.password)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", t.baseURL, bytes.NewBufferString(form.Encode()))
+	if t.breaker != nil && !t.breaker.Allow() {
+		return nil, nil, nil, ErrCircuitOpen
+	}
+
+	var status int
+	var body []byte
+	var err error
+	for _, ep := range t.pool.candidates(!isReadQuery(sql)) {
+		status, body, err = t.post(ctx, ep.baseURL, form, params)
+		if err == nil && !failoverStatus(status) {
+			t.pool.markUp(ep)
+			break
+		}
+		if err == nil {
+			err = fmt.Errorf("ClickHouse error %d: %s", status, string(body))
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		t.pool.markDown(ep, err)
+	}
 	if err != nil {
-		return nil, err
+		t.recordFailure(ctx, err)
+		return nil, nil, nil, err
+	}
+	if t.breaker != nil {
+		t.breaker.Success()
+	}
+	if status >= 400 {
+		return nil, nil, nil, fmt.Errorf("ClickHouse error %d: %s", status, string(body))
+	}
+
+	var result struct {
+		Meta []struct {
+			Name string `json:"name"`
+			Type string `json:"type"`
+		} `json:"meta"`
+		Data []json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, nil, nil, rawResponseError(body)
+	}
+
+	columns := make([]string, len(result.Meta))
+	types := make([]string, len(result.Meta))
+	for i, m := range result.Meta {
+		columns[i], types[i] = m.Name, m.Type
+	}
+
+	rows := make([][]interface{}, 0, len(result.Data))
+	for _, raw := range result.Data {
+		var row []interface{}
+		if err := json.Unmarshal(raw, &row); err == nil {
+			rows = append(rows, row)
+			continue
+		}
+		// FORMAT JSON 的行是对象, 按 meta 的列顺序取值
+		var obj map[string]interface{}
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to parse ClickHouse row: %w", err)
+		}
+		row = make([]interface{}, len(columns))
+		for i, c := range columns {
+			row[i] = obj[c]
+		}
+		rows = append(rows, row)
 	}
+	return columns, types, rows, nil
+}
 
+// post 向单个地址发送查询, 返回状态码和响应体
+func (t *SecOpsQueryDataTool) post(ctx context.Context, baseURL string, form url.Values, params map[string]string) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if len(params) > 0 {
+		q := req.URL.Query()
+		for k, v := range params {
+			q.Set("param_"+k, v)
+		}
+		req.URL.RawQuery = q.Encode()
+	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return nil, err
+		return 0, nil, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
-	}
-
-	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("ClickHouse error %d: %s", resp.StatusCode, string(body))
-	}
-
-	var result struct {
-		Data [][]interface{} `json:"data"`
+		return 0, nil, fmt.Errorf("failed to read response: %w", err)
 	}
+	return resp.StatusCode, body, nil
+}
 
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, err
+// recordFailure 向熔断器记录一次失败; 调用方取消的请求不计入
+func (t *SecOpsQueryDataTool) recordFailure(ctx context.Context, err error) {
+	if t.breaker != nil && ctx.Err() == nil {
+		t.breaker.Failure(err)
 	}
-
-	return result.Data, nil
 }
@@ -0,0 +1,133 @@
+package secops
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestQueryDataClickHouseTable(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		got = r.PostForm.Get("query")
+		if strings.HasSuffix(got, "FORMAT JSON") {
+			fmt.Fprint(w, `{"meta":[{"name":"ip","type":"String"},{"name":"cnt","type":"UInt64"}],"data":[{"ip":"1.2.3.4","cnt":"3"}],"rows":1}`)
+			return
+		}
+		fmt.Fprint(w, `{"meta":[{"name":"ip","type":"String"},{"name":"ua","type":"Nullable(String)"}],"data":[["1.2.3.4","a|b\nc"],["5.6.7.8",null]],"rows":2}`)
+	}))
+	defer srv.Close()
+
+	tool := NewSecOpsQueryDataTool(map[string]string{"by_ip": "SELECT ip, ua FROM access WHERE ip = '$ip';"}, srv.URL, "", "")
+	res := tool.Execute(context.Background(), map[string]interface{}{"sql_id": "by_ip", "params": "ip=1.2.3.4"})
+	if res.IsError {
+		t.Fatalf("Execute: %s", res.ForLLM)
+	}
+	if got != "SELECT ip, ua FROM access WHERE ip = '1.2.3.4' FORMAT JSONCompact" {
+		t.Errorf("query = %s", got)
+	}
+	for _, want := range []string{
+		"| ip (String) | ua (Nullable(String)) |",
+		`| 1.2.3.4 | a\|b<br>c |`,
+		"| 5.6.7.8 | NULL |",
+	} {
+		if !strings.Contains(res.ForLLM, want) {
+			t.Errorf("output missing %q:\n%s", want, res.ForLLM)
+		}
+	}
+
+	// 查询自带 FORMAT JSON 时不再追加, 按 meta 列顺序读取对象行
+	res = tool.Execute(context.Background(), map[string]interface{}{"raw_sql": "SELECT ip, count() AS cnt FROM access GROUP BY ip FORMAT JSON"})
+	if !strings.HasSuffix(got, "GROUP BY ip FORMAT JSON") || !strings.Contains(res.ForLLM, "| 1.2.3.4 | 3 |") {
+		t.Errorf("query = %s, output:\n%s", got, res.ForLLM)
+	}
+}
+
+func TestQueryDataParameterBinding(t *testing.T) {
+	var query, param string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		query = r.PostForm.Get("query")
+		param = r.URL.Query().Get("param_ip")
+		fmt.Fprint(w, `{"meta":[],"data":[]}`)
+	}))
+	defer srv.Close()
+
+	payload := `1.2.3.4' OR 1=1 --`
+	tool := NewSecOpsQueryDataTool(map[string]string{
+		"native": "SELECT ip FROM access WHERE ip = {ip:String} LIMIT {batch_size:UInt32}",
+		"legacy": "SELECT ip FROM access WHERE ip = '$ip' LIMIT $batch_size",
+	}, srv.URL, "", "")
+
+	// 原生参数: SQL 不变, 值经 param_ 传给服务端
+	res := tool.Execute(context.Background(), map[string]interface{}{"sql_id": "native", "params": "ip=" + payload + ",batch_size=5"})
+	if res.IsError || query != "SELECT ip FROM access WHERE ip = {ip:String} LIMIT {batch_size:UInt32} FORMAT JSONCompact" || param != payload {
+		t.Errorf("native: query=%s param=%q result=%s", query, param, res.ForLLM)
+	}
+
+	// 旧式模板: 引号内转义, 无法闭合字符串
+	res = tool.Execute(context.Background(), map[string]interface{}{"sql_id": "legacy", "params": "ip=" + payload + ",batch_size=5"})
+	if res.IsError || !strings.Contains(query, `ip = '1.2.3.4\' OR 1=1 --' LIMIT 5`) {
+		t.Errorf("legacy: query=%s result=%s", query, res.ForLLM)
+	}
+
+	// 引号外只接受数字或标识符
+	query = ""
+	res = tool.Execute(context.Background(), map[string]interface{}{"sql_id": "legacy", "params": "ip=1.2.3.4,batch_size=5 UNION SELECT password FROM users"})
+	if !res.IsError || query != "" {
+		t.Errorf("unquoted injection should be rejected: query=%s result=%s", query, res.ForLLM)
+	}
+}
+
+func TestReplaceParams(t *testing.T) {
+	got, err := replaceParams(`SELECT '{{.a}}', '$ab', $a_b FROM t WHERE x = 'it\'s $a'`, map[string]string{"a": `x\y`, "ab": "z", "a_b": "7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `SELECT 'x\\y', 'z', 7 FROM t WHERE x = 'it\'s x\\y'`; got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+	if _, err := replaceParams("SELECT $missing", nil); err == nil {
+		t.Error("expected missing parameter error")
+	}
+}
+
+func TestReplaceParams_CommentsAndIdentifiers(t *testing.T) {
+	params := map[string]string{"a": "x' OR 1=1 --", "n": "7", "col": "src_ip"}
+	tests := []struct {
+		name     string
+		template string
+		want     string // 为空表示应报错
+	}{
+		{"line comment quote", "SELECT 1 -- it's\nWHERE x = $a", ""},
+		{"hash comment quote", "SELECT 1 # it's\nWHERE x = $a", ""},
+		{"block comment quote", "SELECT /* it's */ $a", ""},
+		{"line comment kept", "SELECT $n -- uses $a\n", "SELECT 7 -- uses $a\n"},
+		{"block comment kept", "SELECT /* $a /* nested */ '$a' */ $n", "SELECT /* $a /* nested */ '$a' */ 7"},
+		{"double quote identifier", `SELECT "$col", "it's" FROM t WHERE y = $a`, ""},
+		{"backtick identifier", "SELECT `$col`, `it's` FROM t WHERE y = $a", ""},
+		{"identifier substituted", "SELECT `$col`, \"{{col}}\" FROM t LIMIT $n", "SELECT `src_ip`, \"src_ip\" FROM t LIMIT 7"},
+		{"identifier rejects unsafe", `SELECT "$a" FROM t`, ""},
+		{"string after identifier", `SELECT "it's" FROM t WHERE x = '$a'`, `SELECT "it's" FROM t WHERE x = 'x\' OR 1=1 --'`},
+		{"unterminated block comment", "SELECT 1 /* $a", ""},
+		{"unterminated identifier", "SELECT `col", ""},
+	}
+	for _, tt := range tests {
+		got, err := replaceParams(tt.template, params)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%s: expected error, got %s", tt.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		} else if got != tt.want {
+			t.Errorf("%s:\ngot  %s\nwant %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package secops
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 查询结果的输出格式
+const (
+	FormatMarkdown = "markdown" // Markdown 表格, 默认
+	FormatCSV      = "csv"      // CSV, 便于按列处理
+	FormatJSON     = "json"     // JSON 对象, 每行为列名到值的映射
+	FormatCompact  = "compact"  // 制表符分隔, 占用 token 最少
+)
+
+const (
+	// defaultMaxRows 未指定 max_rows 时输出的行数
+	defaultMaxRows = 10
+	// maxResultRows max_rows 的上限, 避免单次结果撑满上下文
+	maxResultRows = 500
+)
+
+// resultFormat 查询结果的输出方式
+type resultFormat struct {
+	format  string
+	maxRows int
+}
+
+// parseResultFormat 解析 format 和 max_rows 参数
+func parseResultFormat(args map[string]interface{}) (resultFormat, error) {
+	f := resultFormat{format: FormatMarkdown, maxRows: defaultMaxRows}
+	if v, _ := args["format"].(string); v != "" {
+		switch v = strings.ToLower(strings.TrimSpace(v)); v {
+		case FormatMarkdown, FormatCSV, FormatJSON, FormatCompact:
+			f.format = v
+		default:
+			return f, fmt.Errorf("invalid format: %q (markdown, csv, json, compact)", v)
+		}
+	}
+
+	var n int
+	switch v := args["max_rows"].(type) {
+	case nil:
+		return f, nil
+	case float64:
+		n = int(v)
+		if float64(n) != v {
+			return f, fmt.Errorf("max_rows must be an integer")
+		}
+	case int:
+		n = v
+	case string:
+		var err error
+		if n, err = strconv.Atoi(strings.TrimSpace(v)); err != nil {
+			return f, fmt.Errorf("max_rows must be an integer")
+		}
+	default:
+		return f, fmt.Errorf("max_rows must be an integer")
+	}
+	if n < 1 || n > maxResultRows {
+		return f, fmt.Errorf("max_rows must be between 1 and %d", maxResultRows)
+	}
+	f.maxRows = n
+	return f, nil
+}
+
+// formatResult 按输出格式渲染查询结果, 最多输出 maxRows 行; types 非空时附带列类型
+func formatResult(columns, types []string, rows [][]interface{}, f resultFormat) string {
+	if len(columns) == 0 && len(rows) > 0 {
+		for i := range rows[0] {
+			columns = append(columns, fmt.Sprintf("col%d", i+1))
+		}
+	}
+	shown := rows
+	if len(shown) > f.maxRows {
+		shown = shown[:f.maxRows]
+	}
+
+	switch f.format {
+	case FormatJSON:
+		return formatJSON(columns, types, rows, shown)
+	case FormatCSV:
+		if len(rows) == 0 {
+			return "查询结果为空"
+		}
+		return formatCSV(columns, rows, shown)
+	case FormatCompact:
+		if len(rows) == 0 {
+			return "查询结果为空"
+		}
+		return formatCompact(columns, rows, shown)
+	default:
+		return formatTable(columns, types, rows, f.maxRows)
+	}
+}
+
+// formatJSON {"total", "columns", "types", "rows", "truncated"}, 输出为单个 JSON 对象便于解析
+func formatJSON(columns, types []string, rows, shown [][]interface{}) string {
+	items := make([]map[string]interface{}, 0, len(shown))
+	for _, row := range shown {
+		item := make(map[string]interface{}, len(columns))
+		for j, c := range columns {
+			if j < len(row) {
+				item[c] = row[j]
+			} else {
+				item[c] = nil
+			}
+		}
+		items = append(items, item)
+	}
+	result := map[string]interface{}{
+		"total":     len(rows),
+		"columns":   columns,
+		"rows":      items,
+		"truncated": len(rows) > len(shown),
+	}
+	if len(types) > 0 {
+		result["types"] = types
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Sprintf("failed to encode result: %v", err)
+	}
+	return string(data)
+}
+
+// formatCSV 首行为结果总数, 之后为带表头的 CSV; NULL 输出为空
+func formatCSV(columns []string, rows, shown [][]interface{}) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "共 %d 条结果:\n\n", len(rows))
+	w := csv.NewWriter(&sb)
+	w.Write(columns)
+	for _, row := range shown {
+		record := make([]string, len(columns))
+		for j := range record {
+			if j < len(row) && row[j] != nil {
+				record[j] = formatValue(row[j])
+			}
+		}
+		w.Write(record)
+	}
+	w.Flush()
+	if len(rows) > len(shown) {
+		fmt.Fprintf(&sb, "\n... 还有 %d 条结果", len(rows)-len(shown))
+	}
+	return sb.String()
+}
+
+// formatCompact 制表符分隔, 单元格中的制表符和换行替换为空格
+func formatCompact(columns []string, rows, shown [][]interface{}) string {
+	clean := strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ")
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "共 %d 条结果\n", len(rows))
+	sb.WriteString(strings.Join(columns, "\t") + "\n")
+	for _, row := range shown {
+		cells := make([]string, len(columns))
+		for j := range cells {
+			if j >= len(row) || row[j] == nil {
+				cells[j] = "NULL"
+				continue
+			}
+			cells[j] = clean.Replace(formatValue(row[j]))
+		}
+		sb.WriteString(strings.Join(cells, "\t") + "\n")
+	}
+	if len(rows) > len(shown) {
+		fmt.Fprintf(&sb, "... 还有 %d 条结果", len(rows)-len(shown))
+	}
+	return sb.String()
+}
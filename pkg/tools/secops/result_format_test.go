@@ -0,0 +1,63 @@
+package secops
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestFormatResult(t *testing.T) {
+	columns := []string{"ip", "ua"}
+	types := []string{"String", "Nullable(String)"}
+	rows := [][]interface{}{{"1.2.3.4", "a,b\tc"}, {"5.6.7.8", nil}, {"9.9.9.9", "curl"}}
+
+	f, err := parseResultFormat(map[string]interface{}{"format": "json", "max_rows": float64(2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result struct {
+		Total     int                      `json:"total"`
+		Columns   []string                 `json:"columns"`
+		Types     []string                 `json:"types"`
+		Rows      []map[string]interface{} `json:"rows"`
+		Truncated bool                     `json:"truncated"`
+	}
+	if err := json.Unmarshal([]byte(formatResult(columns, types, rows, f)), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 3 || !result.Truncated || len(result.Rows) != 2 || result.Rows[0]["ua"] != "a,b\tc" || result.Rows[1]["ua"] != nil || result.Types[1] != "Nullable(String)" {
+		t.Errorf("json = %+v", result)
+	}
+
+	f, _ = parseResultFormat(map[string]interface{}{"format": "csv"})
+	out := formatResult(columns, nil, rows, f)
+	records, err := csv.NewReader(strings.NewReader(strings.SplitN(out, "\n\n", 2)[1])).ReadAll()
+	if err != nil || len(records) != 4 || records[1][1] != "a,b\tc" || records[2][1] != "" {
+		t.Errorf("csv = %q, %v", out, err)
+	}
+
+	f, _ = parseResultFormat(map[string]interface{}{"format": "compact", "max_rows": "1"})
+	out = formatResult(columns, nil, rows, f)
+	if !strings.Contains(out, "ip\tua\n1.2.3.4\ta,b c\n") || !strings.Contains(out, "还有 2 条结果") {
+		t.Errorf("compact = %q", out)
+	}
+
+	// 默认 Markdown 表格, 前 10 行
+	f, _ = parseResultFormat(map[string]interface{}{})
+	if out := formatResult(columns, types, rows, f); !strings.Contains(out, "| 5.6.7.8 | NULL |") {
+		t.Errorf("markdown = %q", out)
+	}
+
+	for _, args := range []map[string]interface{}{
+		{"format": "xml"},
+		{"max_rows": float64(0)},
+		{"max_rows": float64(maxResultRows + 1)},
+		{"max_rows": 2.5},
+		{"max_rows": "many"},
+	} {
+		if _, err := parseResultFormat(args); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
@@ -4,10 +4,15 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
 	"strings"
+	"text/template"
 
 	"github.com/sipeed/picoclaw/pkg/tools"
 )
@@ -15,26 +20,63 @@ import (
 // SheikahAPITool 调用内部 API
 type SecOpsSheikahAPITool struct {
 	apis   map[string]APIConfig
+	bodies  map[string]*template.Template // 已解析的请求体模板
+	invalid map[string]error              // 请求体模板解析失败的 API
 	baseURL string
 	apiKey  string
 	client  *http.Client
 }
 
 // APIConfig API 端点配置
+//
+// Body 为 Go text/template, 参数通过 .name 引用, 可用 json/esc/default/fromJSON/split 函数及
+// if/range 表达可选字段和数组; 不含 {{ 的旧格式中 $name 按 JSON 字符串转义后替换。
+// Path 中的 $name 按 URL 转义后替换。
 type APIConfig struct {
 	Method string `json:"method"`
 	Path   string `json:"path"`
 	Body   string `json:"body,omitempty"`
 }
 
-// NewSecOpsSheikahAPITool 创建 API 调用工具
+// NewSecOpsSheikahAPITool 创建 API 调用工具, 请求体模板在此解析, 错误通过 Validate 获取
 func NewSecOpsSheikahAPITool(apis map[string]APIConfig, baseURL, apiKey string) *SecOpsSheikahAPITool {
-	return &SecOpsSheikahAPITool{
+	t := &SecOpsSheikahAPITool{
 		apis:    apis,
+		bodies:  make(map[string]*template.Template),
+		invalid: make(map[string]error),
 		baseURL: baseURL,
 		apiKey:  apiKey,
 		client:  &http.Client{},
 	}
+	for id, api := range apis {
+		tmpl, err := parseBody(id, api.Body)
+		if err != nil {
+			t.invalid[id] = err
+			continue
+		}
+		t.bodies[id] = tmpl
+	}
+	return t
+}
+
+// HasAPI 是否配置了该 API
+func (t *SecOpsSheikahAPITool) HasAPI(apiID string) bool {
+	_, ok := t.apis[apiID]
+	return ok
+}
+
+// Validate 返回请求体模板的解析错误
+func (t *SecOpsSheikahAPITool) Validate() error {
+	ids := make([]string, 0, len(t.invalid))
+	for id := range t.invalid {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	errs := make([]error, 0, len(ids))
+	for _, id := range ids {
+		errs = append(errs, t.invalid[id])
+	}
+	return errors.Join(errs...)
 }
 
 // Name 工具名称
@@ -50,10 +92,12 @@ func (t *SecOpsSheikahAPITool) Description() string {
 	}
 	return fmt.Sprintf(`调用内部 Sheikah API 进行处置操作。使用方法:
 - api: API 标识 (如 %s)
-- params: 参数替换, 格式为 key1=value1,key2=value2
+- params: 参数对象, 如 {"host": "a.example.com", "note": "xxx"}; 值可以是字符串、数字或数组 (批量), 代入请求体时按 JSON 转义,
+  值中含逗号、等号也无需处理。旧的 key1=value1,key2=value2 字符串格式仍然可用
 
 示例:
-sheikah_api --api confirm_risk --params content=xxx,host=xxx,risk=xxx
+sheikah_api --api confirm_risk --params {"content": "xxx", "host": "a", "risk": "xxx", "note": "见 https://wiki/x?a=1,b=2"}
+sheikah_api --api confirm_risk --params {"items": [{"content": "xxx", "host": "a", "risk": "xxx"}, {"content": "yyy", "host": "b", "risk": "xxx"}]}
 sheikah_api --api create_proposal --params type=risk,data=xxx`, strings.Join(apiList, ", "))
 }
 
@@ -67,41 +111,195 @@ func (t *SecOpsSheikahAPITool) Parameters() map[string]interface{} {
 				"description": "API 标识",
 			},
 			"params": map[string]interface{}{
-				"type":        "string",
-				"description": "参数替换, 格式: key1=value1,key2=value2",
+				"type":                 "object",
+				"description":          "参数对象, 键为参数名; 兼容旧的 key1=value1,key2=value2 字符串",
+				"additionalProperties": true,
 			},
 		},
 		"required": []string{"api"},
 	}
 }
 
+// IsMutating 非 GET 的 API 调用会修改 Sheikah 中的数据; 未知 API 按修改处理
+func (t *SecOpsSheikahAPITool) IsMutating(args map[string]interface{}) bool {
+	apiID, _ := args["api"].(string)
+	apiConfig, ok := t.apis[apiID]
+	return !ok || !strings.EqualFold(apiConfig.Method, http.MethodGet)
+}
+
 // Execute 执行 API 调用
 func (t *SecOpsSheikahAPITool) Execute(ctx context.Context, args map[string]interface{}) *tools.ToolResult {
 	apiID, _ := args["api"].(string)
-	paramsStr, _ := args["params"].(string)
-
 	if apiID == "" {
 		return tools.ErrorResult("api is required")
 	}
 
+	var params map[string]interface{}
+	switch v := args["params"].(type) {
+	case map[string]interface{}:
+		params = v
+	case string:
+		var err error
+		if params, err = parseParams(v); err != nil {
+			return tools.ErrorResult(err.Error())
+		}
+	case nil:
+		params = map[string]interface{}{}
+	default:
+		return tools.ErrorResult(fmt.Sprintf("params must be an object, got %T", v))
+	}
+
+	apiConfig, path, body, err := t.build(apiID, params)
+	if err != nil {
+		return tools.ErrorResult(err.Error())
+	}
+
+	if DryRunFromContext(ctx) && t.IsMutating(args) {
+		return tools.UserResult(dryRunResponse(apiID, apiConfig.Method, path, body))
+	}
+
+	respBody, err := t.do(ctx, apiConfig.Method, path, body)
+	if err != nil {
+		return tools.ErrorResult(err.Error())
+	}
+	return tools.UserResult(respBody)
+}
+
+// Call 以参数表调用 API, 供提案执行层使用; 参数值按 JSON 字符串转义, 路径中的参数按 URL 转义
+func (t *SecOpsSheikahAPITool) Call(ctx context.Context, apiID string, params map[string]string) (string, error) {
+	apiConfig, path, body, err := t.build(apiID, stringParams(params))
+	if err != nil {
+		return "", err
+	}
+	return t.do(ctx, apiConfig.Method, path, body)
+}
+
+// RenderedRequest 渲染后的 API 请求, 凭据已脱敏, 用于执行前预览
+type RenderedRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body,omitempty"`
+}
+
+// secretMask 预览中替换凭据的占位符
+const secretMask = "******"
+
+// Render 按 Call 的方式渲染请求但不发送; API Key 和 baseURL 中的账号密码被遮盖
+func (t *SecOpsSheikahAPITool) Render(apiID string, params map[string]string) (*RenderedRequest, error) {
+	apiConfig, path, body, err := t.build(apiID, stringParams(params))
+	if err != nil {
+		return nil, err
+	}
+
+	base := t.baseURL
+	if u, err := url.Parse(base); err == nil {
+		base = u.Redacted()
+	}
+	req := &RenderedRequest{
+		Method:  strings.ToUpper(apiConfig.Method),
+		URL:     base + path,
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Body:    body,
+	}
+	if t.apiKey != "" {
+		req.Headers["sw-api-key"] = secretMask
+		req.URL = strings.ReplaceAll(req.URL, t.apiKey, secretMask)
+		req.Body = strings.ReplaceAll(req.Body, t.apiKey, secretMask)
+	}
+	return req, nil
+}
+
+// build 替换路径中的参数并渲染请求体; 路径中长参数名先替换, 避免 $host 误替换 $hostname 的前缀
+func (t *SecOpsSheikahAPITool) build(apiID string, params map[string]interface{}) (APIConfig, string, string, error) {
 	apiConfig, ok := t.apis[apiID]
 	if !ok {
-		return tools.ErrorResult(fmt.Sprintf("api not found: %s", apiID))
+		return apiConfig, "", "", fmt.Errorf("api not found: %s", apiID)
+	}
+	if err := t.invalid[apiID]; err != nil {
+		return apiConfig, "", "", err
+	}
+
+	values := make(map[string]string, len(params))
+	keys := make([]string, 0, len(params))
+	for k, v := range params {
+		if s, ok := scalarText(v); ok {
+			values[k] = s
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+
+	path := apiConfig.Path
+	for _, k := range keys {
+		path = strings.ReplaceAll(path, "$"+k, url.PathEscape(values[k]))
+	}
+
+	body, err := renderBody(t.bodies[apiID], params)
+	if err != nil {
+		return apiConfig, "", "", fmt.Errorf("%s: %w", apiID, err)
+	}
+	return apiConfig, path, body, nil
+}
+
+// stringParams 转换为模板数据
+func stringParams(params map[string]string) map[string]interface{} {
+	data := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		data[k] = v
 	}
+	return data
+}
 
-	// 替换参数
-	body := t.replaceParams(apiConfig.Body, paramsStr)
+// legacyKey 旧格式参数中 key= 的开头
+var legacyKey = regexp.MustCompile(`^\s*[A-Za-z_][A-Za-z0-9_.-]*\s*=`)
+
+// parseParams 解析字符串形式的工具参数: JSON 对象 (可含数组等结构化值) 或 key1=value1,key2=value2;
+// 旧格式中不以 key= 开头的逗号分段并入前一个值, 值中的等号原样保留
+func parseParams(paramsStr string) (map[string]interface{}, error) {
+	params := make(map[string]interface{})
+	paramsStr = strings.TrimSpace(paramsStr)
+	if strings.HasPrefix(paramsStr, "{") {
+		if err := json.Unmarshal([]byte(paramsStr), &params); err != nil {
+			return nil, fmt.Errorf("invalid params JSON: %v", err)
+		}
+		return params, nil
+	}
+
+	var pairs []string
+	for _, part := range strings.Split(paramsStr, ",") {
+		if len(pairs) > 0 && !legacyKey.MatchString(part) {
+			pairs[len(pairs)-1] += "," + part
+			continue
+		}
+		pairs = append(pairs, part)
+	}
+	for _, pair := range pairs {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) == 2 {
+			params[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+	return params, nil
+}
 
+// do 发送请求, 返回格式化后的响应; 4xx/5xx 视为失败
+func (t *SecOpsSheikahAPITool) do(ctx context.Context, method, path, body string) (string, error) {
 	// 构建请求
-	url := t.baseURL + apiConfig.Path
+	endpoint := t.baseURL + path
 	var reqBody io.Reader
 	if body != "" {
 		reqBody = bytes.NewBufferString(body)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, apiConfig.Method, url, reqBody)
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reqBody)
 	if err != nil {
-		return tools.ErrorResult(fmt.Sprintf("failed to create request: %v", err))
+		return "", fmt.Errorf("failed to create request: %v", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -112,52 +310,33 @@ func (t *SecOpsSheikahAPITool) Execute(ctx context.Context, args map[string]inte
 	// 发送请求
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return tools.ErrorResult(fmt.Sprintf("request failed: %v", err))
+		return "", fmt.Errorf("request failed: %v", err)
 	}
 	defer resp.Body.Close()
 
 	// 读取响应
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return tools.ErrorResult(fmt.Sprintf("failed to read response: %v", err))
+		return "", fmt.Errorf("failed to read response: %v", err)
 	}
 
 	if resp.StatusCode >= 400 {
-		return tools.ErrorResult(fmt.Sprintf("API returned error: %d - %s", resp.StatusCode, string(respBody)))
+		return "", fmt.Errorf("API returned error: %d - %s", resp.StatusCode, string(respBody))
 	}
 
 	// 尝试解析 JSON 响应
 	var prettyJSON bytes.Buffer
 	if err := json.Indent(&prettyJSON, respBody, "", "  "); err == nil {
-		return tools.UserResult(prettyJSON.String())
+		return prettyJSON.String(), nil
 	}
 
-	return tools.UserResult(string(respBody))
+	return string(respBody), nil
 }
 
-// replaceParams 替换参数
-func (t *SecOpsSheikahAPITool) replaceParams(template, paramsStr string) string {
-	if template == "" || paramsStr == "" {
-		return template
-	}
-
-	params := make(map[string]string)
-	pairs := strings.Split(paramsStr, ",")
-	for _, pair := range pairs {
-		kv := strings.SplitN(pair, "=", 2)
-		if len(kv) == 2 {
-			params[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
-		}
-	}
-
-	result := template
-	for k, v := range params {
-		result = strings.ReplaceAll(result, "{{."+k+"}}", v)
-		result = strings.ReplaceAll(result, "{{"+k+"}}", v)
-		result = strings.ReplaceAll(result, "$"+k, v)
-	}
-
-	return result
+// jsonEscape 转义为 JSON 字符串内容 (不含两端引号)
+func jsonEscape(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b[1 : len(b)-1])
 }
 
 // Close 关闭客户端
@@ -165,3 +344,16 @@ func (t *SecOpsSheikahAPITool) Close() error {
 	t.client = nil
 	return nil
 }
+
+// SetTransport 替换 HTTP 传输层, 用于演示模式和测试
+func (t *SecOpsSheikahAPITool) SetTransport(rt http.RoundTripper) {
+	t.client = &http.Client{Transport: rt}
+}
+
+// Transport 当前的 HTTP 传输层, 供故障注入等在其外层包装
+func (t *SecOpsSheikahAPITool) Transport() http.RoundTripper {
+	if t.client == nil || t.client.Transport == nil {
+		return http.DefaultTransport
+	}
+	return t.client.Transport
+}
@@ -0,0 +1,285 @@
+package secops
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultSplunkQueries Splunk 的内置查询, 与 ClickHouse 的同名模板对应; 参数写作 $name, 不加引号
+var defaultSplunkQueries = map[string]string{
+	"access_by_ip":     `search index=access src_ip=$ip earliest=-24h | head 30 | table _time, src_ip, method, uri, status, req_risk`,
+	"access_by_user":   `search index=access uid=$user_id earliest=-24h | head 30 | table _time, src_ip, method, uri, status, req_risk`,
+	"access_by_device": `search index=access sid=$device_id earliest=-24h | head 30 | table _time, src_ip, method, uri, status, req_risk`,
+}
+
+const (
+	defaultSplunkTimeout    = 60 * time.Second
+	defaultSplunkMaxResults = 1000
+	splunkPageSize          = 500
+)
+
+// SplunkConfig Splunk 数据源参数
+type SplunkConfig struct {
+	BaseURL            string // 管理端口地址, 如 https://splunk.internal:8089
+	Token              string // 认证令牌, 优先于用户名密码
+	Username           string
+	Password           string
+	Timeout            time.Duration // 搜索作业超时, 超时后取消作业; 默认 60s
+	MaxResults         int           // 最多读取的结果条数, 默认 1000
+	InsecureSkipVerify bool
+}
+
+// SplunkSource 通过 REST API 提交 SPL 搜索作业并轮询结果的数据源
+//
+// 模板中引号外的 $name 替换为转义后的带引号字符串, 参数值无法改变 SPL 结构。
+type SplunkSource struct {
+	cfg          SplunkConfig
+	queries      map[string]string
+	client       *http.Client
+	pollInterval time.Duration
+}
+
+// NewSplunkSource 创建 Splunk 数据源; queries 覆盖或补充内置查询
+func NewSplunkSource(cfg SplunkConfig, queries map[string]string) (*SplunkSource, error) {
+	if cfg.BaseURL == "" {
+		return nil, errors.New("splunk base url is required")
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultSplunkTimeout
+	}
+	if cfg.MaxResults <= 0 {
+		cfg.MaxResults = defaultSplunkMaxResults
+	}
+
+	merged := make(map[string]string)
+	for id, q := range defaultSplunkQueries {
+		merged[id] = q
+	}
+	for id, q := range queries {
+		merged[id] = q
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.InsecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	return &SplunkSource{
+		cfg:          cfg,
+		queries:      merged,
+		client:       &http.Client{Transport: transport, Timeout: 30 * time.Second},
+		pollInterval: time.Second,
+	}, nil
+}
+
+// Driver 数据源类型
+func (s *SplunkSource) Driver() string {
+	return "splunk"
+}
+
+// Queries 可用的查询模板
+func (s *SplunkSource) Queries() map[string]string {
+	return s.queries
+}
+
+// Query 提交搜索作业, 等待完成后分页读取结果; 超时或 ctx 取消时取消作业
+func (s *SplunkSource) Query(ctx context.Context, query string, params map[string]string) ([]string, [][]interface{}, error) {
+	spl, err := expandParams(query, params, func(_, value string) string {
+		return splunkQuote(value)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	spl = strings.TrimSpace(spl)
+	if !strings.HasPrefix(spl, "search ") && !strings.HasPrefix(spl, "|") {
+		spl = "search " + spl
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	sid, err := s.submit(ctx, spl)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.wait(ctx, sid); err != nil {
+		s.cancelJob(sid)
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, nil, fmt.Errorf("splunk search %s timed out after %s", sid, s.cfg.Timeout)
+		}
+		return nil, nil, err
+	}
+	return s.results(ctx, sid)
+}
+
+// Close 关闭空闲连接
+func (s *SplunkSource) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// submit 创建搜索作业, 返回 sid
+func (s *SplunkSource) submit(ctx context.Context, spl string) (string, error) {
+	form := url.Values{}
+	form.Set("search", spl)
+	form.Set("output_mode", "json")
+	form.Set("max_count", strconv.Itoa(s.cfg.MaxResults))
+
+	var resp struct {
+		SID string `json:"sid"`
+	}
+	if err := s.do(ctx, http.MethodPost, "/services/search/jobs", form, &resp); err != nil {
+		return "", fmt.Errorf("failed to submit splunk search: %w", err)
+	}
+	if resp.SID == "" {
+		return "", errors.New("splunk did not return a search id")
+	}
+	return resp.SID, nil
+}
+
+// wait 轮询作业状态直到完成
+func (s *SplunkSource) wait(ctx context.Context, sid string) error {
+	for {
+		var resp struct {
+			Entry []struct {
+				Content struct {
+					DispatchState string `json:"dispatchState"`
+					IsDone        bool   `json:"isDone"`
+					IsFailed      bool   `json:"isFailed"`
+					Messages      []struct {
+						Type string `json:"type"`
+						Text string `json:"text"`
+					} `json:"messages"`
+				} `json:"content"`
+			} `json:"entry"`
+		}
+		q := url.Values{"output_mode": {"json"}}
+		if err := s.do(ctx, http.MethodGet, "/services/search/jobs/"+url.PathEscape(sid)+"?"+q.Encode(), nil, &resp); err != nil {
+			return err
+		}
+		if len(resp.Entry) > 0 {
+			job := resp.Entry[0].Content
+			if job.IsFailed || job.DispatchState == "FAILED" {
+				var msgs []string
+				for _, m := range job.Messages {
+					msgs = append(msgs, m.Text)
+				}
+				return fmt.Errorf("splunk search failed: %s", strings.Join(msgs, "; "))
+			}
+			if job.IsDone || job.DispatchState == "DONE" {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.pollInterval):
+		}
+	}
+}
+
+// results 分页读取作业结果, 最多 MaxResults 条
+func (s *SplunkSource) results(ctx context.Context, sid string) ([]string, [][]interface{}, error) {
+	var columns []string
+	var rows [][]interface{}
+	for offset := 0; offset < s.cfg.MaxResults; {
+		count := splunkPageSize
+		if rest := s.cfg.MaxResults - offset; rest < count {
+			count = rest
+		}
+		q := url.Values{
+			"output_mode": {"json"},
+			"offset":      {strconv.Itoa(offset)},
+			"count":       {strconv.Itoa(count)},
+		}
+		var page struct {
+			Fields []struct {
+				Name string `json:"name"`
+			} `json:"fields"`
+			Results []map[string]interface{} `json:"results"`
+		}
+		if err := s.do(ctx, http.MethodGet, "/services/search/jobs/"+url.PathEscape(sid)+"/results?"+q.Encode(), nil, &page); err != nil {
+			return nil, nil, fmt.Errorf("failed to read splunk results: %w", err)
+		}
+		if columns == nil {
+			for _, f := range page.Fields {
+				columns = append(columns, f.Name)
+			}
+		}
+		for _, r := range page.Results {
+			row := make([]interface{}, len(columns))
+			for i, c := range columns {
+				row[i] = r[c]
+			}
+			rows = append(rows, row)
+		}
+		if len(page.Results) < count {
+			break
+		}
+		offset += len(page.Results)
+	}
+	return columns, rows, nil
+}
+
+// cancelJob 取消作业, 释放 Splunk 端资源
+func (s *SplunkSource) cancelJob(sid string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	form := url.Values{"action": {"cancel"}}
+	s.do(ctx, http.MethodPost, "/services/search/jobs/"+url.PathEscape(sid)+"/control", form, nil)
+}
+
+// do 发送请求并解析 JSON 响应
+func (s *SplunkSource) do(ctx context.Context, method, path string, form url.Values, out interface{}) error {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.cfg.BaseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if s.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.cfg.Token)
+	} else if s.cfg.Username != "" {
+		req.SetBasicAuth(s.cfg.Username, s.cfg.Password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("splunk error %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// splunkQuote 转义为 SPL 双引号字符串
+func splunkQuote(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `"`, `\"`)
+	return `"` + v + `"`
+}
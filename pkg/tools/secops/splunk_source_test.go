@@ -0,0 +1,101 @@
+package secops
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeSplunk 模拟搜索作业接口: 第 doneAfter 次轮询后完成, 共 total 条结果
+func fakeSplunk(t *testing.T, doneAfter, total int, search *string, cancelled *int32) *httptest.Server {
+	t.Helper()
+	var polls int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /services/search/jobs", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		r.ParseForm()
+		*search = r.PostForm.Get("search")
+		fmt.Fprint(w, `{"sid":"job1"}`)
+	})
+	mux.HandleFunc("GET /services/search/jobs/job1", func(w http.ResponseWriter, r *http.Request) {
+		done := int(atomic.AddInt32(&polls, 1)) > doneAfter
+		fmt.Fprintf(w, `{"entry":[{"content":{"dispatchState":"RUNNING","isDone":%t}}]}`, done)
+	})
+	mux.HandleFunc("GET /services/search/jobs/job1/results", func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		count, _ := strconv.Atoi(r.URL.Query().Get("count"))
+		var results []string
+		for i := offset; i < total && i < offset+count; i++ {
+			results = append(results, fmt.Sprintf(`{"src_ip":"10.0.0.%d","status":"200"}`, i))
+		}
+		fmt.Fprintf(w, `{"fields":[{"name":"src_ip"},{"name":"status"}],"results":[%s]}`, strings.Join(results, ","))
+	})
+	mux.HandleFunc("POST /services/search/jobs/job1/control", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(cancelled, 1)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSplunkSourceQuery(t *testing.T) {
+	var search string
+	var cancelled int32
+	srv := fakeSplunk(t, 2, 1200, &search, &cancelled)
+
+	src, err := NewSplunkSource(SplunkConfig{BaseURL: srv.URL, Token: "tok", MaxResults: 1100}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src.pollInterval = time.Millisecond
+
+	columns, rows, err := src.Query(context.Background(), src.Queries()["access_by_ip"], map[string]string{"ip": `1.2.3.4" OR index=*`})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if !strings.Contains(search, `src_ip="1.2.3.4\" OR index=*"`) {
+		t.Errorf("search = %s", search)
+	}
+	if len(columns) != 2 || columns[0] != "src_ip" {
+		t.Errorf("columns = %v", columns)
+	}
+	// 分页读取, 截断到 MaxResults
+	if len(rows) != 1100 || rows[1099][0] != "10.0.0.1099" {
+		t.Errorf("rows = %d", len(rows))
+	}
+	if cancelled != 0 {
+		t.Errorf("finished job should not be cancelled")
+	}
+}
+
+func TestSplunkSourceTimeout(t *testing.T) {
+	var search string
+	var cancelled int32
+	srv := fakeSplunk(t, 1<<30, 0, &search, &cancelled)
+
+	src, err := NewSplunkSource(SplunkConfig{BaseURL: srv.URL, Token: "tok", Timeout: 50 * time.Millisecond}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src.pollInterval = 5 * time.Millisecond
+
+	_, _, err = src.Query(context.Background(), "index=main | head 1", nil)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("err = %v, want timeout", err)
+	}
+	if search != "search index=main | head 1" {
+		t.Errorf("search = %s", search)
+	}
+	if atomic.LoadInt32(&cancelled) != 1 {
+		t.Errorf("timed out job should be cancelled")
+	}
+}
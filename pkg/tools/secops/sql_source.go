@@ -0,0 +1,206 @@
+package secops
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+)
+
+// sqlDialects 支持的 database/sql 驱动
+var sqlDialects = map[string]bool{
+	"postgres": true,
+	"mysql":    true,
+}
+
+// defaultSQLQueries 审计日志的内置查询, 与 ClickHouse 的同名模板对应; 参数写作 $name, 不加引号
+var defaultSQLQueries = map[string]map[string]string{
+	"postgres": {
+		"access_by_ip":     `SELECT ip, ts, method, url, status, req_risk FROM access WHERE ip = $ip AND ts > now() - interval '1 day' ORDER BY ts DESC LIMIT 30`,
+		"access_by_user":   `SELECT ip, ts, method, url, status, req_risk FROM access WHERE uid = $user_id AND ts > now() - interval '1 day' ORDER BY ts DESC LIMIT 30`,
+		"access_by_device": `SELECT ip, ts, method, url, status, req_risk FROM access WHERE sid = $device_id AND ts > now() - interval '1 day' ORDER BY ts DESC LIMIT 30`,
+		"http_details":     `SELECT req, res FROM access_raw WHERE id = $id LIMIT 3`,
+	},
+	"mysql": {
+		"access_by_ip":     "SELECT ip, ts, method, url, status, req_risk FROM access WHERE ip = $ip AND ts > NOW() - INTERVAL 1 DAY ORDER BY ts DESC LIMIT 30",
+		"access_by_user":   "SELECT ip, ts, method, url, status, req_risk FROM access WHERE uid = $user_id AND ts > NOW() - INTERVAL 1 DAY ORDER BY ts DESC LIMIT 30",
+		"access_by_device": "SELECT ip, ts, method, url, status, req_risk FROM access WHERE sid = $device_id AND ts > NOW() - INTERVAL 1 DAY ORDER BY ts DESC LIMIT 30",
+		"http_details":     "SELECT req, res FROM access_raw WHERE id = $id LIMIT 3",
+	},
+}
+
+// QuerySource query_data 工具通过 source 参数选择的数据源
+type QuerySource interface {
+	// Driver 数据源类型, 如 postgres、mysql、splunk
+	Driver() string
+	// Queries 可用的查询模板
+	Queries() map[string]string
+	// Query 填入参数并执行查询, 返回列名和行
+	Query(ctx context.Context, query string, params map[string]string) ([]string, [][]interface{}, error)
+	Close() error
+}
+
+// SQLSource 基于 database/sql 的查询数据源 (PostgreSQL/MySQL)
+//
+// 模板中的 $name 绑定为驱动占位符, 参数值不会拼接进 SQL; 查询在只读事务中执行。
+type SQLSource struct {
+	driver  string
+	db      *sql.DB
+	queries map[string]string
+}
+
+// OpenSQLSource 打开数据源; queries 覆盖或补充该驱动的内置查询
+func OpenSQLSource(driver, dsn string, queries map[string]string) (*SQLSource, error) {
+	if !sqlDialects[driver] {
+		return nil, fmt.Errorf("unsupported driver %q (supported: postgres, mysql, splunk, loki)", driver)
+	}
+	if dsn == "" {
+		return nil, fmt.Errorf("dsn is required")
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(4)
+	db.SetConnMaxIdleTime(5 * time.Minute)
+	return NewSQLSource(driver, db, queries), nil
+}
+
+// NewSQLSource 使用已打开的连接创建数据源
+func NewSQLSource(driver string, db *sql.DB, queries map[string]string) *SQLSource {
+	merged := make(map[string]string)
+	for id, q := range defaultSQLQueries[driver] {
+		merged[id] = q
+	}
+	for id, q := range queries {
+		merged[id] = q
+	}
+	return &SQLSource{driver: driver, db: db, queries: merged}
+}
+
+// Driver 驱动名称
+func (s *SQLSource) Driver() string {
+	return s.driver
+}
+
+// Queries 可用的查询模板
+func (s *SQLSource) Queries() map[string]string {
+	return s.queries
+}
+
+// Query 绑定参数并执行查询, 返回列名和行
+func (s *SQLSource) Query(ctx context.Context, query string, params map[string]string) ([]string, [][]interface{}, error) {
+	stmt, args, err := bindParams(s.driver, query, params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+	var data [][]interface{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, nil, err
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		data = append(data, values)
+	}
+	return columns, data, rows.Err()
+}
+
+// Close 关闭连接池
+func (s *SQLSource) Close() error {
+	return s.db.Close()
+}
+
+// bindParams 将 $name 替换为驱动占位符 (postgres 为 $1.., mysql 为 ?) 并按顺序返回参数值;
+// 引号内的文本和 postgres 的 $1 原样保留
+func bindParams(driver, query string, params map[string]string) (string, []interface{}, error) {
+	var args []interface{}
+	index := make(map[string]int)
+	stmt, err := expandParams(query, params, func(name, value string) string {
+		if driver != "postgres" {
+			args = append(args, value)
+			return "?"
+		}
+		n, seen := index[name]
+		if !seen {
+			args = append(args, value)
+			n = len(args)
+			index[name] = n
+		}
+		return "$" + strconv.Itoa(n)
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return stmt, args, nil
+}
+
+// expandParams 将引号外的 $name 替换为 emit 的返回值, 参数缺失时报错
+func expandParams(query string, params map[string]string, emit func(name, value string) string) (string, error) {
+	var out strings.Builder
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := strings.IndexByte(query[i+1:], c)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated quote in query")
+			}
+			out.WriteString(query[i : i+end+2])
+			i += end + 1
+		case c == '$' && i+1 < len(query) && isParamStart(query[i+1]):
+			j := i + 1
+			for j < len(query) && isParamChar(query[j]) {
+				j++
+			}
+			name := query[i+1 : j]
+			value, ok := params[name]
+			if !ok {
+				return "", fmt.Errorf("missing parameter: %s", name)
+			}
+			out.WriteString(emit(name, value))
+			i = j - 1
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.String(), nil
+}
+
+func isParamStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isParamChar(c byte) bool {
+	return isParamStart(c) || (c >= '0' && c <= '9')
+}
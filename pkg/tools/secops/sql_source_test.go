@@ -0,0 +1,73 @@
+package secops
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBindParams(t *testing.T) {
+	params := map[string]string{"ip": "1.2.3.4' OR '1'='1", "uid": "u1"}
+	query := `SELECT '$ip', "$uid" FROM access WHERE ip = $ip AND (uid = $uid OR owner = $uid) AND n > $1`
+
+	stmt, args, err := bindParams("postgres", query, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `SELECT '$ip', "$uid" FROM access WHERE ip = $1 AND (uid = $2 OR owner = $2) AND n > $1`; stmt != want {
+		t.Errorf("postgres stmt = %s", stmt)
+	}
+	if !reflect.DeepEqual(args, []interface{}{params["ip"], "u1"}) {
+		t.Errorf("postgres args = %v", args)
+	}
+
+	stmt, args, err = bindParams("mysql", "SELECT * FROM access WHERE uid = $uid OR owner = $uid", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stmt != "SELECT * FROM access WHERE uid = ? OR owner = ?" || len(args) != 2 {
+		t.Errorf("mysql stmt = %s, args = %v", stmt, args)
+	}
+
+	if _, _, err := bindParams("mysql", "SELECT * FROM access WHERE ip = $ip AND sid = $device_id", params); err == nil || !strings.Contains(err.Error(), "device_id") {
+		t.Errorf("missing param: err = %v", err)
+	}
+	if _, _, err := bindParams("mysql", "SELECT 'unterminated", nil); err == nil {
+		t.Error("expected error for unterminated quote")
+	}
+}
+
+func TestOpenSQLSource(t *testing.T) {
+	if _, err := OpenSQLSource("sqlite", "file.db", nil); err == nil {
+		t.Error("expected error for unsupported driver")
+	}
+
+	src, err := OpenSQLSource("postgres", "postgres://127.0.0.1:1/audit", map[string]string{
+		"login_failures": "SELECT * FROM audit WHERE user_name = $user",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	if _, ok := src.Queries()["access_by_ip"]; !ok {
+		t.Error("built-in query pack missing")
+	}
+	if _, ok := src.Queries()["login_failures"]; !ok {
+		t.Error("configured query missing")
+	}
+
+	tool := NewSecOpsQueryDataTool(nil, "http://127.0.0.1:1", "", "")
+	tool.AddSource("audit", src)
+	if !strings.Contains(tool.Description(), "audit (postgres)") {
+		t.Errorf("description does not list source: %s", tool.Description())
+	}
+	res := tool.Execute(context.Background(), map[string]interface{}{"source": "nope", "sql_id": "access_by_ip"})
+	if !res.IsError || !strings.Contains(res.ForLLM, "audit") {
+		t.Errorf("unknown source result = %+v", res)
+	}
+	res = tool.Execute(context.Background(), map[string]interface{}{"source": "audit", "sql_id": "missing"})
+	if !res.IsError {
+		t.Errorf("unknown sql_id result = %+v", res)
+	}
+}
@@ -0,0 +1,180 @@
+// Package updatecheck compares the running version against the latest
+// release published at a releases endpoint.
+package updatecheck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultInterval = 24 * time.Hour
+
+// Status is the result of the most recent check.
+type Status struct {
+	Current         string    `json:"current"`
+	Latest          string    `json:"latest,omitempty"`
+	UpdateAvailable bool      `json:"updateAvailable"`
+	ReleaseURL      string    `json:"releaseUrl,omitempty"`
+	PublishedAt     string    `json:"publishedAt,omitempty"`
+	CheckedAt       time.Time `json:"checkedAt,omitempty"`
+	Error           string    `json:"error,omitempty"`
+}
+
+// release accepts both the GitHub "latest release" payload and a minimal
+// {"version": "...", "url": "..."} document for self-hosted mirrors.
+type release struct {
+	TagName     string `json:"tag_name"`
+	HTMLURL     string `json:"html_url"`
+	PublishedAt string `json:"published_at"`
+	Version     string `json:"version"`
+	URL         string `json:"url"`
+}
+
+type Checker struct {
+	url      string
+	current  string
+	interval time.Duration
+	client   *http.Client
+
+	mu       sync.Mutex
+	status   Status
+	checking bool // a fetch is in flight; other callers get the cached status
+}
+
+func NewChecker(url, current string, interval time.Duration) *Checker {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Checker{
+		url:      url,
+		current:  current,
+		interval: interval,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		status:   Status{Current: current},
+	}
+}
+
+// Status returns the cached result, refreshing it when older than the interval.
+// The lock is not held during the fetch, so a slow releases endpoint only
+// delays the caller that triggered the refresh.
+func (c *Checker) Status(ctx context.Context) Status {
+	c.mu.Lock()
+	if c.checking || (!c.status.CheckedAt.IsZero() && time.Since(c.status.CheckedAt) < c.interval) {
+		defer c.mu.Unlock()
+		return c.status
+	}
+	c.checking = true
+	c.mu.Unlock()
+
+	status := Status{Current: c.current, CheckedAt: time.Now()}
+	rel, err := c.fetch(ctx)
+	if err != nil {
+		status.Error = err.Error()
+	} else {
+		status.Latest = rel.version()
+		status.ReleaseURL = rel.url()
+		status.PublishedAt = rel.PublishedAt
+		status.UpdateAvailable = Newer(status.Latest, c.current)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.status = status
+	c.checking = false
+	return status
+}
+
+func (c *Checker) fetch(ctx context.Context) (*release, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("releases endpoint returned HTTP %d", resp.StatusCode)
+	}
+
+	var rel release
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&rel); err != nil {
+		return nil, fmt.Errorf("decode release: %w", err)
+	}
+	if rel.version() == "" {
+		return nil, fmt.Errorf("release has no version")
+	}
+	return &rel, nil
+}
+
+func (r *release) version() string {
+	if r.TagName != "" {
+		return r.TagName
+	}
+	return r.Version
+}
+
+// url returns the release page, or "" unless it is an absolute https URL:
+// the UI renders it as a link, so other schemes (javascript:, data:) are dropped.
+func (r *release) url() string {
+	raw := r.HTMLURL
+	if raw == "" {
+		raw = r.URL
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return ""
+	}
+	return raw
+}
+
+// Newer reports whether latest is a higher release than current. Versions
+// that are not vX.Y.Z (e.g. "dev") are never considered outdated. A
+// `git describe` suffix such as "v1.2.0-3-gabc123" counts as v1.2.0.
+func Newer(latest, current string) bool {
+	l, ok := parse(latest)
+	if !ok {
+		return false
+	}
+	c, ok := parse(current)
+	if !ok {
+		return false
+	}
+	for i := range l {
+		if l[i] != c[i] {
+			return l[i] > c[i]
+		}
+	}
+	return false
+}
+
+func parse(v string) ([3]int, bool) {
+	var out [3]int
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+ "); i >= 0 {
+		v = v[:i]
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) == 0 || len(parts) > 3 {
+		return out, false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return out, false
+		}
+		out[i] = n
+	}
+	return out, true
+}
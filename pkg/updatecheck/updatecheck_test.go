@@ -0,0 +1,128 @@
+package updatecheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewer(t *testing.T) {
+	tests := []struct {
+		latest, current string
+		want            bool
+	}{
+		{"v1.2.0", "v1.1.9", true},
+		{"v1.10.0", "v1.9.0", true},
+		{"1.2.0", "v1.2.0", false},
+		{"v1.2.0", "v1.2.0-3-gabc1234", false},
+		{"v1.2.1", "v1.2.0-3-gabc1234-dirty", true},
+		{"v1.2", "v1.2.0", false},
+		{"v2.0.0", "dev", false},
+		{"nightly", "v1.0.0", false},
+		{"v1.0.0", "v2.0.0", false},
+	}
+	for _, tt := range tests {
+		if got := Newer(tt.latest, tt.current); got != tt.want {
+			t.Errorf("Newer(%q, %q) = %v, want %v", tt.latest, tt.current, got, tt.want)
+		}
+	}
+}
+
+func TestCheckerStatus(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Write([]byte(`{"tag_name": "v0.3.0", "html_url": "https://example.com/releases/v0.3.0", "published_at": "2026-10-01T00:00:00Z"}`))
+	}))
+	defer srv.Close()
+
+	c := NewChecker(srv.URL, "v0.2.1", time.Hour)
+	st := c.Status(context.Background())
+	if !st.UpdateAvailable || st.Latest != "v0.3.0" || st.ReleaseURL != "https://example.com/releases/v0.3.0" || st.Error != "" {
+		t.Errorf("Status() = %+v", st)
+	}
+
+	c.Status(context.Background())
+	if hits.Load() != 1 {
+		t.Errorf("endpoint hit %d times, want 1 (cached)", hits.Load())
+	}
+}
+
+func TestCheckerGenericPayloadAndErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/generic":
+			w.Write([]byte(`{"version": "1.0.0", "url": "https://mirror/soclaw"}`))
+		case "/empty":
+			w.Write([]byte(`{}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	st := NewChecker(srv.URL+"/generic", "1.0.0", 0).Status(context.Background())
+	if st.UpdateAvailable || st.Latest != "1.0.0" || st.ReleaseURL != "https://mirror/soclaw" {
+		t.Errorf("generic Status() = %+v", st)
+	}
+
+	for _, path := range []string{"/empty", "/missing"} {
+		st := NewChecker(srv.URL+path, "1.0.0", 0).Status(context.Background())
+		if st.Error == "" || st.UpdateAvailable {
+			t.Errorf("%s: Status() = %+v, want error", path, st)
+		}
+	}
+}
+
+func TestCheckerDropsNonHTTPSReleaseURL(t *testing.T) {
+	for _, payload := range []string{
+		`{"tag_name": "v0.3.0", "html_url": "javascript:alert(document.cookie)"}`,
+		`{"version": "0.3.0", "url": "http://mirror/soclaw"}`,
+		`{"version": "0.3.0", "url": "https:///no-host"}`,
+	} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(payload))
+		}))
+		st := NewChecker(srv.URL, "v0.2.0", 0).Status(context.Background())
+		srv.Close()
+		if st.ReleaseURL != "" || !st.UpdateAvailable {
+			t.Errorf("%s: Status() = %+v, want update without release URL", payload, st)
+		}
+	}
+}
+
+func TestCheckerDoesNotBlockDuringFetch(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte(`{"version": "1.1.0"}`))
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	c := NewChecker(srv.URL, "1.0.0", time.Hour)
+	go c.Status(context.Background())
+	for {
+		c.mu.Lock()
+		checking := c.checking
+		c.mu.Unlock()
+		if checking {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	done := make(chan Status)
+	go func() { done <- c.Status(context.Background()) }()
+	select {
+	case st := <-done:
+		if st.Current != "1.0.0" || !st.CheckedAt.IsZero() {
+			t.Errorf("Status() during fetch = %+v, want cached status", st)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Status() blocked while another check was in flight")
+	}
+}